@@ -4,9 +4,11 @@ import (
 	"context"
 	"embed"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"os"
 	"reflect"
+	"time"
 
 	"github.com/danielgtaylor/huma/v2"
 	"github.com/gofiber/fiber/v2"
@@ -18,8 +20,11 @@ import (
 	"go.uber.org/zap"
 
 	"code-kanban/api/h"
+	"code-kanban/model"
+	"code-kanban/service"
 	"code-kanban/service/terminal"
 	"code-kanban/utils"
+	"code-kanban/utils/git"
 )
 
 // AppInfo 应用信息
@@ -51,7 +56,7 @@ func Init(ctx context.Context, cfg *utils.AppConfig, assets embed.FS, info *AppI
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     cfg.CorsAllowOrigins,
 		AllowMethods:     "GET,POST",
-		AllowHeaders:     "Origin, Content-Type, Accept, Authorization",
+		AllowHeaders:     "Origin, Content-Type, Accept, Authorization, Idempotency-Key",
 		AllowCredentials: cfg.CorsAllowOrigins != "*",
 	}))
 	app.Use(recover.New(recover.Config{EnableStackTrace: true}))
@@ -73,25 +78,140 @@ func Init(ctx context.Context, cfg *utils.AppConfig, assets embed.FS, info *AppI
 		ScrollbackEnabled:         cfg.Developer.EnableTerminalScrollback,
 		RenameTitleEachCommand:    cfg.Developer.RenameSessionTitleEachCommand,
 		AutoCreateTaskOnStartWork: cfg.Developer.AutoCreateTaskOnStartWork,
+		DetectLinks:               cfg.Developer.DetectTerminalLinks,
+		ConfirmMultilineInput:     cfg.Developer.ConfirmMultilineInput,
+		AIDetectionDiagnostics:    cfg.Developer.AIDetectionDiagnostics,
+		CaptureRawOutput:          cfg.Developer.CaptureRawOutput,
+		CloseOnMaxLifetime:        cfg.Terminal.CloseOnMaxLifetime,
+		QuotaMaxSessions:          cfg.Terminal.QuotaMaxSessions,
+		QuotaMaxMemoryBytes:       uint64(max(cfg.Terminal.QuotaMaxMemoryMB, 0)) * 1024 * 1024,
+		RecordEvents:              cfg.Terminal.RecordEvents,
+		RecordEventsDir:           cfg.Terminal.RecordEventsDir,
+		RecordEventsMaxFiles:      cfg.Terminal.RecordEventsMaxFiles,
+		OutputTriggers:            outputTriggersFromConfig(cfg.Terminal.OutputTriggers),
+		Macros:                    macrosFromConfig(cfg.Terminal.Macros),
+		TitleRules:                titleRulesFromConfig(cfg.Terminal.TitleRules),
+		HighlightRules:            highlightRulesFromConfig(cfg.Terminal.HighlightRules),
+		AlertRules:                alertRulesFromConfig(cfg.Terminal.AlertRules),
+		OutputFilters:             outputFiltersFromConfig(cfg.Terminal.OutputFilters),
+		OutputLog:                 outputLogFromConfig(cfg.Terminal.OutputLog),
+		Screenshots:               screenshotsFromConfig(cfg.Terminal.Screenshots, theLogger),
+		Term:                      cfg.Terminal.Term,
+		TermEnv:                   cfg.Terminal.TermEnv,
+		CompletionTemplate:        cfg.Terminal.Notifications.CompletionTemplate,
+		ApprovalTemplate:          cfg.Terminal.Notifications.ApprovalTemplate,
+		CompletionSettleDelay:     time.Duration(cfg.Terminal.Notifications.CompletionDelayMs) * time.Millisecond,
+		ApprovalTimeout:           approvalTimeoutFromConfig(cfg.Terminal.Notifications.ApprovalTimeout, theLogger),
+		ModelPricing:              cfg.Terminal.ModelPricing,
+		Webhook:                   webhookFromConfig(cfg.Terminal.Webhook, theLogger),
+		ScrollbackSpill:           scrollbackSpillFromConfig(cfg.Terminal.ScrollbackSpill),
+		ScrollbackCollapse:        cfg.Terminal.ScrollbackCollapse,
+		ScrollbackTotalBytes:      max(cfg.Terminal.ScrollbackTotalMB, 0) * 1024 * 1024,
+		BinaryOutput:              cfg.Terminal.BinaryOutput,
+		AIProfiles:                aiProfilesFromConfig(cfg.Terminal.AIProfiles),
 	}, theLogger)
 	terminalManager.StartBackground(ctx)
+	service.RegisterWorktreeStatusObserver(func(worktree *model.Worktree, status *git.WorktreeStatus) {
+		terminalManager.UpdateWorktreeConflicts(worktree.Id, status.Conflicted)
+	})
+	service.RegisterWorktreeMoveObserver(func(worktree *model.Worktree, oldPath string) {
+		terminalManager.RelocateWorktree(worktree.Id, oldPath, worktree.Path)
+	})
+	service.SetWorktreeSessionChecker(terminalManager.HasWorktreeSessions)
+	startWorktreeWatcher(ctx, cfg, terminalManager, theLogger)
+	configureCommitPolicy(cfg, theLogger)
+	service.SetWorktreeBranchSync(cfg.Git.SyncBranchOnSwitch)
 
 	registerHealthRoutes(app, humaAPI)
+	registerMetricsRoute(app, terminalManager)
 	registerProjectRoutes(v1)
-	registerWorktreeRoutes(v1)
+	registerProjectTransferRoutes(v1)
+	registerProjectHealthRoutes(v1)
+	registerProjectGitConfigRoutes(v1)
+	registerWorktreeRoutes(v1, terminalManager)
 	registerBranchRoutes(v1)
 	registerTaskRoutes(v1)
 	registerNotePadRoutes(v1)
 	registerSystemRoutes(v1, cfg, terminalManager)
+	registerStatsRoutes(v1)
 	registerUploadRoutes(v1, cfg, theLogger)
 	registerTerminalRoutes(app, v1, cfg, terminalManager, theLogger)
 	registerCaptureDebugRoute(app, terminalManager, theLogger)
+	registerWorktreeEventsRoute(app)
+	registerGitStreamRoutes(ctx, app, theLogger)
 	mountStatic(app, cfg, assets, theLogger)
 	exposeOpenAPI(app, humaAPI, cfg, theLogger)
 
 	return app.Listen(cfg.ServeAt)
 }
 
+// configureCommitPolicy 加载提交消息校验规则，正则无效时记录警告并跳过校验
+func configureCommitPolicy(cfg *utils.AppConfig, logger *zap.Logger) {
+	rule, err := git.NewCommitMessageRule(cfg.Git.CommitMessagePattern, cfg.Git.CommitMessageHint)
+	if err != nil {
+		logger.Warn("ignoring commit message pattern", zap.Error(err))
+	}
+	service.SetCommitPolicy(&service.CommitPolicy{Rule: rule, Template: cfg.Git.CommitTemplate})
+}
+
+// startWorktreeWatcher 启动文件监听，worktree 内容或 .git 关键文件变化后自动刷新状态。
+// activeOnly 时只监听有打开终端的 worktree，终端创建与关闭时立即开关监听
+func startWorktreeWatcher(ctx context.Context, cfg *utils.AppConfig, terminalManager *terminal.Manager, logger *zap.Logger) {
+	if !cfg.WorktreeWatch.Enabled {
+		return
+	}
+	watchCfg := service.WorktreeWatcherConfig{
+		MaxWatchers: cfg.WorktreeWatch.MaxWatchers,
+		Debounce:    time.Duration(cfg.WorktreeWatch.DebounceMs) * time.Millisecond,
+	}
+	if cfg.WorktreeWatch.ActiveOnly {
+		watchCfg.IsActive = terminalManager.HasWorktreeSessions
+	}
+	watcher, err := service.NewWorktreeWatcher(watchCfg)
+	if err != nil {
+		logger.Warn("worktree watcher unavailable, status refresh stays manual", zap.Error(err))
+		return
+	}
+	watcher.Start(ctx)
+	if cfg.WorktreeWatch.ActiveOnly {
+		watchSessionWorktrees(ctx, watcher, terminalManager, logger)
+	}
+	service.RegisterWorktreeMoveObserver(func(worktree *model.Worktree, oldPath string) {
+		if watchCfg.IsActive != nil && !watchCfg.IsActive(worktree.Id) {
+			return
+		}
+		if err := watcher.Watch(worktree); err != nil {
+			logger.Debug("failed to watch moved worktree", zap.String("worktreeId", worktree.Id), zap.Error(err))
+		}
+	})
+}
+
+// watchSessionWorktrees 通过会话生命周期钩子开关 worktree 的监听，定期同步只用于兜底
+func watchSessionWorktrees(ctx context.Context, watcher *service.WorktreeWatcher, terminalManager *terminal.Manager, logger *zap.Logger) {
+	worktreeSvc := service.NewWorktreeService()
+	terminalManager.OnSessionCreated(func(event terminal.SessionLifecycleEvent) {
+		if event.WorktreeID == "" {
+			return
+		}
+		worktree, err := worktreeSvc.GetWorktree(ctx, event.WorktreeID)
+		if err != nil || worktree.IsBare {
+			return
+		}
+		// 钩子并发执行，会话可能已经关闭
+		if !terminalManager.HasWorktreeSessions(worktree.Id) {
+			return
+		}
+		if err := watcher.Watch(worktree); err != nil && !errors.Is(err, service.ErrWorktreeWatchLimit) {
+			logger.Debug("failed to watch worktree of new session", zap.String("worktreeId", worktree.Id), zap.Error(err))
+		}
+	})
+	terminalManager.OnSessionClosed(func(event terminal.SessionLifecycleEvent) {
+		if event.WorktreeID != "" && !terminalManager.HasWorktreeSessions(event.WorktreeID) {
+			watcher.Unwatch(event.WorktreeID)
+		}
+	})
+}
+
 // registerHealthRoutes 注册健康探测接口，用于服务监控
 func registerHealthRoutes(app *fiber.App, api huma.API) {
 	huma.Register(api, huma.Operation{
@@ -107,6 +227,191 @@ func registerHealthRoutes(app *fiber.App, api huma.API) {
 	})
 }
 
+func outputTriggersFromConfig(items []utils.TerminalOutputTrigger) []terminal.OutputTrigger {
+	triggers := make([]terminal.OutputTrigger, 0, len(items))
+	for _, item := range items {
+		triggers = append(triggers, terminal.OutputTrigger{
+			Pattern:         item.Pattern,
+			Input:           item.Input,
+			CooldownSeconds: item.CooldownSeconds,
+		})
+	}
+	return triggers
+}
+
+func macrosFromConfig(items []utils.TerminalInputMacro) []terminal.InputMacro {
+	macros := make([]terminal.InputMacro, 0, len(items))
+	for _, item := range items {
+		macros = append(macros, terminal.InputMacro{
+			Name:      item.Name,
+			Input:     item.Input,
+			Assistant: item.Assistant,
+		})
+	}
+	return macros
+}
+
+func aiProfilesFromConfig(items []utils.TerminalAIProfile) []terminal.AIProfile {
+	profiles := make([]terminal.AIProfile, 0, len(items))
+	for _, item := range items {
+		profiles = append(profiles, terminal.AIProfile{
+			ID:           item.ID,
+			Name:         item.Name,
+			Assistant:    item.Assistant,
+			Command:      item.Command,
+			Env:          item.Env,
+			InitCommands: item.InitCommands,
+			ShellName:    item.ShellName,
+		})
+	}
+	return profiles
+}
+
+func titleRulesFromConfig(items []utils.TerminalTitleRule) []terminal.TitleRule {
+	rules := make([]terminal.TitleRule, 0, len(items))
+	for _, item := range items {
+		rules = append(rules, terminal.TitleRule{
+			Pattern:   item.Pattern,
+			Group:     item.Group,
+			Assistant: item.Assistant,
+		})
+	}
+	return rules
+}
+
+func highlightRulesFromConfig(items []utils.TerminalHighlightRule) []terminal.HighlightRule {
+	rules := make([]terminal.HighlightRule, 0, len(items))
+	for _, item := range items {
+		rules = append(rules, terminal.HighlightRule{
+			Keyword:  item.Keyword,
+			Pattern:  item.Pattern,
+			Category: item.Category,
+		})
+	}
+	return rules
+}
+
+func alertRulesFromConfig(items []utils.TerminalAlertRule) []terminal.AlertRule {
+	rules := make([]terminal.AlertRule, 0, len(items))
+	for _, item := range items {
+		rules = append(rules, terminal.AlertRule{
+			Name:            item.Name,
+			Pattern:         item.Pattern,
+			CooldownSeconds: item.CooldownSeconds,
+		})
+	}
+	return rules
+}
+
+func outputFiltersFromConfig(items []utils.TerminalOutputFilterRule) []terminal.OutputFilterRule {
+	rules := make([]terminal.OutputFilterRule, 0, len(items))
+	for _, item := range items {
+		rules = append(rules, terminal.OutputFilterRule{
+			Name:     item.Name,
+			Pattern:  item.Pattern,
+			Replace:  item.Replace,
+			DropLine: item.DropLine,
+		})
+	}
+	return rules
+}
+
+func outputLogFromConfig(cfg utils.TerminalOutputLogConfig) terminal.OutputLogConfig {
+	if !cfg.Enabled || cfg.Dir == "" {
+		return terminal.OutputLogConfig{}
+	}
+	return terminal.OutputLogConfig{
+		Dir:      cfg.Dir,
+		MaxBytes: int64(max(cfg.MaxSizeMB, 0)) * 1024 * 1024,
+		MaxFiles: cfg.MaxFiles,
+		Raw:      cfg.Raw,
+	}
+}
+
+func screenshotsFromConfig(cfg utils.TerminalScreenshotConfig, logger *zap.Logger) terminal.ScreenshotConfig {
+	if !cfg.Enabled || cfg.Dir == "" {
+		return terminal.ScreenshotConfig{}
+	}
+	var interval time.Duration
+	if cfg.Interval != "" {
+		parsed, err := time.ParseDuration(cfg.Interval)
+		if err != nil || parsed < 0 {
+			logger.Warn("invalid terminal screenshot interval, using default", zap.String("interval", cfg.Interval))
+		} else {
+			interval = parsed
+		}
+	}
+	return terminal.ScreenshotConfig{
+		Dir:         cfg.Dir,
+		Interval:    interval,
+		MaxFiles:    cfg.MaxFiles,
+		MaxSessions: cfg.MaxSessions,
+	}
+}
+
+func scrollbackSpillFromConfig(cfg utils.TerminalSpillConfig) terminal.ScrollbackSpillConfig {
+	if !cfg.Enabled {
+		return terminal.ScrollbackSpillConfig{}
+	}
+	return terminal.ScrollbackSpillConfig{
+		Enabled:  true,
+		Dir:      cfg.Dir,
+		MaxBytes: int64(max(cfg.MaxSizeMB, 0)) * 1024 * 1024,
+	}
+}
+
+func webhookFromConfig(cfg utils.TerminalWebhookConfig, logger *zap.Logger) terminal.WebhookConfig {
+	if cfg.URL == "" {
+		return terminal.WebhookConfig{}
+	}
+	var timeout time.Duration
+	if cfg.Timeout != "" {
+		parsed, err := time.ParseDuration(cfg.Timeout)
+		if err != nil || parsed < 0 {
+			logger.Warn("invalid terminal webhook timeout, using default", zap.String("timeout", cfg.Timeout))
+		} else {
+			timeout = parsed
+		}
+	}
+	return terminal.WebhookConfig{
+		URL:        cfg.URL,
+		Headers:    cfg.Headers,
+		Timeout:    timeout,
+		MaxRetries: cfg.MaxRetries,
+	}
+}
+
+func approvalTimeoutFromConfig(cfg utils.TerminalApprovalTimeoutConfig, logger *zap.Logger) terminal.ApprovalTimeoutConfig {
+	policy := func(name string, raw utils.TerminalApprovalTimeoutPolicy) terminal.ApprovalTimeoutPolicy {
+		var timeout time.Duration
+		if raw.Timeout != "" {
+			parsed, err := time.ParseDuration(raw.Timeout)
+			if err != nil || parsed < 0 {
+				logger.Warn("invalid terminal approval timeout, disabling it", zap.String("assistant", name), zap.String("timeout", raw.Timeout))
+			} else {
+				timeout = parsed
+			}
+		}
+		return terminal.ApprovalTimeoutPolicy{Timeout: timeout, Action: raw.Action}
+	}
+	result := terminal.ApprovalTimeoutConfig{Default: policy("default", utils.TerminalApprovalTimeoutPolicy{Timeout: cfg.Timeout, Action: cfg.Action})}
+	if len(cfg.Assistants) > 0 {
+		result.Assistants = make(map[string]terminal.ApprovalTimeoutPolicy, len(cfg.Assistants))
+		for name, raw := range cfg.Assistants {
+			result.Assistants[name] = policy(name, raw)
+		}
+	}
+	return result
+}
+
+// registerMetricsRoute 以 Prometheus 文本格式暴露 AI 助手相关指标
+func registerMetricsRoute(app *fiber.App, manager *terminal.Manager) {
+	app.Get("/metrics", func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+		return manager.WriteMetrics(c.Response().BodyWriter())
+	})
+}
+
 // mountStatic 将内置静态资源或自定义目录挂载到 Fiber 上
 func mountStatic(app *fiber.App, cfg *utils.AppConfig, assets embed.FS, logger *zap.Logger) {
 	var fs http.FileSystem
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+	"go.uber.org/zap"
+
+	"code-kanban/service"
+	"code-kanban/service/terminal"
+	"code-kanban/utils/git"
+)
+
+func TestWatchSessionWorktrees(t *testing.T) {
+	project, worktreeIDs := newTerminalTestProject(t, 1)
+	worktree, err := service.NewWorktreeService().GetWorktree(context.Background(), worktreeIDs[0])
+	if err != nil {
+		t.Fatalf("GetWorktree: %v", err)
+	}
+	watcher, err := service.NewWorktreeWatcher(service.WorktreeWatcherConfig{})
+	if err != nil {
+		t.Skipf("fsnotify unavailable: %v", err)
+	}
+	t.Cleanup(watcher.Close)
+	manager := terminal.NewManager(terminal.Config{}, zap.NewNop())
+	t.Cleanup(func() { manager.CloseWorktreeSessions(worktree.Id) })
+	watchSessionWorktrees(context.Background(), watcher, manager, zap.NewNop())
+
+	waitWatched := func(want int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for watcher.WatchedCount() != want {
+			if time.Now().After(deadline) {
+				t.Fatalf("expected %d watched worktrees, got %d", want, watcher.WatchedCount())
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	// 不必等待定期同步，创建会话后立即开始监听，最后一个会话关闭后立即停止
+	newSession := func() *terminal.Session {
+		t.Helper()
+		session, err := manager.CreateSession(context.Background(), terminal.CreateSessionParams{
+			ProjectID:  project.Id,
+			WorktreeID: worktree.Id,
+			WorkingDir: worktree.Path,
+			Rows:       24,
+			Cols:       80,
+		})
+		if err != nil {
+			t.Skipf("pty unavailable: %v", err)
+		}
+		return session
+	}
+	first := newSession()
+	waitWatched(1)
+	second := newSession()
+
+	closeSession := func(session *terminal.Session) {
+		t.Helper()
+		if err := session.Close(); err != nil {
+			t.Fatalf("close session: %v", err)
+		}
+		<-session.Closed()
+	}
+	closeSession(first)
+	time.Sleep(100 * time.Millisecond)
+	waitWatched(1)
+	closeSession(second)
+	waitWatched(0)
+}
+
+func TestMapWorktreeErrorMoveUnsupported(t *testing.T) {
+	err := mapWorktreeError(fmt.Errorf("move worktree failed: %w", git.ErrWorktreeMoveUnsupported))
+	var status huma.StatusError
+	if !errors.As(err, &status) || status.GetStatus() != http.StatusConflict {
+		t.Fatalf("expected 409 for unsupported worktree move, got %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"code-kanban/api/h"
 	"code-kanban/model"
 	"code-kanban/service"
+	"code-kanban/utils/git"
 )
 
 const branchTag = "branch-分支管理"
@@ -28,6 +29,19 @@ type mergeBranchBody struct {
 	CommitMessage string `json:"commitMessage" doc:"提交信息（仅 squash 合并生效）" default:""`
 }
 
+type previewMergeBody struct {
+	SourceBranch string `json:"sourceBranch" minLength:"1" doc:"源分支"`
+	Strategy     string `json:"strategy" enum:"merge,rebase,squash" doc:"合并策略" default:"merge"`
+}
+
+type setUpstreamBody struct {
+	RemoteBranch string `json:"remoteBranch" minLength:"1" doc:"上游分支，如 origin/feature-x，需已存在（必要时先 fetch）"`
+}
+
+type rebaseWorktreeBody struct {
+	Onto string `json:"onto,omitempty" doc:"目标基底分支，留空使用项目默认分支"`
+}
+
 func registerBranchRoutes(group *huma.Group) {
 	branchSvc := service.NewBranchService()
 
@@ -87,6 +101,73 @@ func registerBranchRoutes(group *huma.Group) {
 	}, func(op *huma.Operation) {
 		op.OperationID = "branch-delete"
 		op.Summary = "删除分支"
+		op.Description = "非 force 时若分支有未推送到上游的提交，返回 409 及提交列表（details 中 value 为提交 SHA）；无上游分支时跳过检测"
+		op.Tags = []string{branchTag}
+	})
+
+	huma.Get(group, "/projects/{projectId}/branches/merged", func(
+		ctx context.Context,
+		input *struct {
+			ProjectID string `path:"projectId"`
+			Into      string `query:"into" doc:"目标分支，默认为项目默认分支"`
+		},
+	) (*h.ItemsResponse[string], error) {
+		branches, err := branchSvc.ListMergedBranches(ctx, input.ProjectID, input.Into)
+		if err != nil {
+			return nil, mapBranchError(err)
+		}
+		resp := h.NewItemsResponse(branches)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "branch-list-merged"
+		op.Summary = "获取已合并的本地分支"
+		op.Tags = []string{branchTag}
+	})
+
+	huma.Get(group, "/projects/{projectId}/compare", func(
+		ctx context.Context,
+		input *struct {
+			ProjectID string `path:"projectId"`
+			Base      string `query:"base" doc:"基准分支，默认为项目默认分支"`
+			Head      string `query:"head" required:"true" doc:"要比较的分支，也可以是提交或 tag"`
+			Force     bool   `query:"force" default:"false" doc:"强制刷新，忽略缓存"`
+		},
+	) (*h.ItemResponse[git.BranchComparison], error) {
+		result, err := branchSvc.CompareBranches(ctx, input.ProjectID, input.Base, input.Head, input.Force)
+		if err != nil {
+			return nil, mapBranchError(err)
+		}
+		resp := h.NewItemResponse(*result)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "branch-compare"
+		op.Summary = "比较两个分支"
+		op.Description = "返回 head 相对 base 领先与落后的提交，以及 git diff base...head 的文件增删统计，即把 head 合并进 base 将引入的改动"
+		op.Tags = []string{branchTag}
+	})
+
+	huma.Post(group, "/projects/{projectId}/branches/merged/delete", func(
+		ctx context.Context,
+		input *struct {
+			ProjectID string `path:"projectId"`
+			Body      struct {
+				Into string `json:"into,omitempty" doc:"目标分支，默认为项目默认分支"`
+			}
+		},
+	) (*h.ItemsResponse[model.MergedBranchDeletion], error) {
+		results, err := branchSvc.DeleteMergedBranches(ctx, input.ProjectID, input.Body.Into)
+		if err != nil {
+			return nil, mapBranchError(err)
+		}
+		resp := h.NewItemsResponse(results)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "branch-delete-merged"
+		op.Summary = "批量删除已合并的分支"
+		op.Description = "跳过默认分支与当前分支，并删除分支对应的 worktree；worktree 有打开的终端会话、任务或未推送提交时跳过该分支，逐个返回删除结果"
 		op.Tags = []string{branchTag}
 	})
 
@@ -114,6 +195,166 @@ func registerBranchRoutes(group *huma.Group) {
 		op.Summary = "合并分支"
 		op.Tags = []string{branchTag}
 	})
+
+	huma.Post(group, "/worktrees/{id}/merge/preview", func(
+		ctx context.Context,
+		input *struct {
+			ID   string `path:"id"`
+			Body previewMergeBody
+		},
+	) (*h.ItemResponse[model.MergePreview], error) {
+		preview, err := branchSvc.PreviewMerge(ctx, input.ID, input.Body.SourceBranch, input.Body.Strategy)
+		if err != nil {
+			return nil, mapBranchError(err)
+		}
+		resp := h.NewItemResponse(*preview)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "branch-merge-preview"
+		op.Summary = "合并预览（dry-run）"
+		op.Description = "按指定策略试合并并返回会改动的文件与冲突：merge 与 squash 在工作区内执行后撤销，要求工作区干净；" +
+			"rebase 在临时 worktree 中重放提交，遇到冲突时返回第一个冲突提交的冲突文件"
+		op.Tags = []string{branchTag}
+	})
+
+	huma.Get(group, "/worktrees/{id}/upstream", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+		},
+	) (*h.ItemResponse[model.WorktreeUpstream], error) {
+		upstream, err := branchSvc.GetUpstream(ctx, input.ID)
+		if err != nil {
+			return nil, mapBranchError(err)
+		}
+		resp := h.NewItemResponse(*upstream)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "branch-upstream-get"
+		op.Summary = "获取 worktree 分支的上游"
+		op.Description = "返回当前分支的上游及 ahead/behind；hasUpstream 为 false 时 ahead/behind 恒为 0，前端可提示设置上游。HEAD 游离时返回 409"
+		op.Tags = []string{branchTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/upstream", func(
+		ctx context.Context,
+		input *struct {
+			ID   string `path:"id"`
+			Body setUpstreamBody
+		},
+	) (*h.ItemResponse[model.WorktreeUpstream], error) {
+		upstream, err := branchSvc.SetUpstream(ctx, input.ID, input.Body.RemoteBranch)
+		if err != nil {
+			return nil, mapBranchError(err)
+		}
+		resp := h.NewItemResponse(*upstream)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "branch-upstream-set"
+		op.Summary = "设置 worktree 分支的上游"
+		op.Description = "执行 git branch --set-upstream-to，上游分支不存在时返回 404；设置后刷新 worktree 状态，使 ahead/behind 生效"
+		op.Tags = []string{branchTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/rebase", func(
+		ctx context.Context,
+		input *struct {
+			ID   string `path:"id"`
+			Body rebaseWorktreeBody
+		},
+	) (*h.ItemResponse[model.MergeResult], error) {
+		result, err := branchSvc.RebaseWorktree(ctx, input.ID, input.Body.Onto)
+		if err != nil {
+			return nil, mapBranchError(err)
+		}
+		resp := h.NewItemResponse(*result)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "branch-rebase"
+		op.Summary = "Rebase 分支"
+		op.Description = "在 worktree 中执行 git rebase <onto>。出现冲突时 rebase 保持进行中并返回冲突文件，解决后调用 continue，或调用 abort 放弃"
+		op.Tags = []string{branchTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/rebase/continue", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+		},
+	) (*h.ItemResponse[model.MergeResult], error) {
+		result, err := branchSvc.ContinueRebase(ctx, input.ID)
+		if err != nil {
+			return nil, mapBranchError(err)
+		}
+		resp := h.NewItemResponse(*result)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "branch-rebase-continue"
+		op.Summary = "继续 Rebase"
+		op.Tags = []string{branchTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/rebase/skip", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+		},
+	) (*h.ItemResponse[model.MergeResult], error) {
+		result, err := branchSvc.SkipRebase(ctx, input.ID)
+		if err != nil {
+			return nil, mapBranchError(err)
+		}
+		resp := h.NewItemResponse(*result)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "branch-rebase-skip"
+		op.Summary = "跳过当前提交继续 Rebase"
+		op.Tags = []string{branchTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/continue", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+		},
+	) (*h.ItemResponse[model.MergeResult], error) {
+		result, err := branchSvc.ContinueOperation(ctx, input.ID)
+		if err != nil {
+			return nil, mapBranchError(err)
+		}
+		resp := h.NewItemResponse(*result)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "branch-operation-continue"
+		op.Summary = "继续进行中的 git 操作"
+		op.Description = "自动判断 worktree 中进行中的 rebase、merge、cherry-pick 或 revert 并继续，仍有未解决冲突时返回冲突文件"
+		op.Tags = []string{branchTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/rebase/abort", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+		},
+	) (*h.MessageResponse, error) {
+		if err := branchSvc.AbortRebase(ctx, input.ID); err != nil {
+			return nil, mapBranchError(err)
+		}
+		resp := h.NewMessageResponse("rebase aborted")
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "branch-rebase-abort"
+		op.Summary = "放弃 Rebase"
+		op.Tags = []string{branchTag}
+	})
 }
 
 func mapBranchError(err error) error {
@@ -123,16 +364,25 @@ func mapBranchError(err error) error {
 	case errors.Is(err, model.ErrDBNotInitialized):
 		return huma.Error503ServiceUnavailable("database is not initialized")
 	case errors.Is(err, model.ErrProjectNotFound),
-		errors.Is(err, model.ErrWorktreeNotFound):
+		errors.Is(err, model.ErrWorktreeNotFound),
+		errors.Is(err, git.ErrRefNotFound):
 		return huma.Error404NotFound(err.Error())
+	case errors.Is(err, model.ErrUnpushedCommits):
+		return unpushedCommitsConflict(err)
 	case errors.Is(err, model.ErrBranchHasWorktree),
-		errors.Is(err, model.ErrWorktreeDirty):
+		errors.Is(err, model.ErrWorktreeDirty),
+		errors.Is(err, model.ErrWorktreeDetached),
+		errors.Is(err, model.ErrWorktreeReadOnly):
 		return huma.Error409Conflict(err.Error())
-	case errors.Is(err, model.ErrProtectedBranch):
+	case errors.Is(err, model.ErrProtectedBranch),
+		errors.Is(err, git.ErrNoRebaseInProgress),
+		errors.Is(err, git.ErrNoOperationInProgress):
 		return huma.Error409Conflict(err.Error())
 	case errors.Is(err, model.ErrInvalidBranchName):
 		return huma.Error400BadRequest(err.Error())
-	default:
-		return huma.Error400BadRequest(err.Error())
 	}
+	if mapped := mapGitFailure(err); mapped != nil {
+		return mapped
+	}
+	return huma.Error400BadRequest(err.Error())
 }
@@ -8,6 +8,7 @@ import (
 	"html"
 	"html/template"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
 	"time"
@@ -51,12 +52,25 @@ h1{font-size:20px;margin:0 0 10px;}
 .color-preview{margin-top:8px;display:flex;gap:18px;align-items:center;flex-wrap:wrap;}
 .color-box{width:46px;height:18px;border:1px solid #555;display:inline-block;margin:0 6px;}
 .color-code{font-size:12px;letter-spacing:0.5px;opacity:0.8;}
+.nav{font-size:14px;margin:0 0 12px;display:flex;gap:16px;align-items:center;}
+.nav a{color:#4da3ff;text-decoration:none;}
+.nav .disabled{opacity:.4;}
+.detections{margin-top:18px;border-collapse:collapse;font-size:12px;width:100%;}
+.detections td,.detections th{border:1px solid #2c2f36;padding:4px 6px;text-align:left;vertical-align:top;}
+.detections pre{margin:0;white-space:pre-wrap;opacity:.8;}
 </style>
 </head>
 <body>
 <h1>/capture-debug</h1>
 <div class="source">{{.Source}}</div>
 {{if .Stats}}<div class="stats">{{.Stats}}</div>{{end}}
+{{if .Replay}}
+<div class="nav">
+    {{if .PrevURL}}<a href="{{.PrevURL}}">&larr; 上一帧</a>{{else}}<span class="disabled">&larr; 上一帧</span>{{end}}
+    <span>{{.FrameLabel}}</span>
+    {{if .NextURL}}<a href="{{.NextURL}}">下一帧 &rarr;</a>{{else}}<span class="disabled">下一帧 &rarr;</span>{{end}}
+</div>
+{{end}}
 {{if .Message}}<div class="message">{{.Message}}</div>{{end}}
 {{if .HasGrid}}
 <div class="grid">
@@ -87,7 +101,25 @@ h1{font-size:20px;margin:0 0 10px;}
     </div>
 </div>
 {{else}}
-<div class="empty">例如：/capture-debug?sessionId=xxx 或 /capture-debug?data=BASE64&rows=30&cols=120</div>
+<div class="empty">例如：/capture-debug?sessionId=xxx、/capture-debug?sessionId=xxx&upto=2024-01-02T15:04:05Z（按时间回放 scrollback）或 /capture-debug?data=BASE64&rows=30&cols=120</div>
+{{end}}
+{{if .Detections}}
+<h2>AI 状态检测诊断（最近 {{len .Detections}} 次判定）</h2>
+<table class="detections">
+<tr><th>时间</th><th>来源</th><th>状态</th><th>检测结果</th><th>命中规则</th><th>距上次确认</th></tr>
+{{range .Detections}}
+<tr>
+    <td>{{.Time.Format "15:04:05.000"}}</td>
+    <td>{{.Source}}</td>
+    <td>{{.PreviousState}} &rarr; {{.State}}{{if .Changed}} *{{end}}</td>
+    <td>{{.DetectedState}}{{if not .ActuallyDetected}}（未确认）{{end}}</td>
+    <td>{{.Match}}</td>
+    <td>{{.SinceLastDetectedMs}}ms</td>
+</tr>
+<tr><td colspan="6"><details><summary>可见行（{{len .Lines}}）光标 {{.CursorX}},{{.CursorY}}</summary><pre>{{range .Lines}}{{.}}
+{{end}}</pre></details></td></tr>
+{{end}}
+</table>
 {{end}}
 <script>
 (function(){
@@ -150,6 +182,15 @@ type captureDebugPage struct {
 	Stats   string
 	HasGrid bool
 	Grid    [][]captureDebugCell
+
+	// Detections 为开启 AI 检测诊断后 session 记录的判定过程
+	Detections []ai_assistant2.DetectionRecord
+
+	// Replay 模式下用于逐帧浏览 scrollback
+	Replay     bool
+	FrameLabel string
+	PrevURL    string
+	NextURL    string
 }
 
 func registerCaptureDebugRoute(app *fiber.App, manager *terminal.Manager, logger *zap.Logger) {
@@ -179,6 +220,8 @@ func registerCaptureDebugRoute(app *fiber.App, manager *terminal.Manager, logger
 		sessionID := strings.TrimSpace(c.Query("sessionId"))
 		timeout := parseCaptureTimeout(c.Query("timeout"))
 		trimView := !parseBool(c.Query("full"), false)
+		rawUpto := strings.TrimSpace(c.Query("upto"))
+		rawFrame := strings.TrimSpace(c.Query("frame"))
 
 		var chunkBytes []byte
 		var chunkSource string
@@ -199,6 +242,7 @@ func registerCaptureDebugRoute(app *fiber.App, manager *terminal.Manager, logger
 				page.Message = fmt.Sprintf("无法找到 session %s：%v", sessionID, err)
 				return renderCaptureDebugPage(c, page)
 			}
+			page.Detections = session.AIDiagnostics()
 			snap := session.Snapshot()
 			if !rowsProvided && snap.Rows > 0 {
 				page.Rows = clampInt(snap.Rows, 1, captureMaxRows)
@@ -207,6 +251,36 @@ func registerCaptureDebugRoute(app *fiber.App, manager *terminal.Manager, logger
 				page.Cols = clampInt(snap.Cols, 1, captureMaxCols)
 			}
 
+			if rawUpto != "" || rawFrame != "" {
+				var frame *terminal.ScrollbackFrame
+				if rawFrame != "" {
+					index, err := strconv.Atoi(rawFrame)
+					if err != nil {
+						return fiber.NewError(http.StatusBadRequest, "frame 需要为整数")
+					}
+					frame = session.ScrollbackFrameAt(index)
+				} else {
+					upto, err := parseCaptureUpto(rawUpto)
+					if err != nil {
+						return fiber.NewError(http.StatusBadRequest, "upto 需要为 RFC3339 时间或毫秒时间戳")
+					}
+					frame = session.ScrollbackFrameUntil(upto)
+				}
+
+				page.Replay = true
+				page.FrameLabel = describeScrollbackFrame(frame)
+				if frame.Index > 1 {
+					page.PrevURL = buildCaptureFrameURL(c, sessionID, frame.Index-1)
+				}
+				if frame.Index < frame.Total {
+					page.NextURL = buildCaptureFrameURL(c, sessionID, frame.Index+1)
+				}
+				page.Source = fmt.Sprintf("session %s scrollback 回放：%s", sessionID, page.FrameLabel)
+				chunkBytes = frame.Data
+				chunkSource = page.Source
+				break
+			}
+
 			chunk, err := manager.CaptureChunk(context.Background(), sessionID, timeout)
 			if err != nil {
 				if logger != nil {
@@ -272,6 +346,35 @@ func renderCaptureDebugPage(c *fiber.Ctx, page captureDebugPage) error {
 	return c.Send(buf.Bytes())
 }
 
+// parseCaptureUpto 解析 upto 参数，支持 RFC3339(Nano) 时间与毫秒级 Unix 时间戳
+func parseCaptureUpto(raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if millis, err := strconv.ParseInt(raw, 10, 64); err == nil {
+		return time.UnixMilli(millis), nil
+	}
+	return time.Parse(time.RFC3339Nano, raw)
+}
+
+func describeScrollbackFrame(frame *terminal.ScrollbackFrame) string {
+	if frame.Index == 0 {
+		return fmt.Sprintf("第 0/%d 帧（所选时间点之前没有分片）", frame.Total)
+	}
+	return fmt.Sprintf("第 %d/%d 帧 @ %s（%d 字节）", frame.Index, frame.Total, frame.Timestamp.Format(time.RFC3339Nano), len(frame.Data))
+}
+
+// buildCaptureFrameURL 生成指向指定帧的链接，保留 rows/cols/full 参数
+func buildCaptureFrameURL(c *fiber.Ctx, sessionID string, index int) string {
+	values := url.Values{}
+	values.Set("sessionId", sessionID)
+	values.Set("frame", strconv.Itoa(index))
+	for _, key := range []string{"rows", "cols", "full"} {
+		if value := strings.TrimSpace(c.Query(key)); value != "" {
+			values.Set(key, value)
+		}
+	}
+	return "/capture-debug?" + values.Encode()
+}
+
 func parseBoundedInt(raw string, fallback, min, max int) (int, bool, error) {
 	raw = strings.TrimSpace(raw)
 	if raw == "" {
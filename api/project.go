@@ -4,11 +4,17 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/danielgtaylor/huma/v2"
 
 	"code-kanban/api/h"
 	"code-kanban/model"
+	"code-kanban/model/tables"
+	"code-kanban/service"
+	"code-kanban/utils/git"
 )
 
 const projectTag = "project-项目管理"
@@ -23,6 +29,47 @@ type createProjectInput struct {
 	}
 }
 
+type cloneProjectInput struct {
+	Body cloneProjectBody
+}
+
+type cloneProjectBody struct {
+	URL               string `json:"url" minLength:"1" doc:"远程仓库地址"`
+	Path              string `json:"path" minLength:"1" doc:"clone 目标目录，必须是尚不存在的绝对路径"`
+	Name              string `json:"name,omitempty" maxLength:"100" doc:"项目名称，为空时取仓库名"`
+	Description       string `json:"description,omitempty" doc:"项目描述"`
+	Depth             int    `json:"depth,omitempty" minimum:"0" doc:"浅克隆深度，0 表示完整克隆"`
+	Branch            string `json:"branch,omitempty" doc:"检出的分支或标签，为空时使用远程默认分支"`
+	RecurseSubmodules bool   `json:"recurseSubmodules,omitempty" doc:"同时克隆子模块"`
+}
+
+func (b cloneProjectBody) params() model.CloneProjectParams {
+	return model.CloneProjectParams{
+		URL:               b.URL,
+		Name:              b.Name,
+		Path:              b.Path,
+		Description:       b.Description,
+		Depth:             b.Depth,
+		Branch:            b.Branch,
+		RecurseSubmodules: b.RecurseSubmodules,
+	}
+}
+
+// mapCloneProjectError 映射 clone 建项目的错误，认证、网络等已分类的 git 失败按 mapGitFailure 返回，
+// 其余 git clone 失败带 stderr 作为 400 返回
+func mapCloneProjectError(err error) error {
+	switch {
+	case errors.Is(err, model.ErrDBNotInitialized):
+		return huma.Error503ServiceUnavailable("database is not initialized")
+	case errors.Is(err, model.ErrProjectAlreadyExists):
+		return huma.Error409Conflict("project already exists")
+	}
+	if mapped := mapGitFailure(err); mapped != nil {
+		return mapped
+	}
+	return huma.Error400BadRequest(err.Error())
+}
+
 type updateProjectInput struct {
 	ID   string `path:"id"`
 	Body struct {
@@ -32,6 +79,13 @@ type updateProjectInput struct {
 	}
 }
 
+type updateProtectedBranchesInput struct {
+	ID   string `path:"id"`
+	Body struct {
+		Patterns []string `json:"patterns" doc:"受保护的分支名或通配模式（如 release/*），* 不跨越 /；空列表表示清除规则"`
+	}
+}
+
 type updateProjectPriorityInput struct {
 	ID   string `path:"id"`
 	Body struct {
@@ -85,7 +139,25 @@ func registerProjectRoutes(group *huma.Group) {
 		op.Tags = []string{projectTag}
 	})
 
-	huma.Get(group, "/projects", func(ctx context.Context, _ *struct{}) (*h.ItemsResponse[*model.Project], error) {
+	huma.Post(group, "/projects/clone", func(ctx context.Context, input *cloneProjectInput) (*h.ItemResponse[model.Project], error) {
+		project, err := service.CreateFromClone(ctx, input.Body.params())
+		if err != nil {
+			return nil, mapCloneProjectError(err)
+		}
+
+		resp := h.NewItemResponse(*project)
+		resp.Status = http.StatusCreated
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-clone"
+		op.Summary = "clone 远程仓库并创建项目"
+		op.Description = "需要进度时使用 SSE 接口 POST /api/v1/projects/clone/stream（请求体相同）；clone 或建项目失败时清理已 clone 的目录"
+		op.Tags = []string{projectTag}
+	})
+
+	huma.Get(group, "/projects", func(ctx context.Context, input *struct {
+		IncludeArchived bool `query:"includeArchived" doc:"是否包含已归档的项目"`
+	}) (*h.ItemsResponse[*model.Project], error) {
 		projects, err := service.ListProjects(ctx)
 		if err != nil {
 			if errors.Is(err, model.ErrDBNotInitialized) {
@@ -93,6 +165,11 @@ func registerProjectRoutes(group *huma.Group) {
 			}
 			return nil, huma.Error500InternalServerError("failed to load projects", err)
 		}
+		if !input.IncludeArchived {
+			projects = slices.DeleteFunc(projects, func(project *model.Project) bool {
+				return project.Archived
+			})
+		}
 
 		resp := h.NewItemsResponse(projects)
 		resp.Status = http.StatusOK
@@ -100,6 +177,7 @@ func registerProjectRoutes(group *huma.Group) {
 	}, func(op *huma.Operation) {
 		op.OperationID = "project-list"
 		op.Summary = "项目列表"
+		op.Description = "默认不返回已归档的项目，includeArchived=1 时一并返回"
 		op.Tags = []string{projectTag}
 	})
 
@@ -176,6 +254,124 @@ func registerProjectRoutes(group *huma.Group) {
 		op.Tags = []string{projectTag}
 	})
 
+	huma.Get(group, "/projects/{id}/protected-branches", func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*h.ItemsResponse[string], error) {
+		project, err := service.GetProject(ctx, input.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrDBNotInitialized):
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			case errors.Is(err, model.ErrProjectNotFound):
+				return nil, huma.Error404NotFound("project not found")
+			default:
+				return nil, huma.Error500InternalServerError("failed to load project", err)
+			}
+		}
+
+		patterns := model.ProtectedBranchPatterns(project)
+		if patterns == nil {
+			patterns = []string{}
+		}
+		resp := h.NewItemsResponse(patterns)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-protected-branches-get"
+		op.Summary = "获取分支保护规则"
+		op.Description = "返回项目配置的受保护分支模式；默认分支与当前分支始终禁止删除，不在列表中"
+		op.Tags = []string{projectTag}
+	})
+
+	huma.Post(group, "/projects/{id}/protected-branches", func(ctx context.Context, input *updateProtectedBranchesInput) (*h.ItemResponse[model.Project], error) {
+		project, err := service.UpdateProtectedBranches(ctx, input.ID, input.Body.Patterns)
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrDBNotInitialized):
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			case errors.Is(err, model.ErrInvalidBranchPattern):
+				return nil, huma.Error400BadRequest(err.Error())
+			case errors.Is(err, model.ErrProjectNotFound):
+				return nil, huma.Error404NotFound("project not found")
+			default:
+				return nil, huma.Error500InternalServerError("failed to update protected branches", err)
+			}
+		}
+
+		resp := h.NewItemResponse(*project)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-protected-branches-update"
+		op.Summary = "更新分支保护规则"
+		op.Description = "命中规则的分支禁止删除、强制推送与直接合并，操作被拒绝时返回 409 并说明命中的规则"
+		op.Tags = []string{projectTag}
+	})
+
+	for _, action := range []struct {
+		path     string
+		archived bool
+		summary  string
+	}{
+		{path: "archive", archived: true, summary: "归档项目"},
+		{path: "unarchive", archived: false, summary: "取消归档项目"},
+	} {
+		huma.Post(group, "/projects/{id}/"+action.path, func(ctx context.Context, input *struct {
+			ID string `path:"id"`
+		}) (*h.ItemResponse[model.Project], error) {
+			project, err := service.SetArchived(ctx, input.ID, action.archived)
+			if err != nil {
+				switch {
+				case errors.Is(err, model.ErrDBNotInitialized):
+					return nil, huma.Error503ServiceUnavailable("database is not initialized")
+				case errors.Is(err, model.ErrProjectNotFound):
+					return nil, huma.Error404NotFound("project not found")
+				default:
+					return nil, huma.Error500InternalServerError("failed to update project archive state", err)
+				}
+			}
+
+			resp := h.NewItemResponse(*project)
+			resp.Status = http.StatusOK
+			return resp, nil
+		}, func(op *huma.Operation) {
+			op.OperationID = "project-" + action.path
+			op.Summary = action.summary
+			op.Description = "归档只是状态标记，不影响已有的 worktree、任务与终端；归档后项目列表默认隐藏，且拒绝新建终端与 worktree"
+			op.Tags = []string{projectTag}
+		})
+	}
+
+	huma.Post(group, "/projects/{id}/credential", func(ctx context.Context, input *struct {
+		ID   string `path:"id"`
+		Body struct {
+			Token string `json:"token" doc:"访问令牌，user:password 形式按 Basic 认证发送，其余按 Bearer 发送；为空清除"`
+		} `json:"body"`
+	}) (*h.MessageResponse, error) {
+		if err := service.SetCredential(ctx, input.ID, input.Body.Token); err != nil {
+			if errors.Is(err, model.ErrDBNotInitialized) {
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			}
+			if errors.Is(err, model.ErrProjectNotFound) {
+				return nil, huma.Error404NotFound("project not found")
+			}
+			return nil, huma.Error500InternalServerError("failed to set project credential", err)
+		}
+
+		message := "Project credential set"
+		if strings.TrimSpace(input.Body.Token) == "" {
+			message = "Project credential cleared"
+		}
+		resp := h.NewMessageResponse(message)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-credential-set"
+		op.Summary = "设置项目的临时 git 凭据"
+		op.Description = "令牌只保存在内存中，1 小时后过期；fetch/pull 时通过 http.extraHeader 以环境变量注入，不写入仓库配置、remote URL 与日志"
+		op.Tags = []string{projectTag}
+	})
+
 	huma.Post(group, "/projects/{id}/delete", func(ctx context.Context, input *struct {
 		ID string `path:"id"`
 	}) (*h.MessageResponse, error) {
@@ -197,4 +393,223 @@ func registerProjectRoutes(group *huma.Group) {
 		op.Summary = "删除项目"
 		op.Tags = []string{projectTag}
 	})
+	activityService := &model.ActivityService{}
+	huma.Get(group, "/projects/{id}/activity", func(ctx context.Context, input *struct {
+		ID     string `path:"id"`
+		Limit  int    `query:"limit" default:"50" minimum:"1" maximum:"200" doc:"返回条数"`
+		Before string `query:"before" doc:"只返回早于该时间（RFC3339）的活动，传入上一页最后一条的 time 翻页"`
+	}) (*h.ItemsResponse[tables.ActivityLogTable], error) {
+		var before *time.Time
+		if input.Before != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, input.Before)
+			if err != nil {
+				return nil, huma.Error400BadRequest("before must be an RFC3339 timestamp")
+			}
+			before = &parsed
+		}
+
+		if _, err := service.GetProject(ctx, input.ID); err != nil {
+			if errors.Is(err, model.ErrDBNotInitialized) {
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			}
+			if errors.Is(err, model.ErrProjectNotFound) {
+				return nil, huma.Error404NotFound("project not found")
+			}
+			return nil, huma.Error500InternalServerError("failed to load project", err)
+		}
+
+		activities, err := activityService.ListActivities(ctx, &model.ListActivitiesRequest{
+			ProjectID: input.ID,
+			Limit:     input.Limit,
+			Before:    before,
+		})
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to load activities", err)
+		}
+
+		resp := h.NewItemsResponse(activities)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-activity-list"
+		op.Summary = "项目活动流"
+		op.Description = "按时间倒序返回分支、worktree、提交与 AI 完成等活动"
+		op.Tags = []string{projectTag}
+	})
+}
+
+type importProjectInput struct {
+	Body struct {
+		Data model.ProjectExport `json:"data" doc:"GET /projects/{id}/export 导出的数据"`
+		Path string              `json:"path,omitempty" doc:"本机上的项目目录，为空时使用导出数据中的路径"`
+	}
+}
+
+func registerProjectTransferRoutes(group *huma.Group) {
+	transferSvc := service.NewProjectTransferService()
+
+	huma.Get(group, "/projects/{id}/export", func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*h.ItemResponse[model.ProjectExport], error) {
+		export, err := transferSvc.ExportProject(ctx, input.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrDBNotInitialized):
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			case errors.Is(err, model.ErrProjectNotFound):
+				return nil, huma.Error404NotFound("project not found")
+			default:
+				return nil, huma.Error500InternalServerError("failed to export project", err)
+			}
+		}
+
+		resp := h.NewItemResponse(*export)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-export"
+		op.Summary = "导出项目配置与便签"
+		op.Description = "打包项目设置、项目便签与 worktree 列表为 JSON，不包含 git 内容"
+		op.Tags = []string{projectTag}
+	})
+
+	huma.Post(group, "/projects/import", func(ctx context.Context, input *importProjectInput) (*h.ItemResponse[model.ProjectImportReport], error) {
+		report, err := transferSvc.ImportProject(ctx, &input.Body.Data, input.Body.Path)
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrDBNotInitialized):
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			case errors.Is(err, model.ErrInvalidProjectExport),
+				errors.Is(err, model.ErrInvalidProjectInput),
+				errors.Is(err, model.ErrInvalidProjectPath):
+				return nil, huma.Error400BadRequest(err.Error())
+			case errors.Is(err, model.ErrProjectAlreadyExists):
+				return nil, huma.Error409Conflict("project already exists")
+			default:
+				return nil, huma.Error500InternalServerError("failed to import project", err)
+			}
+		}
+
+		resp := h.NewItemResponse(*report)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-import"
+		op.Summary = "导入项目配置与便签"
+		op.Description = "从导出数据重建项目，所有记录重新生成 ID；路径已被其他项目使用时导入到该项目并跳过相同的便签。" +
+			"items 逐项说明导入结果，status 为 missing 的 worktree 目录在本机不存在，重新创建后同步即可恢复"
+		op.Tags = []string{projectTag}
+	})
+}
+
+func registerProjectHealthRoutes(group *huma.Group) {
+	healthSvc := service.NewProjectHealthService()
+
+	huma.Get(group, "/projects/{id}/health", func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*h.ItemResponse[model.ProjectHealth], error) {
+		health, err := healthSvc.Health(ctx, input.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrDBNotInitialized):
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			case errors.Is(err, model.ErrProjectNotFound):
+				return nil, huma.Error404NotFound("project not found")
+			default:
+				return nil, huma.Error500InternalServerError("failed to analyze repository", err)
+			}
+		}
+
+		resp := h.NewItemResponse(*health)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-health"
+		op.Summary = "分析仓库对象存储"
+		op.Description = "基于 git count-objects 统计松散对象、pack 与垃圾文件，suggestGc 为 true 时 reasons 说明原因；gc 字段为最近一次后台 gc 的状态"
+		op.Tags = []string{projectTag}
+	})
+
+	huma.Post(group, "/projects/{id}/gc", func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*h.ItemResponse[model.ProjectGCStatus], error) {
+		status, err := healthSvc.StartGC(ctx, input.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrDBNotInitialized):
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			case errors.Is(err, model.ErrProjectNotFound):
+				return nil, huma.Error404NotFound("project not found")
+			case errors.Is(err, model.ErrGCAlreadyRunning):
+				return nil, huma.Error409Conflict(err.Error())
+			default:
+				return nil, huma.Error500InternalServerError("failed to start git gc", err)
+			}
+		}
+
+		resp := h.NewItemResponse(*status)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-gc"
+		op.Summary = "后台执行 git gc"
+		op.Description = "立即返回，gc 在后台执行，超时 30 分钟；通过 GET /projects/{id}/health 的 gc 字段查看进度，同一项目同时只能执行一次"
+		op.Tags = []string{projectTag}
+	})
+}
+
+func registerProjectGitConfigRoutes(group *huma.Group) {
+	configSvc := service.NewProjectGitConfigService()
+
+	gitConfigError := func(err error, action string) error {
+		switch {
+		case errors.Is(err, model.ErrDBNotInitialized):
+			return huma.Error503ServiceUnavailable("database is not initialized")
+		case errors.Is(err, model.ErrProjectNotFound):
+			return huma.Error404NotFound("project not found")
+		case errors.Is(err, git.ErrInvalidConfigKey), errors.Is(err, git.ErrInvalidConfigValue), errors.Is(err, git.ErrConfigKeyNotAllowed):
+			return huma.Error400BadRequest(err.Error())
+		default:
+			return huma.Error500InternalServerError(action, err)
+		}
+	}
+
+	huma.Get(group, "/projects/{id}/git-config", func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*h.ItemResponse[model.ProjectGitConfig], error) {
+		config, err := configSvc.Get(ctx, input.ID)
+		if err != nil {
+			return nil, gitConfigError(err, "failed to read git config")
+		}
+
+		resp := h.NewItemResponse(*config)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-git-config-get"
+		op.Summary = "读取仓库本地 git 配置"
+		op.Description = "只包含 .git/config 中的配置，键名为小写，远程地址中的凭据与 *.extraheader 的值已隐去；commonKeys 列出可写入的配置项及可选值"
+		op.Tags = []string{projectTag}
+	})
+
+	huma.Put(group, "/projects/{id}/git-config", func(ctx context.Context, input *struct {
+		ID   string `path:"id"`
+		Body struct {
+			Values map[string]string `json:"values" minProperties:"1" doc:"要写入的配置，键须为 commonKeys 中的配置项"`
+		}
+	}) (*h.ItemResponse[model.ProjectGitConfig], error) {
+		config, err := configSvc.Set(ctx, input.ID, input.Body.Values)
+		if err != nil {
+			return nil, gitConfigError(err, "failed to write git config")
+		}
+
+		resp := h.NewItemResponse(*config)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-git-config-update"
+		op.Summary = "写入仓库本地 git 配置"
+		op.Description = "只写入 local 作用域，只允许 commonKeys 中的配置项，其值须在给出的可选值内；core.fsmonitor、alias.* 等会执行命令的配置一律拒绝。返回写入后的配置"
+		op.Tags = []string{projectTag}
+	})
 }
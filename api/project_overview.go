@@ -0,0 +1,141 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"code-kanban/api/h"
+	"code-kanban/model"
+	"code-kanban/service"
+	"code-kanban/service/terminal"
+)
+
+// projectOverviewTimeout 收集概览的总超时，超时的部分留空并在 errors 中说明
+const projectOverviewTimeout = 5 * time.Second
+
+type projectOverview struct {
+	Project model.Project `json:"project"`
+	// Worktrees 数据库中记录的 worktree 及其最近一次刷新的状态，不触发同步
+	Worktrees []*model.Worktree       `json:"worktrees"`
+	Branches  projectOverviewBranches `json:"branches"`
+	// Sessions 未关闭的终端会话，aiState 为 AI 助手状态
+	Sessions      []terminalSessionView        `json:"sessions"`
+	Notifications terminal.ProjectRecordCounts `json:"notifications"`
+	// Errors 收集失败或超时的部分（worktrees、branches）及原因，其余部分仍然有效
+	Errors map[string]string `json:"errors,omitempty"`
+}
+
+type projectOverviewBranches struct {
+	Local  int `json:"local"`
+	Remote int `json:"remote"`
+}
+
+// overviewBranchSource 提供概览中的分支列表，测试中替换为可控的实现
+type overviewBranchSource interface {
+	ListBranches(ctx context.Context, projectID string, forceRefresh bool) (*model.BranchListResult, error)
+}
+
+// overviewResult 是并发收集的一部分概览数据
+type overviewResult[T any] struct {
+	value T
+	err   error
+}
+
+func collectOverview[T any](ctx context.Context, fn func(context.Context) (T, error)) <-chan overviewResult[T] {
+	ch := make(chan overviewResult[T], 1)
+	go func() {
+		value, err := fn(ctx)
+		ch <- overviewResult[T]{value: value, err: err}
+	}()
+	return ch
+}
+
+func awaitOverview[T any](ctx context.Context, ch <-chan overviewResult[T]) (T, error) {
+	select {
+	case result := <-ch:
+		return result.value, result.err
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+func (c *terminalController) registerProjectOverview(group *huma.Group) {
+	branchSvc := service.NewBranchService()
+
+	huma.Get(group, "/projects/{id}/overview", func(ctx context.Context, input *struct {
+		ID string `path:"id"`
+	}) (*h.ItemResponse[projectOverview], error) {
+		project, err := c.projectSvc.GetProject(ctx, input.ID)
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrDBNotInitialized):
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			case errors.Is(err, model.ErrProjectNotFound):
+				return nil, huma.Error404NotFound("project not found")
+			default:
+				return nil, huma.Error500InternalServerError("failed to load project", err)
+			}
+		}
+
+		resp := h.NewItemResponse(c.handleProjectOverview(ctx, project, branchSvc, projectOverviewTimeout))
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-overview"
+		op.Summary = "获取项目概览"
+		op.Description = "一次返回看板首屏所需的数据：worktree 列表（含状态）、本地与远程分支数、未关闭的终端会话（含 AI 状态）以及未处理的通知数。" +
+			"worktree 与分支并发收集，总超时 5 秒；失败或超时的部分留空并在 errors 中说明，不影响其余部分"
+		op.Tags = []string{projectTag}
+	})
+}
+
+// handleProjectOverview collects the overview of project. Worktrees and
+// branches are loaded concurrently; a section that fails or does not finish
+// within timeout is left empty and reported in Errors.
+func (c *terminalController) handleProjectOverview(ctx context.Context, project *model.Project, branches overviewBranchSource, timeout time.Duration) projectOverview {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	worktreesCh := collectOverview(ctx, func(ctx context.Context) ([]*model.Worktree, error) {
+		return c.worktreeSvc.ListWorktrees(ctx, project.Id)
+	})
+	branchesCh := collectOverview(ctx, func(ctx context.Context) (*model.BranchListResult, error) {
+		return branches.ListBranches(ctx, project.Id, false)
+	})
+
+	overview := projectOverview{
+		Project:       *project,
+		Worktrees:     make([]*model.Worktree, 0),
+		Sessions:      make([]terminalSessionView, 0),
+		Notifications: c.manager.GetRecordManager().ProjectCounts(project.Id),
+	}
+	for _, snapshot := range c.manager.ListSessions(project.Id) {
+		if snapshot.Status != terminal.SessionStatusClosed && snapshot.Status != terminal.SessionStatusError {
+			overview.Sessions = append(overview.Sessions, c.viewFromSnapshot(snapshot))
+		}
+	}
+	addError := func(section string, err error) {
+		if overview.Errors == nil {
+			overview.Errors = make(map[string]string)
+		}
+		overview.Errors[section] = err.Error()
+	}
+
+	if worktrees, err := awaitOverview(ctx, worktreesCh); err != nil {
+		addError("worktrees", err)
+	} else if worktrees != nil {
+		overview.Worktrees = worktrees
+	}
+	if list, err := awaitOverview(ctx, branchesCh); err != nil {
+		addError("branches", err)
+	} else {
+		overview.Branches.Local = len(list.Local)
+		overview.Branches.Remote = len(list.Remote)
+	}
+
+	return overview
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/service"
+	"code-kanban/service/terminal"
+)
+
+// blockingBranchSource 不响应 ctx，直到测试结束才返回
+type blockingBranchSource struct {
+	release chan struct{}
+}
+
+func (s *blockingBranchSource) ListBranches(ctx context.Context, projectID string, forceRefresh bool) (*model.BranchListResult, error) {
+	<-s.release
+	return &model.BranchListResult{}, nil
+}
+
+func TestProjectOverviewBranchTimeout(t *testing.T) {
+	project, worktreeIDs := newTerminalTestProject(t, 1)
+	c := &terminalController{
+		manager:     terminal.NewManager(terminal.Config{}, zap.NewNop()),
+		worktreeSvc: service.NewWorktreeService(),
+		projectSvc:  model.NewProjectService(),
+		logger:      zap.NewNop(),
+	}
+	source := &blockingBranchSource{release: make(chan struct{})}
+	t.Cleanup(func() { close(source.release) })
+
+	start := time.Now()
+	overview := c.handleProjectOverview(context.Background(), project, source, 200*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Fatalf("expected the overview to return at the timeout, took %s", elapsed)
+	}
+
+	// 分支超时只影响 branches，其余部分照常返回
+	if msg := overview.Errors["branches"]; !strings.Contains(msg, context.DeadlineExceeded.Error()) {
+		t.Fatalf("expected a branches timeout error, got %q", overview.Errors)
+	}
+	if _, ok := overview.Errors["worktrees"]; ok || len(overview.Errors) != 1 {
+		t.Fatalf("expected only branches to fail, got %v", overview.Errors)
+	}
+	if overview.Branches != (projectOverviewBranches{}) {
+		t.Fatalf("expected empty branch counts, got %+v", overview.Branches)
+	}
+	if overview.Project.Id != project.Id {
+		t.Fatalf("expected project %s, got %s", project.Id, overview.Project.Id)
+	}
+	ids := make([]string, 0, len(overview.Worktrees))
+	for _, worktree := range overview.Worktrees {
+		ids = append(ids, worktree.Id)
+	}
+	if !slices.Contains(ids, worktreeIDs[0]) {
+		t.Fatalf("expected worktree %s in %v", worktreeIDs[0], ids)
+	}
+	if overview.Sessions == nil {
+		t.Fatalf("expected an empty session list, not nil")
+	}
+}
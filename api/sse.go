@@ -0,0 +1,28 @@
+package api
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// setSSEHeaders prepares a raw fiber response for server-sent events.
+func setSSEHeaders(c *fiber.Ctx) {
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+}
+
+// writeSSEEvent writes one named event with a JSON payload and flushes it. The
+// flush error signals that the client has disconnected.
+func writeSSEEvent(w *bufio.Writer, event string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return w.Flush()
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"code-kanban/api/h"
+	"code-kanban/model"
+	"code-kanban/model/tables"
+)
+
+const statsTag = "stats-统计"
+
+type aiUsageStatsInput struct {
+	From      string `query:"from" doc:"统计起始时间（RFC3339），默认为今天零点"`
+	To        string `query:"to" doc:"统计结束时间（RFC3339，不含），默认为当前时间"`
+	GroupBy   string `query:"groupBy" enum:"assistant,project,day" default:"assistant" doc:"分组方式：assistant 按助手类型、project 按项目、day 按天（服务器本地时区）"`
+	ProjectID string `query:"projectId" doc:"只统计指定项目"`
+}
+
+func registerStatsRoutes(group *huma.Group) {
+	usageService := &model.AIUsageService{}
+	correctionService := &model.AIStateCorrectionService{}
+
+	huma.Get(group, "/stats/ai-usage", func(ctx context.Context, input *aiUsageStatsInput) (*h.ItemsResponse[model.AIUsageGroup], error) {
+		now := time.Now()
+		from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		to := now
+		if input.From != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, input.From)
+			if err != nil {
+				return nil, huma.Error400BadRequest("from must be an RFC3339 timestamp")
+			}
+			from = parsed
+		}
+		if input.To != "" {
+			parsed, err := time.Parse(time.RFC3339Nano, input.To)
+			if err != nil {
+				return nil, huma.Error400BadRequest("to must be an RFC3339 timestamp")
+			}
+			to = parsed
+		}
+
+		groups, err := usageService.AIUsageStats(ctx, &model.AIUsageStatsRequest{
+			From:      from,
+			To:        to,
+			GroupBy:   input.GroupBy,
+			ProjectID: input.ProjectID,
+			Location:  time.Local,
+		})
+		if err != nil {
+			switch {
+			case errors.Is(err, model.ErrAIUsageRange), errors.Is(err, model.ErrAIUsageGroupBy):
+				return nil, huma.Error400BadRequest(err.Error())
+			case errors.Is(err, model.ErrDBNotInitialized):
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			default:
+				return nil, huma.Error500InternalServerError("failed to load ai usage", err)
+			}
+		}
+
+		resp := h.NewItemsResponse(groups)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "stats-ai-usage"
+		op.Summary = "AI 使用统计"
+		op.Description = "按助手、项目或天聚合时间窗口内的会话数、完成数、各状态累计时长以及 token 与估算成本（助手提供时）"
+		op.Tags = []string{statsTag}
+	})
+	huma.Get(group, "/stats/ai-state-corrections", func(
+		ctx context.Context,
+		input *struct {
+			Assistant string `query:"assistant" doc:"只列出指定助手类型的纠正记录"`
+			Limit     int    `query:"limit" minimum:"0" maximum:"1000" doc:"返回的最大条数，默认 100"`
+		},
+	) (*h.ItemsResponse[tables.AIStateCorrectionTable], error) {
+		entries, err := correctionService.ListAIStateCorrections(ctx, input.Assistant, input.Limit)
+		if err != nil {
+			if errors.Is(err, model.ErrDBNotInitialized) {
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			}
+			return nil, huma.Error500InternalServerError("failed to load ai state corrections", err)
+		}
+		resp := h.NewItemsResponse(entries)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "stats-ai-state-corrections"
+		op.Summary = "AI 状态纠正记录"
+		op.Description = "按时间倒序列出用户对助手状态检测的纠正，包含当时的屏幕文本与原始输出，可导出为检测器的测试用例"
+		op.Tags = []string{statsTag}
+	})
+}
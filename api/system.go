@@ -4,11 +4,14 @@ import (
 	"context"
 	"errors"
 	"net/http"
+	"strings"
 
 	"github.com/danielgtaylor/huma/v2"
 
 	"code-kanban/api/h"
+	"code-kanban/service/terminal"
 	"code-kanban/utils"
+	"code-kanban/utils/ai_assistant2"
 	"code-kanban/utils/system"
 )
 
@@ -19,6 +22,16 @@ type systemTerminalManager interface {
 	UpdateScrollbackEnabled(bool)
 	UpdateRenameTitleEachCommand(bool)
 	UpdateAutoCreateTaskOnStartWork(bool)
+	UpdateDetectLinks(bool)
+	UpdateConfirmMultilineInput(bool)
+	UpdateAIDetectionDiagnostics(bool)
+	UpdateCaptureRawOutput(bool)
+	UpdateOutputTriggers([]terminal.OutputTrigger) error
+	ListShells() []utils.ShellOption
+	AIProfiles() []terminal.AIProfile
+	EffectiveConfig() terminal.EffectiveConfig
+	Health() *terminal.TerminalHealth
+	ScrollbackUsage() *terminal.ScrollbackUsage
 }
 
 type versionResponse struct {
@@ -39,6 +52,24 @@ type checkUpdateResponse struct {
 	} `json:"body"`
 }
 
+type systemConfigResponse struct {
+	Body struct {
+		ConfigPath string                    `json:"configPath" doc:"实际加载的配置文件路径"`
+		Config     utils.AppConfig           `json:"config" doc:"当前配置，密钥、token、数据库密码与 webhook 请求头已脱敏"`
+		Terminal   *terminal.EffectiveConfig `json:"terminal,omitempty" doc:"终端实际生效的配置（默认值填充后）及各会话级覆盖"`
+	} `json:"body"`
+}
+
+// aiProfileView 对外展示的 AI 档案，环境变量只给出变量名
+type aiProfileView struct {
+	terminal.AIProfile
+	EnvKeys []string `json:"envKeys,omitempty"`
+}
+
+type checkToolInput struct {
+	Name string `query:"name" doc:"工具命令名，如 claude、codex" required:"true"`
+}
+
 type openPathInput struct {
 	Body struct {
 		Path string `json:"path" doc:"目标路径" required:"true"`
@@ -66,6 +97,50 @@ func registerSystemRoutes(group *huma.Group, cfg *utils.AppConfig, terminalManag
 		op.Tags = []string{systemTag}
 	})
 
+	huma.Get(group, "/system/config", func(ctx context.Context, input *struct{}) (*systemConfigResponse, error) {
+		resp := &systemConfigResponse{}
+		resp.Body.ConfigPath = utils.ActiveConfigPath()
+		resp.Body.Config = utils.RedactedConfig(cfg)
+		if terminalManager != nil {
+			effective := terminalManager.EffectiveConfig()
+			resp.Body.Terminal = &effective
+		}
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "system-config-get"
+		op.Summary = "获取当前生效的配置"
+		op.Description = "返回脱敏后的完整配置，以及终端在默认值填充后实际使用的设置和各会话的覆盖值，用于排查配置问题"
+		op.Tags = []string{systemTag}
+	})
+
+	huma.Get(group, "/system/terminal-health", func(ctx context.Context, input *struct{}) (*h.ItemResponse[terminal.TerminalHealth], error) {
+		if terminalManager == nil {
+			return nil, huma.Error503ServiceUnavailable("terminal manager is not available")
+		}
+		resp := h.NewItemResponse(*terminalManager.Health())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "system-terminal-health"
+		op.Summary = "获取终端服务健康状态"
+		op.Description = "汇总所有会话的状态分布、error 会话列表、订阅者总数与泄漏数、事件丢弃计数和内存中 scrollback 占用，用于发现异常"
+		op.Tags = []string{systemTag}
+	})
+
+	huma.Get(group, "/system/scrollback-usage", func(ctx context.Context, input *struct{}) (*h.ItemResponse[terminal.ScrollbackUsage], error) {
+		if terminalManager == nil {
+			return nil, huma.Error503ServiceUnavailable("terminal manager is not available")
+		}
+		resp := h.NewItemResponse(*terminalManager.ScrollbackUsage())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "system-scrollback-usage"
+		op.Summary = "获取终端 scrollback 内存占用"
+		op.Description = "返回所有会话内存中 scrollback 的总占用、terminal.scrollbackTotalMB 配置的总预算、因超出预算累计裁剪的字节数，以及按占用从大到小排列的各会话明细"
+		op.Tags = []string{systemTag}
+	})
+
 	huma.Get(group, "/system/check-update", func(ctx context.Context, input *struct{}) (*checkUpdateResponse, error) {
 		resp := &checkUpdateResponse{}
 		resp.Body.CurrentVersion = appInfo.Version
@@ -143,6 +218,70 @@ func registerSystemRoutes(group *huma.Group, cfg *utils.AppConfig, terminalManag
 		op.Tags = []string{systemTag}
 	})
 
+	huma.Get(group, "/system/ai-assistants", func(ctx context.Context, input *struct{}) (*h.ItemsResponse[ai_assistant2.AssistantCapability], error) {
+		resp := h.NewItemsResponse(ai_assistant2.ListAssistantCapabilities())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "system-ai-assistants"
+		op.Summary = "获取支持的 AI 助手列表"
+		op.Description = "返回支持的 AI 助手类型及其能力，例如是否支持进度跟踪、token 统计以及识别命令关键字"
+		op.Tags = []string{systemTag}
+	})
+
+	huma.Get(group, "/system/shells", func(ctx context.Context, input *struct{}) (*h.ItemsResponse[utils.ShellOption], error) {
+		var shells []utils.ShellOption
+		if terminalManager != nil {
+			shells = terminalManager.ListShells()
+		} else {
+			shells = utils.ListShells(cfg.Terminal.Shell)
+		}
+		resp := h.NewItemsResponse(shells)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "system-shells"
+		op.Summary = "获取可用的终端 shell 列表"
+		op.Description = "返回默认 shell 与 terminal.shell.named 中配置的命名 shell，创建终端时通过 shellName 选择"
+		op.Tags = []string{systemTag}
+	})
+
+	huma.Get(group, "/system/ai-profiles", func(ctx context.Context, input *struct{}) (*h.ItemsResponse[aiProfileView], error) {
+		var profiles []terminal.AIProfile
+		if terminalManager != nil {
+			profiles = terminalManager.AIProfiles()
+		} else {
+			profiles = aiProfilesFromConfig(cfg.Terminal.AIProfiles)
+		}
+		views := make([]aiProfileView, 0, len(profiles))
+		for _, profile := range profiles {
+			views = append(views, aiProfileView{AIProfile: profile, EnvKeys: profile.EnvKeys()})
+		}
+		resp := h.NewItemsResponse(views)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "system-ai-profiles"
+		op.Summary = "获取 AI 模型配置档案列表"
+		op.Description = "返回 terminal.aiProfiles 中配置的档案，创建终端时通过 profileId 选择；环境变量只返回变量名，不返回值"
+		op.Tags = []string{systemTag}
+	})
+
+	huma.Get(group, "/system/check-tool", func(ctx context.Context, input *checkToolInput) (*h.ItemResponse[system.ToolInfo], error) {
+		info, err := system.CheckTool(ctx, input.Name)
+		if err != nil {
+			return nil, mapSystemError(err)
+		}
+		resp := h.NewItemResponse(*info)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "system-check-tool"
+		op.Summary = "检测 AI CLI 是否已安装"
+		op.Description = "在 PATH 中查找常见 AI CLI（claude、codex、gemini、qwen、copilot、cursor-agent），返回路径、--version 输出与安装命令，结果缓存 30 秒"
+		op.Tags = []string{systemTag}
+	})
+
 	// AI 助手状态监测配置
 	huma.Get(group, "/system/ai-assistant-status", func(ctx context.Context, input *struct{}) (*h.ItemResponse[utils.AIAssistantStatusConfig], error) {
 		resp := h.NewItemResponse(cfg.Terminal.AIAssistantStatus)
@@ -158,6 +297,11 @@ func registerSystemRoutes(group *huma.Group, cfg *utils.AppConfig, terminalManag
 	huma.Post(group, "/system/ai-assistant-status/update", func(ctx context.Context, input *struct {
 		Body utils.AIAssistantStatusConfig `json:"body"`
 	}) (*h.MessageResponse, error) {
+		input.Body.Preset = strings.ToLower(strings.TrimSpace(input.Body.Preset))
+		if !utils.ValidAIDetectionPreset(input.Body.Preset) {
+			return nil, huma.Error400BadRequest("preset must be empty, sensitive, balanced or conservative")
+		}
+
 		// 更新内存中的配置
 		cfg.Terminal.AIAssistantStatus = input.Body
 
@@ -175,7 +319,48 @@ func registerSystemRoutes(group *huma.Group, cfg *utils.AppConfig, terminalManag
 	}, func(op *huma.Operation) {
 		op.OperationID = "system-ai-assistant-status-update"
 		op.Summary = "更新 AI 助手状态监测配置"
-		op.Description = "更新 AI 助手状态监测的启用/禁用配置，立即对所有终端生效"
+		op.Description = "更新 AI 助手状态监测的启用/禁用配置与检测参数预设，立即对所有终端生效；detection 中非 0 的字段覆盖预设"
+		op.Tags = []string{systemTag}
+	})
+
+	huma.Get(group, "/system/output-triggers", func(ctx context.Context, input *struct{}) (*h.ItemsResponse[utils.TerminalOutputTrigger], error) {
+		resp := h.NewItemsResponse(cfg.Terminal.OutputTriggers)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "system-output-triggers-get"
+		op.Summary = "获取全局输出触发器"
+		op.Description = "返回对所有会话生效的输出触发器（terminal.outputTriggers）"
+		op.Tags = []string{systemTag}
+	})
+
+	huma.Post(group, "/system/output-triggers/update", func(ctx context.Context, input *struct {
+		Body struct {
+			Triggers []utils.TerminalOutputTrigger `json:"triggers" doc:"输出匹配正则 pattern 时自动写入 input，覆盖已有的全局触发器"`
+		} `json:"body"`
+	}) (*h.ItemsResponse[utils.TerminalOutputTrigger], error) {
+		triggers := input.Body.Triggers
+		if triggers == nil {
+			triggers = []utils.TerminalOutputTrigger{}
+		}
+
+		// 热重载：先应用到所有现有终端，正则非法时不写配置
+		if terminalManager != nil {
+			if err := terminalManager.UpdateOutputTriggers(outputTriggersFromConfig(triggers)); err != nil {
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+		}
+
+		cfg.Terminal.OutputTriggers = triggers
+		utils.WriteConfig(cfg)
+
+		resp := h.NewItemsResponse(triggers)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "system-output-triggers-update"
+		op.Summary = "更新全局输出触发器"
+		op.Description = "替换对所有会话生效的输出触发器并写回配置文件，立即对所有终端生效；pattern 不是合法正则时返回 400"
 		op.Tags = []string{systemTag}
 	})
 
@@ -200,6 +385,10 @@ func registerSystemRoutes(group *huma.Group, cfg *utils.AppConfig, terminalManag
 			terminalManager.UpdateScrollbackEnabled(input.Body.EnableTerminalScrollback)
 			terminalManager.UpdateRenameTitleEachCommand(input.Body.RenameSessionTitleEachCommand)
 			terminalManager.UpdateAutoCreateTaskOnStartWork(input.Body.AutoCreateTaskOnStartWork)
+			terminalManager.UpdateDetectLinks(input.Body.DetectTerminalLinks)
+			terminalManager.UpdateConfirmMultilineInput(input.Body.ConfirmMultilineInput)
+			terminalManager.UpdateAIDetectionDiagnostics(input.Body.AIDetectionDiagnostics)
+			terminalManager.UpdateCaptureRawOutput(input.Body.CaptureRawOutput)
 		}
 
 		resp := h.NewMessageResponse("Developer config updated.")
@@ -225,7 +414,8 @@ func mapSystemError(err error) error {
 	case errors.Is(err, system.ErrEditorCommandMissing):
 		return huma.Error503ServiceUnavailable(err.Error())
 	case errors.Is(err, system.ErrUnsupportedEditor),
-		errors.Is(err, system.ErrCustomEditorCommand):
+		errors.Is(err, system.ErrCustomEditorCommand),
+		errors.Is(err, system.ErrUnknownTool):
 		return huma.Error400BadRequest(err.Error())
 	default:
 		return huma.Error500InternalServerError(err.Error())
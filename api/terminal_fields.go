@@ -0,0 +1,112 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/danielgtaylor/huma/v2"
+
+	"code-kanban/service/terminal"
+)
+
+// terminalProcessFields 需要查询进程信息才能填充的字段，都未选择时跳过进程查询
+var terminalProcessFields = []string{
+	"processPid", "processStatus", "processHasChildren", "runningCommand", "aiAssistant", "aiState",
+}
+
+// terminalSessionFieldNames 会话视图可选择的字段，即 terminalSessionView 的 JSON 键
+var terminalSessionFieldNames = func() []string {
+	viewType := reflect.TypeOf(terminalSessionView{})
+	names := make([]string, 0, viewType.NumField())
+	for i := 0; i < viewType.NumField(); i++ {
+		name, _, _ := strings.Cut(viewType.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			names = append(names, name)
+		}
+	}
+	return names
+}()
+
+// terminalFieldSelection is the set of view fields requested with fields=; nil
+// selects every field.
+type terminalFieldSelection map[string]struct{}
+
+// parseTerminalFields parses a comma separated field list. id is always
+// included so clients can key the items.
+func parseTerminalFields(raw string) (terminalFieldSelection, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, nil
+	}
+	selection := terminalFieldSelection{"id": {}}
+	for _, part := range strings.Split(raw, ",") {
+		name := strings.TrimSpace(part)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(terminalSessionFieldNames, name) {
+			return nil, fmt.Errorf("unknown field %q, allowed: %s", name, strings.Join(terminalSessionFieldNames, ","))
+		}
+		selection[name] = struct{}{}
+	}
+	return selection, nil
+}
+
+// snapshotOptions skips the snapshot work no selected field depends on.
+func (f terminalFieldSelection) snapshotOptions() terminal.SnapshotOptions {
+	if f == nil {
+		return terminal.SnapshotOptions{}
+	}
+	opts := terminal.SnapshotOptions{SkipProcess: true}
+	for _, name := range terminalProcessFields {
+		if _, ok := f[name]; ok {
+			opts.SkipProcess = false
+			break
+		}
+	}
+	_, tokenUsage := f["tokenUsage"]
+	opts.SkipTokenUsage = !tokenUsage
+	return opts
+}
+
+// terminalSessionItem is a session view that only serializes the selected fields.
+type terminalSessionItem struct {
+	terminalSessionView
+	fields terminalFieldSelection
+}
+
+// Schema documents the item as a session view whose fields are all optional.
+func (terminalSessionItem) Schema(r huma.Registry) *huma.Schema {
+	schema := *huma.SchemaFromType(r, reflect.TypeOf(terminalSessionView{}))
+	schema.Required = nil
+	return &schema
+}
+
+func (i terminalSessionItem) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(i.terminalSessionView)
+	if err != nil || i.fields == nil {
+		return data, err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, err
+	}
+	selected := make(map[string]json.RawMessage, len(i.fields))
+	for name := range i.fields {
+		if value, ok := all[name]; ok {
+			selected[name] = value
+		}
+	}
+	return json.Marshal(selected)
+}
+
+func (c *terminalController) selectedViews(snapshots []terminal.SessionSnapshot, fields terminalFieldSelection) []terminalSessionItem {
+	items := make([]terminalSessionItem, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		items = append(items, terminalSessionItem{terminalSessionView: c.viewFromSnapshot(snapshot), fields: fields})
+	}
+	return items
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestParseTerminalFields(t *testing.T) {
+	cases := []struct {
+		name    string
+		raw     string
+		want    []string
+		wantErr string
+	}{
+		{name: "empty selects all", raw: ""},
+		{name: "blank selects all", raw: "  "},
+		{name: "id always included", raw: "title", want: []string{"id", "title"}},
+		{name: "whitespace around names", raw: " title , workingDir ", want: []string{"id", "title", "workingDir"}},
+		{name: "duplicates collapse", raw: "title,title,id", want: []string{"id", "title"}},
+		{name: "empty entries skipped", raw: "title,,", want: []string{"id", "title"}},
+		{name: "unknown field", raw: "title,secret", wantErr: `unknown field "secret"`},
+		// 字段名区分大小写，与 JSON 键一致
+		{name: "case sensitive", raw: "Title", wantErr: `unknown field "Title"`},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := parseTerminalFields(tc.raw)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tc.want == nil {
+				if got != nil {
+					t.Fatalf("expected nil selection, got %v", got)
+				}
+				return
+			}
+			names := slices.Sorted(maps.Keys(got))
+			if !slices.Equal(names, tc.want) {
+				t.Fatalf("expected %v, got %v", tc.want, names)
+			}
+		})
+	}
+}
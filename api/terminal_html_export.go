@@ -0,0 +1,192 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/tuzig/vt10x"
+	"golang.org/x/text/width"
+)
+
+const (
+	htmlExportDefaultLines = 2000
+	htmlExportMaxLines     = 10000
+)
+
+const htmlExportHead = `<!DOCTYPE html>
+<html lang="zh">
+<head>
+<meta charset="utf-8"/>
+<title>%s</title>
+<style>
+body{margin:0;padding:16px;background:%s;color:%s;}
+pre{margin:0;font-family:Consolas,Menlo,"DejaVu Sans Mono",monospace;font-size:13px;line-height:1.3;white-space:pre;}
+.w{display:inline-block;width:2ch;text-align:center;}
+</style>
+</head>
+<body>
+<pre>`
+
+const htmlExportTail = "</pre>\n</body>\n</html>\n"
+
+// htmlCellStyle 决定一段连续单元格能否合并为同一个 span
+type htmlCellStyle struct {
+	fg, bg string
+	mode   int16
+}
+
+func (s htmlCellStyle) css() string {
+	var parts []string
+	if s.fg != captureFallbackFG {
+		parts = append(parts, "color:"+s.fg)
+	}
+	if s.bg != captureFallbackBG {
+		parts = append(parts, "background:"+s.bg)
+	}
+	if s.mode&vt10x.AttrBold != 0 {
+		parts = append(parts, "font-weight:bold")
+	}
+	if s.mode&vt10x.AttrItalic != 0 {
+		parts = append(parts, "font-style:italic")
+	}
+	if s.mode&vt10x.AttrUnderline != 0 {
+		parts = append(parts, "text-decoration:underline")
+	}
+	if s.mode&vt10x.AttrFaint != 0 {
+		parts = append(parts, "opacity:.6")
+	}
+	return strings.Join(parts, ";")
+}
+
+func glyphHTMLStyle(glyph vt10x.Glyph) htmlCellStyle {
+	fg := colorToCSS(glyph.FG, captureFallbackFG)
+	bg := colorToCSS(glyph.BG, captureFallbackBG)
+	if glyph.Mode&vt10x.AttrReverse != 0 {
+		fg, bg = bg, fg
+	}
+	return htmlCellStyle{
+		fg:   fg,
+		bg:   bg,
+		mode: glyph.Mode & (vt10x.AttrBold | vt10x.AttrItalic | vt10x.AttrUnderline | vt10x.AttrFaint),
+	}
+}
+
+// writeTerminalHTML renders output on a terminal tall enough for its last
+// maxLines lines and writes them as an HTML page with inline styles. Runs of
+// cells sharing a style become one span, blank cells at the end of a row are
+// dropped, and wide runes get a two-column box so rows stay aligned.
+func writeTerminalHTML(w io.Writer, title string, output []byte, cols, maxLines int) error {
+	if cols <= 0 {
+		cols = captureDefaultCols
+	}
+	if maxLines <= 0 {
+		maxLines = htmlExportDefaultLines
+	}
+	maxLines = min(maxLines, htmlExportMaxLines)
+	rows := min(bytes.Count(output, []byte{'\n'})+1, maxLines)
+
+	term := vt10x.New(vt10x.WithSize(cols, rows))
+	_, _ = term.Write(output)
+	grid := make([][]vt10x.Glyph, rows)
+	first, last := -1, -1
+	for y := 0; y < rows; y++ {
+		row := make([]vt10x.Glyph, cols)
+		for x := 0; x < cols; x++ {
+			row[x] = term.Cell(x, y)
+		}
+		grid[y] = trimBlankGlyphs(row)
+		if len(grid[y]) > 0 {
+			if first < 0 {
+				first = y
+			}
+			last = y
+		}
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, htmlExportHead, html.EscapeString(title), captureFallbackBG, captureFallbackFG)
+	if first >= 0 {
+		for _, row := range grid[first : last+1] {
+			writeGlyphRowHTML(&buf, row)
+			buf.WriteByte('\n')
+		}
+	}
+	buf.WriteString(htmlExportTail)
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// trimBlankGlyphs drops trailing cells that show nothing: empty or space
+// cells without a background.
+func trimBlankGlyphs(row []vt10x.Glyph) []vt10x.Glyph {
+	end := len(row)
+	for end > 0 {
+		glyph := row[end-1]
+		blank := glyph.Char == 0 || glyph.Char == ' ' || unicode.IsControl(glyph.Char)
+		if !blank || glyphHTMLStyle(glyph).bg != captureFallbackBG {
+			break
+		}
+		end--
+	}
+	return row[:end]
+}
+
+func writeGlyphRowHTML(buf *bytes.Buffer, row []vt10x.Glyph) {
+	for start := 0; start < len(row); {
+		style := glyphHTMLStyle(row[start])
+		end := start + 1
+		for end < len(row) && continuesHTMLStyle(row[end], style) {
+			end++
+		}
+		css := style.css()
+		if css != "" {
+			fmt.Fprintf(buf, `<span style="%s">`, css)
+		}
+		for _, glyph := range row[start:end] {
+			writeGlyphHTML(buf, glyph.Char)
+		}
+		if css != "" {
+			buf.WriteString("</span>")
+		}
+		start = end
+	}
+}
+
+// continuesHTMLStyle reports whether glyph can join a span of style. Blank
+// cells only show their background, so their foreground and bold/italic
+// attributes do not split spans.
+func continuesHTMLStyle(glyph vt10x.Glyph, style htmlCellStyle) bool {
+	current := glyphHTMLStyle(glyph)
+	if current == style {
+		return true
+	}
+	if glyph.Char != 0 && glyph.Char != ' ' {
+		return false
+	}
+	return current.bg == style.bg && current.mode&vt10x.AttrUnderline == style.mode&vt10x.AttrUnderline
+}
+
+func writeGlyphHTML(buf *bytes.Buffer, r rune) {
+	switch {
+	case r == 0 || unicode.IsControl(r):
+		buf.WriteByte(' ')
+	case isWideRune(r):
+		buf.WriteString(`<span class="w">`)
+		buf.WriteString(html.EscapeString(string(r)))
+		buf.WriteString("</span>")
+	default:
+		buf.WriteString(html.EscapeString(string(r)))
+	}
+}
+
+func isWideRune(r rune) bool {
+	switch width.LookupRune(r).Kind() {
+	case width.EastAsianWide, width.EastAsianFullwidth:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// renderHTMLRows returns the content of the <pre> block.
+func renderHTMLRows(t *testing.T, title, output string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := writeTerminalHTML(&buf, title, []byte(output), 20, 0); err != nil {
+		t.Fatalf("writeTerminalHTML: %v", err)
+	}
+	page := buf.String()
+	start := strings.Index(page, "<pre>")
+	end := strings.Index(page, "</pre>")
+	if start < 0 || end < start {
+		t.Fatalf("missing <pre> block in %q", page)
+	}
+	return page[start+len("<pre>") : end]
+}
+
+func TestWriteTerminalHTML(t *testing.T) {
+	cases := []struct {
+		name   string
+		output string
+		want   string
+	}{
+		{
+			// 同样式的连续字符（含中间的空格）合并为一个 span，无样式文本不加 span
+			name:   "merge spans",
+			output: "\x1b[31mred\x1b[0m plain \x1b[1;31mbold\x1b[0m\r\n",
+			want:   `<span style="color:#cc0000">red </span>plain <span style="color:#cc0000;font-weight:bold">bold</span>` + "\n",
+		},
+		{
+			name:   "split on style change",
+			output: "\x1b[31ma \x1b[32mb\x1b[0m\r\n",
+			want:   `<span style="color:#cc0000">a </span><span style="color:#4e9a06">b</span>` + "\n",
+		},
+		{
+			// 有背景色的空白保留
+			name:   "keep blank cells with background",
+			output: "\x1b[41m  \x1b[0m\r\n",
+			want:   `<span style="background:#cc0000">  </span>` + "\n",
+		},
+		{
+			name:   "wide runes",
+			output: "中文ab\r\n",
+			want:   `<span class="w">中</span><span class="w">文</span>ab` + "\n",
+		},
+		{
+			name:   "escape html",
+			output: "<a & b>\r\n",
+			want:   "&lt;a &amp; b&gt;\n",
+		},
+		{
+			name:   "trim trailing blanks",
+			output: "x   \r\n\r\n\r\n",
+			want:   "x\n",
+		},
+		{
+			name:   "trim leading blank lines",
+			output: "\r\n\r\nmid\r\n\r\n",
+			want:   "mid\n",
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := renderHTMLRows(t, "t", tc.output); got != tc.want {
+				t.Fatalf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestWriteTerminalHTMLTitle(t *testing.T) {
+	var buf bytes.Buffer
+	if err := writeTerminalHTML(&buf, "a<b>&c", []byte("x"), 20, 0); err != nil {
+		t.Fatalf("writeTerminalHTML: %v", err)
+	}
+	if page := buf.String(); !strings.Contains(page, "<title>a&lt;b&gt;&amp;c</title>") {
+		t.Fatalf("title not escaped: %q", page)
+	}
+}
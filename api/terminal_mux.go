@@ -0,0 +1,205 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gorilla/websocket"
+	"github.com/valyala/fasthttp/fasthttpadaptor"
+	"go.uber.org/zap"
+
+	"code-kanban/service/terminal"
+)
+
+const (
+	terminalMuxWSPath = "/api/v1/terminal/mux"
+	// terminalMuxMaxSessions 单个多路复用连接同时 attach 的会话上限
+	terminalMuxMaxSessions = 64
+)
+
+// muxAttachment is one session attached to a multiplexed connection.
+type muxAttachment struct {
+	cancel context.CancelFunc
+	input  *wsInputHandler
+}
+
+// terminalMux multiplexes several sessions over one WebSocket. Every message
+// carries sessionId: clients attach and detach sessions at any time, and the
+// server tags data, metadata and other events with the session they belong to.
+type terminalMux struct {
+	c    *terminalController
+	ctx  context.Context
+	send func(wsMessage) error
+
+	mu          sync.Mutex
+	attachments map[string]*muxAttachment
+}
+
+func (c *terminalController) registerMuxWebsocket(app *fiber.App) {
+	handler := fasthttpadaptor.NewFastHTTPHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.serveMuxWebsocket(w, r)
+	}))
+	app.Get(terminalMuxWSPath, func(ctx *fiber.Ctx) error {
+		handler(ctx.Context())
+		return nil
+	})
+}
+
+func (c *terminalController) serveMuxWebsocket(w http.ResponseWriter, r *http.Request) {
+	conn, err := c.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		c.logger.Warn("upgrade websocket failed", zap.Error(err))
+		return
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	writeMu := &sync.Mutex{}
+	mux := &terminalMux{
+		c:   c,
+		ctx: ctx,
+		send: func(msg wsMessage) error {
+			writeMu.Lock()
+			defer writeMu.Unlock()
+			return conn.WriteJSON(msg)
+		},
+		attachments: make(map[string]*muxAttachment),
+	}
+	defer mux.detachAll()
+
+	for {
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.logger.Debug("websocket read error", zap.Error(err))
+			}
+			return
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			continue
+		}
+		if msg.SessionID == "" {
+			_ = mux.send(wsMessage{Type: "error", Data: "sessionId is required"})
+			continue
+		}
+		switch msg.Type {
+		case "attach":
+			mux.attach(msg)
+		case "detach":
+			mux.detach(msg.SessionID)
+			_ = mux.send(wsMessage{Type: "detached", SessionID: msg.SessionID})
+		default:
+			mux.mu.Lock()
+			attachment := mux.attachments[msg.SessionID]
+			mux.mu.Unlock()
+			if attachment == nil {
+				_ = mux.send(wsMessage{Type: "error", SessionID: msg.SessionID, Data: "session is not attached"})
+				continue
+			}
+			if !attachment.input.handle(msg) {
+				mux.detach(msg.SessionID)
+			}
+		}
+	}
+}
+
+// attach subscribes to a session and relays its events until the session
+// ends or the client detaches it. Attaching an attached session replaces the
+// previous subscription, e.g. to resume from a newer seq.
+func (m *terminalMux) attach(msg wsMessage) {
+	sessionID := msg.SessionID
+	fail := func(message string) {
+		_ = m.send(wsMessage{Type: "error", SessionID: sessionID, Data: message})
+	}
+	opts, err := terminalMuxAttachOptions(msg)
+	if err != nil {
+		fail(err.Error())
+		return
+	}
+	session, err := m.c.manager.GetSession(sessionID)
+	if err != nil {
+		if errors.Is(err, terminal.ErrSessionNotFound) {
+			fail("session not found")
+		} else {
+			fail("failed to load session")
+		}
+		return
+	}
+
+	m.detach(sessionID)
+	m.mu.Lock()
+	if len(m.attachments) >= terminalMuxMaxSessions {
+		m.mu.Unlock()
+		fail("too many attached sessions")
+		return
+	}
+	ctx, cancel := context.WithCancel(m.ctx)
+	send := func(out wsMessage) error {
+		out.SessionID = sessionID
+		return m.send(out)
+	}
+	attachment := &muxAttachment{cancel: cancel, input: &wsInputHandler{c: m.c, session: session, send: send}}
+	m.attachments[sessionID] = attachment
+	m.mu.Unlock()
+
+	stream, lastSeq, err := m.c.attachSession(ctx, session, opts, send)
+	if err != nil || stream == nil {
+		m.release(sessionID, attachment)
+		return
+	}
+	go func() {
+		m.c.forwardPTY(ctx, session, stream, opts.metadataPatch, lastSeq, send)
+		m.release(sessionID, attachment)
+	}()
+}
+
+// detach stops relaying a session; input for it is rejected afterwards.
+func (m *terminalMux) detach(sessionID string) {
+	m.mu.Lock()
+	attachment := m.attachments[sessionID]
+	delete(m.attachments, sessionID)
+	m.mu.Unlock()
+	if attachment != nil {
+		attachment.cancel()
+	}
+}
+
+// release drops attachment once its stream ended, unless it was replaced.
+func (m *terminalMux) release(sessionID string, attachment *muxAttachment) {
+	m.mu.Lock()
+	if m.attachments[sessionID] == attachment {
+		delete(m.attachments, sessionID)
+	}
+	m.mu.Unlock()
+	attachment.cancel()
+}
+
+func (m *terminalMux) detachAll() {
+	m.mu.Lock()
+	attachments := m.attachments
+	m.attachments = make(map[string]*muxAttachment)
+	m.mu.Unlock()
+	for _, attachment := range attachments {
+		attachment.cancel()
+	}
+}
+
+func terminalMuxAttachOptions(msg wsMessage) (wsAttachOptions, error) {
+	eventTypes, err := terminal.ParseStreamEventTypes(msg.Events)
+	if err != nil {
+		return wsAttachOptions{}, err
+	}
+	return wsAttachOptions{
+		eventTypes:    eventTypes,
+		metadataPatch: msg.MetadataPatch,
+		sinceSeq:      msg.Seq,
+		tail:          msg.Tail,
+	}, nil
+}
@@ -0,0 +1,302 @@
+package api
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"go.uber.org/zap"
+
+	"code-kanban/service/terminal"
+)
+
+func newMuxTestController(t *testing.T) *terminalController {
+	t.Helper()
+	return newTerminalTestController(t, terminal.Config{})
+}
+
+func newTerminalTestController(t *testing.T, cfg terminal.Config) *terminalController {
+	t.Helper()
+	manager := terminal.NewManager(cfg, zap.NewNop())
+	t.Cleanup(func() { manager.CloseWorktreeSessions("wt-mux") })
+	return &terminalController{
+		manager: manager,
+		logger:  zap.NewNop(),
+		upgrader: websocket.Upgrader{
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+func newMuxTestSession(t *testing.T, c *terminalController) *terminal.Session {
+	t.Helper()
+	session, err := c.manager.CreateSession(context.Background(), terminal.CreateSessionParams{
+		ProjectID:  "p-mux",
+		WorktreeID: "wt-mux",
+		WorkingDir: t.TempDir(),
+		Rows:       24,
+		Cols:       80,
+	})
+	if err != nil {
+		t.Skipf("pty unavailable: %v", err)
+	}
+	// 等待管理器监控 AI 助手状态的订阅就绪，之后的订阅数只来自多路复用连接
+	waitSubscribers(t, session, 1)
+	return session
+}
+
+// muxTestClient reads messages of one multiplexed connection.
+type muxTestClient struct {
+	t    *testing.T
+	conn *websocket.Conn
+}
+
+func dialMux(t *testing.T, c *terminalController) *muxTestClient {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(c.serveMuxWebsocket))
+	t.Cleanup(server.Close)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("dial mux websocket: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return &muxTestClient{t: t, conn: conn}
+}
+
+func (c *muxTestClient) send(msg wsMessage) {
+	c.t.Helper()
+	if err := c.conn.WriteJSON(msg); err != nil {
+		c.t.Fatalf("write mux message: %v", err)
+	}
+}
+
+// waitFor reads messages until match accepts one.
+func (c *muxTestClient) waitFor(what string, match func(wsMessage) bool) wsMessage {
+	c.t.Helper()
+	_ = c.conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	for {
+		var msg wsMessage
+		if err := c.conn.ReadJSON(&msg); err != nil {
+			c.t.Fatalf("waiting for %s: %v", what, err)
+		}
+		if match(msg) {
+			return msg
+		}
+	}
+}
+
+func (c *muxTestClient) waitOutput(sessionID, text string) {
+	c.t.Helper()
+	var output strings.Builder
+	c.waitFor("output "+text, func(msg wsMessage) bool {
+		if msg.Type != "data" || msg.SessionID != sessionID {
+			return false
+		}
+		data, _ := base64.StdEncoding.DecodeString(msg.Data)
+		output.Write(data)
+		return strings.Contains(output.String(), text)
+	})
+}
+
+func waitSubscribers(t *testing.T, session *terminal.Session, want int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for session.SubscriberCount() != want {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d subscribers, got %d", want, session.SubscriberCount())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestTerminalMuxAttachDetach(t *testing.T) {
+	c := newMuxTestController(t)
+	first := newMuxTestSession(t, c)
+	second := newMuxTestSession(t, c)
+	const base = 1
+	client := dialMux(t, c)
+
+	// 没有 sessionId 或未 attach 的会话不接受输入
+	client.send(wsMessage{Type: "input", Data: "echo lost\r"})
+	client.waitFor("missing sessionId error", func(msg wsMessage) bool {
+		return msg.Type == "error" && msg.SessionID == "" && msg.Data == "sessionId is required"
+	})
+	client.send(wsMessage{Type: "input", SessionID: first.ID(), Data: "echo lost\r"})
+	client.waitFor("not attached error", func(msg wsMessage) bool {
+		return msg.Type == "error" && msg.SessionID == first.ID() && msg.Data == "session is not attached"
+	})
+	client.send(wsMessage{Type: "attach", SessionID: "missing"})
+	client.waitFor("session not found error", func(msg wsMessage) bool {
+		return msg.Type == "error" && msg.SessionID == "missing" && msg.Data == "session not found"
+	})
+
+	// 两个会话的输出按 sessionId 区分
+	client.send(wsMessage{Type: "attach", SessionID: first.ID()})
+	client.waitFor("first synced", func(msg wsMessage) bool {
+		return msg.Type == "viewport-synced" && msg.SessionID == first.ID()
+	})
+	client.send(wsMessage{Type: "attach", SessionID: second.ID()})
+	client.waitFor("second synced", func(msg wsMessage) bool {
+		return msg.Type == "viewport-synced" && msg.SessionID == second.ID()
+	})
+	client.send(wsMessage{Type: "input", SessionID: second.ID(), Data: "echo mux-second\r"})
+	client.waitOutput(second.ID(), "mux-second")
+	client.send(wsMessage{Type: "input", SessionID: first.ID(), Data: "echo mux-first\r"})
+	client.waitOutput(first.ID(), "mux-first")
+
+	// 再次 attach 替换原订阅，不会重复订阅
+	client.send(wsMessage{Type: "attach", SessionID: first.ID()})
+	client.waitFor("first re-synced", func(msg wsMessage) bool {
+		return msg.Type == "viewport-synced" && msg.SessionID == first.ID()
+	})
+	waitSubscribers(t, first, base+1)
+	client.send(wsMessage{Type: "input", SessionID: first.ID(), Data: "echo mux-again\r"})
+	client.waitOutput(first.ID(), "mux-again")
+
+	// detach 后释放订阅，输入被拒绝，另一个会话不受影响
+	client.send(wsMessage{Type: "detach", SessionID: first.ID()})
+	client.waitFor("detached", func(msg wsMessage) bool {
+		return msg.Type == "detached" && msg.SessionID == first.ID()
+	})
+	waitSubscribers(t, first, base)
+	client.send(wsMessage{Type: "input", SessionID: first.ID(), Data: "echo lost\r"})
+	client.waitFor("not attached after detach", func(msg wsMessage) bool {
+		return msg.Type == "error" && msg.SessionID == first.ID() && msg.Data == "session is not attached"
+	})
+	client.send(wsMessage{Type: "input", SessionID: second.ID(), Data: "echo still-second\r"})
+	client.waitOutput(second.ID(), "still-second")
+
+	// 断开连接时释放所有订阅
+	client.conn.Close()
+	waitSubscribers(t, second, base)
+}
+
+func TestTerminalMuxSessionLimit(t *testing.T) {
+	c := newMuxTestController(t)
+	session := newMuxTestSession(t, c)
+
+	var errs []string
+	mux := &terminalMux{
+		c:   c,
+		ctx: context.Background(),
+		send: func(msg wsMessage) error {
+			if msg.Type == "error" {
+				errs = append(errs, msg.Data)
+			}
+			return nil
+		},
+		attachments: make(map[string]*muxAttachment),
+	}
+	for i := range terminalMuxMaxSessions {
+		mux.attachments[strings.Repeat("x", i+1)] = &muxAttachment{cancel: func() {}}
+	}
+	mux.attach(wsMessage{Type: "attach", SessionID: session.ID()})
+	if len(errs) != 1 || errs[0] != "too many attached sessions" {
+		t.Fatalf("expected the attach limit to apply, got %q", errs)
+	}
+	if _, ok := mux.attachments[session.ID()]; ok {
+		t.Fatalf("session attached beyond the limit")
+	}
+
+	// 释放一个位置后可以 attach
+	mux.detach("x")
+	mux.attach(wsMessage{Type: "attach", SessionID: session.ID()})
+	if len(errs) != 1 {
+		t.Fatalf("unexpected errors %q", errs)
+	}
+	if _, ok := mux.attachments[session.ID()]; !ok {
+		t.Fatalf("expected session to be attached")
+	}
+	mux.detachAll()
+}
+
+func TestWsInputHandler(t *testing.T) {
+	c := newMuxTestController(t)
+	session := newMuxTestSession(t, c)
+	var sent []wsMessage
+	handler := &wsInputHandler{c: c, session: session, send: func(msg wsMessage) error {
+		sent = append(sent, msg)
+		return nil
+	}}
+
+	if !handler.handle(wsMessage{Type: "input", Data: "echo handled\r"}) || len(sent) != 0 {
+		t.Fatalf("expected input to be written, got %+v", sent)
+	}
+	if !handler.handle(wsMessage{Type: "input"}) {
+		t.Fatalf("expected empty input to be ignored")
+	}
+	// 未知按键报错但保持连接
+	if !handler.handle(wsMessage{Type: "key", Key: "no-such-key"}) || len(sent) != 1 || sent[0].Type != "error" {
+		t.Fatalf("expected an error for an unknown key, got %+v", sent)
+	}
+	if !handler.handle(wsMessage{Type: "resize", Cols: 100, Rows: 30}) {
+		t.Fatalf("expected resize to keep the client attached")
+	}
+	if snapshot := session.Snapshot(); snapshot.Cols != 100 || snapshot.Rows != 30 {
+		t.Fatalf("expected resize to apply, got %dx%d", snapshot.Cols, snapshot.Rows)
+	}
+	if handler.handle(wsMessage{Type: "close"}) {
+		t.Fatalf("expected close to detach the client")
+	}
+	select {
+	case <-session.Closed():
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected close to end the session")
+	}
+}
+
+func TestAttachSessionKeepsOutputDuringReplay(t *testing.T) {
+	c := newTerminalTestController(t, terminal.Config{ScrollbackEnabled: true, ScrollbackBytes: 64 * 1024})
+	session := newMuxTestSession(t, c)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const marker = "marker-212"
+	base := session.SubscriberCount()
+	var mu sync.Mutex
+	var output strings.Builder
+	send := func(msg wsMessage) error {
+		switch msg.Type {
+		case "ready":
+			if got := session.SubscriberCount(); got != base+1 {
+				t.Errorf("expected to subscribe before replaying, got %d subscribers", got)
+			}
+			// 订阅之后、取回放之前产生的输出
+			if err := session.WriteInput([]byte("printf 'marker-%s\\n' 212\r")); err != nil {
+				t.Errorf("WriteInput failed: %v", err)
+			}
+			waitScrollback(t, session, marker)
+		case "data":
+			data, _ := base64.StdEncoding.DecodeString(msg.Data)
+			mu.Lock()
+			output.Write(data)
+			mu.Unlock()
+		}
+		return nil
+	}
+	stream, lastSeq, err := c.attachSession(ctx, session, wsAttachOptions{tail: 1000}, send)
+	if err != nil || stream == nil {
+		t.Fatalf("attachSession failed: %v", err)
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.forwardPTY(ctx, session, stream, false, lastSeq, send)
+	}()
+
+	// 回放与实时输出之间可能有重复的分片，只应转发一次
+	time.Sleep(200 * time.Millisecond)
+	cancel()
+	<-done
+	mu.Lock()
+	defer mu.Unlock()
+	if n := strings.Count(output.String(), marker); n != 1 {
+		t.Fatalf("expected the output once, got %d times in %q", n, output.String())
+	}
+}
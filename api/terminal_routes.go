@@ -1,14 +1,18 @@
 package api
 
 import (
+	"bytes"
 	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
@@ -21,15 +25,20 @@ import (
 
 	"code-kanban/api/h"
 	"code-kanban/model"
+	"code-kanban/model/tables"
 	"code-kanban/service"
 	"code-kanban/service/terminal"
 	"code-kanban/utils"
 	"code-kanban/utils/ai_assistant2"
+	"code-kanban/utils/ai_assistant2/types"
 )
 
 const (
 	terminalTag    = "terminal-session-终端会话"
 	terminalWSPath = "/api/v1/terminal/ws"
+
+	// terminalInputFileMaxBytes 限制单次文件输入的大小
+	terminalInputFileMaxBytes = 1 << 20
 )
 
 type terminalController struct {
@@ -37,6 +46,7 @@ type terminalController struct {
 	manager        *terminal.Manager
 	worktreeSvc    *service.WorktreeService
 	taskService    *model.TaskService
+	projectSvc     *model.ProjectService
 	logger         *zap.Logger
 	upgrader       websocket.Upgrader
 	wsPathTemplate string
@@ -51,6 +61,7 @@ func registerTerminalRoutes(app *fiber.App, group *huma.Group, cfg *utils.AppCon
 		manager:     manager,
 		worktreeSvc: service.NewWorktreeService(),
 		taskService: &model.TaskService{},
+		projectSvc:  model.NewProjectService(),
 		logger:      logger.Named("terminal-controller"),
 		upgrader: websocket.Upgrader{
 			ReadBufferSize:  32 * 1024,
@@ -60,7 +71,11 @@ func registerTerminalRoutes(app *fiber.App, group *huma.Group, cfg *utils.AppCon
 	}
 
 	ctrl.registerHTTP(group)
+	ctrl.registerProjectOverview(group)
 	ctrl.registerWebsocket(app)
+	ctrl.registerMuxWebsocket(app)
+	ctrl.registerStream(app)
+	ctrl.registerStateStream(app)
 }
 
 func (c *terminalController) registerHTTP(group *huma.Group) {
@@ -78,6 +93,22 @@ func (c *terminalController) registerHTTP(group *huma.Group) {
 	}, func(op *huma.Operation) {
 		op.OperationID = "terminal-session-create"
 		op.Summary = "创建终端会话"
+		op.Description = "携带 Idempotency-Key header 或 clientRequestId 时，短时间内相同 key 的重复请求返回同一会话"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/projects/{projectId}/terminals/batch", func(
+		ctx context.Context,
+		input *terminalBatchCreateInput,
+	) (*h.ItemsResponse[terminalBatchCreateResult], error) {
+		results := c.handleBatchCreate(ctx, input)
+		resp := h.NewItemsResponse(results)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-batch-create"
+		op.Summary = "批量为多个 worktree 创建终端会话"
+		op.Description = "逐个创建并返回每个 worktree 的会话或错误，部分失败不会回滚已创建的会话，仍受每个项目的会话数上限约束"
 		op.Tags = []string{terminalTag}
 	})
 
@@ -85,19 +116,61 @@ func (c *terminalController) registerHTTP(group *huma.Group) {
 		ctx context.Context,
 		input *struct {
 			ProjectID string `path:"projectId"`
+			Fields    string `query:"fields" doc:"逗号分隔的返回字段，如 id,title,status,aiState；为空返回全部字段"`
+			Source    string `query:"source" enum:"manual,api,clone,playbook" doc:"只返回该来源创建的会话"`
 		},
-	) (*h.ItemsResponse[terminalSessionView], error) {
-		sessions := c.manager.ListSessions(input.ProjectID)
-		views := make([]terminalSessionView, 0, len(sessions))
-		for _, snapshot := range sessions {
-			views = append(views, c.viewFromSnapshot(snapshot))
+	) (*h.ItemsResponse[terminalSessionItem], error) {
+		fields, err := parseTerminalFields(input.Fields)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
 		}
-		resp := h.NewItemsResponse(views)
+		sessions := terminal.FilterSnapshotsBySource(c.manager.ListSessionsWith(input.ProjectID, fields.snapshotOptions()), terminal.SessionSource(input.Source))
+		resp := h.NewItemsResponse(c.selectedViews(sessions, fields))
 		resp.Status = http.StatusOK
 		return resp, nil
 	}, func(op *huma.Operation) {
 		op.OperationID = "terminal-session-list"
 		op.Summary = "获取终端会话列表"
+		op.Description = "fields 只返回指定字段（id 始终返回），未选择进程相关字段时跳过进程与前台命令查询"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/worktrees/{id}/terminals", func(
+		ctx context.Context,
+		input *struct {
+			ID     string `path:"id"`
+			Fields string `query:"fields" doc:"逗号分隔的返回字段，为空返回全部字段"`
+			Source string `query:"source" enum:"manual,api,clone,playbook" doc:"只返回该来源创建的会话"`
+		},
+	) (*h.ItemsResponse[terminalSessionItem], error) {
+		fields, err := parseTerminalFields(input.Fields)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		sessions := terminal.FilterSnapshotsBySource(c.manager.ListSessionsByWorktreeWith(input.ID, fields.snapshotOptions()), terminal.SessionSource(input.Source))
+		resp := h.NewItemsResponse(c.selectedViews(sessions, fields))
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-list-by-worktree"
+		op.Summary = "获取 Worktree 下的终端会话列表"
+		op.Description = "返回关联到该 worktree 的会话及其状态，排序与项目会话列表一致；fields 用法同项目会话列表"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/projects/{projectId}/terminals/usage", func(
+		ctx context.Context,
+		input *struct {
+			ProjectID string `path:"projectId"`
+		},
+	) (*h.ItemResponse[terminal.TokenUsage], error) {
+		resp := h.NewItemResponse(*c.manager.ProjectTokenUsage(input.ProjectID))
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-project-token-usage"
+		op.Summary = "汇总项目的 AI token 用量与估算成本"
+		op.Description = "汇总项目当前所有终端会话的 token 用量，任一模型缺少价格配置时不返回 estimatedCostUSD"
 		op.Tags = []string{terminalTag}
 	})
 
@@ -105,7 +178,7 @@ func (c *terminalController) registerHTTP(group *huma.Group) {
 		ctx context.Context,
 		input *struct{},
 	) (*terminalCountsResponse, error) {
-		sessions := c.manager.ListSessions("")
+		sessions := c.manager.ListSessionsWith("", terminal.SnapshotOptions{SkipProcess: true, SkipTokenUsage: true})
 		counts := make(map[string]int)
 		for _, snapshot := range sessions {
 			counts[snapshot.ProjectID]++
@@ -170,6 +243,32 @@ func (c *terminalController) registerHTTP(group *huma.Group) {
 		op.Tags = []string{terminalTag}
 	})
 
+	huma.Post(group, "/projects/{projectId}/terminals/{sessionId}/idle-timeout", func(
+		ctx context.Context,
+		input *terminalIdleTimeoutInput,
+	) (*h.ItemResponse[terminalSessionView], error) {
+		timeout, err := parseIdleTimeoutOverride(input.Body.IdleTimeout)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+
+		session, err := c.manager.SetSessionIdleTimeout(input.ProjectID, input.SessionID, timeout)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to update idle timeout", err)
+		}
+		view := c.viewFromSnapshot(session.Snapshot())
+		resp := h.NewItemResponse(view)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-idle-timeout"
+		op.Summary = "设置终端会话空闲超时"
+		op.Tags = []string{terminalTag}
+	})
+
 	huma.Post(group, "/projects/{projectId}/terminals/{sessionId}/tasks/link", func(
 		ctx context.Context,
 		input *terminalTaskLinkInput,
@@ -258,120 +357,1515 @@ func (c *terminalController) registerHTTP(group *huma.Group) {
 		op.Tags = []string{terminalTag}
 	})
 
-	huma.Get(group, "/terminals/{sessionId}/debug", func(
+	huma.Post(group, "/terminals/{sessionId}/to-task", func(
+		ctx context.Context,
+		input *terminalToTaskInput,
+	) (*h.ItemResponse[terminalToTaskResult], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		if taskID := session.TaskID(); taskID != "" {
+			return nil, huma.Error409Conflict(fmt.Sprintf("session already linked to task %s", taskID))
+		}
+
+		draft, err := c.manager.SessionTaskDraft(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to build task", err)
+		}
+
+		status := strings.TrimSpace(input.Body.Status)
+		if status == "" {
+			status = "done"
+		}
+		var worktreeID *string
+		if id := session.WorktreeID(); id != "" {
+			worktreeID = &id
+		}
+		task, err := c.taskService.CreateTask(ctx, &model.CreateTaskRequest{
+			ProjectID:   session.ProjectID(),
+			WorktreeID:  worktreeID,
+			Title:       draft.Title,
+			Description: draft.Description,
+			Status:      status,
+			Tags:        taskTagsFromDraft(draft),
+		})
+		if err != nil {
+			if errors.Is(err, model.ErrInvalidTaskStatus) {
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to create task", err)
+		}
+
+		session, err = c.manager.LinkTask(input.SessionID, task.ID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to link task", err)
+		}
+
+		resp := h.NewItemResponse(terminalToTaskResult{
+			Task:    task,
+			Session: c.viewFromSnapshot(session.Snapshot()),
+		})
+		resp.Status = http.StatusCreated
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-to-task"
+		op.Summary = "将终端会话归档为任务卡片"
+		op.Description = "用会话标题、最近用户输入、助手类型和完成时间生成任务（默认状态 done），并与会话建立关联；会话已关联任务时返回 409"
+		op.Tags = []string{terminalTag}
+	})
+
+	for _, action := range []struct {
+		path    string
+		id      string
+		summary string
+		apply   func(string) (*terminal.Session, error)
+	}{
+		{"pause", "terminal-session-pause", "暂停终端会话", c.manager.PauseSession},
+		{"resume", "terminal-session-resume", "恢复终端会话", c.manager.ResumeSession},
+	} {
+		huma.Post(group, "/terminals/{sessionId}/"+action.path, func(
+			ctx context.Context,
+			input *struct {
+				SessionID string `path:"sessionId"`
+			},
+		) (*h.ItemResponse[terminalSessionView], error) {
+			session, err := action.apply(input.SessionID)
+			if err != nil {
+				switch {
+				case errors.Is(err, terminal.ErrSessionNotFound):
+					return nil, huma.Error404NotFound(err.Error())
+				case errors.Is(err, terminal.ErrSessionNotRunning):
+					return nil, huma.Error409Conflict(err.Error())
+				case errors.Is(err, terminal.ErrPauseUnsupported):
+					return nil, huma.Error501NotImplemented(err.Error())
+				}
+				return nil, huma.Error500InternalServerError("failed to "+action.path+" session", err)
+			}
+			view := c.viewFromSnapshot(session.Snapshot())
+			resp := h.NewItemResponse(view)
+			resp.Status = http.StatusOK
+			return resp, nil
+		}, func(op *huma.Operation) {
+			op.OperationID = action.id
+			op.Summary = action.summary
+			op.Tags = []string{terminalTag}
+			op.Description = "通过 SIGSTOP/SIGCONT 冻结或恢复会话的整棵进程树，暂停期间停止状态轮询且不会被空闲回收；Windows 暂不支持"
+		})
+	}
+
+	huma.Post(group, "/terminals/{sessionId}/move", func(
+		ctx context.Context,
+		input *terminalMoveInput,
+	) (*h.ItemResponse[terminalSessionView], error) {
+		session, err := c.manager.MoveSession(input.SessionID, input.Body.OrderIndex)
+		if err != nil {
+			switch {
+			case errors.Is(err, terminal.ErrSessionNotFound):
+				return nil, huma.Error404NotFound(err.Error())
+			case errors.Is(err, terminal.ErrInvalidOrderIndex):
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to move session", err)
+		}
+		view := c.viewFromSnapshot(session.Snapshot())
+		resp := h.NewItemResponse(view)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-move"
+		op.Summary = "调整终端标签顺序"
+		op.Description = "会话列表按 orderIndex 升序返回，新建会话排在末尾；顺序在会话生命周期内保留"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/encoding", func(
+		ctx context.Context,
+		input *terminalEncodingInput,
+	) (*h.ItemResponse[terminalSessionView], error) {
+		session, err := c.manager.SetSessionEncoding(input.SessionID, input.Body.Encoding, input.Body.Redecode)
+		if err != nil {
+			switch {
+			case errors.Is(err, terminal.ErrSessionNotFound):
+				return nil, huma.Error404NotFound(err.Error())
+			case errors.Is(err, terminal.ErrInvalidEncoding):
+				return nil, huma.Error400BadRequest(fmt.Sprintf("encoding %q is not supported (utf-8, gbk, gb18030, gb2312)", input.Body.Encoding))
+			}
+			return nil, huma.Error500InternalServerError("failed to change session encoding", err)
+		}
+		view := c.viewFromSnapshot(session.Snapshot())
+		resp := h.NewItemResponse(view)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-encoding"
+		op.Summary = "切换终端会话编码"
+		op.Description = "运行中切换会话的输入输出编码，不需要重开终端。redecode 为 true 时按新编码重新解码 scrollback，" +
+			"并通过 encoding-changed 事件广播，客户端清屏后写入事件数据即可；原编码为 utf-8 时可无损转换"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/clone", func(
 		ctx context.Context,
 		input *struct {
 			SessionID string `path:"sessionId"`
 		},
-	) (*h.ItemResponse[terminal.DebugInfo], error) {
-		debugInfo, err := c.manager.GetSessionDebugInfo(input.SessionID)
+	) (*h.ItemResponse[terminalSessionView], error) {
+		session, err := c.manager.CloneSession(input.SessionID)
+		if err != nil {
+			switch {
+			case errors.Is(err, terminal.ErrSessionNotFound):
+				return nil, huma.Error404NotFound(err.Error())
+			case errors.Is(err, terminal.ErrShellNotFound),
+				errors.Is(err, terminal.ErrUnknownShell),
+				errors.Is(err, terminal.ErrUnknownProfile):
+				return nil, huma.Error400BadRequest(err.Error())
+			case errors.Is(err, terminal.ErrSessionLimitReached),
+				errors.Is(err, terminal.ErrQuotaExceeded):
+				return nil, huma.Error429TooManyRequests(err.Error())
+			case errors.Is(err, model.ErrProjectArchived):
+				return nil, huma.Error409Conflict(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to clone terminal session", err)
+		}
+		view := c.viewFromSnapshot(session.Snapshot())
+		resp := h.NewItemResponse(view)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-clone"
+		op.Summary = "克隆终端会话"
+		op.Description = "按源会话的创建参数（worktree、工作目录、shell、环境变量、终端类型与重启策略）新建会话，尺寸取源会话当前尺寸；" +
+			"不复制 scrollback、进程状态与任务关联。标题追加 (2)、(3) 等编号，受会话数量上限与配额限制，超出返回 429；项目已归档时返回 409"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/expect", func(
+		ctx context.Context,
+		input *terminalExpectInput,
+	) (*h.ItemResponse[terminalExpectResult], error) {
+		data := []byte(input.Body.Input)
+		if input.Body.AppendNewline {
+			data = append(data, '\r')
+		}
+		timeout := time.Duration(input.Body.Timeout) * time.Second
+		line, err := c.manager.SendAndWait(ctx, input.SessionID, data, input.Body.Pattern, timeout)
+		if err != nil {
+			switch {
+			case errors.Is(err, terminal.ErrSessionNotFound):
+				return nil, huma.Error404NotFound(err.Error())
+			case errors.Is(err, terminal.ErrInvalidWaitPattern):
+				return nil, huma.Error400BadRequest(err.Error())
+			case errors.Is(err, terminal.ErrWaitTimeout):
+				return nil, huma.NewError(http.StatusRequestTimeout, err.Error())
+			case errors.Is(err, terminal.ErrSessionNotRunning):
+				return nil, huma.Error409Conflict("session exited before the pattern matched")
+			case errors.Is(err, terminal.ErrSessionReadOnly):
+				return nil, huma.Error409Conflict(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to wait for output", err)
+		}
+		resp := h.NewItemResponse(terminalExpectResult{Line: line})
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-expect"
+		op.Summary = "发送输入并等待特定输出"
+		op.Description = "先订阅输出再写入 input（可为空），之后按会话尺寸渲染输出，屏幕上有一行匹配 pattern 正则即返回该行；" +
+			"超时返回 408，会话先退出返回 409。输入的回显同样参与匹配，适合脚本驱动 AI CLI 时等待提示符"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/input-file", func(
+		ctx context.Context,
+		input *terminalInputFileInput,
+	) (*h.MessageResponse, error) {
+		session, err := c.manager.GetSession(input.SessionID)
 		if err != nil {
 			if errors.Is(err, terminal.ErrSessionNotFound) {
 				return nil, huma.Error404NotFound(err.Error())
 			}
-			return nil, huma.Error500InternalServerError("failed to get debug info", err)
+			return nil, huma.Error500InternalServerError("failed to load session", err)
 		}
-		resp := h.NewItemResponse(*debugInfo)
+
+		file := input.RawBody.Data().File
+		defer file.Close()
+		if file.Size > terminalInputFileMaxBytes {
+			return nil, huma.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds %d bytes", terminalInputFileMaxBytes))
+		}
+
+		data, err := io.ReadAll(io.LimitReader(file, terminalInputFileMaxBytes+1))
+		if err != nil {
+			return nil, huma.Error400BadRequest("failed to read file", err)
+		}
+		if len(data) > terminalInputFileMaxBytes {
+			return nil, huma.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("file exceeds %d bytes", terminalInputFileMaxBytes))
+		}
+		if input.AppendNewline {
+			data = append(data, '\r')
+		}
+
+		if err := session.WriteInput(data); err != nil {
+			if errors.Is(err, terminal.ErrSessionReadOnly) {
+				return nil, huma.Error409Conflict(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to write input", err)
+		}
+
+		resp := h.NewMessageResponse(fmt.Sprintf("%d bytes written", len(data)))
 		resp.Status = http.StatusOK
 		return resp, nil
 	}, func(op *huma.Operation) {
-		op.OperationID = "terminal-session-debug"
-		op.Summary = "获取终端调试信息（包含完整输出内容）"
+		op.OperationID = "terminal-session-input-file"
+		op.Summary = "将文件内容写入终端输入"
+		op.Description = "上传文件并按会话编码写入 PTY，适合把准备好的 prompt 一次性送入 AI 助手"
 		op.Tags = []string{terminalTag}
-		op.Description = "用于调试，返回终端的 scrollback 缓冲区内容、AI 助手状态、录制信息等"
+		op.MaxBodyBytes = terminalInputFileMaxBytes + 64*1024
 	})
 
-	huma.Get(group, "/terminals/{sessionId}/capture", func(
+	huma.Get(group, "/terminals/{sessionId}/events-export", func(
 		ctx context.Context,
 		input *struct {
 			SessionID string `path:"sessionId"`
-			Timeout   int    `query:"timeout" doc:"超时时间（秒），默认为 2 秒，0 表示使用默认值" minimum:"0" maximum:"10"`
 		},
-	) (*h.ItemResponse[terminal.CapturedChunk], error) {
-		timeout := 2 * time.Second
-		if input.Timeout > 0 {
-			timeout = time.Duration(input.Timeout) * time.Second
+	) (*huma.StreamResponse, error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		if !session.RecordsEvents() {
+			return nil, huma.Error409Conflict(terminal.ErrEventRecordingDisabled.Error())
 		}
 
-		chunk, err := c.manager.CaptureChunk(ctx, input.SessionID, timeout)
+		return &huma.StreamResponse{
+			Body: func(hctx huma.Context) {
+				hctx.SetHeader("Content-Type", "application/x-ndjson")
+				hctx.SetHeader("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.jsonl"`, session.ID()))
+				if err := session.ExportEvents(hctx.BodyWriter()); err != nil {
+					c.logger.Warn("failed to export terminal events",
+						zap.String("sessionId", session.ID()),
+						zap.Error(err))
+				}
+			},
+		}, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-events-export"
+		op.Summary = "导出终端会话事件（JSONL）"
+		op.Description = "每行一个事件（data/metadata/exit 等）及时间戳，data 使用 base64 编码；需在配置中开启 terminal.recordEvents"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/scrollback", func(
+		ctx context.Context,
+		input *terminalScrollbackInput,
+	) (*h.ItemResponse[terminalScrollbackPage], error) {
+		session, err := c.manager.GetSession(input.SessionID)
 		if err != nil {
 			if errors.Is(err, terminal.ErrSessionNotFound) {
 				return nil, huma.Error404NotFound(err.Error())
 			}
-			return nil, huma.Error500InternalServerError("failed to capture chunk", err)
+			return nil, huma.Error500InternalServerError("failed to load session", err)
 		}
-		resp := h.NewItemResponse(*chunk)
+
+		var page terminalScrollbackPage
+		switch {
+		case input.byLines:
+			lines := session.ScrollbackLines(input.Offset, input.Limit)
+			page.Lines, page.Offset, page.Total, page.LatestSeq = lines.Lines, lines.Offset, lines.Total, lines.LatestSeq
+		case input.Before > 0 || input.Since <= 0:
+			page.Chunks = session.ScrollbackBefore(input.Before, input.Limit)
+			page.LatestSeq = session.ScrollbackLatestSeq()
+		default:
+			page.Chunks, page.LatestSeq = session.ScrollbackSince(input.Since)
+		}
+		if page.Chunks == nil {
+			page.Chunks = []terminal.ScrollbackChunk{}
+		}
+		resp := h.NewItemResponse(page)
 		resp.Status = http.StatusOK
 		return resp, nil
 	}, func(op *huma.Operation) {
-		op.OperationID = "terminal-session-capture-chunk"
-		op.Summary = "触发 resize 并捕获下一个输出 chunk"
+		op.OperationID = "terminal-session-scrollback"
+		op.Summary = "分页获取终端 scrollback"
+		op.Description = "默认按分片返回，分片 data 为 base64；since 用于增量获取，before+limit 用于向前加载历史。指定 offset 时改为按逻辑行分页：内存中的 scrollback 按换行切分并剥离控制序列，返回从 offset 起的 limit 行纯文本（最多 2000 行）及总行数，新输出只增量切分"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/extract-code", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Limit     int    `query:"limit" minimum:"1" maximum:"200" default:"20" doc:"返回最近的代码块数量上限"`
+		},
+	) (*h.ItemsResponse[terminal.CodeSnippet], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		resp := h.NewItemsResponse(session.ExtractCode(input.Limit))
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-extract-code"
+		op.Summary = "从终端输出中提取代码块"
+		op.Description = "在渲染后的 scrollback 中识别 ``` 围栏代码块，以及按关键字、符号与缩进猜测出的未加围栏的代码片段，按输出顺序返回最近的若干个，附带猜测的语言与可直接复制的 Markdown；startLine/endLine 与 scrollback 按逻辑行分页时的行号一致。识别基于启发式，可能漏掉或多包含少量行"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/scrollback/search", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Query     string `query:"q" required:"true" doc:"搜索的文本，不区分大小写"`
+			Limit     int    `query:"limit" default:"200" doc:"返回最近的匹配行数上限"`
+		},
+	) (*h.ItemsResponse[terminal.ScrollbackMatch], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		matches, err := session.SearchScrollback(input.Query, input.Limit)
+		if err != nil {
+			return nil, huma.Error500InternalServerError("failed to search scrollback", err)
+		}
+		resp := h.NewItemsResponse(matches)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-scrollback-search"
+		op.Summary = "搜索终端 scrollback"
+		op.Description = "按行搜索去掉控制序列后的输出，开启 terminal.scrollbackSpill 时包含已落盘的早期输出；seq 为该行所在分片，可配合 before 游标定位"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/scrollback/export", func(
+		ctx context.Context,
+		input *struct {
+			SessionID  string `path:"sessionId"`
+			Plain      bool   `query:"plain" doc:"去掉 ANSI 控制序列，导出纯文本"`
+			Filtered   bool   `query:"filtered" doc:"导出纯文本并应用输出美化规则，隐含 plain"`
+			Timestamps bool   `query:"timestamps" doc:"导出纯文本并在每行前标注接收时间（精确到毫秒），隐含 plain，可与 filtered 同时使用"`
+		},
+	) (*huma.StreamResponse, error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+
+		return &huma.StreamResponse{
+			Body: func(hctx huma.Context) {
+				hctx.SetHeader("Content-Type", "text/plain; charset=utf-8")
+				hctx.SetHeader("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.txt"`, session.ID()))
+				var err error
+				if input.Timestamps {
+					err = session.WriteTimestampedScrollback(hctx.BodyWriter(), input.Filtered)
+				} else if input.Filtered {
+					err = session.WriteFilteredScrollback(hctx.BodyWriter())
+				} else if input.Plain {
+					err = session.WritePlainScrollback(hctx.BodyWriter())
+				} else {
+					err = session.WriteScrollback(hctx.BodyWriter())
+				}
+				if err != nil {
+					c.logger.Warn("failed to export terminal scrollback",
+						zap.String("sessionId", session.ID()),
+						zap.Error(err))
+				}
+			},
+		}, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-scrollback-export"
+		op.Summary = "导出终端 scrollback"
+		op.Description = "按顺序导出会话保留的全部输出，开启 terminal.scrollbackSpill 时先输出已落盘的早期部分；filtered 按行应用输出美化规则；timestamps 为每行标注所在分片的接收时间，便于分析各阶段耗时"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/export.html", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Lines     int    `query:"lines" minimum:"0" maximum:"10000" doc:"导出最后多少行，0 使用默认值 2000"`
+			Download  bool   `query:"download" doc:"以附件形式下载，否则在浏览器中直接打开"`
+		},
+	) (*huma.StreamResponse, error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		var output bytes.Buffer
+		if err := session.WriteScrollback(&output); err != nil {
+			return nil, huma.Error500InternalServerError("failed to read scrollback", err)
+		}
+		snapshot := session.Snapshot()
+
+		return &huma.StreamResponse{
+			Body: func(hctx huma.Context) {
+				hctx.SetHeader("Content-Type", "text/html; charset=utf-8")
+				if input.Download {
+					hctx.SetHeader("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.html"`, session.ID()))
+				}
+				if err := writeTerminalHTML(hctx.BodyWriter(), snapshot.Title, output.Bytes(), snapshot.Cols, input.Lines); err != nil {
+					c.logger.Warn("failed to export terminal html",
+						zap.String("sessionId", session.ID()),
+						zap.Error(err))
+				}
+			},
+		}, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-export-html"
+		op.Summary = "导出带颜色的终端 HTML"
+		op.Description = "按会话列宽渲染 scrollback，保留前景/背景色、粗体、斜体、下划线等属性，输出为内联样式的 HTML，便于贴进文档或分享；清屏与光标移动按终端语义处理，全屏 TUI 只保留最终画面"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/preview", func(
+		ctx context.Context,
+		input *struct {
+			SessionID   string `path:"sessionId"`
+			Lines       int    `query:"lines" default:"5" minimum:"1" maximum:"50" doc:"返回最后几行"`
+			IfNoneMatch string `header:"If-None-Match" doc:"上次响应的 ETag，内容未变时返回 304"`
+		},
+	) (*terminalPreviewResponse, error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+
+		preview := session.OutputPreview(input.Lines)
+		resp := &terminalPreviewResponse{Status: http.StatusOK, ETag: preview.ETag}
+		if etagMatches(input.IfNoneMatch, preview.ETag) {
+			resp.Status = http.StatusNotModified
+			return resp, nil
+		}
+		resp.Body.Lines = preview.Lines
+		resp.Body.AutoWrap = preview.AutoWrap
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-preview"
+		op.Summary = "获取终端输出的缩略预览"
+		op.Description = "按当前尺寸渲染 scrollback，返回去掉控制序列后的最后 N 行纯文本，供看板卡片轮询；携带 If-None-Match 且内容未变时返回 304"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/hyperlinks", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.ItemsResponse[terminal.TerminalHyperlink], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		resp := h.NewItemsResponse(session.Hyperlinks())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-hyperlinks"
+		op.Summary = "解析 scrollback 中的 OSC 8 超链接"
+		op.Description = "返回可见文本与 URL 的映射，只包含 http/https/file/mailto 链接；未开启 scrollback 时为空"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/triggers", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.ItemsResponse[terminal.OutputTrigger], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		resp := h.NewItemsResponse(session.OutputTriggers())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-triggers"
+		op.Summary = "获取会话级输出触发器"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/triggers", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Body      struct {
+				Triggers []terminal.OutputTrigger `json:"triggers" doc:"输出匹配正则 pattern 时自动写入 input，覆盖已有的会话级触发器"`
+			} `json:"body"`
+		},
+	) (*h.ItemsResponse[terminal.OutputTrigger], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		if err := session.SetOutputTriggers(input.Body.Triggers); err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		resp := h.NewItemsResponse(session.OutputTriggers())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-triggers-update"
+		op.Summary = "设置会话级输出触发器"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/alerts", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.ItemsResponse[terminal.AlertRule], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		resp := h.NewItemsResponse(session.AlertRules())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-alerts"
+		op.Summary = "获取会话级告警规则"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/alerts", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Body      struct {
+				Rules []terminal.AlertRule `json:"rules" doc:"输出匹配正则 pattern 时生成告警记录，覆盖已有的会话级告警规则"`
+			} `json:"body"`
+		},
+	) (*h.ItemsResponse[terminal.AlertRule], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		if err := session.SetAlertRules(input.Body.Rules); err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		resp := h.NewItemsResponse(session.AlertRules())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-alerts-update"
+		op.Summary = "设置会话级告警规则"
+		op.Description = "渲染后的输出行命中规则时生成告警记录（带命中行上下文），同时在会话流中推送 alert 事件；同一规则在 cooldownSeconds（默认 60 秒）内不重复告警，每个会话的每条规则只保留最新一条告警记录。配置中的 terminal.alertRules 对所有会话生效"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/output-filters", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.ItemsResponse[terminal.OutputFilterRule], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		resp := h.NewItemsResponse(session.OutputFilters())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-output-filters"
+		op.Summary = "获取会话级输出美化规则"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/output-filters", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Body      struct {
+				Rules []terminal.OutputFilterRule `json:"rules" doc:"按顺序作用于输出行的正则替换或删除规则，覆盖已有的会话级规则"`
+			} `json:"body"`
+		},
+	) (*h.ItemsResponse[terminal.OutputFilterRule], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		if err := session.SetOutputFilters(input.Body.Rules); err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		resp := h.NewItemsResponse(session.OutputFilters())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-output-filters-update"
+		op.Summary = "设置会话级输出美化规则"
+		op.Description = "命中 pattern 的部分替换为 replace（为空则删除，删空的行一并去掉），dropLine 删除整行。规则只作用于预览和 filtered 导出，原始 scrollback 与实时输出不变；会话规则先于配置中的 terminal.outputFilters 执行"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/correct-state", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Body      struct {
+				State string `json:"state" enum:"working,waiting_input,waiting_approval" doc:"助手实际所处的状态"`
+				Note  string `json:"note,omitempty" maxLength:"500" doc:"可选的补充说明"`
+			} `json:"body"`
+		},
+	) (*h.ItemResponse[tables.AIStateCorrectionTable], error) {
+		entry, err := c.manager.CorrectAssistantState(input.SessionID, types.State(input.Body.State), strings.TrimSpace(input.Body.Note))
+		if err != nil {
+			switch {
+			case errors.Is(err, terminal.ErrSessionNotFound):
+				return nil, huma.Error404NotFound(err.Error())
+			case errors.Is(err, terminal.ErrInvalidAssistantState):
+				return nil, huma.Error400BadRequest(err.Error())
+			case errors.Is(err, terminal.ErrNoAssistantTracked):
+				return nil, huma.Error409Conflict(err.Error())
+			default:
+				return nil, huma.Error500InternalServerError("failed to correct assistant state", err)
+			}
+		}
+		resp := h.NewItemResponse(*entry)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-correct-state"
+		op.Summary = "纠正 AI 助手状态"
+		op.Description = "立即把显示的助手状态改为用户给出的状态，直到有新输出后重新检测；同时记录纠正前的检测结果、屏幕文本与最近输出，用于改进检测规则和回归测试"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/tracking/pause", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.ItemResponse[terminal.AssistantTracking], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		tracking, err := session.SetAssistantTrackingPaused(true)
+		if err != nil {
+			if errors.Is(err, terminal.ErrNoAssistantTracked) {
+				return nil, huma.Error409Conflict(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to update assistant tracking", err)
+		}
+		resp := h.NewItemResponse(tracking)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-tracking-pause"
+		op.Summary = "暂停 AI 助手状态检测"
+		op.Description = "手动接管终端做与 AI 无关的操作时暂停检测，状态保持暂停前的值，期间不会产生状态变化与完成通知；重复暂停无副作用"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/tracking/resume", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.ItemResponse[terminal.AssistantTracking], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		tracking, err := session.SetAssistantTrackingPaused(false)
+		if err != nil {
+			if errors.Is(err, terminal.ErrNoAssistantTracked) {
+				return nil, huma.Error409Conflict(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to update assistant tracking", err)
+		}
+		resp := h.NewItemResponse(tracking)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-tracking-resume"
+		op.Summary = "恢复 AI 助手状态检测"
+		op.Description = "重新开始检测并重置稳定性计数，屏幕按当前内容重新判断；未暂停时无副作用"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/macros", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.ItemsResponse[terminal.InputMacro], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		resp := h.NewItemsResponse(session.Macros())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-macros"
+		op.Summary = "获取会话可用的输入宏"
+		op.Description = "会话宏在前，配置中的全局宏在后"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/macros", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Body      struct {
+				Macros []terminal.InputMacro `json:"macros" doc:"会话级输入宏，覆盖已有的会话宏"`
+			} `json:"body"`
+		},
+	) (*h.ItemsResponse[terminal.InputMacro], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		if err := session.SetMacros(input.Body.Macros); err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		resp := h.NewItemsResponse(session.Macros())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-macros-update"
+		op.Summary = "设置会话级输入宏"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/macro/{name}", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Name      string `path:"name"`
+		},
+	) (*h.ItemResponse[terminal.InputMacro], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		macro, err := session.RunMacro(input.Name)
+		if err != nil {
+			if errors.Is(err, terminal.ErrMacroNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			if errors.Is(err, terminal.ErrSessionReadOnly) {
+				return nil, huma.Error409Conflict(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to run macro", err)
+		}
+		resp := h.NewItemResponse(macro)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-macro-run"
+		op.Summary = "执行输入宏"
+		op.Description = "在会话中一次性写入宏的输入；限定助手类型的宏优先于通用宏，会话宏优先于全局宏"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/quota", func(
+		ctx context.Context,
+		input *struct {
+			Key string `query:"key" doc:"配额聚合维度，如用户ID"`
+		},
+	) (*h.ItemResponse[terminal.QuotaUsage], error) {
+		key := strings.TrimSpace(input.Key)
+		if key == "" {
+			return nil, huma.Error400BadRequest("key is required")
+		}
+		resp := h.NewItemResponse(c.manager.QuotaUsage(key))
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-quota-usage"
+		op.Summary = "查询终端资源配额用量"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/debug", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.ItemResponse[terminal.DebugInfo], error) {
+		debugInfo, err := c.manager.GetSessionDebugInfo(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to get debug info", err)
+		}
+		resp := h.NewItemResponse(*debugInfo)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-debug"
+		op.Summary = "获取终端调试信息（包含完整输出内容）"
+		op.Tags = []string{terminalTag}
+		op.Description = "用于调试，返回终端的 scrollback 缓冲区内容、AI 助手状态、录制信息等"
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/debug/raw", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Bytes     int    `query:"bytes" minimum:"0" maximum:"65536" doc:"返回最近多少字节，0 表示全部保留内容"`
+		},
+	) (*h.ItemResponse[terminal.RawOutputSnapshot], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		resp := h.NewItemResponse(session.RawOutput(input.Bytes))
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-debug-raw"
+		op.Summary = "获取终端最近的原始 PTY 输出"
+		op.Description = "返回解码前的原始字节与按当前编码解码后的结果（均为 base64），用于定位乱码来源。需先通过 developer.captureRawOutput 或 debug/raw-capture 开启采集，每个会话最多保留 64KB"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/debug/raw-capture", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Body      struct {
+				Enabled bool `json:"enabled" doc:"是否采集原始 PTY 输出，切换时清空已采集内容"`
+			}
+		},
+	) (*h.MessageResponse, error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		session.SetRawCapture(input.Body.Enabled)
+		resp := h.NewMessageResponse("raw output capture updated")
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-debug-raw-capture"
+		op.Summary = "开关单个终端的原始输出采集"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/log-level", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Body      struct {
+				Level string `json:"level" doc:"会话日志级别：debug、info、warn、error；留空或 default 恢复全局级别"`
+			}
+		},
+	) (*h.MessageResponse, error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		if err := session.SetLogLevel(input.Body.Level); err != nil {
+			if errors.Is(err, terminal.ErrInvalidLogLevel) {
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to set log level", err)
+		}
+		resp := h.NewMessageResponse("session log level updated")
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-log-level"
+		op.Summary = "调整单个终端的日志级别"
+		op.Description = "只影响该会话的日志，不改变全局级别；设为 debug 时会记录 PTY 读取、事件广播与 AI 状态识别的细节，排查结束后设为 default 恢复"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/output-sink", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.ItemResponse[*terminal.OutputSinkInfo], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		resp := h.NewItemResponse(session.OutputSink())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-output-sink"
+		op.Summary = "查看终端的输出旁路"
+		op.Description = "返回当前接收会话输出的外部 socket 或命名管道及已写出字节数、因对方读取过慢丢弃的分片数；未设置或已因写入失败断开时 item 为 null"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/output-sink", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Body      struct {
+				Target string `json:"target" doc:"外部程序监听的 unix socket 或命名管道（FIFO）的绝对路径，Windows 可用 \\\\.\\pipe\\ 开头的命名管道"`
+			}
+		},
+	) (*h.ItemResponse[*terminal.OutputSinkInfo], error) {
+		info, err := c.manager.SetOutputSink(input.SessionID, input.Body.Target)
+		if err != nil {
+			switch {
+			case errors.Is(err, terminal.ErrSessionNotFound):
+				return nil, huma.Error404NotFound(err.Error())
+			case errors.Is(err, terminal.ErrInvalidOutputSink):
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+			return nil, huma.Error400BadRequest("failed to connect output sink: " + err.Error())
+		}
+		resp := h.NewItemResponse(info)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-output-sink-set"
+		op.Summary = "把终端输出旁路到外部程序"
+		op.Description = "会话输出（含 ANSI 控制序列，与 data 事件相同）在广播的同时实时写入指定的 socket 或命名管道，替换已有的旁路；对方读取过慢时丢弃分片，写入失败或超时 5 秒自动断开，不影响会话本身"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/output-sink/delete", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.MessageResponse, error) {
+		removed, err := c.manager.RemoveOutputSink(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to remove output sink", err)
+		}
+		message := "output sink removed"
+		if !removed {
+			message = "no output sink attached"
+		}
+		resp := h.NewMessageResponse(message)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-output-sink-delete"
+		op.Summary = "断开终端的输出旁路"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/clear-before-send", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Body      struct {
+				Enabled bool `json:"enabled" doc:"是否在每次提交输入前先发送 Ctrl+L 清屏"`
+			}
+		},
+	) (*h.MessageResponse, error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		session.SetClearBeforeSend(input.Body.Enabled)
+		resp := h.NewMessageResponse("clear before send updated")
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-clear-before-send"
+		op.Summary = "开关终端的发送前清屏"
+		op.Description = "开启后通过 WebSocket 提交的输入（包含回车）会先发送 Ctrl+L 清屏；单条 input 消息也可以用 clear 字段临时开启"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Put(group, "/terminals/{sessionId}/annotations", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Body      struct {
+				Annotations map[string]string `json:"annotations" doc:"完整的自定义键值，覆盖已有内容；最多 64 个键，键不超过 128 字节，值不超过 4096 字节"`
+			}
+		},
+	) (*h.ItemResponse[map[string]string], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		annotations, err := session.SetAnnotations(input.Body.Annotations)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		return newAnnotationsResponse(annotations), nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-annotations-set"
+		op.Summary = "设置会话的自定义键值"
+		op.Description = "供插件或上层集成给会话附加任意数据（如外部 ticket ID），后端不解释内容，随会话列表返回，会话关闭后丢弃"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Patch(group, "/terminals/{sessionId}/annotations", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Body      struct {
+				Annotations map[string]*string `json:"annotations" doc:"要修改的键值，值为 null 表示删除该键，未列出的键保持不变"`
+			}
+		},
+	) (*h.ItemResponse[map[string]string], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		annotations, err := session.PatchAnnotations(input.Body.Annotations)
+		if err != nil {
+			return nil, huma.Error400BadRequest(err.Error())
+		}
+		return newAnnotationsResponse(annotations), nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-annotations-patch"
+		op.Summary = "增量修改会话的自定义键值"
+		op.Description = "按 JSON merge patch 的方式合并，修改后超出数量或大小限制时整体不生效"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/diagnostics", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.ItemResponse[terminal.SessionDiagnostics], error) {
+		diagnostics, err := c.manager.GetSessionDiagnostics(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to get diagnostics", err)
+		}
+		resp := h.NewItemResponse(*diagnostics)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-diagnostics"
+		op.Summary = "获取终端会话诊断信息"
+		op.Tags = []string{terminalTag}
+		op.Description = "返回订阅者积压、广播/丢弃计数、AI 检测队列、输出行数与屏幕非空行数统计，不含终端输出内容，用于定位慢订阅者导致的卡顿"
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/screenshots", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+		},
+	) (*h.ItemsResponse[terminal.Screenshot], error) {
+		shots, err := c.manager.ListScreenshots(input.SessionID)
+		if err != nil {
+			switch {
+			case errors.Is(err, terminal.ErrSessionNotFound):
+				return nil, huma.Error404NotFound(err.Error())
+			case errors.Is(err, terminal.ErrScreenshotsDisabled):
+				return nil, huma.Error409Conflict(err.Error())
+			default:
+				return nil, huma.Error500InternalServerError("failed to list screenshots", err)
+			}
+		}
+		resp := h.NewItemsResponse(shots)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-screenshots"
+		op.Summary = "查看终端屏幕快照历史"
+		op.Tags = []string{terminalTag}
+		op.Description = "按时间顺序返回定时归档的屏幕文本快照，会话关闭后仍可查看；需在配置中开启 terminal.screenshots"
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/input-history", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Query     string `query:"q" doc:"按子串过滤，不区分大小写"`
+			Limit     int    `query:"limit" doc:"最多返回条数，0 表示全部" minimum:"0" maximum:"500"`
+		},
+	) (*h.ItemsResponse[terminal.InputHistoryEntry], error) {
+		entries, err := c.manager.InputHistory(input.SessionID, input.Query, input.Limit)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to get input history", err)
+		}
+		resp := h.NewItemsResponse(entries)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-input-history"
+		op.Summary = "查看终端会话的输入历史"
+		op.Tags = []string{terminalTag}
+		op.Description = "按时间倒序返回用户在该会话中按回车提交的命令或 prompt，关闭回显时的输入（如密码）与以空格开头的输入不记录；历史随会话保存，最多保留 500 条"
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/complete", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Prefix    string `query:"prefix" doc:"输入框中已输入的内容"`
+			Limit     int    `query:"limit" doc:"最多返回条数，0 使用默认值 50" minimum:"0" maximum:"200"`
+		},
+	) (*h.ItemsResponse[terminal.CompletionCandidate], error) {
+		candidates, err := c.manager.Complete(input.SessionID, input.Prefix, input.Limit)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to complete input", err)
+		}
+		resp := h.NewItemsResponse(candidates)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-complete"
+		op.Summary = "获取终端输入的补全候选"
+		op.Tags = []string{terminalTag}
+		op.Description = "依次返回以 / 开头时匹配的 AI 助手命令、输入历史中以 prefix 开头的条目、以及把最后一个词当作路径在工作目录内匹配的文件（最多 30 个，不会越出会话的工作目录）；text 为补全后的完整输入"
+	})
+
+	huma.Post(group, "/terminals/{sessionId}/repeat-last", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Index     int    `query:"index" doc:"重跑输入历史中的第几条，0 为最近一次，顺序同 input-history" minimum:"0" maximum:"499"`
+		},
+	) (*h.ItemResponse[terminal.InputHistoryEntry], error) {
+		session, err := c.manager.GetSession(input.SessionID)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to load session", err)
+		}
+		entry, err := session.RepeatInput(input.Index)
+		if err != nil {
+			if errors.Is(err, terminal.ErrInputHistoryEntryNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to repeat input", err)
+		}
+		resp := h.NewItemResponse(entry)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-repeat-last"
+		op.Summary = "再次运行上次的输入"
+		op.Tags = []string{terminalTag}
+		op.Description = "重新提交输入历史中的一条命令或 prompt（默认最近一次），返回被重跑的条目；关闭回显时的输入不在历史中，无法重跑"
+	})
+
+	huma.Get(group, "/terminals/{sessionId}/capture", func(
+		ctx context.Context,
+		input *struct {
+			SessionID string `path:"sessionId"`
+			Timeout   int    `query:"timeout" doc:"超时时间（秒），默认为 2 秒，0 表示使用默认值" minimum:"0" maximum:"10"`
+		},
+	) (*h.ItemResponse[terminal.CapturedChunk], error) {
+		timeout := 2 * time.Second
+		if input.Timeout > 0 {
+			timeout = time.Duration(input.Timeout) * time.Second
+		}
+
+		chunk, err := c.manager.CaptureChunk(ctx, input.SessionID, timeout)
+		if err != nil {
+			if errors.Is(err, terminal.ErrSessionNotFound) {
+				return nil, huma.Error404NotFound(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to capture chunk", err)
+		}
+		resp := h.NewItemResponse(*chunk)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-session-capture-chunk"
+		op.Summary = "触发 resize 并捕获下一个输出 chunk"
+		op.Tags = []string{terminalTag}
+		op.Description = "发送一个 resize 命令给终端，然后捕获并返回接下来的第一个输出 chunk，用于调试和测试"
+	})
+
+	// 完成记录相关 API
+	huma.Get(group, "/terminals/completion-records", func(
+		ctx context.Context,
+		input *struct{},
+	) (*h.ItemsResponse[*terminal.CompletionRecord], error) {
+		records := c.manager.GetRecordManager().GetCompletions()
+		resp := h.NewItemsResponse(records)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-completion-records-list"
+		op.Summary = "获取所有未关闭的完成记录"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/approval-records", func(
+		ctx context.Context,
+		input *struct{},
+	) (*h.ItemsResponse[*terminal.ApprovalRecord], error) {
+		records := c.manager.GetRecordManager().GetApprovals()
+		resp := h.NewItemsResponse(records)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-approval-records-list"
+		op.Summary = "获取所有未关闭的审批记录"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/terminals/alert-records", func(
+		ctx context.Context,
+		input *struct{},
+	) (*h.ItemsResponse[*terminal.AlertRecord], error) {
+		records := c.manager.GetRecordManager().GetAlerts()
+		resp := h.NewItemsResponse(records)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-alert-records-list"
+		op.Summary = "获取所有未关闭的告警记录"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Get(group, "/notifications/summary", func(
+		ctx context.Context,
+		input *struct{},
+	) (*h.ItemResponse[*terminal.RecordSummary], error) {
+		resp := h.NewItemResponse(c.manager.GetRecordManager().Summary())
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "notification-summary"
+		op.Summary = "获取通知分组统计"
+		op.Description = "按项目和助手类型统计未关闭的完成、审批和运行中记录"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/completion-records/{recordId}/dismiss", func(
+		ctx context.Context,
+		input *struct {
+			RecordID string `path:"recordId"`
+		},
+	) (*h.MessageResponse, error) {
+		if !c.manager.DismissCompletion(input.RecordID) {
+			return nil, huma.Error404NotFound("record not found")
+		}
+		resp := h.NewMessageResponse("record dismissed")
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-completion-record-dismiss"
+		op.Summary = "关闭完成记录"
+		op.Tags = []string{terminalTag}
+	})
+
+	huma.Post(group, "/terminals/completion-records/{recordId}/update", func(
+		ctx context.Context,
+		input *struct {
+			RecordID string `path:"recordId"`
+			Body     updateCompletionNoteBody
+		},
+	) (*h.ItemResponse[*terminal.CompletionRecord], error) {
+		record, err := c.manager.UpdateCompletionNote(input.RecordID, input.Body.Note, input.Body.Tags)
+		if err != nil {
+			switch {
+			case errors.Is(err, terminal.ErrRecordNotFound):
+				return nil, huma.Error404NotFound(err.Error())
+			case errors.Is(err, terminal.ErrInvalidRecordNote):
+				return nil, huma.Error400BadRequest(err.Error())
+			}
+			return nil, huma.Error500InternalServerError("failed to update record", err)
+		}
+		resp := h.NewItemResponse(record)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "terminal-completion-record-update"
+		op.Summary = "更新完成记录的备注和标签"
+		op.Description = "省略的字段保持不变，tags 传空数组清空标签。备注会落库，记录被替换或服务重启后仍可在备注历史中查到；关闭带备注的记录时备注同时写入项目活动"
 		op.Tags = []string{terminalTag}
-		op.Description = "发送一个 resize 命令给终端，然后捕获并返回接下来的第一个输出 chunk，用于调试和测试"
 	})
 
-	// 完成记录相关 API
-	huma.Get(group, "/terminals/completion-records", func(
+	huma.Get(group, "/terminals/completion-notes", func(
 		ctx context.Context,
-		input *struct{},
-	) (*h.ItemsResponse[*terminal.CompletionRecord], error) {
-		records := c.manager.GetRecordManager().GetCompletions()
-		resp := h.NewItemsResponse(records)
+		input *struct {
+			ProjectID string `query:"projectId" doc:"只列出指定项目的备注"`
+			Limit     int    `query:"limit" minimum:"0" maximum:"1000" doc:"返回的最大条数，默认 100"`
+		},
+	) (*h.ItemsResponse[tables.CompletionNoteTable], error) {
+		notes, err := (&model.CompletionNoteService{}).ListCompletionNotes(ctx, input.ProjectID, input.Limit)
+		if err != nil {
+			if errors.Is(err, model.ErrDBNotInitialized) {
+				return nil, huma.Error503ServiceUnavailable("database is not initialized")
+			}
+			return nil, huma.Error500InternalServerError("failed to load completion notes", err)
+		}
+		resp := h.NewItemsResponse(notes)
 		resp.Status = http.StatusOK
 		return resp, nil
 	}, func(op *huma.Operation) {
-		op.OperationID = "terminal-completion-records-list"
-		op.Summary = "获取所有未关闭的完成记录"
+		op.OperationID = "terminal-completion-notes"
+		op.Summary = "完成记录备注历史"
+		op.Description = "按更新时间倒序列出用户为完成记录添加的备注与标签（标签每行一个），包括已关闭和已被替换的记录"
 		op.Tags = []string{terminalTag}
 	})
 
-	huma.Get(group, "/terminals/approval-records", func(
+	huma.Post(group, "/terminals/approval-records/{recordId}/dismiss", func(
 		ctx context.Context,
-		input *struct{},
-	) (*h.ItemsResponse[*terminal.ApprovalRecord], error) {
-		records := c.manager.GetRecordManager().GetApprovals()
-		resp := h.NewItemsResponse(records)
+		input *struct {
+			RecordID string `path:"recordId"`
+		},
+	) (*h.MessageResponse, error) {
+		if !c.manager.GetRecordManager().DismissApproval(input.RecordID) {
+			return nil, huma.Error404NotFound("record not found")
+		}
+		resp := h.NewMessageResponse("record dismissed")
 		resp.Status = http.StatusOK
 		return resp, nil
 	}, func(op *huma.Operation) {
-		op.OperationID = "terminal-approval-records-list"
-		op.Summary = "获取所有未关闭的审批记录"
+		op.OperationID = "terminal-approval-record-dismiss"
+		op.Summary = "关闭审批记录"
 		op.Tags = []string{terminalTag}
 	})
 
-	huma.Post(group, "/terminals/completion-records/{recordId}/dismiss", func(
+	huma.Post(group, "/notifications/approvals/{recordId}/respond", func(
 		ctx context.Context,
 		input *struct {
 			RecordID string `path:"recordId"`
+			Body     struct {
+				Action string `json:"action" enum:"approve,reject" doc:"approve 选择 Yes 并确认，reject 按 ESC 拒绝"`
+			}
 		},
-	) (*h.MessageResponse, error) {
-		if !c.manager.GetRecordManager().DismissCompletion(input.RecordID) {
-			return nil, huma.Error404NotFound("record not found")
+	) (*h.ItemResponse[terminal.ApprovalRecord], error) {
+		record, err := c.manager.RespondApproval(input.RecordID, input.Body.Action == "approve")
+		if err != nil {
+			switch {
+			case errors.Is(err, terminal.ErrRecordNotFound):
+				return nil, huma.Error404NotFound("record not found")
+			case errors.Is(err, terminal.ErrSessionNotFound):
+				return nil, huma.Error404NotFound("session not found")
+			case errors.Is(err, terminal.ErrNoAssistantTracked),
+				errors.Is(err, terminal.ErrNotAwaitingApproval),
+				errors.Is(err, terminal.ErrApprovalPromptUnrecognized),
+				errors.Is(err, terminal.ErrApprovalPromptChanged):
+				return nil, huma.Error409Conflict(err.Error())
+			default:
+				return nil, huma.Error500InternalServerError("failed to respond approval", err)
+			}
 		}
-		resp := h.NewMessageResponse("record dismissed")
+		resp := h.NewItemResponse(*record)
 		resp.Status = http.StatusOK
 		return resp, nil
 	}, func(op *huma.Operation) {
-		op.OperationID = "terminal-completion-record-dismiss"
-		op.Summary = "关闭完成记录"
+		op.OperationID = "notification-approval-respond"
+		op.Summary = "直接批准或拒绝审批"
+		op.Description = "向审批记录关联的终端写入助手审批菜单对应的按键，成功后关闭记录。助手不在审批态、无法识别菜单或屏幕上已换成另一条审批提示时返回 409"
 		op.Tags = []string{terminalTag}
 	})
 
-	huma.Post(group, "/terminals/approval-records/{recordId}/dismiss", func(
+	huma.Post(group, "/terminals/alert-records/{recordId}/dismiss", func(
 		ctx context.Context,
 		input *struct {
 			RecordID string `path:"recordId"`
 		},
 	) (*h.MessageResponse, error) {
-		if !c.manager.GetRecordManager().DismissApproval(input.RecordID) {
+		if !c.manager.GetRecordManager().DismissAlert(input.RecordID) {
 			return nil, huma.Error404NotFound("record not found")
 		}
 		resp := h.NewMessageResponse("record dismissed")
 		resp.Status = http.StatusOK
 		return resp, nil
 	}, func(op *huma.Operation) {
-		op.OperationID = "terminal-approval-record-dismiss"
-		op.Summary = "关闭审批记录"
+		op.OperationID = "terminal-alert-record-dismiss"
+		op.Summary = "关闭告警记录"
 		op.Tags = []string{terminalTag}
 	})
 }
@@ -397,6 +1891,16 @@ func (c *terminalController) handleCreate(ctx context.Context, input *terminalCr
 	if worktree.ProjectId != input.ProjectID {
 		return nil, huma.Error404NotFound("worktree does not belong to project")
 	}
+	if err := c.projectSvc.EnsureProjectActive(ctx, input.ProjectID); err != nil {
+		switch {
+		case errors.Is(err, model.ErrProjectArchived):
+			return nil, huma.Error409Conflict(err.Error())
+		case errors.Is(err, model.ErrProjectNotFound):
+			return nil, huma.Error404NotFound("project not found")
+		default:
+			return nil, huma.Error500InternalServerError("failed to fetch project", err)
+		}
+	}
 
 	workingDir, err := c.resolveWorkingDir(worktree.Path, strings.TrimSpace(input.Body.WorkingDir))
 	if err != nil {
@@ -422,6 +1926,34 @@ func (c *terminalController) handleCreate(ctx context.Context, input *terminalCr
 		title = fmt.Sprintf("%s 终端", worktree.BranchName)
 	}
 
+	idleTimeout, err := parseIdleTimeoutOverride(input.Body.IdleTimeout)
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	var maxLifetime time.Duration
+	if raw := strings.TrimSpace(input.Body.MaxLifetime); raw != "" {
+		maxLifetime, err = time.ParseDuration(raw)
+		if err != nil || maxLifetime < 0 {
+			return nil, huma.Error400BadRequest("invalid maxLifetime")
+		}
+	}
+
+	source, err := resolveSessionSource(input.Body.Source, input.Origin)
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	restartPolicy, err := terminal.ParseRestartPolicy(input.Body.RestartPolicy)
+	if err != nil {
+		return nil, huma.Error400BadRequest(err.Error())
+	}
+
+	idempotencyKey := strings.TrimSpace(input.IdempotencyKey)
+	if idempotencyKey == "" {
+		idempotencyKey = strings.TrimSpace(input.Body.ClientRequestID)
+	}
+
 	rows := input.Body.Rows
 	if rows <= 0 {
 		rows = 24
@@ -439,11 +1971,37 @@ func (c *terminalController) handleCreate(ctx context.Context, input *terminalCr
 		Rows:       rows,
 		Cols:       cols,
 		TaskID:     taskID,
+
+		IdleTimeoutOverride: idleTimeout,
+		WorktreeRoot:        worktree.Path,
+		MaxLifetime:         maxLifetime,
+		QuotaKey:            input.Body.QuotaKey,
+		Term:                input.Body.Term,
+		TermEnv:             input.Body.TermEnv,
+		IdempotencyKey:      idempotencyKey,
+		RestartPolicy:       restartPolicy,
+		MaxRestarts:         input.Body.MaxRestarts,
+		ShellName:           input.Body.ShellName,
+		ClearBeforeSend:     input.Body.ClearBeforeSend,
+		Nice:                input.Body.Nice,
+		ProfileID:           input.Body.ProfileID,
+		ReadOnly:            input.Body.ReadOnly,
+		TrackOnly:           input.Body.TrackOnly,
+		Source:              source,
 	})
 	if err != nil {
 		switch {
-		case errors.Is(err, terminal.ErrSessionLimitReached):
+		case errors.Is(err, terminal.ErrInvalidTermEnv),
+			errors.Is(err, terminal.ErrUnknownShell),
+			errors.Is(err, terminal.ErrUnknownProfile):
+			return nil, huma.Error400BadRequest(err.Error())
+		case errors.Is(err, terminal.ErrShellNotFound):
+			return nil, huma.Error400BadRequest("terminal shell is unavailable, fix terminal.shell in the config file: " + err.Error())
+		case errors.Is(err, terminal.ErrSessionLimitReached),
+			errors.Is(err, terminal.ErrQuotaExceeded):
 			return nil, huma.Error429TooManyRequests(err.Error())
+		case errors.Is(err, model.ErrProjectArchived):
+			return nil, huma.Error409Conflict(err.Error())
 		default:
 			return nil, huma.Error500InternalServerError("failed to create terminal session", err)
 		}
@@ -453,6 +2011,62 @@ func (c *terminalController) handleCreate(ctx context.Context, input *terminalCr
 	return &view, nil
 }
 
+// handleBatchCreate creates one session per worktree in order and reports each outcome.
+func (c *terminalController) handleBatchCreate(ctx context.Context, input *terminalBatchCreateInput) []terminalBatchCreateResult {
+	results := make([]terminalBatchCreateResult, 0, len(input.Body.WorktreeIDs))
+	seen := make(map[string]struct{}, len(input.Body.WorktreeIDs))
+	for _, rawID := range input.Body.WorktreeIDs {
+		worktreeID := strings.TrimSpace(rawID)
+		result := terminalBatchCreateResult{WorktreeID: worktreeID}
+		if _, ok := seen[worktreeID]; ok || worktreeID == "" {
+			result.Status = http.StatusBadRequest
+			result.Error = "duplicate or empty worktree id"
+			results = append(results, result)
+			continue
+		}
+		seen[worktreeID] = struct{}{}
+
+		view, err := c.handleCreate(ctx, &terminalCreateInput{
+			ProjectID:  input.ProjectID,
+			WorktreeID: worktreeID,
+			Origin:     input.Origin,
+			Body: terminalCreateBody{
+				Title:       input.Body.Title,
+				Rows:        input.Body.Rows,
+				Cols:        input.Body.Cols,
+				IdleTimeout: input.Body.IdleTimeout,
+				MaxLifetime: input.Body.MaxLifetime,
+				QuotaKey:    input.Body.QuotaKey,
+				Source:      input.Body.Source,
+			},
+		})
+		if err != nil {
+			result.Status = http.StatusInternalServerError
+			var statusErr huma.StatusError
+			if errors.As(err, &statusErr) {
+				result.Status = statusErr.GetStatus()
+			}
+			result.Error = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.Status = http.StatusCreated
+		result.Session = view
+		if command := strings.TrimSpace(input.Body.InitCommand); command != "" {
+			if session, err := c.manager.GetSession(view.ID); err == nil {
+				if err := session.WriteInput([]byte(command + "\r")); err != nil {
+					c.logger.Warn("failed to run init command for batch terminal",
+						zap.String("sessionId", view.ID),
+						zap.Error(err))
+				}
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
 func (c *terminalController) serveWebsocket(w http.ResponseWriter, r *http.Request) {
 	sessionID := r.URL.Query().Get("sessionId")
 	if sessionID == "" {
@@ -460,6 +2074,13 @@ func (c *terminalController) serveWebsocket(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	query := r.URL.Query()
+	opts, err := parseWSAttachOptions(query.Get("events"), query.Get("metadata") == "patch", query.Get("sinceSeq"), query.Get("tail"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	session, err := c.manager.GetSession(sessionID)
 	if err != nil {
 		http.Error(w, "session not found", http.StatusNotFound)
@@ -483,52 +2104,133 @@ func (c *terminalController) serveWebsocket(w http.ResponseWriter, r *http.Reque
 		return conn.WriteJSON(msg)
 	}
 
+	stream, lastSeq, err := c.attachSession(ctx, session, opts, send)
+	if err != nil || stream == nil {
+		return
+	}
+
+	go c.forwardPTY(ctx, session, stream, opts.metadataPatch, lastSeq, send)
+	input := &wsInputHandler{c: c, session: session, send: send}
+	for {
+		_, payload, err := conn.ReadMessage()
+		if err != nil {
+			if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
+				c.logger.Debug("websocket read error", zap.Error(err))
+			}
+			return
+		}
+		var msg wsMessage
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			continue
+		}
+		if !input.handle(msg) {
+			return
+		}
+	}
+}
+
+// wsAttachOptions 选择订阅的事件类型与回放范围
+type wsAttachOptions struct {
+	// eventTypes 只订阅指定类型的事件，为空订阅全部，纯监控客户端不接收 data 流
+	eventTypes []terminal.StreamEventType
+	// metadataPatch 首条 metadata 发全量，之后只推送 metadata-patch 差量
+	metadataPatch bool
+	// sinceSeq 只回放该序号之后的分片（断线重连），tail 只回放最近 N 个分片，
+	// 更早的历史由客户端通过 scrollback 接口按游标向前拉取
+	sinceSeq int64
+	tail     int
+}
+
+func parseWSAttachOptions(events string, metadataPatch bool, sinceSeq, tail string) (wsAttachOptions, error) {
+	eventTypes, err := terminal.ParseStreamEventTypes(events)
+	if err != nil {
+		return wsAttachOptions{}, err
+	}
+	opts := wsAttachOptions{eventTypes: eventTypes, metadataPatch: metadataPatch}
+	opts.sinceSeq, _ = strconv.ParseInt(sinceSeq, 10, 64)
+	opts.tail, _ = strconv.Atoi(tail)
+	return opts, nil
+}
+
+// attachSession sends ready and the scrollback replay of session. It returns
+// the stream to forward together with the last replayed sequence number, or a
+// nil stream when the session already ended and exit was sent. The stream is
+// released when ctx is cancelled.
+func (c *terminalController) attachSession(ctx context.Context, session *terminal.Session, opts wsAttachOptions, send func(wsMessage) error) (*terminal.SessionStream, int64, error) {
 	status := session.Status()
+	closed := status == terminal.SessionStatusClosed || status == terminal.SessionStatusError
+	// 先订阅再取回放内容，两步之间产生的输出同时出现在两边，重复部分由 forwardPTY 按序号跳过；
+	// 提前返回时订阅随 ctx 取消释放
+	var stream *terminal.SessionStream
+	if !closed {
+		var err error
+		stream, err = session.Subscribe(ctx, opts.eventTypes...)
+		if err != nil {
+			c.logger.Warn("failed to subscribe session stream", zap.Error(err))
+			_ = send(wsMessage{Type: "error", Data: "failed to attach terminal stream"})
+			return nil, 0, err
+		}
+	}
+	if err := send(wsMessage{Type: "ready", Data: string(status)}); err != nil {
+		return nil, 0, err
+	}
 
-	if err := send(wsMessage{
-		Type: "ready",
-		Data: string(status),
-	}); err != nil {
-		return
+	var scrollback []terminal.ScrollbackChunk
+	gap := false
+	// 未订阅 data 时不回放历史输出
+	if len(opts.eventTypes) == 0 || slices.Contains(opts.eventTypes, terminal.StreamEventData) {
+		if opts.sinceSeq > 0 {
+			scrollback, gap = session.ScrollbackResume(opts.sinceSeq)
+		} else {
+			scrollback = session.ScrollbackBefore(0, opts.tail)
+		}
 	}
 
-	scrollback := session.Scrollback()
+	// 续传的起点已被裁剪时先通知客户端，seq 为实际续传的第一个分片
+	if gap && len(scrollback) > 0 {
+		if err := send(wsMessage{Type: "scrollback-gap", Seq: scrollback[0].Seq}); err != nil {
+			return nil, 0, err
+		}
+	}
+	lastSeq := int64(0)
+	lines := 0
 	for _, chunk := range scrollback {
-		if len(chunk) == 0 {
+		if len(chunk.Data) == 0 {
 			continue
 		}
-		encoded := base64.StdEncoding.EncodeToString(chunk)
-		if err := send(wsMessage{Type: "data", Data: encoded}); err != nil {
-			return
+		encoded := base64.StdEncoding.EncodeToString(chunk.Data)
+		if err := send(wsMessage{Type: "data", Data: encoded, Seq: chunk.Seq}); err != nil {
+			return nil, 0, err
 		}
+		lastSeq = chunk.Seq
+		lines += bytes.Count(chunk.Data, []byte{'\n'})
+	}
+	// 回放结束，之后的 data 都是实时输出，客户端据此恢复滚动位置
+	if err := send(wsMessage{Type: "viewport-synced", Seq: lastSeq, Lines: lines}); err != nil {
+		return nil, 0, err
 	}
 
-	if status == terminal.SessionStatusClosed || status == terminal.SessionStatusError {
+	if closed {
 		message := "session closed"
 		if err := session.Err(); err != nil {
 			message = err.Error()
 		}
 		_ = send(wsMessage{Type: "exit", Data: message})
-		return
-	}
-
-	stream, err := session.Subscribe(ctx)
-	if err != nil {
-		c.logger.Warn("failed to subscribe session stream", zap.Error(err))
-		_ = send(wsMessage{Type: "error", Data: "failed to attach terminal stream"})
-		return
+		return nil, lastSeq, nil
 	}
-
-	go c.forwardPTY(ctx, session, stream, send)
-	c.consumeClient(ctx, session, conn, send)
+	return stream, lastSeq, nil
 }
 
-func (c *terminalController) forwardPTY(ctx context.Context, session *terminal.Session, stream *terminal.SessionStream, send func(wsMessage) error) {
+// forwardPTY relays stream events to the client. Data events whose sequence
+// number is not after lastSeq were already replayed from the scrollback.
+func (c *terminalController) forwardPTY(ctx context.Context, session *terminal.Session, stream *terminal.SessionStream, metadataPatch bool, lastSeq int64, send func(wsMessage) error) {
 	if stream == nil {
 		return
 	}
 	defer stream.Close()
 
+	var differ terminal.MetadataDiffer
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -539,11 +2241,16 @@ func (c *terminalController) forwardPTY(ctx context.Context, session *terminal.S
 			}
 			switch event.Type {
 			case terminal.StreamEventData:
-				if len(event.Data) == 0 {
+				if len(event.Data) == 0 || (event.Seq > 0 && event.Seq <= lastSeq) {
 					continue
 				}
 				chunk := base64.StdEncoding.EncodeToString(event.Data)
-				if writeErr := send(wsMessage{Type: "data", Data: chunk}); writeErr != nil {
+				if writeErr := send(wsMessage{
+					Type:   "data",
+					Data:   chunk,
+					Seq:    event.Seq,
+					Append: terminal.IsBottomAppend(event.Data),
+				}); writeErr != nil {
 					return
 				}
 			case terminal.StreamEventExit:
@@ -556,11 +2263,52 @@ func (c *terminalController) forwardPTY(ctx context.Context, session *terminal.S
 				_ = send(wsMessage{Type: "exit", Data: message})
 				return
 			case terminal.StreamEventMetadata:
-				if event.Metadata != nil {
-					if writeErr := send(wsMessage{Type: "metadata", Metadata: event.Metadata}); writeErr != nil {
-						return
+				if event.Metadata == nil {
+					continue
+				}
+				msg := wsMessage{Type: "metadata", Metadata: event.Metadata}
+				if metadataPatch {
+					patch, full, err := differ.Next(event.Metadata)
+					switch {
+					case err != nil:
+						// 编码失败时退回全量，并让下一条重新建立基线
+						c.logger.Warn("failed to build metadata patch", zap.Error(err))
+						differ.Reset()
+					case !full && patch == nil:
+						continue
+					case !full:
+						msg = wsMessage{Type: "metadata-patch", Patch: patch}
 					}
 				}
+				if writeErr := send(msg); writeErr != nil {
+					return
+				}
+			case terminal.StreamEventLifetimeExceeded:
+				if writeErr := send(wsMessage{Type: "lifetime-exceeded", Data: string(event.Data)}); writeErr != nil {
+					return
+				}
+			case terminal.StreamEventWorktreeMoved:
+				if writeErr := send(wsMessage{Type: "worktree-moved", Data: string(event.Data)}); writeErr != nil {
+					return
+				}
+			case terminal.StreamEventEncodingChanged:
+				// 客户端清屏后写入 data 中按新编码重新解码的 scrollback，data 为空时只需刷新后续输出
+				chunk := base64.StdEncoding.EncodeToString(event.Data)
+				if writeErr := send(wsMessage{Type: "encoding-changed", Data: chunk, Seq: session.ScrollbackLatestSeq()}); writeErr != nil {
+					return
+				}
+			case terminal.StreamEventLinks:
+				if writeErr := send(wsMessage{Type: "links", Links: event.Links}); writeErr != nil {
+					return
+				}
+			case terminal.StreamEventHighlights:
+				if writeErr := send(wsMessage{Type: "highlights", Highlights: event.Highlights}); writeErr != nil {
+					return
+				}
+			case terminal.StreamEventAlert:
+				if writeErr := send(wsMessage{Type: "alert", Alert: event.Alert}); writeErr != nil {
+					return
+				}
 			default:
 				continue
 			}
@@ -568,54 +2316,69 @@ func (c *terminalController) forwardPTY(ctx context.Context, session *terminal.S
 	}
 }
 
-func (c *terminalController) consumeClient(ctx context.Context, session *terminal.Session, conn *websocket.Conn, send func(wsMessage) error) {
-	for {
-		select {
-		case <-ctx.Done():
-			return
-		default:
-			_, payload, err := conn.ReadMessage()
-			if err != nil {
-				if !websocket.IsCloseError(err, websocket.CloseNormalClosure, websocket.CloseGoingAway) {
-					c.logger.Debug("websocket read error", zap.Error(err))
-				}
-				return
-			}
+// wsInputHandler applies client messages to one session. Multiline input
+// waiting for confirmation is kept per session, only the latest one.
+type wsInputHandler struct {
+	c       *terminalController
+	session *terminal.Session
+	send    func(wsMessage) error
 
-			var msg wsMessage
-			if err := json.Unmarshal(payload, &msg); err != nil {
-				continue
-			}
+	pendingID    string
+	pendingInput string
+	pendingClear bool
+}
 
-			switch msg.Type {
-			case "input":
-				if msg.Data == "" {
-					continue
-				}
-				if _, writeErr := session.Write([]byte(msg.Data)); writeErr != nil {
-					_ = send(wsMessage{Type: "error", Data: writeErr.Error()})
-					return
-				}
-			case "resize":
-				_ = session.Resize(msg.Cols, msg.Rows)
-			case "close":
-				_ = session.Close()
-				return
-			default:
-				continue
-			}
+// handle applies msg and reports whether the client should stay attached.
+func (h *wsInputHandler) handle(msg wsMessage) bool {
+	switch msg.Type {
+	case "input":
+		if msg.Data == "" {
+			return true
+		}
+		if h.c.manager.ConfirmMultilineInput() && terminal.IsMultilineInput(msg.Data) {
+			h.pendingID = utils.NewID()
+			h.pendingInput = msg.Data
+			h.pendingClear = msg.Clear
+			return h.send(wsMessage{Type: "confirm-required", ID: h.pendingID, Data: terminal.BuildInputPreview(msg.Data)}) == nil
+		}
+		if _, writeErr := h.session.Send([]byte(msg.Data), msg.Clear); writeErr != nil {
+			_ = h.send(wsMessage{Type: "error", Data: writeErr.Error()})
+			return errors.Is(writeErr, terminal.ErrSessionReadOnly)
+		}
+	case "confirm":
+		if h.pendingID == "" || msg.ID != h.pendingID {
+			return true
 		}
+		data, clear := h.pendingInput, h.pendingClear
+		h.pendingID, h.pendingInput, h.pendingClear = "", "", false
+		if _, writeErr := h.session.Send([]byte(data), clear); writeErr != nil {
+			_ = h.send(wsMessage{Type: "error", Data: writeErr.Error()})
+			return errors.Is(writeErr, terminal.ErrSessionReadOnly)
+		}
+	case "key":
+		if writeErr := h.session.WriteKey(msg.Key); writeErr != nil {
+			_ = h.send(wsMessage{Type: "error", Data: writeErr.Error()})
+			return errors.Is(writeErr, terminal.ErrUnknownKey) || errors.Is(writeErr, terminal.ErrSessionReadOnly)
+		}
+	case "resize":
+		_ = h.session.Resize(msg.Cols, msg.Rows)
+	case "close":
+		_ = h.session.Close()
+		return false
 	}
+	return true
 }
 
 func (c *terminalController) viewFromSnapshot(snapshot terminal.SessionSnapshot) terminalSessionView {
 	wsPath := fmt.Sprintf("%s?sessionId=%s", terminalWSPath, snapshot.ID)
-	return terminalSessionView{
+	view := terminalSessionView{
 		ID:         snapshot.ID,
 		ProjectID:  snapshot.ProjectID,
 		WorktreeID: snapshot.WorktreeID,
 		WorkingDir: snapshot.WorkingDir,
+		CurrentDir: snapshot.CurrentDir,
 		Title:      snapshot.Title,
+		OrderIndex: snapshot.OrderIndex,
 		CreatedAt:  snapshot.CreatedAt,
 		LastActive: snapshot.LastActive,
 		Status:     string(snapshot.Status),
@@ -624,6 +2387,8 @@ func (c *terminalController) viewFromSnapshot(snapshot terminal.SessionSnapshot)
 		Rows:       snapshot.Rows,
 		Cols:       snapshot.Cols,
 		Encoding:   snapshot.Encoding,
+		Term:       snapshot.Term,
+		ShellName:  snapshot.ShellName,
 		// Process information
 		ProcessPID:         snapshot.ProcessPID,
 		ProcessStatus:      snapshot.ProcessStatus,
@@ -631,7 +2396,73 @@ func (c *terminalController) viewFromSnapshot(snapshot terminal.SessionSnapshot)
 		RunningCommand:     snapshot.RunningCommand,
 		AIAssistant:        snapshot.AIAssistant,
 		TaskID:             snapshot.TaskID,
+		TaskType:           snapshot.TaskType,
+		TaskStartedAt:      snapshot.TaskStartedAt,
+		IdleTimeout:        formatIdleTimeoutOverride(snapshot.IdleTimeoutOverride),
+		TokenUsage:         snapshot.TokenUsage,
+		TurnCount:          snapshot.TurnCount,
+		InterruptedTurns:   snapshot.InterruptedTurns,
+		Annotations:        snapshot.Annotations,
+		RestartPolicy:      string(snapshot.RestartPolicy),
+		Restarts:           snapshot.Restarts,
+		ReadOnly:           snapshot.ReadOnly,
+		TrackOnly:          snapshot.TrackOnly,
+		LogLevel:           snapshot.LogLevel,
+		Source:             string(snapshot.Source),
+	}
+	if snapshot.AIAssistant != nil {
+		view.AIState = snapshot.AIAssistant.State
+	}
+	if snapshot.MaxLifetime > 0 {
+		view.MaxLifetime = snapshot.MaxLifetime.String()
+	}
+	if !snapshot.Deadline.IsZero() {
+		deadline := snapshot.Deadline
+		view.Deadline = &deadline
+	}
+	return view
+}
+
+// resolveSessionSource 解析创建来源；未指定时带 Origin 的浏览器请求视为手动创建
+func resolveSessionSource(raw, origin string) (terminal.SessionSource, error) {
+	source, err := terminal.ParseSessionSource(raw)
+	if err != nil || source != "" {
+		return source, err
+	}
+	if strings.TrimSpace(origin) != "" {
+		return terminal.SessionSourceManual, nil
+	}
+	return terminal.SessionSourceAPI, nil
+}
+
+// parseIdleTimeoutOverride 解析会话空闲超时，空字符串视为使用全局配置；
+// 不带单位的负数（如 -1）与接口返回的 never 都表示永不超时
+func parseIdleTimeoutOverride(raw string) (time.Duration, error) {
+	value := strings.TrimSpace(raw)
+	if value == "" || value == "0" {
+		return 0, nil
+	}
+	if value == "never" {
+		return -1, nil
+	}
+	if n, err := strconv.Atoi(value); err == nil && n < 0 {
+		return -1, nil
+	}
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid idleTimeout: %w", err)
+	}
+	return timeout, nil
+}
+
+func formatIdleTimeoutOverride(timeout time.Duration) string {
+	if timeout == 0 {
+		return ""
 	}
+	if timeout < 0 {
+		return "never"
+	}
+	return timeout.String()
 }
 
 func (c *terminalController) resolveWorkingDir(root, user string) (string, error) {
@@ -659,6 +2490,11 @@ func (c *terminalController) resolveWorkingDir(root, user string) (string, error
 	if !isSubPath(base, target) {
 		return "", fmt.Errorf("working directory escapes the worktree root")
 	}
+	if c.cfg != nil {
+		if err := checkWorkingDirPolicy(target, c.cfg.Terminal.DeniedWorkingDirs, c.cfg.Terminal.AllowedRoots); err != nil {
+			return "", err
+		}
+	}
 	return target, nil
 }
 
@@ -681,15 +2517,67 @@ func isSubPath(root, target string) bool {
 	return !strings.HasPrefix(rel, "..")
 }
 
+type terminalCreateBody struct {
+	WorkingDir  string `json:"workingDir" doc:"工作目录"`
+	Title       string `json:"title" doc:"终端标题"`
+	Rows        int    `json:"rows" doc:"终端行数"`
+	Cols        int    `json:"cols" doc:"终端列数"`
+	TaskID      string `json:"taskId,omitempty" doc:"要关联的任务ID"`
+	IdleTimeout string `json:"idleTimeout,omitempty" doc:"会话空闲超时（如 30m），0 或留空使用全局配置，负数（如 -1）或 never 表示永不超时"`
+	MaxLifetime string `json:"maxLifetime,omitempty" doc:"会话最大运行时长（如 2h），留空表示不限制"`
+	QuotaKey    string `json:"quotaKey,omitempty" doc:"资源配额的聚合维度（如用户ID），留空不参与配额统计"`
+	Term        string `json:"term,omitempty" doc:"TERM 环境变量（如 xterm-kitty、tmux-256color），留空使用全局配置"`
+	// TermEnv 只允许 COLORTERM、TERM_PROGRAM、TERM_PROGRAM_VERSION
+	TermEnv         map[string]string `json:"termEnv,omitempty" doc:"附加的终端能力变量，仅支持 COLORTERM、TERM_PROGRAM、TERM_PROGRAM_VERSION"`
+	ClientRequestID string            `json:"clientRequestId,omitempty" maxLength:"128" doc:"幂等请求标识，与 Idempotency-Key header 等价，header 优先"`
+	RestartPolicy   string            `json:"restartPolicy,omitempty" enum:"never,on-failure,always" doc:"shell 退出后的重启策略，留空为 never"`
+	MaxRestarts     int               `json:"maxRestarts,omitempty" minimum:"0" doc:"连续重启次数上限，0 使用默认值 5；进程稳定运行 1 分钟后重新计数"`
+	ShellName       string            `json:"shellName,omitempty" doc:"使用的命名 shell，可选值见 GET /system/shells，留空使用默认 shell"`
+	ClearBeforeSend bool              `json:"clearBeforeSend,omitempty" doc:"每次提交输入前先发送 Ctrl+L 清屏，便于识别 AI 助手状态"`
+	Nice            int               `json:"nice,omitempty" doc:"shell 进程的调度优先级（-20 到 19，越大越低），Windows 映射为优先级类；0 不调整，超出范围或平台不支持时忽略"`
+	ProfileID       string            `json:"profileId,omitempty" doc:"套用的 AI 档案，可选值见 GET /system/ai-profiles；档案中的 shell 在未指定 shellName 时生效，启动命令在会话创建后自动执行"`
+	ReadOnly        bool              `json:"readOnly,omitempty" doc:"以只读方式打开：只展示输出，拒绝键盘输入、宏与文件输入，适合查看只读 worktree"`
+	TrackOnly       bool              `json:"trackOnly,omitempty" doc:"仅跟踪 AI 状态的监控会话：不保留 scrollback，输出只转发给 events 显式包含 data 的订阅，metadata 照常广播"`
+	Source          string            `json:"source,omitempty" enum:"manual,api,playbook" doc:"创建来源，用于统计手动与自动化使用；留空时浏览器请求记为 manual，其余记为 api"`
+}
+
 type terminalCreateInput struct {
-	ProjectID  string `path:"projectId"`
-	WorktreeID string `path:"worktreeId"`
-	Body       struct {
-		WorkingDir string `json:"workingDir" doc:"工作目录"`
-		Title      string `json:"title" doc:"终端标题"`
-		Rows       int    `json:"rows" doc:"终端行数"`
-		Cols       int    `json:"cols" doc:"终端列数"`
-		TaskID     string `json:"taskId,omitempty" doc:"要关联的任务ID"`
+	ProjectID      string             `path:"projectId"`
+	WorktreeID     string             `path:"worktreeId"`
+	IdempotencyKey string             `header:"Idempotency-Key" maxLength:"128" doc:"幂等请求标识，短时间内重复提交返回同一会话"`
+	Origin         string             `header:"Origin" doc:"浏览器发起的请求会带上，未指定 source 时据此区分手动创建与接口调用"`
+	Body           terminalCreateBody `json:"body"`
+}
+
+type terminalBatchCreateInput struct {
+	ProjectID string `path:"projectId"`
+	Origin    string `header:"Origin"`
+	Body      struct {
+		WorktreeIDs []string `json:"worktreeIds" doc:"要创建终端的 worktree ID 列表" minItems:"1"`
+		Title       string   `json:"title,omitempty" doc:"终端标题，留空使用各 worktree 的分支名"`
+		Rows        int      `json:"rows,omitempty" doc:"终端行数"`
+		Cols        int      `json:"cols,omitempty" doc:"终端列数"`
+		IdleTimeout string   `json:"idleTimeout,omitempty" doc:"会话空闲超时（如 30m），0 或留空使用全局配置，负数（如 -1）或 never 表示永不超时"`
+		MaxLifetime string   `json:"maxLifetime,omitempty" doc:"会话最大运行时长（如 2h），留空表示不限制"`
+		QuotaKey    string   `json:"quotaKey,omitempty" doc:"资源配额的聚合维度（如用户ID），留空不参与配额统计"`
+		InitCommand string   `json:"initCommand,omitempty" doc:"会话创建后执行的初始化命令，会自动追加回车"`
+		Source      string   `json:"source,omitempty" enum:"manual,api,playbook" doc:"创建来源，规则同单个创建"`
+	} `json:"body"`
+}
+
+// terminalBatchCreateResult 单个 worktree 的创建结果，Session 与 Error 二选一
+type terminalBatchCreateResult struct {
+	WorktreeID string               `json:"worktreeId"`
+	Session    *terminalSessionView `json:"session,omitempty"`
+	Error      string               `json:"error,omitempty"`
+	Status     int                  `json:"status" doc:"与单个创建接口一致的 HTTP 状态码"`
+}
+
+type terminalIdleTimeoutInput struct {
+	ProjectID string `path:"projectId"`
+	SessionID string `path:"sessionId"`
+	Body      struct {
+		IdleTimeout string `json:"idleTimeout" doc:"会话空闲超时（如 30m），0 使用全局配置，负数（如 -1）或 never 表示永不超时"`
 	} `json:"body"`
 }
 
@@ -701,6 +2589,55 @@ type terminalRenameInput struct {
 	} `json:"body"`
 }
 
+type terminalMoveInput struct {
+	SessionID string `path:"sessionId"`
+	Body      struct {
+		OrderIndex float64 `json:"orderIndex" doc:"新的排序值，取相邻两个标签 orderIndex 的中间值即可插入其间"`
+	} `json:"body"`
+}
+
+type terminalEncodingInput struct {
+	SessionID string `path:"sessionId"`
+	Body      struct {
+		Encoding string `json:"encoding" doc:"utf-8、gbk、gb18030 或 gb2312"`
+		Redecode bool   `json:"redecode,omitempty" doc:"按新编码重新解码已有的 scrollback 并广播"`
+	} `json:"body"`
+}
+
+type terminalExpectInput struct {
+	SessionID string `path:"sessionId"`
+	Body      struct {
+		Pattern       string `json:"pattern" doc:"等待匹配的正则表达式（Go 语法），按渲染后的纯文本行匹配"`
+		Input         string `json:"input,omitempty" doc:"开始等待后写入的输入"`
+		AppendNewline bool   `json:"appendNewline,omitempty" doc:"在输入末尾追加回车"`
+		Timeout       int    `json:"timeout,omitempty" doc:"超时时间（秒），0 使用默认 30 秒" minimum:"0" maximum:"600"`
+	} `json:"body"`
+}
+
+type terminalExpectResult struct {
+	Line string `json:"line"`
+}
+
+type terminalToTaskInput struct {
+	SessionID string `path:"sessionId"`
+	Body      struct {
+		Status string `json:"status,omitempty" doc:"任务状态，默认 done"`
+	} `json:"body"`
+}
+
+type terminalToTaskResult struct {
+	Task    *tables.TaskTable   `json:"task"`
+	Session terminalSessionView `json:"session"`
+}
+
+// taskTagsFromDraft 以助手类型作为任务标签，便于在看板上筛选 AI 产出
+func taskTagsFromDraft(draft *terminal.SessionTaskDraft) tables.StringArray {
+	if draft.AssistantType == "" {
+		return nil
+	}
+	return tables.StringArray{draft.AssistantType}
+}
+
 type terminalTaskLinkInput struct {
 	ProjectID string `path:"projectId"`
 	SessionID string `path:"sessionId"`
@@ -709,6 +2646,19 @@ type terminalTaskLinkInput struct {
 	} `json:"body"`
 }
 
+type terminalInputFileInput struct {
+	SessionID     string `path:"sessionId"`
+	AppendNewline bool   `query:"appendNewline" default:"false" doc:"写入后是否追加回车"`
+	RawBody       huma.MultipartFormFiles[struct {
+		File huma.FormFile `form:"file" required:"true" doc:"要写入终端的文件"`
+	}]
+}
+
+type updateCompletionNoteBody struct {
+	Note *string  `json:"note,omitempty" maxLength:"1000" doc:"备注，空字符串清空"`
+	Tags []string `json:"tags,omitempty" maxItems:"10" doc:"标签，省略时保持不变"`
+}
+
 type terminalTaskUnlinkInput struct {
 	ProjectID string `path:"projectId"`
 	SessionID string `path:"sessionId"`
@@ -719,7 +2669,9 @@ type terminalSessionView struct {
 	ProjectID  string    `json:"projectId"`
 	WorktreeID string    `json:"worktreeId"`
 	WorkingDir string    `json:"workingDir"`
+	CurrentDir string    `json:"currentDir" doc:"shell 通过 OSC 7 上报的当前目录，未上报时与 workingDir 相同"`
 	Title      string    `json:"title"`
+	OrderIndex float64   `json:"orderIndex"`
 	CreatedAt  time.Time `json:"createdAt"`
 	LastActive time.Time `json:"lastActive"`
 	Status     string    `json:"status"`
@@ -728,13 +2680,87 @@ type terminalSessionView struct {
 	Rows       int       `json:"rows"`
 	Cols       int       `json:"cols"`
 	Encoding   string    `json:"encoding"`
+	Term       string    `json:"term,omitempty"`
+	ShellName  string    `json:"shellName,omitempty" doc:"命名 shell，默认 shell 时为空"`
 	// Process information
 	ProcessPID         int32                          `json:"processPid,omitempty"`
 	ProcessStatus      string                         `json:"processStatus,omitempty"`
 	ProcessHasChildren bool                           `json:"processHasChildren,omitempty"`
 	RunningCommand     string                         `json:"runningCommand,omitempty"`
 	AIAssistant        *ai_assistant2.AIAssistantInfo `json:"aiAssistant,omitempty"`
+	AIState            string                         `json:"aiState,omitempty" doc:"AI 助手状态，等同 aiAssistant.state，便于只选择该字段"`
 	TaskID             string                         `json:"taskId,omitempty"`
+	TaskType           string                         `json:"taskType,omitempty" doc:"非 AI 长任务类型，如 build"`
+	TaskStartedAt      *time.Time                     `json:"taskStartedAt,omitempty"`
+	IdleTimeout        string                         `json:"idleTimeout,omitempty"`
+	MaxLifetime        string                         `json:"maxLifetime,omitempty"`
+	Deadline           *time.Time                     `json:"deadline,omitempty"`
+	TokenUsage         *terminal.TokenUsage           `json:"tokenUsage,omitempty" doc:"AI 助手累计 token 用量，配置了模型价格时带估算成本"`
+	TurnCount          int                            `json:"turnCount,omitempty" doc:"AI 助手本次运行完成的对话轮次（working→waiting_input），不含被打断的轮次"`
+	InterruptedTurns   int                            `json:"interruptedTurns,omitempty" doc:"被用户打断的对话轮次"`
+	Annotations        map[string]string              `json:"annotations,omitempty" doc:"通过 annotations 接口附加的自定义键值"`
+	RestartPolicy      string                         `json:"restartPolicy,omitempty"`
+	Restarts           int64                          `json:"restarts,omitempty" doc:"shell 进程已自动重启的次数"`
+	ReadOnly           bool                           `json:"readOnly,omitempty" doc:"只读会话，只展示输出不接受输入"`
+	TrackOnly          bool                           `json:"trackOnly,omitempty" doc:"仅跟踪 AI 状态，不保留 scrollback"`
+	LogLevel           string                         `json:"logLevel,omitempty" doc:"会话级日志级别覆盖，为空时沿用全局级别"`
+	Source             string                         `json:"source,omitempty" doc:"创建来源：manual、api、clone 或 playbook"`
+}
+
+type terminalScrollbackInput struct {
+	SessionID string `path:"sessionId"`
+	Since     int64  `query:"since" doc:"返回序号大于该值的分片（增量获取）"`
+	Before    int64  `query:"before" doc:"返回序号小于该值的分片（向前翻页），与 since 互斥"`
+	Offset    int    `query:"offset" minimum:"0" doc:"指定时按逻辑行分页，从第几行开始"`
+	Limit     int    `query:"limit" default:"200" doc:"向前翻页时的最大分片数，按逻辑行分页时为本页行数"`
+
+	byLines bool
+}
+
+// Resolve 记录请求是否带了 offset，offset=0 也表示按逻辑行分页
+func (i *terminalScrollbackInput) Resolve(ctx huma.Context) []error {
+	i.byLines = ctx.Query("offset") != ""
+	return nil
+}
+
+type terminalScrollbackPage struct {
+	Chunks    []terminal.ScrollbackChunk `json:"chunks"`
+	LatestSeq int64                      `json:"latestSeq" doc:"当前最新分片序号，可作为下次增量获取的游标"`
+	Lines     []string                   `json:"lines,omitempty" doc:"按逻辑行分页时本页的纯文本行"`
+	Offset    int                        `json:"offset,omitempty" doc:"按逻辑行分页时本页的起始行"`
+	Total     int                        `json:"total,omitempty" doc:"按逻辑行分页时的逻辑行总数"`
+}
+
+type terminalPreviewResponse struct {
+	Status int    `json:"-"`
+	ETag   string `header:"ETag"`
+	Body   struct {
+		Lines    []string `json:"lines" doc:"渲染后的最后几行纯文本"`
+		AutoWrap bool     `json:"autoWrap" doc:"终端是否开启自动换行（DECAWM），关闭时被折断的行不会合并"`
+	} `json:"body"`
+}
+
+// newAnnotationsResponse returns the annotations after an update, an empty
+// object rather than null when there are none.
+func newAnnotationsResponse(annotations map[string]string) *h.ItemResponse[map[string]string] {
+	if annotations == nil {
+		annotations = map[string]string{}
+	}
+	resp := h.NewItemResponse(annotations)
+	resp.Status = http.StatusOK
+	return resp
+}
+
+// etagMatches reports whether an If-None-Match header lists etag; weak
+// validators compare equal to their strong form.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
 }
 
 type terminalCountsResponse struct {
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/danielgtaylor/huma/v2/humatest"
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/service"
+	"code-kanban/service/terminal"
+	"code-kanban/utils/git"
+)
+
+func TestParseIdleTimeoutOverride(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want time.Duration
+	}{
+		{"", 0},
+		{" 0 ", 0},
+		{"30m", 30 * time.Minute},
+		{"-1", -1},
+		{"-30", -1},
+		{"-1s", -time.Second},
+		{"never", -1},
+	}
+	for _, tc := range cases {
+		got, err := parseIdleTimeoutOverride(tc.raw)
+		if err != nil || got != tc.want {
+			t.Fatalf("parseIdleTimeoutOverride(%q) = %v, %v; want %v", tc.raw, got, err, tc.want)
+		}
+	}
+	// 不带单位的正数含义不明确，必须写明单位
+	for _, raw := range []string{"30", "soon"} {
+		if _, err := parseIdleTimeoutOverride(raw); err == nil {
+			t.Fatalf("expected %q to be rejected", raw)
+		}
+	}
+	if got := formatIdleTimeoutOverride(-1); got != "never" {
+		t.Fatalf("unexpected format %q", got)
+	}
+}
+
+// newTerminalTestProject 初始化内存数据库，创建一个项目及 count 个 worktree
+func newTerminalTestProject(t *testing.T, count int) (*model.Project, []string) {
+	t.Helper()
+	if err := model.InitWithDSN("file:"+t.Name()+"?mode=memory&cache=shared", 0, true); err != nil {
+		t.Fatalf("InitWithDSN: %v", err)
+	}
+	gitEnv := []string{
+		"GIT_TERMINAL_PROMPT=0",
+		"GIT_AUTHOR_NAME=Test User",
+		"GIT_AUTHOR_EMAIL=test@example.com",
+		"GIT_COMMITTER_NAME=Test User",
+		"GIT_COMMITTER_EMAIL=test@example.com",
+		"GIT_CONFIG_NOSYSTEM=1",
+		"GIT_CONFIG_GLOBAL=/dev/null",
+		"HOME=" + os.TempDir(),
+	}
+	git.SetTestEnvOverride(gitEnv)
+	t.Cleanup(func() {
+		git.SetTestEnvOverride(nil)
+		model.DBClose()
+	})
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("demo"), 0o644); err != nil {
+		t.Fatalf("write readme: %v", err)
+	}
+	for _, args := range [][]string{{"init", "-b", "main"}, {"add", "README.md"}, {"commit", "-m", "init"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		cmd.Env = append(os.Environ(), gitEnv...)
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, output)
+		}
+	}
+
+	ctx := context.Background()
+	project, err := (&model.ProjectService{}).CreateProject(ctx, model.CreateProjectParams{Name: "Batch", Path: dir})
+	if err != nil {
+		t.Fatalf("create project: %v", err)
+	}
+	worktreeSvc := service.NewWorktreeService()
+	worktreeSvc.AsyncRefresh(false)
+	ids := make([]string, 0, count)
+	for i := range count {
+		worktree, err := worktreeSvc.CreateWorktree(ctx, project.Id, "feature/batch-"+string(rune('a'+i)), "main", true, false)
+		if err != nil {
+			t.Fatalf("CreateWorktree: %v", err)
+		}
+		ids = append(ids, worktree.Id)
+	}
+	return project, ids
+}
+
+func TestTerminalBatchCreate(t *testing.T) {
+	project, worktreeIDs := newTerminalTestProject(t, 2)
+	manager := terminal.NewManager(terminal.Config{MaxSessionsPerProject: 1}, zap.NewNop())
+	t.Cleanup(func() {
+		for _, id := range worktreeIDs {
+			manager.CloseWorktreeSessions(id)
+		}
+	})
+	c := &terminalController{
+		manager:     manager,
+		worktreeSvc: service.NewWorktreeService(),
+		taskService: &model.TaskService{},
+		projectSvc:  model.NewProjectService(),
+		logger:      zap.NewNop(),
+	}
+
+	input := &terminalBatchCreateInput{ProjectID: project.Id}
+	input.Body.WorktreeIDs = []string{worktreeIDs[0], " " + worktreeIDs[0], "missing", "", worktreeIDs[1]}
+	results := c.handleBatchCreate(context.Background(), input)
+	if len(results) != 5 {
+		t.Fatalf("expected one result per worktree id, got %+v", results)
+	}
+	if results[0].Status != http.StatusCreated && results[0].Error != "" {
+		t.Skipf("pty unavailable: %s", results[0].Error)
+	}
+
+	// 部分失败不影响已创建的会话，每项按单个创建接口的状态码报告
+	want := []int{
+		http.StatusCreated,
+		http.StatusBadRequest,
+		http.StatusNotFound,
+		http.StatusBadRequest,
+		http.StatusTooManyRequests,
+	}
+	for i, result := range results {
+		if result.Status != want[i] {
+			t.Fatalf("result %d: expected status %d, got %+v", i, want[i], result)
+		}
+		if (result.Session != nil) != (result.Status == http.StatusCreated) || (result.Error == "") != (result.Status == http.StatusCreated) {
+			t.Fatalf("result %d: expected either a session or an error, got %+v", i, result)
+		}
+	}
+	if _, err := manager.GetSession(results[0].Session.ID); err != nil {
+		t.Fatalf("created session was rolled back: %v", err)
+	}
+	if sessions := manager.ListSessions(project.Id); len(sessions) != 1 {
+		t.Fatalf("expected the session limit to hold, got %d sessions", len(sessions))
+	}
+}
+
+func TestTerminalScrollbackInputResolve(t *testing.T) {
+	cases := map[string]bool{
+		"/terminals/x/scrollback":                   false,
+		"/terminals/x/scrollback?since=3":           false,
+		"/terminals/x/scrollback?offset=0":          true,
+		"/terminals/x/scrollback?offset=20&limit=5": true,
+	}
+	for target, want := range cases {
+		input := &terminalScrollbackInput{}
+		ctx := humatest.NewContext(nil, httptest.NewRequest(http.MethodGet, target, nil), httptest.NewRecorder())
+		if errs := input.Resolve(ctx); len(errs) != 0 || input.byLines != want {
+			t.Fatalf("%s: expected byLines=%v, got %v (%v)", target, want, input.byLines, errs)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"code-kanban/service/terminal"
+)
+
+const (
+	terminalStreamPath      = "/api/v1/terminals/:sessionId/stream"
+	terminalStatePath       = "/api/v1/terminals/:sessionId/state-stream"
+	terminalStreamHeartbeat = 20 * time.Second
+)
+
+type terminalStreamData struct {
+	Data string `json:"data"` // base64 编码的原始输出
+	Seq  int64  `json:"seq,omitempty"`
+}
+
+type terminalStreamMessage struct {
+	Message string `json:"message"`
+}
+
+// registerStream 注册会话输出的 SSE 镜像，供脚本等非浏览器客户端只读消费，
+// 事件名与 WebSocket 消息类型一致：data、metadata、links、exit 等。
+// 支持与 WebSocket 相同的 events= 过滤，sinceSeq= 先补发该序号之后的 scrollback 分片。
+func (c *terminalController) registerStream(app *fiber.App) {
+	app.Get(terminalStreamPath, func(ctx *fiber.Ctx) error {
+		eventTypes, err := terminal.ParseStreamEventTypes(ctx.Query("events"))
+		if err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, err.Error())
+		}
+		session, err := c.manager.GetSession(ctx.Params("sessionId"))
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "session not found")
+		}
+
+		since := int64(0)
+		if len(eventTypes) == 0 || slices.Contains(eventTypes, terminal.StreamEventData) {
+			if value, err := strconv.ParseInt(ctx.Query("sinceSeq"), 10, 64); err == nil && value > 0 {
+				since = value
+			}
+		}
+
+		// 先订阅再取回放内容，两步之间产生的输出同时出现在两边，按 seq 去重
+		streamCtx, cancel := context.WithCancel(context.Background())
+		stream, err := session.Subscribe(streamCtx, eventTypes...)
+		if err != nil {
+			cancel()
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to attach terminal stream")
+		}
+
+		setSSEHeaders(ctx)
+		ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer cancel()
+			defer stream.Close()
+			writeTerminalStream(w, session, stream, func() []terminal.ScrollbackChunk {
+				if since <= 0 {
+					return nil
+				}
+				scrollback, _ := session.ScrollbackSince(since)
+				return scrollback
+			})
+		})
+		return nil
+	})
+}
+
+// writeTerminalStream replays the chunks returned by replay and then relays
+// stream events until the session exits or the client disconnects. stream must
+// be subscribed before replay runs; data events already replayed are skipped
+// by seq.
+func writeTerminalStream(w *bufio.Writer, session *terminal.Session, stream *terminal.SessionStream, replay func() []terminal.ScrollbackChunk) {
+	fmt.Fprint(w, ": connected\n\n")
+	if err := w.Flush(); err != nil {
+		return
+	}
+	lastSeq := int64(0)
+	for _, chunk := range replay() {
+		if len(chunk.Data) == 0 {
+			continue
+		}
+		if err := writeSSEEvent(w, "data", terminalStreamData{Data: base64.StdEncoding.EncodeToString(chunk.Data), Seq: chunk.Seq}); err != nil {
+			return
+		}
+		lastSeq = chunk.Seq
+	}
+	if status := session.Status(); status == terminal.SessionStatusClosed || status == terminal.SessionStatusError {
+		_ = writeSSEEvent(w, "exit", terminalStreamMessage{Message: sessionExitMessage(session, nil)})
+		return
+	}
+
+	heartbeat := time.NewTicker(terminalStreamHeartbeat)
+	defer heartbeat.Stop()
+	// 客户端断开后写入返回错误，借此结束订阅
+	for {
+		select {
+		case event, ok := <-stream.Events():
+			if !ok {
+				return
+			}
+			if err := writeTerminalStreamEvent(w, session, event, &lastSeq); err != nil {
+				return
+			}
+			if event.Type == terminal.StreamEventExit {
+				return
+			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": ping\n\n")
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// registerStateStream 注册单个会话的状态时间线 SSE：连接后先发送 state（当前状态），
+// 之后 AI 状态、进程状态或前台命令每次变化发送一条 change（字段、旧值、新值、时间），
+// 会话结束时发送 exit。基于 metadata 事件，其他 metadata 字段的变化不会推送。
+func (c *terminalController) registerStateStream(app *fiber.App) {
+	app.Get(terminalStatePath, func(ctx *fiber.Ctx) error {
+		session, err := c.manager.GetSession(ctx.Params("sessionId"))
+		if err != nil {
+			return fiber.NewError(fiber.StatusNotFound, "session not found")
+		}
+
+		streamCtx, cancel := context.WithCancel(context.Background())
+		stream, err := session.Subscribe(streamCtx, terminal.StreamEventMetadata, terminal.StreamEventExit)
+		if err != nil {
+			cancel()
+			return fiber.NewError(fiber.StatusInternalServerError, "failed to attach terminal stream")
+		}
+
+		setSSEHeaders(ctx)
+		ctx.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer cancel()
+			defer stream.Close()
+
+			var differ terminal.StateChangeDiffer
+			initial := terminal.SessionStateOfSnapshot(session.Snapshot(), time.Now())
+			differ.Next(initial)
+			if err := writeSSEEvent(w, "state", initial); err != nil {
+				return
+			}
+			if status := session.Status(); status == terminal.SessionStatusClosed || status == terminal.SessionStatusError {
+				_ = writeSSEEvent(w, "exit", terminalStreamMessage{Message: sessionExitMessage(session, nil)})
+				return
+			}
+
+			heartbeat := time.NewTicker(terminalStreamHeartbeat)
+			defer heartbeat.Stop()
+			for {
+				select {
+				case event, ok := <-stream.Events():
+					if !ok {
+						return
+					}
+					switch event.Type {
+					case terminal.StreamEventMetadata:
+						if event.Metadata == nil {
+							continue
+						}
+						for _, change := range differ.Next(terminal.SessionStateOf(event.Metadata, time.Now())) {
+							if err := writeSSEEvent(w, "change", change); err != nil {
+								return
+							}
+						}
+					case terminal.StreamEventExit:
+						_ = writeSSEEvent(w, "exit", terminalStreamMessage{Message: sessionExitMessage(session, event.Err)})
+						return
+					}
+				case <-heartbeat.C:
+					fmt.Fprint(w, ": ping\n\n")
+					if err := w.Flush(); err != nil {
+						return
+					}
+				}
+			}
+		})
+		return nil
+	})
+}
+
+// writeTerminalStreamEvent writes one session event; data chunks already sent
+// from the scrollback replay are skipped.
+func writeTerminalStreamEvent(w *bufio.Writer, session *terminal.Session, event terminal.StreamEvent, lastSeq *int64) error {
+	switch event.Type {
+	case terminal.StreamEventData:
+		if len(event.Data) == 0 || (event.Seq > 0 && event.Seq <= *lastSeq) {
+			return nil
+		}
+		return writeSSEEvent(w, "data", terminalStreamData{Data: base64.StdEncoding.EncodeToString(event.Data), Seq: event.Seq})
+	case terminal.StreamEventMetadata:
+		if event.Metadata == nil {
+			return nil
+		}
+		return writeSSEEvent(w, "metadata", event.Metadata)
+	case terminal.StreamEventLinks:
+		return writeSSEEvent(w, "links", event.Links)
+	case terminal.StreamEventHighlights:
+		return writeSSEEvent(w, "highlights", event.Highlights)
+	case terminal.StreamEventAlert:
+		if event.Alert == nil {
+			return nil
+		}
+		return writeSSEEvent(w, "alert", event.Alert)
+	case terminal.StreamEventExit:
+		return writeSSEEvent(w, "exit", terminalStreamMessage{Message: sessionExitMessage(session, event.Err)})
+	case terminal.StreamEventEncodingChanged:
+		return writeSSEEvent(w, "encoding-changed", terminalStreamData{Data: base64.StdEncoding.EncodeToString(event.Data), Seq: session.ScrollbackLatestSeq()})
+	case terminal.StreamEventLifetimeExceeded, terminal.StreamEventWorktreeMoved:
+		return writeSSEEvent(w, string(event.Type), terminalStreamMessage{Message: string(event.Data)})
+	default:
+		return nil
+	}
+}
+
+func sessionExitMessage(session *terminal.Session, eventErr error) string {
+	if eventErr != nil {
+		return eventErr.Error()
+	}
+	if err := session.Err(); err != nil {
+		return err.Error()
+	}
+	return "session closed"
+}
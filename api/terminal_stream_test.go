@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"code-kanban/service/terminal"
+)
+
+// waitScrollback 等待 scrollback 中出现 text
+func waitScrollback(t *testing.T, session *terminal.Session, text string) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !bytes.Contains(bytes.Join(session.Scrollback(), nil), []byte(text)) {
+		if time.Now().After(deadline) {
+			t.Fatalf("output %q did not reach the scrollback", text)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWriteTerminalStreamKeepsOutputDuringReplay(t *testing.T) {
+	c := newTerminalTestController(t, terminal.Config{ScrollbackEnabled: true, ScrollbackBytes: 64 * 1024})
+	session := newMuxTestSession(t, c)
+	stream, err := session.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+
+	const marker = "marker-176"
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		defer writer.Close()
+		writeTerminalStream(bufio.NewWriter(writer), session, stream, func() []terminal.ScrollbackChunk {
+			// 订阅之后、取回放之前产生的输出
+			if err := session.WriteInput([]byte("printf 'marker-%s\\n' 176\r")); err != nil {
+				t.Errorf("WriteInput failed: %v", err)
+			}
+			waitScrollback(t, session, marker)
+			scrollback, _ := session.ScrollbackSince(1)
+			return scrollback
+		})
+	}()
+
+	var output strings.Builder
+	scanner := bufio.NewScanner(reader)
+	event := ""
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: ") && event == "data":
+			var payload terminalStreamData
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &payload); err != nil {
+				t.Fatalf("invalid data event %q: %v", line, err)
+			}
+			data, _ := base64.StdEncoding.DecodeString(payload.Data)
+			output.Write(data)
+			// 输出到达后结束会话，之后收到 exit 事件
+			if strings.Contains(output.String(), marker) {
+				go session.Close()
+			}
+		}
+	}
+	<-done
+	if n := strings.Count(output.String(), marker); n != 1 {
+		t.Fatalf("expected the output once, got %d times in %q", n, output.String())
+	}
+}
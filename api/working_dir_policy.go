@@ -0,0 +1,82 @@
+package api
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// checkWorkingDirPolicy rejects a terminal working directory that is one of
+// the denied directories, such as / or the home directory, and, when allowed
+// roots are configured, one that lies outside all of them. Denied entries
+// only match the directory itself, so "/" does not deny every path. When
+// allowed roots are configured but none of them expands, every directory is
+// rejected rather than silently lifting the restriction.
+func checkWorkingDirPolicy(target string, denied, allowed []string) error {
+	for _, entry := range denied {
+		dir, ok := expandPolicyPath(entry)
+		if ok && samePath(dir, target) {
+			return fmt.Errorf("working directory %s is not allowed, choose a project folder", target)
+		}
+	}
+
+	var roots []string
+	configured := false
+	for _, entry := range allowed {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		configured = true
+		if root, ok := expandPolicyPath(entry); ok {
+			roots = append(roots, root)
+		}
+	}
+	if !configured {
+		return nil
+	}
+	// 配置了允许项但全部无法展开（如环境变量未设置）时拒绝，避免策略失效后放行任意目录
+	if len(roots) == 0 {
+		return fmt.Errorf("working directory %s is not allowed, none of the allowed roots could be resolved", target)
+	}
+	for _, root := range roots {
+		if isSubPath(root, target) {
+			return nil
+		}
+	}
+	return fmt.Errorf("working directory %s is outside the allowed roots", target)
+}
+
+// expandPolicyPath expands a leading ~ and $VAR or ${VAR} references, such as
+// $HOME or ${SystemRoot}, and returns the cleaned absolute path.
+func expandPolicyPath(entry string) (string, bool) {
+	path := strings.TrimSpace(entry)
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, `~\`) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", false
+		}
+		path = home + path[1:]
+	}
+	path = os.ExpandEnv(path)
+	if path == "" {
+		return "", false
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	return abs, true
+}
+
+// policyPathsFoldCase 路径比较是否忽略大小写，Windows 文件系统不区分大小写
+var policyPathsFoldCase = runtime.GOOS == "windows"
+
+func samePath(a, b string) bool {
+	a, b = filepath.Clean(a), filepath.Clean(b)
+	if policyPathsFoldCase {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
@@ -0,0 +1,109 @@
+package api
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCheckWorkingDirPolicy(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	base := t.TempDir()
+	t.Setenv("KANBAN_POLICY_ROOT", base)
+	project := filepath.Join(base, "project")
+
+	cases := []struct {
+		name    string
+		target  string
+		denied  []string
+		allowed []string
+		wantErr string
+	}{
+		{name: "no policy", target: home},
+		{name: "deny home via tilde", target: home, denied: []string{"~"}, wantErr: "not allowed"},
+		{name: "deny via env var", target: base, denied: []string{"$KANBAN_POLICY_ROOT"}, wantErr: "not allowed"},
+		{name: "deny via braced env var", target: base, denied: []string{"${KANBAN_POLICY_ROOT}"}, wantErr: "not allowed"},
+		// 拒绝项只匹配目录本身，不影响其子目录
+		{name: "denied entry does not cover children", target: project, denied: []string{"$KANBAN_POLICY_ROOT", "~"}},
+		{name: "denied entry with trailing separator", target: home, denied: []string{"~/"}, wantErr: "not allowed"},
+		{name: "unset env var is ignored", target: project, denied: []string{"$KANBAN_POLICY_UNSET"}},
+		{name: "inside allowed root", target: project, allowed: []string{"$KANBAN_POLICY_ROOT"}},
+		{name: "allowed root itself", target: base, allowed: []string{base}},
+		{name: "inside tilde root", target: filepath.Join(home, "code"), allowed: []string{"~/code"}},
+		{name: "outside allowed roots", target: home, allowed: []string{"$KANBAN_POLICY_ROOT"}, wantErr: "outside the allowed roots"},
+		{name: "sibling with shared prefix", target: base + "-other", allowed: []string{base}, wantErr: "outside the allowed roots"},
+		{name: "any of several roots", target: filepath.Join(home, "x"), allowed: []string{base, "~"}},
+		// 无法展开的允许项被忽略，但全部无效时拒绝所有目录
+		{name: "unexpandable root is skipped", target: project, allowed: []string{"$KANBAN_POLICY_UNSET", base}},
+		{name: "only unexpandable roots", target: home, allowed: []string{"$KANBAN_POLICY_UNSET", " "}, wantErr: "could be resolved"},
+		{name: "only blank roots", target: home, allowed: []string{" ", ""}},
+		{name: "denied wins over allowed", target: base, denied: []string{base}, allowed: []string{base}, wantErr: "not allowed"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := checkWorkingDirPolicy(tc.target, tc.denied, tc.allowed)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Fatalf("expected error containing %q, got %v", tc.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestCheckWorkingDirPolicyFoldCase(t *testing.T) {
+	base := filepath.Join(t.TempDir(), "Project")
+	upper := filepath.Join(filepath.Dir(base), "PROJECT")
+
+	prev := policyPathsFoldCase
+	t.Cleanup(func() { policyPathsFoldCase = prev })
+
+	policyPathsFoldCase = false
+	if err := checkWorkingDirPolicy(upper, []string{base}, nil); err != nil {
+		t.Fatalf("case-sensitive paths should differ: %v", err)
+	}
+	// Windows 下大小写不同的同一目录也要被拒绝
+	policyPathsFoldCase = true
+	if err := checkWorkingDirPolicy(upper, []string{base}, nil); err == nil {
+		t.Fatalf("expected case-insensitive match to deny %s", upper)
+	}
+}
+
+func TestExpandPolicyPath(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("KANBAN_POLICY_ROOT", home)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("getwd: %v", err)
+	}
+
+	cases := map[string]string{
+		"~":                       home,
+		"~/a":                     filepath.Join(home, "a"),
+		" ~/a/../b ":              filepath.Join(home, "b"),
+		"$KANBAN_POLICY_ROOT/c":   filepath.Join(home, "c"),
+		"${KANBAN_POLICY_ROOT}/d": filepath.Join(home, "d"),
+		"relative":                filepath.Join(wd, "relative"),
+		"~other":                  filepath.Join(wd, "~other"),
+	}
+	for entry, want := range cases {
+		got, ok := expandPolicyPath(entry)
+		if !ok || got != want {
+			t.Fatalf("expandPolicyPath(%q) = %q, %v; want %q", entry, got, ok, want)
+		}
+	}
+	for _, entry := range []string{"", "  ", "$KANBAN_POLICY_UNSET"} {
+		if got, ok := expandPolicyPath(entry); ok {
+			t.Fatalf("expandPolicyPath(%q) = %q, expected it to be ignored", entry, got)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 
 	"github.com/danielgtaylor/huma/v2"
@@ -11,26 +12,64 @@ import (
 	"code-kanban/api/h"
 	"code-kanban/model"
 	"code-kanban/service"
+	"code-kanban/service/terminal"
 	"code-kanban/utils"
+	"code-kanban/utils/git"
 )
 
 const worktreeTag = "worktree-工作树"
 
 type createWorktreeInput struct {
 	Body struct {
-		BranchName   string `json:"branchName" doc:"分支名称" required:"true"`
-		BaseBranch   string `json:"baseBranch" doc:"基础分支" default:""`
+		BranchName   string `json:"branchName" doc:"分支名称，不创建分支时可传远程分支（如 origin/feature-x），自动检出为本地跟踪分支；detach 时忽略" default:""`
+		BaseBranch   string `json:"baseBranch" doc:"基础分支，为远程分支时新分支会跟踪该远程分支；detach 时为要检出的提交（SHA、tag 或分支）" default:""`
 		CreateBranch bool   `json:"createBranch" doc:"是否创建新分支" default:"true"`
+		Detach       bool   `json:"detach,omitempty" doc:"以 detached HEAD 检出 baseBranch 指定的提交，不创建也不关联分支"`
 	} `json:"body"`
 }
 
 type commitWorktreeInput struct {
 	Body struct {
 		Message string `json:"message" doc:"提交信息" minLength:"1"`
+		Sign    bool   `json:"sign,omitempty" doc:"是否签名提交（git commit -S），需预先配置 GPG/SSH 签名密钥"`
 	} `json:"body"`
 }
 
-func registerWorktreeRoutes(group *huma.Group) {
+type resetWorktreeInput struct {
+	Body struct {
+		Ref     string `json:"ref,omitempty" doc:"目标提交或引用，默认 HEAD"`
+		Mode    string `json:"mode,omitempty" enum:"soft,mixed,hard" doc:"重置模式，默认 mixed"`
+		Confirm bool   `json:"confirm,omitempty" doc:"hard 模式必须显式确认"`
+		Force   bool   `json:"force,omitempty" doc:"允许在有未提交更改时执行 hard 重置"`
+	} `json:"body"`
+}
+
+type worktreeFilesInput struct {
+	Body struct {
+		Files []string `json:"files" doc:"相对 worktree 根目录的文件路径，与改动明细中的 path 一致" minItems:"1"`
+	} `json:"body"`
+}
+
+type discardWorktreeFilesInput struct {
+	Body struct {
+		Files   []string `json:"files" doc:"相对 worktree 根目录的文件路径，与改动明细中的 path 一致" minItems:"1"`
+		Confirm bool     `json:"confirm,omitempty" doc:"丢弃的改动无法恢复，必须显式确认"`
+	} `json:"body"`
+}
+
+type moveWorktreeInput struct {
+	Body struct {
+		NewPath string `json:"newPath" doc:"新的 worktree 路径，必须不存在且父目录可写" minLength:"1"`
+	} `json:"body"`
+}
+
+// worktreeTerminalManager 删除 worktree 前检查并关闭关联的终端会话
+type worktreeTerminalManager interface {
+	ListSessionsByWorktree(worktreeID string) []terminal.SessionSnapshot
+	CloseWorktreeSessions(worktreeID string) int
+}
+
+func registerWorktreeRoutes(group *huma.Group, terminalManager worktreeTerminalManager) {
 	worktreeSvc := service.NewWorktreeService()
 
 	huma.Post(group, "/projects/{projectId}/worktrees/create", func(
@@ -46,6 +85,7 @@ func registerWorktreeRoutes(group *huma.Group) {
 			input.Body.BranchName,
 			input.Body.BaseBranch,
 			input.Body.CreateBranch,
+			input.Body.Detach,
 		)
 		if err != nil {
 			return nil, mapWorktreeError(err)
@@ -101,20 +141,45 @@ func registerWorktreeRoutes(group *huma.Group) {
 		ctx context.Context,
 		input *struct {
 			ID           string `path:"id"`
-			Force        bool   `query:"force" default:"false"`
+			Force        bool   `query:"force" default:"false" doc:"强制删除有未提交改动的 worktree，并级联关闭关联的终端会话"`
 			DeleteBranch bool   `query:"deleteBranch" default:"true"`
 		},
 	) (*h.MessageResponse, error) {
+		// 终端会话的工作目录会随 worktree 一起失效，默认拒绝删除
+		sessions := terminalManager.ListSessionsByWorktree(input.ID)
+		if len(sessions) > 0 && !input.Force {
+			details := make([]error, 0, len(sessions))
+			for i, session := range sessions {
+				details = append(details, &huma.ErrorDetail{
+					Message:  fmt.Sprintf("terminal session %q is running in this worktree", session.Title),
+					Location: fmt.Sprintf("sessions[%d]", i),
+					Value:    session.ID,
+				})
+			}
+			return nil, huma.Error409Conflict("worktree has active terminal sessions, close them or delete with force", details...)
+		}
+
+		// 先关闭会话，避免 shell 占用目录导致 git worktree remove 失败
+		closed := 0
+		if len(sessions) > 0 {
+			closed = terminalManager.CloseWorktreeSessions(input.ID)
+		}
 		if err := worktreeSvc.DeleteWorktree(ctx, input.ID, input.Force, input.DeleteBranch); err != nil {
 			return nil, mapWorktreeError(err)
 		}
 
-		resp := h.NewMessageResponse("worktree deleted successfully")
+		message := "worktree deleted successfully"
+		if closed > 0 {
+			message = fmt.Sprintf("worktree deleted successfully, %d terminal session(s) closed", closed)
+		}
+		resp := h.NewMessageResponse(message)
 		resp.Status = http.StatusOK
 		return resp, nil
 	}, func(op *huma.Operation) {
 		op.OperationID = "worktree-delete"
 		op.Summary = "删除 Worktree"
+		op.Description = "存在关联的终端会话时返回 409 及会话列表（details 中 value 为 sessionId）；force=true 时先级联关闭这些会话再删除。" +
+			"非 force 时若检测到未推送到上游的提交，同样返回 409 及提交列表（details 中 value 为提交 SHA）"
 		op.Tags = []string{worktreeTag}
 	})
 
@@ -125,7 +190,7 @@ func registerWorktreeRoutes(group *huma.Group) {
 			commitWorktreeInput
 		},
 	) (*h.ItemResponse[model.Worktree], error) {
-		worktree, err := worktreeSvc.CommitWorktree(ctx, input.ID, input.Body.Message)
+		worktree, err := worktreeSvc.CommitWorktree(ctx, input.ID, input.Body.Message, input.Body.Sign)
 		if err != nil {
 			return nil, mapWorktreeError(err)
 		}
@@ -135,6 +200,240 @@ func registerWorktreeRoutes(group *huma.Group) {
 	}, func(op *huma.Operation) {
 		op.OperationID = "worktree-commit"
 		op.Summary = "提交 Worktree 更改"
+		op.Description = "配置了 git.commitMessagePattern 时，不符合规则的提交消息返回 422"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Get(group, "/projects/{id}/commit-template", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+		},
+	) (*h.ItemResponse[service.CommitTemplate], error) {
+		template, err := service.GetCommitTemplate(ctx, input.ID)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(*template)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "project-commit-template"
+		op.Summary = "获取提交消息模板"
+		op.Description = "返回配置的提交模板（未配置时使用仓库的 commit.template）以及提交消息校验规则，供前端预填与提示"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/reset", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+			resetWorktreeInput
+		},
+	) (*h.ItemResponse[model.Worktree], error) {
+		worktree, err := worktreeSvc.ResetWorktree(ctx, input.ID, input.Body.Ref, input.Body.Mode, input.Body.Confirm, input.Body.Force)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(*worktree)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-reset"
+		op.Summary = "重置 Worktree 到指定提交"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Get(group, "/worktrees/{id}/ref-backup", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+		},
+	) (*h.ItemResponse[model.RefBackup], error) {
+		backup, err := worktreeSvc.LastRefBackup(ctx, input.ID)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(*backup)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-ref-backup"
+		op.Summary = "查看最近一次破坏性操作前的 HEAD"
+		op.Description = "merge、rebase、reset 执行前会记录 worktree 的 HEAD（仅保存在服务进程内），可用于撤销上一次操作"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/revert", func(
+		ctx context.Context,
+		input *struct {
+			ID   string `path:"id"`
+			Body struct {
+				Ref     string `json:"ref" minLength:"1" doc:"恢复到的提交，通常为操作结果中的 backupRef"`
+				Confirm bool   `json:"confirm,omitempty" doc:"恢复会丢弃未提交的更改，必须显式确认"`
+			} `json:"body"`
+		},
+	) (*h.ItemResponse[model.Worktree], error) {
+		worktree, err := worktreeSvc.RevertToRef(ctx, input.ID, input.Body.Ref, input.Body.Confirm)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(*worktree)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-revert"
+		op.Summary = "恢复 Worktree 到指定提交"
+		op.Description = "先中止进行中的 rebase，再 hard reset 到 ref，用于 merge/rebase/reset 失败或后悔时回退"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/undo", func(
+		ctx context.Context,
+		input *struct {
+			ID   string `path:"id"`
+			Body struct {
+				Confirm bool `json:"confirm,omitempty" doc:"撤销会丢弃未提交的更改，必须显式确认"`
+			} `json:"body"`
+		},
+	) (*h.ItemResponse[model.Worktree], error) {
+		worktree, _, err := worktreeSvc.UndoLastOperation(ctx, input.ID, input.Body.Confirm)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(*worktree)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-undo"
+		op.Summary = "撤销上一次破坏性操作"
+		op.Description = "恢复到最近一次 merge、rebase 或 reset 前记录的 HEAD，成功后清除该记录"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Get(group, "/worktrees/{id}/changes", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+		},
+	) (*h.ItemResponse[service.WorktreeChanges], error) {
+		changes, err := worktreeSvc.Changes(ctx, input.ID)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(*changes)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-changes"
+		op.Summary = "获取 Worktree 的文件改动明细"
+		op.Description = "逐文件返回暂存区与工作区的状态，未跟踪目录会展开为其中的文件；groups 按 staged / unstaged / untracked 分组，每个文件带对应一侧的状态码与增删行数，同时有暂存与未暂存改动的文件会出现在两组中。勾选后可调用 stage、unstage、commit 接口，它们返回同样的结构"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/stage", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+			worktreeFilesInput
+		},
+	) (*h.ItemResponse[service.WorktreeChanges], error) {
+		changes, err := worktreeSvc.StageFiles(ctx, input.ID, input.Body.Files)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(*changes)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-stage"
+		op.Summary = "暂存指定文件"
+		op.Description = "对指定文件执行 git add（包括删除），返回刷新后的 Worktree 状态与改动明细"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/unstage", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+			worktreeFilesInput
+		},
+	) (*h.ItemResponse[service.WorktreeChanges], error) {
+		changes, err := worktreeSvc.UnstageFiles(ctx, input.ID, input.Body.Files)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(*changes)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-unstage"
+		op.Summary = "取消暂存指定文件"
+		op.Description = "把指定文件移出暂存区，工作区内容不变，返回刷新后的 Worktree 状态与改动明细"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/discard", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+			discardWorktreeFilesInput
+		},
+	) (*h.ItemResponse[service.WorktreeChanges], error) {
+		changes, err := worktreeSvc.DiscardChanges(ctx, input.ID, input.Body.Files, input.Body.Confirm)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(*changes)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-discard"
+		op.Summary = "丢弃指定文件的未暂存改动"
+		op.Description = "已跟踪文件从暂存区恢复（git checkout --），未跟踪文件直接删除；已暂存的改动保留。需 confirm=true，返回刷新后的 Worktree 状态与改动明细"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/move", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+			moveWorktreeInput
+		},
+	) (*h.ItemResponse[model.Worktree], error) {
+		worktree, err := worktreeSvc.MoveWorktree(ctx, input.ID, input.Body.NewPath)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(*worktree)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-move"
+		op.Summary = "移动 Worktree 到新路径"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/read-only", func(
+		ctx context.Context,
+		input *struct {
+			ID   string `path:"id"`
+			Body struct {
+				ReadOnly bool `json:"readOnly" doc:"开启后拒绝提交、合并、变基、重置、暂存与丢弃改动等写操作"`
+			} `json:"body"`
+		},
+	) (*h.ItemResponse[model.Worktree], error) {
+		worktree, err := worktreeSvc.SetWorktreeReadOnly(ctx, input.ID, input.Body.ReadOnly)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(*worktree)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-set-read-only"
+		op.Summary = "设置 Worktree 只读模式"
+		op.Description = "只读 worktree 仅供查看（如交给 reviewer），内容相关的写操作返回 409；创建终端时可传 readOnly 以只读方式打开"
 		op.Tags = []string{worktreeTag}
 	})
 
@@ -158,6 +457,144 @@ func registerWorktreeRoutes(group *huma.Group) {
 		op.Tags = []string{worktreeTag}
 	})
 
+	huma.Get(group, "/worktrees/{id}/diff-summary", func(
+		ctx context.Context,
+		input *struct {
+			ID      string `path:"id"`
+			Base    string `query:"base" doc:"对比的基准提交或分支，默认 HEAD（即未提交的改动）"`
+			Refresh bool   `query:"refresh" default:"false" doc:"跳过缓存重新统计"`
+		},
+	) (*h.ItemResponse[git.DiffSummary], error) {
+		summary, err := worktreeSvc.DiffSummary(ctx, input.ID, input.Base, input.Refresh)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+
+		resp := h.NewItemResponse(*summary)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-diff-summary"
+		op.Summary = "按文件类型汇总 Worktree 改动"
+		op.Description = "基于 git diff --numstat 按扩展名聚合增删行数（未跟踪文件不计入）。结果缓存到 HEAD 变化或状态刷新为止，最长 30 秒"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Get(group, "/worktrees/{id}/lfs", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+		},
+	) (*h.ItemResponse[git.LFSStatus], error) {
+		status, err := worktreeSvc.LFSStatus(ctx, input.ID)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+
+		resp := h.NewItemResponse(*status)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-lfs"
+		op.Summary = "获取 Worktree 的 Git LFS 状态"
+		op.Description = "读取 .gitattributes 中的 filter=lfs 规则，返回是否启用 LFS、本机是否安装 git-lfs 以及仍是指针文件（内容未下载）的路径。" +
+			"按需计算，不包含在 Worktree 状态刷新中"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Get(group, "/worktrees/{id}/submodules", func(
+		ctx context.Context,
+		input *struct {
+			ID string `path:"id"`
+		},
+	) (*h.ItemsResponse[git.Submodule], error) {
+		submodules, err := worktreeSvc.Submodules(ctx, input.ID)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+
+		resp := h.NewItemsResponse(submodules)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-submodules"
+		op.Summary = "获取 Worktree 的子模块状态"
+		op.Description = "解析 .gitmodules 与 git submodule status，返回子模块路径、提交、是否已初始化以及是否与父仓库记录的提交不一致。" +
+			"按需计算，不包含在 Worktree 状态刷新中"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Post(group, "/worktrees/{id}/submodules/update", func(
+		ctx context.Context,
+		input *struct {
+			ID   string `path:"id"`
+			Body struct {
+				Recursive bool `json:"recursive,omitempty" doc:"同时更新嵌套的子模块"`
+			}
+		},
+	) (*h.ItemsResponse[git.Submodule], error) {
+		submodules, err := worktreeSvc.UpdateSubmodules(ctx, input.ID, input.Body.Recursive)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+
+		resp := h.NewItemsResponse(submodules)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-submodules-update"
+		op.Summary = "更新 Worktree 的子模块"
+		op.Description = "执行 git submodule update --init，把子模块检出到父仓库记录的提交，完成后刷新 Worktree 状态"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Get(group, "/worktrees/{id}/commits/{sha}", func(
+		ctx context.Context,
+		input *struct {
+			ID     string `path:"id"`
+			SHA    string `path:"sha" doc:"提交 SHA（可缩写）或其他指向提交的引用"`
+			Diff   bool   `query:"diff" default:"false" doc:"同时返回 patch，默认只返回文件列表"`
+			Offset int    `query:"offset" minimum:"0" default:"0" doc:"patch 从第几个文件开始"`
+			Limit  int    `query:"limit" minimum:"0" maximum:"100" default:"20" doc:"本页 patch 覆盖的文件数"`
+		},
+	) (*h.ItemResponse[git.CommitDetail], error) {
+		detail, err := worktreeSvc.CommitDetail(ctx, input.ID, input.SHA, input.Diff, input.Offset, input.Limit)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+
+		resp := h.NewItemResponse(*detail)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-commit-detail"
+		op.Summary = "查看提交详情"
+		op.Description = "返回提交信息与变更文件列表（git show --name-status），合并提交与第一个父提交比较，并附带签名校验结果（git %G?）。diff=true 时按文件分页返回 patch，单页超过 512KB 会被截断"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Get(group, "/worktrees/{id}/reflog", func(
+		ctx context.Context,
+		input *struct {
+			ID    string `path:"id"`
+			Limit int    `query:"limit" minimum:"1" maximum:"1000" default:"100" doc:"返回的条目数"`
+		},
+	) (*h.ItemsResponse[git.ReflogEntry], error) {
+		entries, err := worktreeSvc.Reflog(ctx, input.ID, input.Limit)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+
+		resp := h.NewItemsResponse(entries)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-reflog"
+		op.Summary = "查看 HEAD 的 reflog"
+		op.Description = "按时间倒序返回 HEAD 的移动记录（提交、reset、rebase、切换分支等）。误操作丢失的提交可用条目中的 commit 调用 reset 接口恢复"
+		op.Tags = []string{worktreeTag}
+	})
+
 	huma.Post(group, "/projects/{projectId}/refresh-all-worktrees", func(
 		ctx context.Context,
 		input *struct {
@@ -199,6 +636,46 @@ func registerWorktreeRoutes(group *huma.Group) {
 		op.Tags = []string{worktreeTag}
 	})
 
+	huma.Post(group, "/projects/{projectId}/worktrees/prune", func(
+		ctx context.Context,
+		input *struct {
+			ProjectID string `path:"projectId"`
+		},
+	) (*h.ItemResponse[*model.WorktreePruneResult], error) {
+		result, err := worktreeSvc.PruneWorktrees(ctx, input.ProjectID)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(result)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-prune"
+		op.Summary = "清理失效的 Worktree 引用"
+		op.Description = "执行 git worktree prune 清理目录已被删除的 worktree，并同步删除数据库中对应的记录"
+		op.Tags = []string{worktreeTag}
+	})
+
+	huma.Post(group, "/projects/{projectId}/worktrees/cleanup-orphans", func(
+		ctx context.Context,
+		input *struct {
+			ProjectID string `path:"projectId"`
+		},
+	) (*h.ItemResponse[*model.WorktreeCleanupResult], error) {
+		result, err := worktreeSvc.CleanupOrphans(ctx, input.ProjectID)
+		if err != nil {
+			return nil, mapWorktreeError(err)
+		}
+		resp := h.NewItemResponse(result)
+		resp.Status = http.StatusOK
+		return resp, nil
+	}, func(op *huma.Operation) {
+		op.OperationID = "worktree-cleanup-orphans"
+		op.Summary = "清理磁盘与数据库不一致的 Worktree"
+		op.Description = "软删除目录已不存在的记录，并移除 worktree 目录下没有记录的 worktree；有未提交或未跟踪改动的不会移除，在 skippedWorktrees 中返回"
+		op.Tags = []string{worktreeTag}
+	})
+
 	huma.Post(group, "/projects/{projectId}/worktrees/refresh-commits", func(
 		ctx context.Context,
 		input *struct {
@@ -219,23 +696,80 @@ func registerWorktreeRoutes(group *huma.Group) {
 	})
 }
 
+// unpushedCommitsConflict 将未推送提交列在 details 中（value 为提交 SHA），用户确认后以 force 重试
+func unpushedCommitsConflict(err error) error {
+	var unpushed *model.UnpushedCommitsError
+	if !errors.As(err, &unpushed) {
+		return huma.Error409Conflict(err.Error())
+	}
+	details := make([]error, 0, len(unpushed.Commits))
+	for i, commit := range unpushed.Commits {
+		details = append(details, &huma.ErrorDetail{
+			Message:  commit.Message,
+			Location: fmt.Sprintf("commits[%d]", i),
+			Value:    commit.SHA,
+		})
+	}
+	return huma.Error409Conflict(fmt.Sprintf("%d commits on %s have not been pushed to upstream, delete with force to confirm", len(unpushed.Commits), unpushed.Branch), details...)
+}
+
+// mapGitFailure maps classified git command failures to HTTP statuses and
+// returns nil for other errors: credentials 403, lock 423, diverged history or
+// conflicts 409 and unreachable remotes 502.
+func mapGitFailure(err error) error {
+	switch {
+	case errors.Is(err, git.ErrAuthFailed):
+		return huma.Error403Forbidden(err.Error())
+	case errors.Is(err, git.ErrRepositoryLocked):
+		return huma.NewError(http.StatusLocked, err.Error())
+	case errors.Is(err, git.ErrNonFastForward),
+		errors.Is(err, git.ErrMergeConflict):
+		return huma.Error409Conflict(err.Error())
+	case errors.Is(err, git.ErrNetwork):
+		return huma.Error502BadGateway(err.Error())
+	default:
+		return nil
+	}
+}
+
 func mapWorktreeError(err error) error {
 	switch {
 	case err == nil:
 		return nil
+	case errors.Is(err, model.ErrUnpushedCommits):
+		return unpushedCommitsConflict(err)
 	case errors.Is(err, model.ErrDBNotInitialized):
 		return huma.Error503ServiceUnavailable("database is not initialized")
 	case errors.Is(err, model.ErrWorktreeNotFound),
-		errors.Is(err, model.ErrProjectNotFound):
+		errors.Is(err, model.ErrProjectNotFound),
+		errors.Is(err, model.ErrNoRefBackup),
+		errors.Is(err, git.ErrCommitNotFound):
 		return huma.Error404NotFound(err.Error())
 	case errors.Is(err, model.ErrWorktreeIsMain),
-		errors.Is(err, model.ErrWorktreeHasTasks):
+		errors.Is(err, model.ErrWorktreeHasTasks),
+		errors.Is(err, model.ErrWorktreeDirty),
+		errors.Is(err, model.ErrWorktreePathConflict),
+		errors.Is(err, model.ErrWorktreeDetached),
+		errors.Is(err, model.ErrWorktreeReadOnly),
+		errors.Is(err, git.ErrNoUnstagedChanges),
+		errors.Is(err, git.ErrWorktreeMoveUnsupported),
+		errors.Is(err, model.ErrProjectArchived):
 		return huma.Error409Conflict(err.Error())
-	case errors.Is(err, model.ErrWorktreeClean):
-		return huma.Error400BadRequest(err.Error())
-	default:
+	case errors.Is(err, model.ErrResetConfirmRequired),
+		errors.Is(err, model.ErrDiscardConfirmRequired):
+		return huma.Error412PreconditionFailed(err.Error())
+	case errors.Is(err, model.ErrWorktreeClean),
+		errors.Is(err, git.ErrInvalidFilePath):
 		return huma.Error400BadRequest(err.Error())
+	case errors.Is(err, git.ErrCommitSigningFailed):
+		return huma.Error412PreconditionFailed(err.Error())
+	case errors.Is(err, git.ErrCommitMessageInvalid):
+		return huma.Error422UnprocessableEntity(err.Error())
+	}
+	if mapped := mapGitFailure(err); mapped != nil {
+		return mapped
 	}
+	return huma.Error400BadRequest(err.Error())
 }
 
 type refreshAllResult struct {
@@ -0,0 +1,125 @@
+package api
+
+import (
+	"bufio"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+
+	"code-kanban/model"
+	"code-kanban/service"
+	"code-kanban/utils/git"
+)
+
+const (
+	worktreeEventsPath = "/api/v1/worktrees/events"
+	// worktreeEventsBuffer 慢客户端积压超过该数量时丢弃事件，前端可重新拉取列表
+	worktreeEventsBuffer    = 32
+	worktreeEventsHeartbeat = 20 * time.Second
+)
+
+// worktreeStatusEvent is pushed to SSE clients after a worktree status refresh.
+type worktreeStatusEvent struct {
+	Worktree *model.Worktree `json:"worktree"`
+	Detached bool            `json:"detached"`
+	// Upstream 当前分支的上游；HasUpstream 为 false 且未游离时前端提示设置上游，否则 ahead/behind 恒为 0
+	Upstream    string            `json:"upstream,omitempty"`
+	HasUpstream bool              `json:"hasUpstream"`
+	Renamed     int               `json:"renamed"`
+	Copied      int               `json:"copied"`
+	Renames     []git.RenamedFile `json:"renames,omitempty"`
+}
+
+type worktreeEventSubscriber struct {
+	projectID string
+	ch        chan worktreeStatusEvent
+}
+
+// worktreeEventHub fans worktree status refreshes out to SSE subscribers.
+type worktreeEventHub struct {
+	mu          sync.Mutex
+	subscribers map[*worktreeEventSubscriber]struct{}
+}
+
+func newWorktreeEventHub() *worktreeEventHub {
+	hub := &worktreeEventHub{subscribers: make(map[*worktreeEventSubscriber]struct{})}
+	service.RegisterWorktreeStatusObserver(hub.publish)
+	return hub
+}
+
+func (h *worktreeEventHub) publish(worktree *model.Worktree, status *git.WorktreeStatus) {
+	event := worktreeStatusEvent{
+		Worktree:    worktree,
+		Detached:    status.Detached,
+		Upstream:    status.Upstream,
+		HasUpstream: status.Upstream != "",
+		Renamed:     status.Renamed,
+		Copied:      status.Copied,
+		Renames:     status.Renames,
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for sub := range h.subscribers {
+		if sub.projectID != "" && sub.projectID != worktree.ProjectId {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
+
+func (h *worktreeEventHub) subscribe(projectID string) *worktreeEventSubscriber {
+	sub := &worktreeEventSubscriber{projectID: projectID, ch: make(chan worktreeStatusEvent, worktreeEventsBuffer)}
+	h.mu.Lock()
+	h.subscribers[sub] = struct{}{}
+	h.mu.Unlock()
+	return sub
+}
+
+func (h *worktreeEventHub) unsubscribe(sub *worktreeEventSubscriber) {
+	h.mu.Lock()
+	delete(h.subscribers, sub)
+	h.mu.Unlock()
+}
+
+// registerWorktreeEventsRoute 注册 worktree 状态变更的 SSE 推送，可用 ?projectId= 只订阅单个项目。
+// huma 的 fiber 适配器会缓冲响应体，因此直接挂在 fiber 上使用流式写入。
+func registerWorktreeEventsRoute(app *fiber.App) {
+	hub := newWorktreeEventHub()
+	app.Get(worktreeEventsPath, func(c *fiber.Ctx) error {
+		sub := hub.subscribe(c.Query("projectId"))
+
+		setSSEHeaders(c)
+
+		c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+			defer hub.unsubscribe(sub)
+
+			heartbeat := time.NewTicker(worktreeEventsHeartbeat)
+			defer heartbeat.Stop()
+
+			fmt.Fprint(w, ": connected\n\n")
+			if err := w.Flush(); err != nil {
+				return
+			}
+			// 客户端断开后写入返回错误，借此结束订阅
+			for {
+				select {
+				case event := <-sub.ch:
+					if err := writeSSEEvent(w, "worktree-status", event); err != nil {
+						return
+					}
+				case <-heartbeat.C:
+					fmt.Fprint(w, ": ping\n\n")
+					if err := w.Flush(); err != nil {
+						return
+					}
+				}
+			}
+		})
+		return nil
+	})
+}
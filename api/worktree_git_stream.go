@@ -0,0 +1,179 @@
+package api
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/service"
+	"code-kanban/utils/git"
+)
+
+const (
+	// gitProgressBuffer 进度事件缓冲，写出跟不上时丢弃中间进度，只保证最终结果送达
+	gitProgressBuffer = 64
+	// gitStreamHeartbeat 长时间没有进度时写心跳，及时发现客户端断开
+	gitStreamHeartbeat = 15 * time.Second
+)
+
+type gitStreamError struct {
+	Message string `json:"message"`
+	// Kind 失败分类（auth/locked/non_fast_forward/conflict/network），未识别时为空
+	Kind string `json:"kind,omitempty"`
+}
+
+type cloneStreamBody struct {
+	URL  string `json:"url"`
+	Path string `json:"path"`
+}
+
+type cloneStreamResult struct {
+	Path string `json:"path"`
+}
+
+// registerGitStreamRoutes 注册 fetch/pull/clone 及 clone 建项目的 SSE 进度推送：
+// 过程中发送 progress 事件（GitProgress），结束时发送 done（结果）或 error。
+// 客户端断开连接或服务关闭（ctx 取消）会取消正在执行的 git 命令。
+func registerGitStreamRoutes(ctx context.Context, app *fiber.App, logger *zap.Logger) {
+	worktreeService := service.NewWorktreeService()
+
+	app.Post("/api/v1/worktrees/:id/fetch/stream", func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		remote := c.Query("remote")
+		return streamGitOperation(ctx, c, logger, "fetch", func(ctx context.Context, onProgress git.ProgressFunc) (any, error) {
+			return worktreeService.FetchWorktree(ctx, id, remote, onProgress)
+		})
+	})
+
+	app.Post("/api/v1/worktrees/:id/pull/stream", func(c *fiber.Ctx) error {
+		id := c.Params("id")
+		return streamGitOperation(ctx, c, logger, "pull", func(ctx context.Context, onProgress git.ProgressFunc) (any, error) {
+			return worktreeService.PullWorktree(ctx, id, onProgress)
+		})
+	})
+
+	app.Post("/api/v1/git/clone/stream", func(c *fiber.Ctx) error {
+		var body cloneStreamBody
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+		}
+		if strings.TrimSpace(body.URL) == "" || strings.TrimSpace(body.Path) == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "url and path are required")
+		}
+		return streamGitOperation(ctx, c, logger, "clone", func(ctx context.Context, onProgress git.ProgressFunc) (any, error) {
+			path, err := service.CloneRepository(ctx, body.URL, body.Path, onProgress)
+			if err != nil {
+				return nil, err
+			}
+			return cloneStreamResult{Path: path}, nil
+		})
+	})
+
+	projectService := model.NewProjectService()
+	app.Post("/api/v1/projects/clone/stream", func(c *fiber.Ctx) error {
+		var body cloneProjectBody
+		if err := c.BodyParser(&body); err != nil {
+			return fiber.NewError(fiber.StatusBadRequest, "invalid request body")
+		}
+		if strings.TrimSpace(body.URL) == "" || strings.TrimSpace(body.Path) == "" {
+			return fiber.NewError(fiber.StatusBadRequest, "url and path are required")
+		}
+		return streamGitOperation(ctx, c, logger, "clone", func(ctx context.Context, onProgress git.ProgressFunc) (any, error) {
+			params := body.params()
+			params.OnProgress = onProgress
+			return projectService.CreateFromClone(ctx, params)
+		})
+	})
+}
+
+// streamGitOperation runs op in the background and relays its progress as SSE.
+// The operation context is cancelled as soon as the client goes away or parent
+// is cancelled.
+func streamGitOperation(parent context.Context, c *fiber.Ctx, logger *zap.Logger, name string, op func(ctx context.Context, onProgress git.ProgressFunc) (any, error)) error {
+	setSSEHeaders(c)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		ctx, cancel := context.WithCancel(parent)
+		defer cancel()
+
+		heartbeat := time.NewTicker(gitStreamHeartbeat)
+		defer heartbeat.Stop()
+
+		progress := make(chan git.GitProgress, gitProgressBuffer)
+		type opResult struct {
+			value any
+			err   error
+		}
+		finished := make(chan opResult, 1)
+		go func() {
+			value, err := op(ctx, func(p git.GitProgress) {
+				select {
+				case progress <- p:
+				default:
+				}
+			})
+			finished <- opResult{value: value, err: err}
+		}()
+
+		for {
+			select {
+			case <-ctx.Done():
+				// 服务关闭时不再写出，等 git 命令退出后结束
+				<-finished
+				return
+			case p := <-progress:
+				if err := writeSSEEvent(w, "progress", p); err != nil {
+					cancel()
+					<-finished
+					return
+				}
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				if err := w.Flush(); err != nil {
+					cancel()
+					<-finished
+					return
+				}
+			case result := <-finished:
+				// 先把剩余的进度写完，保证 done/error 是最后一个事件
+				for drained := false; !drained; {
+					select {
+					case p := <-progress:
+						if err := writeSSEEvent(w, "progress", p); err != nil {
+							return
+						}
+					default:
+						drained = true
+					}
+				}
+				if result.err != nil {
+					if !errors.Is(result.err, context.Canceled) {
+						logger.Warn("git operation failed", zap.String("operation", name), zap.Error(result.err))
+					}
+					_ = writeSSEEvent(w, "error", gitStreamError{Message: gitStreamErrorMessage(result.err), Kind: git.FailureKind(result.err)})
+					return
+				}
+				_ = writeSSEEvent(w, "done", result.value)
+				return
+			}
+		}
+	})
+	return nil
+}
+
+func gitStreamErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, model.ErrWorktreeNotFound):
+		return "worktree not found"
+	case errors.Is(err, model.ErrProjectNotFound):
+		return "project not found"
+	default:
+		return err.Error()
+	}
+}
@@ -0,0 +1,35 @@
+package api
+
+import (
+	"context"
+	"io"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"go.uber.org/zap"
+
+	"code-kanban/utils/git"
+)
+
+func TestStreamGitOperationStopsOnParentCancel(t *testing.T) {
+	parent, cancel := context.WithCancel(context.Background())
+	app := fiber.New()
+	app.Post("/stream", func(c *fiber.Ctx) error {
+		return streamGitOperation(parent, c, zap.NewNop(), "fetch", func(ctx context.Context, onProgress git.ProgressFunc) (any, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+	})
+
+	time.AfterFunc(50*time.Millisecond, cancel)
+	resp, err := app.Test(httptest.NewRequest("POST", "/stream", nil), 5000)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if len(body) != 0 {
+		t.Fatalf("expected no events after shutdown, got %q", body)
+	}
+}
@@ -1,13 +1,37 @@
 package api
 
 import (
+	"encoding/json"
+
 	"code-kanban/service/terminal"
 )
 
 type wsMessage struct {
 	Type     string                    `json:"type"`
+	ID       string                    `json:"id,omitempty"`
+	Seq      int64                     `json:"seq,omitempty"`
 	Data     string                    `json:"data,omitempty"`
+	Key      string                    `json:"key,omitempty"`
+	Clear    bool                      `json:"clear,omitempty"`
 	Cols     int                       `json:"cols,omitempty"`
 	Rows     int                       `json:"rows,omitempty"`
 	Metadata *terminal.SessionMetadata `json:"metadata,omitempty"`
+	Links    []terminal.TerminalLink   `json:"links,omitempty"`
+	// Highlights highlights 消息中按高亮规则标注的输出范围
+	Highlights []terminal.TerminalHighlight `json:"highlights,omitempty"`
+	// Alert alert 消息中命中告警规则的输出行
+	Alert *terminal.OutputAlert `json:"alert,omitempty"`
+	// Patch metadata-patch 消息的 JSON Merge Patch，客户端合并到本地 metadata
+	Patch json.RawMessage `json:"patch,omitempty"`
+	// Append 实时 data 消息只在底部追加了内容，为 false 时是原地重绘，客户端不必自动滚到底
+	Append bool `json:"append,omitempty"`
+	// Lines viewport-synced 消息中回放输出的总行数，同一消息的 Seq 为回放的最后序号
+	Lines int `json:"lines,omitempty"`
+	// SessionID 多路复用连接中消息所属的会话
+	SessionID string `json:"sessionId,omitempty"`
+	// Events/Tail/MetadataPatch 多路复用 attach 消息的订阅选项，含义同单会话连接的
+	// events、tail、metadata=patch 参数，续传起点使用 Seq
+	Events        string `json:"events,omitempty"`
+	Tail          int    `json:"tail,omitempty"`
+	MetadataPatch bool   `json:"metadataPatch,omitempty"`
 }
@@ -0,0 +1,149 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"code-kanban/model/tables"
+
+	"gorm.io/gorm"
+)
+
+// 活动类型
+const (
+	ActivityBranchCreated   = "branch.created"
+	ActivityBranchDeleted   = "branch.deleted"
+	ActivityBranchMerged    = "branch.merged"
+	ActivityBranchRebased   = "branch.rebased"
+	ActivityWorktreeCreated = "worktree.created"
+	ActivityWorktreeDeleted = "worktree.deleted"
+	// ActivityWorktreeBranchSwitched 在终端里手动切换了 worktree 的分支
+	ActivityWorktreeBranchSwitched = "worktree.branch_switched"
+	ActivityCommitCreated          = "commit.created"
+	ActivityAICompleted            = "ai.completed"
+	// ActivityAIInterrupted 用户按 ESC 打断了 AI 的本轮任务
+	ActivityAIInterrupted = "ai.interrupted"
+	// ActivityAINoted 关闭带备注或标签的 AI 完成通知，Detail 为备注与标签
+	ActivityAINoted = "ai.noted"
+	// ActivityAIApprovalAutoRejected 审批提示超时未响应，按策略自动拒绝，Detail 为超时时长
+	ActivityAIApprovalAutoRejected = "ai.approval_auto_rejected"
+	// ActivityWorktreeReset 重置了 worktree 的 HEAD，Detail 含重置前的提交
+	ActivityWorktreeReset = "worktree.reset"
+	// ActivityWorktreeReverted 把 worktree 恢复到破坏性操作前记录的提交
+	ActivityWorktreeReverted = "worktree.reverted"
+	// ActivityWorktreeReadOnly 开启或关闭了 worktree 的只读模式，Detail 为 on/off
+	ActivityWorktreeReadOnly = "worktree.read_only"
+)
+
+// 活动的触发者
+const (
+	ActivityActorUser   = "user"
+	ActivityActorSystem = "system"
+	// ActivityActorAI 的活动记录为 "ai:<assistant type>"
+	ActivityActorAI = "ai"
+)
+
+const (
+	defaultActivityLimit = 50
+	maxActivityLimit     = 200
+)
+
+// ErrActivityInvalid indicates an activity entry without project or type.
+var ErrActivityInvalid = errors.New("activity project id and type are required")
+
+// ActivityService records and lists the project activity stream.
+type ActivityService struct{}
+
+// RecordActivityRequest captures one activity entry. Time defaults to now.
+type RecordActivityRequest struct {
+	ProjectID string
+	Type      string
+	Actor     string
+	Target    string
+	Detail    string
+	Time      time.Time
+}
+
+// ListActivitiesRequest pages through activities newest first; Before excludes
+// entries at or after the given time so the last item's time can be used as cursor.
+type ListActivitiesRequest struct {
+	ProjectID string
+	Limit     int
+	Before    *time.Time
+}
+
+// RecordActivity inserts an activity entry.
+func (s *ActivityService) RecordActivity(ctx context.Context, req *RecordActivityRequest) (*tables.ActivityLogTable, error) {
+	if req == nil || strings.TrimSpace(req.ProjectID) == "" || strings.TrimSpace(req.Type) == "" {
+		return nil, ErrActivityInvalid
+	}
+	dbCtx, err := s.dbWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	occurredAt := req.Time
+	if occurredAt.IsZero() {
+		occurredAt = time.Now()
+	}
+	actor := strings.TrimSpace(req.Actor)
+	if actor == "" {
+		actor = ActivityActorUser
+	}
+	activity := &tables.ActivityLogTable{
+		ProjectID: strings.TrimSpace(req.ProjectID),
+		Type:      strings.TrimSpace(req.Type),
+		Actor:     actor,
+		Target:    req.Target,
+		Detail:    req.Detail,
+		Time:      occurredAt,
+	}
+	if err := dbCtx.Create(activity).Error; err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// ListActivities returns activities of a project ordered by time descending.
+func (s *ActivityService) ListActivities(ctx context.Context, req *ListActivitiesRequest) ([]tables.ActivityLogTable, error) {
+	if req == nil || strings.TrimSpace(req.ProjectID) == "" {
+		return nil, ErrActivityInvalid
+	}
+	dbCtx, err := s.dbWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultActivityLimit
+	}
+	if limit > maxActivityLimit {
+		limit = maxActivityLimit
+	}
+
+	query := dbCtx.Model(&tables.ActivityLogTable{}).Where("project_id = ?", req.ProjectID)
+	if req.Before != nil {
+		query = query.Where("time < ?", *req.Before)
+	}
+
+	activities := make([]tables.ActivityLogTable, 0)
+	if err := query.
+		Order("time DESC").
+		Order("id DESC").
+		Limit(limit).
+		Find(&activities).Error; err != nil {
+		return nil, err
+	}
+	return activities, nil
+}
+
+func (s *ActivityService) dbWithContext(ctx context.Context) (*gorm.DB, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, ErrDBNotInitialized
+	}
+	return db.WithContext(ensureContext(ctx)), nil
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestActivityServiceListPaginates(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	service := &ActivityService{}
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	for i, activityType := range []string{ActivityBranchCreated, ActivityWorktreeCreated, ActivityCommitCreated} {
+		if _, err := service.RecordActivity(ctx, &RecordActivityRequest{
+			ProjectID: "p1",
+			Type:      activityType,
+			Target:    "feature/one",
+			Time:      base.Add(time.Duration(i) * time.Minute),
+		}); err != nil {
+			t.Fatalf("RecordActivity returned error: %v", err)
+		}
+	}
+	if _, err := service.RecordActivity(ctx, &RecordActivityRequest{ProjectID: "p2", Type: ActivityBranchCreated}); err != nil {
+		t.Fatalf("RecordActivity returned error: %v", err)
+	}
+	if _, err := service.RecordActivity(ctx, &RecordActivityRequest{ProjectID: "p1"}); !errors.Is(err, ErrActivityInvalid) {
+		t.Fatalf("expected ErrActivityInvalid, got %v", err)
+	}
+
+	page, err := service.ListActivities(ctx, &ListActivitiesRequest{ProjectID: "p1", Limit: 2})
+	if err != nil {
+		t.Fatalf("ListActivities returned error: %v", err)
+	}
+	if len(page) != 2 || page[0].Type != ActivityCommitCreated || page[1].Type != ActivityWorktreeCreated {
+		t.Fatalf("unexpected first page: %+v", page)
+	}
+	if page[0].Actor != ActivityActorUser {
+		t.Fatalf("expected default actor %q, got %q", ActivityActorUser, page[0].Actor)
+	}
+
+	before := page[1].Time
+	next, err := service.ListActivities(ctx, &ListActivitiesRequest{ProjectID: "p1", Limit: 2, Before: &before})
+	if err != nil {
+		t.Fatalf("ListActivities returned error: %v", err)
+	}
+	if len(next) != 1 || next[0].Type != ActivityBranchCreated {
+		t.Fatalf("unexpected second page: %+v", next)
+	}
+}
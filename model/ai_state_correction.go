@@ -0,0 +1,53 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"code-kanban/model/tables"
+)
+
+// ErrAIStateCorrectionInvalid indicates a correction without session, project, assistant or states.
+var ErrAIStateCorrectionInvalid = errors.New("ai state correction session id, project id, assistant and states are required")
+
+// defaultAIStateCorrectionLimit 未指定数量时列出的纠正记录数
+const defaultAIStateCorrectionLimit = 100
+
+// AIStateCorrectionService stores user corrections of AI assistant state detection.
+type AIStateCorrectionService struct{}
+
+// RecordAIStateCorrection inserts a correction entry.
+func (s *AIStateCorrectionService) RecordAIStateCorrection(ctx context.Context, entry *tables.AIStateCorrectionTable) error {
+	if entry == nil || strings.TrimSpace(entry.SessionID) == "" || strings.TrimSpace(entry.ProjectID) == "" ||
+		strings.TrimSpace(entry.Assistant) == "" || strings.TrimSpace(entry.DetectedState) == "" ||
+		strings.TrimSpace(entry.CorrectState) == "" {
+		return ErrAIStateCorrectionInvalid
+	}
+	db := GetDB()
+	if db == nil {
+		return ErrDBNotInitialized
+	}
+	return db.WithContext(ensureContext(ctx)).Create(entry).Error
+}
+
+// ListAIStateCorrections returns the newest corrections, optionally of one assistant.
+// A non-positive limit uses the default.
+func (s *AIStateCorrectionService) ListAIStateCorrections(ctx context.Context, assistant string, limit int) ([]tables.AIStateCorrectionTable, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, ErrDBNotInitialized
+	}
+	if limit <= 0 {
+		limit = defaultAIStateCorrectionLimit
+	}
+	query := db.WithContext(ensureContext(ctx)).Model(&tables.AIStateCorrectionTable{})
+	if assistant = strings.TrimSpace(assistant); assistant != "" {
+		query = query.Where("assistant = ?", assistant)
+	}
+	entries := make([]tables.AIStateCorrectionTable, 0)
+	if err := query.Order("created_at DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,51 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"code-kanban/model/tables"
+)
+
+func TestAIStateCorrectionRecordAndList(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	service := &AIStateCorrectionService{}
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	entries := []*tables.AIStateCorrectionTable{
+		{SessionID: "s1", ProjectID: "p1", Assistant: "claude-code", DetectedState: "working",
+			CorrectState: "waiting_input", Screen: "> ", Output: []byte("\x1b[2K> ")},
+		{SessionID: "s2", ProjectID: "p1", Assistant: "codex", DetectedState: "waiting_input", CorrectState: "working"},
+		{SessionID: "s1", ProjectID: "p1", Assistant: "claude-code", DetectedState: "waiting_input",
+			CorrectState: "waiting_approval", Note: "approval prompt"},
+	}
+	for i, entry := range entries {
+		entry.CreatedAt = base.Add(time.Duration(i) * time.Minute)
+		if err := service.RecordAIStateCorrection(ctx, entry); err != nil {
+			t.Fatalf("RecordAIStateCorrection returned error: %v", err)
+		}
+	}
+	if err := service.RecordAIStateCorrection(ctx, &tables.AIStateCorrectionTable{SessionID: "s1"}); !errors.Is(err, ErrAIStateCorrectionInvalid) {
+		t.Fatalf("expected ErrAIStateCorrectionInvalid, got %v", err)
+	}
+
+	list, err := service.ListAIStateCorrections(ctx, "claude-code", 0)
+	if err != nil {
+		t.Fatalf("ListAIStateCorrections returned error: %v", err)
+	}
+	if len(list) != 2 || list[0].CorrectState != "waiting_approval" || string(list[1].Output) != "\x1b[2K> " {
+		t.Fatalf("unexpected corrections: %+v", list)
+	}
+
+	all, err := service.ListAIStateCorrections(ctx, "", 1)
+	if err != nil {
+		t.Fatalf("ListAIStateCorrections returned error: %v", err)
+	}
+	if len(all) != 1 || all[0].Note != "approval prompt" {
+		t.Fatalf("unexpected limited corrections: %+v", all)
+	}
+}
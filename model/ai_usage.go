@@ -0,0 +1,213 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"code-kanban/model/tables"
+
+	"gorm.io/gorm"
+)
+
+// AI 使用记录的类型
+const (
+	AIUsageKindState      = "state"
+	AIUsageKindCompletion = "completion"
+)
+
+// AI 使用统计的分组方式
+const (
+	AIUsageGroupByAssistant = "assistant"
+	AIUsageGroupByProject   = "project"
+	AIUsageGroupByDay       = "day"
+)
+
+var (
+	// ErrAIUsageInvalid indicates a usage entry without session, project, assistant or kind.
+	ErrAIUsageInvalid = errors.New("ai usage session id, project id, assistant and kind are required")
+	// ErrAIUsageGroupBy indicates an unsupported groupBy value.
+	ErrAIUsageGroupBy = errors.New("groupBy must be assistant, project or day")
+	// ErrAIUsageRange indicates a time window whose end is not after its start.
+	ErrAIUsageRange = errors.New("to must be after from")
+)
+
+// AIUsageService records AI assistant usage and aggregates it for reports.
+type AIUsageService struct{}
+
+// AIUsageStatsRequest selects the time window and grouping of a usage report.
+// Day groups use Location, UTC when nil.
+type AIUsageStatsRequest struct {
+	From      time.Time
+	To        time.Time
+	GroupBy   string
+	ProjectID string
+	Location  *time.Location
+}
+
+// AIUsageGroup is the aggregated usage of one assistant, project or day.
+type AIUsageGroup struct {
+	// Key 为助手类型、项目 ID 或日期（2006-01-02）
+	Key string `json:"key"`
+	// Name 分组为项目时的项目名称
+	Name        string `json:"name,omitempty"`
+	Sessions    int    `json:"sessions"`
+	Completions int    `json:"completions"`
+	Interrupted int    `json:"interrupted"`
+	// StateDurationsMs 各状态在时间窗口内的累计毫秒数
+	StateDurationsMs map[string]int64 `json:"stateDurationsMs"`
+	InputTokens      int64            `json:"inputTokens"`
+	OutputTokens     int64            `json:"outputTokens"`
+	// CostUSD 仅在每条带 token 的完成记录都有估算成本时给出
+	CostUSD *float64 `json:"costUSD,omitempty"`
+}
+
+// RecordAIUsage inserts a usage entry. EndedAt defaults to now and StartedAt to EndedAt.
+func (s *AIUsageService) RecordAIUsage(ctx context.Context, entry *tables.AIUsageLogTable) error {
+	if entry == nil || strings.TrimSpace(entry.SessionID) == "" || strings.TrimSpace(entry.ProjectID) == "" ||
+		strings.TrimSpace(entry.Assistant) == "" || strings.TrimSpace(entry.Kind) == "" {
+		return ErrAIUsageInvalid
+	}
+	db := GetDB()
+	if db == nil {
+		return ErrDBNotInitialized
+	}
+	if entry.EndedAt.IsZero() {
+		entry.EndedAt = time.Now()
+	}
+	if entry.StartedAt.IsZero() {
+		entry.StartedAt = entry.EndedAt
+	}
+	return db.WithContext(ensureContext(ctx)).Create(entry).Error
+}
+
+// AIUsageStats aggregates the usage within [From, To). State spans crossing the
+// window are clipped to it; day groups use the day the span or completion ended.
+func (s *AIUsageService) AIUsageStats(ctx context.Context, req *AIUsageStatsRequest) ([]AIUsageGroup, error) {
+	if req == nil || !req.To.After(req.From) {
+		return nil, ErrAIUsageRange
+	}
+	groupBy := strings.ToLower(strings.TrimSpace(req.GroupBy))
+	if groupBy == "" {
+		groupBy = AIUsageGroupByAssistant
+	}
+	switch groupBy {
+	case AIUsageGroupByAssistant, AIUsageGroupByProject, AIUsageGroupByDay:
+	default:
+		return nil, ErrAIUsageGroupBy
+	}
+	location := req.Location
+	if location == nil {
+		location = time.UTC
+	}
+
+	db := GetDB()
+	if db == nil {
+		return nil, ErrDBNotInitialized
+	}
+	query := db.WithContext(ensureContext(ctx)).Model(&tables.AIUsageLogTable{}).
+		Where("ended_at >= ? AND started_at < ?", req.From, req.To)
+	if projectID := strings.TrimSpace(req.ProjectID); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+	entries := make([]tables.AIUsageLogTable, 0)
+	if err := query.Order("ended_at ASC").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string]*AIUsageGroup)
+	sessions := make(map[string]map[string]struct{})
+	unpriced := make(map[string]bool)
+	for _, entry := range entries {
+		var key string
+		switch groupBy {
+		case AIUsageGroupByProject:
+			key = entry.ProjectID
+		case AIUsageGroupByDay:
+			key = entry.EndedAt.In(location).Format(time.DateOnly)
+		default:
+			key = entry.Assistant
+		}
+		group, ok := groups[key]
+		if !ok {
+			group = &AIUsageGroup{Key: key, StateDurationsMs: make(map[string]int64)}
+			groups[key] = group
+			sessions[key] = make(map[string]struct{})
+		}
+		sessions[key][entry.SessionID] = struct{}{}
+
+		switch entry.Kind {
+		case AIUsageKindState:
+			start, end := entry.StartedAt, entry.EndedAt
+			if start.Before(req.From) {
+				start = req.From
+			}
+			if end.After(req.To) {
+				end = req.To
+			}
+			if end.After(start) {
+				group.StateDurationsMs[entry.State] += end.Sub(start).Milliseconds()
+			}
+		case AIUsageKindCompletion:
+			if entry.EndedAt.Before(req.From) || !entry.EndedAt.Before(req.To) {
+				continue
+			}
+			group.Completions++
+			if entry.Interrupted {
+				group.Interrupted++
+			}
+			group.InputTokens += entry.InputTokens
+			group.OutputTokens += entry.OutputTokens
+			if entry.InputTokens == 0 && entry.OutputTokens == 0 {
+				continue
+			}
+			if entry.CostUSD == nil {
+				unpriced[key] = true
+				continue
+			}
+			cost := *entry.CostUSD
+			if group.CostUSD != nil {
+				cost += *group.CostUSD
+			}
+			group.CostUSD = &cost
+		}
+	}
+
+	result := make([]AIUsageGroup, 0, len(groups))
+	for key, group := range groups {
+		group.Sessions = len(sessions[key])
+		if unpriced[key] {
+			group.CostUSD = nil
+		}
+		result = append(result, *group)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Key < result[j].Key })
+
+	if groupBy == AIUsageGroupByProject && len(result) > 0 {
+		if err := fillAIUsageProjectNames(db.WithContext(ensureContext(ctx)), result); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func fillAIUsageProjectNames(db *gorm.DB, groups []AIUsageGroup) error {
+	ids := make([]string, 0, len(groups))
+	for _, group := range groups {
+		ids = append(ids, group.Key)
+	}
+	projects := make([]tables.ProjectTable, 0, len(ids))
+	if err := db.Select("id", "name").Where("id IN ?", ids).Find(&projects).Error; err != nil {
+		return err
+	}
+	names := make(map[string]string, len(projects))
+	for _, project := range projects {
+		names[project.ID] = project.Name
+	}
+	for i := range groups {
+		groups[i].Name = names[groups[i].Key]
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"code-kanban/model/tables"
+)
+
+func TestAIUsageStatsAggregates(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	service := &AIUsageService{}
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	cost := 0.5
+	entries := []*tables.AIUsageLogTable{
+		// 跨越窗口起点的状态区间只计入窗口内的部分
+		{SessionID: "s1", ProjectID: "p1", Assistant: "claude", Kind: AIUsageKindState, State: "working",
+			StartedAt: base.Add(-time.Minute), EndedAt: base.Add(2 * time.Minute), DurationMs: 180000},
+		{SessionID: "s1", ProjectID: "p1", Assistant: "claude", Kind: AIUsageKindCompletion,
+			EndedAt: base.Add(2 * time.Minute), InputTokens: 100, OutputTokens: 20, CostUSD: &cost},
+		{SessionID: "s2", ProjectID: "p2", Assistant: "claude", Kind: AIUsageKindCompletion,
+			EndedAt: base.Add(3 * time.Minute), Interrupted: true},
+		{SessionID: "s3", ProjectID: "p2", Assistant: "codex", Kind: AIUsageKindCompletion,
+			EndedAt: base.Add(4 * time.Minute), InputTokens: 50, OutputTokens: 5},
+		// 窗口之外的记录不参与统计
+		{SessionID: "s4", ProjectID: "p1", Assistant: "claude", Kind: AIUsageKindCompletion,
+			EndedAt: base.Add(2 * time.Hour)},
+	}
+	for _, entry := range entries {
+		if err := service.RecordAIUsage(ctx, entry); err != nil {
+			t.Fatalf("RecordAIUsage returned error: %v", err)
+		}
+	}
+	if err := service.RecordAIUsage(ctx, &tables.AIUsageLogTable{SessionID: "s1"}); !errors.Is(err, ErrAIUsageInvalid) {
+		t.Fatalf("expected ErrAIUsageInvalid, got %v", err)
+	}
+
+	window := &AIUsageStatsRequest{From: base, To: base.Add(time.Hour)}
+	groups, err := service.AIUsageStats(ctx, window)
+	if err != nil {
+		t.Fatalf("AIUsageStats returned error: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Key != "claude" || groups[1].Key != "codex" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+	claude := groups[0]
+	if claude.Sessions != 2 || claude.Completions != 2 || claude.Interrupted != 1 {
+		t.Fatalf("unexpected claude counts: %+v", claude)
+	}
+	if claude.StateDurationsMs["working"] != (2 * time.Minute).Milliseconds() {
+		t.Fatalf("expected clipped working duration, got %+v", claude.StateDurationsMs)
+	}
+	if claude.InputTokens != 100 || claude.OutputTokens != 20 || claude.CostUSD == nil || *claude.CostUSD != 0.5 {
+		t.Fatalf("unexpected claude tokens: %+v", claude)
+	}
+	if codex := groups[1]; codex.InputTokens != 50 || codex.CostUSD != nil {
+		t.Fatalf("expected codex tokens without cost, got %+v", codex)
+	}
+
+	window.GroupBy = AIUsageGroupByProject
+	window.ProjectID = "p2"
+	groups, err = service.AIUsageStats(ctx, window)
+	if err != nil {
+		t.Fatalf("AIUsageStats returned error: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Key != "p2" || groups[0].Sessions != 2 || groups[0].Completions != 2 {
+		t.Fatalf("unexpected project groups: %+v", groups)
+	}
+
+	window.GroupBy = AIUsageGroupByDay
+	window.ProjectID = ""
+	window.To = base.Add(24 * time.Hour)
+	groups, err = service.AIUsageStats(ctx, window)
+	if err != nil {
+		t.Fatalf("AIUsageStats returned error: %v", err)
+	}
+	if len(groups) != 1 || groups[0].Key != "2024-01-01" || groups[0].Completions != 4 {
+		t.Fatalf("unexpected day groups: %+v", groups)
+	}
+
+	if _, err := service.AIUsageStats(ctx, &AIUsageStatsRequest{From: base, To: base.Add(time.Hour), GroupBy: "week"}); !errors.Is(err, ErrAIUsageGroupBy) {
+		t.Fatalf("expected ErrAIUsageGroupBy, got %v", err)
+	}
+	if _, err := service.AIUsageStats(ctx, &AIUsageStatsRequest{From: base, To: base}); !errors.Is(err, ErrAIUsageRange) {
+		t.Fatalf("expected ErrAIUsageRange, got %v", err)
+	}
+}
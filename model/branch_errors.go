@@ -1,14 +1,37 @@
 package model
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+
+	"code-kanban/utils/git"
+)
 
 var (
 	// ErrBranchHasWorktree indicates a branch is still referenced by one or more worktrees.
 	ErrBranchHasWorktree = errors.New("branch has associated worktree")
 	// ErrWorktreeDirty indicates merge cannot proceed due to local modifications.
 	ErrWorktreeDirty = errors.New("worktree has uncommitted changes")
-	// ErrProtectedBranch indicates delete attempts against default/current branches.
-	ErrProtectedBranch = errors.New("branch is protected and cannot be deleted")
+	// ErrProtectedBranch indicates the operation is blocked by a branch protection rule;
+	// see ProtectedBranchError for the matched rule.
+	ErrProtectedBranch = errors.New("branch is protected")
 	// ErrInvalidBranchName indicates user input fails git ref validation.
 	ErrInvalidBranchName = errors.New("invalid branch name")
+	// ErrUnpushedCommits indicates a non-forced delete would drop commits not yet pushed to upstream.
+	ErrUnpushedCommits = errors.New("commits not pushed to upstream")
 )
+
+// UnpushedCommitsError lists the commits a delete would drop; it matches
+// ErrUnpushedCommits and callers retry with force after the user confirms.
+type UnpushedCommitsError struct {
+	Branch  string
+	Commits []git.CommitInfo
+}
+
+func (e *UnpushedCommitsError) Error() string {
+	return fmt.Sprintf("%d commits on %s not pushed to upstream", len(e.Commits), e.Branch)
+}
+
+func (e *UnpushedCommitsError) Unwrap() error {
+	return ErrUnpushedCommits
+}
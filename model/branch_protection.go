@@ -0,0 +1,137 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// ErrInvalidBranchPattern indicates a protected branch pattern is not a valid glob.
+var ErrInvalidBranchPattern = errors.New("invalid protected branch pattern")
+
+// BranchOperation names a destructive branch operation checked against protection rules.
+type BranchOperation string
+
+const (
+	BranchOperationDelete BranchOperation = "delete"
+	// BranchOperationMerge 直接在受保护分支上合并其他分支
+	BranchOperationMerge BranchOperation = "merge"
+	// BranchOperationRebase 在 worktree 中 rebase 受保护分支，会改写其历史
+	BranchOperationRebase BranchOperation = "rebase"
+)
+
+// Built-in rules reported when no project pattern matched.
+const (
+	ProtectedRuleDefaultBranch = "default branch"
+	ProtectedRuleCurrentBranch = "current branch"
+)
+
+// ProtectedBranchError reports the rule that blocked an operation; it matches
+// ErrProtectedBranch.
+type ProtectedBranchError struct {
+	Branch    string
+	Operation BranchOperation
+	// Rule 命中的通配模式，或 ProtectedRuleDefaultBranch/ProtectedRuleCurrentBranch
+	Rule string
+}
+
+func (e *ProtectedBranchError) Error() string {
+	rule := e.Rule
+	if rule != ProtectedRuleDefaultBranch && rule != ProtectedRuleCurrentBranch {
+		rule = fmt.Sprintf("protection rule %q", rule)
+	}
+	return fmt.Sprintf("branch %s is protected as %s, %s is not allowed", e.Branch, rule, e.Operation)
+}
+
+func (e *ProtectedBranchError) Unwrap() error {
+	return ErrProtectedBranch
+}
+
+// ProtectedBranchPatterns returns the protected branch patterns stored on the project.
+func ProtectedBranchPatterns(project *Project) []string {
+	if project == nil || project.ProtectedBranches == nil {
+		return nil
+	}
+	var patterns []string
+	for _, line := range strings.Split(*project.ProtectedBranches, "\n") {
+		if pattern := strings.TrimSpace(line); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// MatchProtectedBranch returns the first pattern matching branch. Patterns use
+// path.Match syntax, so * does not cross a slash: release/* matches release/1.0
+// but not release/1.0/hotfix.
+func MatchProtectedBranch(patterns []string, branch string) (string, bool) {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(pattern, branch); err == nil && matched {
+			return pattern, true
+		}
+	}
+	return "", false
+}
+
+// normalizeBranchPatterns trims, validates and deduplicates patterns.
+func normalizeBranchPatterns(patterns []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(patterns))
+	result := make([]string, 0, len(patterns))
+	for _, raw := range patterns {
+		pattern := strings.TrimSpace(raw)
+		if pattern == "" {
+			continue
+		}
+		if strings.ContainsAny(pattern, " \t\n") {
+			return nil, fmt.Errorf("%w: %q contains whitespace", ErrInvalidBranchPattern, pattern)
+		}
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrInvalidBranchPattern, pattern, err)
+		}
+		if _, ok := seen[pattern]; ok {
+			continue
+		}
+		seen[pattern] = struct{}{}
+		result = append(result, pattern)
+	}
+	return result, nil
+}
+
+// UpdateProtectedBranches replaces the protected branch patterns of a project.
+// An empty list removes every rule; default and current branches stay protected.
+func (s *ProjectService) UpdateProtectedBranches(ctx context.Context, id string, patterns []string) (*Project, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	normalized, err := normalizeBranchPatterns(patterns)
+	if err != nil {
+		return nil, err
+	}
+	var stored *string
+	if len(normalized) > 0 {
+		value := strings.Join(normalized, "\n")
+		stored = &value
+	}
+
+	q, err := resolveQueries(nil)
+	if err != nil {
+		return nil, err
+	}
+	project, err := q.ProjectUpdateProtectedBranches(ctx, &ProjectUpdateProtectedBranchesParams{
+		UpdatedAt:         time.Now(),
+		ProtectedBranches: stored,
+		Id:                id,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	return project, nil
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestMatchProtectedBranch(t *testing.T) {
+	patterns := []string{"main", "release/*"}
+	cases := map[string]string{
+		"main":               "main",
+		"release/1.0":        "release/*",
+		"release/1.0/hotfix": "",
+		"feature/main":       "",
+	}
+	for branch, want := range cases {
+		got, ok := MatchProtectedBranch(patterns, branch)
+		if got != want || ok != (want != "") {
+			t.Fatalf("MatchProtectedBranch(%q) = %q, %v; want %q", branch, got, ok, want)
+		}
+	}
+}
+
+func TestProjectServiceUpdateProtectedBranches(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	service := &ProjectService{}
+	ctx := context.Background()
+	project, err := service.CreateProject(ctx, CreateProjectParams{
+		Name: "Protected Project",
+		Path: createProjectTestRepo(t),
+	})
+	if err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+
+	updated, err := service.UpdateProtectedBranches(ctx, project.Id, []string{" release/* ", "", "main", "release/*"})
+	if err != nil {
+		t.Fatalf("UpdateProtectedBranches returned error: %v", err)
+	}
+	if got := ProtectedBranchPatterns(updated); !reflect.DeepEqual(got, []string{"release/*", "main"}) {
+		t.Fatalf("unexpected patterns %v", got)
+	}
+
+	if _, err := service.UpdateProtectedBranches(ctx, project.Id, []string{"release/["}); !errors.Is(err, ErrInvalidBranchPattern) {
+		t.Fatalf("expected ErrInvalidBranchPattern, got %v", err)
+	}
+
+	cleared, err := service.UpdateProtectedBranches(ctx, project.Id, nil)
+	if err != nil {
+		t.Fatalf("clearing patterns returned error: %v", err)
+	}
+	if got := ProtectedBranchPatterns(cleared); len(got) != 0 {
+		t.Fatalf("expected no patterns after clearing, got %v", got)
+	}
+
+	if _, err := service.UpdateProtectedBranches(ctx, "missing", nil); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("expected ErrProjectNotFound, got %v", err)
+	}
+}
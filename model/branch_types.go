@@ -1,6 +1,10 @@
 package model
 
-import "code-kanban/utils/git"
+import (
+	"time"
+
+	"code-kanban/utils/git"
+)
 
 // BranchListResult describes the response for listing branches.
 type BranchListResult struct {
@@ -13,6 +17,20 @@ type MergeResult struct {
 	Success   bool     `json:"success"`
 	Conflicts []string `json:"conflicts"`
 	Message   string   `json:"message"`
+	// Operation 继续执行的操作类型（rebase/merge/cherry-pick/revert），仅 continue 接口返回
+	Operation string `json:"operation,omitempty"`
+	// BackupRef 操作前 worktree 的 HEAD，可传给 revert 接口撤销本次操作
+	BackupRef string `json:"backupRef,omitempty"`
+}
+
+// RefBackup is the HEAD of a worktree recorded before a destructive git
+// operation such as merge, rebase or reset.
+type RefBackup struct {
+	WorktreeID string    `json:"worktreeId"`
+	Operation  string    `json:"operation"`
+	Head       string    `json:"head"`
+	Branch     string    `json:"branch,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
 }
 
 // MergeBranchOptions describes optional behaviors for merge operations.
@@ -22,3 +40,32 @@ type MergeBranchOptions struct {
 	Commit        bool
 	CommitMessage string
 }
+
+// MergePreview describes the predicted outcome of merging without touching the worktree.
+type MergePreview struct {
+	SourceBranch string                 `json:"sourceBranch"`
+	TargetBranch string                 `json:"targetBranch"`
+	Strategy     string                 `json:"strategy"`
+	Files        []git.MergePreviewFile `json:"files"`
+	Conflicts    []string               `json:"conflicts"`
+	HasConflicts bool                   `json:"hasConflicts"`
+	UpToDate     bool                   `json:"upToDate"`
+}
+
+// MergedBranchDeletion reports the outcome of deleting one merged branch.
+type MergedBranchDeletion struct {
+	Branch    string   `json:"branch"`
+	Deleted   bool     `json:"deleted"`
+	Worktrees []string `json:"worktrees,omitempty"` // 随分支一起删除的 worktree ID
+	Reason    string   `json:"reason,omitempty"`    // 跳过或失败的原因
+}
+
+// WorktreeUpstream describes the upstream of the branch checked out in a
+// worktree. Without an upstream ahead/behind cannot be computed and stay 0.
+type WorktreeUpstream struct {
+	Branch      string `json:"branch"`
+	Upstream    string `json:"upstream,omitempty"`
+	HasUpstream bool   `json:"hasUpstream"`
+	Ahead       int    `json:"ahead"`
+	Behind      int    `json:"behind"`
+}
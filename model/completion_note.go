@@ -0,0 +1,74 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"gorm.io/gorm/clause"
+
+	"code-kanban/model/tables"
+)
+
+// ErrCompletionNoteInvalid indicates a note without record, session or project.
+var ErrCompletionNoteInvalid = errors.New("completion note record id, session id and project id are required")
+
+// defaultCompletionNoteLimit 未指定数量时列出的备注数
+const defaultCompletionNoteLimit = 100
+
+// CompletionNoteService stores the notes and tags of AI completion records.
+type CompletionNoteService struct{}
+
+// SaveCompletionNote inserts or updates the note of a completion record. A
+// note without text and tags is deleted.
+func (s *CompletionNoteService) SaveCompletionNote(ctx context.Context, entry *tables.CompletionNoteTable) error {
+	if entry == nil || strings.TrimSpace(entry.ID) == "" || strings.TrimSpace(entry.SessionID) == "" ||
+		strings.TrimSpace(entry.ProjectID) == "" {
+		return ErrCompletionNoteInvalid
+	}
+	db := GetDB()
+	if db == nil {
+		return ErrDBNotInitialized
+	}
+	dbCtx := db.WithContext(ensureContext(ctx))
+	if entry.Note == "" && entry.Tags == "" {
+		return dbCtx.Unscoped().Delete(&tables.CompletionNoteTable{}, "id = ?", entry.ID).Error
+	}
+	return dbCtx.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"title", "note", "tags", "updated_at"}),
+	}).Create(entry).Error
+}
+
+// MarkCompletionNoteDismissed records when the completion record behind a note
+// was dismissed. Records without a note are ignored.
+func (s *CompletionNoteService) MarkCompletionNoteDismissed(ctx context.Context, recordID string, at time.Time) error {
+	db := GetDB()
+	if db == nil {
+		return ErrDBNotInitialized
+	}
+	return db.WithContext(ensureContext(ctx)).Model(&tables.CompletionNoteTable{}).
+		Where("id = ?", recordID).Update("dismissed_at", at).Error
+}
+
+// ListCompletionNotes returns the most recently updated notes, optionally of
+// one project. A non-positive limit uses the default.
+func (s *CompletionNoteService) ListCompletionNotes(ctx context.Context, projectID string, limit int) ([]tables.CompletionNoteTable, error) {
+	db := GetDB()
+	if db == nil {
+		return nil, ErrDBNotInitialized
+	}
+	if limit <= 0 {
+		limit = defaultCompletionNoteLimit
+	}
+	query := db.WithContext(ensureContext(ctx)).Model(&tables.CompletionNoteTable{})
+	if projectID = strings.TrimSpace(projectID); projectID != "" {
+		query = query.Where("project_id = ?", projectID)
+	}
+	entries := make([]tables.CompletionNoteTable, 0)
+	if err := query.Order("updated_at DESC").Limit(limit).Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,63 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"code-kanban/model/tables"
+	"code-kanban/utils/model_base"
+)
+
+func TestCompletionNoteSaveAndList(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	service := &CompletionNoteService{}
+	note := func(id, projectID, text, tags string) *tables.CompletionNoteTable {
+		return &tables.CompletionNoteTable{
+			StringPKBaseModel: model_base.StringPKBaseModel{ID: id},
+			SessionID:         "s-" + id,
+			ProjectID:         projectID,
+			Title:             "task " + id,
+			Note:              text,
+			Tags:              tags,
+		}
+	}
+	if err := service.SaveCompletionNote(ctx, note("r1", "p1", "reviewed", "")); err != nil {
+		t.Fatalf("SaveCompletionNote returned error: %v", err)
+	}
+	if err := service.SaveCompletionNote(ctx, note("r2", "p2", "", "todo")); err != nil {
+		t.Fatalf("SaveCompletionNote returned error: %v", err)
+	}
+	// 同一记录再次保存时更新原有备注
+	if err := service.SaveCompletionNote(ctx, note("r1", "p1", "reviewed, pending test", "qa\nlater")); err != nil {
+		t.Fatalf("SaveCompletionNote returned error: %v", err)
+	}
+	if err := service.SaveCompletionNote(ctx, note("", "p1", "x", "")); !errors.Is(err, ErrCompletionNoteInvalid) {
+		t.Fatalf("expected ErrCompletionNoteInvalid, got %v", err)
+	}
+	dismissedAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	if err := service.MarkCompletionNoteDismissed(ctx, "r1", dismissedAt); err != nil {
+		t.Fatalf("MarkCompletionNoteDismissed returned error: %v", err)
+	}
+
+	list, err := service.ListCompletionNotes(ctx, "p1", 0)
+	if err != nil {
+		t.Fatalf("ListCompletionNotes returned error: %v", err)
+	}
+	if len(list) != 1 || list[0].Note != "reviewed, pending test" || list[0].Tags != "qa\nlater" ||
+		list[0].DismissedAt == nil || !list[0].DismissedAt.Equal(dismissedAt) {
+		t.Fatalf("unexpected notes: %+v", list)
+	}
+
+	// 清空备注和标签后删除
+	if err := service.SaveCompletionNote(ctx, note("r2", "p2", "", "")); err != nil {
+		t.Fatalf("SaveCompletionNote returned error: %v", err)
+	}
+	if all, err := service.ListCompletionNotes(ctx, "", 10); err != nil || len(all) != 1 || all[0].ID != "r1" {
+		t.Fatalf("unexpected notes after clearing: %+v (%v)", all, err)
+	}
+}
@@ -54,9 +54,15 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.projectUpdateStmt, err = db.PrepareContext(ctx, projectUpdate); err != nil {
 		return nil, fmt.Errorf("error preparing query ProjectUpdate: %w", err)
 	}
+	if q.projectUpdateArchivedStmt, err = db.PrepareContext(ctx, projectUpdateArchived); err != nil {
+		return nil, fmt.Errorf("error preparing query ProjectUpdateArchived: %w", err)
+	}
 	if q.projectUpdatePriorityStmt, err = db.PrepareContext(ctx, projectUpdatePriority); err != nil {
 		return nil, fmt.Errorf("error preparing query ProjectUpdatePriority: %w", err)
 	}
+	if q.projectUpdateProtectedBranchesStmt, err = db.PrepareContext(ctx, projectUpdateProtectedBranches); err != nil {
+		return nil, fmt.Errorf("error preparing query ProjectUpdateProtectedBranches: %w", err)
+	}
 	if q.taskCountByWorktreeStmt, err = db.PrepareContext(ctx, taskCountByWorktree); err != nil {
 		return nil, fmt.Errorf("error preparing query TaskCountByWorktree: %w", err)
 	}
@@ -102,6 +108,15 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.worktreeUpdateMetadataStmt, err = db.PrepareContext(ctx, worktreeUpdateMetadata); err != nil {
 		return nil, fmt.Errorf("error preparing query WorktreeUpdateMetadata: %w", err)
 	}
+	if q.worktreeUpdateOrphanStmt, err = db.PrepareContext(ctx, worktreeUpdateOrphan); err != nil {
+		return nil, fmt.Errorf("error preparing query WorktreeUpdateOrphan: %w", err)
+	}
+	if q.worktreeUpdatePathStmt, err = db.PrepareContext(ctx, worktreeUpdatePath); err != nil {
+		return nil, fmt.Errorf("error preparing query WorktreeUpdatePath: %w", err)
+	}
+	if q.worktreeUpdateReadOnlyStmt, err = db.PrepareContext(ctx, worktreeUpdateReadOnly); err != nil {
+		return nil, fmt.Errorf("error preparing query WorktreeUpdateReadOnly: %w", err)
+	}
 	if q.worktreeUpdateStatusStmt, err = db.PrepareContext(ctx, worktreeUpdateStatus); err != nil {
 		return nil, fmt.Errorf("error preparing query WorktreeUpdateStatus: %w", err)
 	}
@@ -160,11 +175,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing projectUpdateStmt: %w", cerr)
 		}
 	}
+	if q.projectUpdateArchivedStmt != nil {
+		if cerr := q.projectUpdateArchivedStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing projectUpdateArchivedStmt: %w", cerr)
+		}
+	}
 	if q.projectUpdatePriorityStmt != nil {
 		if cerr := q.projectUpdatePriorityStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing projectUpdatePriorityStmt: %w", cerr)
 		}
 	}
+	if q.projectUpdateProtectedBranchesStmt != nil {
+		if cerr := q.projectUpdateProtectedBranchesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing projectUpdateProtectedBranchesStmt: %w", cerr)
+		}
+	}
 	if q.taskCountByWorktreeStmt != nil {
 		if cerr := q.taskCountByWorktreeStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing taskCountByWorktreeStmt: %w", cerr)
@@ -240,6 +265,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing worktreeUpdateMetadataStmt: %w", cerr)
 		}
 	}
+	if q.worktreeUpdateOrphanStmt != nil {
+		if cerr := q.worktreeUpdateOrphanStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing worktreeUpdateOrphanStmt: %w", cerr)
+		}
+	}
+	if q.worktreeUpdatePathStmt != nil {
+		if cerr := q.worktreeUpdatePathStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing worktreeUpdatePathStmt: %w", cerr)
+		}
+	}
+	if q.worktreeUpdateReadOnlyStmt != nil {
+		if cerr := q.worktreeUpdateReadOnlyStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing worktreeUpdateReadOnlyStmt: %w", cerr)
+		}
+	}
 	if q.worktreeUpdateStatusStmt != nil {
 		if cerr := q.worktreeUpdateStatusStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing worktreeUpdateStatusStmt: %w", cerr)
@@ -282,67 +322,77 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 }
 
 type Queries struct {
-	db                               DBTX
-	tx                               *sql.Tx
-	accessTokenCreateStmt            *sql.Stmt
-	accessTokenDeleteAllByUserIdStmt *sql.Stmt
-	accessTokenGetByIdStmt           *sql.Stmt
-	accessTokenRefreshStmt           *sql.Stmt
-	getOneStmt                       *sql.Stmt
-	projectCreateStmt                *sql.Stmt
-	projectGetByIDStmt               *sql.Stmt
-	projectListStmt                  *sql.Stmt
-	projectSoftDeleteStmt            *sql.Stmt
-	projectUpdateStmt                *sql.Stmt
-	projectUpdatePriorityStmt        *sql.Stmt
-	taskCountByWorktreeStmt          *sql.Stmt
-	userCreateStmt                   *sql.Stmt
-	userDeleteStmt                   *sql.Stmt
-	userDisableStmt                  *sql.Stmt
-	userGetByIdStmt                  *sql.Stmt
-	userGetByUsernameStmt            *sql.Stmt
-	userListStmt                     *sql.Stmt
-	userListCountStmt                *sql.Stmt
-	userUpdateInfoStmt               *sql.Stmt
-	userUpdatePasswordStmt           *sql.Stmt
-	worktreeCreateStmt               *sql.Stmt
-	worktreeGetByIDStmt              *sql.Stmt
-	worktreeListByProjectStmt        *sql.Stmt
-	worktreeSoftDeleteStmt           *sql.Stmt
-	worktreeUpdateMetadataStmt       *sql.Stmt
-	worktreeUpdateStatusStmt         *sql.Stmt
+	db                                 DBTX
+	tx                                 *sql.Tx
+	accessTokenCreateStmt              *sql.Stmt
+	accessTokenDeleteAllByUserIdStmt   *sql.Stmt
+	accessTokenGetByIdStmt             *sql.Stmt
+	accessTokenRefreshStmt             *sql.Stmt
+	getOneStmt                         *sql.Stmt
+	projectCreateStmt                  *sql.Stmt
+	projectGetByIDStmt                 *sql.Stmt
+	projectListStmt                    *sql.Stmt
+	projectSoftDeleteStmt              *sql.Stmt
+	projectUpdateStmt                  *sql.Stmt
+	projectUpdateArchivedStmt          *sql.Stmt
+	projectUpdatePriorityStmt          *sql.Stmt
+	projectUpdateProtectedBranchesStmt *sql.Stmt
+	taskCountByWorktreeStmt            *sql.Stmt
+	userCreateStmt                     *sql.Stmt
+	userDeleteStmt                     *sql.Stmt
+	userDisableStmt                    *sql.Stmt
+	userGetByIdStmt                    *sql.Stmt
+	userGetByUsernameStmt              *sql.Stmt
+	userListStmt                       *sql.Stmt
+	userListCountStmt                  *sql.Stmt
+	userUpdateInfoStmt                 *sql.Stmt
+	userUpdatePasswordStmt             *sql.Stmt
+	worktreeCreateStmt                 *sql.Stmt
+	worktreeGetByIDStmt                *sql.Stmt
+	worktreeListByProjectStmt          *sql.Stmt
+	worktreeSoftDeleteStmt             *sql.Stmt
+	worktreeUpdateMetadataStmt         *sql.Stmt
+	worktreeUpdateOrphanStmt           *sql.Stmt
+	worktreeUpdatePathStmt             *sql.Stmt
+	worktreeUpdateReadOnlyStmt         *sql.Stmt
+	worktreeUpdateStatusStmt           *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
-		db:                               tx,
-		tx:                               tx,
-		accessTokenCreateStmt:            q.accessTokenCreateStmt,
-		accessTokenDeleteAllByUserIdStmt: q.accessTokenDeleteAllByUserIdStmt,
-		accessTokenGetByIdStmt:           q.accessTokenGetByIdStmt,
-		accessTokenRefreshStmt:           q.accessTokenRefreshStmt,
-		getOneStmt:                       q.getOneStmt,
-		projectCreateStmt:                q.projectCreateStmt,
-		projectGetByIDStmt:               q.projectGetByIDStmt,
-		projectListStmt:                  q.projectListStmt,
-		projectSoftDeleteStmt:            q.projectSoftDeleteStmt,
-		projectUpdateStmt:                q.projectUpdateStmt,
-		projectUpdatePriorityStmt:        q.projectUpdatePriorityStmt,
-		taskCountByWorktreeStmt:          q.taskCountByWorktreeStmt,
-		userCreateStmt:                   q.userCreateStmt,
-		userDeleteStmt:                   q.userDeleteStmt,
-		userDisableStmt:                  q.userDisableStmt,
-		userGetByIdStmt:                  q.userGetByIdStmt,
-		userGetByUsernameStmt:            q.userGetByUsernameStmt,
-		userListStmt:                     q.userListStmt,
-		userListCountStmt:                q.userListCountStmt,
-		userUpdateInfoStmt:               q.userUpdateInfoStmt,
-		userUpdatePasswordStmt:           q.userUpdatePasswordStmt,
-		worktreeCreateStmt:               q.worktreeCreateStmt,
-		worktreeGetByIDStmt:              q.worktreeGetByIDStmt,
-		worktreeListByProjectStmt:        q.worktreeListByProjectStmt,
-		worktreeSoftDeleteStmt:           q.worktreeSoftDeleteStmt,
-		worktreeUpdateMetadataStmt:       q.worktreeUpdateMetadataStmt,
-		worktreeUpdateStatusStmt:         q.worktreeUpdateStatusStmt,
+		db:                                 tx,
+		tx:                                 tx,
+		accessTokenCreateStmt:              q.accessTokenCreateStmt,
+		accessTokenDeleteAllByUserIdStmt:   q.accessTokenDeleteAllByUserIdStmt,
+		accessTokenGetByIdStmt:             q.accessTokenGetByIdStmt,
+		accessTokenRefreshStmt:             q.accessTokenRefreshStmt,
+		getOneStmt:                         q.getOneStmt,
+		projectCreateStmt:                  q.projectCreateStmt,
+		projectGetByIDStmt:                 q.projectGetByIDStmt,
+		projectListStmt:                    q.projectListStmt,
+		projectSoftDeleteStmt:              q.projectSoftDeleteStmt,
+		projectUpdateStmt:                  q.projectUpdateStmt,
+		projectUpdateArchivedStmt:          q.projectUpdateArchivedStmt,
+		projectUpdatePriorityStmt:          q.projectUpdatePriorityStmt,
+		projectUpdateProtectedBranchesStmt: q.projectUpdateProtectedBranchesStmt,
+		taskCountByWorktreeStmt:            q.taskCountByWorktreeStmt,
+		userCreateStmt:                     q.userCreateStmt,
+		userDeleteStmt:                     q.userDeleteStmt,
+		userDisableStmt:                    q.userDisableStmt,
+		userGetByIdStmt:                    q.userGetByIdStmt,
+		userGetByUsernameStmt:              q.userGetByUsernameStmt,
+		userListStmt:                       q.userListStmt,
+		userListCountStmt:                  q.userListCountStmt,
+		userUpdateInfoStmt:                 q.userUpdateInfoStmt,
+		userUpdatePasswordStmt:             q.userUpdatePasswordStmt,
+		worktreeCreateStmt:                 q.worktreeCreateStmt,
+		worktreeGetByIDStmt:                q.worktreeGetByIDStmt,
+		worktreeListByProjectStmt:          q.worktreeListByProjectStmt,
+		worktreeSoftDeleteStmt:             q.worktreeSoftDeleteStmt,
+		worktreeUpdateMetadataStmt:         q.worktreeUpdateMetadataStmt,
+		worktreeUpdateOrphanStmt:           q.worktreeUpdateOrphanStmt,
+		worktreeUpdatePathStmt:             q.worktreeUpdatePathStmt,
+		worktreeUpdateReadOnlyStmt:         q.worktreeUpdateReadOnlyStmt,
+		worktreeUpdateStatusStmt:           q.worktreeUpdateStatusStmt,
 	}
 }
@@ -17,6 +17,10 @@ func GetAllModels() []any {
 		&tables.TaskTable{},
 		&tables.TaskCommentTable{},
 		&tables.NotePadTable{},
+		&tables.ActivityLogTable{},
+		&tables.AIUsageLogTable{},
+		&tables.AIStateCorrectionTable{},
+		&tables.CompletionNoteTable{},
 	}
 }
 
@@ -9,19 +9,21 @@ import (
 )
 
 type Project struct {
-	Id               string     `db:"id" json:"id"`
-	CreatedAt        time.Time  `db:"created_at" json:"createdAt"`
-	UpdatedAt        time.Time  `db:"updated_at" json:"updatedAt"`
-	DeletedAt        *time.Time `db:"deleted_at" json:"deletedAt"`
-	Name             string     `db:"name" json:"name"`
-	Path             string     `db:"path" json:"path"`
-	Description      *string    `db:"description" json:"description"`
-	DefaultBranch    *string    `db:"default_branch" json:"defaultBranch"`
-	WorktreeBasePath *string    `db:"worktree_base_path" json:"worktreeBasePath"`
-	RemoteUrl        *string    `db:"remote_url" json:"remoteUrl"`
-	LastSyncAt       *time.Time `db:"last_sync_at" json:"lastSyncAt"`
-	HidePath         bool       `db:"hide_path" json:"hidePath"`
-	Priority         *int64     `db:"priority" json:"priority"`
+	Id                string     `db:"id" json:"id"`
+	CreatedAt         time.Time  `db:"created_at" json:"createdAt"`
+	UpdatedAt         time.Time  `db:"updated_at" json:"updatedAt"`
+	DeletedAt         *time.Time `db:"deleted_at" json:"deletedAt"`
+	Name              string     `db:"name" json:"name"`
+	Path              string     `db:"path" json:"path"`
+	Description       *string    `db:"description" json:"description"`
+	DefaultBranch     *string    `db:"default_branch" json:"defaultBranch"`
+	WorktreeBasePath  *string    `db:"worktree_base_path" json:"worktreeBasePath"`
+	RemoteUrl         *string    `db:"remote_url" json:"remoteUrl"`
+	LastSyncAt        *time.Time `db:"last_sync_at" json:"lastSyncAt"`
+	HidePath          bool       `db:"hide_path" json:"hidePath"`
+	Priority          *int64     `db:"priority" json:"priority"`
+	ProtectedBranches *string    `db:"protected_branches" json:"protectedBranches"`
+	Archived          bool       `db:"archived" json:"archived"`
 }
 
 type User struct {
@@ -58,4 +60,10 @@ type Worktree struct {
 	StatusUntracked   *int64     `db:"status_untracked" json:"statusUntracked"`
 	StatusConflicts   *int64     `db:"status_conflicts" json:"statusConflicts"`
 	StatusUpdatedAt   *time.Time `db:"status_updated_at" json:"statusUpdatedAt"`
+	IsOrphaned        bool       `db:"is_orphaned" json:"isOrphaned"`
+	OrphanReason      *string    `db:"orphan_reason" json:"orphanReason"`
+	BranchMismatch    bool       `db:"branch_mismatch" json:"branchMismatch"`
+	ActualBranch      *string    `db:"actual_branch" json:"actualBranch"`
+	IsDetached        bool       `db:"is_detached" json:"isDetached"`
+	ReadOnly          bool       `db:"read_only" json:"readOnly"`
 }
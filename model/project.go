@@ -27,6 +27,8 @@ var (
 	ErrProjectAlreadyExists = errors.New("project already exists")
 	// ErrProjectNotFound indicates the requested project does not exist.
 	ErrProjectNotFound = errors.New("project not found")
+	// ErrProjectArchived indicates the project is archived and does not accept new terminals or worktrees.
+	ErrProjectArchived = errors.New("project is archived")
 )
 
 // CreateProjectParams contains inputs for creating a project record.
@@ -37,7 +37,7 @@ INSERT INTO projects (
   ?10,
   ?11,
   ?12
-) RETURNING id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority
+) RETURNING id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority, protected_branches, archived
 `
 
 type ProjectCreateParams struct {
@@ -85,12 +85,14 @@ func (q *Queries) ProjectCreate(ctx context.Context, arg *ProjectCreateParams) (
 		&i.LastSyncAt,
 		&i.HidePath,
 		&i.Priority,
+		&i.ProtectedBranches,
+		&i.Archived,
 	)
 	return &i, err
 }
 
 const projectGetByID = `-- name: ProjectGetByID :one
-SELECT id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority FROM projects
+SELECT id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority, protected_branches, archived FROM projects
 WHERE id = ?1
   AND deleted_at IS NULL
 LIMIT 1
@@ -113,12 +115,14 @@ func (q *Queries) ProjectGetByID(ctx context.Context, id string) (*Project, erro
 		&i.LastSyncAt,
 		&i.HidePath,
 		&i.Priority,
+		&i.ProtectedBranches,
+		&i.Archived,
 	)
 	return &i, err
 }
 
 const projectList = `-- name: ProjectList :many
-SELECT id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority FROM projects
+SELECT id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority, protected_branches, archived FROM projects
 WHERE deleted_at IS NULL
 ORDER BY created_at DESC
 `
@@ -146,6 +150,8 @@ func (q *Queries) ProjectList(ctx context.Context) ([]*Project, error) {
 			&i.LastSyncAt,
 			&i.HidePath,
 			&i.Priority,
+			&i.ProtectedBranches,
+			&i.Archived,
 		); err != nil {
 			return nil, err
 		}
@@ -192,7 +198,7 @@ SET
   hide_path = ?4
 WHERE id = ?5
   AND deleted_at IS NULL
-RETURNING id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority
+RETURNING id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority, protected_branches, archived
 `
 
 type ProjectUpdateParams struct {
@@ -226,6 +232,47 @@ func (q *Queries) ProjectUpdate(ctx context.Context, arg *ProjectUpdateParams) (
 		&i.LastSyncAt,
 		&i.HidePath,
 		&i.Priority,
+		&i.ProtectedBranches,
+		&i.Archived,
+	)
+	return &i, err
+}
+
+const projectUpdateArchived = `-- name: ProjectUpdateArchived :one
+UPDATE projects
+SET
+  updated_at = ?1,
+  archived = ?2
+WHERE id = ?3
+  AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority, protected_branches, archived
+`
+
+type ProjectUpdateArchivedParams struct {
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+	Archived  bool      `db:"archived" json:"archived"`
+	Id        string    `db:"id" json:"id"`
+}
+
+func (q *Queries) ProjectUpdateArchived(ctx context.Context, arg *ProjectUpdateArchivedParams) (*Project, error) {
+	row := q.queryRow(ctx, q.projectUpdateArchivedStmt, projectUpdateArchived, arg.UpdatedAt, arg.Archived, arg.Id)
+	var i Project
+	err := row.Scan(
+		&i.Id,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Name,
+		&i.Path,
+		&i.Description,
+		&i.DefaultBranch,
+		&i.WorktreeBasePath,
+		&i.RemoteUrl,
+		&i.LastSyncAt,
+		&i.HidePath,
+		&i.Priority,
+		&i.ProtectedBranches,
+		&i.Archived,
 	)
 	return &i, err
 }
@@ -237,7 +284,7 @@ SET
   priority = ?2
 WHERE id = ?3
   AND deleted_at IS NULL
-RETURNING id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority
+RETURNING id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority, protected_branches, archived
 `
 
 type ProjectUpdatePriorityParams struct {
@@ -263,6 +310,47 @@ func (q *Queries) ProjectUpdatePriority(ctx context.Context, arg *ProjectUpdateP
 		&i.LastSyncAt,
 		&i.HidePath,
 		&i.Priority,
+		&i.ProtectedBranches,
+		&i.Archived,
+	)
+	return &i, err
+}
+
+const projectUpdateProtectedBranches = `-- name: ProjectUpdateProtectedBranches :one
+UPDATE projects
+SET
+  updated_at = ?1,
+  protected_branches = ?2
+WHERE id = ?3
+  AND deleted_at IS NULL
+RETURNING id, created_at, updated_at, deleted_at, name, path, description, default_branch, worktree_base_path, remote_url, last_sync_at, hide_path, priority, protected_branches, archived
+`
+
+type ProjectUpdateProtectedBranchesParams struct {
+	UpdatedAt         time.Time `db:"updated_at" json:"updatedAt"`
+	ProtectedBranches *string   `db:"protected_branches" json:"protectedBranches"`
+	Id                string    `db:"id" json:"id"`
+}
+
+func (q *Queries) ProjectUpdateProtectedBranches(ctx context.Context, arg *ProjectUpdateProtectedBranchesParams) (*Project, error) {
+	row := q.queryRow(ctx, q.projectUpdateProtectedBranchesStmt, projectUpdateProtectedBranches, arg.UpdatedAt, arg.ProtectedBranches, arg.Id)
+	var i Project
+	err := row.Scan(
+		&i.Id,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.DeletedAt,
+		&i.Name,
+		&i.Path,
+		&i.Description,
+		&i.DefaultBranch,
+		&i.WorktreeBasePath,
+		&i.RemoteUrl,
+		&i.LastSyncAt,
+		&i.HidePath,
+		&i.Priority,
+		&i.ProtectedBranches,
+		&i.Archived,
 	)
 	return &i, err
 }
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+// SetArchived archives or restores a project. Archiving only marks the project:
+// its worktrees, tasks and running terminals are kept, but it is hidden from
+// the project list by default and no new terminals or worktrees are created
+// for it.
+func (s *ProjectService) SetArchived(ctx context.Context, id string, archived bool) (*Project, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	q, err := resolveQueries(nil)
+	if err != nil {
+		return nil, err
+	}
+	project, err := q.ProjectUpdateArchived(ctx, &ProjectUpdateArchivedParams{
+		UpdatedAt: time.Now(),
+		Archived:  archived,
+		Id:        id,
+	})
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrProjectNotFound
+		}
+		return nil, err
+	}
+	return project, nil
+}
+
+// EnsureProjectActive returns ErrProjectArchived when the project is archived.
+func (s *ProjectService) EnsureProjectActive(ctx context.Context, id string) error {
+	project, err := s.GetProject(ctx, id)
+	if err != nil {
+		return err
+	}
+	if project.Archived {
+		return ErrProjectArchived
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestProjectServiceSetArchived(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	service := &ProjectService{}
+
+	ctx := context.Background()
+	project, err := service.CreateProject(ctx, CreateProjectParams{
+		Name: "Archive Me",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+	if project.Archived {
+		t.Fatalf("expected new project to be active")
+	}
+	if err := service.EnsureProjectActive(ctx, project.Id); err != nil {
+		t.Fatalf("EnsureProjectActive returned error: %v", err)
+	}
+
+	archived, err := service.SetArchived(ctx, project.Id, true)
+	if err != nil {
+		t.Fatalf("SetArchived returned error: %v", err)
+	}
+	if !archived.Archived {
+		t.Fatalf("expected project to be archived")
+	}
+	if err := service.EnsureProjectActive(ctx, project.Id); !errors.Is(err, ErrProjectArchived) {
+		t.Fatalf("expected ErrProjectArchived, got %v", err)
+	}
+
+	restored, err := service.SetArchived(ctx, project.Id, false)
+	if err != nil {
+		t.Fatalf("SetArchived returned error: %v", err)
+	}
+	if restored.Archived {
+		t.Fatalf("expected project to be restored")
+	}
+
+	if _, err := service.SetArchived(ctx, "missing", true); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("expected ErrProjectNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils"
+	"code-kanban/utils/git"
+)
+
+// CloneProjectParams describes a remote repository to clone and register as a project.
+type CloneProjectParams struct {
+	URL string
+	// Name 为空时取仓库地址的最后一段（去掉 .git）
+	Name string
+	// Path clone 的目标目录，必须是尚不存在的绝对路径
+	Path        string
+	Description string
+	Depth       int
+	Branch      string
+	// RecurseSubmodules 同时初始化并克隆子模块
+	RecurseSubmodules bool
+	OnProgress        git.ProgressFunc
+}
+
+// CreateFromClone clones the repository and registers the clone as a project.
+// The cloned directory is removed again when the project cannot be created.
+func (s *ProjectService) CreateFromClone(ctx context.Context, params CloneProjectParams) (*Project, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	url := strings.TrimSpace(params.URL)
+	dest := strings.TrimSpace(params.Path)
+	if url == "" || dest == "" {
+		return nil, fmt.Errorf("%w: clone url and path are required", ErrInvalidProjectInput)
+	}
+	if !filepath.IsAbs(dest) {
+		return nil, fmt.Errorf("%w: clone destination must be an absolute path", ErrInvalidProjectPath)
+	}
+	dest = filepath.Clean(dest)
+	if _, err := os.Stat(dest); err == nil {
+		return nil, fmt.Errorf("%w: %s already exists", ErrInvalidProjectPath, dest)
+	} else if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidProjectPath, err)
+	}
+	if _, err := resolveQueries(nil); err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		name = repositoryNameFromURL(url)
+	}
+	if name == "" {
+		name = filepath.Base(dest)
+	}
+
+	if err := git.Clone(ctx, url, dest, git.CloneOptions{
+		Depth:             params.Depth,
+		Branch:            params.Branch,
+		RecurseSubmodules: params.RecurseSubmodules,
+		OnProgress:        params.OnProgress,
+	}); err != nil {
+		return nil, err
+	}
+
+	project, err := s.CreateProject(ctx, CreateProjectParams{
+		Name:        name,
+		Path:        dest,
+		Description: params.Description,
+	})
+	if err != nil {
+		if removeErr := os.RemoveAll(dest); removeErr != nil {
+			utils.Logger().Warn("failed to remove cloned repository",
+				zap.String("path", dest),
+				zap.Error(removeErr),
+			)
+		}
+		return nil, err
+	}
+	return project, nil
+}
+
+// repositoryNameFromURL returns the last path segment of a clone url without
+// the .git suffix, for https, ssh and scp-like (git@host:owner/repo.git) urls.
+func repositoryNameFromURL(url string) string {
+	trimmed := strings.TrimRight(strings.TrimSpace(url), "/")
+	if idx := strings.LastIndexAny(trimmed, "/:\\"); idx >= 0 {
+		trimmed = trimmed[idx+1:]
+	}
+	trimmed = strings.TrimSuffix(trimmed, ".git")
+	if trimmed == "" || trimmed == "." || trimmed == ".." {
+		return ""
+	}
+	return trimmed
+}
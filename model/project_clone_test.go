@@ -0,0 +1,61 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProjectServiceCreateFromClone(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	source := createProjectTestRepo(t)
+	service := &ProjectService{}
+	ctx := context.Background()
+	dest := filepath.Join(t.TempDir(), "cloned")
+
+	project, err := service.CreateFromClone(ctx, CloneProjectParams{
+		URL:   "file://" + filepath.ToSlash(source),
+		Path:  dest,
+		Depth: 1,
+	})
+	if err != nil {
+		t.Fatalf("CreateFromClone returned error: %v", err)
+	}
+	if project.Path != dest || project.Name != filepath.Base(source) {
+		t.Fatalf("unexpected project %q at %q", project.Name, project.Path)
+	}
+	if _, err := os.Stat(filepath.Join(dest, ".git")); err != nil {
+		t.Fatalf("expected cloned repository: %v", err)
+	}
+
+	if _, err := service.CreateFromClone(ctx, CloneProjectParams{URL: source, Path: dest}); !errors.Is(err, ErrInvalidProjectPath) {
+		t.Fatalf("expected ErrInvalidProjectPath for existing destination, got %v", err)
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	if _, err := service.CreateFromClone(ctx, CloneProjectParams{URL: "file:///nonexistent/repo", Path: missing}); err == nil {
+		t.Fatalf("expected clone failure")
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Fatalf("expected failed clone to be cleaned up, got %v", err)
+	}
+}
+
+func TestRepositoryNameFromURL(t *testing.T) {
+	cases := map[string]string{
+		"https://github.com/fy0/CodeKanban.git": "CodeKanban",
+		"git@github.com:fy0/CodeKanban.git":     "CodeKanban",
+		"ssh://git@example.com/group/repo/":     "repo",
+		"file:///tmp/projects/local":            "local",
+		"https://example.com/":                  "example.com",
+	}
+	for url, want := range cases {
+		if got := repositoryNameFromURL(url); got != want {
+			t.Fatalf("repositoryNameFromURL(%q) = %q, want %q", url, got, want)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package model
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"code-kanban/utils/git"
+)
+
+// ProjectCredentialTTL 项目临时凭据的有效期，过期后网络操作回到系统凭据
+const ProjectCredentialTTL = time.Hour
+
+type projectCredential struct {
+	token     string
+	expiresAt time.Time
+}
+
+// projectCredentials 只保存在内存中，进程退出即失效
+var projectCredentials = struct {
+	mu      sync.Mutex
+	entries map[string]projectCredential
+}{entries: make(map[string]projectCredential)}
+
+// SetCredential keeps a token for the git network operations of the project,
+// e.g. for an internal HTTP remote, for ProjectCredentialTTL. The token is only
+// held in memory and is never written to the repository config or the remote
+// URL; see git.WithCredential for how it is sent. An empty token removes the
+// credential.
+func (s *ProjectService) SetCredential(ctx context.Context, projectID, token string) error {
+	project, err := s.GetProject(ctx, projectID)
+	if err != nil {
+		return err
+	}
+	token = strings.TrimSpace(token)
+
+	projectCredentials.mu.Lock()
+	defer projectCredentials.mu.Unlock()
+	if token == "" {
+		delete(projectCredentials.entries, project.Id)
+		return nil
+	}
+	projectCredentials.entries[project.Id] = projectCredential{
+		token:     token,
+		expiresAt: time.Now().Add(ProjectCredentialTTL),
+	}
+	return nil
+}
+
+// WithProjectCredential attaches the unexpired credential of the project to ctx
+// for git commands; ctx is returned unchanged when there is none.
+func WithProjectCredential(ctx context.Context, projectID string) context.Context {
+	projectCredentials.mu.Lock()
+	entry, ok := projectCredentials.entries[projectID]
+	if ok && time.Now().After(entry.expiresAt) {
+		delete(projectCredentials.entries, projectID)
+		ok = false
+	}
+	projectCredentials.mu.Unlock()
+	if !ok {
+		return ctx
+	}
+	return git.WithCredential(ctx, entry.token)
+}
@@ -0,0 +1,54 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestProjectServiceSetCredential(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	service := &ProjectService{}
+	ctx := context.Background()
+	if err := service.SetCredential(ctx, "missing", "token"); !errors.Is(err, ErrProjectNotFound) {
+		t.Fatalf("expected ErrProjectNotFound, got %v", err)
+	}
+
+	project, err := service.CreateProject(ctx, CreateProjectParams{Name: "demo", Path: createProjectTestRepo(t)})
+	if err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+	if got := WithProjectCredential(ctx, project.Id); got != ctx {
+		t.Fatalf("expected context unchanged without credential")
+	}
+
+	if err := service.SetCredential(ctx, project.Id, "token"); err != nil {
+		t.Fatalf("SetCredential returned error: %v", err)
+	}
+	if got := WithProjectCredential(ctx, project.Id); got == ctx {
+		t.Fatalf("expected credential to be attached")
+	}
+
+	// 过期的凭据不再使用并被移除
+	projectCredentials.mu.Lock()
+	entry := projectCredentials.entries[project.Id]
+	entry.expiresAt = time.Now().Add(-time.Second)
+	projectCredentials.entries[project.Id] = entry
+	projectCredentials.mu.Unlock()
+	if got := WithProjectCredential(ctx, project.Id); got != ctx {
+		t.Fatalf("expected expired credential to be ignored")
+	}
+
+	if err := service.SetCredential(ctx, project.Id, "token"); err != nil {
+		t.Fatalf("SetCredential returned error: %v", err)
+	}
+	if err := service.SetCredential(ctx, project.Id, " "); err != nil {
+		t.Fatalf("SetCredential clear returned error: %v", err)
+	}
+	if got := WithProjectCredential(ctx, project.Id); got != ctx {
+		t.Fatalf("expected cleared credential to be removed")
+	}
+}
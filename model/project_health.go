@@ -0,0 +1,35 @@
+package model
+
+import (
+	"errors"
+	"time"
+
+	"code-kanban/utils/git"
+)
+
+// ErrGCAlreadyRunning indicates a git gc for the project has not finished yet.
+var ErrGCAlreadyRunning = errors.New("git gc is already running for this project")
+
+// ProjectGCStatus describes the last background git gc of a project.
+type ProjectGCStatus struct {
+	Running    bool       `json:"running"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// ProjectHealth reports the object storage of a project repository.
+type ProjectHealth struct {
+	ProjectID string `json:"projectId"`
+	git.RepoHealth
+	// GC 最近一次后台 gc 的状态，从未执行时为空
+	GC *ProjectGCStatus `json:"gc,omitempty"`
+}
+
+// ProjectGitConfig lists the repository-local git config of a project.
+type ProjectGitConfig struct {
+	ProjectID string            `json:"projectId"`
+	Entries   map[string]string `json:"entries"`
+	// CommonKeys 便于表单化编辑的常用配置项
+	CommonKeys []git.ConfigKey `json:"commonKeys"`
+}
@@ -0,0 +1,79 @@
+package model
+
+import (
+	"errors"
+	"time"
+)
+
+// ProjectExportVersion 当前导出格式版本，导入时拒绝更高的版本
+const ProjectExportVersion = 1
+
+// ErrInvalidProjectExport indicates the import data is not a usable project export.
+var ErrInvalidProjectExport = errors.New("invalid project export")
+
+// ProjectExport is the portable JSON form of a project: its settings, notepads and
+// worktree list. Git content is never included.
+type ProjectExport struct {
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exportedAt"`
+	Project    ProjectExportInfo `json:"project"`
+	NotePads   []NotePadExport   `json:"notepads"`
+	Worktrees  []WorktreeExport  `json:"worktrees"`
+}
+
+// ProjectExportInfo holds the exported project settings.
+type ProjectExportInfo struct {
+	ID                string   `json:"id"` // 原机器上的项目 ID，导入时重新生成
+	Name              string   `json:"name"`
+	Path              string   `json:"path"`
+	Description       string   `json:"description,omitempty"`
+	DefaultBranch     string   `json:"defaultBranch,omitempty"`
+	WorktreeBasePath  string   `json:"worktreeBasePath,omitempty"`
+	RemoteURL         string   `json:"remoteUrl,omitempty"`
+	HidePath          bool     `json:"hidePath"`
+	Priority          *int64   `json:"priority,omitempty"`
+	ProtectedBranches []string `json:"protectedBranches,omitempty"` // 分支保护规则，导入新项目时一并恢复
+	Archived          bool     `json:"archived,omitempty"`          // 已归档的项目导入后仍为归档状态
+}
+
+// NotePadExport is an exported project notepad.
+type NotePadExport struct {
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	Content    string  `json:"content"`
+	OrderIndex float64 `json:"orderIndex"`
+}
+
+// WorktreeExport is an exported worktree record.
+type WorktreeExport struct {
+	ID         string `json:"id"`
+	BranchName string `json:"branchName"`
+	Path       string `json:"path"`
+	IsMain     bool   `json:"isMain"`
+}
+
+// Statuses reported for each item of a project import.
+const (
+	ImportStatusImported = "imported"
+	// ImportStatusSkipped 已存在相同内容，未重复导入
+	ImportStatusSkipped = "skipped"
+	// ImportStatusMissing 目标在本机不存在，补齐后重新导入即可恢复
+	ImportStatusMissing = "missing"
+)
+
+// ProjectImportItem reports what happened to one exported item.
+type ProjectImportItem struct {
+	Kind     string `json:"kind"` // project、notepad 或 worktree
+	Name     string `json:"name"`
+	SourceID string `json:"sourceId,omitempty"` // 导出数据中的 ID
+	ID       string `json:"id,omitempty"`       // 本机的 ID，未导入时为空
+	Status   string `json:"status"`
+	Reason   string `json:"reason,omitempty"`
+}
+
+// ProjectImportReport summarizes a project import.
+type ProjectImportReport struct {
+	Project *Project            `json:"project"`
+	Created bool                `json:"created"` // false 表示导入到了路径相同的已有项目
+	Items   []ProjectImportItem `json:"items"`
+}
@@ -58,6 +58,15 @@ SET
 WHERE id = @id
   AND deleted_at IS NULL;
 
+-- name: ProjectUpdateArchived :one
+UPDATE projects
+SET
+  updated_at = @updated_at,
+  archived = @archived
+WHERE id = @id
+  AND deleted_at IS NULL
+RETURNING *;
+
 -- name: ProjectUpdatePriority :one
 UPDATE projects
 SET
@@ -66,3 +75,12 @@ SET
 WHERE id = @id
   AND deleted_at IS NULL
 RETURNING *;
+
+-- name: ProjectUpdateProtectedBranches :one
+UPDATE projects
+SET
+  updated_at = @updated_at,
+  protected_branches = @protected_branches
+WHERE id = @id
+  AND deleted_at IS NULL
+RETURNING *;
@@ -17,7 +17,8 @@ INSERT INTO worktrees (
   status_staged,
   status_untracked,
   status_conflicts,
-  status_updated_at
+  status_updated_at,
+  is_detached
 ) VALUES (
   @id,
   @created_at,
@@ -36,7 +37,8 @@ INSERT INTO worktrees (
   @status_staged,
   @status_untracked,
   @status_conflicts,
-  @status_updated_at
+  @status_updated_at,
+  @is_detached
 ) RETURNING *;
 
 -- name: WorktreeGetByID :one
@@ -59,7 +61,13 @@ SELECT
   status_staged,
   status_untracked,
   status_conflicts,
-  status_updated_at
+  status_updated_at,
+  is_orphaned,
+  orphan_reason,
+  branch_mismatch,
+  actual_branch,
+  is_detached,
+  read_only
 FROM worktrees
 WHERE id = @id
   AND deleted_at IS NULL
@@ -90,6 +98,11 @@ SET
   status_untracked = @status_untracked,
   status_conflicts = @status_conflicts,
   status_updated_at = @status_updated_at,
+  is_orphaned = @is_orphaned,
+  orphan_reason = @orphan_reason,
+  branch_mismatch = @branch_mismatch,
+  actual_branch = @actual_branch,
+  branch_name = COALESCE(@branch_name, branch_name),
   head_commit = COALESCE(@head_commit, head_commit),
   head_commit_message = COALESCE(@head_commit_message, head_commit_message),
   head_commit_date = COALESCE(@head_commit_date, head_commit_date)
@@ -114,7 +127,9 @@ RETURNING
   status_staged,
   status_untracked,
   status_conflicts,
-  status_updated_at;
+  status_updated_at,
+  is_orphaned,
+  orphan_reason;
 
 -- name: WorktreeUpdateMetadata :exec
 UPDATE worktrees
@@ -124,6 +139,33 @@ SET
   head_commit = @head_commit,
   head_commit_message = NULL,
   is_main = @is_main,
-  is_bare = @is_bare
+  is_bare = @is_bare,
+  branch_mismatch = false,
+  actual_branch = NULL
+WHERE id = @id
+  AND deleted_at IS NULL;
+
+-- name: WorktreeUpdatePath :exec
+UPDATE worktrees
+SET
+  updated_at = @updated_at,
+  path = @path
+WHERE id = @id
+  AND deleted_at IS NULL;
+
+-- name: WorktreeUpdateOrphan :exec
+UPDATE worktrees
+SET
+  updated_at = @updated_at,
+  is_orphaned = @is_orphaned,
+  orphan_reason = @orphan_reason
+WHERE id = @id
+  AND deleted_at IS NULL;
+
+-- name: WorktreeUpdateReadOnly :exec
+UPDATE worktrees
+SET
+  updated_at = @updated_at,
+  read_only = @read_only
 WHERE id = @id
   AND deleted_at IS NULL;
@@ -14,13 +14,13 @@ CREATE INDEX "idx_access_tokens_user_id" ON "user_access_tokens"("user_id");
 CREATE INDEX "idx_user_access_tokens_deleted_at" ON "user_access_tokens"("deleted_at");
 
 
-CREATE TABLE "projects" ("id" text NOT NULL,"created_at" datetime,"updated_at" datetime,"deleted_at" datetime,"name" text NOT NULL,"path" text NOT NULL,"description" text,"default_branch" text,"worktree_base_path" text,"remote_url" text,"last_sync_at" datetime,"hide_path" boolean NOT NULL DEFAULT false,"priority" integer,PRIMARY KEY ("id"));
+CREATE TABLE "projects" ("id" text NOT NULL,"created_at" datetime,"updated_at" datetime,"deleted_at" datetime,"name" text NOT NULL,"path" text NOT NULL,"description" text,"default_branch" text,"worktree_base_path" text,"remote_url" text,"last_sync_at" datetime,"hide_path" boolean NOT NULL DEFAULT false,"priority" integer,"protected_branches" text,"archived" boolean NOT NULL DEFAULT false,PRIMARY KEY ("id"));
 CREATE UNIQUE INDEX "idx_projects_path" ON "projects"("path");
 CREATE INDEX "idx_projects_name" ON "projects"("name");
 CREATE INDEX "idx_projects_deleted_at" ON "projects"("deleted_at");
 
 
-CREATE TABLE "worktrees" ("id" text NOT NULL,"created_at" datetime,"updated_at" datetime,"deleted_at" datetime,"project_id" text NOT NULL,"branch_name" text NOT NULL,"path" text NOT NULL,"is_main" boolean DEFAULT false,"is_bare" boolean DEFAULT false,"head_commit" text,"head_commit_message" text,"head_commit_date" datetime,"status_ahead" integer DEFAULT 0,"status_behind" integer DEFAULT 0,"status_modified" integer DEFAULT 0,"status_staged" integer DEFAULT 0,"status_untracked" integer DEFAULT 0,"status_conflicts" integer DEFAULT 0,"status_updated_at" datetime,PRIMARY KEY ("id"));
+CREATE TABLE "worktrees" ("id" text NOT NULL,"created_at" datetime,"updated_at" datetime,"deleted_at" datetime,"project_id" text NOT NULL,"branch_name" text NOT NULL,"path" text NOT NULL,"is_main" boolean DEFAULT false,"is_bare" boolean DEFAULT false,"head_commit" text,"head_commit_message" text,"head_commit_date" datetime,"status_ahead" integer DEFAULT 0,"status_behind" integer DEFAULT 0,"status_modified" integer DEFAULT 0,"status_staged" integer DEFAULT 0,"status_untracked" integer DEFAULT 0,"status_conflicts" integer DEFAULT 0,"status_updated_at" datetime,"is_orphaned" boolean DEFAULT false,"orphan_reason" text,"branch_mismatch" boolean DEFAULT false,"actual_branch" text,"is_detached" boolean NOT NULL DEFAULT false,"read_only" boolean NOT NULL DEFAULT false,PRIMARY KEY ("id"));
 CREATE UNIQUE INDEX "idx_worktrees_path" ON "worktrees"("path") WHERE deleted_at IS NULL;
 CREATE INDEX "idx_worktrees_branch_name" ON "worktrees"("branch_name");
 CREATE INDEX "idx_worktrees_project_id" ON "worktrees"("project_id");
@@ -46,3 +46,25 @@ CREATE INDEX "idx_notepads_order_index" ON "notepads"("order_index");
 CREATE INDEX "idx_notepads_project_id" ON "notepads"("project_id");
 CREATE INDEX "idx_notepads_deleted_at" ON "notepads"("deleted_at");
 
+
+CREATE TABLE "activity_logs" ("id" text NOT NULL,"created_at" datetime,"updated_at" datetime,"deleted_at" datetime,"project_id" text NOT NULL,"type" text NOT NULL,"actor" text,"target" text,"detail" text,"time" datetime NOT NULL,PRIMARY KEY ("id"));
+CREATE INDEX "idx_activity_logs_type" ON "activity_logs"("type");
+CREATE INDEX "idx_activity_logs_project_time" ON "activity_logs"("project_id","time");
+CREATE INDEX "idx_activity_logs_deleted_at" ON "activity_logs"("deleted_at");
+
+
+CREATE TABLE "ai_usage_logs" ("id" text NOT NULL,"created_at" datetime,"updated_at" datetime,"deleted_at" datetime,"session_id" text NOT NULL,"project_id" text NOT NULL,"assistant" text NOT NULL,"kind" text NOT NULL,"state" text,"started_at" datetime NOT NULL,"ended_at" datetime NOT NULL,"duration_ms" integer NOT NULL DEFAULT 0,"interrupted" boolean NOT NULL DEFAULT false,"input_tokens" integer NOT NULL DEFAULT 0,"output_tokens" integer NOT NULL DEFAULT 0,"cost_usd" real,PRIMARY KEY ("id"));
+CREATE INDEX "idx_ai_usage_logs_session_id" ON "ai_usage_logs"("session_id");
+CREATE INDEX "idx_ai_usage_logs_project_id" ON "ai_usage_logs"("project_id");
+CREATE INDEX "idx_ai_usage_logs_ended_at" ON "ai_usage_logs"("ended_at");
+CREATE INDEX "idx_ai_usage_logs_deleted_at" ON "ai_usage_logs"("deleted_at");
+
+CREATE TABLE "ai_state_corrections" ("id" text NOT NULL,"created_at" datetime,"updated_at" datetime,"deleted_at" datetime,"session_id" text NOT NULL,"project_id" text NOT NULL,"assistant" text NOT NULL,"detected_state" text NOT NULL,"detected_match" text,"detected_confidence" text,"correct_state" text NOT NULL,"note" text,"screen" text,"output" blob,"rows" integer NOT NULL DEFAULT 0,"cols" integer NOT NULL DEFAULT 0,PRIMARY KEY ("id"));
+CREATE INDEX "idx_ai_state_corrections_session_id" ON "ai_state_corrections"("session_id");
+CREATE INDEX "idx_ai_state_corrections_assistant" ON "ai_state_corrections"("assistant");
+CREATE INDEX "idx_ai_state_corrections_deleted_at" ON "ai_state_corrections"("deleted_at");
+
+CREATE TABLE "completion_notes" ("id" text NOT NULL,"created_at" datetime,"updated_at" datetime,"deleted_at" datetime,"session_id" text NOT NULL,"project_id" text NOT NULL,"title" text,"note" text,"tags" text,"dismissed_at" datetime,PRIMARY KEY ("id"));
+CREATE INDEX "idx_completion_notes_session_id" ON "completion_notes"("session_id");
+CREATE INDEX "idx_completion_notes_project_id" ON "completion_notes"("project_id");
+CREATE INDEX "idx_completion_notes_deleted_at" ON "completion_notes"("deleted_at");
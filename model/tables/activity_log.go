@@ -0,0 +1,24 @@
+package tables
+
+import (
+	"time"
+
+	"code-kanban/utils/model_base"
+)
+
+// ActivityLogTable stores project level activity entries such as branch, worktree and commit events.
+type ActivityLogTable struct {
+	model_base.StringPKBaseModel
+
+	ProjectID string    `gorm:"type:text;not null;index:idx_activity_logs_project_time,priority:1" json:"projectId"`
+	Type      string    `gorm:"type:text;not null;index" json:"type"`
+	Actor     string    `gorm:"type:text" json:"actor"`
+	Target    string    `gorm:"type:text" json:"target"`
+	Detail    string    `gorm:"type:text" json:"detail"`
+	Time      time.Time `gorm:"not null;index:idx_activity_logs_project_time,priority:2" json:"time"`
+}
+
+// TableName maps the gorm model to the activity_logs table.
+func (ActivityLogTable) TableName() string {
+	return "activity_logs"
+}
@@ -0,0 +1,32 @@
+package tables
+
+import (
+	"code-kanban/utils/model_base"
+)
+
+// AIStateCorrectionTable stores a user correction of a misdetected AI assistant
+// state together with the screen the detector saw, for improving detectors and
+// as regression fixtures.
+type AIStateCorrectionTable struct {
+	model_base.StringPKBaseModel
+
+	SessionID string `gorm:"type:text;not null;index" json:"sessionId"`
+	ProjectID string `gorm:"type:text;not null" json:"projectId"`
+	Assistant string `gorm:"type:text;not null;index" json:"assistant"`
+	// DetectedState/DetectedMatch/DetectedConfidence 纠正前检测器给出的判断
+	DetectedState      string `gorm:"type:text;not null" json:"detectedState"`
+	DetectedMatch      string `gorm:"type:text" json:"detectedMatch,omitempty"`
+	DetectedConfidence string `gorm:"type:text" json:"detectedConfidence,omitempty"`
+	CorrectState       string `gorm:"type:text;not null" json:"correctState"`
+	Note               string `gorm:"type:text" json:"note,omitempty"`
+	// Screen 纠正时渲染的屏幕文本，Output 为最近的原始输出，可直接回放给检测器
+	Screen string `gorm:"type:text" json:"screen"`
+	Output []byte `gorm:"type:blob" json:"output,omitempty"`
+	Rows   int    `gorm:"not null;default:0" json:"rows"`
+	Cols   int    `gorm:"not null;default:0" json:"cols"`
+}
+
+// TableName maps the gorm model to the ai_state_corrections table.
+func (AIStateCorrectionTable) TableName() string {
+	return "ai_state_corrections"
+}
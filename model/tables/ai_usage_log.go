@@ -0,0 +1,34 @@
+package tables
+
+import (
+	"time"
+
+	"code-kanban/utils/model_base"
+)
+
+// AIUsageLogTable stores AI assistant usage of terminal sessions: one row per
+// finished state span and one per completed task.
+type AIUsageLogTable struct {
+	model_base.StringPKBaseModel
+
+	SessionID string `gorm:"type:text;not null;index" json:"sessionId"`
+	ProjectID string `gorm:"type:text;not null;index" json:"projectId"`
+	Assistant string `gorm:"type:text;not null" json:"assistant"`
+	// Kind 为 state（状态持续区间）或 completion（一次任务完成）
+	Kind  string `gorm:"type:text;not null" json:"kind"`
+	State string `gorm:"type:text" json:"state"`
+	// StartedAt/EndedAt 状态区间的起止时间，完成记录两者相同
+	StartedAt   time.Time `gorm:"not null" json:"startedAt"`
+	EndedAt     time.Time `gorm:"not null;index" json:"endedAt"`
+	DurationMs  int64     `gorm:"not null;default:0" json:"durationMs"`
+	Interrupted bool      `gorm:"type:boolean;not null;default:false" json:"interrupted"`
+	// 本次任务新增的 token 与估算成本，未知时为 0 / 空
+	InputTokens  int64    `gorm:"not null;default:0" json:"inputTokens"`
+	OutputTokens int64    `gorm:"not null;default:0" json:"outputTokens"`
+	CostUSD      *float64 `json:"costUSD,omitempty"`
+}
+
+// TableName maps the gorm model to the ai_usage_logs table.
+func (AIUsageLogTable) TableName() string {
+	return "ai_usage_logs"
+}
@@ -0,0 +1,29 @@
+package tables
+
+import (
+	"time"
+
+	"code-kanban/utils/model_base"
+)
+
+// CompletionNoteTable stores the note and tags a user attached to an AI
+// completion record. Records live in memory with their session; the note is
+// kept here so it survives the record being replaced, cleared or the server
+// restarting. The primary key is the completion record ID.
+type CompletionNoteTable struct {
+	model_base.StringPKBaseModel
+
+	SessionID string `gorm:"type:text;not null;index" json:"sessionId"`
+	ProjectID string `gorm:"type:text;not null;index" json:"projectId"`
+	Title     string `gorm:"type:text" json:"title"`
+	Note      string `gorm:"type:text" json:"note,omitempty"`
+	// Tags 标签，每行一个
+	Tags string `gorm:"type:text" json:"tags,omitempty"`
+	// DismissedAt 用户关闭记录的时间，未关闭时为空
+	DismissedAt *time.Time `gorm:"type:datetime" json:"dismissedAt,omitempty"`
+}
+
+// TableName maps the gorm model to the completion_notes table.
+func (CompletionNoteTable) TableName() string {
+	return "completion_notes"
+}
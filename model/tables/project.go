@@ -19,6 +19,10 @@ type ProjectTable struct {
 	LastSyncAt       *time.Time `gorm:"type:datetime" json:"lastSyncAt"`
 	HidePath         bool       `gorm:"type:boolean;not null;default:false" json:"hidePath"`
 	Priority         *int64     `gorm:"type:integer" json:"priority"`
+	// ProtectedBranches 受保护分支名或通配模式，每行一个
+	ProtectedBranches *string `gorm:"type:text" json:"protectedBranches"`
+	// Archived 归档的项目默认不在列表中显示，也不能新建终端与 worktree
+	Archived bool `gorm:"type:boolean;not null;default:false" json:"archived"`
 }
 
 // TableName maps the gorm model to the projects table.
@@ -27,6 +27,20 @@ type WorktreeTable struct {
 	StatusConflicts int        `gorm:"type:integer;default:0" json:"statusConflicts"`
 	StatusUpdatedAt *time.Time `gorm:"type:datetime" json:"statusUpdatedAt"`
 
+	IsOrphaned   bool   `gorm:"type:boolean;default:false" json:"isOrphaned"`
+	OrphanReason string `gorm:"type:text" json:"orphanReason"`
+
+	// BranchMismatch worktree 实际检出的分支（ActualBranch）与 BranchName 不一致，
+	// 通常是用户在终端里手动 git checkout 了其他分支
+	BranchMismatch bool   `gorm:"type:boolean;default:false" json:"branchMismatch"`
+	ActualBranch   string `gorm:"type:text" json:"actualBranch"`
+
+	// IsDetached 基于某个提交以 detached HEAD 创建，不关联分支，BranchName 为空
+	IsDetached bool `gorm:"type:boolean;not null;default:false" json:"isDetached"`
+
+	// ReadOnly 只读模式，拒绝提交、合并、重置等改动内容的操作；与删除保护无关
+	ReadOnly bool `gorm:"type:boolean;not null;default:false" json:"readOnly"`
+
 	Project *ProjectTable `gorm:"foreignKey:ProjectID;constraint:OnDelete:CASCADE" json:"project,omitempty"`
 }
 
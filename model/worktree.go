@@ -14,10 +14,47 @@ var (
 	ErrWorktreeIsMain = errors.New("cannot delete main worktree")
 	// ErrWorktreeHasTasks indicates there are tasks referencing the worktree requiring a force delete.
 	ErrWorktreeHasTasks = errors.New("worktree has active tasks")
+	// ErrWorktreeHasSessions indicates terminal sessions are still open in the worktree.
+	ErrWorktreeHasSessions = errors.New("worktree has open terminal sessions")
 	// ErrWorktreeClean indicates there are no changes to commit.
 	ErrWorktreeClean = errors.New("worktree has no changes to commit")
+	// ErrResetConfirmRequired indicates a hard reset was requested without explicit confirmation.
+	ErrResetConfirmRequired = errors.New("hard reset requires explicit confirmation")
+	// ErrDiscardConfirmRequired indicates discarding file changes was requested without explicit confirmation.
+	ErrDiscardConfirmRequired = errors.New("discarding changes requires explicit confirmation")
+	// ErrWorktreePathConflict indicates the move target already exists.
+	ErrWorktreePathConflict = errors.New("target path already exists")
+	// ErrWorktreeDetached indicates the operation needs a branch but the worktree has a detached HEAD.
+	ErrWorktreeDetached = errors.New("worktree has a detached HEAD and no branch")
+	// ErrNoRefBackup indicates no HEAD was recorded before a destructive operation in the worktree.
+	ErrNoRefBackup = errors.New("no ref backup recorded for worktree")
+	// ErrWorktreeReadOnly indicates the worktree is read-only and its contents cannot be changed.
+	ErrWorktreeReadOnly = errors.New("worktree is read-only")
 )
 
+// Orphan reasons reported when a worktree no longer points at its tracked branch.
+const (
+	// WorktreeOrphanDetachedHead indicates the worktree HEAD is detached from any branch.
+	WorktreeOrphanDetachedHead = "detached_head"
+	// WorktreeOrphanBranchMissing indicates the recorded branch no longer exists.
+	WorktreeOrphanBranchMissing = "branch_missing"
+	// WorktreeOrphanPathMissing indicates the worktree directory has been removed from disk.
+	WorktreeOrphanPathMissing = "path_missing"
+)
+
+// WorktreeCleanupResult summarizes what CleanupOrphans removed.
+type WorktreeCleanupResult struct {
+	RemovedRecords   []string `json:"removedRecords"`   // 已软删除的数据库记录 ID
+	RemovedWorktrees []string `json:"removedWorktrees"` // 已从磁盘移除的 worktree 路径
+	SkippedWorktrees []string `json:"skippedWorktrees"` // 有本地改动而保留的无记录 worktree 路径
+}
+
+// WorktreePruneResult summarizes what PruneWorktrees cleaned up.
+type WorktreePruneResult struct {
+	Pruned         []string `json:"pruned"`         // git worktree prune -v 清理的条目
+	RemovedRecords []string `json:"removedRecords"` // 随后同步时软删除的数据库记录 ID
+}
+
 // NormalizePathCase cleans the path and lowercases it on Windows for reliable comparisons.
 func NormalizePathCase(path string) string {
 	clean := filepath.Clean(path)
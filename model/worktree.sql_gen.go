@@ -29,7 +29,8 @@ INSERT INTO worktrees (
   status_staged,
   status_untracked,
   status_conflicts,
-  status_updated_at
+  status_updated_at,
+  is_detached
 ) VALUES (
   ?1,
   ?2,
@@ -48,8 +49,9 @@ INSERT INTO worktrees (
   ?15,
   ?16,
   ?17,
-  ?18
-) RETURNING id, created_at, updated_at, deleted_at, project_id, branch_name, path, is_main, is_bare, head_commit, head_commit_message, head_commit_date, status_ahead, status_behind, status_modified, status_staged, status_untracked, status_conflicts, status_updated_at
+  ?18,
+  ?19
+) RETURNING id, created_at, updated_at, deleted_at, project_id, branch_name, path, is_main, is_bare, head_commit, head_commit_message, head_commit_date, status_ahead, status_behind, status_modified, status_staged, status_untracked, status_conflicts, status_updated_at, is_orphaned, orphan_reason, branch_mismatch, actual_branch, is_detached, read_only
 `
 
 type WorktreeCreateParams struct {
@@ -71,6 +73,7 @@ type WorktreeCreateParams struct {
 	StatusUntracked   *int64     `db:"status_untracked" json:"statusUntracked"`
 	StatusConflicts   *int64     `db:"status_conflicts" json:"statusConflicts"`
 	StatusUpdatedAt   *time.Time `db:"status_updated_at" json:"statusUpdatedAt"`
+	IsDetached        bool       `db:"is_detached" json:"isDetached"`
 }
 
 func (q *Queries) WorktreeCreate(ctx context.Context, arg *WorktreeCreateParams) (*Worktree, error) {
@@ -93,6 +96,7 @@ func (q *Queries) WorktreeCreate(ctx context.Context, arg *WorktreeCreateParams)
 		arg.StatusUntracked,
 		arg.StatusConflicts,
 		arg.StatusUpdatedAt,
+		arg.IsDetached,
 	)
 	var i Worktree
 	err := row.Scan(
@@ -115,6 +119,12 @@ func (q *Queries) WorktreeCreate(ctx context.Context, arg *WorktreeCreateParams)
 		&i.StatusUntracked,
 		&i.StatusConflicts,
 		&i.StatusUpdatedAt,
+		&i.IsOrphaned,
+		&i.OrphanReason,
+		&i.BranchMismatch,
+		&i.ActualBranch,
+		&i.IsDetached,
+		&i.ReadOnly,
 	)
 	return &i, err
 }
@@ -139,7 +149,13 @@ SELECT
   status_staged,
   status_untracked,
   status_conflicts,
-  status_updated_at
+  status_updated_at,
+  is_orphaned,
+  orphan_reason,
+  branch_mismatch,
+  actual_branch,
+  is_detached,
+  read_only
 FROM worktrees
 WHERE id = ?1
   AND deleted_at IS NULL
@@ -169,12 +185,18 @@ func (q *Queries) WorktreeGetByID(ctx context.Context, id string) (*Worktree, er
 		&i.StatusUntracked,
 		&i.StatusConflicts,
 		&i.StatusUpdatedAt,
+		&i.IsOrphaned,
+		&i.OrphanReason,
+		&i.BranchMismatch,
+		&i.ActualBranch,
+		&i.IsDetached,
+		&i.ReadOnly,
 	)
 	return &i, err
 }
 
 const worktreeListByProject = `-- name: WorktreeListByProject :many
-SELECT id, created_at, updated_at, deleted_at, project_id, branch_name, path, is_main, is_bare, head_commit, head_commit_message, head_commit_date, status_ahead, status_behind, status_modified, status_staged, status_untracked, status_conflicts, status_updated_at FROM worktrees
+SELECT id, created_at, updated_at, deleted_at, project_id, branch_name, path, is_main, is_bare, head_commit, head_commit_message, head_commit_date, status_ahead, status_behind, status_modified, status_staged, status_untracked, status_conflicts, status_updated_at, is_orphaned, orphan_reason, branch_mismatch, actual_branch, is_detached, read_only FROM worktrees
 WHERE project_id = ?1
   AND deleted_at IS NULL
 ORDER BY is_main DESC, created_at ASC
@@ -209,6 +231,12 @@ func (q *Queries) WorktreeListByProject(ctx context.Context, projectID string) (
 			&i.StatusUntracked,
 			&i.StatusConflicts,
 			&i.StatusUpdatedAt,
+			&i.IsOrphaned,
+			&i.OrphanReason,
+			&i.BranchMismatch,
+			&i.ActualBranch,
+			&i.IsDetached,
+			&i.ReadOnly,
 		); err != nil {
 			return nil, err
 		}
@@ -254,7 +282,9 @@ SET
   head_commit = ?3,
   head_commit_message = NULL,
   is_main = ?4,
-  is_bare = ?5
+  is_bare = ?5,
+  branch_mismatch = false,
+  actual_branch = NULL
 WHERE id = ?6
   AND deleted_at IS NULL
 `
@@ -280,6 +310,73 @@ func (q *Queries) WorktreeUpdateMetadata(ctx context.Context, arg *WorktreeUpdat
 	return err
 }
 
+const worktreeUpdateOrphan = `-- name: WorktreeUpdateOrphan :exec
+UPDATE worktrees
+SET
+  updated_at = ?1,
+  is_orphaned = ?2,
+  orphan_reason = ?3
+WHERE id = ?4
+  AND deleted_at IS NULL
+`
+
+type WorktreeUpdateOrphanParams struct {
+	UpdatedAt    time.Time `db:"updated_at" json:"updatedAt"`
+	IsOrphaned   bool      `db:"is_orphaned" json:"isOrphaned"`
+	OrphanReason *string   `db:"orphan_reason" json:"orphanReason"`
+	Id           string    `db:"id" json:"id"`
+}
+
+func (q *Queries) WorktreeUpdateOrphan(ctx context.Context, arg *WorktreeUpdateOrphanParams) error {
+	_, err := q.exec(ctx, q.worktreeUpdateOrphanStmt, worktreeUpdateOrphan,
+		arg.UpdatedAt,
+		arg.IsOrphaned,
+		arg.OrphanReason,
+		arg.Id,
+	)
+	return err
+}
+
+const worktreeUpdatePath = `-- name: WorktreeUpdatePath :exec
+UPDATE worktrees
+SET
+  updated_at = ?1,
+  path = ?2
+WHERE id = ?3
+  AND deleted_at IS NULL
+`
+
+type WorktreeUpdatePathParams struct {
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+	Path      string    `db:"path" json:"path"`
+	Id        string    `db:"id" json:"id"`
+}
+
+func (q *Queries) WorktreeUpdatePath(ctx context.Context, arg *WorktreeUpdatePathParams) error {
+	_, err := q.exec(ctx, q.worktreeUpdatePathStmt, worktreeUpdatePath, arg.UpdatedAt, arg.Path, arg.Id)
+	return err
+}
+
+const worktreeUpdateReadOnly = `-- name: WorktreeUpdateReadOnly :exec
+UPDATE worktrees
+SET
+  updated_at = ?1,
+  read_only = ?2
+WHERE id = ?3
+  AND deleted_at IS NULL
+`
+
+type WorktreeUpdateReadOnlyParams struct {
+	UpdatedAt time.Time `db:"updated_at" json:"updatedAt"`
+	ReadOnly  bool      `db:"read_only" json:"readOnly"`
+	Id        string    `db:"id" json:"id"`
+}
+
+func (q *Queries) WorktreeUpdateReadOnly(ctx context.Context, arg *WorktreeUpdateReadOnlyParams) error {
+	_, err := q.exec(ctx, q.worktreeUpdateReadOnlyStmt, worktreeUpdateReadOnly, arg.UpdatedAt, arg.ReadOnly, arg.Id)
+	return err
+}
+
 const worktreeUpdateStatus = `-- name: WorktreeUpdateStatus :one
 UPDATE worktrees
 SET
@@ -291,10 +388,15 @@ SET
   status_untracked = ?6,
   status_conflicts = ?7,
   status_updated_at = ?8,
-  head_commit = COALESCE(?9, head_commit),
-  head_commit_message = COALESCE(?10, head_commit_message),
-  head_commit_date = COALESCE(?11, head_commit_date)
-WHERE id = ?12
+  is_orphaned = ?9,
+  orphan_reason = ?10,
+  branch_mismatch = ?11,
+  actual_branch = ?12,
+  branch_name = COALESCE(?13, branch_name),
+  head_commit = COALESCE(?14, head_commit),
+  head_commit_message = COALESCE(?15, head_commit_message),
+  head_commit_date = COALESCE(?16, head_commit_date)
+WHERE id = ?17
   AND deleted_at IS NULL
 RETURNING
   id,
@@ -315,7 +417,13 @@ RETURNING
   status_staged,
   status_untracked,
   status_conflicts,
-  status_updated_at
+  status_updated_at,
+  is_orphaned,
+  orphan_reason,
+  branch_mismatch,
+  actual_branch,
+  is_detached,
+  read_only
 `
 
 type WorktreeUpdateStatusParams struct {
@@ -327,6 +435,11 @@ type WorktreeUpdateStatusParams struct {
 	StatusUntracked   *int64     `db:"status_untracked" json:"statusUntracked"`
 	StatusConflicts   *int64     `db:"status_conflicts" json:"statusConflicts"`
 	StatusUpdatedAt   *time.Time `db:"status_updated_at" json:"statusUpdatedAt"`
+	IsOrphaned        bool       `db:"is_orphaned" json:"isOrphaned"`
+	OrphanReason      *string    `db:"orphan_reason" json:"orphanReason"`
+	BranchMismatch    bool       `db:"branch_mismatch" json:"branchMismatch"`
+	ActualBranch      *string    `db:"actual_branch" json:"actualBranch"`
+	BranchName        *string    `db:"branch_name" json:"branchName"`
 	HeadCommit        *string    `db:"head_commit" json:"headCommit"`
 	HeadCommitMessage *string    `db:"head_commit_message" json:"headCommitMessage"`
 	HeadCommitDate    *time.Time `db:"head_commit_date" json:"headCommitDate"`
@@ -343,6 +456,11 @@ func (q *Queries) WorktreeUpdateStatus(ctx context.Context, arg *WorktreeUpdateS
 		arg.StatusUntracked,
 		arg.StatusConflicts,
 		arg.StatusUpdatedAt,
+		arg.IsOrphaned,
+		arg.OrphanReason,
+		arg.BranchMismatch,
+		arg.ActualBranch,
+		arg.BranchName,
 		arg.HeadCommit,
 		arg.HeadCommitMessage,
 		arg.HeadCommitDate,
@@ -369,6 +487,12 @@ func (q *Queries) WorktreeUpdateStatus(ctx context.Context, arg *WorktreeUpdateS
 		&i.StatusUntracked,
 		&i.StatusConflicts,
 		&i.StatusUpdatedAt,
+		&i.IsOrphaned,
+		&i.OrphanReason,
+		&i.BranchMismatch,
+		&i.ActualBranch,
+		&i.IsDetached,
+		&i.ReadOnly,
 	)
 	return &i, err
 }
@@ -0,0 +1,22 @@
+package service
+
+import (
+	"context"
+
+	"code-kanban/model"
+	"code-kanban/utils"
+
+	"go.uber.org/zap"
+)
+
+// recordActivity appends an entry to the project activity stream. Failures are
+// only logged so the activity log never breaks the operation being recorded.
+func recordActivity(ctx context.Context, req model.RecordActivityRequest) {
+	if _, err := (&model.ActivityService{}).RecordActivity(ctx, &req); err != nil {
+		utils.LoggerFromContext(ctx).Warn("record activity failed",
+			zap.Error(err),
+			zap.String("projectId", req.ProjectID),
+			zap.String("type", req.Type),
+		)
+	}
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/utils/git"
+)
+
+// RebaseWorktree rebases the branch checked out in the worktree onto another
+// branch, the project default branch when onto is empty. On conflicts the
+// rebase stays in progress and the result lists the conflicted files; resolve
+// them and call ContinueRebase, or AbortRebase to restore the branch.
+func (s *BranchService) RebaseWorktree(ctx context.Context, worktreeID, onto string) (*model.MergeResult, error) {
+	ctx = ensureContext(ctx)
+	logger := s.logger(ctx)
+
+	worktreeService := NewWorktreeService()
+	worktree, err := worktreeService.GetWorktree(ctx, worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureWorktreeWritable(worktree); err != nil {
+		return nil, err
+	}
+
+	project, repo, err := s.getProjectAndRepo(ctx, worktree.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	branch := strings.TrimSpace(worktree.BranchName)
+	if branch == "" {
+		if worktree.IsDetached {
+			return nil, model.ErrWorktreeDetached
+		}
+		return nil, errors.New("worktree has no branch to rebase")
+	}
+	upstream := s.mergeTarget(project, onto)
+	if upstream == "" {
+		return nil, errors.New("onto branch is required")
+	}
+	if upstream == branch {
+		return nil, fmt.Errorf("cannot rebase %s onto itself", branch)
+	}
+	if err := checkBranchProtection(project, "", branch, model.BranchOperationRebase); err != nil {
+		logger.Warn("attempted to rebase protected branch",
+			zap.Error(err),
+			zap.String("projectId", project.Id),
+			zap.String("worktreeId", worktree.Id),
+			zap.String("onto", upstream),
+		)
+		return nil, err
+	}
+
+	status, err := git.GetWorktreeStatus(worktree.Path)
+	if err != nil {
+		return nil, err
+	}
+	if status.Modified > 0 || status.Staged > 0 || status.Conflicted > 0 {
+		logger.Warn("worktree dirty before rebase",
+			zap.String("projectId", project.Id),
+			zap.String("worktreeId", worktree.Id),
+			zap.String("path", worktree.Path),
+		)
+		return nil, model.ErrWorktreeDirty
+	}
+
+	backup := backupWorktreeHead(ctx, worktree, "rebase")
+	result, err := s.rebaseResult(ctx, worktree, repo.RebaseOnto(worktree.Path, upstream))
+	if err != nil {
+		logger.Error("rebase failed",
+			zap.Error(err),
+			zap.String("projectId", project.Id),
+			zap.String("worktreeId", worktree.Id),
+			zap.String("branch", branch),
+			zap.String("onto", upstream),
+		)
+		return nil, err
+	}
+	result.BackupRef = backup
+	s.refreshBranches(ctx, worktreeService, project.Id, branch)
+	s.invalidateCache(project.Id)
+	if result.Success {
+		recordActivity(ctx, model.RecordActivityRequest{
+			ProjectID: project.Id,
+			Type:      model.ActivityBranchRebased,
+			Target:    branch,
+			Detail:    fmt.Sprintf("rebase %s onto %s", branch, upstream),
+		})
+		logger.Info("rebase completed",
+			zap.String("projectId", project.Id),
+			zap.String("worktreeId", worktree.Id),
+			zap.String("branch", branch),
+			zap.String("onto", upstream),
+		)
+	}
+	return result, nil
+}
+
+// ContinueRebase resumes a rebase stopped on conflicts once they are resolved
+// and staged. Further conflicts are reported the same way as RebaseWorktree.
+func (s *BranchService) ContinueRebase(ctx context.Context, worktreeID string) (*model.MergeResult, error) {
+	return s.stepRebase(ctx, worktreeID, (*git.GitRepo).RebaseContinue)
+}
+
+// SkipRebase drops the commit the rebase stopped on and continues with the rest.
+func (s *BranchService) SkipRebase(ctx context.Context, worktreeID string) (*model.MergeResult, error) {
+	return s.stepRebase(ctx, worktreeID, (*git.GitRepo).RebaseSkip)
+}
+
+func (s *BranchService) stepRebase(ctx context.Context, worktreeID string, step func(*git.GitRepo, string) error) (*model.MergeResult, error) {
+	ctx = ensureContext(ctx)
+	worktree, repo, err := s.getWritableWorktreeAndRepo(ctx, worktreeID)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := s.rebaseResult(ctx, worktree, step(repo, worktree.Path))
+	if err != nil {
+		return nil, err
+	}
+	s.refreshBranches(ctx, NewWorktreeService(), worktree.ProjectId, worktree.BranchName)
+	s.invalidateCache(worktree.ProjectId)
+	return result, nil
+}
+
+// ContinueOperation continues the rebase, merge, cherry-pick or revert stopped
+// in the worktree, whichever is in progress. Unresolved files are reported as
+// conflicts without touching the worktree.
+func (s *BranchService) ContinueOperation(ctx context.Context, worktreeID string) (*model.MergeResult, error) {
+	ctx = ensureContext(ctx)
+	worktree, repo, err := s.getWritableWorktreeAndRepo(ctx, worktreeID)
+	if err != nil {
+		return nil, err
+	}
+
+	operation := repo.InProgressOperation(worktree.Path)
+	if operation == git.OperationNone {
+		return nil, git.ErrNoOperationInProgress
+	}
+	if conflicts := repo.GetConflictFiles(worktree.Path); len(conflicts) > 0 {
+		return &model.MergeResult{
+			Success:   false,
+			Conflicts: conflicts,
+			Message:   "resolve conflicts before continuing",
+			Operation: string(operation),
+		}, nil
+	}
+
+	_, continueErr := repo.ContinueOperation(worktree.Path)
+	result, err := s.rebaseResult(ctx, worktree, continueErr)
+	if err != nil {
+		s.logger(ctx).Error("continue operation failed",
+			zap.Error(err),
+			zap.String("projectId", worktree.ProjectId),
+			zap.String("worktreeId", worktree.Id),
+			zap.String("operation", string(operation)),
+		)
+		return nil, err
+	}
+	if result.Success {
+		result.Message = fmt.Sprintf("%s continued successfully", operation)
+	}
+	result.Operation = string(operation)
+	s.refreshBranches(ctx, NewWorktreeService(), worktree.ProjectId, worktree.BranchName)
+	s.invalidateCache(worktree.ProjectId)
+	return result, nil
+}
+
+// AbortRebase abandons a stopped rebase and restores the worktree branch.
+func (s *BranchService) AbortRebase(ctx context.Context, worktreeID string) error {
+	ctx = ensureContext(ctx)
+	worktree, repo, err := s.getWritableWorktreeAndRepo(ctx, worktreeID)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.RebaseAbort(worktree.Path); err != nil {
+		return err
+	}
+	s.logger(ctx).Info("rebase aborted",
+		zap.String("projectId", worktree.ProjectId),
+		zap.String("worktreeId", worktree.Id),
+	)
+	s.refreshBranches(ctx, NewWorktreeService(), worktree.ProjectId, worktree.BranchName)
+	s.invalidateCache(worktree.ProjectId)
+	return nil
+}
+
+// getWritableWorktreeAndRepo loads a worktree for an operation that changes
+// its contents, rejecting read-only worktrees.
+func (s *BranchService) getWritableWorktreeAndRepo(ctx context.Context, worktreeID string) (*model.Worktree, *git.GitRepo, error) {
+	worktree, err := NewWorktreeService().GetWorktree(ctx, worktreeID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := ensureWorktreeWritable(worktree); err != nil {
+		return nil, nil, err
+	}
+	_, repo, err := s.getProjectAndRepo(ctx, worktree.ProjectId)
+	if err != nil {
+		return nil, nil, err
+	}
+	return worktree, repo, nil
+}
+
+// rebaseResult converts a rebase conflict into an unsuccessful MergeResult.
+func (s *BranchService) rebaseResult(ctx context.Context, worktree *model.Worktree, err error) (*model.MergeResult, error) {
+	var conflict *git.RebaseConflictError
+	switch {
+	case err == nil:
+		return &model.MergeResult{Success: true, Message: "rebased successfully"}, nil
+	case errors.As(err, &conflict):
+		s.logger(ctx).Warn("rebase encountered conflicts",
+			zap.String("projectId", worktree.ProjectId),
+			zap.String("worktreeId", worktree.Id),
+			zap.Strings("conflicts", conflict.Files),
+		)
+		return &model.MergeResult{Success: false, Conflicts: conflict.Files, Message: "rebase has conflicts"}, nil
+	default:
+		return nil, err
+	}
+}
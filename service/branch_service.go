@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 
@@ -129,7 +130,7 @@ func (s *BranchService) CreateBranch(ctx context.Context, projectID, name, base
 
 	if createWorktree {
 		worktreeService := NewWorktreeService()
-		if _, err := worktreeService.CreateWorktree(ctx, projectID, branchName, baseBranch, false); err != nil {
+		if _, err := worktreeService.CreateWorktree(ctx, projectID, branchName, baseBranch, false, false); err != nil {
 			logger.Error("create worktree for branch failed",
 				zap.Error(err),
 				zap.String("projectId", projectID),
@@ -140,6 +141,12 @@ func (s *BranchService) CreateBranch(ctx context.Context, projectID, name, base
 	}
 
 	s.invalidateCache(projectID)
+	recordActivity(ctx, model.RecordActivityRequest{
+		ProjectID: projectID,
+		Type:      model.ActivityBranchCreated,
+		Target:    branchName,
+		Detail:    "from " + baseBranch,
+	})
 	logger.Info("branch created",
 		zap.String("projectId", projectID),
 		zap.String("branch", branchName),
@@ -163,22 +170,13 @@ func (s *BranchService) DeleteBranch(ctx context.Context, projectID, name string
 	if branchName == "" {
 		return fmt.Errorf("branch name is required")
 	}
-	if project.DefaultBranch != nil {
-		defaultBranch := strings.TrimSpace(*project.DefaultBranch)
-		if defaultBranch != "" && branchName == defaultBranch {
-			logger.Warn("attempted to delete default branch",
-				zap.String("projectId", projectID),
-				zap.String("branch", branchName),
-			)
-			return model.ErrProtectedBranch
-		}
-	}
-	if current, currErr := repo.GetCurrentBranch(); currErr == nil && branchName == current {
-		logger.Warn("attempted to delete current branch",
+	if err := checkBranchProtection(project, currentBranch(repo), branchName, model.BranchOperationDelete); err != nil {
+		logger.Warn("attempted to delete protected branch",
+			zap.Error(err),
 			zap.String("projectId", projectID),
 			zap.String("branch", branchName),
 		)
-		return model.ErrProtectedBranch
+		return err
 	}
 
 	dbCtx, err := s.dbWithContext(ctx)
@@ -218,6 +216,13 @@ func (s *BranchService) DeleteBranch(ctx context.Context, projectID, name string
 		}
 	}
 
+	if !force {
+		commits, err := repo.UnpushedCommits(branchName)
+		if err := checkUnpushedCommits(ctx, branchName, commits, err); err != nil {
+			return err
+		}
+	}
+
 	if err := repo.DeleteBranch(branchName, force); err != nil {
 		logger.Error("delete branch failed",
 			zap.Error(err),
@@ -229,6 +234,11 @@ func (s *BranchService) DeleteBranch(ctx context.Context, projectID, name string
 	}
 
 	s.invalidateCache(projectID)
+	recordActivity(ctx, model.RecordActivityRequest{
+		ProjectID: projectID,
+		Type:      model.ActivityBranchDeleted,
+		Target:    branchName,
+	})
 	logger.Info("branch deleted",
 		zap.String("projectId", projectID),
 		zap.String("branch", branchName),
@@ -237,6 +247,197 @@ func (s *BranchService) DeleteBranch(ctx context.Context, projectID, name string
 	return nil
 }
 
+// ListMergedBranches returns local branches already merged into intoBranch,
+// which defaults to the project's default branch. intoBranch itself is excluded.
+func (s *BranchService) ListMergedBranches(ctx context.Context, projectID, intoBranch string) ([]string, error) {
+	ctx = ensureContext(ctx)
+	project, repo, err := s.getProjectAndRepo(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	into := s.mergeTarget(project, intoBranch)
+	branches, err := repo.ListMergedBranches(into)
+	if err != nil {
+		return nil, err
+	}
+	merged := make([]string, 0, len(branches))
+	for _, branch := range branches {
+		if branch != into {
+			merged = append(merged, branch)
+		}
+	}
+	return merged, nil
+}
+
+// DeleteMergedBranches deletes every branch merged into intoBranch together with
+// its worktrees. Protected branches and branches whose worktrees still have open
+// terminal sessions are skipped, and worktrees are removed without force so
+// tasks and unpushed commits still block the cleanup of that branch. Failures
+// are reported per branch instead of aborting.
+func (s *BranchService) DeleteMergedBranches(ctx context.Context, projectID, intoBranch string) ([]model.MergedBranchDeletion, error) {
+	ctx = ensureContext(ctx)
+	logger := s.logger(ctx)
+
+	project, repo, err := s.getProjectAndRepo(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	branches, err := s.ListMergedBranches(ctx, projectID, intoBranch)
+	if err != nil {
+		return nil, err
+	}
+	dbCtx, err := s.dbWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	current := currentBranch(repo)
+	worktreeService := NewWorktreeService()
+	results := make([]model.MergedBranchDeletion, 0, len(branches))
+	for _, branch := range branches {
+		result := model.MergedBranchDeletion{Branch: branch}
+		if err := checkBranchProtection(project, current, branch, model.BranchOperationDelete); err != nil {
+			result.Reason = err.Error()
+			results = append(results, result)
+			continue
+		}
+
+		var worktrees []tables.WorktreeTable
+		if err := dbCtx.Where("project_id = ? AND branch_name = ?", projectID, branch).Find(&worktrees).Error; err != nil {
+			return results, err
+		}
+		// 终端仍在 worktree 中运行时整个分支跳过，避免删掉会话的工作目录
+		if slices.ContainsFunc(worktrees, func(wt tables.WorktreeTable) bool { return worktreeHasSessions(wt.ID) }) {
+			result.Reason = model.ErrWorktreeHasSessions.Error()
+			results = append(results, result)
+			continue
+		}
+		var failed error
+		for _, wt := range worktrees {
+			if err := worktreeService.DeleteWorktree(ctx, wt.ID, false, false); err != nil && !errors.Is(err, model.ErrWorktreeNotFound) {
+				failed = err
+				break
+			}
+			result.Worktrees = append(result.Worktrees, wt.ID)
+		}
+		if failed == nil {
+			// 已确认合并进目标分支，提交不会丢失；目标分支不是 HEAD 时 -d 会拒绝删除
+			failed = repo.DeleteBranch(branch, true)
+		}
+		if failed != nil {
+			logger.Warn("delete merged branch failed",
+				zap.Error(failed),
+				zap.String("projectId", projectID),
+				zap.String("branch", branch),
+			)
+			result.Reason = failed.Error()
+			results = append(results, result)
+			continue
+		}
+
+		result.Deleted = true
+		results = append(results, result)
+		recordActivity(ctx, model.RecordActivityRequest{
+			ProjectID: projectID,
+			Type:      model.ActivityBranchDeleted,
+			Target:    branch,
+		})
+	}
+
+	s.invalidateCache(projectID)
+	logger.Info("merged branches cleaned up",
+		zap.String("projectId", projectID),
+		zap.Int("merged", len(branches)),
+	)
+	return results, nil
+}
+
+// checkBranchProtection applies the project patterns to every operation. The
+// default branch and the branch checked out in the main repository, current, are
+// additionally protected from deletion.
+func checkBranchProtection(project *model.Project, current, branch string, op model.BranchOperation) error {
+	if op == model.BranchOperationDelete {
+		if project.DefaultBranch != nil {
+			if defaultBranch := strings.TrimSpace(*project.DefaultBranch); defaultBranch != "" && branch == defaultBranch {
+				return &model.ProtectedBranchError{Branch: branch, Operation: op, Rule: model.ProtectedRuleDefaultBranch}
+			}
+		}
+		if current != "" && branch == current {
+			return &model.ProtectedBranchError{Branch: branch, Operation: op, Rule: model.ProtectedRuleCurrentBranch}
+		}
+	}
+	if rule, ok := model.MatchProtectedBranch(model.ProtectedBranchPatterns(project), branch); ok {
+		return &model.ProtectedBranchError{Branch: branch, Operation: op, Rule: rule}
+	}
+	return nil
+}
+
+func currentBranch(repo *git.GitRepo) string {
+	if repo == nil {
+		return ""
+	}
+	current, err := repo.GetCurrentBranch()
+	if err != nil {
+		return ""
+	}
+	return current
+}
+
+// CompareBranches compares head against base (the project default branch when
+// empty). Results are cached by the commits both refs point to, so a new commit
+// on either side is picked up right away; forceRefresh skips the cache.
+func (s *BranchService) CompareBranches(ctx context.Context, projectID, base, head string, forceRefresh bool) (*git.BranchComparison, error) {
+	ctx = ensureContext(ctx)
+	project, err := s.getProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	base = s.mergeTarget(project, base)
+	head = strings.TrimSpace(head)
+	if base == "" || head == "" {
+		return nil, fmt.Errorf("base and head branches are required")
+	}
+
+	baseCommit, err := git.ResolveCommit(project.Path, base)
+	if err != nil {
+		return nil, err
+	}
+	headCommit, err := git.ResolveCommit(project.Path, head)
+	if err != nil {
+		return nil, err
+	}
+	key := fmt.Sprintf("compare:%s:%s:%s:%s:%s", project.Id, base, head, baseCommit, headCommit)
+	if forceRefresh {
+		s.cache.Delete(key)
+	} else if value, ok := s.cache.Get(key); ok {
+		if result, ok := value.(*git.BranchComparison); ok {
+			return result, nil
+		}
+	}
+
+	result, err := git.CompareBranches(project.Path, base, head)
+	if err != nil {
+		s.logger(ctx).Warn("compare branches failed", zap.Error(err),
+			zap.String("projectId", project.Id), zap.String("base", base), zap.String("head", head))
+		return nil, err
+	}
+	s.cache.Set(key, result)
+	return result, nil
+}
+
+// mergeTarget resolves the branch merged branches are checked against.
+func (s *BranchService) mergeTarget(project *model.Project, intoBranch string) string {
+	if into := strings.TrimSpace(intoBranch); into != "" {
+		return into
+	}
+	if project.DefaultBranch != nil {
+		if defaultBranch := strings.TrimSpace(*project.DefaultBranch); defaultBranch != "" {
+			return defaultBranch
+		}
+	}
+	return ""
+}
+
 // MergeBranch merges source branch into the selected worktree using the requested strategy.
 func (s *BranchService) MergeBranch(ctx context.Context, worktreeID, sourceBranch string, opts model.MergeBranchOptions) (_ *model.MergeResult, err error) {
 	ctx = ensureContext(ctx)
@@ -252,6 +453,9 @@ func (s *BranchService) MergeBranch(ctx context.Context, worktreeID, sourceBranc
 	if err != nil {
 		return nil, err
 	}
+	if err := ensureWorktreeWritable(worktree); err != nil {
+		return nil, err
+	}
 
 	project, repo, err := s.getProjectAndRepo(ctx, worktree.ProjectId)
 	if err != nil {
@@ -263,6 +467,10 @@ func (s *BranchService) MergeBranch(ctx context.Context, worktreeID, sourceBranc
 		targetBranch = worktree.BranchName
 	}
 	if targetBranch == "" {
+		// detached worktree 没有可合入的分支，需先在其中创建分支
+		if worktree.IsDetached {
+			return nil, model.ErrWorktreeDetached
+		}
 		return nil, fmt.Errorf("target branch is required")
 	}
 
@@ -270,6 +478,15 @@ func (s *BranchService) MergeBranch(ctx context.Context, worktreeID, sourceBranc
 	if strategy == "" {
 		return nil, fmt.Errorf("unsupported merge strategy: %s", opts.Strategy)
 	}
+	if err := checkBranchProtection(project, "", targetBranch, model.BranchOperationMerge); err != nil {
+		logger.Warn("attempted to merge into protected branch",
+			zap.Error(err),
+			zap.String("projectId", project.Id),
+			zap.String("worktreeId", worktree.Id),
+			zap.String("source", source),
+		)
+		return nil, err
+	}
 
 	if opts.Commit && strategy != git.MergeStrategySquash {
 		return nil, errors.New("commit option is only available for squash merges")
@@ -288,6 +505,7 @@ func (s *BranchService) MergeBranch(ctx context.Context, worktreeID, sourceBranc
 		return nil, model.ErrWorktreeDirty
 	}
 
+	backup := backupWorktreeHead(ctx, worktree, "merge")
 	if err := repo.MergeBranch(worktree.Path, source, strategy); err != nil {
 		if git.IsConflictError(err) {
 			conflicts := repo.GetConflictFiles(worktree.Path)
@@ -302,6 +520,7 @@ func (s *BranchService) MergeBranch(ctx context.Context, worktreeID, sourceBranc
 				Success:   false,
 				Conflicts: conflicts,
 				Message:   "merge has conflicts",
+				BackupRef: backup,
 			}, nil
 		}
 		logger.Error("merge failed",
@@ -319,7 +538,7 @@ func (s *BranchService) MergeBranch(ctx context.Context, worktreeID, sourceBranc
 		if commitMessage == "" {
 			commitMessage = fmt.Sprintf("merge %s into %s", source, targetBranch)
 		}
-		if err := repo.Commit(worktree.Path, commitMessage); err != nil {
+		if err := repo.Commit(worktree.Path, commitMessage, false); err != nil {
 			logger.Error("commit after squash failed",
 				zap.Error(err),
 				zap.String("projectId", project.Id),
@@ -338,6 +557,12 @@ func (s *BranchService) MergeBranch(ctx context.Context, worktreeID, sourceBranc
 
 	s.refreshBranches(ctx, worktreeService, project.Id, targetBranch, source)
 	s.invalidateCache(project.Id)
+	recordActivity(ctx, model.RecordActivityRequest{
+		ProjectID: project.Id,
+		Type:      model.ActivityBranchMerged,
+		Target:    targetBranch,
+		Detail:    fmt.Sprintf("merge %s into %s (%s)", source, targetBranch, strategy),
+	})
 	logger.Info("merge completed",
 		zap.String("projectId", project.Id),
 		zap.String("worktreeId", worktree.Id),
@@ -351,8 +576,82 @@ func (s *BranchService) MergeBranch(ctx context.Context, worktreeID, sourceBranc
 		resultMsg = "merged and committed successfully"
 	}
 	return &model.MergeResult{
-		Success: true,
-		Message: resultMsg,
+		Success:   true,
+		Message:   resultMsg,
+		BackupRef: backup,
+	}, nil
+}
+
+// PreviewMerge dry-runs integrating sourceBranch into the worktree with the given strategy
+// and reports the files that would change and potential conflicts. The worktree is restored
+// afterwards.
+func (s *BranchService) PreviewMerge(ctx context.Context, worktreeID, sourceBranch string, strategy string) (*model.MergePreview, error) {
+	ctx = ensureContext(ctx)
+	logger := s.logger(ctx)
+
+	source := strings.TrimSpace(sourceBranch)
+	if source == "" {
+		return nil, fmt.Errorf("source branch is required")
+	}
+
+	mergeStrategy := parseMergeStrategy(strategy)
+	if mergeStrategy == "" {
+		return nil, fmt.Errorf("unsupported merge strategy: %s", strategy)
+	}
+
+	worktreeService := NewWorktreeService()
+	worktree, err := worktreeService.GetWorktree(ctx, worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	if worktree.IsDetached && strings.TrimSpace(worktree.BranchName) == "" {
+		return nil, model.ErrWorktreeDetached
+	}
+
+	project, repo, err := s.getProjectAndRepo(ctx, worktree.ProjectId)
+	if err != nil {
+		return nil, err
+	}
+
+	// 预览依赖在工作区内实际执行 merge，脏工作区会导致 abort 时丢失本地改动
+	status, err := git.GetWorktreeStatus(worktree.Path)
+	if err != nil {
+		return nil, err
+	}
+	if status.Modified > 0 || status.Staged > 0 || status.Conflicted > 0 {
+		return nil, model.ErrWorktreeDirty
+	}
+
+	preview, err := repo.PreviewMerge(worktree.Path, source, mergeStrategy)
+	if err != nil {
+		logger.Error("merge preview failed",
+			zap.Error(err),
+			zap.String("projectId", project.Id),
+			zap.String("worktreeId", worktree.Id),
+			zap.String("source", source),
+			zap.String("strategy", string(mergeStrategy)),
+		)
+		return nil, err
+	}
+
+	logger.Info("merge preview completed",
+		zap.String("projectId", project.Id),
+		zap.String("worktreeId", worktree.Id),
+		zap.String("source", source),
+		zap.String("target", worktree.BranchName),
+		zap.String("strategy", string(mergeStrategy)),
+		zap.Int("files", len(preview.Files)),
+		zap.Int("conflicts", len(preview.Conflicts)),
+	)
+
+	return &model.MergePreview{
+		SourceBranch: source,
+		TargetBranch: worktree.BranchName,
+		Strategy:     string(mergeStrategy),
+		Files:        preview.Files,
+		Conflicts:    preview.Conflicts,
+		HasConflicts: len(preview.Conflicts) > 0,
+		UpToDate:     preview.UpToDate,
 	}, nil
 }
 
@@ -462,6 +761,22 @@ func (s *BranchService) logger(ctx context.Context) *zap.Logger {
 	return utils.LoggerFromContext(ctx).Named("branch-service")
 }
 
+// checkUnpushedCommits turns detected unpushed commits into an UnpushedCommitsError
+// so the delete is confirmed first. Detection failures are only logged.
+func checkUnpushedCommits(ctx context.Context, branch string, commits []git.CommitInfo, err error) error {
+	if err != nil {
+		utils.LoggerFromContext(ctx).Warn("detect unpushed commits failed",
+			zap.Error(err),
+			zap.String("branch", branch),
+		)
+		return nil
+	}
+	if len(commits) == 0 {
+		return nil
+	}
+	return &model.UnpushedCommitsError{Branch: branch, Commits: commits}
+}
+
 func parseMergeStrategy(strategy string) git.MergeStrategy {
 	switch strings.ToLower(strings.TrimSpace(strategy)) {
 	case "", "merge":
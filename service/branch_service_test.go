@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"reflect"
 	"runtime"
 	"testing"
 
@@ -93,6 +94,162 @@ func TestBranchServiceDeleteProtected(t *testing.T) {
 	}
 }
 
+func TestBranchServiceProtectionRules(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	ctx := context.Background()
+	project, err := projectService.CreateProject(ctx, model.CreateProjectParams{
+		Name: "Rule Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+	project, err = projectService.UpdateProtectedBranches(ctx, project.Id, []string{"release/*"})
+	if err != nil {
+		t.Fatalf("UpdateProtectedBranches failed: %v", err)
+	}
+
+	branchSvc := NewBranchService()
+	if err := branchSvc.CreateBranch(ctx, project.Id, "release/1.0", "", false); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+
+	err = branchSvc.DeleteBranch(ctx, project.Id, "release/1.0", true)
+	var protectedErr *model.ProtectedBranchError
+	if !errors.As(err, &protectedErr) || !errors.Is(err, model.ErrProtectedBranch) {
+		t.Fatalf("expected ProtectedBranchError, got %v", err)
+	}
+	if protectedErr.Rule != "release/*" || protectedErr.Operation != model.BranchOperationDelete {
+		t.Fatalf("unexpected rule %q operation %q", protectedErr.Rule, protectedErr.Operation)
+	}
+
+	err = checkBranchProtection(project, "", "release/1.0", model.BranchOperationMerge)
+	if !errors.As(err, &protectedErr) || protectedErr.Operation != model.BranchOperationMerge {
+		t.Fatalf("expected merge into a protected branch to be rejected, got %v", err)
+	}
+
+	err = branchSvc.DeleteBranch(ctx, project.Id, defaultBranch(project), false)
+	if !errors.As(err, &protectedErr) || protectedErr.Rule != model.ProtectedRuleDefaultBranch {
+		t.Fatalf("expected default branch rule, got %v", err)
+	}
+
+	// 默认分支仅禁止删除，合并由项目规则决定
+	if err := checkBranchProtection(project, "", defaultBranch(project), model.BranchOperationMerge); err != nil {
+		t.Fatalf("expected merge into default branch to be allowed, got %v", err)
+	}
+}
+
+func TestBranchServiceDeleteWithUnpushedCommits(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Unpushed Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+
+	branchSvc := NewBranchService()
+	ctx := context.Background()
+	if err := branchSvc.CreateBranch(ctx, project.Id, "feature/unpushed", "", false); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+
+	// 远程只有分支的上一个提交，本地多出一个未推送的提交
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	runGitCommand(t, repoPath, "init", "--bare", remote)
+	runGitCommand(t, repoPath, "remote", "add", "origin", remote)
+	runGitCommand(t, repoPath, "checkout", "feature/unpushed")
+	if err := os.WriteFile(filepath.Join(repoPath, "feature.txt"), []byte("feature"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitCommand(t, repoPath, "add", "feature.txt")
+	runGitCommand(t, repoPath, "commit", "-m", "feature work")
+	runGitCommand(t, repoPath, "checkout", defaultBranch(project))
+	runGitCommand(t, repoPath, "push", "origin", "feature/unpushed~1:refs/heads/feature/unpushed")
+	runGitCommand(t, repoPath, "fetch", "origin")
+	runGitCommand(t, repoPath, "branch", "--set-upstream-to=origin/feature/unpushed", "feature/unpushed")
+
+	err = branchSvc.DeleteBranch(ctx, project.Id, "feature/unpushed", false)
+	var unpushed *model.UnpushedCommitsError
+	if !errors.As(err, &unpushed) || !errors.Is(err, model.ErrUnpushedCommits) {
+		t.Fatalf("expected UnpushedCommitsError, got %v", err)
+	}
+	if len(unpushed.Commits) != 1 || unpushed.Commits[0].Message != "feature work" {
+		t.Fatalf("unexpected unpushed commits: %+v", unpushed.Commits)
+	}
+
+	if err := branchSvc.DeleteBranch(ctx, project.Id, "feature/unpushed", true); err != nil {
+		t.Fatalf("force delete failed: %v", err)
+	}
+}
+
+func TestBranchServiceSetUpstream(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	ctx := context.Background()
+	project, err := projectService.CreateProject(ctx, model.CreateProjectParams{
+		Name: "Upstream Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+	worktrees, err := NewWorktreeService().ListWorktrees(ctx, project.Id)
+	if err != nil || len(worktrees) == 0 {
+		t.Fatalf("ListWorktrees failed: %v", err)
+	}
+	mainWT := worktrees[0]
+
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	runGitCommand(t, repoPath, "init", "--bare", remote)
+	runGitCommand(t, repoPath, "remote", "add", "origin", remote)
+	runGitCommand(t, repoPath, "push", "origin", defaultBranch(project))
+	if err := os.WriteFile(filepath.Join(repoPath, "local.txt"), []byte("local"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitCommand(t, repoPath, "add", "local.txt")
+	runGitCommand(t, repoPath, "commit", "-m", "local work")
+
+	branchSvc := NewBranchService()
+	upstream, err := branchSvc.GetUpstream(ctx, mainWT.Id)
+	if err != nil {
+		t.Fatalf("GetUpstream failed: %v", err)
+	}
+	if upstream.HasUpstream || upstream.Ahead != 0 {
+		t.Fatalf("expected no upstream, got %+v", upstream)
+	}
+
+	if _, err := branchSvc.SetUpstream(ctx, mainWT.Id, "origin/missing"); !errors.Is(err, git.ErrRefNotFound) {
+		t.Fatalf("expected ErrRefNotFound, got %v", err)
+	}
+	upstream, err = branchSvc.SetUpstream(ctx, mainWT.Id, "origin/"+defaultBranch(project))
+	if err != nil {
+		t.Fatalf("SetUpstream failed: %v", err)
+	}
+	if !upstream.HasUpstream || upstream.Upstream != "origin/"+defaultBranch(project) || upstream.Ahead != 1 {
+		t.Fatalf("unexpected upstream after set: %+v", upstream)
+	}
+	refreshed, err := NewWorktreeService().GetWorktree(ctx, mainWT.Id)
+	if err != nil {
+		t.Fatalf("GetWorktree failed: %v", err)
+	}
+	if refreshed.StatusAhead == nil || *refreshed.StatusAhead != 1 {
+		t.Fatalf("expected refreshed ahead count, got %v", refreshed.StatusAhead)
+	}
+}
+
 func TestBranchServiceForceDeleteWithWorktree(t *testing.T) {
 	if runtime.GOOS == "windows" {
 		t.Skip("force deleting worktrees is flaky on Windows")
@@ -162,6 +319,89 @@ func TestBranchServiceForceDeleteWithWorktree(t *testing.T) {
 	}
 }
 
+func TestBranchServiceDeleteMergedBranches(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("deleting worktrees is flaky on Windows")
+	}
+
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Merged Cleanup Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+
+	branchSvc := NewBranchService()
+	ctx := context.Background()
+	if err := branchSvc.CreateBranch(ctx, project.Id, "feature/merged", "", true); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	if err := branchSvc.CreateBranch(ctx, project.Id, "feature/done", "", false); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	if err := branchSvc.CreateBranch(ctx, project.Id, "feature/open", "", false); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	runGitCommand(t, repoPath, "checkout", "feature/open")
+	if err := os.WriteFile(filepath.Join(repoPath, "open.txt"), []byte("open"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitCommand(t, repoPath, "add", "open.txt")
+	runGitCommand(t, repoPath, "commit", "-m", "open work")
+	runGitCommand(t, repoPath, "checkout", defaultBranch(project))
+
+	merged, err := branchSvc.ListMergedBranches(ctx, project.Id, "")
+	if err != nil {
+		t.Fatalf("ListMergedBranches failed: %v", err)
+	}
+	if want := []string{"feature/done", "feature/merged"}; !reflect.DeepEqual(merged, want) {
+		t.Fatalf("expected merged branches %v, got %v", want, merged)
+	}
+
+	// worktree 中还有打开的终端会话时跳过该分支
+	SetWorktreeSessionChecker(func(string) bool { return true })
+	t.Cleanup(func() { SetWorktreeSessionChecker(nil) })
+	results, err := branchSvc.DeleteMergedBranches(ctx, project.Id, "")
+	if err != nil {
+		t.Fatalf("DeleteMergedBranches failed: %v", err)
+	}
+	if len(results) != 2 || !results[0].Deleted || results[1].Deleted ||
+		results[1].Reason != model.ErrWorktreeHasSessions.Error() || len(results[1].Worktrees) != 0 {
+		t.Fatalf("expected feature/merged skipped for its open session, got %+v", results)
+	}
+
+	SetWorktreeSessionChecker(nil)
+	results, err = branchSvc.DeleteMergedBranches(ctx, project.Id, "")
+	if err != nil {
+		t.Fatalf("DeleteMergedBranches failed: %v", err)
+	}
+	if len(results) != 1 || !results[0].Deleted || len(results[0].Worktrees) != 1 {
+		t.Fatalf("expected feature/merged and its worktree removed, got %+v", results)
+	}
+
+	repo, err := git.DetectRepository(repoPath)
+	if err != nil {
+		t.Fatalf("DetectRepository failed: %v", err)
+	}
+	local, _, err := repo.ListBranches()
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+	names := make(map[string]bool, len(local))
+	for _, branch := range local {
+		names[branch.Name] = true
+	}
+	if names["feature/merged"] || names["feature/done"] || !names["feature/open"] || !names[defaultBranch(project)] {
+		t.Fatalf("unexpected branches after cleanup: %v", names)
+	}
+}
+
 func TestBranchServiceMergeSuccess(t *testing.T) {
 	cleanup := initTestDB(t)
 	defer cleanup()
@@ -301,9 +541,147 @@ func TestBranchServiceSquashMergeCommit(t *testing.T) {
 	}
 }
 
+func TestBranchServiceRebaseWorktree(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	ctx := context.Background()
+	project, err := projectService.CreateProject(ctx, model.CreateProjectParams{
+		Name: "Rebase Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+
+	branchSvc := NewBranchService()
+	const featureBranch = "feature/rebase"
+	if err := branchSvc.CreateBranch(ctx, project.Id, featureBranch, "", true); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+
+	worktreeService := NewWorktreeService()
+	worktrees, err := worktreeService.ListWorktrees(ctx, project.Id)
+	if err != nil {
+		t.Fatalf("ListWorktrees failed: %v", err)
+	}
+	var featureWT, mainWT *model.Worktree
+	for _, wt := range worktrees {
+		switch wt.BranchName {
+		case featureBranch:
+			featureWT = wt
+		case defaultBranch(project):
+			mainWT = wt
+		}
+	}
+	if featureWT == nil || mainWT == nil {
+		t.Fatalf("failed to locate worktrees: %+v", worktrees)
+	}
+
+	if err := os.WriteFile(filepath.Join(featureWT.Path, "feature.txt"), []byte("feature"), 0o644); err != nil {
+		t.Fatalf("write feature file failed: %v", err)
+	}
+	runGitCommand(t, featureWT.Path, "add", "feature.txt")
+	runGitCommand(t, featureWT.Path, "commit", "-m", "add feature file")
+	if err := os.WriteFile(filepath.Join(repoPath, "main.txt"), []byte("main"), 0o644); err != nil {
+		t.Fatalf("write main file failed: %v", err)
+	}
+	runGitCommand(t, repoPath, "add", "main.txt")
+	runGitCommand(t, repoPath, "commit", "-m", "add main file")
+
+	// 脏工作区与 merge 一样直接拒绝
+	dirtyFile := filepath.Join(featureWT.Path, "feature.txt")
+	if err := os.WriteFile(dirtyFile, []byte("dirty"), 0o644); err != nil {
+		t.Fatalf("write dirty file failed: %v", err)
+	}
+	if _, err := branchSvc.RebaseWorktree(ctx, featureWT.Id, ""); !errors.Is(err, model.ErrWorktreeDirty) {
+		t.Fatalf("expected ErrWorktreeDirty, got %v", err)
+	}
+	runGitCommand(t, featureWT.Path, "checkout", "--", "feature.txt")
+
+	result, err := branchSvc.RebaseWorktree(ctx, featureWT.Id, "")
+	if err != nil {
+		t.Fatalf("RebaseWorktree returned error: %v", err)
+	}
+	if !result.Success || len(result.Conflicts) != 0 {
+		t.Fatalf("expected rebase success, got %+v", result)
+	}
+	if _, err := os.Stat(filepath.Join(featureWT.Path, "main.txt")); err != nil {
+		t.Fatalf("expected main.txt after rebase: %v", err)
+	}
+
+	if err := branchSvc.AbortRebase(ctx, featureWT.Id); !errors.Is(err, git.ErrNoRebaseInProgress) {
+		t.Fatalf("expected ErrNoRebaseInProgress, got %v", err)
+	}
+	if _, err := branchSvc.RebaseWorktree(ctx, mainWT.Id, mainWT.BranchName); err == nil {
+		t.Fatal("expected rebasing a branch onto itself to fail")
+	}
+
+	if _, err := projectService.UpdateProtectedBranches(ctx, project.Id, []string{"feature/*"}); err != nil {
+		t.Fatalf("UpdateProtectedBranches failed: %v", err)
+	}
+	_, err = branchSvc.RebaseWorktree(ctx, featureWT.Id, "")
+	var protectedErr *model.ProtectedBranchError
+	if !errors.As(err, &protectedErr) || protectedErr.Operation != model.BranchOperationRebase {
+		t.Fatalf("expected rebase of protected branch to be rejected, got %v", err)
+	}
+}
+
 func defaultBranch(project *model.Project) string {
 	if project.DefaultBranch == nil {
 		return ""
 	}
 	return *project.DefaultBranch
 }
+
+func TestBranchServiceCompareBranches(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Compare Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+
+	ctx := context.Background()
+	branchSvc := NewBranchService()
+	if err := branchSvc.CreateBranch(ctx, project.Id, "feature/compare", "", false); err != nil {
+		t.Fatalf("CreateBranch failed: %v", err)
+	}
+	runGitCommand(t, repoPath, "checkout", "feature/compare")
+	if err := os.WriteFile(filepath.Join(repoPath, "compare.txt"), []byte("compare\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitCommand(t, repoPath, "add", "compare.txt")
+	runGitCommand(t, repoPath, "commit", "-m", "compare change")
+
+	// base 为空时与项目默认分支比较
+	result, err := branchSvc.CompareBranches(ctx, project.Id, "", "feature/compare", false)
+	if err != nil {
+		t.Fatalf("CompareBranches returned error: %v", err)
+	}
+	if result.Base != defaultBranch(project) || result.Ahead != 1 || result.Behind != 0 || len(result.Files) != 1 {
+		t.Fatalf("unexpected comparison %+v", result)
+	}
+
+	// 新提交改变 head 指向，缓存不会返回旧结果
+	if err := os.WriteFile(filepath.Join(repoPath, "compare.txt"), []byte("compare\nagain\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGitCommand(t, repoPath, "commit", "-am", "compare again")
+	result, err = branchSvc.CompareBranches(ctx, project.Id, "", "feature/compare", false)
+	if err != nil || result.Ahead != 2 {
+		t.Fatalf("expected refreshed comparison, got %+v, %v", result, err)
+	}
+
+	if _, err := branchSvc.CompareBranches(ctx, project.Id, "", "missing", false); !errors.Is(err, git.ErrRefNotFound) {
+		t.Fatalf("expected ErrRefNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/utils/git"
+)
+
+// GetUpstream reports the upstream of the branch checked out in the worktree
+// and how far the branch is ahead of and behind it.
+func (s *BranchService) GetUpstream(ctx context.Context, worktreeID string) (*model.WorktreeUpstream, error) {
+	ctx = ensureContext(ctx)
+	worktree, err := NewWorktreeService().GetWorktree(ctx, worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	return worktreeUpstream(worktree)
+}
+
+// SetUpstream sets the upstream of the branch checked out in the worktree to
+// remoteBranch, e.g. origin/feature-x, and refreshes the cached worktree
+// status so ahead/behind pick it up.
+func (s *BranchService) SetUpstream(ctx context.Context, worktreeID, remoteBranch string) (*model.WorktreeUpstream, error) {
+	ctx = ensureContext(ctx)
+	logger := s.logger(ctx)
+
+	worktreeService := NewWorktreeService()
+	worktree, err := worktreeService.GetWorktree(ctx, worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureWorktreeWritable(worktree); err != nil {
+		return nil, err
+	}
+	if worktree.IsDetached {
+		return nil, model.ErrWorktreeDetached
+	}
+
+	if err := git.SetUpstream(worktree.Path, remoteBranch); err != nil {
+		logger.Warn("set upstream failed",
+			zap.Error(err),
+			zap.String("worktreeId", worktree.Id),
+			zap.String("upstream", remoteBranch),
+		)
+		return nil, err
+	}
+	logger.Info("upstream set",
+		zap.String("worktreeId", worktree.Id),
+		zap.String("branch", worktree.BranchName),
+		zap.String("upstream", remoteBranch),
+	)
+	if _, err := worktreeService.RefreshWorktreeStatus(ctx, worktree.Id); err != nil {
+		logger.Warn("refresh worktree status after setting upstream failed", zap.Error(err), zap.String("worktreeId", worktree.Id))
+	}
+	return worktreeUpstream(worktree)
+}
+
+func worktreeUpstream(worktree *model.Worktree) (*model.WorktreeUpstream, error) {
+	status, err := git.GetWorktreeStatus(worktree.Path)
+	if err != nil {
+		return nil, err
+	}
+	if status.Detached {
+		return nil, model.ErrWorktreeDetached
+	}
+	upstream := status.Upstream
+	if upstream == "" {
+		// go-git 回退路径不解析上游，单独查询一次
+		if upstream, err = git.GetUpstream(worktree.Path); err != nil {
+			return nil, err
+		}
+	}
+	return &model.WorktreeUpstream{
+		Branch:      status.Branch,
+		Upstream:    upstream,
+		HasUpstream: upstream != "",
+		Ahead:       status.Ahead,
+		Behind:      status.Behind,
+	}, nil
+}
@@ -0,0 +1,78 @@
+package service
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/utils"
+	"code-kanban/utils/git"
+)
+
+// ProjectGitConfigService reads and writes the local git config of project repositories.
+type ProjectGitConfigService struct {
+	projects *model.ProjectService
+}
+
+// NewProjectGitConfigService constructs a ProjectGitConfigService.
+func NewProjectGitConfigService() *ProjectGitConfigService {
+	return &ProjectGitConfigService{projects: &model.ProjectService{}}
+}
+
+// Get lists the repository-local config of the project.
+func (s *ProjectGitConfigService) Get(ctx context.Context, projectID string) (*model.ProjectGitConfig, error) {
+	project, repo, err := s.projectRepo(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := repo.ListConfig()
+	if err != nil {
+		return nil, err
+	}
+	return &model.ProjectGitConfig{
+		ProjectID:  project.Id,
+		Entries:    entries,
+		CommonKeys: git.CommonConfigKeys(),
+	}, nil
+}
+
+// Set writes values to the repository-local config and returns the resulting
+// config. All keys and values are validated before anything is written.
+func (s *ProjectGitConfigService) Set(ctx context.Context, projectID string, values map[string]string) (*model.ProjectGitConfig, error) {
+	ctx = ensureContext(ctx)
+	_, repo, err := s.projectRepo(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range values {
+		if err := git.ValidateConfigKeyAllowed(key); err != nil {
+			return nil, err
+		}
+		if err := git.ValidateConfigValue(key, value); err != nil {
+			return nil, err
+		}
+	}
+	for key, value := range values {
+		if err := repo.SetConfig(key, value); err != nil {
+			return nil, err
+		}
+	}
+	utils.LoggerFromContext(ctx).Info("project git config updated",
+		zap.String("projectId", projectID),
+		zap.Int("keys", len(values)),
+	)
+	return s.Get(ctx, projectID)
+}
+
+func (s *ProjectGitConfigService) projectRepo(ctx context.Context, projectID string) (*model.Project, *git.GitRepo, error) {
+	project, err := s.projects.GetProject(ensureContext(ctx), projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+	repo, err := git.DetectRepository(project.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return project, repo, nil
+}
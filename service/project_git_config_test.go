@@ -0,0 +1,48 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"code-kanban/model"
+	"code-kanban/utils/git"
+)
+
+func TestProjectGitConfigServiceSet(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	project, err := (&model.ProjectService{}).CreateProject(ctx, model.CreateProjectParams{
+		Name: "Config Project",
+		Path: createProjectTestRepo(t),
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewProjectGitConfigService()
+	// 任意一项无效时不写入其余配置
+	if _, err := svc.Set(ctx, project.Id, map[string]string{"user.name": "Nobody", "core.autocrlf": "maybe"}); !errors.Is(err, git.ErrInvalidConfigValue) {
+		t.Fatalf("expected ErrInvalidConfigValue, got %v", err)
+	}
+	config, err := svc.Get(ctx, project.Id)
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if _, ok := config.Entries["user.name"]; ok || len(config.CommonKeys) == 0 {
+		t.Fatalf("unexpected config after rejected update: %+v", config)
+	}
+
+	config, err = svc.Set(ctx, project.Id, map[string]string{"user.name": "Config User", "core.autocrlf": "false"})
+	if err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if config.Entries["user.name"] != "Config User" || config.Entries["core.autocrlf"] != "false" {
+		t.Fatalf("unexpected config entries %#v", config.Entries)
+	}
+	if _, err := svc.Get(ctx, "missing"); !errors.Is(err, model.ErrProjectNotFound) {
+		t.Fatalf("expected ErrProjectNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/utils"
+	"code-kanban/utils/git"
+)
+
+// projectGCTimeout 单次 gc 的最长时间，大仓库的 gc 可能需要数分钟
+const projectGCTimeout = 30 * time.Minute
+
+// projectGCRuns 按项目记录后台 gc 状态，服务实例按路由分别创建，因此放在包级别
+var projectGCRuns = struct {
+	mu   sync.Mutex
+	runs map[string]*model.ProjectGCStatus
+}{runs: make(map[string]*model.ProjectGCStatus)}
+
+// ProjectHealthService inspects project repositories and runs git gc on them.
+type ProjectHealthService struct {
+	projects *model.ProjectService
+}
+
+// NewProjectHealthService constructs a ProjectHealthService.
+func NewProjectHealthService() *ProjectHealthService {
+	return &ProjectHealthService{projects: &model.ProjectService{}}
+}
+
+// Health analyzes the object storage of the project repository.
+func (s *ProjectHealthService) Health(ctx context.Context, projectID string) (*model.ProjectHealth, error) {
+	project, err := s.projects.GetProject(ensureContext(ctx), projectID)
+	if err != nil {
+		return nil, err
+	}
+	repoHealth, err := git.AnalyzeRepo(project.Path)
+	if err != nil {
+		return nil, err
+	}
+	return &model.ProjectHealth{
+		ProjectID:  project.Id,
+		RepoHealth: *repoHealth,
+		GC:         projectGCStatus(project.Id),
+	}, nil
+}
+
+// StartGC runs git gc for the project in the background and returns at once;
+// progress is reported by Health. Only one gc per project runs at a time.
+func (s *ProjectHealthService) StartGC(ctx context.Context, projectID string) (*model.ProjectGCStatus, error) {
+	ctx = ensureContext(ctx)
+	project, err := s.projects.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	projectGCRuns.mu.Lock()
+	if run, ok := projectGCRuns.runs[project.Id]; ok && run.Running {
+		projectGCRuns.mu.Unlock()
+		return nil, model.ErrGCAlreadyRunning
+	}
+	startedAt := time.Now()
+	run := &model.ProjectGCStatus{Running: true, StartedAt: &startedAt}
+	projectGCRuns.runs[project.Id] = run
+	status := *run
+	projectGCRuns.mu.Unlock()
+
+	logger := utils.LoggerFromContext(ctx)
+	go func(projectID, path string) {
+		gcCtx, cancel := context.WithTimeout(context.Background(), projectGCTimeout)
+		defer cancel()
+		gcErr := git.RunGC(gcCtx, path)
+
+		finishedAt := time.Now()
+		projectGCRuns.mu.Lock()
+		run.Running = false
+		run.FinishedAt = &finishedAt
+		if gcErr != nil {
+			run.Error = gcErr.Error()
+		}
+		projectGCRuns.mu.Unlock()
+
+		if gcErr != nil {
+			logger.Warn("git gc failed", zap.Error(gcErr), zap.String("projectId", projectID))
+			return
+		}
+		logger.Info("git gc finished",
+			zap.String("projectId", projectID),
+			zap.Duration("duration", finishedAt.Sub(startedAt)),
+		)
+	}(project.Id, project.Path)
+
+	return &status, nil
+}
+
+func projectGCStatus(projectID string) *model.ProjectGCStatus {
+	projectGCRuns.mu.Lock()
+	defer projectGCRuns.mu.Unlock()
+	run, ok := projectGCRuns.runs[projectID]
+	if !ok {
+		return nil
+	}
+	status := *run
+	return &status
+}
@@ -0,0 +1,66 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"code-kanban/model"
+)
+
+func TestProjectHealthServiceGC(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	project, err := (&model.ProjectService{}).CreateProject(ctx, model.CreateProjectParams{
+		Name: "Health Project",
+		Path: createProjectTestRepo(t),
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewProjectHealthService()
+	health, err := svc.Health(ctx, project.Id)
+	if err != nil {
+		t.Fatalf("Health failed: %v", err)
+	}
+	if health.LooseObjects == 0 || health.GC != nil {
+		t.Fatalf("unexpected health before gc: %+v", health)
+	}
+
+	status, err := svc.StartGC(ctx, project.Id)
+	if err != nil {
+		t.Fatalf("StartGC failed: %v", err)
+	}
+	if !status.Running || status.StartedAt == nil {
+		t.Fatalf("expected running gc status, got %+v", status)
+	}
+
+	deadline := time.Now().Add(30 * time.Second)
+	for {
+		health, err = svc.Health(ctx, project.Id)
+		if err != nil {
+			t.Fatalf("Health failed: %v", err)
+		}
+		if !health.GC.Running {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("git gc did not finish in time")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if health.GC.Error != "" || health.GC.FinishedAt == nil {
+		t.Fatalf("unexpected gc status: %+v", health.GC)
+	}
+	if health.LooseObjects != 0 || health.Packs == 0 {
+		t.Fatalf("expected objects packed after gc, got %+v", health)
+	}
+
+	if _, err := svc.Health(ctx, "missing"); !errors.Is(err, model.ErrProjectNotFound) {
+		t.Fatalf("expected ErrProjectNotFound, got %v", err)
+	}
+}
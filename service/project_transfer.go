@@ -0,0 +1,306 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"code-kanban/model"
+	"code-kanban/utils"
+
+	"go.uber.org/zap"
+)
+
+// ProjectTransferService exports a project's settings, notepads and worktree list
+// as JSON and rebuilds them on another machine.
+type ProjectTransferService struct {
+	projects  *model.ProjectService
+	notepads  *model.NotePadService
+	worktrees *WorktreeService
+}
+
+// NewProjectTransferService constructs a ProjectTransferService.
+func NewProjectTransferService() *ProjectTransferService {
+	worktrees := NewWorktreeService()
+	// 导入后立即比对 worktree 列表，不能等异步刷新
+	worktrees.AsyncRefresh(false)
+	return &ProjectTransferService{
+		projects:  &model.ProjectService{},
+		notepads:  &model.NotePadService{},
+		worktrees: worktrees,
+	}
+}
+
+// ExportProject collects the project settings, its notepads and worktrees.
+func (s *ProjectTransferService) ExportProject(ctx context.Context, projectID string) (*model.ProjectExport, error) {
+	ctx = ensureContext(ctx)
+	project, err := s.projects.GetProject(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	notepads, err := s.notepads.ListNotePads(ctx, &project.Id)
+	if err != nil {
+		return nil, err
+	}
+	worktrees, err := s.worktrees.ListWorktrees(ctx, project.Id)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &model.ProjectExport{
+		Version:    model.ProjectExportVersion,
+		ExportedAt: time.Now(),
+		Project: model.ProjectExportInfo{
+			ID:                project.Id,
+			Name:              project.Name,
+			Path:              project.Path,
+			Description:       derefString(project.Description),
+			DefaultBranch:     derefString(project.DefaultBranch),
+			WorktreeBasePath:  derefString(project.WorktreeBasePath),
+			RemoteURL:         derefString(project.RemoteUrl),
+			HidePath:          project.HidePath,
+			Priority:          project.Priority,
+			ProtectedBranches: model.ProtectedBranchPatterns(project),
+			Archived:          project.Archived,
+		},
+		NotePads:  make([]model.NotePadExport, 0, len(notepads)),
+		Worktrees: make([]model.WorktreeExport, 0, len(worktrees)),
+	}
+	for _, notepad := range notepads {
+		export.NotePads = append(export.NotePads, model.NotePadExport{
+			ID:         notepad.ID,
+			Name:       notepad.Name,
+			Content:    notepad.Content,
+			OrderIndex: notepad.OrderIndex,
+		})
+	}
+	for _, wt := range worktrees {
+		export.Worktrees = append(export.Worktrees, model.WorktreeExport{
+			ID:         wt.Id,
+			BranchName: wt.BranchName,
+			Path:       wt.Path,
+			IsMain:     wt.IsMain,
+		})
+	}
+	return export, nil
+}
+
+// ImportProject rebuilds an exported project at path, or at the exported path
+// when path is empty. Every record gets a new ID. When a project already tracks
+// the path, notepads are merged into it and identical ones are skipped.
+// Worktrees cannot be recreated without git content, so the report marks those
+// whose directory is missing on this machine.
+func (s *ProjectTransferService) ImportProject(ctx context.Context, data *model.ProjectExport, path string) (*model.ProjectImportReport, error) {
+	ctx = ensureContext(ctx)
+	if data == nil || strings.TrimSpace(data.Project.Name) == "" {
+		return nil, fmt.Errorf("%w: project name is required", model.ErrInvalidProjectExport)
+	}
+	if data.Version < 1 || data.Version > model.ProjectExportVersion {
+		return nil, fmt.Errorf("%w: unsupported version %d", model.ErrInvalidProjectExport, data.Version)
+	}
+
+	sourceRoot := strings.TrimSpace(data.Project.Path)
+	targetRoot := strings.TrimSpace(path)
+	if targetRoot == "" {
+		targetRoot = sourceRoot
+	}
+	if targetRoot == "" {
+		return nil, fmt.Errorf("%w: project path is required", model.ErrInvalidProjectExport)
+	}
+	if abs, err := filepath.Abs(targetRoot); err == nil {
+		targetRoot = filepath.Clean(abs)
+	}
+	if info, err := os.Stat(targetRoot); err != nil || !info.IsDir() {
+		return nil, fmt.Errorf("%w: %s does not exist on this machine, import again with the project location as path", model.ErrInvalidProjectPath, targetRoot)
+	}
+
+	report := &model.ProjectImportReport{Items: make([]model.ProjectImportItem, 0)}
+	project, err := s.findProjectByPath(ctx, targetRoot)
+	if err != nil {
+		return nil, err
+	}
+	projectItem := model.ProjectImportItem{Kind: "project", Name: data.Project.Name, SourceID: data.Project.ID}
+	if project != nil {
+		projectItem.Status = model.ImportStatusSkipped
+		projectItem.Reason = fmt.Sprintf("project %q already tracks this path, importing into it", project.Name)
+	} else {
+		project, err = s.projects.CreateProject(ctx, model.CreateProjectParams{
+			Name:             data.Project.Name,
+			Path:             targetRoot,
+			Description:      data.Project.Description,
+			WorktreeBasePath: rebasePath(data.Project.WorktreeBasePath, sourceRoot, targetRoot),
+			HidePath:         data.Project.HidePath,
+		})
+		if err != nil {
+			return nil, err
+		}
+		if data.Project.Priority != nil {
+			if updated, err := s.projects.UpdateProjectPriority(ctx, project.Id, data.Project.Priority); err == nil {
+				project = updated
+			}
+		}
+		if len(data.Project.ProtectedBranches) > 0 {
+			if updated, err := s.projects.UpdateProtectedBranches(ctx, project.Id, data.Project.ProtectedBranches); err == nil {
+				project = updated
+			}
+		}
+		if data.Project.Archived {
+			if updated, err := s.projects.SetArchived(ctx, project.Id, true); err == nil {
+				project = updated
+			}
+		}
+		report.Created = true
+		projectItem.Status = model.ImportStatusImported
+	}
+	projectItem.ID = project.Id
+	report.Project = project
+	report.Items = append(report.Items, projectItem)
+
+	notepadItems, err := s.importNotePads(ctx, project.Id, data.NotePads)
+	if err != nil {
+		return nil, err
+	}
+	report.Items = append(report.Items, notepadItems...)
+
+	worktreeItems, err := s.matchWorktrees(ctx, project.Id, data.Worktrees, sourceRoot, targetRoot)
+	if err != nil {
+		return nil, err
+	}
+	report.Items = append(report.Items, worktreeItems...)
+
+	utils.Logger().Info("project imported",
+		zap.String("projectId", project.Id),
+		zap.String("sourceId", data.Project.ID),
+		zap.Bool("created", report.Created),
+		zap.Int("items", len(report.Items)),
+	)
+	return report, nil
+}
+
+func (s *ProjectTransferService) findProjectByPath(ctx context.Context, path string) (*model.Project, error) {
+	projects, err := s.projects.ListProjects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	target := model.NormalizePathCase(path)
+	for _, project := range projects {
+		if model.NormalizePathCase(project.Path) == target {
+			return project, nil
+		}
+	}
+	return nil, nil
+}
+
+// importNotePads creates the notepads in their exported order, skipping those
+// with the same name and content as an existing one.
+func (s *ProjectTransferService) importNotePads(ctx context.Context, projectID string, notepads []model.NotePadExport) ([]model.ProjectImportItem, error) {
+	existing, err := s.notepads.ListNotePads(ctx, &projectID)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]string, len(existing))
+	for _, notepad := range existing {
+		seen[notepad.Name+"\x00"+notepad.Content] = notepad.ID
+	}
+
+	ordered := slices.Clone(notepads)
+	slices.SortStableFunc(ordered, func(a, b model.NotePadExport) int {
+		switch {
+		case a.OrderIndex < b.OrderIndex:
+			return -1
+		case a.OrderIndex > b.OrderIndex:
+			return 1
+		}
+		return 0
+	})
+
+	items := make([]model.ProjectImportItem, 0, len(ordered))
+	for _, notepad := range ordered {
+		item := model.ProjectImportItem{Kind: "notepad", Name: notepad.Name, SourceID: notepad.ID}
+		key := notepad.Name + "\x00" + notepad.Content
+		if id, ok := seen[key]; ok {
+			item.ID = id
+			item.Status = model.ImportStatusSkipped
+			item.Reason = "an identical notepad already exists"
+			items = append(items, item)
+			continue
+		}
+		created, err := s.notepads.CreateNotePad(ctx, &model.CreateNotePadRequest{
+			ProjectID: &projectID,
+			Name:      notepad.Name,
+			Content:   notepad.Content,
+		})
+		if err != nil {
+			return nil, err
+		}
+		seen[key] = created.ID
+		item.ID = created.ID
+		item.Status = model.ImportStatusImported
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// matchWorktrees syncs the worktrees git knows about and reports which exported
+// worktrees exist on this machine.
+func (s *ProjectTransferService) matchWorktrees(ctx context.Context, projectID string, worktrees []model.WorktreeExport, sourceRoot, targetRoot string) ([]model.ProjectImportItem, error) {
+	if err := s.worktrees.SyncWorktrees(ctx, projectID); err != nil && !errors.Is(err, model.ErrWorktreeNotFound) {
+		utils.Logger().Warn("failed to sync worktrees after import",
+			zap.Error(err),
+			zap.String("projectId", projectID),
+		)
+	}
+	current, err := s.worktrees.ListWorktrees(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+	byPath := make(map[string]*model.Worktree, len(current))
+	for _, wt := range current {
+		byPath[model.NormalizePathCase(wt.Path)] = wt
+	}
+
+	items := make([]model.ProjectImportItem, 0, len(worktrees))
+	for _, wt := range worktrees {
+		// 主 worktree 随项目一起创建
+		if wt.IsMain {
+			continue
+		}
+		item := model.ProjectImportItem{Kind: "worktree", Name: wt.BranchName, SourceID: wt.ID}
+		path := rebasePath(wt.Path, sourceRoot, targetRoot)
+		if found, ok := byPath[model.NormalizePathCase(path)]; ok {
+			item.ID = found.Id
+			item.Status = model.ImportStatusImported
+		} else {
+			item.Status = model.ImportStatusMissing
+			item.Reason = fmt.Sprintf("worktree %s does not exist, recreate it from branch %q and sync", path, wt.BranchName)
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// rebasePath moves path from under sourceRoot to under targetRoot; paths outside
+// sourceRoot are kept as is.
+func rebasePath(path, sourceRoot, targetRoot string) string {
+	if path == "" || sourceRoot == "" {
+		return path
+	}
+	rel, err := filepath.Rel(model.NormalizePathCase(sourceRoot), model.NormalizePathCase(path))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return path
+	}
+	return filepath.Join(targetRoot, rel)
+}
+
+func derefString(value *string) string {
+	if value == nil {
+		return ""
+	}
+	return *value
+}
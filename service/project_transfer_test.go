@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"code-kanban/model"
+)
+
+func TestProjectTransferExportImport(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name:        "Transfer Project",
+		Path:        repoPath,
+		Description: "notes and worktrees",
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	ctx := context.Background()
+	if _, err := projectService.UpdateProtectedBranches(ctx, project.Id, []string{"release/*"}); err != nil {
+		t.Fatalf("UpdateProtectedBranches failed: %v", err)
+	}
+	notepads := &model.NotePadService{}
+	for _, name := range []string{"todo", "ideas"} {
+		if _, err := notepads.CreateNotePad(ctx, &model.CreateNotePadRequest{ProjectID: &project.Id, Name: name, Content: name + " content"}); err != nil {
+			t.Fatalf("CreateNotePad failed: %v", err)
+		}
+	}
+	worktrees := NewWorktreeService()
+	worktrees.AsyncRefresh(false)
+	if _, err := worktrees.CreateWorktree(ctx, project.Id, "feature/transfer", "main", true, false); err != nil {
+		t.Fatalf("CreateWorktree failed: %v", err)
+	}
+
+	svc := NewProjectTransferService()
+	export, err := svc.ExportProject(ctx, project.Id)
+	if err != nil {
+		t.Fatalf("ExportProject failed: %v", err)
+	}
+	if export.Version != model.ProjectExportVersion || export.Project.Name != "Transfer Project" || len(export.NotePads) != 2 ||
+		!slices.Equal(export.Project.ProtectedBranches, []string{"release/*"}) {
+		t.Fatalf("unexpected export %+v", export)
+	}
+
+	// 导入到同一路径：合并到已有项目，相同便签跳过
+	report, err := svc.ImportProject(ctx, export, "")
+	if err != nil {
+		t.Fatalf("ImportProject into existing project failed: %v", err)
+	}
+	if report.Created || report.Project.Id != project.Id {
+		t.Fatalf("expected import into existing project, got %+v", report)
+	}
+	counts := importStatusCounts(report)
+	if counts["notepad/"+model.ImportStatusSkipped] != 2 || counts["worktree/"+model.ImportStatusImported] != 1 {
+		t.Fatalf("unexpected items %+v", report.Items)
+	}
+
+	// 导入到另一台机器上的新路径：重新生成 ID，worktree 目录不存在时报告 missing
+	newRoot := createProjectTestRepo(t)
+	report, err = svc.ImportProject(ctx, export, newRoot)
+	if err != nil {
+		t.Fatalf("ImportProject into new path failed: %v", err)
+	}
+	if !report.Created || report.Project.Id == project.Id || report.Project.Path != filepath.Clean(newRoot) {
+		t.Fatalf("expected a new project at %s, got %+v", newRoot, report.Project)
+	}
+	counts = importStatusCounts(report)
+	if counts["notepad/"+model.ImportStatusImported] != 2 || counts["worktree/"+model.ImportStatusMissing] != 1 {
+		t.Fatalf("unexpected items %+v", report.Items)
+	}
+	imported, err := notepads.ListNotePads(ctx, &report.Project.Id)
+	if err != nil {
+		t.Fatalf("ListNotePads failed: %v", err)
+	}
+	if len(imported) != 2 || imported[0].Name != "todo" || imported[1].Name != "ideas" || imported[0].ID == export.NotePads[0].ID {
+		t.Fatalf("unexpected imported notepads %+v", imported)
+	}
+	if report.Project.WorktreeBasePath == nil || *report.Project.WorktreeBasePath != filepath.Join(newRoot, ".worktrees") {
+		t.Fatalf("expected worktree base path to be rebased, got %v", report.Project.WorktreeBasePath)
+	}
+	if patterns := model.ProtectedBranchPatterns(report.Project); !slices.Equal(patterns, []string{"release/*"}) {
+		t.Fatalf("expected protected branches to be imported, got %v", patterns)
+	}
+	if report.Project.Archived {
+		t.Fatalf("expected an active project to stay active after import")
+	}
+
+	// 归档状态随导出一起迁移
+	if _, err := projectService.SetArchived(ctx, project.Id, true); err != nil {
+		t.Fatalf("SetArchived failed: %v", err)
+	}
+	archivedExport, err := svc.ExportProject(ctx, project.Id)
+	if err != nil || !archivedExport.Project.Archived {
+		t.Fatalf("expected archived flag in export, got %+v, %v", archivedExport, err)
+	}
+	archivedReport, err := svc.ImportProject(ctx, archivedExport, createProjectTestRepo(t))
+	if err != nil {
+		t.Fatalf("ImportProject of archived project failed: %v", err)
+	}
+	if !archivedReport.Created || !archivedReport.Project.Archived {
+		t.Fatalf("expected the imported project to be archived, got %+v", archivedReport.Project)
+	}
+
+	if _, err := svc.ImportProject(ctx, export, filepath.Join(newRoot, "missing")); !errors.Is(err, model.ErrInvalidProjectPath) {
+		t.Fatalf("expected ErrInvalidProjectPath for missing path, got %v", err)
+	}
+	export.Version = model.ProjectExportVersion + 1
+	if _, err := svc.ImportProject(ctx, export, newRoot); !errors.Is(err, model.ErrInvalidProjectExport) {
+		t.Fatalf("expected ErrInvalidProjectExport for newer version, got %v", err)
+	}
+}
+
+func importStatusCounts(report *model.ProjectImportReport) map[string]int {
+	counts := make(map[string]int)
+	for _, item := range report.Items {
+		counts[item.Kind+"/"+item.Status]++
+	}
+	return counts
+}
@@ -0,0 +1,125 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// ErrUnknownProfile indicates the requested AI profile is not configured.
+var ErrUnknownProfile = errors.New("terminal ai profile is not configured")
+
+// AIProfile is a named set of settings for starting an AI assistant, such as
+// a model, endpoint or local runner. Creating a session with a profile applies
+// its shell and environment, then types its init commands and command.
+type AIProfile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Assistant 档案启动的助手类型（如 claude-code），仅用于展示
+	Assistant string `json:"assistant,omitempty"`
+	// Command 启动 AI CLI 的命令，如 "claude --model opus"
+	Command string `json:"command,omitempty"`
+	// Env 附加的环境变量，如 ANTHROPIC_BASE_URL；值可能是密钥，不通过接口返回
+	Env map[string]string `json:"-"`
+	// InitCommands 在 Command 之前依次执行的命令，如激活虚拟环境
+	InitCommands []string `json:"initCommands,omitempty"`
+	// ShellName 选择 terminal.shell.named 中的 shell，创建会话时显式指定的 shell 优先
+	ShellName string `json:"shellName,omitempty"`
+}
+
+// EnvKeys returns the sorted names of the variables the profile sets.
+func (p AIProfile) EnvKeys() []string {
+	keys := make([]string, 0, len(p.Env))
+	for key := range p.Env {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// envList returns the profile variables as KEY=VALUE pairs in key order.
+func (p AIProfile) envList() []string {
+	keys := p.EnvKeys()
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+p.Env[key])
+	}
+	return env
+}
+
+// inputs returns the lines typed into a new session: init commands, then the command.
+func (p AIProfile) inputs() []string {
+	lines := make([]string, 0, len(p.InitCommands)+1)
+	for _, command := range p.InitCommands {
+		if command = strings.TrimSpace(command); command != "" {
+			lines = append(lines, command)
+		}
+	}
+	if command := strings.TrimSpace(p.Command); command != "" {
+		lines = append(lines, command)
+	}
+	return lines
+}
+
+// validateAIProfiles checks ids, environment variable names and that every
+// profile runs something.
+func validateAIProfiles(profiles []AIProfile) error {
+	seen := make(map[string]struct{}, len(profiles))
+	for i, profile := range profiles {
+		if !macroNamePattern.MatchString(profile.ID) {
+			return fmt.Errorf("ai profile %d: invalid id %q", i, profile.ID)
+		}
+		if _, ok := seen[profile.ID]; ok {
+			return fmt.Errorf("ai profile %q: defined more than once", profile.ID)
+		}
+		seen[profile.ID] = struct{}{}
+		if len(profile.inputs()) == 0 {
+			return fmt.Errorf("ai profile %q: command or initCommands is required", profile.ID)
+		}
+		for key := range profile.Env {
+			if key == "" || strings.ContainsAny(key, "=\x00") {
+				return fmt.Errorf("ai profile %q: invalid env name %q", profile.ID, key)
+			}
+		}
+		if profile.Assistant != "" && !knownAssistantType(profile.Assistant) {
+			return fmt.Errorf("ai profile %q: unknown assistant %q", profile.ID, profile.Assistant)
+		}
+	}
+	return nil
+}
+
+// AIProfiles returns the configured AI profiles.
+func (m *Manager) AIProfiles() []AIProfile {
+	return slices.Clone(m.aiProfiles)
+}
+
+func (m *Manager) aiProfile(id string) (AIProfile, error) {
+	id = strings.TrimSpace(id)
+	for _, profile := range m.aiProfiles {
+		if profile.ID == id {
+			return profile, nil
+		}
+	}
+	return AIProfile{}, fmt.Errorf("%w: %q", ErrUnknownProfile, id)
+}
+
+// runProfileInputs types the profile commands into a new session. Input is
+// buffered until the shell is ready, so the commands run in order after its
+// prompt appears. Read-only sessions only get the profile shell and environment.
+func (m *Manager) runProfileInputs(session *Session, profile AIProfile) {
+	if session.ReadOnly() {
+		return
+	}
+	for _, line := range profile.inputs() {
+		if err := session.WriteInput([]byte(line + "\r")); err != nil {
+			m.logger.Warn("failed to run ai profile command",
+				zap.String("sessionId", session.ID()),
+				zap.String("profileId", profile.ID),
+				zap.Error(err))
+			return
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package terminal
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestValidateAIProfiles(t *testing.T) {
+	invalid := [][]AIProfile{
+		{{ID: "bad id", Command: "claude"}},
+		{{ID: "opus", Command: "  "}},
+		{{ID: "opus", Command: "claude", Assistant: "vim"}},
+		{{ID: "opus", Command: "claude", Env: map[string]string{"A=B": "x"}}},
+		{{ID: "opus", Command: "claude"}, {ID: "opus", Command: "claude --model opus"}},
+	}
+	for _, profiles := range invalid {
+		if err := validateAIProfiles(profiles); err == nil {
+			t.Fatalf("expected %+v to be rejected", profiles)
+		}
+	}
+	if err := validateAIProfiles([]AIProfile{
+		{ID: "opus", Command: "claude --model opus", Assistant: "claude-code"},
+		{ID: "local", InitCommands: []string{"source .venv/bin/activate"}},
+	}); err != nil {
+		t.Fatalf("expected profiles to be valid: %v", err)
+	}
+}
+
+func TestAIProfileInputsAndEnv(t *testing.T) {
+	profile := AIProfile{
+		ID:           "proxy",
+		Command:      " claude ",
+		InitCommands: []string{"cd app", "", "nvm use"},
+		Env:          map[string]string{"ZED": "1", "ANTHROPIC_BASE_URL": "http://localhost:8080"},
+	}
+	if got := profile.inputs(); !slices.Equal(got, []string{"cd app", "nvm use", "claude"}) {
+		t.Fatalf("unexpected inputs %q", got)
+	}
+	if got := profile.EnvKeys(); !slices.Equal(got, []string{"ANTHROPIC_BASE_URL", "ZED"}) {
+		t.Fatalf("unexpected env keys %q", got)
+	}
+	if got := profile.envList(); !slices.Equal(got, []string{"ANTHROPIC_BASE_URL=http://localhost:8080", "ZED=1"}) {
+		t.Fatalf("unexpected env %q", got)
+	}
+}
+
+func TestManagerAIProfile(t *testing.T) {
+	m := &Manager{aiProfiles: []AIProfile{{ID: "opus", Command: "claude --model opus"}}}
+	profile, err := m.aiProfile("opus")
+	if err != nil || profile.Command != "claude --model opus" {
+		t.Fatalf("unexpected profile %+v err=%v", profile, err)
+	}
+	if _, err := m.aiProfile("missing"); !errors.Is(err, ErrUnknownProfile) {
+		t.Fatalf("expected ErrUnknownProfile, got %v", err)
+	}
+}
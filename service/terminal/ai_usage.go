@@ -0,0 +1,84 @@
+package terminal
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/model/tables"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// usageRecorder persists the AI usage of one session for the usage report:
+// a span each time the assistant state changes and the new tokens at each
+// completion. It is only used from monitorAssistantRecords.
+type usageRecorder struct {
+	m         *Manager
+	session   *Session
+	assistant string
+	state     string
+	since     time.Time
+	// reported 已计入之前完成记录的累计 token 用量
+	reported TokenUsage
+}
+
+// transition closes the span of the previous state. An empty assistant means
+// the assistant detached or the session ended.
+func (r *usageRecorder) transition(assistant, state string, now time.Time) {
+	if assistant == r.assistant && state == r.state {
+		return
+	}
+	if r.assistant != "" && r.state != "" && r.state != string(types.StateUnknown) && now.After(r.since) {
+		r.save(&tables.AIUsageLogTable{
+			Assistant:  r.assistant,
+			Kind:       model.AIUsageKindState,
+			State:      r.state,
+			StartedAt:  r.since,
+			EndedAt:    now,
+			DurationMs: now.Sub(r.since).Milliseconds(),
+		})
+	}
+	r.assistant, r.state, r.since = assistant, state, now
+}
+
+// completion records a finished task with the tokens used since the previous one.
+func (r *usageRecorder) completion(assistant string, now time.Time) {
+	entry := &tables.AIUsageLogTable{
+		Assistant:   assistant,
+		Kind:        model.AIUsageKindCompletion,
+		State:       string(types.StateWaitingInput),
+		StartedAt:   now,
+		EndedAt:     now,
+		DurationMs:  r.session.AssistantWorkDuration().Milliseconds(),
+		Interrupted: r.session.AssistantInterrupted(),
+	}
+	if current := r.session.AssistantTokenUsage(); current != nil {
+		entry.InputTokens = current.InputTokens - r.reported.InputTokens
+		entry.OutputTokens = current.OutputTokens - r.reported.OutputTokens
+		previousCost := 0.0
+		if r.reported.EstimatedCostUSD != nil {
+			previousCost = *r.reported.EstimatedCostUSD
+		}
+		// 累计值变小说明统计被重置，整段计入本次
+		if entry.InputTokens < 0 || entry.OutputTokens < 0 {
+			entry.InputTokens, entry.OutputTokens = current.InputTokens, current.OutputTokens
+			previousCost = 0
+		}
+		if current.EstimatedCostUSD != nil {
+			cost := max(*current.EstimatedCostUSD-previousCost, 0)
+			entry.CostUSD = &cost
+		}
+		r.reported = *current
+	}
+	r.save(entry)
+}
+
+func (r *usageRecorder) save(entry *tables.AIUsageLogTable) {
+	entry.SessionID = r.session.ID()
+	entry.ProjectID = r.session.ProjectID()
+	if err := (&model.AIUsageService{}).RecordAIUsage(context.Background(), entry); err != nil {
+		r.m.logger.Debug("record ai usage failed", zap.Error(err), zap.String("sessionId", entry.SessionID))
+	}
+}
@@ -0,0 +1,235 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils/ai_assistant2"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+const (
+	// alertBufferMaxBytes 告警规则只检查最近的输出
+	alertBufferMaxBytes  = 8 * 1024
+	defaultAlertCooldown = time.Minute
+	// alertContextLines 告警附带的命中行之前的上下文行数
+	alertContextLines = 2
+)
+
+// outputLineBreakPattern 预筛时视为换行的光标定位序列（CUP/HVP/VPA/CNL/CPL）
+var outputLineBreakPattern = regexp.MustCompile(`\x1b\[[0-9;]*[HfdEF]`)
+
+// AlertRule raises a notification when a rendered line matches Pattern.
+type AlertRule struct {
+	// Name 显示在通知中的规则名称，为空时使用 Pattern
+	Name    string `json:"name,omitempty" yaml:"name"`
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// CooldownSeconds 同一规则两次告警的最小间隔，0 使用默认 60 秒
+	CooldownSeconds int `json:"cooldownSeconds,omitempty" yaml:"cooldownSeconds"`
+}
+
+type compiledAlertRule struct {
+	AlertRule
+	re        *regexp.Regexp
+	cooldown  time.Duration
+	lastFired time.Time
+}
+
+// OutputAlert is a line matched by an alert rule, with the lines before it.
+type OutputAlert struct {
+	Rule    string   `json:"rule"`
+	Pattern string   `json:"pattern"`
+	Line    string   `json:"line"`
+	Context []string `json:"context,omitempty"`
+}
+
+// compileAlertRules validates alert rules; the index of an invalid rule is reported.
+func compileAlertRules(rules []AlertRule) ([]*compiledAlertRule, error) {
+	compiled := make([]*compiledAlertRule, 0, len(rules))
+	for i, rule := range rules {
+		if strings.TrimSpace(rule.Pattern) == "" {
+			return nil, fmt.Errorf("alert rule %d: pattern is required", i)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("alert rule %d: invalid pattern: %w", i, err)
+		}
+		cooldown := time.Duration(rule.CooldownSeconds) * time.Second
+		if cooldown <= 0 {
+			cooldown = defaultAlertCooldown
+		}
+		compiled = append(compiled, &compiledAlertRule{AlertRule: rule, re: re, cooldown: cooldown})
+	}
+	return compiled, nil
+}
+
+// setGlobalAlertRules replaces the alert rules shared by all sessions.
+func (s *Session) setGlobalAlertRules(rules []*compiledAlertRule) {
+	s.alertMu.Lock()
+	s.globalAlertRules = cloneCompiledAlertRules(rules)
+	s.resetAlertBufferLocked()
+	s.alertMu.Unlock()
+}
+
+// SetAlertRules replaces the session scoped alert rules.
+func (s *Session) SetAlertRules(rules []AlertRule) error {
+	compiled, err := compileAlertRules(rules)
+	if err != nil {
+		return err
+	}
+	s.alertMu.Lock()
+	s.sessionAlertRules = compiled
+	s.resetAlertBufferLocked()
+	s.alertMu.Unlock()
+	return nil
+}
+
+// AlertRules returns the session scoped alert rules.
+func (s *Session) AlertRules() []AlertRule {
+	s.alertMu.Lock()
+	defer s.alertMu.Unlock()
+	result := make([]AlertRule, 0, len(s.sessionAlertRules))
+	for _, rule := range s.sessionAlertRules {
+		result = append(result, rule.AlertRule)
+	}
+	return result
+}
+
+func (s *Session) resetAlertBufferLocked() {
+	if len(s.globalAlertRules) == 0 && len(s.sessionAlertRules) == 0 {
+		s.alertBuffer = nil
+	}
+}
+
+// checkAlertRules broadcasts an alert event for the first rule matching a
+// rendered line of recent output. Rendering is costly, so the output since the
+// last line break is first matched without rendering and only rules matching
+// it there are checked on the rendered screen. The buffer is cleared after an
+// alert so the same line is not reported again, and each rule additionally
+// honours its cooldown; a match suppressed by the cooldown clears the buffer
+// too, so the stale line is not reported once the cooldown ends.
+func (s *Session) checkAlertRules(chunk []byte) {
+	s.alertMu.Lock()
+	if len(s.globalAlertRules) == 0 && len(s.sessionAlertRules) == 0 {
+		s.alertMu.Unlock()
+		return
+	}
+	// 预筛从新输出所在行的行首开始，跨分片的行也能命中
+	start := bytes.LastIndexByte(s.alertBuffer, '\n') + 1
+	s.alertBuffer = append(s.alertBuffer, chunk...)
+	if overflow := len(s.alertBuffer) - alertBufferMaxBytes; overflow > 0 {
+		s.alertBuffer = append([]byte(nil), s.alertBuffer[overflow:]...)
+		start = max(start-overflow, 0)
+	}
+	rules := make([]*compiledAlertRule, 0, len(s.sessionAlertRules)+len(s.globalAlertRules))
+	rules = append(rules, s.sessionAlertRules...)
+	rules = append(rules, s.globalAlertRules...)
+	pending := outputPreMatchLines(s.alertBuffer[start:])
+	candidates := rules[:0]
+	for _, rule := range rules {
+		if preMatchLines(rule.re, pending) {
+			candidates = append(candidates, rule)
+		}
+	}
+	if len(candidates) == 0 {
+		s.alertMu.Unlock()
+		return
+	}
+	data := append([]byte(nil), s.alertBuffer...)
+	s.alertMu.Unlock()
+
+	s.mu.RLock()
+	rows, cols := s.rows, s.cols
+	s.mu.RUnlock()
+
+	lines := s.renderLogicalLines(data, rows, cols)
+	now := time.Now()
+	suppressed := false
+	for _, rule := range candidates {
+		alert, ok := rule.match(lines)
+		if !ok {
+			continue
+		}
+
+		s.alertMu.Lock()
+		if now.Sub(rule.lastFired) < rule.cooldown {
+			s.alertMu.Unlock()
+			suppressed = true
+			continue
+		}
+		rule.lastFired = now
+		s.alertBuffer = nil
+		s.alertMu.Unlock()
+
+		s.logger.Debug("terminal output alert raised",
+			zap.String("sessionId", s.id),
+			zap.String("pattern", rule.Pattern))
+		s.broadcast(StreamEvent{Type: StreamEventAlert, Alert: alert})
+		return
+	}
+	if suppressed {
+		s.alertMu.Lock()
+		s.alertBuffer = nil
+		s.alertMu.Unlock()
+	}
+}
+
+// outputPreMatchLines roughly splits raw output into text lines for matching
+// rules without rendering: cursor positioning starts a new line and escape
+// sequences are removed.
+func outputPreMatchLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	text := outputLineBreakPattern.ReplaceAllString(string(data), "\n")
+	return strings.Split(types.StripANSI(text), "\n")
+}
+
+// preMatchLines reports whether re matches any of lines.
+func preMatchLines(re *regexp.Regexp, lines []string) bool {
+	for _, line := range lines {
+		if line != "" && re.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
+
+// match returns the last line matched by the rule with up to alertContextLines
+// non-empty lines before it.
+func (r *compiledAlertRule) match(lines []ai_assistant2.LogicalLine) (*OutputAlert, bool) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		text := lines[i].Text
+		if text == "" || !r.re.MatchString(text) {
+			continue
+		}
+		context := make([]string, 0, alertContextLines)
+		for j := i - 1; j >= 0 && len(context) < alertContextLines; j-- {
+			if strings.TrimSpace(lines[j].Text) != "" {
+				context = append([]string{lines[j].Text}, context...)
+			}
+		}
+		name := r.Name
+		if name == "" {
+			name = r.Pattern
+		}
+		return &OutputAlert{Rule: name, Pattern: r.Pattern, Line: text, Context: context}, true
+	}
+	return nil, false
+}
+
+// cloneCompiledAlertRules gives every session its own cooldown state for shared rules.
+func cloneCompiledAlertRules(rules []*compiledAlertRule) []*compiledAlertRule {
+	result := make([]*compiledAlertRule, 0, len(rules))
+	for _, rule := range rules {
+		copied := *rule
+		copied.lastFired = time.Time{}
+		result = append(result, &copied)
+	}
+	return result
+}
@@ -0,0 +1,152 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestCompileAlertRules(t *testing.T) {
+	if _, err := compileAlertRules([]AlertRule{{Pattern: "("}}); err == nil {
+		t.Fatalf("expected invalid pattern to fail")
+	}
+	if _, err := compileAlertRules([]AlertRule{{Name: "empty"}}); err == nil {
+		t.Fatalf("expected empty pattern to fail")
+	}
+	compiled, err := compileAlertRules([]AlertRule{{Pattern: "build failed"}})
+	if err != nil || len(compiled) != 1 || compiled[0].cooldown != defaultAlertCooldown {
+		t.Fatalf("unexpected compile result %+v %v", compiled, err)
+	}
+}
+
+func TestSessionAlertRuleBroadcastsWithCooldown(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 10, Cols: 40})
+	if err := session.SetAlertRules([]AlertRule{{Name: "build", Pattern: `build failed`}}); err != nil {
+		t.Fatalf("SetAlertRules failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := session.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+
+	session.checkAlertRules([]byte("compiling\r\n\r\nlinking\r\nbuild failed: exit 2\r\n"))
+	select {
+	case event := <-stream.Events():
+		if event.Type != StreamEventAlert || event.Alert == nil {
+			t.Fatalf("expected alert event, got %+v", event)
+		}
+		if event.Alert.Rule != "build" || event.Alert.Line != "build failed: exit 2" ||
+			!slices.Equal(event.Alert.Context, []string{"compiling", "linking"}) {
+			t.Fatalf("unexpected alert %+v", event.Alert)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected alert event")
+	}
+
+	// 冷却期内同一规则不重复告警，被抑制的输出也随之丢弃
+	session.checkAlertRules([]byte("build failed again\r\n"))
+	select {
+	case event := <-stream.Events():
+		t.Fatalf("expected no alert within cooldown, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+	session.alertMu.Lock()
+	pending := len(session.alertBuffer)
+	session.sessionAlertRules[0].lastFired = time.Time{}
+	session.alertMu.Unlock()
+	if pending != 0 {
+		t.Fatalf("expected the suppressed output to be dropped, %d bytes left", pending)
+	}
+	// 冷却结束后不再报告冷却期内的旧输出
+	session.checkAlertRules([]byte("all good\r\n"))
+	select {
+	case event := <-stream.Events():
+		t.Fatalf("expected no alert for output seen during the cooldown, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestOutputPreMatchLines(t *testing.T) {
+	lines := outputPreMatchLines([]byte("\x1b[31mbuild\x1b[0m failed\x1b[5;1H^anchored\r\nnext"))
+	if !slices.Equal(lines, []string{"build failed", "^anchored", "next"}) {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+	compiled, err := compileAlertRules([]AlertRule{{Pattern: `^next$`}, {Pattern: `missing`}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !preMatchLines(compiled[0].re, lines) || preMatchLines(compiled[1].re, lines) {
+		t.Fatalf("unexpected pre-match result for %q", lines)
+	}
+}
+
+// 跨分片的行在预筛时按整行匹配
+func TestSessionAlertRuleAcrossChunks(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 10, Cols: 40})
+	if err := session.SetAlertRules([]AlertRule{{Pattern: `^panic: `}}); err != nil {
+		t.Fatalf("SetAlertRules failed: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := session.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+
+	session.checkAlertRules([]byte("ok\r\npan"))
+	session.checkAlertRules([]byte("ic: nil map\r\n"))
+	select {
+	case event := <-stream.Events():
+		if event.Alert == nil || event.Alert.Line != "panic: nil map" {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("expected alert event")
+	}
+}
+
+func TestRecordManagerAlerts(t *testing.T) {
+	rm := NewRecordManager()
+	rm.AddAlert(&AlertRecord{ID: "a1", SessionID: "s1", Rule: "build", TriggeredAt: time.Now()})
+	rm.AddAlert(&AlertRecord{ID: "a2", SessionID: "s1", Rule: "test", TriggeredAt: time.Now().Add(time.Second)})
+
+	alerts := rm.GetAlerts()
+	if len(alerts) != 2 || alerts[0].ID != "a1" || rm.Summary().Alerts != 2 {
+		t.Fatalf("unexpected alerts %+v", alerts)
+	}
+	if !rm.DismissAlert("a1") || rm.DismissAlert("missing") {
+		t.Fatalf("unexpected dismiss result")
+	}
+	if got := rm.GetAlerts(); len(got) != 1 || got[0].ID != "a2" {
+		t.Fatalf("expected dismissed alert to be hidden, got %+v", got)
+	}
+
+	// 助手 detach 只清理完成/审批记录，告警在会话关闭时清理
+	rm.ClearSessionRecords("s1")
+	if len(rm.GetAlerts()) != 1 {
+		t.Fatalf("expected alerts to survive assistant records cleanup")
+	}
+	rm.ClearAlertsBySession("s1")
+	if len(rm.GetAlerts()) != 0 {
+		t.Fatalf("expected alerts to be cleared with the session")
+	}
+
+	// 同一会话的同一规则只保留最新一条
+	for i := range 100 {
+		rm.AddAlert(&AlertRecord{ID: fmt.Sprintf("b%d", i), SessionID: "s1", Rule: "build", TriggeredAt: time.Now().Add(time.Duration(i) * time.Second)})
+	}
+	rm.AddAlert(&AlertRecord{ID: "other", SessionID: "s2", Rule: "build", TriggeredAt: time.Now()})
+	if len(rm.alerts) != 2 || len(rm.sessionAlerts["s1"]) != 1 {
+		t.Fatalf("expected one record per session and rule, got %d records", len(rm.alerts))
+	}
+	if got := rm.GetAlerts(); len(got) != 2 || got[1].ID != "b99" {
+		t.Fatalf("expected the latest build alert to be kept, got %+v", got)
+	}
+}
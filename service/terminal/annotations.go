@@ -0,0 +1,90 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// annotationsMaxKeys 每个会话最多保存的键数量
+	annotationsMaxKeys = 64
+	// annotationKeyMaxBytes / annotationValueMaxBytes 单个键、值的长度上限
+	annotationKeyMaxBytes   = 128
+	annotationValueMaxBytes = 4096
+	// annotationsMaxBytes 所有键值的总长度上限
+	annotationsMaxBytes = 32 * 1024
+)
+
+// ErrInvalidAnnotations indicates annotations that break the key or size limits.
+var ErrInvalidAnnotations = errors.New("terminal: invalid annotations")
+
+// Annotations returns a copy of the custom key-value pairs attached to the
+// session, nil when there are none.
+func (s *Session) Annotations() map[string]string {
+	s.annotationMu.Lock()
+	defer s.annotationMu.Unlock()
+	if len(s.annotations) == 0 {
+		return nil
+	}
+	return maps.Clone(s.annotations)
+}
+
+// SetAnnotations replaces every annotation of the session and returns the result.
+func (s *Session) SetAnnotations(values map[string]string) (map[string]string, error) {
+	next := maps.Clone(values)
+	if err := validateAnnotations(next); err != nil {
+		return nil, err
+	}
+	s.annotationMu.Lock()
+	s.annotations = next
+	s.annotationMu.Unlock()
+	return s.Annotations(), nil
+}
+
+// PatchAnnotations sets the given keys and removes those mapped to nil, like a
+// JSON merge patch, and returns the result. Nothing changes when the result
+// would break the limits.
+func (s *Session) PatchAnnotations(changes map[string]*string) (map[string]string, error) {
+	s.annotationMu.Lock()
+	next := maps.Clone(s.annotations)
+	if next == nil {
+		next = make(map[string]string, len(changes))
+	}
+	for key, value := range changes {
+		if value == nil {
+			delete(next, key)
+			continue
+		}
+		next[key] = *value
+	}
+	if err := validateAnnotations(next); err != nil {
+		s.annotationMu.Unlock()
+		return nil, err
+	}
+	s.annotations = next
+	s.annotationMu.Unlock()
+	return s.Annotations(), nil
+}
+
+func validateAnnotations(values map[string]string) error {
+	if len(values) > annotationsMaxKeys {
+		return fmt.Errorf("%w: at most %d keys are allowed", ErrInvalidAnnotations, annotationsMaxKeys)
+	}
+	total := 0
+	for key, value := range values {
+		if strings.TrimSpace(key) == "" || len(key) > annotationKeyMaxBytes || !utf8.ValidString(key) {
+			return fmt.Errorf("%w: key %q must be non-empty UTF-8 of at most %d bytes", ErrInvalidAnnotations, key, annotationKeyMaxBytes)
+		}
+		if len(value) > annotationValueMaxBytes || !utf8.ValidString(value) {
+			return fmt.Errorf("%w: value of %q must be UTF-8 of at most %d bytes", ErrInvalidAnnotations, key, annotationValueMaxBytes)
+		}
+		total += len(key) + len(value)
+	}
+	if total > annotationsMaxBytes {
+		return fmt.Errorf("%w: total size exceeds %d bytes", ErrInvalidAnnotations, annotationsMaxBytes)
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package terminal
+
+import (
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+)
+
+func TestSessionAnnotations(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	if got := session.Annotations(); got != nil {
+		t.Fatalf("expected no annotations, got %v", got)
+	}
+
+	if _, err := session.SetAnnotations(map[string]string{"ticket": "JIRA-1", "owner": "ops"}); err != nil {
+		t.Fatalf("SetAnnotations: %v", err)
+	}
+	ticket := "JIRA-2"
+	got, err := session.PatchAnnotations(map[string]*string{"ticket": &ticket, "owner": nil, "missing": nil})
+	if err != nil {
+		t.Fatalf("PatchAnnotations: %v", err)
+	}
+	if want := map[string]string{"ticket": "JIRA-2"}; !maps.Equal(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	if snapshot := session.Snapshot(); snapshot.Annotations["ticket"] != "JIRA-2" {
+		t.Fatalf("expected annotations in snapshot, got %v", snapshot.Annotations)
+	}
+
+	// 返回的是副本，修改不影响会话
+	got["ticket"] = "changed"
+	if session.Annotations()["ticket"] != "JIRA-2" {
+		t.Fatalf("annotations leaked to the caller")
+	}
+
+	tooLong := strings.Repeat("v", annotationValueMaxBytes+1)
+	if _, err := session.PatchAnnotations(map[string]*string{"big": &tooLong}); !errors.Is(err, ErrInvalidAnnotations) {
+		t.Fatalf("expected ErrInvalidAnnotations, got %v", err)
+	}
+	if _, err := session.SetAnnotations(map[string]string{" ": "x"}); !errors.Is(err, ErrInvalidAnnotations) {
+		t.Fatalf("expected ErrInvalidAnnotations for blank key, got %v", err)
+	}
+	tooMany := make(map[string]string, annotationsMaxKeys+1)
+	for i := 0; i <= annotationsMaxKeys; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+	if _, err := session.SetAnnotations(tooMany); !errors.Is(err, ErrInvalidAnnotations) {
+		t.Fatalf("expected ErrInvalidAnnotations for too many keys, got %v", err)
+	}
+	if got := session.Annotations(); len(got) != 1 {
+		t.Fatalf("rejected updates must not change annotations, got %v", got)
+	}
+
+	if _, err := session.SetAnnotations(nil); err != nil || session.Annotations() != nil {
+		t.Fatalf("expected annotations to be cleared, got %v, %v", session.Annotations(), err)
+	}
+}
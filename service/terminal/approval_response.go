@@ -0,0 +1,157 @@
+package terminal
+
+import (
+	"bytes"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils/ai_assistant2"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// approvalKeyInterval 逐个发送按键的间隔，TUI 需要时间处理光标移动后再确认
+const approvalKeyInterval = 50 * time.Millisecond
+
+// approvalPromptCheckDelay 审批态下输出停顿多久后重新识别屏幕上的审批提示
+const approvalPromptCheckDelay = 300 * time.Millisecond
+
+// approvalScreenCache keeps the last rendered approval screen. Rendering the
+// whole scrollback is costly and the monitor asks for the screen on every
+// metadata event and prompt check, usually without new output in between.
+type approvalScreenCache struct {
+	mu    sync.Mutex
+	valid bool
+	rev   uint64
+	rows  int
+	cols  int
+	lines []string
+}
+
+// approvalScreen renders the scrollback the way the detector sees it.
+func (s *Session) approvalScreen() []string {
+	// 仅跟踪会话不保留 scrollback，改读跟踪器模拟的屏幕
+	if s.trackOnly {
+		if tracker := s.assistantTracker; tracker != nil {
+			return tracker.ScreenLines()
+		}
+		return nil
+	}
+	s.mu.RLock()
+	rows, cols := s.rows, s.cols
+	s.mu.RUnlock()
+
+	cache := &s.approvalCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	s.scrollMu.RLock()
+	if cache.valid && cache.rev == s.scrollbackRev && cache.rows == rows && cache.cols == cols {
+		s.scrollMu.RUnlock()
+		return cache.lines
+	}
+	// 与分片在同一把锁下读取版本号，缓存的内容与版本号一致
+	rev := s.scrollbackRev
+	data := bytes.Join(s.scrollback, nil)
+	s.scrollMu.RUnlock()
+
+	cache.lines = ai_assistant2.RenderLinesFromBuffer(data, rows, cols)
+	cache.valid, cache.rev, cache.rows, cache.cols = true, rev, rows, cols
+	return cache.lines
+}
+
+// ApprovalPromptID identifies the approval prompt currently on screen, "" when
+// the assistant is not waiting for approval or its prompt is not recognized.
+func (s *Session) ApprovalPromptID() string {
+	tracker := s.assistantTracker
+	if tracker == nil {
+		return ""
+	}
+	return tracker.ApprovalPromptID(s.approvalScreen())
+}
+
+// awaitingApprovalPrompt reports whether the assistant is still waiting for
+// approval on the prompt identified by promptID.
+func (s *Session) awaitingApprovalPrompt(promptID string) bool {
+	tracker := s.assistantTracker
+	if tracker == nil {
+		return false
+	}
+	if state, _ := tracker.State(); state != types.StateWaitingApproval {
+		return false
+	}
+	return tracker.ApprovalPromptID(s.approvalScreen()) == promptID
+}
+
+// approvalKeys returns the keys that answer the approval prompt currently on
+// screen, which must be the prompt identified by promptID. The prompt is read
+// from the rendered scrollback so the answer is based on the same lines the
+// detector saw.
+func (s *Session) approvalKeys(promptID string, approve bool) ([]string, error) {
+	tracker := s.assistantTracker
+	if tracker == nil {
+		return nil, ErrNoAssistantTracked
+	}
+	assistant := tracker.AssistantType()
+	if assistant == "" || assistant == types.AssistantTypeUnknown {
+		return nil, ErrNoAssistantTracked
+	}
+	if state, _ := tracker.State(); state != types.StateWaitingApproval {
+		return nil, ErrNotAwaitingApproval
+	}
+
+	lines := s.approvalScreen()
+	keys, ok := tracker.ApprovalInput(lines, approve)
+	if !ok {
+		return nil, ErrApprovalPromptUnrecognized
+	}
+	// 审批记录对应的提示已被新的提示替换，拒绝作答以免批准另一条命令
+	if tracker.ApprovalPromptID(lines) != promptID {
+		return nil, ErrApprovalPromptChanged
+	}
+	return keys, nil
+}
+
+// RespondApproval approves or rejects the approval prompt identified by
+// promptID by typing the keys the tracked assistant's menu expects. Keys are
+// written one at a time so the TUI sees cursor moves before the confirmation.
+func (s *Session) RespondApproval(promptID string, approve bool) error {
+	keys, err := s.approvalKeys(promptID, approve)
+	if err != nil {
+		return err
+	}
+	for i, key := range keys {
+		if i > 0 {
+			time.Sleep(approvalKeyInterval)
+		}
+		if _, err := s.Write([]byte(key)); err != nil {
+			return err
+		}
+	}
+	s.logger.Info("approval answered from notification",
+		zap.String("sessionId", s.id),
+		zap.Bool("approve", approve),
+	)
+	return nil
+}
+
+// RespondApproval answers the prompt behind an approval record and dismisses
+// the record. Records that were dismissed before are treated as missing, and a
+// record whose prompt is no longer on screen is refused with
+// ErrApprovalPromptChanged.
+func (m *Manager) RespondApproval(recordID string, approve bool) (*ApprovalRecord, error) {
+	record, ok := m.recordManager.approval(recordID)
+	if !ok {
+		return nil, ErrRecordNotFound
+	}
+	session, err := m.GetSession(record.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := session.RespondApproval(record.PromptID, approve); err != nil {
+		return nil, err
+	}
+	m.recordManager.DismissApproval(recordID)
+	record.Dismissed = true
+	return &record, nil
+}
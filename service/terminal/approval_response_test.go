@@ -0,0 +1,109 @@
+package terminal
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"code-kanban/utils/ai_assistant2"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+func TestSessionApprovalKeys(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 12, Cols: 60, ScrollbackLimit: 4096})
+	if _, err := session.approvalKeys("", true); !errors.Is(err, ErrNoAssistantTracked) {
+		t.Fatalf("expected ErrNoAssistantTracked, got %v", err)
+	}
+
+	tracker := session.assistantTracker
+	tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+	tracker.Activate(types.AssistantTypeCodex, 12, 60)
+	defer tracker.Deactivate()
+	tracker.Calibrate(types.StateWorking, "test")
+	if _, err := session.approvalKeys("", true); !errors.Is(err, ErrNotAwaitingApproval) {
+		t.Fatalf("expected ErrNotAwaitingApproval, got %v", err)
+	}
+
+	tracker.Calibrate(types.StateWaitingApproval, "test")
+	if _, err := session.approvalKeys("", true); !errors.Is(err, ErrApprovalPromptUnrecognized) {
+		t.Fatalf("expected ErrApprovalPromptUnrecognized, got %v", err)
+	}
+
+	session.appendScrollback([]byte("Would you like to run the following command?\r\n\r\n" +
+		"  1. Yes, proceed (y)\r\n" +
+		"  2. Yes, and don't ask again (a)\r\n" +
+		"› 3. No, and tell Codex what to do differently (esc)\r\n\r\n" +
+		"  Press enter to confirm or esc to cancel\r\n"))
+	promptID := session.ApprovalPromptID()
+	if promptID == "" {
+		t.Fatal("expected the codex prompt to be identified")
+	}
+	if _, err := session.approvalKeys("stale", true); !errors.Is(err, ErrApprovalPromptChanged) {
+		t.Fatalf("expected ErrApprovalPromptChanged, got %v", err)
+	}
+	keys, err := session.approvalKeys(promptID, true)
+	if err != nil {
+		t.Fatalf("approvalKeys: %v", err)
+	}
+	if want := []string{"\x1b[A", "\x1b[A", "\r"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("unexpected approve keys %q", keys)
+	}
+	if keys, _ := session.approvalKeys(promptID, false); !reflect.DeepEqual(keys, []string{"\x1b"}) {
+		t.Fatalf("unexpected reject keys %q", keys)
+	}
+
+	// 移动光标不改变提示标识，换成另一条命令的提示后标识随之改变
+	session.appendScrollback([]byte("\x1b[2J\x1b[HWould you like to run the following command?\r\n\r\n" +
+		"› 1. Yes, proceed (y)\r\n" +
+		"  2. Yes, and don't ask again (a)\r\n" +
+		"  3. No, and tell Codex what to do differently (esc)\r\n\r\n" +
+		"  Press enter to confirm or esc to cancel\r\n"))
+	if id := session.ApprovalPromptID(); id != promptID {
+		t.Fatalf("cursor move changed the prompt id: %q != %q", id, promptID)
+	}
+	session.appendScrollback([]byte("\x1b[2J\x1b[HWould you like to run the following command?\r\n\r\n" +
+		"  $ rm -rf build\r\n\r\n" +
+		"› 1. Yes, proceed (y)\r\n" +
+		"  2. Yes, and don't ask again (a)\r\n" +
+		"  3. No, and tell Codex what to do differently (esc)\r\n"))
+	if id := session.ApprovalPromptID(); id == "" || id == promptID {
+		t.Fatalf("expected a new prompt id, got %q", id)
+	}
+	if _, err := session.approvalKeys(promptID, true); !errors.Is(err, ErrApprovalPromptChanged) {
+		t.Fatalf("expected ErrApprovalPromptChanged for the replaced prompt, got %v", err)
+	}
+}
+
+func TestManagerRespondApprovalRecordNotFound(t *testing.T) {
+	manager := &Manager{recordManager: NewRecordManager()}
+	manager.recordManager.AddApproval(&ApprovalRecord{ID: "a1", SessionID: "s1"})
+	manager.recordManager.DismissApproval("a1")
+	if _, err := manager.RespondApproval("a1", true); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("expected ErrRecordNotFound for dismissed record, got %v", err)
+	}
+	if _, err := manager.RespondApproval("missing", false); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+}
+
+func TestSessionApprovalScreenCache(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 4, Cols: 20, ScrollbackLimit: 4096})
+	session.appendScrollback([]byte("first\r\n"))
+	screen := session.approvalScreen()
+	if len(screen) == 0 || strings.TrimSpace(screen[0]) != "first" {
+		t.Fatalf("unexpected screen %q", screen)
+	}
+	// 没有新输出时复用上次的渲染结果
+	if again := session.approvalScreen(); &again[0] != &screen[0] {
+		t.Fatal("expected the cached screen without new output")
+	}
+	session.appendScrollback([]byte("second\r\n"))
+	if screen := session.approvalScreen(); len(screen) < 2 || strings.TrimSpace(screen[1]) != "second" {
+		t.Fatalf("cache not invalidated by new output: %q", screen)
+	}
+	session.UpdateScrollbackLimit(0)
+	if screen := session.approvalScreen(); len(screen) > 0 && strings.TrimSpace(screen[0]) != "" {
+		t.Fatalf("cache not invalidated by clearing the scrollback: %q", screen)
+	}
+}
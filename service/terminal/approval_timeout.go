@@ -0,0 +1,198 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/utils"
+)
+
+const (
+	// ApprovalTimeoutEscalate 超时后只升级通知，不动会话
+	ApprovalTimeoutEscalate = "escalate"
+	// ApprovalTimeoutReject 超时后替用户选择拒绝，需显式配置
+	ApprovalTimeoutReject = "reject"
+
+	// approvalTimeoutInterval 检查审批超时的间隔
+	approvalTimeoutInterval = 15 * time.Second
+	// minApprovalRejectTimeout 自动拒绝的最短超时，避免配置过短时在用户看到提示前就替其拒绝
+	minApprovalRejectTimeout = time.Minute
+	// approvalTimeoutAlertRule 审批超时生成的告警记录使用的规则名
+	approvalTimeoutAlertRule = "approval-timeout"
+)
+
+// ApprovalTimeoutPolicy decides what happens to an approval prompt nobody
+// answered within Timeout. Timeout 0 disables it.
+type ApprovalTimeoutPolicy struct {
+	Timeout time.Duration
+	// Action escalate（默认）或 reject
+	Action string
+}
+
+// ApprovalTimeoutConfig holds the global policy and per-assistant overrides,
+// keyed by assistant type such as claude-code or codex.
+type ApprovalTimeoutConfig struct {
+	Default    ApprovalTimeoutPolicy
+	Assistants map[string]ApprovalTimeoutPolicy
+}
+
+// policyFor returns the override of the assistant, falling back to the global
+// policy.
+func (c ApprovalTimeoutConfig) policyFor(assistantType string) ApprovalTimeoutPolicy {
+	if policy, ok := c.Assistants[assistantType]; ok {
+		return policy
+	}
+	return c.Default
+}
+
+// enabled reports whether any policy has a timeout.
+func (c ApprovalTimeoutConfig) enabled() bool {
+	if c.Default.Timeout > 0 {
+		return true
+	}
+	for _, policy := range c.Assistants {
+		if policy.Timeout > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ApprovalTimeoutPolicy) normalize() {
+	p.Action = strings.ToLower(strings.TrimSpace(p.Action))
+	if p.Action == "" {
+		p.Action = ApprovalTimeoutEscalate
+	}
+}
+
+// check validates the policy; invalid values fall back to escalation, never to
+// rejection.
+func (p *ApprovalTimeoutPolicy) check(name string, fix bool) []error {
+	var errs []error
+	if p.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("approvalTimeout %s: timeout %s must not be negative, use 0 to disable", name, p.Timeout))
+		if fix {
+			p.Timeout = 0
+		}
+	}
+	switch p.Action {
+	case "", ApprovalTimeoutEscalate:
+	case ApprovalTimeoutReject:
+		if p.Timeout > 0 && p.Timeout < minApprovalRejectTimeout {
+			errs = append(errs, fmt.Errorf("approvalTimeout %s: timeout %s must be at least %s to reject automatically", name, p.Timeout, minApprovalRejectTimeout))
+			if fix {
+				p.Action = ApprovalTimeoutEscalate
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("approvalTimeout %s: action %q must be escalate or reject", name, p.Action))
+		if fix {
+			p.Action = ApprovalTimeoutEscalate
+		}
+	}
+	return errs
+}
+
+func (m *Manager) approvalTimeoutLoop(ctx context.Context) {
+	if !m.cfg.ApprovalTimeout.enabled() {
+		return
+	}
+	ticker := time.NewTicker(approvalTimeoutInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.checkApprovalTimeouts(now)
+		}
+	}
+}
+
+// checkApprovalTimeouts handles every approval record left unanswered longer
+// than its policy allows. Each record is handled once: it is escalated, or
+// with the reject action the prompt is rejected through the same path as the
+// notification buttons. Records whose session is no longer waiting on the
+// same prompt are skipped; the monitor replaces or clears them. Rejecting is
+// skipped, and the record escalated instead, unless the prompt's menu is
+// recognized.
+func (m *Manager) checkApprovalTimeouts(now time.Time) {
+	for _, record := range m.recordManager.pendingApprovalTimeouts() {
+		assistantType := ""
+		if record.Assistant != nil {
+			assistantType = record.Assistant.Type
+		}
+		policy := m.cfg.ApprovalTimeout.policyFor(assistantType)
+		if policy.Timeout <= 0 || now.Sub(record.RequestedAt) < policy.Timeout {
+			continue
+		}
+		session, err := m.GetSession(record.SessionID)
+		if err != nil || !session.awaitingApprovalPrompt(record.PromptID) {
+			continue
+		}
+
+		// 先标记再处理，拒绝后审批状态结束，记录会被监控协程清理
+		if !m.recordManager.markApprovalTimedOut(record.ID, now) {
+			continue
+		}
+		rejected := false
+		if policy.Action == ApprovalTimeoutReject {
+			rejected = m.autoRejectApproval(session, record, policy.Timeout)
+		}
+
+		line := fmt.Sprintf("审批已等待 %s 未响应", policy.Timeout)
+		if rejected {
+			line = fmt.Sprintf("审批等待 %s 未响应，已自动拒绝", policy.Timeout)
+		} else {
+			m.logger.Warn("approval prompt unanswered, escalating notification",
+				zap.String("sessionId", record.SessionID),
+				zap.String("recordId", record.ID),
+				zap.String("assistant", assistantType),
+				zap.Duration("timeout", policy.Timeout))
+		}
+		m.recordManager.AddAlert(&AlertRecord{
+			ID:          utils.NewID(),
+			SessionID:   record.SessionID,
+			ProjectID:   record.ProjectID,
+			ProjectName: record.ProjectName,
+			Title:       record.Title,
+			Rule:        approvalTimeoutAlertRule,
+			Line:        line,
+			TriggeredAt: now,
+		})
+	}
+}
+
+// autoRejectApproval answers the prompt behind record with "no". Failures are
+// logged and reported as false so the caller escalates instead.
+func (m *Manager) autoRejectApproval(session *Session, record ApprovalRecord, timeout time.Duration) bool {
+	fields := []zap.Field{
+		zap.String("sessionId", record.SessionID),
+		zap.String("recordId", record.ID),
+		zap.Duration("timeout", timeout),
+	}
+	if err := session.RespondApproval(record.PromptID, false); err != nil {
+		m.logger.Warn("approval timeout could not reject prompt, escalating instead", append(fields, zap.Error(err))...)
+		return false
+	}
+	m.logger.Warn("approval prompt rejected automatically after timeout", fields...)
+	m.recordManager.markApprovalAutoRejected(record.ID)
+
+	if _, err := (&model.ActivityService{}).RecordActivity(context.Background(), &model.RecordActivityRequest{
+		ProjectID: record.ProjectID,
+		Type:      model.ActivityAIApprovalAutoRejected,
+		Actor:     model.ActivityActorSystem,
+		Target:    record.Title,
+		Detail:    fmt.Sprintf("timeout %s", timeout),
+		Time:      time.Now(),
+	}); err != nil {
+		m.logger.Debug("record approval auto reject activity failed", append(fields, zap.Error(err))...)
+	}
+	return true
+}
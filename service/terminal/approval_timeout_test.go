@@ -0,0 +1,330 @@
+package terminal
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils/ai_assistant2"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+func TestCheckApprovalTimeouts(t *testing.T) {
+	mgr := NewManager(Config{ApprovalTimeout: ApprovalTimeoutConfig{
+		Default: ApprovalTimeoutPolicy{Timeout: 5 * time.Minute},
+		Assistants: map[string]ApprovalTimeoutPolicy{
+			string(types.AssistantTypeCodex): {Timeout: 2 * time.Minute, Action: ApprovalTimeoutReject},
+		},
+	}}, zap.NewNop())
+	// 处于审批状态但屏幕上没有可识别的菜单，自动拒绝必须放弃并改为升级通知
+	waitingSession := func(id string, assistant types.AssistantType) *Session {
+		session := newTestSession(t, SessionParams{ID: id, Rows: 12, Cols: 60, ScrollbackLimit: 4096, Logger: zap.NewNop()})
+		mgr.addSession(session)
+		tracker := session.assistantTracker
+		tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+		tracker.Activate(assistant, 12, 60)
+		t.Cleanup(tracker.Deactivate)
+		tracker.Calibrate(types.StateWaitingApproval, "test")
+		return session
+	}
+	codexSession := waitingSession("codex-session", types.AssistantTypeCodex)
+	claudeSession := waitingSession("claude-session", types.AssistantTypeClaudeCode)
+
+	now := time.Now()
+	records := mgr.GetRecordManager()
+	codexInfo := &ai_assistant2.AIAssistantInfo{Type: string(types.AssistantTypeCodex)}
+	records.AddApproval(&ApprovalRecord{
+		ID:          "claude",
+		SessionID:   claudeSession.ID(),
+		Assistant:   &ai_assistant2.AIAssistantInfo{Type: string(types.AssistantTypeClaudeCode)},
+		RequestedAt: now.Add(-3 * time.Minute),
+	})
+	records.AddApproval(&ApprovalRecord{
+		ID:          "codex",
+		SessionID:   codexSession.ID(),
+		Assistant:   codexInfo,
+		RequestedAt: now.Add(-3 * time.Minute),
+	})
+	// 会话已不存在或屏幕上已换成另一条提示的记录不处理
+	records.AddApproval(&ApprovalRecord{
+		ID:          "orphan",
+		SessionID:   "missing",
+		Assistant:   codexInfo,
+		RequestedAt: now.Add(-time.Hour),
+	})
+	records.AddApproval(&ApprovalRecord{
+		ID:          "stale",
+		SessionID:   codexSession.ID(),
+		Assistant:   codexInfo,
+		RequestedAt: now.Add(-time.Hour),
+		PromptID:    "previous-prompt",
+	})
+
+	mgr.checkApprovalTimeouts(now)
+	approvals := make(map[string]ApprovalRecord)
+	for _, record := range records.GetApprovals() {
+		approvals[record.ID] = *record
+	}
+	for _, id := range []string{"claude", "orphan", "stale"} {
+		if approvals[id].TimedOutAt != nil {
+			t.Fatalf("record %s should not time out: %+v", id, approvals[id])
+		}
+	}
+	codex := approvals["codex"]
+	if codex.TimedOutAt == nil || codex.AutoRejected || codex.Dismissed {
+		t.Fatalf("codex record should be escalated without rejecting: %+v", codex)
+	}
+	alerts := records.GetAlerts()
+	if len(alerts) != 1 || alerts[0].Rule != approvalTimeoutAlertRule || alerts[0].SessionID != codexSession.ID() {
+		t.Fatalf("expected one approval timeout alert, got %+v", alerts)
+	}
+
+	// 每条记录只处理一次
+	mgr.checkApprovalTimeouts(now.Add(time.Minute))
+	if alerts := records.GetAlerts(); len(alerts) != 1 {
+		t.Fatalf("record handled twice: %+v", alerts)
+	}
+	mgr.checkApprovalTimeouts(now.Add(3 * time.Minute))
+	if alerts := records.GetAlerts(); len(alerts) != 2 || alerts[1].SessionID != claudeSession.ID() {
+		t.Fatalf("expected claude record to escalate after the default timeout, got %+v", alerts)
+	}
+
+	// 离开审批状态后记录不再处理
+	codexSession.assistantTracker.Calibrate(types.StateWorking, "test")
+	records.AddApproval(&ApprovalRecord{
+		ID:          "answered",
+		SessionID:   codexSession.ID(),
+		Assistant:   codexInfo,
+		RequestedAt: now.Add(-time.Hour),
+	})
+	mgr.checkApprovalTimeouts(now.Add(3 * time.Minute))
+	if alerts := records.GetAlerts(); len(alerts) != 2 {
+		t.Fatalf("record of an answered prompt escalated: %+v", alerts)
+	}
+}
+
+func TestCheckApprovalTimeoutsAutoReject(t *testing.T) {
+	mgr := NewManager(Config{ApprovalTimeout: ApprovalTimeoutConfig{
+		Default: ApprovalTimeoutPolicy{Timeout: 2 * time.Minute, Action: ApprovalTimeoutReject},
+	}}, zap.NewNop())
+	session := newTestSession(t, SessionParams{ID: "codex-session", Rows: 12, Cols: 60, ScrollbackLimit: 4096, Logger: zap.NewNop()})
+	device := &chunkedPty{chunk: 64}
+	session.pty = device
+	session.inputReady = true
+	session.setStatus(SessionStatusRunning)
+	mgr.addSession(session)
+	tracker := session.assistantTracker
+	tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+	tracker.Activate(types.AssistantTypeCodex, 12, 60)
+	defer tracker.Deactivate()
+	tracker.Calibrate(types.StateWaitingApproval, "test")
+	session.appendScrollback([]byte("Would you like to run the following command?\r\n\r\n" +
+		"  $ rm -rf build\r\n\r\n" +
+		"› 1. Yes, proceed (y)\r\n" +
+		"  2. No, and tell Codex what to do differently (esc)\r\n"))
+	promptID := session.ApprovalPromptID()
+	if promptID == "" {
+		t.Fatal("expected the codex prompt to be identified")
+	}
+
+	now := time.Now()
+	records := mgr.GetRecordManager()
+	codexInfo := &ai_assistant2.AIAssistantInfo{Type: string(types.AssistantTypeCodex)}
+	// 屏幕上已换成另一条提示的记录不能被拒绝
+	records.AddApproval(&ApprovalRecord{
+		ID:          "changed",
+		SessionID:   session.ID(),
+		Assistant:   codexInfo,
+		RequestedAt: now.Add(-time.Hour),
+		PromptID:    "previous-prompt",
+	})
+	records.AddApproval(&ApprovalRecord{
+		ID:          "current",
+		SessionID:   session.ID(),
+		Assistant:   codexInfo,
+		RequestedAt: now.Add(-3 * time.Minute),
+		PromptID:    promptID,
+	})
+
+	mgr.checkApprovalTimeouts(now)
+	if got := device.out.String(); got != "\x1b" {
+		t.Fatalf("expected a single esc to reject the prompt, got %q", got)
+	}
+	approvals := make(map[string]ApprovalRecord)
+	for _, record := range records.GetApprovals() {
+		approvals[record.ID] = *record
+	}
+	if current := approvals["current"]; current.TimedOutAt == nil || !current.AutoRejected {
+		t.Fatalf("current record should be rejected: %+v", current)
+	}
+	if changed := approvals["changed"]; changed.TimedOutAt != nil || changed.AutoRejected {
+		t.Fatalf("record of a replaced prompt should be left alone: %+v", changed)
+	}
+	alerts := records.GetAlerts()
+	if len(alerts) != 1 || alerts[0].Line != "审批等待 2m0s 未响应，已自动拒绝" || alerts[0].SessionID != session.ID() {
+		t.Fatalf("expected one auto reject alert, got %+v", alerts)
+	}
+}
+
+func TestManagerApprovalRecordPerPrompt(t *testing.T) {
+	mgr := NewManager(Config{}, zap.NewNop())
+	session := newTestSession(t, SessionParams{ID: "s1", ProjectID: "p1", Rows: 12, Cols: 60, ScrollbackLimit: 4096, Logger: zap.NewNop()})
+	tracker := session.assistantTracker
+	tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+	tracker.Activate(types.AssistantTypeCodex, 12, 60)
+	defer tracker.Deactivate()
+	tracker.Calibrate(types.StateWaitingApproval, "test")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		mgr.monitorAssistantRecords(session)
+	}()
+	// 等待监控协程退出，避免其在之后的测试中写入数据库
+	t.Cleanup(func() {
+		session.broadcast(StreamEvent{Type: StreamEventExit})
+		<-done
+	})
+	deadline := time.Now().Add(time.Second)
+	for session.SubscriberCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("monitor did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	setState := func(state types.State) {
+		session.broadcast(StreamEvent{Type: StreamEventMetadata, Metadata: &SessionMetadata{
+			AIAssistant: &ai_assistant2.AIAssistantInfo{Type: string(types.AssistantTypeCodex), Detected: true, State: string(state)},
+		}})
+	}
+	showPrompt := func(command string) {
+		data := []byte("\x1b[2J\x1b[HWould you like to run the following command?\r\n\r\n" +
+			"  $ " + command + "\r\n\r\n" +
+			"› 1. Yes, proceed (y)\r\n" +
+			"  2. No, and tell Codex what to do differently (esc)\r\n")
+		session.appendScrollback(data)
+		session.broadcast(StreamEvent{Type: StreamEventData, Data: data})
+	}
+	waitApprovals := func(check func([]*ApprovalRecord) bool) []*ApprovalRecord {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			approvals := mgr.GetRecordManager().GetApprovals()
+			if check(approvals) {
+				return approvals
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("unexpected approvals %+v", approvals)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	showPrompt("go test ./...")
+	setState(types.StateWaitingApproval)
+	first := waitApprovals(func(records []*ApprovalRecord) bool {
+		return len(records) == 1 && records[0].PromptID != ""
+	})[0]
+
+	// 审批态下直接出现下一条提示，替换为新记录并重新计时
+	showPrompt("rm -rf build")
+	second := waitApprovals(func(records []*ApprovalRecord) bool {
+		return len(records) == 1 && records[0].ID != first.ID
+	})[0]
+	if second.PromptID == "" || second.PromptID == first.PromptID || !second.RequestedAt.After(first.RequestedAt) {
+		t.Fatalf("expected a new record for the new prompt, got %+v after %+v", second, first)
+	}
+
+	// 直接回到 waiting_input 也要清理审批记录
+	setState(types.StateWaitingInput)
+	waitApprovals(func(records []*ApprovalRecord) bool { return len(records) == 0 })
+}
+
+// 仅跟踪会话不向未指定类型的订阅者投递输出，监控协程仍要收到 data 事件并识别新提示
+func TestManagerApprovalRecordTrackOnly(t *testing.T) {
+	mgr := NewManager(Config{}, zap.NewNop())
+	session := newTestSession(t, SessionParams{ID: "s1", ProjectID: "p1", Rows: 12, Cols: 60, TrackOnly: true, Logger: zap.NewNop()})
+	tracker := session.assistantTracker
+	tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+	tracker.Activate(types.AssistantTypeCodex, 12, 60)
+	defer tracker.Deactivate()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		mgr.monitorAssistantRecords(session)
+	}()
+	t.Cleanup(func() {
+		session.broadcast(StreamEvent{Type: StreamEventExit})
+		<-done
+	})
+	deadline := time.Now().Add(time.Second)
+	for session.SubscriberCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("monitor did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	showPrompt := func(command string) {
+		session.emitOutput([]byte("\x1b[2J\x1b[HWould you like to run the following command?\r\n\r\n" +
+			"  $ " + command + "\r\n\r\n" +
+			"› 1. Yes, proceed (y)\r\n" +
+			"  2. No, and tell Codex what to do differently (esc)\r\n"))
+	}
+	waitApproval := func(check func(*ApprovalRecord) bool) *ApprovalRecord {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			approvals := mgr.GetRecordManager().GetApprovals()
+			if len(approvals) == 1 && check(approvals[0]) {
+				return approvals[0]
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("unexpected approvals %+v", approvals)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+
+	showPrompt("go test ./...")
+	tracker.Calibrate(types.StateWaitingApproval, "test")
+	session.broadcast(StreamEvent{Type: StreamEventMetadata, Metadata: &SessionMetadata{
+		AIAssistant: &ai_assistant2.AIAssistantInfo{Type: string(types.AssistantTypeCodex), Detected: true, State: string(types.StateWaitingApproval)},
+	}})
+	first := waitApproval(func(record *ApprovalRecord) bool { return record.PromptID != "" })
+
+	showPrompt("rm -rf build")
+	second := waitApproval(func(record *ApprovalRecord) bool { return record.ID != first.ID })
+	if second.PromptID == "" || second.PromptID == first.PromptID {
+		t.Fatalf("expected a new record for the new prompt, got %+v after %+v", second, first)
+	}
+}
+
+func TestApprovalTimeoutConfigCheck(t *testing.T) {
+	cfg := Config{ApprovalTimeout: ApprovalTimeoutConfig{
+		Default: ApprovalTimeoutPolicy{Timeout: 10 * time.Second, Action: " Reject "},
+		Assistants: map[string]ApprovalTimeoutPolicy{
+			"codex":       {Timeout: -time.Second, Action: "ignore"},
+			"claude-code": {Timeout: 10 * time.Minute, Action: "reject"},
+		},
+	}}
+	cfg.ApplyDefaults()
+	if errs := cfg.check(true); len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %v", errs)
+	}
+	if cfg.ApprovalTimeout.Default != (ApprovalTimeoutPolicy{Timeout: 10 * time.Second, Action: ApprovalTimeoutEscalate}) {
+		t.Fatalf("short reject timeout should fall back to escalate: %+v", cfg.ApprovalTimeout.Default)
+	}
+	if policy := cfg.ApprovalTimeout.policyFor("codex"); policy != (ApprovalTimeoutPolicy{Action: ApprovalTimeoutEscalate}) {
+		t.Fatalf("invalid codex policy not reset: %+v", policy)
+	}
+	if policy := cfg.ApprovalTimeout.policyFor("claude-code"); policy.Action != ApprovalTimeoutReject {
+		t.Fatalf("valid reject policy changed: %+v", policy)
+	}
+	if policy := cfg.ApprovalTimeout.policyFor("gemini"); policy != cfg.ApprovalTimeout.Default {
+		t.Fatalf("unknown assistant should use the default policy: %+v", policy)
+	}
+}
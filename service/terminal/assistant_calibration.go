@@ -0,0 +1,101 @@
+package terminal
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils/ai_assistant2/types"
+	"code-kanban/utils/process"
+)
+
+const (
+	// assistantIdleCalibrationAfter 检测器显示 working，但 AI 进程没有子进程且终端没有输出
+	// 超过该时长时，认为工作提示已冻结，降级为 waiting_input
+	assistantIdleCalibrationAfter = time.Minute
+	// assistantCalibrationMatch 校准后状态的命中规则，便于与检测器结果区分
+	assistantCalibrationMatch = "process-idle"
+)
+
+type assistantCalibrationAction int
+
+const (
+	calibrationNone assistantCalibrationAction = iota
+	// calibrationDowngrade working 长时间没有进程活动，降级为 waiting_input
+	calibrationDowngrade
+	// calibrationRecheck 进程忙碌但状态为 waiting_input，立即重新检测屏幕
+	calibrationRecheck
+)
+
+// calibrateAssistantState cross-checks the detector state with the process tree
+// of the assistant. Only the shell's first child is treated as the assistant,
+// matching GetForegroundCommand. Assistants that keep helper processes alive
+// (MCP servers) always look busy and are never downgraded, which errs on the
+// side of trusting the detector.
+func (s *Session) calibrateAssistantState(shellPID int32, now time.Time) {
+	tracker := s.assistantTracker
+	if tracker == nil {
+		return
+	}
+	state, changedAt := tracker.State()
+	if state != types.StateWorking && state != types.StateWaitingInput {
+		return
+	}
+
+	// 进程树为 shell、AI 进程及其后代，多于两项说明 AI 进程正在运行子命令
+	assistantBusy := len(process.GetProcessTree(shellPID)) > 2
+	switch s.assistantCalibrationAction(state, changedAt, assistantBusy, now) {
+	case calibrationDowngrade:
+		if tracker.Calibrate(types.StateWaitingInput, assistantCalibrationMatch) {
+			s.stats.assistantCalibrations.Add(1)
+			s.logger.Info("assistant state calibrated by process activity",
+				zap.String("sessionId", s.id),
+				zap.String("from", string(state)),
+				zap.String("to", string(types.StateWaitingInput)))
+		}
+	case calibrationRecheck:
+		s.stats.assistantRechecks.Add(1)
+		if tracker.Recheck() {
+			s.logger.Debug("assistant state rechecked on process activity",
+				zap.String("sessionId", s.id))
+		}
+	}
+}
+
+// assistantCalibrationAction decides how to reconcile state with process
+// activity. Rechecks fire once per busy period so a waiting screen that really
+// is waiting is not redetected every tick.
+func (s *Session) assistantCalibrationAction(state types.State, changedAt time.Time, assistantBusy bool, now time.Time) assistantCalibrationAction {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+
+	if assistantBusy {
+		s.assistantBusyAt = now
+	}
+	if !assistantBusy || state != types.StateWaitingInput {
+		s.assistantRechecked = false
+	}
+
+	switch state {
+	case types.StateWorking:
+		if assistantBusy {
+			return calibrationNone
+		}
+		activeAt := changedAt
+		if s.assistantBusyAt.After(activeAt) {
+			activeAt = s.assistantBusyAt
+		}
+		if last := s.stats.lastOutputAt.Load(); last > activeAt.UnixNano() {
+			activeAt = time.Unix(0, last)
+		}
+		if now.Sub(activeAt) >= assistantIdleCalibrationAfter {
+			return calibrationDowngrade
+		}
+	case types.StateWaitingInput:
+		if assistantBusy && !s.assistantRechecked {
+			s.assistantRechecked = true
+			return calibrationRecheck
+		}
+	}
+	return calibrationNone
+}
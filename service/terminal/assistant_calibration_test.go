@@ -0,0 +1,49 @@
+package terminal
+
+import (
+	"testing"
+	"time"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+func TestAssistantCalibrationAction(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	now := time.Now()
+	changedAt := now.Add(-2 * assistantIdleCalibrationAfter)
+
+	// working 但进程与输出都长时间没有活动：降级
+	if got := session.assistantCalibrationAction(types.StateWorking, changedAt, false, now); got != calibrationDowngrade {
+		t.Fatalf("expected downgrade for idle working assistant, got %d", got)
+	}
+
+	// 最近有输出时保持 working
+	session.stats.lastOutputAt.Store(now.Add(-time.Second).UnixNano())
+	if got := session.assistantCalibrationAction(types.StateWorking, changedAt, false, now); got != calibrationNone {
+		t.Fatalf("expected no action with recent output, got %d", got)
+	}
+	session.stats.lastOutputAt.Store(0)
+
+	// 子进程刚结束，从进程最后忙碌的时刻开始计时
+	if got := session.assistantCalibrationAction(types.StateWorking, changedAt, true, now); got != calibrationNone {
+		t.Fatalf("expected no action while busy, got %d", got)
+	}
+	if got := session.assistantCalibrationAction(types.StateWorking, changedAt, false, now.Add(time.Second)); got != calibrationNone {
+		t.Fatalf("expected no action right after busy, got %d", got)
+	}
+	if got := session.assistantCalibrationAction(types.StateWorking, changedAt, false, now.Add(assistantIdleCalibrationAfter)); got != calibrationDowngrade {
+		t.Fatalf("expected downgrade after idle threshold, got %d", got)
+	}
+
+	// waiting_input 但进程忙碌：每个忙碌周期只复检一次
+	if got := session.assistantCalibrationAction(types.StateWaitingInput, changedAt, true, now); got != calibrationRecheck {
+		t.Fatalf("expected recheck for busy waiting assistant, got %d", got)
+	}
+	if got := session.assistantCalibrationAction(types.StateWaitingInput, changedAt, true, now); got != calibrationNone {
+		t.Fatalf("expected single recheck per busy period, got %d", got)
+	}
+	session.assistantCalibrationAction(types.StateWaitingInput, changedAt, false, now)
+	if got := session.assistantCalibrationAction(types.StateWaitingInput, changedAt, true, now); got != calibrationRecheck {
+		t.Fatalf("expected recheck in a new busy period, got %d", got)
+	}
+}
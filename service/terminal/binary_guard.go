@@ -0,0 +1,99 @@
+package terminal
+
+import (
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	// BinaryOutputOff 不检测二进制输出
+	BinaryOutputOff = ""
+	// BinaryOutputTruncate 丢弃疑似二进制的输出分片，只保留一行提示
+	BinaryOutputTruncate = "truncate"
+	// BinaryOutputReset 在截断的基础上，二进制输出结束后发送软复位序列，
+	// 恢复被漏过的片段打乱的字符集与颜色
+	BinaryOutputReset = "reset"
+
+	// binaryMinChunkBytes 小于该大小的分片不做判断，短的控制序列和提示符不会被误判
+	binaryMinChunkBytes = 256
+	// binaryNonPrintableRatio 非打印字节占比达到该值视为二进制
+	binaryNonPrintableRatio = 0.3
+)
+
+const (
+	binaryTruncatedNotice = "\r\n\x1b[2m[binary output detected, truncated]\x1b[0m\r\n"
+	// terminalSoftReset DECSTR 软复位，并把 G0 字符集切回 ASCII、清除字符属性，不清屏
+	terminalSoftReset = "\x1b[!p\x1b(B\x1b[0m"
+)
+
+// binaryGuard replaces output that looks binary, such as cat on an executable,
+// so control bytes neither garble clients nor fill the scrollback. A run of
+// binary chunks yields a single notice.
+type binaryGuard struct {
+	mode string
+
+	mu sync.Mutex
+	// active 上一个分片已被截断
+	active bool
+}
+
+func newBinaryGuard(mode string) *binaryGuard {
+	if mode != BinaryOutputTruncate && mode != BinaryOutputReset {
+		return nil
+	}
+	return &binaryGuard{mode: mode}
+}
+
+// filter returns what to emit for chunk: chunk itself, the truncation notice
+// for the first chunk of a binary run, or nothing for the rest of the run.
+// truncated is true when the run starts with chunk.
+func (g *binaryGuard) filter(chunk []byte) (out []byte, truncated bool) {
+	if g == nil {
+		return chunk, false
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if looksBinary(chunk) {
+		if g.active {
+			return nil, false
+		}
+		g.active = true
+		return []byte(binaryTruncatedNotice), true
+	}
+	if g.active {
+		g.active = false
+		if g.mode == BinaryOutputReset {
+			return append([]byte(terminalSoftReset), chunk...), false
+		}
+	}
+	return chunk, false
+}
+
+// looksBinary reports chunks in which invalid UTF-8 and control bytes other
+// than the ones terminals use for layout and escape sequences reach
+// binaryNonPrintableRatio.
+func looksBinary(chunk []byte) bool {
+	if len(chunk) < binaryMinChunkBytes {
+		return false
+	}
+	nonPrintable := 0
+	for rest := chunk; len(rest) > 0; {
+		r, size := utf8.DecodeRune(rest)
+		rest = rest[size:]
+		switch {
+		case r == utf8.RuneError:
+			// 按字节计数，GBK 等解码后的替换字符占 3 字节
+			nonPrintable += size
+		case r == 0x7f:
+			nonPrintable++
+		case r < 0x20:
+			switch r {
+			case '\n', '\r', '\t', '\b', '\a', '\x1b':
+			default:
+				nonPrintable++
+			}
+		}
+	}
+	return float64(nonPrintable) >= float64(len(chunk))*binaryNonPrintableRatio
+}
@@ -0,0 +1,60 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func binaryTestChunk(n int) []byte {
+	chunk := make([]byte, n)
+	for i := range chunk {
+		chunk[i] = byte(i * 131)
+	}
+	return chunk
+}
+
+func TestLooksBinary(t *testing.T) {
+	if !looksBinary(binaryTestChunk(1024)) {
+		t.Fatalf("expected random bytes to look binary")
+	}
+	if looksBinary(binaryTestChunk(64)) {
+		t.Fatalf("expected short chunk to be ignored")
+	}
+	text := strings.Repeat("\x1b[32m编译通过\x1b[0m\tok\r\n", 40)
+	if looksBinary([]byte(text)) {
+		t.Fatalf("expected colored CJK text not to look binary")
+	}
+}
+
+func TestBinaryGuardFilter(t *testing.T) {
+	if newBinaryGuard(BinaryOutputOff) != nil {
+		t.Fatalf("expected no guard when disabled")
+	}
+	var disabled *binaryGuard
+	if out, _ := disabled.filter(binaryTestChunk(512)); len(out) != 512 {
+		t.Fatalf("expected nil guard to pass output through")
+	}
+
+	guard := newBinaryGuard(BinaryOutputReset)
+	out, truncated := guard.filter(binaryTestChunk(512))
+	if !truncated || string(out) != binaryTruncatedNotice {
+		t.Fatalf("expected notice for first binary chunk, got %q", out)
+	}
+	if out, truncated := guard.filter(binaryTestChunk(4096)); out != nil || truncated {
+		t.Fatalf("expected rest of binary run to be dropped, got %q", out)
+	}
+	out, _ = guard.filter([]byte("$ "))
+	if !bytes.Equal(out, []byte(terminalSoftReset+"$ ")) {
+		t.Fatalf("expected soft reset before prompt, got %q", out)
+	}
+	if out, _ := guard.filter([]byte("$ ")); string(out) != "$ " {
+		t.Fatalf("expected plain output after reset, got %q", out)
+	}
+
+	truncate := newBinaryGuard(BinaryOutputTruncate)
+	truncate.filter(binaryTestChunk(512))
+	if out, _ := truncate.filter([]byte("$ ")); string(out) != "$ " {
+		t.Fatalf("expected no reset in truncate mode, got %q", out)
+	}
+}
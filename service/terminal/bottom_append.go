@@ -0,0 +1,90 @@
+package terminal
+
+import "bytes"
+
+// IsBottomAppend reports whether chunk only adds output at the cursor: text,
+// line breaks, colors, titles and erasing the rest of the line. Chunks that
+// move the cursor back, clear the screen, switch to the alternate screen or
+// overwrite the line after a lone \r (spinner frames) rewrite the viewport in
+// place, so clients should not treat them as new content at the bottom.
+// Escape sequences cut off at the end of chunk are given the benefit of the
+// doubt.
+func IsBottomAppend(chunk []byte) bool {
+	for i := 0; i < len(chunk); i++ {
+		switch chunk[i] {
+		case '\r':
+			if i+1 < len(chunk) && chunk[i+1] != '\n' {
+				return false
+			}
+		case '\b':
+			return false
+		case 0x1b:
+			next, ok := appendOnlyEscape(chunk, i)
+			if !ok {
+				return false
+			}
+			i = next
+		}
+	}
+	return true
+}
+
+// appendOnlyEscape inspects the escape sequence at chunk[start] and returns the
+// index of its last byte and whether it leaves earlier output untouched.
+func appendOnlyEscape(chunk []byte, start int) (int, bool) {
+	if start+1 >= len(chunk) {
+		return len(chunk) - 1, true
+	}
+	switch chunk[start+1] {
+	case '[':
+		for i := start + 2; i < len(chunk); i++ {
+			b := chunk[i]
+			if b < 0x40 || b > 0x7e {
+				continue
+			}
+			params := chunk[start+2 : i]
+			switch b {
+			case 'm', 'C':
+				return i, true
+			case 'K':
+				// 只允许擦除光标右侧
+				return i, len(params) == 0 || string(params) == "0"
+			case 'h', 'l':
+				// 光标显示、括号粘贴等模式开关无害，切换备用屏幕会重绘整个视口
+				return i, !isAltScreenMode(params)
+			default:
+				return i, false
+			}
+		}
+		return len(chunk) - 1, true
+	case ']':
+		// OSC 以 BEL 或 ST 结束
+		for i := start + 2; i < len(chunk); i++ {
+			if chunk[i] == 0x07 {
+				return i, true
+			}
+			if chunk[i] == 0x1b && i+1 < len(chunk) && chunk[i+1] == '\\' {
+				return i + 1, true
+			}
+		}
+		return len(chunk) - 1, true
+	case '(', ')':
+		// 字符集指定
+		return min(start+2, len(chunk)-1), true
+	default:
+		return start + 1, false
+	}
+}
+
+func isAltScreenMode(params []byte) bool {
+	if len(params) == 0 || params[0] != '?' {
+		return false
+	}
+	for _, mode := range bytes.Split(params[1:], []byte{';'}) {
+		switch string(mode) {
+		case "47", "1047", "1049":
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,35 @@
+package terminal
+
+import "testing"
+
+func TestIsBottomAppend(t *testing.T) {
+	appends := []string{
+		"build ok\r\n",
+		"\x1b[32mPASS\x1b[0m\r\n\x1b[K$ ",
+		"\x1b]0;title\x07line\n",
+		"\x1b[?25l\x1b[?2004hdone\r\n",
+		"中文输出\r\n",
+		"partial line\r",
+		"cut off \x1b[3",
+	}
+	for _, chunk := range appends {
+		if !IsBottomAppend([]byte(chunk)) {
+			t.Fatalf("expected %q to append at the bottom", chunk)
+		}
+	}
+
+	rewrites := []string{
+		"\r⠋ Loading",
+		"\x1b[2K\x1b[1A\x1b[2K\rWorking",
+		"\x1b[H\x1b[2J",
+		"\x1b[?1049h",
+		"\x1b[1K",
+		"\x1b7saved\x1b8",
+		"abc\b\bx",
+	}
+	for _, chunk := range rewrites {
+		if IsBottomAppend([]byte(chunk)) {
+			t.Fatalf("expected %q to rewrite the viewport", chunk)
+		}
+	}
+}
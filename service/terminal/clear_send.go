@@ -0,0 +1,47 @@
+package terminal
+
+import (
+	"bytes"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// clearScreenKey 清屏用的 Ctrl+L，shell 与常见 AI CLI 收到后都会清屏重绘并保留当前输入
+var clearScreenKey = []byte{0x0c}
+
+// SetClearBeforeSend toggles clearing the screen before each submitted input
+// sent through Send.
+func (s *Session) SetClearBeforeSend(enabled bool) {
+	s.clearBeforeSend.Store(enabled)
+}
+
+// ClearBeforeSend reports whether Send clears the screen before submitted input.
+func (s *Session) ClearBeforeSend() bool {
+	return s.clearBeforeSend.Load()
+}
+
+// Send writes interactive input like Write. When the input submits a line and
+// clear is set, or the session clears before every send, Ctrl+L is written
+// first in the same locked write so the assistant detector starts from a clean
+// screen. Input buffered before the session is ready is sent without clearing.
+func (s *Session) Send(p []byte, clear bool) (int, error) {
+	return s.writeInteractive(p, clear || s.clearBeforeSend.Load())
+}
+
+// isSubmittedInput reports whether the input contains Enter, i.e. it submits a
+// line or prompt rather than just typing into it.
+func isSubmittedInput(p []byte) bool {
+	return bytes.IndexByte(p, '\r') >= 0
+}
+
+// resetAssistantStability lets the tracker forget the previous turn when a new
+// prompt is submitted while the assistant is waiting for input.
+func (s *Session) resetAssistantStability() {
+	tracker := s.assistantTracker
+	if tracker == nil {
+		return
+	}
+	if state, _ := tracker.State(); state == types.StateWaitingInput {
+		tracker.ResetStability()
+	}
+}
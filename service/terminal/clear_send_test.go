@@ -0,0 +1,34 @@
+package terminal
+
+import "testing"
+
+func TestSessionSendClearsBeforeSubmit(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	device := &chunkedPty{chunk: 64}
+	session.pty = device
+	session.inputReady = true
+	session.setStatus(SessionStatusRunning)
+
+	// 只有提交（包含回车）的输入才清屏
+	if _, err := session.Send([]byte("fix it"), true); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if _, err := session.Send([]byte("\r"), true); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if got := device.out.String(); got != "fix it\x0c\r" {
+		t.Fatalf("unexpected written input %q", got)
+	}
+
+	device.out.Reset()
+	if _, err := session.Send([]byte("ls\r"), false); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	session.SetClearBeforeSend(true)
+	if _, err := session.Send([]byte("ls\r"), false); err != nil {
+		t.Fatalf("Send failed: %v", err)
+	}
+	if got := device.out.String(); got != "ls\r\x0cls\r" {
+		t.Fatalf("unexpected written input %q", got)
+	}
+}
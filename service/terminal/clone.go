@@ -0,0 +1,63 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+)
+
+// cloneTitleSuffix 克隆时追加的编号后缀
+var cloneTitleSuffix = regexp.MustCompile(` \(\d+\)$`)
+
+// CloneSession starts a new session from the creation parameters of the source
+// session: same worktree, working directory, shell, environment, terminal
+// settings and restart policy, at the current size of the source. Scrollback,
+// process state and the task link are not copied. The clone is titled after
+// the source with a numeric suffix and counts against the session limits like
+// any other session.
+func (m *Manager) CloneSession(sourceID string) (*Session, error) {
+	source, err := m.GetSession(sourceID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := source.createParams
+	params.ID = ""
+	params.IdempotencyKey = ""
+	params.TaskID = ""
+	params.Env = slices.Clone(params.Env)
+	params.TermEnv = maps.Clone(params.TermEnv)
+	source.mu.RLock()
+	params.Rows, params.Cols = source.rows, source.cols
+	source.mu.RUnlock()
+	params.Title = m.cloneTitle(source.ProjectID(), source.Title())
+	params.Source = SessionSourceClone
+
+	return m.createSession(context.Background(), params)
+}
+
+// cloneTitle returns title with the smallest " (n)" suffix, n >= 2, not used by
+// another session of the project. A suffix already on title is replaced, so
+// cloning a clone keeps counting instead of stacking suffixes.
+func (m *Manager) cloneTitle(projectID, title string) string {
+	base := cloneTitleSuffix.ReplaceAllString(strings.TrimSpace(title), "")
+
+	used := make(map[string]struct{})
+	m.sessions.Range(func(_ string, session *Session) bool {
+		if session.ProjectID() == projectID {
+			used[session.Title()] = struct{}{}
+		}
+		return true
+	})
+	for n := 2; ; n++ {
+		suffix := fmt.Sprintf(" (%d)", n)
+		candidate := truncateString(base, maxSessionTitleLength-utf8.RuneCountInString(suffix)) + suffix
+		if _, ok := used[candidate]; !ok {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package terminal
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+)
+
+func TestManagerCloneSession(t *testing.T) {
+	mgr := NewManager(Config{MaxSessionsPerProject: 3}, zap.NewNop())
+	defer mgr.CloseWorktreeSessions("wt-1")
+
+	source, err := mgr.CreateSession(context.Background(), CreateSessionParams{
+		ProjectID:      "p1",
+		WorktreeID:     "wt-1",
+		WorkingDir:     t.TempDir(),
+		Title:          "dev",
+		Env:            []string{"FOO=bar"},
+		TaskID:         "task-1",
+		IdempotencyKey: "req-1",
+		Source:         SessionSourceManual,
+	})
+	if err != nil {
+		t.Skipf("pty unavailable: %v", err)
+	}
+
+	clone, err := mgr.CloneSession(source.ID())
+	if err != nil {
+		t.Fatalf("CloneSession failed: %v", err)
+	}
+	if clone.ID() == source.ID() || clone.Title() != "dev (2)" {
+		t.Fatalf("unexpected clone %s titled %q", clone.ID(), clone.Title())
+	}
+	if clone.WorkingDir() != source.WorkingDir() || clone.WorktreeID() != "wt-1" || clone.TaskID() != "" {
+		t.Fatalf("unexpected clone snapshot %+v", clone.Snapshot())
+	}
+	if len(clone.env) == 0 || clone.env[0] != "FOO=bar" {
+		t.Fatalf("expected env to be copied, got %v", clone.env)
+	}
+	if source.Source() != SessionSourceManual || clone.Source() != SessionSourceClone {
+		t.Fatalf("unexpected sources %q / %q", source.Source(), clone.Source())
+	}
+
+	// 克隆的克隆继续编号，而不是叠加后缀
+	second, err := mgr.CloneSession(clone.ID())
+	if err != nil {
+		t.Fatalf("CloneSession of clone failed: %v", err)
+	}
+	if second.Title() != "dev (3)" {
+		t.Fatalf("expected numbered title, got %q", second.Title())
+	}
+
+	if _, err := mgr.CloneSession(source.ID()); !errors.Is(err, ErrSessionLimitReached) {
+		t.Fatalf("expected session limit to apply, got %v", err)
+	}
+	if _, err := mgr.CloneSession("missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+}
+
+func TestManagerCloneSessionArchivedProject(t *testing.T) {
+	if err := model.InitWithDSN("file:"+t.Name()+"?mode=memory&cache=shared", 0, true); err != nil {
+		t.Fatalf("InitWithDSN: %v", err)
+	}
+	defer model.DBClose()
+	repoPath := t.TempDir()
+	if output, err := exec.Command("git", "init", "-b", "main", repoPath).CombinedOutput(); err != nil {
+		t.Skipf("git unavailable: %v\n%s", err, output)
+	}
+	projects := &model.ProjectService{}
+	ctx := context.Background()
+	project, err := projects.CreateProject(ctx, model.CreateProjectParams{Name: "Archived", Path: repoPath})
+	if err != nil {
+		t.Fatalf("CreateProject returned error: %v", err)
+	}
+
+	mgr := NewManager(Config{}, zap.NewNop())
+	defer mgr.CloseWorktreeSessions("wt-1")
+	source, err := mgr.CreateSession(ctx, CreateSessionParams{ProjectID: project.Id, WorktreeID: "wt-1", WorkingDir: t.TempDir()})
+	if err != nil {
+		t.Skipf("pty unavailable: %v", err)
+	}
+
+	// 归档后克隆与新建都被拒绝
+	if _, err := projects.SetArchived(ctx, project.Id, true); err != nil {
+		t.Fatalf("SetArchived returned error: %v", err)
+	}
+	if _, err := mgr.CloneSession(source.ID()); !errors.Is(err, model.ErrProjectArchived) {
+		t.Fatalf("expected ErrProjectArchived for clone, got %v", err)
+	}
+	if _, err := mgr.CreateSession(ctx, CreateSessionParams{ProjectID: project.Id, WorktreeID: "wt-1", WorkingDir: t.TempDir()}); !errors.Is(err, model.ErrProjectArchived) {
+		t.Fatalf("expected ErrProjectArchived for create, got %v", err)
+	}
+}
@@ -0,0 +1,242 @@
+package terminal
+
+import (
+	"encoding/json"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultCodeSnippetLimit = 20
+	maxCodeSnippetLimit     = 200
+	// codeSnippetMinLines 未加围栏的代码块至少包含的非空行数，单行容易误判
+	codeSnippetMinLines = 2
+)
+
+// CodeSnippet is a code block found in the scrollback lines. StartLine and
+// EndLine are logical line indexes as used by ScrollbackLines, EndLine
+// exclusive.
+type CodeSnippet struct {
+	Language string `json:"language,omitempty"`
+	Code     string `json:"code"`
+	// Markdown 代码块的 Markdown 形式，可直接复制
+	Markdown  string `json:"markdown"`
+	StartLine int    `json:"startLine"`
+	EndLine   int    `json:"endLine"`
+	// Fenced 为 true 表示输出中本来就有 ``` 围栏，语言取自围栏标注
+	Fenced bool `json:"fenced"`
+}
+
+var (
+	codeFencePattern = regexp.MustCompile("^\\s*(```+|~~~+)\\s*([\\w+#.-]*)")
+	// codeKeywordPattern 常见语言语句开头的关键字，以及 shell 提示符
+	codeKeywordPattern = regexp.MustCompile(`^((package|import|from|func|def|class|return|if|elif|else|for|while|switch|case|const|let|var|fn|pub|use|impl|struct|enum|interface|type|export|async|await|try|catch|public|private|protected|static|SELECT|INSERT|UPDATE|DELETE|CREATE|ALTER|npm|pnpm|yarn|go|git|pip|cargo|make|docker|kubectl|curl|cd|echo|sudo)\b|#include|#!|//|/\*|\*/|\$ )`)
+	// codeTrailingPattern 以这些符号结尾的行多半是代码
+	codeTrailingPattern = regexp.MustCompile(`[{}\[\]();,]$|\)\s*:$|=>$|\\$`)
+	codeOperatorPattern = regexp.MustCompile(`:=|==|!=|=>|->|::|&&|\|\||\w\(.*\)|^\s*[})\]]|^\s*<\/?\w+[ >/]`)
+)
+
+// codeLanguageRule 语言特征，命中越多越可能是该语言；按顺序排列，得分相同时靠前的优先
+type codeLanguageRule struct {
+	language string
+	patterns []*regexp.Regexp
+}
+
+var codeLanguageRules = []codeLanguageRule{
+	{"go", compilePatterns(`(?m)^package \w+`, `(?m)^func `, `:= `, `\bfmt\.`, `\berr != nil\b`, `(?m)^import \(`)},
+	{"rust", compilePatterns(`\bfn \w+`, `\blet mut\b`, `(?m)^use \w+::`, `\bimpl\b`, `println!\(`, `-> \w+ \{`)},
+	{"python", compilePatterns(`(?m)^\s*def \w+\(.*\):$`, `(?m)^\s*class \w+.*:$`, `(?m)^(from \w+ )?import \w+$`, `\bself\.`, `\bprint\(`, `(?m)^\s*(elif|except|with) .*:$`)},
+	{"typescript", compilePatterns(`: (string|number|boolean|any)\b`, `(?m)^(export )?interface \w+`, `(?m)^(export )?type \w+ =`, `\bas const\b`)},
+	{"javascript", compilePatterns(`\b(const|let|var) \w+ =`, `=>`, `\bfunction\b`, `console\.log`, `\brequire\(`, `(?m)^export (default|const|function)`)},
+	{"sql", compilePatterns(`(?im)^\s*(select|insert into|update|delete from|create table|alter table)\b`, `(?i)\b(from|where|join|group by|order by)\b`)},
+	{"html", compilePatterns(`(?m)^\s*<(!DOCTYPE|html|head|body|div|span|p|a|ul|li|script|style)[ >]`, `</\w+>`)},
+	{"bash", compilePatterns(`(?m)^#!.*\bsh\b`, `(?m)^\$ `, `(?m)^(npm|pnpm|yarn|go|git|pip|cargo|make|docker|kubectl|curl|cd|ls|echo|export|sudo|mkdir|rm|cp|mv) `, `\s&&\s`, `\|\s*(grep|xargs|sed|awk)\b`)},
+	{"yaml", compilePatterns(`(?m)^\s*[\w.-]+:\s*$`, `(?m)^\s*[\w.-]+: [^;{}()]+$`, `(?m)^\s*- \w+`)},
+}
+
+func compilePatterns(patterns ...string) []*regexp.Regexp {
+	compiled := make([]*regexp.Regexp, len(patterns))
+	for i, pattern := range patterns {
+		compiled[i] = regexp.MustCompile(pattern)
+	}
+	return compiled
+}
+
+// ExtractCode finds code blocks in the scrollback lines and returns the
+// latest limit of them in output order. Fenced blocks are taken as is; other
+// blocks are runs of lines that look like code, guessed from keywords,
+// punctuation and indentation, so prose may occasionally slip in or short
+// snippets be missed. limit <= 0 uses a default.
+func (s *Session) ExtractCode(limit int) []CodeSnippet {
+	if limit <= 0 {
+		limit = defaultCodeSnippetLimit
+	}
+	limit = min(limit, maxCodeSnippetLimit)
+
+	lines, _ := s.scrollbackTextLines()
+
+	snippets := extractCodeSnippets(lines)
+	if len(snippets) > limit {
+		snippets = snippets[len(snippets)-limit:]
+	}
+	return snippets
+}
+
+func extractCodeSnippets(lines []string) []CodeSnippet {
+	snippets := make([]CodeSnippet, 0)
+	for i := 0; i < len(lines); {
+		if match := codeFencePattern.FindStringSubmatch(lines[i]); match != nil {
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), match[1]) {
+				end++
+			}
+			language := strings.ToLower(match[2])
+			code := dedentCode(lines[i+1 : end])
+			if language == "" {
+				language = guessCodeLanguage(code)
+			}
+			if strings.TrimSpace(code) != "" {
+				snippets = append(snippets, newCodeSnippet(code, language, i, min(end+1, len(lines)), true))
+			}
+			i = end + 1
+			continue
+		}
+		if !isCodeLine(lines[i]) {
+			i++
+			continue
+		}
+		end, codeLines := scanCodeRun(lines, i)
+		if codeLines >= codeSnippetMinLines {
+			code := dedentCode(lines[i:end])
+			snippets = append(snippets, newCodeSnippet(code, guessCodeLanguage(code), i, end, false))
+		}
+		i = max(end, i+1)
+	}
+	return snippets
+}
+
+// scanCodeRun extends a code block from start. Lines belong to the block while
+// they look like code or are indented deeper than its first line; a single
+// blank line is allowed inside. It returns the exclusive end and the number of
+// non-blank lines.
+func scanCodeRun(lines []string, start int) (int, int) {
+	baseIndent := indentWidth(lines[start])
+	end, count := start, 0
+	for i := start; i < len(lines); i++ {
+		line := lines[i]
+		if strings.TrimSpace(line) == "" {
+			if i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" && belongsToCode(lines[i+1], baseIndent) {
+				continue
+			}
+			break
+		}
+		if !belongsToCode(line, baseIndent) {
+			break
+		}
+		end, count = i+1, count+1
+	}
+	return end, count
+}
+
+func belongsToCode(line string, baseIndent int) bool {
+	if isUIChromeLine(line) || codeFencePattern.MatchString(line) {
+		return false
+	}
+	return isCodeLine(line) || (indentWidth(line) > baseIndent && !isProseLine(line))
+}
+
+// isCodeLine reports whether a line looks like source code or a shell command.
+func isCodeLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if trimmed == "" || isUIChromeLine(line) || isProseLine(line) {
+		return false
+	}
+	return codeKeywordPattern.MatchString(trimmed) || codeTrailingPattern.MatchString(trimmed) || codeOperatorPattern.MatchString(trimmed)
+}
+
+// isProseLine 像自然语言句子的行：较多单词、以句号结尾且没有代码符号
+func isProseLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	if strings.ContainsAny(trimmed, "{};=") {
+		return false
+	}
+	if strings.HasSuffix(trimmed, "。") || strings.HasSuffix(trimmed, "：") {
+		return true
+	}
+	words := len(strings.Fields(trimmed))
+	return words >= 6 && (strings.HasSuffix(trimmed, ".") || strings.HasSuffix(trimmed, ":") || !strings.ContainsAny(trimmed, "()[]<>"))
+}
+
+// isUIChromeLine AI 助手界面元素：Claude Code 的 ⏺/⎿ 标记、Codex 的 •/› 前缀与边框
+func isUIChromeLine(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	for _, prefix := range []string{"⏺", "⎿", "• ", "◦ ", "› ", "> ", "✻", "│", "╭", "╰", "─"} {
+		if strings.HasPrefix(trimmed, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func indentWidth(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " \t"))
+}
+
+// dedentCode 去掉代码块共同的缩进与行尾空白
+func dedentCode(lines []string) string {
+	common := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if width := indentWidth(line); common < 0 || width < common {
+			common = width
+		}
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if len(line) >= common && common > 0 {
+			line = line[common:]
+		}
+		out[i] = line
+	}
+	return strings.Trim(strings.Join(out, "\n"), "\n")
+}
+
+// guessCodeLanguage picks the language whose features match code most often,
+// "" when nothing matches. JSON is recognized by parsing.
+func guessCodeLanguage(code string) string {
+	trimmed := strings.TrimSpace(code)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "json"
+	}
+	best, bestScore := "", 0
+	for _, rule := range codeLanguageRules {
+		score := 0
+		for _, pattern := range rule.patterns {
+			if pattern.MatchString(code) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = rule.language, score
+		}
+	}
+	return best
+}
+
+func newCodeSnippet(code, language string, start, end int, fenced bool) CodeSnippet {
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return CodeSnippet{
+		Language:  language,
+		Code:      code,
+		Markdown:  fence + language + "\n" + code + "\n" + fence,
+		StartLine: start,
+		EndLine:   end,
+		Fenced:    fenced,
+	}
+}
@@ -0,0 +1,85 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+func TestExtractCodeSnippets(t *testing.T) {
+	lines := []string{
+		"⏺ Here is the helper you asked for:",
+		"",
+		"  func add(a, b int) int {",
+		"      return a + b",
+		"  }",
+		"",
+		"Run it with the following command, it should print the result.",
+		"",
+		"  $ go run ./cmd/demo",
+		"  $ go test ./...",
+		"",
+		"```python",
+		"def greet(name):",
+		"    print(name)",
+		"```",
+		"",
+		"The configuration file is now valid and can be committed.",
+		"  {",
+		`    "name": "demo",`,
+		`    "private": true`,
+		"  }",
+	}
+	snippets := extractCodeSnippets(lines)
+	if len(snippets) != 4 {
+		t.Fatalf("expected 4 snippets, got %d: %+v", len(snippets), snippets)
+	}
+
+	goSnippet := snippets[0]
+	if goSnippet.Language != "go" || goSnippet.Fenced || goSnippet.StartLine != 2 || goSnippet.EndLine != 5 {
+		t.Fatalf("unexpected go snippet: %+v", goSnippet)
+	}
+	if goSnippet.Code != "func add(a, b int) int {\n    return a + b\n}" {
+		t.Fatalf("expected dedented code, got %q", goSnippet.Code)
+	}
+	if goSnippet.Markdown != "```go\n"+goSnippet.Code+"\n```" {
+		t.Fatalf("unexpected markdown %q", goSnippet.Markdown)
+	}
+	if snippets[1].Language != "bash" || !strings.HasPrefix(snippets[1].Code, "$ go run") {
+		t.Fatalf("unexpected shell snippet: %+v", snippets[1])
+	}
+	if snippets[2].Language != "python" || !snippets[2].Fenced || snippets[2].Code != "def greet(name):\n    print(name)" {
+		t.Fatalf("unexpected fenced snippet: %+v", snippets[2])
+	}
+	if snippets[3].Language != "json" {
+		t.Fatalf("expected json snippet, got %+v", snippets[3])
+	}
+}
+
+func TestExtractCodeSkipsProse(t *testing.T) {
+	lines := []string{
+		"I updated the parser so that it handles empty input correctly.",
+		"If you want, I can also add more tests for the edge cases.",
+		"x := 1",
+	}
+	if snippets := extractCodeSnippets(lines); len(snippets) != 0 {
+		t.Fatalf("expected no snippets, got %+v", snippets)
+	}
+}
+
+func TestCodeSnippetMarkdownFence(t *testing.T) {
+	snippet := newCodeSnippet("echo ```", "bash", 0, 1, false)
+	if snippet.Markdown != "````bash\necho ```\n````" {
+		t.Fatalf("unexpected markdown %q", snippet.Markdown)
+	}
+}
+
+func TestSessionExtractCode(t *testing.T) {
+	session := newTestSession(t, SessionParams{ScrollbackLimit: 1 << 16, Rows: 24, Cols: 80, Logger: zap.NewNop()})
+	session.appendScrollback([]byte("done:\r\n\r\n```js\r\nconst a = 1\r\n```\r\n\r\n```sh\r\nls\r\n```\r\n"))
+	snippets := session.ExtractCode(1)
+	if len(snippets) != 1 || snippets[0].Language != "sh" || snippets[0].Code != "ls" {
+		t.Fatalf("expected latest snippet only, got %+v", snippets)
+	}
+}
@@ -1,8 +1,13 @@
 package terminal
 
 import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
 	"code-kanban/utils/ai_assistant2"
 )
@@ -22,6 +27,27 @@ type CompletionRecord struct {
 	LastUserInput string `json:"lastUserInput,omitempty"`
 	// Dismissed 标记用户是否已主动关闭此通知
 	Dismissed bool `json:"dismissed"`
+	// HasConflicts 标记关联 worktree 中存在未解决的冲突
+	HasConflicts        bool `json:"hasConflicts"`
+	UnresolvedConflicts int  `json:"unresolvedConflicts,omitempty"`
+	// Todos 完成时 AI 助手任务清单的快照
+	Todos []ai_assistant2.TodoItem `json:"todos,omitempty"`
+	// Duration 助手显示的本次耗时（秒），无法解析时为空
+	Duration int64 `json:"duration,omitempty"`
+	// DisplayText 按通知模板生成的展示文案
+	DisplayText string `json:"displayText,omitempty"`
+	// TokenUsage 完成时会话累计的 token 用量与估算成本
+	TokenUsage *TokenUsage `json:"tokenUsage,omitempty"`
+	// Interrupted 本轮被用户打断（ESC）而非正常完成，State 仍为 completed
+	Interrupted bool `json:"interrupted,omitempty"`
+	// TurnCount 完成时助手本次运行已完成的对话轮次（含本轮），被打断的轮次计入 InterruptedTurns
+	TurnCount        int `json:"turnCount,omitempty"`
+	InterruptedTurns int `json:"interruptedTurns,omitempty"`
+	// Note/Tags 用户为记录添加的备注与标签，关闭记录后保留
+	Note string   `json:"note,omitempty"`
+	Tags []string `json:"tags,omitempty"`
+	// noteID 备注落库使用的 ID，为最初添加备注的记录 ID，备注随卡片转移到新记录时沿用
+	noteID string
 }
 
 // ApprovalRecord 代表一个等待审批的记录
@@ -35,8 +61,67 @@ type ApprovalRecord struct {
 	RequestedAt time.Time                      `json:"requestedAt"`
 	// Dismissed 标记用户是否已主动关闭此通知
 	Dismissed bool `json:"dismissed"`
+	// DisplayText 按通知模板生成的展示文案
+	DisplayText string `json:"displayText,omitempty"`
+	// TimedOutAt 超过审批超时策略仍未响应的时间，AutoRejected 表示已按策略自动拒绝
+	TimedOutAt   *time.Time `json:"timedOutAt,omitempty"`
+	AutoRejected bool       `json:"autoRejected,omitempty"`
+	// PromptID 创建记录时屏幕上审批提示的标识，作答前据此确认提示未被替换
+	PromptID string `json:"promptId,omitempty"`
 }
 
+// AlertRecord 代表一次输出命中告警规则的通知
+type AlertRecord struct {
+	ID          string `json:"id"`
+	SessionID   string `json:"sessionId"`
+	ProjectID   string `json:"projectId"`
+	ProjectName string `json:"projectName,omitempty"`
+	Title       string `json:"title"`
+	// Rule 命中的规则名称，Line 为命中的行，Context 为命中行之前的几行
+	Rule        string    `json:"rule"`
+	Line        string    `json:"line"`
+	Context     []string  `json:"context,omitempty"`
+	TriggeredAt time.Time `json:"triggeredAt"`
+	// Dismissed 标记用户是否已主动关闭此通知
+	Dismissed bool `json:"dismissed"`
+}
+
+// RecordSummaryGroup 按项目和助手类型聚合的未关闭记录数量
+type RecordSummaryGroup struct {
+	ProjectID     string `json:"projectId"`
+	ProjectName   string `json:"projectName,omitempty"`
+	AssistantType string `json:"assistantType"`
+	Completed     int    `json:"completed"`
+	Approvals     int    `json:"approvals"`
+	Working       int    `json:"working"`
+}
+
+// RecordSummary 汇总所有未关闭的完成/审批记录
+type RecordSummary struct {
+	Groups                []RecordSummaryGroup `json:"groups"`
+	Completed             int                  `json:"completed"`
+	Approvals             int                  `json:"approvals"`
+	Working               int                  `json:"working"`
+	ProjectsWithApprovals int                  `json:"projectsWithApprovals"`
+	// Alerts 未关闭的告警记录数，告警与助手类型无关，不计入分组
+	Alerts int `json:"alerts"`
+}
+
+// ProjectRecordCounts 单个项目未关闭的记录数量
+type ProjectRecordCounts struct {
+	Completed int `json:"completed"`
+	Approvals int `json:"approvals"`
+	Working   int `json:"working"`
+	Alerts    int `json:"alerts"`
+}
+
+// 备注与标签的长度限制
+const (
+	recordNoteMaxRunes = 1000
+	recordTagMaxRunes  = 32
+	recordMaxTags      = 10
+)
+
 // RecordManager 管理完成记录和审批记录
 type RecordManager struct {
 	mu sync.RWMutex
@@ -46,21 +131,79 @@ type RecordManager struct {
 	approvals map[string]*ApprovalRecord
 	// sessionCompletions 按 sessionId 索引，用于快速查找和清理
 	sessionCompletions map[string][]string // sessionId -> []recordId
+	// activeCompletions 每个 session 唯一未关闭的完成记录，AddCompletion 据此原地更新
+	activeCompletions map[string]string // sessionId -> recordId
 	// sessionApprovals 按 sessionId 索引
 	sessionApprovals map[string][]string // sessionId -> []recordId
+	// alerts 存储告警记录，同一会话每条规则一条，会话关闭时清理
+	alerts        map[string]*AlertRecord
+	sessionAlerts map[string][]string // sessionId -> []recordId
+	// completionTemplate / approvalTemplate 生成记录 DisplayText 的模板
+	completionTemplate *recordTemplate
+	approvalTemplate   *recordTemplate
+	// interruptedTemplate 被打断记录的文案，不随配置变化
+	interruptedTemplate *recordTemplate
+	// sessionNotes 每个 session 未关闭卡片的备注，记录被清理后新记录沿用，关闭卡片或会话时删除
+	sessionNotes map[string]recordNote
+}
+
+// recordNote 是随卡片转移的备注与标签
+type recordNote struct {
+	id   string
+	note string
+	tags []string
 }
 
 // NewRecordManager 创建新的记录管理器
 func NewRecordManager() *RecordManager {
+	completionTemplate, _ := parseRecordTemplate(defaultCompletionTemplate)
+	approvalTemplate, _ := parseRecordTemplate(defaultApprovalTemplate)
+	interruptedTemplate, _ := parseRecordTemplate(defaultInterruptedTemplate)
 	return &RecordManager{
-		completions:        make(map[string]*CompletionRecord),
-		approvals:          make(map[string]*ApprovalRecord),
-		sessionCompletions: make(map[string][]string),
-		sessionApprovals:   make(map[string][]string),
+		completions:         make(map[string]*CompletionRecord),
+		approvals:           make(map[string]*ApprovalRecord),
+		sessionCompletions:  make(map[string][]string),
+		activeCompletions:   make(map[string]string),
+		sessionApprovals:    make(map[string][]string),
+		alerts:              make(map[string]*AlertRecord),
+		sessionAlerts:       make(map[string][]string),
+		sessionNotes:        make(map[string]recordNote),
+		completionTemplate:  completionTemplate,
+		approvalTemplate:    approvalTemplate,
+		interruptedTemplate: interruptedTemplate,
 	}
 }
 
-// AddCompletion 添加一个完成记录
+// SetTemplates 设置完成/审批记录的展示文案模板，空模板使用默认格式；
+// 非法模板同样回退到默认格式并返回解析错误
+func (rm *RecordManager) SetTemplates(completion, approval string) error {
+	completionTemplate, completionErr := resolveRecordTemplate(completion, defaultCompletionTemplate)
+	approvalTemplate, approvalErr := resolveRecordTemplate(approval, defaultApprovalTemplate)
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	rm.completionTemplate = completionTemplate
+	rm.approvalTemplate = approvalTemplate
+	return errors.Join(completionErr, approvalErr)
+}
+
+func (rm *RecordManager) renderCompletionLocked(record *CompletionRecord) {
+	tmpl := rm.completionTemplate
+	if record.Interrupted {
+		tmpl = rm.interruptedTemplate
+	}
+	record.DisplayText = tmpl.render(newRecordTemplateVars(
+		record.ProjectID, record.ProjectName, record.Title, record.Assistant, record.LastUserInput))
+}
+
+func (rm *RecordManager) renderApprovalLocked(record *ApprovalRecord) {
+	record.DisplayText = rm.approvalTemplate.render(newRecordTemplateVars(
+		record.ProjectID, record.ProjectName, record.Title, record.Assistant, ""))
+}
+
+// AddCompletion 添加一个完成记录。同一 session 已有未关闭的记录时原地更新该记录：
+// 沿用其 ID 与用户填写的备注标签，保证每个 session 最多一条活跃记录；
+// 否则先清理该 session 已关闭的旧记录再新增，未关闭就被清理的卡片上的备注转到新记录
 func (rm *RecordManager) AddCompletion(record *CompletionRecord) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
@@ -68,8 +211,32 @@ func (rm *RecordManager) AddCompletion(record *CompletionRecord) {
 	if record.State == "" {
 		record.State = "completed"
 	}
+	if existing, ok := rm.completions[rm.activeCompletions[record.SessionID]]; ok && !existing.Dismissed {
+		record.ID = existing.ID
+		if record.Note == "" {
+			record.Note = existing.Note
+		}
+		if record.Tags == nil {
+			record.Tags = existing.Tags
+		}
+		if record.noteID == "" {
+			record.noteID = existing.noteID
+		}
+		rm.renderCompletionLocked(record)
+		rm.completions[record.ID] = record
+		return
+	}
+
+	rm.clearCompletionsLocked(record.SessionID)
+	if carried, ok := rm.sessionNotes[record.SessionID]; ok && record.Note == "" && record.Tags == nil {
+		record.Note = carried.note
+		record.Tags = append([]string(nil), carried.tags...)
+		record.noteID = carried.id
+	}
+	rm.renderCompletionLocked(record)
 	rm.completions[record.ID] = record
 	rm.sessionCompletions[record.SessionID] = append(rm.sessionCompletions[record.SessionID], record.ID)
+	rm.activeCompletions[record.SessionID] = record.ID
 }
 
 // AddApproval 添加一个审批记录
@@ -77,10 +244,30 @@ func (rm *RecordManager) AddApproval(record *ApprovalRecord) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
+	rm.renderApprovalLocked(record)
 	rm.approvals[record.ID] = record
 	rm.sessionApprovals[record.SessionID] = append(rm.sessionApprovals[record.SessionID], record.ID)
 }
 
+// AddAlert 添加一个告警记录。同一会话每条规则只保留最新的一条，
+// 长时间运行的会话反复命中同一规则时记录数不会无限增长
+func (rm *RecordManager) AddAlert(record *AlertRecord) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	ids := rm.sessionAlerts[record.SessionID]
+	kept := ids[:0]
+	for _, id := range ids {
+		if existing, ok := rm.alerts[id]; ok && existing.Rule == record.Rule {
+			delete(rm.alerts, id)
+			continue
+		}
+		kept = append(kept, id)
+	}
+	rm.alerts[record.ID] = record
+	rm.sessionAlerts[record.SessionID] = append(kept, record.ID)
+}
+
 // GetCompletions 获取所有未关闭的完成记录
 func (rm *RecordManager) GetCompletions() []*CompletionRecord {
 	rm.mu.RLock()
@@ -109,30 +296,307 @@ func (rm *RecordManager) GetApprovals() []*ApprovalRecord {
 	return result
 }
 
+// GetAlerts 获取所有未关闭的告警记录，按触发时间排序
+func (rm *RecordManager) GetAlerts() []*AlertRecord {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	result := make([]*AlertRecord, 0)
+	for _, record := range rm.alerts {
+		if !record.Dismissed {
+			result = append(result, record)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].TriggeredAt.Before(result[j].TriggeredAt)
+	})
+	return result
+}
+
+// Summary 遍历未关闭的记录，按项目和助手类型分组统计
+func (rm *RecordManager) Summary() *RecordSummary {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	type groupKey struct {
+		projectID     string
+		assistantType string
+	}
+	groups := make(map[groupKey]*RecordSummaryGroup)
+	groupFor := func(projectID, projectName string, assistant *ai_assistant2.AIAssistantInfo) *RecordSummaryGroup {
+		assistantType := "unknown"
+		if assistant != nil && assistant.Type != "" {
+			assistantType = assistant.Type
+		}
+		key := groupKey{projectID: projectID, assistantType: assistantType}
+		group, ok := groups[key]
+		if !ok {
+			group = &RecordSummaryGroup{ProjectID: projectID, AssistantType: assistantType}
+			groups[key] = group
+		}
+		if group.ProjectName == "" {
+			group.ProjectName = projectName
+		}
+		return group
+	}
+
+	summary := &RecordSummary{}
+	for _, record := range rm.completions {
+		if record.Dismissed {
+			continue
+		}
+		group := groupFor(record.ProjectID, record.ProjectName, record.Assistant)
+		if record.State == "working" {
+			group.Working++
+			summary.Working++
+		} else {
+			group.Completed++
+			summary.Completed++
+		}
+	}
+
+	approvalProjects := make(map[string]struct{})
+	for _, record := range rm.approvals {
+		if record.Dismissed {
+			continue
+		}
+		group := groupFor(record.ProjectID, record.ProjectName, record.Assistant)
+		group.Approvals++
+		summary.Approvals++
+		approvalProjects[record.ProjectID] = struct{}{}
+	}
+	summary.ProjectsWithApprovals = len(approvalProjects)
+
+	for _, record := range rm.alerts {
+		if !record.Dismissed {
+			summary.Alerts++
+		}
+	}
+
+	summary.Groups = make([]RecordSummaryGroup, 0, len(groups))
+	for _, group := range groups {
+		summary.Groups = append(summary.Groups, *group)
+	}
+	sort.Slice(summary.Groups, func(i, j int) bool {
+		if summary.Groups[i].ProjectID != summary.Groups[j].ProjectID {
+			return summary.Groups[i].ProjectID < summary.Groups[j].ProjectID
+		}
+		return summary.Groups[i].AssistantType < summary.Groups[j].AssistantType
+	})
+	return summary
+}
+
+// ProjectCounts 统计指定项目未关闭的完成、审批与告警记录
+func (rm *RecordManager) ProjectCounts(projectID string) ProjectRecordCounts {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	var counts ProjectRecordCounts
+	for _, record := range rm.completions {
+		if record.Dismissed || record.ProjectID != projectID {
+			continue
+		}
+		if record.State == "working" {
+			counts.Working++
+		} else {
+			counts.Completed++
+		}
+	}
+	for _, record := range rm.approvals {
+		if !record.Dismissed && record.ProjectID == projectID {
+			counts.Approvals++
+		}
+	}
+	for _, record := range rm.alerts {
+		if !record.Dismissed && record.ProjectID == projectID {
+			counts.Alerts++
+		}
+	}
+	return counts
+}
+
 // DismissCompletion 关闭一个完成记录
 func (rm *RecordManager) DismissCompletion(recordID string) bool {
+	_, ok := rm.dismissCompletion(recordID)
+	return ok
+}
+
+// dismissCompletion 关闭完成记录并返回关闭前的副本
+func (rm *RecordManager) dismissCompletion(recordID string) (CompletionRecord, bool) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
-	if record, exists := rm.completions[recordID]; exists {
+	record, exists := rm.completions[recordID]
+	if !exists {
+		return CompletionRecord{}, false
+	}
+	before := *record
+	before.Tags = append([]string(nil), record.Tags...)
+	record.Dismissed = true
+	if rm.activeCompletions[record.SessionID] == recordID {
+		delete(rm.activeCompletions, record.SessionID)
+		delete(rm.sessionNotes, record.SessionID)
+	}
+	return before, true
+}
+
+// UpdateCompletionNote 更新完成记录的备注和标签，nil 表示保持不变。
+// 标签会去掉首尾空白并去重，空字符串被忽略
+func (rm *RecordManager) UpdateCompletionNote(recordID string, note *string, tags []string) (*CompletionRecord, error) {
+	var normalizedNote string
+	if note != nil {
+		normalizedNote = strings.TrimSpace(*note)
+		if utf8.RuneCountInString(normalizedNote) > recordNoteMaxRunes {
+			return nil, fmt.Errorf("%w: note exceeds %d characters", ErrInvalidRecordNote, recordNoteMaxRunes)
+		}
+	}
+	normalizedTags, err := normalizeRecordTags(tags)
+	if err != nil {
+		return nil, err
+	}
+
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	record, exists := rm.completions[recordID]
+	if !exists {
+		return nil, ErrRecordNotFound
+	}
+	if note != nil {
+		record.Note = normalizedNote
+	}
+	if tags != nil {
+		record.Tags = normalizedTags
+	}
+	if record.noteID == "" {
+		record.noteID = record.ID
+	}
+	if rm.activeCompletions[record.SessionID] == recordID {
+		if record.Note == "" && len(record.Tags) == 0 {
+			delete(rm.sessionNotes, record.SessionID)
+		} else {
+			rm.sessionNotes[record.SessionID] = recordNote{
+				id:   record.noteID,
+				note: record.Note,
+				tags: append([]string(nil), record.Tags...),
+			}
+		}
+	}
+	snapshot := *record
+	snapshot.Tags = append([]string(nil), record.Tags...)
+	return &snapshot, nil
+}
+
+func normalizeRecordTags(tags []string) ([]string, error) {
+	if tags == nil {
+		return nil, nil
+	}
+	seen := make(map[string]struct{}, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, raw := range tags {
+		tag := strings.TrimSpace(raw)
+		if tag == "" {
+			continue
+		}
+		if utf8.RuneCountInString(tag) > recordTagMaxRunes {
+			return nil, fmt.Errorf("%w: tag %q exceeds %d characters", ErrInvalidRecordNote, tag, recordTagMaxRunes)
+		}
+		if _, ok := seen[tag]; ok {
+			continue
+		}
+		seen[tag] = struct{}{}
+		result = append(result, tag)
+	}
+	if len(result) > recordMaxTags {
+		return nil, fmt.Errorf("%w: at most %d tags are allowed", ErrInvalidRecordNote, recordMaxTags)
+	}
+	return result, nil
+}
+
+// DismissApproval 关闭一个审批记录
+func (rm *RecordManager) DismissApproval(recordID string) bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if record, exists := rm.approvals[recordID]; exists {
 		record.Dismissed = true
 		return true
 	}
 	return false
 }
 
-// DismissApproval 关闭一个审批记录
-func (rm *RecordManager) DismissApproval(recordID string) bool {
+// approval 返回未关闭的审批记录副本
+func (rm *RecordManager) approval(recordID string) (ApprovalRecord, bool) {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	record, exists := rm.approvals[recordID]
+	if !exists || record.Dismissed {
+		return ApprovalRecord{}, false
+	}
+	return *record, true
+}
+
+// pendingApprovalTimeouts 返回尚未按超时策略处理过的未关闭审批记录副本
+func (rm *RecordManager) pendingApprovalTimeouts() []ApprovalRecord {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	result := make([]ApprovalRecord, 0)
+	for _, record := range rm.approvals {
+		if !record.Dismissed && record.TimedOutAt == nil {
+			result = append(result, *record)
+		}
+	}
+	return result
+}
+
+// markApprovalTimedOut 标记审批记录已超时，记录不存在、已关闭或已标记过时返回 false
+func (rm *RecordManager) markApprovalTimedOut(recordID string, at time.Time) bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	record, exists := rm.approvals[recordID]
+	if !exists || record.Dismissed || record.TimedOutAt != nil {
+		return false
+	}
+	record.TimedOutAt = &at
+	return true
+}
+
+// markApprovalAutoRejected 标记审批记录已被自动拒绝
+func (rm *RecordManager) markApprovalAutoRejected(recordID string) {
 	rm.mu.Lock()
 	defer rm.mu.Unlock()
 
 	if record, exists := rm.approvals[recordID]; exists {
+		record.AutoRejected = true
+	}
+}
+
+// DismissAlert 关闭一个告警记录
+func (rm *RecordManager) DismissAlert(recordID string) bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if record, exists := rm.alerts[recordID]; exists {
 		record.Dismissed = true
 		return true
 	}
 	return false
 }
 
+// ClearAlertsBySession 清除某个 session 的所有告警记录（session 关闭时）
+func (rm *RecordManager) ClearAlertsBySession(sessionID string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	for _, recordID := range rm.sessionAlerts[sessionID] {
+		delete(rm.alerts, recordID)
+	}
+	delete(rm.sessionAlerts, sessionID)
+}
+
 // ClearSessionRecords 清除某个 session 的所有记录（当 session 关闭或状态变化时）
 func (rm *RecordManager) ClearSessionRecords(sessionID string) {
 	rm.mu.Lock()
@@ -142,6 +606,13 @@ func (rm *RecordManager) ClearSessionRecords(sessionID string) {
 	rm.clearApprovalsLocked(sessionID)
 }
 
+// ClearSessionNotes 删除 session 待转移的备注（session 关闭时），备注已落库保留在历史中
+func (rm *RecordManager) ClearSessionNotes(sessionID string) {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+	delete(rm.sessionNotes, sessionID)
+}
+
 // ClearCompletionsBySession 清除某个 session 的所有完成记录
 func (rm *RecordManager) ClearCompletionsBySession(sessionID string) {
 	rm.mu.Lock()
@@ -184,9 +655,19 @@ func (rm *RecordManager) UpdateCompletionBySession(sessionID string, state strin
 		for _, recordID := range recordIDs {
 			if record, ok := rm.completions[recordID]; ok {
 				record.State = state
+				rerender := false
+				// 重新开始工作后，上一轮的打断标记不再适用
+				if state == "working" && record.Interrupted {
+					record.Interrupted = false
+					rerender = true
+				}
 				// 只有当有新的用户输入时才更新
 				if userInput != "" {
 					record.LastUserInput = userInput
+					rerender = true
+				}
+				if rerender {
+					rm.renderCompletionLocked(record)
 				}
 				updated = true
 			}
@@ -195,6 +676,25 @@ func (rm *RecordManager) UpdateCompletionBySession(sessionID string, state strin
 	return updated
 }
 
+// SetConflictsBySession 更新 session 对应完成记录的未解决冲突数量
+func (rm *RecordManager) SetConflictsBySession(sessionID string, conflicts int) bool {
+	rm.mu.Lock()
+	defer rm.mu.Unlock()
+
+	if conflicts < 0 {
+		conflicts = 0
+	}
+	updated := false
+	for _, recordID := range rm.sessionCompletions[sessionID] {
+		if record, ok := rm.completions[recordID]; ok {
+			record.HasConflicts = conflicts > 0
+			record.UnresolvedConflicts = conflicts
+			updated = true
+		}
+	}
+	return updated
+}
+
 // GetCompletion 获取单个完成记录
 func (rm *RecordManager) GetCompletion(recordID string) *CompletionRecord {
 	rm.mu.RLock()
@@ -218,6 +718,7 @@ func (rm *RecordManager) clearCompletionsLocked(sessionID string) {
 		}
 		delete(rm.sessionCompletions, sessionID)
 	}
+	delete(rm.activeCompletions, sessionID)
 }
 
 func (rm *RecordManager) clearApprovalsLocked(sessionID string) {
@@ -228,3 +729,25 @@ func (rm *RecordManager) clearApprovalsLocked(sessionID string) {
 		delete(rm.sessionApprovals, sessionID)
 	}
 }
+
+// LastCompletionBySession 返回会话最近一条指定状态的完成记录（含已关闭的），state 为空时不过滤
+func (rm *RecordManager) LastCompletionBySession(sessionID string, state string) *CompletionRecord {
+	rm.mu.RLock()
+	defer rm.mu.RUnlock()
+
+	var latest *CompletionRecord
+	for _, recordID := range rm.sessionCompletions[sessionID] {
+		record, ok := rm.completions[recordID]
+		if !ok || (state != "" && record.State != state) {
+			continue
+		}
+		if latest == nil || record.CompletedAt.After(latest.CompletedAt) {
+			latest = record
+		}
+	}
+	if latest == nil {
+		return nil
+	}
+	copied := *latest
+	return &copied
+}
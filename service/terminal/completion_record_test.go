@@ -1,6 +1,8 @@
 package terminal
 
 import (
+	"errors"
+	"strings"
 	"testing"
 	"time"
 
@@ -179,6 +181,84 @@ func TestRecordManager_ApprovalRecords(t *testing.T) {
 	}
 }
 
+func TestRecordManager_Summary(t *testing.T) {
+	rm := NewRecordManager()
+	claude := &ai_assistant2.AIAssistantInfo{Type: "claude-code"}
+	codex := &ai_assistant2.AIAssistantInfo{Type: "codex"}
+
+	rm.AddCompletion(&CompletionRecord{ID: "c1", SessionID: "s1", ProjectID: "p1", Assistant: claude, State: "completed"})
+	rm.AddCompletion(&CompletionRecord{ID: "c2", SessionID: "s2", ProjectID: "p1", Assistant: claude, State: "working"})
+	rm.AddCompletion(&CompletionRecord{ID: "c3", SessionID: "s3", ProjectID: "p2", Assistant: codex, State: "completed"})
+	rm.AddCompletion(&CompletionRecord{ID: "c4", SessionID: "s4", ProjectID: "p2", Assistant: codex, State: "completed"})
+	rm.AddApproval(&ApprovalRecord{ID: "a1", SessionID: "s5", ProjectID: "p1", Assistant: codex})
+	rm.AddApproval(&ApprovalRecord{ID: "a2", SessionID: "s6", ProjectID: "p2", Assistant: codex})
+	rm.DismissCompletion("c4")
+
+	summary := rm.Summary()
+	if summary.Completed != 2 || summary.Working != 1 || summary.Approvals != 2 {
+		t.Fatalf("unexpected totals: %+v", summary)
+	}
+	if summary.ProjectsWithApprovals != 2 {
+		t.Fatalf("expected 2 projects with approvals, got %d", summary.ProjectsWithApprovals)
+	}
+
+	want := []RecordSummaryGroup{
+		{ProjectID: "p1", AssistantType: "claude-code", Completed: 1, Working: 1},
+		{ProjectID: "p1", AssistantType: "codex", Approvals: 1},
+		{ProjectID: "p2", AssistantType: "codex", Completed: 1, Approvals: 1},
+	}
+	if len(summary.Groups) != len(want) {
+		t.Fatalf("expected %d groups, got %+v", len(want), summary.Groups)
+	}
+	for i, group := range want {
+		if summary.Groups[i] != group {
+			t.Fatalf("group %d: expected %+v, got %+v", i, group, summary.Groups[i])
+		}
+	}
+}
+
+func TestRecordManager_ProjectCounts(t *testing.T) {
+	rm := NewRecordManager()
+	rm.AddCompletion(&CompletionRecord{ID: "c1", SessionID: "s1", ProjectID: "p1", State: "completed"})
+	rm.AddCompletion(&CompletionRecord{ID: "c2", SessionID: "s2", ProjectID: "p1", State: "working"})
+	rm.AddCompletion(&CompletionRecord{ID: "c3", SessionID: "s3", ProjectID: "p1", State: "completed"})
+	rm.AddCompletion(&CompletionRecord{ID: "c4", SessionID: "s4", ProjectID: "p2", State: "completed"})
+	rm.AddApproval(&ApprovalRecord{ID: "a1", SessionID: "s5", ProjectID: "p1"})
+	rm.AddAlert(&AlertRecord{ID: "l1", SessionID: "s1", ProjectID: "p1"})
+	rm.AddAlert(&AlertRecord{ID: "l2", SessionID: "s4", ProjectID: "p2"})
+	rm.DismissCompletion("c3")
+
+	want := ProjectRecordCounts{Completed: 1, Approvals: 1, Working: 1, Alerts: 1}
+	if counts := rm.ProjectCounts("p1"); counts != want {
+		t.Fatalf("expected %+v, got %+v", want, counts)
+	}
+	if counts := rm.ProjectCounts("missing"); counts != (ProjectRecordCounts{}) {
+		t.Fatalf("expected no records, got %+v", counts)
+	}
+}
+
+func TestRecordManager_SetConflictsBySession(t *testing.T) {
+	rm := NewRecordManager()
+	rm.AddCompletion(&CompletionRecord{ID: "rec1", SessionID: "sess1", ProjectID: "proj1"})
+
+	if !rm.SetConflictsBySession("sess1", 2) {
+		t.Fatalf("expected conflicts to be applied")
+	}
+	record := rm.GetCompletion("rec1")
+	if !record.HasConflicts || record.UnresolvedConflicts != 2 {
+		t.Fatalf("expected conflict flag, got %+v", record)
+	}
+
+	rm.SetConflictsBySession("sess1", 0)
+	if record := rm.GetCompletion("rec1"); record.HasConflicts || record.UnresolvedConflicts != 0 {
+		t.Fatalf("expected conflict flag to be cleared, got %+v", record)
+	}
+
+	if rm.SetConflictsBySession("unknown", 1) {
+		t.Fatalf("expected no update for unknown session")
+	}
+}
+
 func TestRecordManager_ClearCompletionsBySession(t *testing.T) {
 	rm := NewRecordManager()
 
@@ -242,3 +322,198 @@ func TestCompletionRecord_WithAssistantInfo(t *testing.T) {
 		t.Fatalf("expected Assistant.DisplayName 'Claude', got %q", completions[0].Assistant.DisplayName)
 	}
 }
+
+func TestRecordManager_DisplayTextTemplates(t *testing.T) {
+	rm := NewRecordManager()
+	if err := rm.SetTemplates("{{assistant}} finished {{title}} in {{project}}: {{input}}", "{{ project }}/{{title}} needs approval"); err != nil {
+		t.Fatalf("SetTemplates returned error: %v", err)
+	}
+
+	assistant := &ai_assistant2.AIAssistantInfo{Type: "claude-code", DisplayName: "Claude Code"}
+	completion := &CompletionRecord{
+		ID:            "rec1",
+		SessionID:     "sess1",
+		ProjectID:     "proj1",
+		ProjectName:   "Kanban",
+		Title:         "Fix bug",
+		Assistant:     assistant,
+		LastUserInput: "fix the login bug",
+	}
+	rm.AddCompletion(completion)
+	if want := "Claude Code finished Fix bug in Kanban: fix the login bug"; completion.DisplayText != want {
+		t.Fatalf("expected %q, got %q", want, completion.DisplayText)
+	}
+
+	rm.UpdateCompletionBySession("sess1", "working", "add tests")
+	if want := "Claude Code finished Fix bug in Kanban: add tests"; completion.DisplayText != want {
+		t.Fatalf("expected display text to follow new input, got %q", completion.DisplayText)
+	}
+
+	// 没有项目名时用项目 ID 代替
+	approval := &ApprovalRecord{ID: "apr1", SessionID: "sess1", ProjectID: "proj1", Title: "Fix bug", Assistant: assistant}
+	rm.AddApproval(approval)
+	if want := "proj1/Fix bug needs approval"; approval.DisplayText != want {
+		t.Fatalf("expected %q, got %q", want, approval.DisplayText)
+	}
+
+	for _, invalid := range []string{"{{unknown}}", "{{title", "title}}"} {
+		if err := rm.SetTemplates(invalid, ""); err == nil {
+			t.Fatalf("expected error for template %q", invalid)
+		}
+		record := &CompletionRecord{ID: "rec-" + invalid, SessionID: "sess2", ProjectID: "proj1", Title: "Fix bug", Assistant: assistant}
+		rm.AddCompletion(record)
+		if want := "[proj1] Fix bug · Claude Code 已完成"; record.DisplayText != want {
+			t.Fatalf("expected fallback to default template for %q, got %q", invalid, record.DisplayText)
+		}
+	}
+}
+
+func TestRecordManager_InterruptedCompletion(t *testing.T) {
+	rm := NewRecordManager()
+	if err := rm.SetTemplates("{{assistant}} finished {{title}}", ""); err != nil {
+		t.Fatalf("SetTemplates returned error: %v", err)
+	}
+
+	assistant := &ai_assistant2.AIAssistantInfo{Type: "codex", DisplayName: "Codex"}
+	record := &CompletionRecord{
+		ID:          "rec1",
+		SessionID:   "sess1",
+		ProjectID:   "proj1",
+		Title:       "Fix bug",
+		Assistant:   assistant,
+		Interrupted: true,
+	}
+	rm.AddCompletion(record)
+	if record.State != "completed" {
+		t.Fatalf("expected interrupted record to keep state completed, got %q", record.State)
+	}
+	// 被打断的记录不使用配置的完成模板
+	if want := "[proj1] Fix bug · Codex 任务被中断"; record.DisplayText != want {
+		t.Fatalf("expected %q, got %q", want, record.DisplayText)
+	}
+
+	rm.UpdateCompletionBySession("sess1", "working", "")
+	if record.Interrupted {
+		t.Fatal("expected interrupted flag to be cleared once the assistant works again")
+	}
+	if want := "Codex finished Fix bug"; record.DisplayText != want {
+		t.Fatalf("expected completion template after clearing, got %q", record.DisplayText)
+	}
+}
+
+func TestRecordManager_UpdateCompletionNote(t *testing.T) {
+	rm := NewRecordManager()
+	rm.AddCompletion(&CompletionRecord{ID: "rec1", SessionID: "sess1", ProjectID: "proj1", Title: "Fix bug"})
+
+	note := "  已 review，待测试 "
+	record, err := rm.UpdateCompletionNote("rec1", &note, []string{" review ", "review", "", "test"})
+	if err != nil {
+		t.Fatalf("UpdateCompletionNote returned error: %v", err)
+	}
+	if record.Note != "已 review，待测试" {
+		t.Fatalf("expected trimmed note, got %q", record.Note)
+	}
+	if len(record.Tags) != 2 || record.Tags[0] != "review" || record.Tags[1] != "test" {
+		t.Fatalf("expected deduplicated tags, got %v", record.Tags)
+	}
+
+	// nil 字段保持不变，空切片清空标签
+	record, err = rm.UpdateCompletionNote("rec1", nil, []string{})
+	if err != nil {
+		t.Fatalf("UpdateCompletionNote returned error: %v", err)
+	}
+	if record.Note != "已 review，待测试" || len(record.Tags) != 0 {
+		t.Fatalf("unexpected record after clearing tags: %+v", record)
+	}
+
+	if _, err := rm.UpdateCompletionNote("missing", &note, nil); !errors.Is(err, ErrRecordNotFound) {
+		t.Fatalf("expected ErrRecordNotFound, got %v", err)
+	}
+	tooLong := strings.Repeat("a", recordTagMaxRunes+1)
+	if _, err := rm.UpdateCompletionNote("rec1", nil, []string{tooLong}); !errors.Is(err, ErrInvalidRecordNote) {
+		t.Fatalf("expected ErrInvalidRecordNote, got %v", err)
+	}
+
+	// 关闭后备注仍保留在记录上
+	if !rm.DismissCompletion("rec1") {
+		t.Fatal("expected dismiss to succeed")
+	}
+	if stored := rm.GetCompletion("rec1"); stored == nil || stored.Note != "已 review，待测试" || !stored.Dismissed {
+		t.Fatalf("expected note to survive dismiss, got %+v", stored)
+	}
+}
+
+func TestRecordManager_AddCompletionUpsertsBySession(t *testing.T) {
+	rm := NewRecordManager()
+
+	rm.AddCompletion(&CompletionRecord{ID: "rec1", SessionID: "sess1", State: "working", LastUserInput: "input1"})
+	note := "keep me"
+	if _, err := rm.UpdateCompletionNote("rec1", &note, []string{"bug"}); err != nil {
+		t.Fatalf("UpdateCompletionNote: %v", err)
+	}
+
+	// 未关闭的记录原地更新，沿用 ID 与备注标签
+	rm.AddCompletion(&CompletionRecord{ID: "rec2", SessionID: "sess1", LastUserInput: "input2"})
+	completions := rm.GetCompletions()
+	if len(completions) != 1 {
+		t.Fatalf("expected 1 completion, got %d", len(completions))
+	}
+	if c := completions[0]; c.ID != "rec1" || c.State != "completed" || c.LastUserInput != "input2" || c.Note != "keep me" || len(c.Tags) != 1 {
+		t.Fatalf("unexpected upserted record %+v", c)
+	}
+	if rm.GetCompletion("rec2") != nil {
+		t.Fatalf("expected no separate record for rec2")
+	}
+
+	// 关闭后再完成则新增记录，旧记录被清理
+	rm.DismissCompletion("rec1")
+	rm.AddCompletion(&CompletionRecord{ID: "rec3", SessionID: "sess1"})
+	completions = rm.GetCompletions()
+	if len(completions) != 1 || completions[0].ID != "rec3" || completions[0].Note != "" {
+		t.Fatalf("expected new record rec3, got %+v", completions)
+	}
+	if rm.GetCompletion("rec1") != nil {
+		t.Fatalf("expected dismissed record to be replaced")
+	}
+
+	// 其他 session 不受影响
+	rm.AddCompletion(&CompletionRecord{ID: "rec4", SessionID: "sess2"})
+	if len(rm.GetCompletions()) != 2 {
+		t.Fatalf("expected records of both sessions")
+	}
+}
+
+func TestRecordManager_NoteCarriedAcrossClear(t *testing.T) {
+	rm := NewRecordManager()
+	rm.AddCompletion(&CompletionRecord{ID: "rec1", SessionID: "sess1"})
+	note := "待测试"
+	if _, err := rm.UpdateCompletionNote("rec1", &note, []string{"qa"}); err != nil {
+		t.Fatalf("UpdateCompletionNote: %v", err)
+	}
+
+	// 未关闭的卡片被清理（助手退出）后，下一条记录沿用备注与落库 ID
+	rm.ClearSessionRecords("sess1")
+	rm.AddCompletion(&CompletionRecord{ID: "rec2", SessionID: "sess1"})
+	record := rm.GetCompletion("rec2")
+	if record == nil || record.Note != "待测试" || len(record.Tags) != 1 || record.noteID != "rec1" {
+		t.Fatalf("expected the note to carry over, got %+v", record)
+	}
+
+	// 关闭卡片后备注不再转移
+	rm.DismissCompletion("rec2")
+	rm.AddCompletion(&CompletionRecord{ID: "rec3", SessionID: "sess1"})
+	if record := rm.GetCompletion("rec3"); record == nil || record.Note != "" || record.noteID != "" {
+		t.Fatalf("dismissed note carried over: %+v", record)
+	}
+
+	// 会话关闭后同样不再转移
+	if _, err := rm.UpdateCompletionNote("rec3", &note, nil); err != nil {
+		t.Fatalf("UpdateCompletionNote: %v", err)
+	}
+	rm.ClearSessionRecords("sess1")
+	rm.ClearSessionNotes("sess1")
+	rm.AddCompletion(&CompletionRecord{ID: "rec4", SessionID: "sess1"})
+	if record := rm.GetCompletion("rec4"); record == nil || record.Note != "" {
+		t.Fatalf("note of a closed session carried over: %+v", record)
+	}
+}
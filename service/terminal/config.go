@@ -0,0 +1,242 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils"
+)
+
+const (
+	defaultScrollbackBytes = 256 * 1024
+	// maxScrollbackBytes 单个会话的滚动缓冲上限，防止配置失误占满内存
+	maxScrollbackBytes = 64 * 1024 * 1024
+	// defaultCompletionSettleDelay waiting_input 持续这么久才生成完成通知，过滤状态抖动
+	defaultCompletionSettleDelay = 1500 * time.Millisecond
+)
+
+// ApplyDefaults normalizes the config and fills values left unset.
+func (c *Config) ApplyDefaults() {
+	c.Encoding = strings.ToLower(strings.TrimSpace(c.Encoding))
+	if c.Encoding == "" {
+		c.Encoding = "utf-8"
+	}
+	if c.ScrollbackBytes == 0 {
+		c.ScrollbackBytes = defaultScrollbackBytes
+	}
+	c.RecordEvents = strings.ToLower(strings.TrimSpace(c.RecordEvents))
+	c.BinaryOutput = strings.ToLower(strings.TrimSpace(c.BinaryOutput))
+	c.Term = strings.TrimSpace(c.Term)
+	if c.Term == "" {
+		c.Term = DefaultTermType
+	}
+	c.Webhook.URL = strings.TrimSpace(c.Webhook.URL)
+	if c.CompletionSettleDelay == 0 {
+		c.CompletionSettleDelay = defaultCompletionSettleDelay
+	}
+	// 复制价格表，check 删除非法项时不影响调用方的配置
+	c.ModelPricing = maps.Clone(c.ModelPricing)
+	c.ApprovalTimeout.Default.normalize()
+	assistants := make(map[string]ApprovalTimeoutPolicy, len(c.ApprovalTimeout.Assistants))
+	for assistant, policy := range c.ApprovalTimeout.Assistants {
+		policy.normalize()
+		assistants[strings.TrimSpace(assistant)] = policy
+	}
+	c.ApprovalTimeout.Assistants = assistants
+}
+
+// Validate reports every invalid field of the config. NewManager logs these and
+// falls back to the defaults, so a typo in config.yaml never prevents startup.
+func (c Config) Validate() error {
+	return errors.Join(c.check(false)...)
+}
+
+// check validates the config; with fix set, invalid fields are reset to their
+// defaults after being reported.
+func (c *Config) check(fix bool) []error {
+	var errs []error
+	if _, name, err := resolveEncoding(c.Encoding); err != nil {
+		errs = append(errs, fmt.Errorf("encoding %q is not supported (utf-8, gbk, gb18030, gb2312)", name))
+		if fix {
+			c.Encoding = "utf-8"
+		}
+	}
+	if c.ScrollbackBytes < 0 || c.ScrollbackBytes > maxScrollbackBytes {
+		errs = append(errs, fmt.Errorf("scrollbackBytes %d must be between 0 and %d", c.ScrollbackBytes, maxScrollbackBytes))
+		if fix {
+			c.ScrollbackBytes = defaultScrollbackBytes
+		}
+	}
+	if c.ScrollbackTotalBytes < 0 {
+		errs = append(errs, fmt.Errorf("scrollbackTotalBytes %d must not be negative, use 0 for unlimited", c.ScrollbackTotalBytes))
+		if fix {
+			c.ScrollbackTotalBytes = 0
+		}
+	}
+	if c.IdleTimeout < 0 {
+		errs = append(errs, fmt.Errorf("idleTimeout %s must not be negative, use 0 to disable", c.IdleTimeout))
+		if fix {
+			c.IdleTimeout = 0
+		}
+	}
+	if c.MaxSessionsPerProject < 0 {
+		errs = append(errs, fmt.Errorf("maxSessionsPerProject %d must not be negative, use 0 for unlimited", c.MaxSessionsPerProject))
+		if fix {
+			c.MaxSessionsPerProject = 0
+		}
+	}
+	if c.QuotaMaxSessions < 0 {
+		errs = append(errs, fmt.Errorf("quotaMaxSessions %d must not be negative, use 0 for unlimited", c.QuotaMaxSessions))
+		if fix {
+			c.QuotaMaxSessions = 0
+		}
+	}
+	switch c.RecordEvents {
+	case EventRecordOff, EventRecordMemory:
+	case EventRecordFile:
+		if strings.TrimSpace(c.RecordEventsDir) == "" {
+			errs = append(errs, errors.New("recordEventsDir is required when recordEvents is file"))
+			if fix {
+				c.RecordEvents = EventRecordOff
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("recordEvents %q must be empty, memory or file", c.RecordEvents))
+		if fix {
+			c.RecordEvents = EventRecordOff
+		}
+	}
+	if c.RecordEventsMaxFiles < 0 {
+		errs = append(errs, fmt.Errorf("recordEventsMaxFiles %d must not be negative", c.RecordEventsMaxFiles))
+		if fix {
+			c.RecordEventsMaxFiles = 0
+		}
+	}
+	switch c.BinaryOutput {
+	case BinaryOutputOff, BinaryOutputTruncate, BinaryOutputReset:
+	default:
+		errs = append(errs, fmt.Errorf("binaryOutput %q must be empty, truncate or reset", c.BinaryOutput))
+		if fix {
+			c.BinaryOutput = BinaryOutputOff
+		}
+	}
+	if _, err := resolveTermType(c.Term, ""); err != nil {
+		errs = append(errs, fmt.Errorf("term: %w", err))
+		if fix {
+			c.Term = DefaultTermType
+		}
+	}
+	if _, err := buildTermEnv(c.TermEnv, nil); err != nil {
+		errs = append(errs, fmt.Errorf("termEnv: %w", err))
+		if fix {
+			c.TermEnv = nil
+		}
+	}
+	for _, tmpl := range []*string{&c.CompletionTemplate, &c.ApprovalTemplate} {
+		if strings.TrimSpace(*tmpl) == "" {
+			continue
+		}
+		if _, err := parseRecordTemplate(*tmpl); err != nil {
+			errs = append(errs, fmt.Errorf("notification template: %w", err))
+			if fix {
+				*tmpl = ""
+			}
+		}
+	}
+	for model, price := range c.ModelPricing {
+		if price.InputPerMillion < 0 || price.OutputPerMillion < 0 {
+			errs = append(errs, fmt.Errorf("modelPricing %q: prices must not be negative", model))
+			if fix {
+				delete(c.ModelPricing, model)
+			}
+		}
+	}
+	if c.OutputLog.MaxBytes < 0 || c.OutputLog.MaxFiles < 0 {
+		errs = append(errs, errors.New("outputLog size and file limits must not be negative"))
+		if fix {
+			c.OutputLog.MaxBytes = 0
+			c.OutputLog.MaxFiles = 0
+		}
+	}
+	if c.ScrollbackSpill.MaxBytes < 0 {
+		errs = append(errs, fmt.Errorf("scrollbackSpill maxBytes %d must not be negative", c.ScrollbackSpill.MaxBytes))
+		if fix {
+			c.ScrollbackSpill.MaxBytes = 0
+		}
+	}
+	if c.Screenshots.Interval < 0 || c.Screenshots.MaxFiles < 0 || c.Screenshots.MaxSessions < 0 {
+		errs = append(errs, errors.New("screenshots interval, file limit and session limit must not be negative"))
+		if fix {
+			c.Screenshots.Interval = 0
+			c.Screenshots.MaxFiles = 0
+			c.Screenshots.MaxSessions = 0
+		}
+	}
+	if c.Webhook.URL != "" {
+		if err := checkWebhookURL(c.Webhook.URL); err != nil {
+			errs = append(errs, fmt.Errorf("webhook url: %w", err))
+			if fix {
+				c.Webhook.URL = ""
+			}
+		}
+	}
+	if c.Webhook.Timeout < 0 {
+		errs = append(errs, fmt.Errorf("webhook timeout %s must not be negative", c.Webhook.Timeout))
+		if fix {
+			c.Webhook.Timeout = 0
+		}
+	}
+	errs = append(errs, c.ApprovalTimeout.Default.check("default", fix)...)
+	for assistant, policy := range c.ApprovalTimeout.Assistants {
+		policyErrs := policy.check(assistant, fix)
+		if len(policyErrs) > 0 && fix {
+			c.ApprovalTimeout.Assistants[assistant] = policy
+		}
+		errs = append(errs, policyErrs...)
+	}
+	shellNames := map[string]bool{utils.DefaultShellName: true}
+	validShells := make([]utils.TerminalNamedShell, 0, len(c.Shell.Named))
+	for _, shell := range c.Shell.Named {
+		name := strings.TrimSpace(shell.Name)
+		switch {
+		case name == "" || strings.TrimSpace(shell.Command) == "":
+			errs = append(errs, fmt.Errorf("shell.named %q: name and command are required", shell.Name))
+		case shellNames[name]:
+			errs = append(errs, fmt.Errorf("shell.named %q: name is reserved or duplicated", name))
+		default:
+			shellNames[name] = true
+			validShells = append(validShells, shell)
+		}
+	}
+	if fix && len(validShells) != len(c.Shell.Named) {
+		c.Shell.Named = validShells
+	}
+	return errs
+}
+
+// logEffective records the values actually used so configuration issues can be
+// traced from the log.
+func (c Config) logEffective(logger *zap.Logger) {
+	logger.Info("terminal config in effect",
+		zap.String("encoding", c.Encoding),
+		zap.Int("scrollbackBytes", c.ScrollbackBytes),
+		zap.Bool("scrollbackEnabled", c.ScrollbackEnabled),
+		zap.Bool("scrollbackSpill", c.ScrollbackSpill.Enabled),
+		zap.Duration("idleTimeout", c.IdleTimeout),
+		zap.Int("maxSessionsPerProject", c.MaxSessionsPerProject),
+		zap.Int("quotaMaxSessions", c.QuotaMaxSessions),
+		zap.Uint64("quotaMaxMemoryBytes", c.QuotaMaxMemoryBytes),
+		zap.String("recordEvents", c.RecordEvents),
+		zap.String("term", c.Term),
+		zap.Bool("outputLog", c.OutputLog.Dir != ""),
+		zap.Bool("screenshots", c.Screenshots.Dir != ""),
+		zap.Bool("webhook", c.Webhook.URL != ""),
+		zap.Bool("approvalTimeout", c.ApprovalTimeout.enabled()),
+		zap.Int("namedShells", len(c.Shell.Named)),
+	)
+}
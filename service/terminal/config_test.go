@@ -0,0 +1,75 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"code-kanban/utils"
+)
+
+func TestConfigApplyDefaults(t *testing.T) {
+	cfg := Config{Encoding: "  GBK "}
+	cfg.ApplyDefaults()
+	if cfg.Encoding != "gbk" {
+		t.Fatalf("expected normalized encoding, got %q", cfg.Encoding)
+	}
+	if cfg.ScrollbackBytes != defaultScrollbackBytes {
+		t.Fatalf("expected default scrollback, got %d", cfg.ScrollbackBytes)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected valid config, got %v", err)
+	}
+
+	empty := Config{}
+	empty.ApplyDefaults()
+	if empty.Encoding != "utf-8" {
+		t.Fatalf("expected utf-8 default, got %q", empty.Encoding)
+	}
+}
+
+func TestConfigValidateReportsAndFixes(t *testing.T) {
+	cfg := Config{
+		Encoding:              "latin1",
+		ScrollbackBytes:       -1,
+		IdleTimeout:           -time.Minute,
+		MaxSessionsPerProject: -2,
+		RecordEvents:          "disk",
+		CompletionTemplate:    "{{nope}}",
+	}
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+	for _, field := range []string{"encoding", "scrollbackBytes", "idleTimeout", "maxSessionsPerProject", "recordEvents", "notification template"} {
+		if !strings.Contains(err.Error(), field) {
+			t.Fatalf("expected error to mention %s, got %v", field, err)
+		}
+	}
+
+	if errs := cfg.check(true); len(errs) != 6 {
+		t.Fatalf("expected 6 errors, got %d: %v", len(errs), errs)
+	}
+	if cfg.Encoding != "utf-8" || cfg.ScrollbackBytes != defaultScrollbackBytes || cfg.IdleTimeout != 0 ||
+		cfg.MaxSessionsPerProject != 0 || cfg.RecordEvents != EventRecordOff || cfg.CompletionTemplate != "" {
+		t.Fatalf("expected invalid values to fall back to defaults, got %+v", cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected fixed config to be valid, got %v", err)
+	}
+}
+
+func TestConfigValidateNamedShells(t *testing.T) {
+	cfg := Config{Shell: utils.TerminalShellConfig{Named: []utils.TerminalNamedShell{
+		{Name: "fish", Command: "fish -l"},
+		{Name: "fish", Command: "/usr/bin/fish"},
+		{Name: "default", Command: "/bin/sh"},
+		{Name: "empty"},
+	}}}
+	if errs := cfg.check(true); len(errs) != 3 {
+		t.Fatalf("expected 3 errors, got %d: %v", len(errs), errs)
+	}
+	if len(cfg.Shell.Named) != 1 || cfg.Shell.Named[0].Command != "fish -l" {
+		t.Fatalf("expected only the first valid shell to remain, got %+v", cfg.Shell.Named)
+	}
+}
@@ -0,0 +1,129 @@
+package terminal
+
+import (
+	"bytes"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+// osc7Prefix shell 集成上报的当前目录：ESC ] 7 ; file://host/path ST
+var osc7Prefix = []byte("\x1b]7;")
+
+// cwdPendingMaxBytes 跨 chunk 的未结束序列上限，超出视为损坏直接丢弃
+const cwdPendingMaxBytes = 4096
+
+// cwdTracker 从输出流中解析 OSC 7，记录 shell 最近上报的工作目录
+type cwdTracker struct {
+	mu      sync.Mutex
+	dir     string
+	pending []byte
+}
+
+func (c *cwdTracker) observe(chunk []byte) {
+	if len(chunk) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	data := chunk
+	if len(c.pending) > 0 {
+		data = append(c.pending, chunk...)
+		c.pending = nil
+	}
+	for {
+		idx := bytes.Index(data, osc7Prefix)
+		if idx < 0 {
+			c.pending = partialPrefix(data, osc7Prefix)
+			return
+		}
+		body := data[idx+len(osc7Prefix):]
+		end, stLen := findStringTerminator(body)
+		if end < 0 {
+			// 序列还没结束：等下一个 chunk；中途出现其他 ESC 说明已损坏
+			if bytes.IndexByte(body, 0x1b) < 0 && len(data)-idx <= cwdPendingMaxBytes {
+				c.pending = append([]byte(nil), data[idx:]...)
+			}
+			return
+		}
+		if dir, ok := parseOSC7Dir(string(body[:end])); ok {
+			c.dir = dir
+		}
+		data = body[end+stLen:]
+	}
+}
+
+func (c *cwdTracker) current() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.dir
+}
+
+func (c *cwdTracker) reset() {
+	c.mu.Lock()
+	c.dir = ""
+	c.pending = nil
+	c.mu.Unlock()
+}
+
+// partialPrefix returns the tail of data that may be the start of prefix.
+func partialPrefix(data, prefix []byte) []byte {
+	for n := min(len(prefix)-1, len(data)); n > 0; n-- {
+		if bytes.HasPrefix(prefix, data[len(data)-n:]) {
+			return append([]byte(nil), data[len(data)-n:]...)
+		}
+	}
+	return nil
+}
+
+// parseOSC7Dir reads the directory from an OSC 7 file URI. Directories on other
+// hosts, e.g. after ssh, are ignored because they do not exist locally.
+func parseOSC7Dir(raw string) (string, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || !strings.EqualFold(parsed.Scheme, "file") || parsed.Path == "" {
+		return "", false
+	}
+	if !isLocalHost(parsed.Host) {
+		return "", false
+	}
+	dir := parsed.Path
+	// Windows 上形如 /C:/Users/me
+	if runtime.GOOS == "windows" && len(dir) >= 3 && dir[0] == '/' && dir[2] == ':' {
+		dir = dir[1:]
+	}
+	dir = filepath.Clean(filepath.FromSlash(dir))
+	if !filepath.IsAbs(dir) {
+		return "", false
+	}
+	return dir, true
+}
+
+func isLocalHost(host string) bool {
+	host = strings.ToLower(host)
+	if host == "" || host == "localhost" {
+		return true
+	}
+	name, err := os.Hostname()
+	if err != nil {
+		return false
+	}
+	name = strings.ToLower(name)
+	// 部分 shell 上报的是完整域名，部分只有短主机名
+	short, _, _ := strings.Cut(name, ".")
+	hostShort, _, _ := strings.Cut(host, ".")
+	return host == name || hostShort == short
+}
+
+// CurrentDir returns the directory the shell last reported through OSC 7,
+// falling back to the working directory the session started in when the shell
+// has no such integration.
+func (s *Session) CurrentDir() string {
+	if dir := s.cwd.current(); dir != "" {
+		return dir
+	}
+	return s.WorkingDir()
+}
@@ -0,0 +1,66 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCwdTrackerObserve(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("OSC 7 paths in this test are POSIX paths")
+	}
+
+	var tracker cwdTracker
+	tracker.observe([]byte("$ ls\r\nREADME.md\r\n"))
+	if dir := tracker.current(); dir != "" {
+		t.Fatalf("expected no directory without OSC 7, got %q", dir)
+	}
+
+	tracker.observe([]byte("\x1b]7;file://localhost/home/me/project\x07$ "))
+	if dir := tracker.current(); dir != "/home/me/project" {
+		t.Fatalf("expected /home/me/project, got %q", dir)
+	}
+
+	// 序列跨 chunk，且路径经过百分号编码，使用 ESC \ 结束
+	tracker.observe([]byte("\x1b]"))
+	tracker.observe([]byte("7;file:///tmp/my%20dir"))
+	tracker.observe([]byte("\x1b\\$ "))
+	if dir := tracker.current(); dir != "/tmp/my dir" {
+		t.Fatalf("expected /tmp/my dir, got %q", dir)
+	}
+
+	// 其他主机（ssh 之后）与非 file URI 都不覆盖本地目录
+	tracker.observe([]byte("\x1b]7;file://remote-host.invalid/srv/app\x07"))
+	tracker.observe([]byte("\x1b]7;http://localhost/etc\x07"))
+	if dir := tracker.current(); dir != "/tmp/my dir" {
+		t.Fatalf("expected remote and invalid URIs to be ignored, got %q", dir)
+	}
+
+	if host, err := os.Hostname(); err == nil && host != "" {
+		tracker.observe([]byte("\x1b]7;file://" + host + "/var/log\x07"))
+		if dir := tracker.current(); dir != filepath.Clean("/var/log") {
+			t.Fatalf("expected local hostname to be accepted, got %q", dir)
+		}
+	}
+
+	tracker.reset()
+	if dir := tracker.current(); dir != "" {
+		t.Fatalf("expected reset to clear the directory, got %q", dir)
+	}
+}
+
+func TestSessionCurrentDirFallback(t *testing.T) {
+	session := newTestSession(t, SessionParams{WorkingDir: "/tmp/start"})
+	if dir := session.CurrentDir(); dir != "/tmp/start" {
+		t.Fatalf("expected fallback to working dir, got %q", dir)
+	}
+	session.cwd.observe([]byte("\x1b]7;file:///tmp/elsewhere\x07"))
+	if dir := session.CurrentDir(); dir != filepath.Clean("/tmp/elsewhere") {
+		t.Fatalf("expected tracked directory, got %q", dir)
+	}
+	if snapshot := session.Snapshot(); snapshot.CurrentDir != session.CurrentDir() || snapshot.WorkingDir != "/tmp/start" {
+		t.Fatalf("unexpected snapshot dirs: %q / %q", snapshot.WorkingDir, snapshot.CurrentDir)
+	}
+}
@@ -0,0 +1,166 @@
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"code-kanban/utils/ai_assistant2"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// detectorGoldenDir 检测器黄金测试的 fixture 目录，子目录名即助手类型，如 claude-code、codex。
+// 每个录制（asciinema .cast 或事件记录导出的 .jsonl）配一个同名 .golden.json，
+// 标注回放时 tracker 应产出的状态序列。新增助手时建对应子目录并补充典型场景的录制。
+//
+// fixture 应来自真实会话：用 asciinema rec 录制 CLI，或开启 terminal.recordEvents 后通过
+// GET /terminals/{sessionId}/events-export 导出；source 为 synthetic 的是按界面手工构造的
+// 过渡 fixture，补上同一场景的真实录制后应删除。
+const detectorGoldenDir = "testdata/detector"
+
+// detectorGolden 是 .golden.json 的内容；Rows/Cols 只用于 .jsonl，.cast 使用录制时的尺寸
+type detectorGolden struct {
+	Description string `json:"description"`
+	// Source captured 为真实录制，synthetic 为手工构造
+	Source string        `json:"source"`
+	Rows   int           `json:"rows,omitempty"`
+	Cols   int           `json:"cols,omitempty"`
+	States []types.State `json:"states"`
+}
+
+func TestDetectorGolden(t *testing.T) {
+	dirs, err := os.ReadDir(detectorGoldenDir)
+	if err != nil {
+		t.Fatalf("read fixtures: %v", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		assistant := types.AssistantType(dir.Name())
+		if ai_assistant2.NewDetector(assistant) == nil {
+			t.Fatalf("fixture directory %q has no status detector", dir.Name())
+		}
+		recordings, err := filepath.Glob(filepath.Join(detectorGoldenDir, dir.Name(), "*"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, path := range recordings {
+			ext := filepath.Ext(path)
+			if ext != ".cast" && ext != ".jsonl" {
+				continue
+			}
+			name := dir.Name() + "/" + strings.TrimSuffix(filepath.Base(path), ext)
+			t.Run(name, func(t *testing.T) {
+				runDetectorGolden(t, assistant, path)
+			})
+		}
+	}
+}
+
+func runDetectorGolden(t *testing.T, assistant types.AssistantType, path string) {
+	goldenPath := strings.TrimSuffix(path, filepath.Ext(path)) + ".golden.json"
+	data, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("read golden: %v", err)
+	}
+	var golden detectorGolden
+	if err := json.Unmarshal(data, &golden); err != nil {
+		t.Fatalf("parse %s: %v", goldenPath, err)
+	}
+
+	if golden.Source != "captured" && golden.Source != "synthetic" {
+		t.Fatalf("%s: source must be captured or synthetic, got %q", goldenPath, golden.Source)
+	}
+	detector := ai_assistant2.NewDetector(assistant)
+	var transitions []ai_assistant2.StateTransition
+	switch filepath.Ext(path) {
+	case ".cast":
+		cast, err := ai_assistant2.LoadCastFile(path)
+		if err != nil {
+			t.Fatalf("load cast: %v", err)
+		}
+		transitions = ai_assistant2.ReplayCast(detector, cast)
+	case ".jsonl":
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		chunks, err := loadRecordedOutput(file)
+		if err != nil {
+			t.Fatalf("load recording: %v", err)
+		}
+		rows, cols := golden.Rows, golden.Cols
+		if rows <= 0 || cols <= 0 {
+			rows, cols = ai_assistant2.DefaultReplayRows, ai_assistant2.DefaultReplayCols
+		}
+		transitions = ai_assistant2.ReplayTimedChunks(detector, chunks, rows, cols)
+	}
+
+	states := make([]types.State, 0, len(transitions))
+	for _, transition := range transitions {
+		states = append(states, transition.To)
+	}
+	if !slices.Equal(states, golden.States) {
+		actual, _ := json.MarshalIndent(transitions, "", "  ")
+		t.Fatalf("state sequence mismatch\nwant: %v\ngot:  %v\ntransitions: %s", golden.States, states, actual)
+	}
+}
+
+// loadRecordedOutput reads an event recording exported as JSONL and returns its
+// data events as replay chunks, timed relative to the first event.
+func loadRecordedOutput(r io.Reader) ([]ai_assistant2.ReplayChunk, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	chunks := make([]ai_assistant2.ReplayChunk, 0)
+	var start time.Time
+	for line := 1; scanner.Scan(); line++ {
+		raw := bytes.TrimSpace(scanner.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+		var event RecordedEvent
+		if err := json.Unmarshal(raw, &event); err != nil {
+			return nil, fmt.Errorf("recording: line %d: %w", line, err)
+		}
+		if start.IsZero() {
+			start = event.Time
+		}
+		if event.Type != StreamEventData || len(event.Data) == 0 {
+			continue
+		}
+		chunks = append(chunks, ai_assistant2.ReplayChunk{Offset: event.Time.Sub(start), Data: event.Data})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return chunks, nil
+}
+
+func TestLoadRecordedOutput(t *testing.T) {
+	input := strings.Join([]string{
+		`{"ts":"2000-01-01T00:00:00Z","type":"metadata","metadata":{"title":"shell"}}`,
+		`{"ts":"2000-01-01T00:00:01.5Z","type":"data","data":"aGVsbG8="}`,
+		``,
+		`{"ts":"2000-01-01T00:00:02Z","type":"exit","error":"done"}`,
+	}, "\n")
+	chunks, err := loadRecordedOutput(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("loadRecordedOutput failed: %v", err)
+	}
+	if len(chunks) != 1 || string(chunks[0].Data) != "hello" || chunks[0].Offset.Seconds() != 1.5 {
+		t.Fatalf("unexpected chunks: %+v", chunks)
+	}
+	if _, err := loadRecordedOutput(strings.NewReader("not json\n")); err == nil {
+		t.Fatalf("expected error for invalid line")
+	}
+}
@@ -0,0 +1,123 @@
+package terminal
+
+import (
+	"slices"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// sessionStats 记录事件分发计数，用于定位慢订阅者导致的画面卡顿
+type sessionStats struct {
+	broadcasts      atomic.Int64
+	drops           atomic.Int64
+	assistantDrops  atomic.Int64
+	lastBroadcastAt atomic.Int64
+	lastDropAt      atomic.Int64
+	lastOutputAt    atomic.Int64
+	outputLines     atomic.Int64
+	// assistantCalibrations/assistantRechecks 进程活动与 AI 状态不一致时的降级与复检次数
+	assistantCalibrations atomic.Int64
+	assistantRechecks     atomic.Int64
+	// recentDrops 最近几分钟内的丢弃数，用于健康检查
+	recentDrops recentCounter
+}
+
+// SubscriberDiagnostics describes the backlog of one stream subscriber.
+type SubscriberDiagnostics struct {
+	ID       string `json:"id"`
+	Backlog  int    `json:"backlog"`
+	Capacity int    `json:"capacity"`
+	// Events 订阅者过滤的事件类型，为空表示接收全部
+	Events          []StreamEventType `json:"events,omitempty"`
+	LastDeliveredAt *time.Time        `json:"lastDeliveredAt,omitempty"`
+	// FullSince channel 持续已满、事件被丢弃的起始时间，正常消费时为空
+	FullSince *time.Time `json:"fullSince,omitempty"`
+}
+
+// SessionDiagnostics is a lightweight snapshot of the session internals. Unlike
+// DebugInfo it never includes terminal output.
+type SessionDiagnostics struct {
+	SessionID       string                  `json:"sessionId"`
+	Status          SessionStatus           `json:"status"`
+	SubscriberCount int                     `json:"subscriberCount"`
+	Subscribers     []SubscriberDiagnostics `json:"subscribers"`
+	BroadcastCount  int64                   `json:"broadcastCount"`
+	DroppedCount    int64                   `json:"droppedCount"`
+	LastBroadcastAt *time.Time              `json:"lastBroadcastAt,omitempty"`
+	LastDropAt      *time.Time              `json:"lastDropAt,omitempty"`
+	LastOutputAt    *time.Time              `json:"lastOutputAt,omitempty"`
+	// AssistantBacklog/AssistantDropped 反映 AI 状态检测队列的积压与丢弃
+	AssistantBacklog  int    `json:"assistantBacklog"`
+	AssistantDropped  int64  `json:"assistantDropped"`
+	AIChunkCount      int64  `json:"aiChunkCount"`
+	TrackingMode      string `json:"trackingMode,omitempty"`
+	AssistantTracking bool   `json:"assistantTracking"`
+	// AssistantCalibrations/AssistantRechecks 依据进程活动校准 AI 状态的次数
+	AssistantCalibrations int64 `json:"assistantCalibrations"`
+	AssistantRechecks     int64 `json:"assistantRechecks"`
+	// Output 输出行数与屏幕内容统计，用于判断会话活跃度
+	Output OutputStats `json:"output"`
+}
+
+func (st *sessionStats) recordBroadcast(now time.Time, dropped int) {
+	st.broadcasts.Add(1)
+	st.lastBroadcastAt.Store(now.UnixNano())
+	if dropped > 0 {
+		st.drops.Add(int64(dropped))
+		st.lastDropAt.Store(now.UnixNano())
+		st.recentDrops.add(now, int64(dropped))
+	}
+}
+
+func unixNanoTime(value int64) *time.Time {
+	if value == 0 {
+		return nil
+	}
+	t := time.Unix(0, value)
+	return &t
+}
+
+// Diagnostics returns subscriber backlogs, broadcast/drop counters and tracker state.
+func (s *Session) Diagnostics() *SessionDiagnostics {
+	subscribers := s.snapshotSubscribers()
+	sort.Slice(subscribers, func(i, j int) bool { return subscribers[i].id < subscribers[j].id })
+	subs := make([]SubscriberDiagnostics, 0, len(subscribers))
+	for _, sub := range subscribers {
+		diag := SubscriberDiagnostics{
+			ID:              sub.id,
+			Backlog:         len(sub.ch),
+			Capacity:        cap(sub.ch),
+			LastDeliveredAt: unixNanoTime(sub.lastDeliveredAt.Load()),
+			FullSince:       unixNanoTime(sub.fullSince.Load()),
+		}
+		for eventType := range sub.types {
+			diag.Events = append(diag.Events, eventType)
+		}
+		slices.Sort(diag.Events)
+		subs = append(subs, diag)
+	}
+
+	info := &SessionDiagnostics{
+		SessionID:             s.id,
+		Status:                s.Status(),
+		SubscriberCount:       len(subs),
+		Subscribers:           subs,
+		BroadcastCount:        s.stats.broadcasts.Load(),
+		DroppedCount:          s.stats.drops.Load(),
+		LastBroadcastAt:       unixNanoTime(s.stats.lastBroadcastAt.Load()),
+		LastDropAt:            unixNanoTime(s.stats.lastDropAt.Load()),
+		LastOutputAt:          unixNanoTime(s.stats.lastOutputAt.Load()),
+		AssistantBacklog:      len(s.assistantOutputCh),
+		AssistantDropped:      s.stats.assistantDrops.Load(),
+		Output:                s.OutputStats(),
+		AssistantCalibrations: s.stats.assistantCalibrations.Load(),
+		AssistantRechecks:     s.stats.assistantRechecks.Load(),
+	}
+	if s.assistantTracker != nil {
+		info.AIChunkCount = s.assistantTracker.ChunkCount()
+		info.TrackingMode = string(s.assistantTracker.TrackingMode())
+		info.AssistantTracking = s.assistantTracker.AssistantType() != ""
+	}
+	return info
+}
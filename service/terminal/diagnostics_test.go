@@ -0,0 +1,67 @@
+package terminal
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSessionDiagnosticsSlowSubscriber(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	stream, err := session.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+
+	total := subscriberBufferSize + 5
+	for i := 0; i < total; i++ {
+		session.broadcast(StreamEvent{Type: StreamEventData, Data: []byte("x")})
+	}
+
+	info := session.Diagnostics()
+	if info.SubscriberCount != 1 || len(info.Subscribers) != 1 {
+		t.Fatalf("expected one subscriber, got %+v", info.Subscribers)
+	}
+	if sub := info.Subscribers[0]; sub.Backlog != subscriberBufferSize || sub.Capacity != subscriberBufferSize {
+		t.Fatalf("expected full backlog, got %+v", sub)
+	}
+	if info.BroadcastCount != int64(total) || info.DroppedCount != 5 {
+		t.Fatalf("unexpected counters broadcast=%d dropped=%d", info.BroadcastCount, info.DroppedCount)
+	}
+	if info.LastBroadcastAt == nil || info.LastDropAt == nil {
+		t.Fatalf("expected broadcast and drop timestamps")
+	}
+	if info.LastOutputAt != nil {
+		t.Fatalf("expected no output before start")
+	}
+}
+
+func TestSessionOutputStats(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 10, Cols: 20, ScrollbackLimit: 4096})
+	stats := session.OutputStats()
+	if stats.TotalLines != 0 || stats.ScreenLines != 0 || stats.LastOutputAt != nil || stats.SecondsSinceOutput != nil {
+		t.Fatalf("expected empty stats, got %+v", stats)
+	}
+
+	session.appendScrollback([]byte("one\r\n\r\ntwo\r\n"))
+	session.stats.outputLines.Add(3)
+	session.stats.lastOutputAt.Store(time.Now().Add(-5 * time.Second).UnixNano())
+	stats = session.OutputStats()
+	if stats.TotalLines != 3 || stats.ScreenLines != 2 {
+		t.Fatalf("unexpected line counts %+v", stats)
+	}
+	if stats.LastOutputAt == nil || stats.SecondsSinceOutput == nil || *stats.SecondsSinceOutput < 5 {
+		t.Fatalf("unexpected output recency %+v", stats)
+	}
+
+	// 缓存命中时不重新渲染
+	session.outputStatsCache.screenLines = 42
+	if got := session.OutputStats().ScreenLines; got != 42 {
+		t.Fatalf("expected cached screen lines, got %d", got)
+	}
+	session.appendScrollback([]byte("\x1b[2J\x1b[Hthree"))
+	if got := session.OutputStats().ScreenLines; got != 1 {
+		t.Fatalf("expected re-rendered screen after new output, got %d", got)
+	}
+}
@@ -0,0 +1,16 @@
+package terminal
+
+// ptyEcho is the echo related termios state of a PTY.
+type ptyEcho struct {
+	// echoOff 前台程序关闭了 ECHO，如密码输入
+	echoOff bool
+	// canonical 行缓冲模式；readline 与 TUI 程序使用 raw 模式，同样关闭 ECHO 但自行绘制输入
+	canonical bool
+}
+
+// hidesInput reports whether typed input is hidden from the screen, such as a
+// password prompt. Raw-mode programs clear ECHO too but draw the input
+// themselves, so only canonical mode counts.
+func (e ptyEcho) hidesInput() bool {
+	return e.echoOff && e.canonical
+}
@@ -0,0 +1,41 @@
+//go:build !windows
+
+package terminal
+
+import (
+	"github.com/charmbracelet/x/termios"
+	"github.com/charmbracelet/x/xpty"
+	"golang.org/x/sys/unix"
+)
+
+// ptyEchoState reads the echo state of the program in the PTY. ok is false when
+// the termios state cannot be read.
+func ptyEchoState(device xpty.Pty) (state ptyEcho, ok bool) {
+	unixPty, isUnix := device.(*xpty.UnixPty)
+	if !isUnix {
+		return ptyEcho{}, false
+	}
+	// 通过 SyscallConn 读取 fd，避免 Fd() 把文件切换为阻塞模式影响读取协程
+	file := unixPty.Slave()
+	if file == nil {
+		file = unixPty.Master()
+	}
+	if file == nil {
+		return ptyEcho{}, false
+	}
+	conn, err := file.SyscallConn()
+	if err != nil {
+		return ptyEcho{}, false
+	}
+	var term *unix.Termios
+	var termErr error
+	if err := conn.Control(func(fd uintptr) {
+		term, termErr = termios.GetTermios(int(fd))
+	}); err != nil || termErr != nil || term == nil {
+		return ptyEcho{}, false
+	}
+	return ptyEcho{
+		echoOff:   term.Lflag&unix.ECHO == 0,
+		canonical: term.Lflag&unix.ICANON != 0,
+	}, true
+}
@@ -0,0 +1,58 @@
+//go:build !windows
+
+package terminal
+
+import (
+	"testing"
+
+	"github.com/charmbracelet/x/termios"
+	"github.com/charmbracelet/x/xpty"
+)
+
+func TestPtyEchoState(t *testing.T) {
+	device, err := xpty.NewPty(80, 24)
+	if err != nil {
+		t.Skipf("pty unavailable: %v", err)
+	}
+	defer device.Close()
+	unixPty, ok := device.(*xpty.UnixPty)
+	if !ok {
+		t.Skip("not a unix pty")
+	}
+	setLflag := func(lflag map[termios.L]bool) {
+		t.Helper()
+		conn, err := unixPty.Slave().SyscallConn()
+		if err != nil {
+			t.Fatalf("syscall conn: %v", err)
+		}
+		var setErr error
+		if err := conn.Control(func(fd uintptr) {
+			setErr = termios.SetTermios(int(fd), 0, 0, nil, nil, nil, nil, lflag)
+		}); err != nil || setErr != nil {
+			t.Fatalf("set termios: %v %v", err, setErr)
+		}
+	}
+
+	cases := []struct {
+		name       string
+		lflag      map[termios.L]bool
+		echoOff    bool
+		hidesInput bool
+	}{
+		{name: "cooked", lflag: map[termios.L]bool{termios.ECHO: true, termios.ICANON: true}},
+		// 密码输入：关闭回显，仍是行缓冲模式
+		{name: "password prompt", lflag: map[termios.L]bool{termios.ECHO: false, termios.ICANON: true}, echoOff: true, hidesInput: true},
+		// raw 模式的 TUI 自己绘制输入，EchoOff 仍如实报告，但输入不算隐藏
+		{name: "raw mode", lflag: map[termios.L]bool{termios.ECHO: false, termios.ICANON: false}, echoOff: true},
+	}
+	for _, tc := range cases {
+		setLflag(tc.lflag)
+		state, ok := ptyEchoState(device)
+		if !ok {
+			t.Fatalf("%s: termios not readable", tc.name)
+		}
+		if state.echoOff != tc.echoOff || state.hidesInput() != tc.hidesInput {
+			t.Fatalf("%s: got echoOff=%v hidesInput=%v", tc.name, state.echoOff, state.hidesInput())
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build windows
+
+package terminal
+
+import "github.com/charmbracelet/x/xpty"
+
+// ptyEchoState ConPTY 不暴露 termios，Windows 上无法感知回显状态
+func ptyEchoState(xpty.Pty) (state ptyEcho, ok bool) {
+	return ptyEcho{}, false
+}
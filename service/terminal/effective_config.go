@@ -0,0 +1,200 @@
+package terminal
+
+import (
+	"sort"
+	"time"
+
+	"code-kanban/utils"
+)
+
+// EffectiveConfig is the terminal configuration in use after defaults were
+// applied and invalid values were reset, together with per-session overrides.
+type EffectiveConfig struct {
+	// Shell 默认 shell 解析后的实际命令，ShellError 为解析失败的原因
+	Shell      []string            `json:"shell"`
+	ShellError string              `json:"shellError,omitempty"`
+	Shells     []utils.ShellOption `json:"shells"`
+	Encoding   string              `json:"encoding"`
+	Term       string              `json:"term"`
+	// IdleTimeoutSeconds 全局空闲超时，0 表示不自动清理
+	IdleTimeoutSeconds    int64 `json:"idleTimeoutSeconds"`
+	MaxSessionsPerProject int   `json:"maxSessionsPerProject"`
+	// ScrollbackBytes 实际生效的 scrollback 上限，未启用 scrollback 时为 0
+	ScrollbackEnabled         bool                          `json:"scrollbackEnabled"`
+	ScrollbackBytes           int                           `json:"scrollbackBytes"`
+	ScrollbackSpill           bool                          `json:"scrollbackSpill"`
+	ScrollbackCollapse        bool                          `json:"scrollbackCollapse"`
+	ScrollbackTotalBytes      int                           `json:"scrollbackTotalBytes"`
+	BinaryOutput              string                        `json:"binaryOutput,omitempty"`
+	AIAssistantStatus         utils.AIAssistantStatusConfig `json:"aiAssistantStatus"`
+	AIDetection               utils.AIDetectionParams       `json:"aiDetection"`
+	AIDetectionDiagnostics    bool                          `json:"aiDetectionDiagnostics"`
+	RenameTitleEachCommand    bool                          `json:"renameTitleEachCommand"`
+	AutoCreateTaskOnStartWork bool                          `json:"autoCreateTaskOnStartWork"`
+	DetectLinks               bool                          `json:"detectLinks"`
+	ConfirmMultilineInput     bool                          `json:"confirmMultilineInput"`
+	CaptureRawOutput          bool                          `json:"captureRawOutput"`
+	CloseOnMaxLifetime        bool                          `json:"closeOnMaxLifetime"`
+	QuotaMaxSessions          int                           `json:"quotaMaxSessions"`
+	QuotaMaxMemoryBytes       uint64                        `json:"quotaMaxMemoryBytes"`
+	RecordEvents              string                        `json:"recordEvents,omitempty"`
+	OutputLog                 bool                          `json:"outputLog"`
+	Screenshots               bool                          `json:"screenshots"`
+	Webhook                   bool                          `json:"webhook"`
+	// ApprovalTimeout 全局审批超时策略，ApprovalTimeoutByAssistant 为按助手类型的覆盖
+	ApprovalTimeout            EffectiveApprovalTimeout            `json:"approvalTimeout"`
+	ApprovalTimeoutByAssistant map[string]EffectiveApprovalTimeout `json:"approvalTimeoutByAssistant,omitempty"`
+	// CompletionSettleMs 完成通知前 waiting_input 需保持的毫秒数，负数表示立即通知
+	CompletionSettleMs int64 `json:"completionSettleMs"`
+	// 全局规则数量，规则内容通过各自的接口查看
+	OutputTriggers int `json:"outputTriggers"`
+	Macros         int `json:"macros"`
+	TitleRules     int `json:"titleRules"`
+	HighlightRules int `json:"highlightRules"`
+	AlertRules     int `json:"alertRules"`
+	OutputFilters  int `json:"outputFilters"`
+	// Sessions 运行中会话实际使用的设置
+	Sessions []SessionEffectiveConfig `json:"sessions"`
+}
+
+// EffectiveApprovalTimeout is an approval timeout policy; TimeoutSeconds 0
+// means disabled.
+type EffectiveApprovalTimeout struct {
+	TimeoutSeconds int64  `json:"timeoutSeconds"`
+	Action         string `json:"action"`
+}
+
+func effectiveApprovalTimeout(policy ApprovalTimeoutPolicy) EffectiveApprovalTimeout {
+	return EffectiveApprovalTimeout{TimeoutSeconds: int64(policy.Timeout.Seconds()), Action: policy.Action}
+}
+
+// SessionEffectiveConfig lists the settings of one session that may differ from
+// the global configuration.
+type SessionEffectiveConfig struct {
+	SessionID string `json:"sessionId"`
+	ProjectID string `json:"projectId"`
+	Title     string `json:"title"`
+	// ShellName 创建时选择的命名 shell，默认 shell 为空
+	ShellName string `json:"shellName,omitempty"`
+	Encoding  string `json:"encoding"`
+	Term      string `json:"term"`
+	// IdleTimeoutSeconds 实际生效的空闲超时，IdleTimeoutOverridden 表示来自会话级设置
+	IdleTimeoutSeconds    int64         `json:"idleTimeoutSeconds"`
+	IdleTimeoutOverridden bool          `json:"idleTimeoutOverridden"`
+	MaxLifetimeSeconds    int64         `json:"maxLifetimeSeconds"`
+	RestartPolicy         RestartPolicy `json:"restartPolicy"`
+	ScrollbackBytes       int           `json:"scrollbackBytes"`
+	DetectLinks           bool          `json:"detectLinks"`
+	RawCapture            bool          `json:"rawCapture"`
+	ClearBeforeSend       bool          `json:"clearBeforeSend"`
+	Nice                  int           `json:"nice"`
+	// 会话级规则数量
+	OutputTriggers int `json:"outputTriggers"`
+	AlertRules     int `json:"alertRules"`
+	OutputFilters  int `json:"outputFilters"`
+}
+
+// EffectiveConfig returns the configuration the manager and its sessions run with.
+func (m *Manager) EffectiveConfig() EffectiveConfig {
+	m.sessionMu.Lock()
+	cfg := m.cfg
+	triggers := len(m.globalTriggers)
+	macros := len(m.globalMacros)
+	m.sessionMu.Unlock()
+
+	result := EffectiveConfig{
+		Shells:                    m.ListShells(),
+		Encoding:                  cfg.Encoding,
+		Term:                      cfg.Term,
+		IdleTimeoutSeconds:        int64(max(cfg.IdleTimeout, 0).Seconds()),
+		MaxSessionsPerProject:     cfg.MaxSessionsPerProject,
+		ScrollbackEnabled:         cfg.ScrollbackEnabled,
+		ScrollbackSpill:           cfg.ScrollbackSpill.Enabled,
+		ScrollbackCollapse:        cfg.ScrollbackCollapse,
+		ScrollbackTotalBytes:      cfg.ScrollbackTotalBytes,
+		BinaryOutput:              cfg.BinaryOutput,
+		AIAssistantStatus:         cfg.AIAssistantStatus,
+		AIDetection:               cfg.AIAssistantStatus.DetectionParams(),
+		AIDetectionDiagnostics:    cfg.AIDetectionDiagnostics,
+		RenameTitleEachCommand:    cfg.RenameTitleEachCommand,
+		AutoCreateTaskOnStartWork: cfg.AutoCreateTaskOnStartWork,
+		DetectLinks:               cfg.DetectLinks,
+		ConfirmMultilineInput:     cfg.ConfirmMultilineInput,
+		CaptureRawOutput:          cfg.CaptureRawOutput,
+		CloseOnMaxLifetime:        cfg.CloseOnMaxLifetime,
+		QuotaMaxSessions:          cfg.QuotaMaxSessions,
+		QuotaMaxMemoryBytes:       cfg.QuotaMaxMemoryBytes,
+		RecordEvents:              cfg.RecordEvents,
+		OutputLog:                 cfg.OutputLog.Dir != "",
+		Screenshots:               cfg.Screenshots.Dir != "",
+		Webhook:                   cfg.Webhook.URL != "",
+		ApprovalTimeout:           effectiveApprovalTimeout(cfg.ApprovalTimeout.Default),
+		CompletionSettleMs:        cfg.CompletionSettleDelay.Milliseconds(),
+		OutputTriggers:            triggers,
+		Macros:                    macros,
+		TitleRules:                len(m.titleRules),
+		HighlightRules:            len(m.highlightRules),
+		AlertRules:                len(m.alertRules),
+		OutputFilters:             len(m.outputFilters),
+		Sessions:                  make([]SessionEffectiveConfig, 0),
+	}
+	if len(cfg.ApprovalTimeout.Assistants) > 0 {
+		result.ApprovalTimeoutByAssistant = make(map[string]EffectiveApprovalTimeout, len(cfg.ApprovalTimeout.Assistants))
+		for assistant, policy := range cfg.ApprovalTimeout.Assistants {
+			result.ApprovalTimeoutByAssistant[assistant] = effectiveApprovalTimeout(policy)
+		}
+	}
+	if cfg.ScrollbackEnabled && cfg.ScrollbackBytes > 0 {
+		result.ScrollbackBytes = cfg.ScrollbackBytes
+	}
+	if command, err := m.shellCommand(""); err != nil {
+		result.ShellError = err.Error()
+	} else {
+		result.Shell = command
+	}
+
+	m.sessions.Range(func(_ string, session *Session) bool {
+		result.Sessions = append(result.Sessions, session.effectiveConfig(cfg.IdleTimeout))
+		return true
+	})
+	sort.Slice(result.Sessions, func(i, j int) bool {
+		return result.Sessions[i].SessionID < result.Sessions[j].SessionID
+	})
+	return result
+}
+
+func (s *Session) effectiveConfig(globalIdle time.Duration) SessionEffectiveConfig {
+	s.mu.RLock()
+	title := s.title
+	s.mu.RUnlock()
+	s.triggerMu.Lock()
+	triggers := len(s.sessionTriggers)
+	s.triggerMu.Unlock()
+	s.rawCapture.mu.Lock()
+	rawCapture := s.rawCapture.enabled
+	s.rawCapture.mu.Unlock()
+	s.scrollMu.RLock()
+	scrollbackLimit := s.scrollbackLimit
+	s.scrollMu.RUnlock()
+
+	return SessionEffectiveConfig{
+		SessionID:             s.id,
+		ProjectID:             s.projectID,
+		Title:                 title,
+		ShellName:             s.shellName,
+		Encoding:              s.Encoding(),
+		Term:                  s.term,
+		IdleTimeoutSeconds:    int64(s.EffectiveIdleTimeout(globalIdle).Seconds()),
+		IdleTimeoutOverridden: s.IdleTimeoutOverride() != 0,
+		MaxLifetimeSeconds:    int64(s.maxLifetime.Seconds()),
+		RestartPolicy:         s.restart.policy,
+		ScrollbackBytes:       scrollbackLimit,
+		DetectLinks:           s.detectLinks.Load(),
+		RawCapture:            rawCapture,
+		ClearBeforeSend:       s.clearBeforeSend.Load(),
+		Nice:                  s.nice,
+		OutputTriggers:        triggers,
+		AlertRules:            len(s.AlertRules()),
+		OutputFilters:         len(s.OutputFilters()),
+	}
+}
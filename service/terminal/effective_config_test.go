@@ -0,0 +1,64 @@
+package terminal
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils"
+)
+
+func TestManagerEffectiveConfig(t *testing.T) {
+	mgr := NewManager(Config{Encoding: "bogus", IdleTimeout: time.Minute}, zap.NewNop())
+	for _, params := range []SessionParams{
+		{ID: "s2", ProjectID: "p1", IdleTimeoutOverride: 5 * time.Minute},
+		{ID: "s1", ProjectID: "p1", Encoding: "gbk"},
+	} {
+		if err := mgr.addSession(newTestSession(t, params)); err != nil {
+			t.Fatalf("addSession failed: %v", err)
+		}
+	}
+
+	cfg := mgr.EffectiveConfig()
+	// 非法编码回退为默认值，未配置的项填充默认值
+	if cfg.Encoding != "utf-8" || cfg.Term != DefaultTermType || cfg.IdleTimeoutSeconds != 60 {
+		t.Fatalf("unexpected global values %+v", cfg)
+	}
+	if cfg.CompletionSettleMs != defaultCompletionSettleDelay.Milliseconds() || cfg.ScrollbackBytes != 0 {
+		t.Fatalf("unexpected defaults %+v", cfg)
+	}
+	if len(cfg.Sessions) != 2 || cfg.Sessions[0].SessionID != "s1" || cfg.Sessions[1].SessionID != "s2" {
+		t.Fatalf("expected sessions sorted by id, got %+v", cfg.Sessions)
+	}
+	if s1 := cfg.Sessions[0]; s1.Encoding != "gbk" || s1.IdleTimeoutSeconds != 60 || s1.IdleTimeoutOverridden {
+		t.Fatalf("unexpected s1 config %+v", s1)
+	}
+	if s2 := cfg.Sessions[1]; s2.IdleTimeoutSeconds != 300 || !s2.IdleTimeoutOverridden {
+		t.Fatalf("unexpected s2 config %+v", s2)
+	}
+}
+
+func TestManagerEffectiveConfigDetectionPreset(t *testing.T) {
+	mgr := NewManager(Config{AIAssistantStatus: utils.AIAssistantStatusConfig{
+		Preset:    utils.AIDetectionPresetConservative,
+		Detection: utils.AIDetectionParams{DebounceMs: 300},
+	}}, zap.NewNop())
+
+	// 预设展开为具体参数，个别字段仍可覆盖
+	want := utils.AIDetectionParams{ProcessIntervalMs: 200, IdleCheckIntervalMs: 1000, WorkingExitMs: 2000, DebounceMs: 300}
+	if got := mgr.EffectiveConfig().AIDetection; got != want {
+		t.Fatalf("unexpected detection params %+v", got)
+	}
+
+	session := newTestSession(t, SessionParams{ID: "s1"})
+	session.applyDetectionParams(want)
+	if session.assistantDebounce != 300*time.Millisecond {
+		t.Fatalf("expected debounce from config, got %s", session.assistantDebounce)
+	}
+
+	unknown := utils.AIAssistantStatusConfig{Preset: "fast"}
+	if got := unknown.DetectionParams(); got.ProcessIntervalMs != 100 || got.DebounceMs != 500 {
+		t.Fatalf("expected unknown preset to fall back to balanced, got %+v", got)
+	}
+}
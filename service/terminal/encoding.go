@@ -0,0 +1,108 @@
+package terminal
+
+import (
+	"bytes"
+
+	"go.uber.org/zap"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/transform"
+)
+
+// Encoding returns the name of the encoding used for PTY input and output.
+func (s *Session) Encoding() string {
+	s.decodeMu.Lock()
+	defer s.decodeMu.Unlock()
+	return s.encName
+}
+
+// SetEncoding switches the session encoding while it runs. Later output and
+// input use the new encoding; the decoder state carried across chunks is
+// dropped so bytes buffered by the old decoder do not leak into the new one.
+//
+// With redecode the scrollback is decoded again with the new encoding and
+// broadcast with StreamEventEncodingChanged so clients can redraw. Output
+// decoded by a non-UTF-8 encoding is first encoded back with that encoding,
+// which cannot restore bytes it failed to decode; output received while the
+// session was UTF-8 is kept verbatim and converts losslessly. Without redecode
+// the event carries no data. It reports whether the encoding changed.
+func (s *Session) SetEncoding(name string, redecode bool) (bool, error) {
+	enc, encName, err := resolveEncoding(name)
+	if err != nil {
+		return false, err
+	}
+
+	s.decodeMu.Lock()
+	prevEnc, prevName := s.encoding, s.encName
+	if encName == prevName {
+		s.decodeMu.Unlock()
+		return false, nil
+	}
+	s.encoding = enc
+	s.encName = encName
+	s.decoder = nil
+	s.decodeTail = nil
+	var data []byte
+	if redecode {
+		data = s.redecodeScrollback(prevEnc, enc)
+	}
+	s.decodeMu.Unlock()
+
+	s.logger.Info("terminal session encoding changed",
+		zap.String("sessionId", s.id),
+		zap.String("from", prevName),
+		zap.String("to", encName),
+		zap.Bool("redecode", redecode),
+	)
+	s.broadcast(StreamEvent{Type: StreamEventEncodingChanged, Data: data})
+	return true, nil
+}
+
+// redecodeScrollback rewrites the scrollback chunks from the from encoding to
+// the to encoding, keeping sequence numbers and timestamps, and returns the
+// whole rewritten scrollback. A nil encoding means UTF-8.
+func (s *Session) redecodeScrollback(from, to encoding.Encoding) []byte {
+	s.scrollMu.Lock()
+	defer s.scrollMu.Unlock()
+
+	var decoder transform.Transformer
+	if to != nil {
+		decoder = to.NewDecoder()
+	}
+	var tail []byte
+	size := 0
+	for i, chunk := range s.scrollback {
+		raw := chunk
+		if from != nil {
+			if encoded, _, err := transform.Bytes(from.NewEncoder(), chunk); err == nil {
+				raw = encoded
+			}
+		}
+		decoded := raw
+		if decoder != nil {
+			decoded, tail = decodeChunk(decoder, tail, raw)
+		}
+		// 最后一个分片末尾的不完整字符原样保留
+		if i == len(s.scrollback)-1 && len(tail) > 0 {
+			decoded = append(decoded, tail...)
+		}
+		s.scrollback[i] = decoded
+		size += len(decoded)
+	}
+	s.scrollbackSize = size
+	s.scrollbackRev++
+	s.scrollbackRewrites++
+	s.trimScrollbackLocked()
+	return bytes.Join(s.scrollback, nil)
+}
+
+// SetSessionEncoding switches the encoding of a session; see Session.SetEncoding.
+func (m *Manager) SetSessionEncoding(sessionID, name string, redecode bool) (*Session, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := session.SetEncoding(name, redecode); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
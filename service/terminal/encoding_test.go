@@ -0,0 +1,79 @@
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+func TestSessionSetEncodingRedecodesScrollback(t *testing.T) {
+	session := newTestSession(t, SessionParams{ScrollbackLimit: 1024})
+	encoded, _, err := transform.Bytes(simplifiedchinese.GBK.NewEncoder(), []byte("中文输出"))
+	if err != nil {
+		t.Fatalf("encode gbk: %v", err)
+	}
+
+	// utf-8 会话收到 GBK 输出：字节原样保留，且在多字节字符中间截断
+	session.appendScrollback(session.NormalizeOutput(encoded[:3]))
+	session.appendScrollback(session.NormalizeOutput(encoded[3:]))
+
+	stream, err := session.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+
+	if _, err := session.SetEncoding("latin1", true); !errors.Is(err, ErrInvalidEncoding) {
+		t.Fatalf("expected ErrInvalidEncoding, got %v", err)
+	}
+	changed, err := session.SetEncoding("GBK", true)
+	if err != nil || !changed {
+		t.Fatalf("SetEncoding failed: changed=%v err=%v", changed, err)
+	}
+	if session.Encoding() != "gbk" {
+		t.Fatalf("expected encoding gbk, got %q", session.Encoding())
+	}
+	if got := string(bytes.Join(session.Scrollback(), nil)); got != "中文输出" {
+		t.Fatalf("unexpected redecoded scrollback %q", got)
+	}
+
+	select {
+	case event := <-stream.Events():
+		if event.Type != StreamEventEncodingChanged || string(event.Data) != "中文输出" {
+			t.Fatalf("unexpected event %s %q", event.Type, event.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for encoding-changed event")
+	}
+
+	if changed, _ := session.SetEncoding("gbk", true); changed {
+		t.Fatal("expected no change for the same encoding")
+	}
+	if got := string(session.prepareInput([]byte("中文"))); got != string(encoded[:4]) {
+		t.Fatalf("expected input encoded as gbk, got %q", got)
+	}
+}
+
+func TestSessionSetEncodingResetsDecoderState(t *testing.T) {
+	session := newTestSession(t, SessionParams{Encoding: "gbk"})
+	encoded, _, err := transform.Bytes(simplifiedchinese.GBK.NewEncoder(), []byte("中"))
+	if err != nil {
+		t.Fatalf("encode gbk: %v", err)
+	}
+	if out := session.NormalizeOutput(encoded[:1]); len(out) != 0 {
+		t.Fatalf("expected partial character held back, got %q", out)
+	}
+
+	if _, err := session.SetEncoding("utf-8", false); err != nil {
+		t.Fatalf("SetEncoding failed: %v", err)
+	}
+	// 旧解码器保留的半个字符不能拼到新编码的输出前
+	if got := string(session.NormalizeOutput([]byte("ok"))); got != "ok" {
+		t.Fatalf("expected clean utf-8 output, got %q", got)
+	}
+}
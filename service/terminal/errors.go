@@ -7,8 +7,50 @@ var (
 	ErrSessionNotFound = errors.New("terminal session not found")
 	// ErrSessionLimitReached indicates the project exceeded the allowed number of sessions.
 	ErrSessionLimitReached = errors.New("terminal session limit reached")
+	// ErrQuotaExceeded indicates the quota key exceeded its session or memory quota.
+	ErrQuotaExceeded = errors.New("terminal session quota exceeded")
 	// ErrInvalidSessionTitle indicates the provided title is invalid.
 	ErrInvalidSessionTitle = errors.New("terminal session title is invalid")
 	// ErrSessionTitleLocked indicates the session title cannot be changed because it's linked to a task.
 	ErrSessionTitleLocked = errors.New("terminal session title locked by task association")
+	// ErrEventRecordingDisabled indicates the session does not record stream events.
+	ErrEventRecordingDisabled = errors.New("terminal session event recording is disabled")
+	// ErrSessionNotRunning indicates the session is not in a state that allows the operation.
+	ErrSessionNotRunning = errors.New("terminal session is not running")
+	// ErrPauseUnsupported indicates pausing sessions is not supported on this platform.
+	ErrPauseUnsupported = errors.New("terminal session pause is not supported on this platform")
+	// ErrShellNotFound indicates neither the configured shell nor any platform default is executable.
+	ErrShellNotFound = errors.New("terminal shell not found")
+	// ErrUnknownShell indicates the requested shell name is not configured.
+	ErrUnknownShell = errors.New("terminal shell name is not configured")
+	// ErrInvalidOrderIndex indicates the provided tab order index is not a finite number.
+	ErrInvalidOrderIndex = errors.New("terminal session order index is invalid")
+	// ErrInvalidRestartPolicy indicates the restart policy is not one of never, on-failure or always.
+	ErrInvalidRestartPolicy = errors.New("invalid terminal restart policy")
+	// ErrScreenshotsDisabled indicates periodic screen snapshots are not enabled in the config.
+	ErrScreenshotsDisabled = errors.New("terminal screenshots are disabled")
+	// ErrRecordNotFound indicates the notification record does not exist.
+	ErrRecordNotFound = errors.New("notification record not found")
+	// ErrInvalidRecordNote indicates the note or tags of a record exceed the limits.
+	ErrInvalidRecordNote = errors.New("notification record note is invalid")
+	// ErrInvalidWaitPattern indicates the pattern to wait for is empty or not a valid regular expression.
+	ErrInvalidWaitPattern = errors.New("terminal wait pattern is invalid")
+	// ErrWaitTimeout indicates no output line matched the pattern before the timeout.
+	ErrWaitTimeout = errors.New("timed out waiting for terminal output")
+	// ErrNoAssistantTracked indicates the session has no AI assistant whose state could be corrected.
+	ErrNoAssistantTracked = errors.New("terminal session has no tracked ai assistant")
+	// ErrInvalidAssistantState indicates a corrected state that is unknown or equal to the current one.
+	ErrInvalidAssistantState = errors.New("ai assistant state is invalid")
+	// ErrNotAwaitingApproval indicates the assistant is not waiting for an approval.
+	ErrNotAwaitingApproval = errors.New("terminal session is not waiting for approval")
+	// ErrApprovalPromptUnrecognized indicates the approval prompt on screen cannot be answered automatically.
+	ErrApprovalPromptUnrecognized = errors.New("approval prompt is not recognized")
+	// ErrApprovalPromptChanged indicates the approval prompt on screen is not the one the record was created for.
+	ErrApprovalPromptChanged = errors.New("approval prompt has changed")
+	// ErrSessionReadOnly indicates the session was opened read-only and does not accept input.
+	ErrSessionReadOnly = errors.New("terminal session is read-only")
+	// ErrInvalidOutputSink indicates the output sink target is not a reachable socket or named pipe.
+	ErrInvalidOutputSink = errors.New("terminal output sink is invalid")
+	// ErrInvalidSessionSource indicates the session source is not one of manual, api, clone or playbook.
+	ErrInvalidSessionSource = errors.New("invalid terminal session source")
 )
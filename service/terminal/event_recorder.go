@@ -0,0 +1,227 @@
+package terminal
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// EventRecordOff 不记录事件
+	EventRecordOff = ""
+	// EventRecordMemory 在内存中保留事件，超过上限时丢弃最早的记录
+	EventRecordMemory = "memory"
+	// EventRecordFile 以 JSONL 追加写入文件，会话结束后文件保留供外部工具分析
+	EventRecordFile = "file"
+
+	eventRecordMemoryMaxBytes = 16 * 1024 * 1024
+	// defaultRecordEventsMaxFiles file 模式下保留事件文件的已关闭会话数
+	defaultRecordEventsMaxFiles = 50
+)
+
+// RecordedEvent is one JSONL line of the exported event stream. Data is encoded
+// as base64 by encoding/json.
+type RecordedEvent struct {
+	Time       time.Time           `json:"ts"`
+	Type       StreamEventType     `json:"type"`
+	Data       []byte              `json:"data,omitempty"`
+	Error      string              `json:"error,omitempty"`
+	Metadata   *SessionMetadata    `json:"metadata,omitempty"`
+	Links      []TerminalLink      `json:"links,omitempty"`
+	Highlights []TerminalHighlight `json:"highlights,omitempty"`
+	Alert      *OutputAlert        `json:"alert,omitempty"`
+}
+
+type eventRecorder struct {
+	mu     sync.Mutex
+	mode   string
+	closed bool
+
+	// memory
+	lines [][]byte
+	size  int
+	limit int
+
+	// file
+	file *os.File
+	path string
+}
+
+// newEventRecorder creates a recorder for the given mode, returning nil when
+// recording is disabled.
+func newEventRecorder(mode, dir, sessionID string) (*eventRecorder, error) {
+	switch mode {
+	case EventRecordMemory:
+		return &eventRecorder{mode: mode, limit: eventRecordMemoryMaxBytes}, nil
+	case EventRecordFile:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+		path := filepath.Join(dir, sessionID+".jsonl")
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, err
+		}
+		return &eventRecorder{mode: mode, file: file, path: path}, nil
+	default:
+		return nil, nil
+	}
+}
+
+func (r *eventRecorder) Record(event StreamEvent, at time.Time) {
+	if r == nil {
+		return
+	}
+	record := RecordedEvent{
+		Time:       at,
+		Type:       event.Type,
+		Data:       event.Data,
+		Metadata:   event.Metadata,
+		Links:      event.Links,
+		Highlights: event.Highlights,
+		Alert:      event.Alert,
+	}
+	if event.Err != nil {
+		record.Error = event.Err.Error()
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	line = append(line, '\n')
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.closed {
+		return
+	}
+	if r.file != nil {
+		_, _ = r.file.Write(line)
+		return
+	}
+	r.lines = append(r.lines, line)
+	r.size += len(line)
+	for r.size > r.limit && len(r.lines) > 0 {
+		r.size -= len(r.lines[0])
+		r.lines = r.lines[1:]
+	}
+}
+
+// WriteTo streams the recorded JSONL to w.
+func (r *eventRecorder) WriteTo(w io.Writer) (int64, error) {
+	if r == nil {
+		return 0, ErrEventRecordingDisabled
+	}
+
+	r.mu.Lock()
+	if r.mode == EventRecordFile {
+		path := r.path
+		var size int64
+		if r.file != nil {
+			if info, err := r.file.Stat(); err == nil {
+				size = info.Size()
+			}
+		}
+		r.mu.Unlock()
+
+		file, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		defer file.Close()
+		if size <= 0 {
+			return io.Copy(w, file)
+		}
+		return io.CopyN(w, file, size)
+	}
+	lines := append([][]byte(nil), r.lines...)
+	r.mu.Unlock()
+
+	var written int64
+	buf := bytes.NewBuffer(nil)
+	for _, line := range lines {
+		buf.Write(line)
+		if buf.Len() < 32*1024 {
+			continue
+		}
+		n, err := buf.WriteTo(w)
+		written += n
+		if err != nil {
+			return written, err
+		}
+	}
+	n, err := buf.WriteTo(w)
+	return written + n, err
+}
+
+// Close stops recording. Recorded memory events remain exportable and files are kept on disk.
+func (r *eventRecorder) Close() {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.closed = true
+	if r.file != nil {
+		_ = r.file.Close()
+		r.file = nil
+	}
+}
+
+// pruneEventRecordFiles removes the JSONL files of closed sessions beyond
+// RecordEventsMaxFiles, oldest first by the time of their last event. Files of
+// live sessions are kept.
+func (m *Manager) pruneEventRecordFiles() {
+	root := m.cfg.RecordEventsDir
+	if m.cfg.RecordEvents != EventRecordFile || root == "" {
+		return
+	}
+	limit := m.cfg.RecordEventsMaxFiles
+	if limit <= 0 {
+		limit = defaultRecordEventsMaxFiles
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			m.logger.Debug("list event record files failed", zap.String("dir", root), zap.Error(err))
+		}
+		return
+	}
+
+	type closedFile struct {
+		name    string
+		modTime time.Time
+	}
+	var closed []closedFile
+	for _, entry := range entries {
+		id, ok := strings.CutSuffix(entry.Name(), ".jsonl")
+		if !ok || !entry.Type().IsRegular() {
+			continue
+		}
+		if _, ok := m.sessions.Load(id); ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		closed = append(closed, closedFile{name: entry.Name(), modTime: info.ModTime()})
+	}
+	if len(closed) <= limit {
+		return
+	}
+	sort.Slice(closed, func(i, j int) bool { return closed[i].modTime.After(closed[j].modTime) })
+	for _, file := range closed[limit:] {
+		if err := os.Remove(filepath.Join(root, file.name)); err != nil {
+			m.logger.Debug("remove event record file failed", zap.String("file", file.name), zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package terminal
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func decodeRecordedEvents(t *testing.T, data []byte) []RecordedEvent {
+	t.Helper()
+	events := make([]RecordedEvent, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		var event RecordedEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("invalid JSONL line %q: %v", scanner.Text(), err)
+		}
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestEventRecorderModes(t *testing.T) {
+	for _, mode := range []string{EventRecordMemory, EventRecordFile} {
+		t.Run(mode, func(t *testing.T) {
+			recorder, err := newEventRecorder(mode, t.TempDir(), "session-1")
+			if err != nil {
+				t.Fatalf("newEventRecorder failed: %v", err)
+			}
+			defer recorder.Close()
+
+			now := time.Now()
+			recorder.Record(StreamEvent{Type: StreamEventData, Data: []byte("hello\r\n")}, now)
+			recorder.Record(StreamEvent{Type: StreamEventMetadata, Metadata: &SessionMetadata{Title: "demo"}}, now)
+			recorder.Record(StreamEvent{Type: StreamEventExit, Err: errors.New("boom")}, now)
+
+			var buf bytes.Buffer
+			if _, err := recorder.WriteTo(&buf); err != nil {
+				t.Fatalf("WriteTo failed: %v", err)
+			}
+			if !bytes.Contains(buf.Bytes(), []byte(`"data":"aGVsbG8NCg=="`)) {
+				t.Fatalf("expected base64 data in export, got %s", buf.String())
+			}
+
+			events := decodeRecordedEvents(t, buf.Bytes())
+			if len(events) != 3 {
+				t.Fatalf("expected 3 events got %d", len(events))
+			}
+			if string(events[0].Data) != "hello\r\n" {
+				t.Fatalf("unexpected data %q", events[0].Data)
+			}
+			if events[1].Metadata == nil || events[1].Metadata.Title != "demo" {
+				t.Fatalf("unexpected metadata %+v", events[1].Metadata)
+			}
+			if events[2].Type != StreamEventExit || events[2].Error != "boom" {
+				t.Fatalf("unexpected exit event %+v", events[2])
+			}
+		})
+	}
+}
+
+func TestEventRecorderDisabled(t *testing.T) {
+	recorder, err := newEventRecorder(EventRecordOff, "", "session-1")
+	if err != nil || recorder != nil {
+		t.Fatalf("expected nil recorder, got %v %v", recorder, err)
+	}
+	recorder.Record(StreamEvent{Type: StreamEventData}, time.Now())
+	if _, err := recorder.WriteTo(&bytes.Buffer{}); !errors.Is(err, ErrEventRecordingDisabled) {
+		t.Fatalf("expected ErrEventRecordingDisabled, got %v", err)
+	}
+}
+
+func TestManagerPruneEventRecordFiles(t *testing.T) {
+	dir := t.TempDir()
+	mgr := NewManager(Config{RecordEvents: EventRecordFile, RecordEventsDir: dir, RecordEventsMaxFiles: 2}, zap.NewNop())
+	live := newTestSession(t, SessionParams{ID: "live", Rows: 4, Cols: 20, Logger: zap.NewNop()})
+	if err := mgr.addSession(live); err != nil {
+		t.Fatalf("addSession failed: %v", err)
+	}
+
+	// live 最早，其余按 closed-0 到 closed-3 依次更新；非 .jsonl 文件不受影响
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"live.jsonl", "closed-0.jsonl", "closed-1.jsonl", "closed-2.jsonl", "closed-3.jsonl", "notes.txt"} {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte("{}\n"), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		modTime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("chtimes %s: %v", name, err)
+		}
+	}
+
+	mgr.pruneEventRecordFiles()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	if want := []string{"closed-2.jsonl", "closed-3.jsonl", "live.jsonl", "notes.txt"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v to remain, got %v", want, got)
+	}
+}
@@ -0,0 +1,126 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// defaultWaitTimeout 未指定超时时等待输出的时长
+	defaultWaitTimeout = 30 * time.Second
+	// waitBufferMaxBytes 等待期间只保留最近的输出用于渲染匹配
+	waitBufferMaxBytes = 64 * 1024
+)
+
+// WaitForPattern blocks until a line rendered from the output produced after
+// the call matches pattern and returns that line. The output is rendered at the
+// session size after every chunk and the visible lines are matched as plain
+// text, including the unfinished last line so prompts without a newline can be
+// awaited. It fails with ErrWaitTimeout after timeout (default 30s), with
+// ErrSessionNotRunning when the session exits first, or with the context error.
+func (s *Session) WaitForPattern(ctx context.Context, pattern string, timeout time.Duration) (string, error) {
+	return s.SendAndWait(ctx, nil, pattern, timeout)
+}
+
+// SendAndWait writes input to the session and waits for pattern like
+// WaitForPattern. The output subscription starts before the input is written,
+// so output triggered by the input cannot be missed. The echo of the input is
+// part of the output and can match the pattern too.
+func (s *Session) SendAndWait(ctx context.Context, input []byte, pattern string, timeout time.Duration) (string, error) {
+	re, err := compileWaitPattern(pattern)
+	if err != nil {
+		return "", err
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if timeout <= 0 {
+		timeout = defaultWaitTimeout
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stream, err := s.Subscribe(waitCtx, StreamEventData)
+	if err != nil {
+		return "", fmt.Errorf("failed to subscribe to session: %w", err)
+	}
+	defer stream.Close()
+
+	if len(input) > 0 {
+		if err := s.WriteInput(input); err != nil {
+			return "", err
+		}
+	}
+
+	var buffer []byte
+	for {
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			return "", ErrWaitTimeout
+		case event, ok := <-stream.Events():
+			if !ok {
+				if ctx.Err() != nil {
+					return "", ctx.Err()
+				}
+				if waitCtx.Err() != nil {
+					return "", ErrWaitTimeout
+				}
+				return "", ErrSessionNotRunning
+			}
+			if event.Type == StreamEventExit {
+				return "", ErrSessionNotRunning
+			}
+			if event.Type != StreamEventData || len(event.Data) == 0 {
+				continue
+			}
+			buffer = append(buffer, event.Data...)
+			if overflow := len(buffer) - waitBufferMaxBytes; overflow > 0 {
+				buffer = append([]byte(nil), buffer[overflow:]...)
+			}
+			if line, ok := s.matchWaitPattern(re, buffer); ok {
+				return line, nil
+			}
+		}
+	}
+}
+
+func compileWaitPattern(pattern string) (*regexp.Regexp, error) {
+	if strings.TrimSpace(pattern) == "" {
+		return nil, fmt.Errorf("%w: pattern is required", ErrInvalidWaitPattern)
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWaitPattern, err)
+	}
+	return re, nil
+}
+
+// matchWaitPattern renders data at the session size and returns the first line
+// matching re.
+func (s *Session) matchWaitPattern(re *regexp.Regexp, data []byte) (string, bool) {
+	s.mu.RLock()
+	rows, cols := s.rows, s.cols
+	s.mu.RUnlock()
+
+	for _, line := range s.renderLogicalLines(data, rows, cols) {
+		if line.Text != "" && re.MatchString(line.Text) {
+			return line.Text, true
+		}
+	}
+	return "", false
+}
+
+// SendAndWait writes input to a session and waits for output; see Session.SendAndWait.
+func (m *Manager) SendAndWait(ctx context.Context, sessionID string, input []byte, pattern string, timeout time.Duration) (string, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return "", err
+	}
+	return session.SendAndWait(ctx, input, pattern, timeout)
+}
@@ -0,0 +1,58 @@
+package terminal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSessionWaitForPattern(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	if _, err := session.WaitForPattern(context.Background(), "(", time.Second); !errors.Is(err, ErrInvalidWaitPattern) {
+		t.Fatalf("expected invalid pattern error, got %v", err)
+	}
+
+	emit := func(chunks ...string) {
+		go func() {
+			for len(session.snapshotSubscribers()) == 0 {
+				time.Sleep(time.Millisecond)
+			}
+			for _, chunk := range chunks {
+				session.broadcast(StreamEvent{Type: StreamEventData, Data: []byte(chunk)})
+			}
+		}()
+	}
+
+	// 提示符分两个 chunk 到达且没有换行
+	emit("building...\r\n\x1b[32mdone\x1b[0m\r\n(y/", "n)? ")
+	line, err := session.WaitForPattern(context.Background(), `\(y/n\)\?$`, 5*time.Second)
+	if err != nil {
+		t.Fatalf("WaitForPattern failed: %v", err)
+	}
+	if line != "(y/n)?" {
+		t.Fatalf("unexpected matched line %q", line)
+	}
+
+	if _, err := session.WaitForPattern(context.Background(), "never", 50*time.Millisecond); !errors.Is(err, ErrWaitTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+
+	// 上一次等待的订阅异步移除，重复广播直到等待结束
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-done:
+				return
+			case <-time.After(10 * time.Millisecond):
+				session.broadcast(StreamEvent{Type: StreamEventExit})
+			}
+		}
+	}()
+	_, err = session.WaitForPattern(context.Background(), "never", 5*time.Second)
+	close(done)
+	if !errors.Is(err, ErrSessionNotRunning) {
+		t.Fatalf("expected exit to end the wait, got %v", err)
+	}
+}
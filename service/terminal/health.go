@@ -0,0 +1,128 @@
+package terminal
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// healthDropBuckets 最近 drop 计数的统计窗口（分钟），每分钟一个桶
+const healthDropBuckets = 5
+
+// recentCounter counts events over the last healthDropBuckets minutes.
+type recentCounter struct {
+	mu      sync.Mutex
+	minutes [healthDropBuckets]int64
+	counts  [healthDropBuckets]int64
+}
+
+func (c *recentCounter) add(now time.Time, n int64) {
+	minute := now.Unix() / 60
+	idx := minute % healthDropBuckets
+	c.mu.Lock()
+	if c.minutes[idx] != minute {
+		c.minutes[idx] = minute
+		c.counts[idx] = 0
+	}
+	c.counts[idx] += n
+	c.mu.Unlock()
+}
+
+func (c *recentCounter) sum(now time.Time) int64 {
+	minute := now.Unix() / 60
+	var total int64
+	c.mu.Lock()
+	for i, bucket := range c.minutes {
+		if minute-bucket < healthDropBuckets {
+			total += c.counts[i]
+		}
+	}
+	c.mu.Unlock()
+	return total
+}
+
+// ErrorSessionInfo identifies a session whose process failed.
+type ErrorSessionInfo struct {
+	SessionID  string    `json:"sessionId"`
+	ProjectID  string    `json:"projectId"`
+	WorktreeID string    `json:"worktreeId,omitempty"`
+	Title      string    `json:"title"`
+	Error      string    `json:"error,omitempty"`
+	LastActive time.Time `json:"lastActive"`
+}
+
+// TerminalHealth summarizes all sessions for spotting failed sessions,
+// subscriber leaks and scrollback memory growth.
+type TerminalHealth struct {
+	// ActiveSessions 未关闭且未出错的会话数
+	ActiveSessions int                   `json:"activeSessions"`
+	TotalSessions  int                   `json:"totalSessions"`
+	StatusCounts   map[SessionStatus]int `json:"statusCounts"`
+	ErrorSessions  []ErrorSessionInfo    `json:"errorSessions"`
+	// Subscribers 订阅者总数；StaleSubscribers 为 channel 已满超过 subscriberStaleAfter 的订阅者
+	Subscribers       int   `json:"subscribers"`
+	StaleSubscribers  int   `json:"staleSubscribers"`
+	PrunedSubscribers int64 `json:"prunedSubscribers"`
+	// DroppedEvents 累计丢弃的事件数；RecentDroppedEvents 为最近 RecentWindowSeconds 内的丢弃数
+	DroppedEvents       int64 `json:"droppedEvents"`
+	RecentDroppedEvents int64 `json:"recentDroppedEvents"`
+	RecentWindowSeconds int   `json:"recentWindowSeconds"`
+	// ScrollbackBytes 内存中 scrollback 的总字节数，不含已溢写到磁盘的部分
+	ScrollbackBytes int64     `json:"scrollbackBytes"`
+	CheckedAt       time.Time `json:"checkedAt"`
+}
+
+// ScrollbackBytes returns the size of the scrollback kept in memory.
+func (s *Session) ScrollbackBytes() int {
+	s.scrollMu.RLock()
+	defer s.scrollMu.RUnlock()
+	return s.scrollbackSize
+}
+
+// Health aggregates status, subscriber, drop and scrollback figures across
+// all sessions.
+func (m *Manager) Health() *TerminalHealth {
+	now := time.Now()
+	health := &TerminalHealth{
+		StatusCounts:        make(map[SessionStatus]int),
+		ErrorSessions:       make([]ErrorSessionInfo, 0),
+		PrunedSubscribers:   m.subscribersPruned.Load(),
+		RecentWindowSeconds: healthDropBuckets * 60,
+		CheckedAt:           now,
+	}
+	m.sessions.Range(func(_ string, session *Session) bool {
+		status := session.Status()
+		health.TotalSessions++
+		health.StatusCounts[status]++
+		if status != SessionStatusClosed && status != SessionStatusError {
+			health.ActiveSessions++
+		}
+		if status == SessionStatusError {
+			info := ErrorSessionInfo{
+				SessionID:  session.ID(),
+				ProjectID:  session.ProjectID(),
+				WorktreeID: session.WorktreeID(),
+				Title:      session.Title(),
+				LastActive: session.LastActive(),
+			}
+			if err := session.Err(); err != nil {
+				info.Error = err.Error()
+			}
+			health.ErrorSessions = append(health.ErrorSessions, info)
+		}
+		for _, sub := range session.snapshotSubscribers() {
+			health.Subscribers++
+			if sub.stale(now, subscriberStaleAfter) {
+				health.StaleSubscribers++
+			}
+		}
+		health.DroppedEvents += session.stats.drops.Load()
+		health.RecentDroppedEvents += session.stats.recentDrops.sum(now)
+		health.ScrollbackBytes += int64(session.ScrollbackBytes())
+		return true
+	})
+	sort.Slice(health.ErrorSessions, func(i, j int) bool {
+		return health.ErrorSessions[i].LastActive.After(health.ErrorSessions[j].LastActive)
+	})
+	return health
+}
@@ -0,0 +1,74 @@
+package terminal
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestRecentCounter(t *testing.T) {
+	var counter recentCounter
+	start := time.Unix(6000, 0)
+	counter.add(start, 2)
+	counter.add(start.Add(time.Minute), 3)
+	if got := counter.sum(start.Add(time.Minute)); got != 5 {
+		t.Fatalf("expected 5 recent events, got %d", got)
+	}
+	// 超出窗口的桶不再计入，复用的桶先清零
+	later := start.Add(healthDropBuckets * time.Minute)
+	if got := counter.sum(later); got != 3 {
+		t.Fatalf("expected only the newer bucket, got %d", got)
+	}
+	counter.add(later, 1)
+	if got := counter.sum(later); got != 4 {
+		t.Fatalf("expected reused bucket to reset, got %d", got)
+	}
+}
+
+func TestManagerHealth(t *testing.T) {
+	mgr := NewManager(Config{}, zap.NewNop())
+	running := newTestSession(t, SessionParams{ID: "s1", ProjectID: "p1", ScrollbackLimit: 1024})
+	running.setStatus(SessionStatusRunning)
+	running.appendScrollback([]byte("hello\r\n"))
+	failed := newTestSession(t, SessionParams{ID: "s2", ProjectID: "p1"})
+	failed.err.Store(sessionError{err: errors.New("exit status 1")})
+	failed.setStatus(SessionStatusError)
+	for _, session := range []*Session{running, failed} {
+		if err := mgr.addSession(session); err != nil {
+			t.Fatalf("addSession failed: %v", err)
+		}
+	}
+
+	stream, err := running.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+	for i := 0; i < subscriberBufferSize+2; i++ {
+		running.broadcast(StreamEvent{Type: StreamEventData, Data: []byte("x")})
+	}
+
+	health := mgr.Health()
+	// 出错的会话不算活跃
+	if health.TotalSessions != 2 || health.ActiveSessions != 1 {
+		t.Fatalf("unexpected session counts %+v", health)
+	}
+	if health.StatusCounts[SessionStatusRunning] != 1 || health.StatusCounts[SessionStatusError] != 1 {
+		t.Fatalf("unexpected status counts %+v", health.StatusCounts)
+	}
+	if len(health.ErrorSessions) != 1 || health.ErrorSessions[0].SessionID != "s2" || health.ErrorSessions[0].Error != "exit status 1" {
+		t.Fatalf("unexpected error sessions %+v", health.ErrorSessions)
+	}
+	if health.Subscribers != 1 || health.StaleSubscribers != 0 {
+		t.Fatalf("unexpected subscriber counts %+v", health)
+	}
+	if health.DroppedEvents != 2 || health.RecentDroppedEvents != 2 {
+		t.Fatalf("expected 2 dropped events, got %+v", health)
+	}
+	if health.ScrollbackBytes != int64(len("hello\r\n")) {
+		t.Fatalf("unexpected scrollback bytes %d", health.ScrollbackBytes)
+	}
+}
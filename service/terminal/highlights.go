@@ -0,0 +1,194 @@
+package terminal
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/mattn/go-runewidth"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+const (
+	maxTerminalHighlights   = 256
+	highlightBufferMaxBytes = 64 * 1024
+)
+
+// HighlightRule tags rendered output with Category where Keyword or Pattern
+// matches; the frontend colours the marked ranges by category.
+type HighlightRule struct {
+	// Keyword 按字面匹配的关键字，与 Pattern 二选一
+	Keyword string `json:"keyword,omitempty" yaml:"keyword"`
+	// Pattern 正则表达式，与 Keyword 二选一
+	Pattern string `json:"pattern,omitempty" yaml:"pattern"`
+	// Category 命中时标注的类别，如 error、warn
+	Category string `json:"category" yaml:"category"`
+}
+
+type compiledHighlightRule struct {
+	HighlightRule
+	re *regexp.Regexp
+}
+
+// TerminalHighlight is a range of rendered output matched by a highlight rule.
+type TerminalHighlight struct {
+	Category string `json:"category"` // 命中规则的类别
+	Text     string `json:"text"`     // 命中的文本
+	Row      int    `json:"row"`      // 起始屏幕行（从 0 开始）
+	Col      int    `json:"col"`      // 起始屏幕列（从 0 开始），按显示宽度计
+	Length   int    `json:"length"`   // 文本占用的屏幕列数，中文等宽字符计 2 列
+}
+
+// compileHighlightRules validates highlight rules; the index of an invalid rule is reported.
+func compileHighlightRules(rules []HighlightRule) ([]*compiledHighlightRule, error) {
+	compiled := make([]*compiledHighlightRule, 0, len(rules))
+	for i, rule := range rules {
+		if strings.TrimSpace(rule.Category) == "" {
+			return nil, fmt.Errorf("highlight rule %d: category is required", i)
+		}
+		var pattern string
+		switch {
+		case rule.Keyword != "" && rule.Pattern != "":
+			return nil, fmt.Errorf("highlight rule %d: keyword and pattern are mutually exclusive", i)
+		case rule.Keyword != "":
+			pattern = regexp.QuoteMeta(rule.Keyword)
+		case strings.TrimSpace(rule.Pattern) != "":
+			pattern = rule.Pattern
+		default:
+			return nil, fmt.Errorf("highlight rule %d: keyword or pattern is required", i)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("highlight rule %d: invalid pattern: %w", i, err)
+		}
+		compiled = append(compiled, &compiledHighlightRule{HighlightRule: rule, re: re})
+	}
+	return compiled, nil
+}
+
+// detectTerminalHighlights marks the ranges of the rendered lines matched by
+// rules. A range already claimed by an earlier rule is not marked again.
+func detectTerminalHighlights(lines []ai_assistant2.LogicalLine, rules []*compiledHighlightRule) []TerminalHighlight {
+	highlights := make([]TerminalHighlight, 0)
+	for _, line := range lines {
+		if line.Text == "" {
+			continue
+		}
+
+		var claimed [][]int
+		runes := []rune(line.Text)
+		for _, rule := range rules {
+			for _, loc := range rule.re.FindAllStringIndex(line.Text, -1) {
+				if loc[0] == loc[1] || overlapsRanges(loc, claimed) {
+					continue
+				}
+				start := utf8.RuneCountInString(line.Text[:loc[0]])
+				if start >= len(line.Cells) {
+					continue
+				}
+				claimed = append(claimed, loc)
+				text := line.Text[loc[0]:loc[1]]
+				highlights = append(highlights, TerminalHighlight{
+					Category: rule.Category,
+					Text:     text,
+					Row:      line.Cells[start].Row,
+					Col:      displayCol(line.Cells, runes, start),
+					Length:   runewidth.StringWidth(text),
+				})
+			}
+		}
+	}
+	if len(highlights) > maxTerminalHighlights {
+		highlights = highlights[len(highlights)-maxTerminalHighlights:]
+	}
+	return highlights
+}
+
+// displayCol 把模拟终端的列号换算为显示列：模拟终端里每个字符只占一格，中文等宽字符实际占 2 列
+func displayCol(cells []ai_assistant2.LineCell, runes []rune, index int) int {
+	row := cells[index].Row
+	col := cells[index].Col
+	for i := index - 1; i >= 0 && cells[i].Row == row; i-- {
+		col += runewidth.RuneWidth(runes[i]) - 1
+	}
+	return col
+}
+
+// highlightsSignature builds a comparable key so unchanged highlights are not re-sent.
+func highlightsSignature(highlights []TerminalHighlight) string {
+	var b strings.Builder
+	for _, highlight := range highlights {
+		b.WriteString(highlight.Category)
+		b.WriteByte('|')
+		b.WriteString(strconv.Itoa(highlight.Row))
+		b.WriteByte(',')
+		b.WriteString(strconv.Itoa(highlight.Col))
+		b.WriteByte(',')
+		b.WriteString(strconv.Itoa(highlight.Length))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// setHighlightRules replaces the highlight rules of the session.
+func (s *Session) setHighlightRules(rules []*compiledHighlightRule) {
+	s.highlightMu.Lock()
+	s.highlightRules = rules
+	if len(rules) == 0 {
+		s.highlightBuffer = nil
+		s.highlightDirty = false
+		s.lastHighlightsSig = ""
+	}
+	s.highlightMu.Unlock()
+}
+
+func (s *Session) appendHighlightBuffer(chunk []byte) {
+	if len(chunk) == 0 {
+		return
+	}
+	s.highlightMu.Lock()
+	defer s.highlightMu.Unlock()
+	if len(s.highlightRules) == 0 {
+		return
+	}
+	s.highlightBuffer = append(s.highlightBuffer, chunk...)
+	if overflow := len(s.highlightBuffer) - highlightBufferMaxBytes; overflow > 0 {
+		s.highlightBuffer = append([]byte(nil), s.highlightBuffer[overflow:]...)
+	}
+	s.highlightDirty = true
+}
+
+// checkAndBroadcastHighlights renders recent output into logical lines and
+// sends the highlighted ranges when they differ from the last broadcast. The
+// data stream itself is left untouched.
+func (s *Session) checkAndBroadcastHighlights() {
+	s.highlightMu.Lock()
+	if len(s.highlightRules) == 0 || !s.highlightDirty {
+		s.highlightMu.Unlock()
+		return
+	}
+	data := append([]byte(nil), s.highlightBuffer...)
+	rules := s.highlightRules
+	s.highlightDirty = false
+	s.highlightMu.Unlock()
+
+	s.mu.RLock()
+	rows, cols := s.rows, s.cols
+	s.mu.RUnlock()
+
+	highlights := detectTerminalHighlights(s.renderLogicalLines(data, rows, cols), rules)
+	signature := highlightsSignature(highlights)
+
+	s.highlightMu.Lock()
+	if signature == s.lastHighlightsSig {
+		s.highlightMu.Unlock()
+		return
+	}
+	s.lastHighlightsSig = signature
+	s.highlightMu.Unlock()
+
+	s.broadcast(StreamEvent{Type: StreamEventHighlights, Highlights: highlights})
+}
@@ -0,0 +1,69 @@
+package terminal
+
+import (
+	"testing"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+func TestDetectTerminalHighlights(t *testing.T) {
+	rules, err := compileHighlightRules([]HighlightRule{
+		{Keyword: "ERROR", Category: "error"},
+		{Pattern: `WARN(ING)?`, Category: "warn"},
+		{Pattern: `ERR\w*`, Category: "other"},
+	})
+	if err != nil {
+		t.Fatalf("compileHighlightRules: %v", err)
+	}
+
+	data := []byte("\x1b[31mERROR\x1b[0m: build failed\r\nok\r\n  WARNING twice WARN\r\n")
+	lines := ai_assistant2.RenderLogicalLinesFromBuffer(data, 10, 40)
+	highlights := detectTerminalHighlights(lines, rules)
+
+	if len(highlights) != 3 {
+		t.Fatalf("expected 3 highlights got %#v", highlights)
+	}
+	if got := highlights[0]; got.Category != "error" || got.Text != "ERROR" || got.Row != 0 || got.Col != 0 || got.Length != 5 {
+		t.Fatalf("unexpected error highlight %#v", got)
+	}
+	if got := highlights[1]; got.Category != "warn" || got.Text != "WARNING" || got.Row != 2 || got.Col != 2 {
+		t.Fatalf("unexpected warning highlight %#v", got)
+	}
+	if got := highlights[2]; got.Text != "WARN" || got.Col != 16 || got.Length != 4 {
+		t.Fatalf("unexpected warn highlight %#v", got)
+	}
+	if highlightsSignature(highlights) == highlightsSignature(highlights[:2]) {
+		t.Fatalf("expected signature to change with highlights")
+	}
+}
+
+func TestCompileHighlightRulesValidation(t *testing.T) {
+	cases := []HighlightRule{
+		{Keyword: "ERROR"},
+		{Category: "error"},
+		{Keyword: "ERROR", Pattern: "ERR", Category: "error"},
+		{Pattern: "(", Category: "error"},
+	}
+	for _, rule := range cases {
+		if _, err := compileHighlightRules([]HighlightRule{rule}); err == nil {
+			t.Fatalf("expected error for rule %#v", rule)
+		}
+	}
+}
+
+func TestDetectTerminalHighlightsWideText(t *testing.T) {
+	rules, err := compileHighlightRules([]HighlightRule{{Keyword: "构建失败", Category: "error"}})
+	if err != nil {
+		t.Fatalf("compileHighlightRules: %v", err)
+	}
+
+	// 中文每个字占 2 列，长度按屏幕列数计
+	lines := ai_assistant2.RenderLogicalLinesFromBuffer([]byte("错误: 构建失败\r\n"), 10, 40)
+	highlights := detectTerminalHighlights(lines, rules)
+	if len(highlights) != 1 {
+		t.Fatalf("expected 1 highlight got %#v", highlights)
+	}
+	if got := highlights[0]; got.Text != "构建失败" || got.Col != 6 || got.Length != 8 {
+		t.Fatalf("unexpected wide highlight %#v", got)
+	}
+}
@@ -0,0 +1,136 @@
+package terminal
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+const (
+	maxTerminalHyperlinks = 256
+	hyperlinkURLMaxBytes  = 2048
+	hyperlinkTextMaxRunes = 512
+)
+
+// osc8Prefix 开启或关闭 OSC 8 超链接：ESC ] 8 ; params ; URI ST，URI 为空表示关闭
+var osc8Prefix = []byte("\x1b]8;")
+
+// hyperlinkSchemes 只返回可安全展示的链接，javascript: 等 scheme 一律丢弃
+var hyperlinkSchemes = map[string]struct{}{
+	"http":   {},
+	"https":  {},
+	"file":   {},
+	"mailto": {},
+}
+
+// TerminalHyperlink is an OSC 8 hyperlink found in the session output.
+type TerminalHyperlink struct {
+	Text string `json:"text"`         // 链接覆盖的可见文本，已去除控制序列
+	URL  string `json:"url"`          // 链接地址
+	ID   string `json:"id,omitempty"` // OSC 8 的 id 参数，相同 id 的文本属于同一个链接
+}
+
+// Hyperlinks parses the OSC 8 hyperlinks kept in the scrollback. Each distinct
+// text/URL pair is reported once in order of first appearance; beyond
+// maxTerminalHyperlinks only the most recent ones are kept.
+func (s *Session) Hyperlinks() []TerminalHyperlink {
+	return parseOSC8Hyperlinks(bytes.Join(s.Scrollback(), nil))
+}
+
+// parseOSC8Hyperlinks extracts OSC 8 hyperlinks from raw terminal output. A link
+// ends at the next OSC 8 sequence; a sequence cut off at the end of data, or a
+// link that is never closed, is ignored.
+func parseOSC8Hyperlinks(data []byte) []TerminalHyperlink {
+	links := make([]TerminalHyperlink, 0)
+	seen := make(map[string]struct{})
+	var open *TerminalHyperlink
+	for {
+		idx := bytes.Index(data, osc8Prefix)
+		if idx < 0 {
+			break
+		}
+		body := data[idx+len(osc8Prefix):]
+		end, stLen := findStringTerminator(body)
+		if end < 0 {
+			break
+		}
+		if open != nil {
+			open.Text = hyperlinkText(data[:idx])
+			if open.Text == "" {
+				open.Text = open.URL
+			}
+			key := open.URL + "\x00" + open.Text
+			if _, ok := seen[key]; !ok {
+				seen[key] = struct{}{}
+				links = append(links, *open)
+			}
+			open = nil
+		}
+
+		params, uri, ok := strings.Cut(string(body[:end]), ";")
+		if ok && uri != "" && validHyperlinkURL(uri) {
+			open = &TerminalHyperlink{URL: uri, ID: hyperlinkID(params)}
+		}
+		data = body[end+stLen:]
+	}
+
+	if len(links) > maxTerminalHyperlinks {
+		links = links[len(links)-maxTerminalHyperlinks:]
+	}
+	return links
+}
+
+// findStringTerminator returns the offset of the BEL or ESC \ ending an OSC
+// sequence and the terminator length, -1 when data ends first.
+func findStringTerminator(data []byte) (int, int) {
+	for i, b := range data {
+		switch b {
+		case 0x07:
+			return i, 1
+		case 0x1b:
+			if i+1 < len(data) && data[i+1] == '\\' {
+				return i, 2
+			}
+			// 其他 ESC 说明序列已被截断或损坏
+			return -1, 0
+		}
+	}
+	return -1, 0
+}
+
+// hyperlinkID reads the id from colon separated key=value params.
+func hyperlinkID(params string) string {
+	for _, param := range strings.Split(params, ":") {
+		if value, ok := strings.CutPrefix(param, "id="); ok {
+			return value
+		}
+	}
+	return ""
+}
+
+func validHyperlinkURL(raw string) bool {
+	if len(raw) > hyperlinkURLMaxBytes {
+		return false
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+	_, ok := hyperlinkSchemes[strings.ToLower(parsed.Scheme)]
+	return ok
+}
+
+// hyperlinkText strips control sequences and collapses whitespace of the text
+// covered by a link.
+func hyperlinkText(raw []byte) string {
+	// PTY 输出以 \r\n 换行，StripANSI 会把行尾 \r 当作覆盖整行
+	text := strings.ReplaceAll(strings.ToValidUTF8(string(raw), ""), "\r\n", "\n")
+	text = types.StripANSI(text)
+	text = strings.Join(strings.Fields(text), " ")
+	if runes := []rune(text); len(runes) > hyperlinkTextMaxRunes {
+		text = string(runes[:hyperlinkTextMaxRunes])
+	}
+	return text
+}
@@ -0,0 +1,58 @@
+package terminal
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+func osc8(params, uri, st string) string {
+	return "\x1b]8;" + params + ";" + uri + st
+}
+
+func TestParseOSC8Hyperlinks(t *testing.T) {
+	output := "see " + osc8("", "https://example.com/docs", "\x1b\\") + "\x1b[1mdocs\x1b[0m" + osc8("", "", "\x1b\\") + " and " +
+		osc8("id=run-1", "file:///tmp/report.txt", "\x07") + "report\r\n.txt" + osc8("", "", "\x07") +
+		osc8("", "javascript:alert(1)", "\x07") + "evil" + osc8("", "", "\x07") +
+		osc8("", "https://example.com/docs", "\x1b\\") + "docs" + osc8("", "", "\x1b\\") +
+		osc8("", "https://example.com/cut", "\x1b\\") + "never closed"
+
+	want := []TerminalHyperlink{
+		{Text: "docs", URL: "https://example.com/docs"},
+		{Text: "report .txt", URL: "file:///tmp/report.txt", ID: "run-1"},
+	}
+	if got := parseOSC8Hyperlinks([]byte(output)); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected hyperlinks:\n got %+v\nwant %+v", got, want)
+	}
+
+	// 被截断的序列不产生链接
+	truncated := osc8("", "https://example.com/a", "\x1b\\") + "a" + "\x1b]8;;"
+	if got := parseOSC8Hyperlinks([]byte(truncated)); len(got) != 0 {
+		t.Fatalf("expected no hyperlinks from truncated output, got %+v", got)
+	}
+}
+
+func TestSessionHyperlinksSurviveEncoding(t *testing.T) {
+	session := newTestSession(t, SessionParams{Encoding: "gbk", ScrollbackLimit: 4096})
+	encoded, _, err := transform.Bytes(simplifiedchinese.GBK.NewEncoder(),
+		[]byte(osc8("", "https://example.com/中", "\x1b\\")+"打开文档"+osc8("", "", "\x1b\\")))
+	if err != nil {
+		t.Fatalf("encode gbk: %v", err)
+	}
+	// 在 OSC 8 序列与多字节字符中间切分
+	for _, chunk := range [][]byte{encoded[:3], encoded[3:20], encoded[20:]} {
+		if normalized := session.NormalizeOutput(chunk); len(normalized) > 0 {
+			session.appendScrollback(normalized)
+		}
+	}
+	if got := bytes.Join(session.Scrollback(), nil); !bytes.Contains(got, []byte("\x1b]8;;https://example.com/中\x1b\\打开文档\x1b]8;;\x1b\\")) {
+		t.Fatalf("OSC 8 sequence damaged by decoding: %q", got)
+	}
+	want := []TerminalHyperlink{{Text: "打开文档", URL: "https://example.com/中"}}
+	if got := session.Hyperlinks(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected hyperlinks %+v", got)
+	}
+}
@@ -0,0 +1,186 @@
+package terminal
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+const (
+	// completeDefaultLimit 未指定数量时返回的候选上限
+	completeDefaultLimit = 50
+	// completeMaxPaths 路径候选的上限，避免大目录一次返回过多条目
+	completeMaxPaths = 30
+)
+
+// CompletionKind tells where a completion candidate came from.
+type CompletionKind string
+
+const (
+	CompletionKindCommand CompletionKind = "command"
+	CompletionKindHistory CompletionKind = "history"
+	CompletionKindPath    CompletionKind = "path"
+)
+
+// CompletionCandidate is one completion of the typed input. Text is the whole
+// input after completion, so the client can replace the input box with it.
+type CompletionCandidate struct {
+	Text        string         `json:"text"`
+	Kind        CompletionKind `json:"kind"`
+	Description string         `json:"description,omitempty"`
+}
+
+// aiSlashCommand 常见的 AI 助手斜杠命令，Assistants 为空表示各助手通用
+type aiSlashCommand struct {
+	Name        string
+	Description string
+	Assistants  []types.AssistantType
+}
+
+var aiSlashCommands = []aiSlashCommand{
+	{Name: "/clear", Description: "清空对话上下文", Assistants: []types.AssistantType{types.AssistantTypeClaudeCode, types.AssistantTypeQwenCode, types.AssistantTypeGemini}},
+	{Name: "/compact", Description: "压缩对话上下文"},
+	{Name: "/model", Description: "切换模型"},
+	{Name: "/status", Description: "查看会话状态"},
+	{Name: "/init", Description: "生成项目说明文件"},
+	{Name: "/help", Description: "查看帮助", Assistants: []types.AssistantType{types.AssistantTypeClaudeCode, types.AssistantTypeQwenCode, types.AssistantTypeGemini}},
+	{Name: "/cost", Description: "查看 token 用量与费用", Assistants: []types.AssistantType{types.AssistantTypeClaudeCode}},
+	{Name: "/memory", Description: "编辑记忆文件", Assistants: []types.AssistantType{types.AssistantTypeClaudeCode, types.AssistantTypeGemini}},
+	{Name: "/review", Description: "审查代码改动", Assistants: []types.AssistantType{types.AssistantTypeClaudeCode, types.AssistantTypeCodex}},
+	{Name: "/resume", Description: "恢复之前的对话", Assistants: []types.AssistantType{types.AssistantTypeClaudeCode}},
+	{Name: "/new", Description: "开始新对话", Assistants: []types.AssistantType{types.AssistantTypeCodex}},
+	{Name: "/diff", Description: "查看工作区改动", Assistants: []types.AssistantType{types.AssistantTypeCodex}},
+	{Name: "/approvals", Description: "调整审批模式", Assistants: []types.AssistantType{types.AssistantTypeCodex}},
+	{Name: "/quit", Description: "退出助手", Assistants: []types.AssistantType{types.AssistantTypeCodex, types.AssistantTypeGemini}},
+}
+
+// Complete returns completion candidates for the typed input prefix: AI slash
+// commands when the input starts with "/", then submitted lines from the input
+// history, then paths inside the working directory matching the last word.
+// Path completion never leaves the working directory the session started in.
+// limit <= 0 uses a default.
+func (s *Session) Complete(prefix string, limit int) []CompletionCandidate {
+	if limit <= 0 {
+		limit = completeDefaultLimit
+	}
+	result := make([]CompletionCandidate, 0)
+	seen := make(map[string]struct{})
+	add := func(candidate CompletionCandidate) bool {
+		if candidate.Text == prefix {
+			return true
+		}
+		if _, ok := seen[candidate.Text]; ok {
+			return true
+		}
+		seen[candidate.Text] = struct{}{}
+		result = append(result, candidate)
+		return len(result) < limit
+	}
+
+	for _, candidate := range completeSlashCommands(prefix, s.assistantTracker.AssistantType()) {
+		if !add(candidate) {
+			return result
+		}
+	}
+	for _, entry := range s.inputHistory.search("", 0) {
+		// 多行粘贴的输入不适合作为补全
+		if strings.Contains(entry.Text, "\n") || !strings.HasPrefix(entry.Text, prefix) {
+			continue
+		}
+		if !add(CompletionCandidate{Text: entry.Text, Kind: CompletionKindHistory}) {
+			return result
+		}
+	}
+	for _, candidate := range completePaths(s.WorkingDir(), s.CurrentDir(), prefix, completeMaxPaths) {
+		if !add(candidate) {
+			return result
+		}
+	}
+	return result
+}
+
+// completeSlashCommands matches prefix against the slash commands of the
+// running assistant, or of every assistant when none is detected.
+func completeSlashCommands(prefix string, assistant types.AssistantType) []CompletionCandidate {
+	if !strings.HasPrefix(prefix, "/") || strings.ContainsAny(prefix, " \t") {
+		return nil
+	}
+	known := assistant != "" && assistant != types.AssistantTypeUnknown
+	result := make([]CompletionCandidate, 0)
+	for _, command := range aiSlashCommands {
+		if known && len(command.Assistants) > 0 && !slices.Contains(command.Assistants, assistant) {
+			continue
+		}
+		if strings.HasPrefix(command.Name, prefix) {
+			result = append(result, CompletionCandidate{Text: command.Name, Kind: CompletionKindCommand, Description: command.Description})
+		}
+	}
+	return result
+}
+
+// completePaths completes the last word of prefix as a path relative to cwd.
+// Directories end with "/". Hidden entries are only offered when the word
+// already starts with a dot. Symlinks are resolved before the directory is
+// checked against root, so a link inside root cannot list what is outside.
+func completePaths(root, cwd, prefix string, limit int) []CompletionCandidate {
+	if root == "" || limit <= 0 {
+		return nil
+	}
+	realRoot, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil
+	}
+	start := strings.LastIndexAny(prefix, " \t") + 1
+	head, word := prefix[:start], prefix[start:]
+	dirPart, partial := path.Split(filepath.ToSlash(word))
+
+	base := cwd
+	if base == "" || !pathWithinRoot(root, base) {
+		base = root
+	}
+	dir := filepath.FromSlash(dirPart)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(base, dir)
+	}
+	realDir, err := filepath.EvalSymlinks(dir)
+	if err != nil || !pathWithinRoot(realRoot, realDir) {
+		return nil
+	}
+	entries, err := os.ReadDir(realDir)
+	if err != nil {
+		return nil
+	}
+
+	result := make([]CompletionCandidate, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if !strings.HasPrefix(name, partial) {
+			continue
+		}
+		if strings.HasPrefix(name, ".") && !strings.HasPrefix(partial, ".") {
+			continue
+		}
+		text := head + dirPart + name
+		if entry.IsDir() {
+			text += "/"
+		}
+		result = append(result, CompletionCandidate{Text: text, Kind: CompletionKindPath})
+		if len(result) >= limit {
+			break
+		}
+	}
+	return result
+}
+
+// Complete returns completion candidates for a session; see Session.Complete.
+func (m *Manager) Complete(id, prefix string, limit int) ([]CompletionCandidate, error) {
+	session, err := m.GetSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return session.Complete(prefix, limit), nil
+}
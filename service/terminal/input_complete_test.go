@@ -0,0 +1,113 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+func completionTexts(candidates []CompletionCandidate) []string {
+	texts := make([]string, 0, len(candidates))
+	for _, candidate := range candidates {
+		texts = append(texts, candidate.Text)
+	}
+	return texts
+}
+
+func TestSessionComplete(t *testing.T) {
+	root := t.TempDir()
+	for _, dir := range []string{"src/main", ".git"} {
+		if err := os.MkdirAll(filepath.Join(root, dir), 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, file := range []string{"src/server.go", "src/session.go", "README.md"} {
+		if err := os.WriteFile(filepath.Join(root, file), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	session := newTestSession(t, SessionParams{WorkingDir: root})
+	now := time.Now()
+	for _, line := range []string{"go test ./...", "git status", "go test ./src/...", "git status"} {
+		session.inputHistory.record([]byte(line+"\r"), false, now)
+	}
+
+	if got := completionTexts(session.Complete("go t", 0)); !slices.Equal(got, []string{"go test ./src/...", "go test ./..."}) {
+		t.Fatalf("unexpected history completions %q", got)
+	}
+	if got := completionTexts(session.Complete("cat src/se", 0)); !slices.Equal(got, []string{"cat src/server.go", "cat src/session.go"}) {
+		t.Fatalf("unexpected path completions %q", got)
+	}
+	if got := completionTexts(session.Complete("cd s", 0)); !slices.Equal(got, []string{"cd src/"}) {
+		t.Fatalf("unexpected directory completions %q", got)
+	}
+	// 隐藏文件只在以 . 开头时补全，不能越出工作目录
+	if got := completionTexts(session.Complete("ls .g", 0)); !slices.Equal(got, []string{"ls .git/"}) {
+		t.Fatalf("unexpected hidden completions %q", got)
+	}
+	if got := session.Complete("ls ../", 0); len(got) != 0 {
+		t.Fatalf("expected no completions outside the working directory, got %+v", got)
+	}
+	if got := session.Complete("cat "+filepath.Dir(root)+"/", 0); len(got) != 0 {
+		t.Fatalf("expected absolute paths outside the working directory to be ignored, got %+v", got)
+	}
+	if got := session.Complete("cat src/s", 1); len(got) != 1 {
+		t.Fatalf("expected limit to apply, got %+v", got)
+	}
+}
+
+func TestCompletePathsSymlinks(t *testing.T) {
+	outside := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outside, "secret.txt"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	target := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(target, "src"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(target, "src", "main.go"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// 工作目录本身是符号链接时仍可补全，指向工作目录之外的链接不能列出内容
+	root := filepath.Join(t.TempDir(), "root")
+	if err := os.Symlink(target, root); err != nil {
+		t.Skipf("symlink unavailable: %v", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(target, "escape")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(filepath.Join(target, "src"), filepath.Join(target, "inner")); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := completionTexts(completePaths(root, root, "cat src/m", 10)); !slices.Equal(got, []string{"cat src/main.go"}) {
+		t.Fatalf("unexpected completions under a symlinked root %q", got)
+	}
+	if got := completionTexts(completePaths(root, root, "cat inner/m", 10)); !slices.Equal(got, []string{"cat inner/main.go"}) {
+		t.Fatalf("unexpected completions through a link inside the root %q", got)
+	}
+	if got := completePaths(root, root, "cat escape/", 10); len(got) != 0 {
+		t.Fatalf("expected a link out of the root to be ignored, got %+v", got)
+	}
+	if got := completePaths(root, filepath.Join(root, "escape"), "cat s", 10); len(got) != 0 {
+		t.Fatalf("expected a working directory linked out of the root to be ignored, got %+v", got)
+	}
+}
+
+func TestCompleteSlashCommands(t *testing.T) {
+	got := completeSlashCommands("/c", types.AssistantTypeCodex)
+	if texts := completionTexts(got); !slices.Equal(texts, []string{"/compact"}) {
+		t.Fatalf("unexpected codex commands %q", texts)
+	}
+	if texts := completionTexts(completeSlashCommands("/c", types.AssistantTypeUnknown)); !slices.Equal(texts, []string{"/clear", "/compact", "/cost"}) {
+		t.Fatalf("unexpected commands without assistant %q", texts)
+	}
+	if got := completeSlashCommands("/compact now", types.AssistantTypeCodex); len(got) != 0 {
+		t.Fatalf("expected no commands once arguments are typed, got %+v", got)
+	}
+}
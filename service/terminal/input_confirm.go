@@ -0,0 +1,43 @@
+package terminal
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	inputPreviewMaxLines = 10
+	inputPreviewMaxRunes = 2000
+)
+
+// splitInputLines 按回车/换行拆分输入，结尾的单个换行不算额外的一行
+func splitInputLines(data string) []string {
+	normalized := strings.ReplaceAll(data, "\r\n", "\n")
+	normalized = strings.ReplaceAll(normalized, "\r", "\n")
+	normalized = strings.TrimSuffix(normalized, "\n")
+	return strings.Split(normalized, "\n")
+}
+
+// IsMultilineInput reports whether data would submit more than one line to the shell.
+func IsMultilineInput(data string) bool {
+	return len(splitInputLines(data)) > 1
+}
+
+// BuildInputPreview returns a bounded, human readable preview of pending input.
+func BuildInputPreview(data string) string {
+	lines := splitInputLines(data)
+	truncated := false
+	if len(lines) > inputPreviewMaxLines {
+		lines = lines[:inputPreviewMaxLines]
+		truncated = true
+	}
+	preview := strings.Join(lines, "\n")
+	if utf8.RuneCountInString(preview) > inputPreviewMaxRunes {
+		preview = string([]rune(preview)[:inputPreviewMaxRunes])
+		truncated = true
+	}
+	if truncated {
+		preview += "\n..."
+	}
+	return preview
+}
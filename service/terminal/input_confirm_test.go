@@ -0,0 +1,40 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsMultilineInput(t *testing.T) {
+	cases := []struct {
+		input string
+		want  bool
+	}{
+		{input: "ls", want: false},
+		{input: "ls\r", want: false},
+		{input: "ls\r\n", want: false},
+		{input: "ls\rpwd\r", want: true},
+		{input: "echo a\necho b", want: true},
+	}
+
+	for _, tc := range cases {
+		if got := IsMultilineInput(tc.input); got != tc.want {
+			t.Fatalf("IsMultilineInput(%q) = %v, want %v", tc.input, got, tc.want)
+		}
+	}
+}
+
+func TestBuildInputPreview(t *testing.T) {
+	if got := BuildInputPreview("ls\r\npwd\r"); got != "ls\npwd" {
+		t.Fatalf("unexpected preview %q", got)
+	}
+
+	long := strings.Repeat("echo hi\n", inputPreviewMaxLines+5)
+	preview := BuildInputPreview(long)
+	if !strings.HasSuffix(preview, "\n...") {
+		t.Fatalf("expected truncated preview, got %q", preview)
+	}
+	if lines := strings.Count(preview, "\n"); lines != inputPreviewMaxLines {
+		t.Fatalf("expected %d preview lines got %d", inputPreviewMaxLines, lines)
+	}
+}
@@ -0,0 +1,219 @@
+package terminal
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	// inputHistoryMaxEntries 每个会话保留的最近输入条数
+	inputHistoryMaxEntries = 500
+	// inputHistoryMaxLineBytes 单条输入的长度上限，超出部分丢弃
+	inputHistoryMaxLineBytes = 8 * 1024
+)
+
+// ErrInputHistoryEntryNotFound indicates there is no input history entry at the requested index.
+var ErrInputHistoryEntryNotFound = errors.New("input history entry not found")
+
+// InputHistoryEntry is one logical input line submitted with Enter.
+type InputHistoryEntry struct {
+	Text string    `json:"text"`
+	At   time.Time `json:"at"`
+}
+
+type inputParseState int
+
+const (
+	inputStateText inputParseState = iota
+	inputStateEscape
+	inputStateCSI
+	inputStateSS3
+)
+
+// inputHistory rebuilds submitted lines from interactive keystrokes. Line editing
+// is only approximated (backspace, Ctrl-U, Ctrl-W, Ctrl-C); cursor movement and
+// shell completion are not replayed. Lines typed while echo is off, and lines
+// starting with a space like HISTCONTROL=ignorespace, are not recorded.
+type inputHistory struct {
+	mu        sync.Mutex
+	entries   []InputHistoryEntry
+	line      []byte
+	sensitive bool
+	state     inputParseState
+	csiParams []byte
+	inPaste   bool
+	lastCR    bool
+}
+
+// record feeds one interactive write. hidden reports whether the PTY hid typed
+// input, such as at a password prompt, when the input was written.
+func (h *inputHistory) record(data []byte, hidden bool, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if hidden && len(data) > 0 {
+		h.sensitive = true
+	}
+	for _, b := range data {
+		switch h.state {
+		case inputStateEscape:
+			switch b {
+			case '[':
+				h.state = inputStateCSI
+				h.csiParams = h.csiParams[:0]
+			case 'O':
+				h.state = inputStateSS3
+			default:
+				h.state = inputStateText
+			}
+			continue
+		case inputStateCSI:
+			if b < 0x40 || b > 0x7e {
+				h.csiParams = append(h.csiParams, b)
+				continue
+			}
+			h.state = inputStateText
+			// 括号粘贴模式：粘贴内容中的换行不代表提交
+			if b == '~' {
+				switch string(h.csiParams) {
+				case "200":
+					h.inPaste = true
+				case "201":
+					h.inPaste = false
+				}
+			}
+			continue
+		case inputStateSS3:
+			h.state = inputStateText
+			continue
+		}
+
+		wasCR := h.lastCR
+		h.lastCR = b == '\r'
+		switch {
+		case b == 0x1b:
+			h.state = inputStateEscape
+		case b == '\r' || b == '\n':
+			if b == '\n' && wasCR {
+				continue
+			}
+			if h.inPaste {
+				h.appendLocked('\n')
+				continue
+			}
+			h.submitLocked(now)
+		case b == '\t' && h.inPaste:
+			h.appendLocked(b)
+		case b == 0x7f || b == 0x08:
+			if len(h.line) > 0 {
+				_, size := utf8.DecodeLastRune(h.line)
+				h.line = h.line[:len(h.line)-size]
+			}
+		case b == 0x03 || b == 0x15:
+			h.line = h.line[:0]
+			h.sensitive = hidden
+		case b == 0x17:
+			trimmed := strings.TrimRight(string(h.line), " ")
+			if idx := strings.LastIndexByte(trimmed, ' '); idx >= 0 {
+				h.line = h.line[:idx+1]
+			} else {
+				h.line = h.line[:0]
+			}
+		case b < 0x20:
+		default:
+			h.appendLocked(b)
+		}
+	}
+}
+
+func (h *inputHistory) appendLocked(b byte) {
+	if len(h.line) < inputHistoryMaxLineBytes {
+		h.line = append(h.line, b)
+	}
+}
+
+func (h *inputHistory) submitLocked(now time.Time) {
+	raw := string(h.line)
+	sensitive := h.sensitive
+	h.line = h.line[:0]
+	h.sensitive = false
+
+	text := strings.TrimSpace(strings.ToValidUTF8(raw, ""))
+	if sensitive || text == "" || strings.HasPrefix(raw, " ") {
+		return
+	}
+	h.entries = append(h.entries, InputHistoryEntry{Text: text, At: now})
+	if overflow := len(h.entries) - inputHistoryMaxEntries; overflow > 0 {
+		h.entries = append([]InputHistoryEntry(nil), h.entries[overflow:]...)
+	}
+}
+
+// search returns up to limit entries containing query (case-insensitive), newest
+// first. limit <= 0 returns every match.
+func (h *inputHistory) search(query string, limit int) []InputHistoryEntry {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	query = strings.ToLower(strings.TrimSpace(query))
+	result := make([]InputHistoryEntry, 0)
+	for i := len(h.entries) - 1; i >= 0; i-- {
+		if limit > 0 && len(result) >= limit {
+			break
+		}
+		entry := h.entries[i]
+		if query != "" && !strings.Contains(strings.ToLower(entry.Text), query) {
+			continue
+		}
+		result = append(result, entry)
+	}
+	return result
+}
+
+// entry returns the index-th entry counted from the newest, 0 being the latest.
+func (h *inputHistory) entry(index int) (InputHistoryEntry, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if index < 0 || index >= len(h.entries) {
+		return InputHistoryEntry{}, false
+	}
+	return h.entries[len(h.entries)-1-index], true
+}
+
+// InputHistory returns the lines the user submitted in this session, newest
+// first, optionally filtered by a case-insensitive substring.
+func (s *Session) InputHistory(query string, limit int) []InputHistoryEntry {
+	return s.inputHistory.search(query, limit)
+}
+
+// RepeatInput submits the index-th input history entry again, 0 being the most
+// recent one. Lines typed with echo off are never in the history, so they cannot
+// be repeated. Entries pasted over several lines are pasted again in bracketed
+// paste mode so the lines are not submitted one by one. The input goes through
+// Send like interactive input: it uses the session write lock and encoding, and
+// is recorded in the history again.
+func (s *Session) RepeatInput(index int) (InputHistoryEntry, error) {
+	entry, ok := s.inputHistory.entry(index)
+	if !ok {
+		return InputHistoryEntry{}, ErrInputHistoryEntryNotFound
+	}
+	payload := entry.Text + "\r"
+	if strings.Contains(entry.Text, "\n") {
+		payload = "\x1b[200~" + strings.ReplaceAll(entry.Text, "\n", "\r") + "\x1b[201~\r"
+	}
+	if _, err := s.Send([]byte(payload), false); err != nil {
+		return InputHistoryEntry{}, err
+	}
+	return entry, nil
+}
+
+// InputHistory returns the input history of a session; see Session.InputHistory.
+func (m *Manager) InputHistory(id, query string, limit int) ([]InputHistoryEntry, error) {
+	session, err := m.GetSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return session.InputHistory(query, limit), nil
+}
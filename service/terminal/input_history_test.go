@@ -0,0 +1,102 @@
+package terminal
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestInputHistoryRecordsSubmittedLines(t *testing.T) {
+	var history inputHistory
+	now := time.Now()
+	feed := func(data string, echoOff bool) {
+		history.record([]byte(data), echoOff, now)
+	}
+
+	feed("ls -la\r", false)
+	// 逐键输入、退格与方向键
+	for _, key := range []string{"g", "i", "x", "\x7f", "t", " ", "\x1b[A", "s", "\r"} {
+		feed(key, false)
+	}
+	feed("rm -rf /tmp/x\x15", false)
+	feed("echo 中文\x7f\x7f你好\r\n", false)
+	// 括号粘贴中的换行属于同一条输入
+	feed("\x1b[200~line one\r\nline two\x1b[201~\r", false)
+	// 关闭回显时的密码与空格开头的输入不记录
+	feed("hunter2\r", true)
+	feed(" secret-token\r", false)
+	feed("\r", false)
+
+	entries := history.search("", 0)
+	want := []string{"line one\nline two", "echo 你好", "git s", "ls -la"}
+	if len(entries) != len(want) {
+		t.Fatalf("expected %d entries, got %+v", len(want), entries)
+	}
+	for i, text := range want {
+		if entries[i].Text != text {
+			t.Fatalf("entry %d: expected %q, got %q", i, text, entries[i].Text)
+		}
+	}
+
+	if got := history.search("GIT", 0); len(got) != 1 || got[0].Text != "git s" {
+		t.Fatalf("unexpected search result %+v", got)
+	}
+	if got := history.search("", 2); len(got) != 2 || got[1].Text != "echo 你好" {
+		t.Fatalf("unexpected limited result %+v", got)
+	}
+}
+
+func TestInputHistoryKeepsRecentEntries(t *testing.T) {
+	var history inputHistory
+	for i := 0; i < inputHistoryMaxEntries+10; i++ {
+		history.record([]byte("cmd\r"), false, time.Now())
+	}
+	if got := len(history.search("", 0)); got != inputHistoryMaxEntries {
+		t.Fatalf("expected %d entries, got %d", inputHistoryMaxEntries, got)
+	}
+}
+
+func TestSessionRepeatInput(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	device := &chunkedPty{chunk: 64}
+	session.pty = device
+	session.inputReady = true
+	session.setStatus(SessionStatusRunning)
+
+	if _, err := session.RepeatInput(0); !errors.Is(err, ErrInputHistoryEntryNotFound) {
+		t.Fatalf("expected ErrInputHistoryEntryNotFound, got %v", err)
+	}
+	for _, input := range []string{"make build\r", "\x1b[200~one\r\ntwo\x1b[201~\r", "npm test\r"} {
+		if _, err := session.Write([]byte(input)); err != nil {
+			t.Fatalf("write failed: %v", err)
+		}
+	}
+	session.inputHidden.Store(true)
+	if _, err := session.Write([]byte("hunter2\r")); err != nil {
+		t.Fatalf("write failed: %v", err)
+	}
+	session.inputHidden.Store(false)
+	device.out.Reset()
+
+	// 关闭回显时的输入不可重跑，最近一次仍是 npm test
+	entry, err := session.RepeatInput(0)
+	if err != nil || entry.Text != "npm test" {
+		t.Fatalf("expected to repeat npm test, got %+v, %v", entry, err)
+	}
+	if got := device.out.String(); got != "npm test\r" {
+		t.Fatalf("unexpected repeated input %q", got)
+	}
+
+	// 重跑的输入重新记入历史，索引 2 为多行粘贴
+	device.out.Reset()
+	entry, err = session.RepeatInput(2)
+	if err != nil || entry.Text != "one\ntwo" {
+		t.Fatalf("expected to repeat the pasted lines, got %+v, %v", entry, err)
+	}
+	if got := device.out.String(); got != "\x1b[200~one\rtwo\x1b[201~\r" {
+		t.Fatalf("expected bracketed paste, got %q", got)
+	}
+	if latest := session.InputHistory("", 1); len(latest) != 1 || latest[0].Text != "one\ntwo" {
+		t.Fatalf("expected repeated input in history, got %+v", latest)
+	}
+}
@@ -0,0 +1,84 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrUnknownKey indicates a key name that has no byte sequence mapping.
+var ErrUnknownKey = errors.New("unknown terminal key")
+
+// keySequences 常用控制键对应的字节序列，方向键等使用普通（非应用）光标模式的 CSI 序列。
+// ctrl-a 到 ctrl-z 由 KeySequence 计算，不在表中列出
+var keySequences = map[string]string{
+	"enter":     "\r",
+	"tab":       "\t",
+	"shift-tab": "\x1b[Z",
+	"backspace": "\x7f",
+	"esc":       "\x1b",
+	"space":     " ",
+	"alt-enter": "\x1b\r",
+	"up":        "\x1b[A",
+	"down":      "\x1b[B",
+	"right":     "\x1b[C",
+	"left":      "\x1b[D",
+	"home":      "\x1b[H",
+	"end":       "\x1b[F",
+	"insert":    "\x1b[2~",
+	"delete":    "\x1b[3~",
+	"pageup":    "\x1b[5~",
+	"pagedown":  "\x1b[6~",
+	"f1":        "\x1bOP",
+	"f2":        "\x1bOQ",
+	"f3":        "\x1bOR",
+	"f4":        "\x1bOS",
+	"f5":        "\x1b[15~",
+	"f6":        "\x1b[17~",
+	"f7":        "\x1b[18~",
+	"f8":        "\x1b[19~",
+	"f9":        "\x1b[20~",
+	"f10":       "\x1b[21~",
+	"f11":       "\x1b[23~",
+	"f12":       "\x1b[24~",
+}
+
+// keyAliases 前端常见的其他写法
+var keyAliases = map[string]string{
+	"return":     "enter",
+	"escape":     "esc",
+	"del":        "delete",
+	"ins":        "insert",
+	"pgup":       "pageup",
+	"pgdn":       "pagedown",
+	"arrowup":    "up",
+	"arrowdown":  "down",
+	"arrowleft":  "left",
+	"arrowright": "right",
+}
+
+// KeySequence returns the bytes sent to the PTY for a named key such as "enter",
+// "up" or "ctrl-c". Names are case-insensitive and "+" may be used instead of "-".
+func KeySequence(key string) ([]byte, error) {
+	name := strings.ReplaceAll(strings.ToLower(strings.TrimSpace(key)), "+", "-")
+	if alias, ok := keyAliases[name]; ok {
+		name = alias
+	}
+	if seq, ok := keySequences[name]; ok {
+		return []byte(seq), nil
+	}
+	if letter, ok := strings.CutPrefix(name, "ctrl-"); ok && len(letter) == 1 && letter[0] >= 'a' && letter[0] <= 'z' {
+		return []byte{letter[0] - 'a' + 1}, nil
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownKey, key)
+}
+
+// WriteKey writes the byte sequence of a named key to the PTY.
+func (s *Session) WriteKey(key string) error {
+	seq, err := KeySequence(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.Write(seq)
+	return err
+}
@@ -0,0 +1,30 @@
+package terminal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestKeySequence(t *testing.T) {
+	cases := map[string]string{
+		"enter":     "\r",
+		"Up":        "\x1b[A",
+		"ArrowLeft": "\x1b[D",
+		"esc":       "\x1b",
+		"ctrl-c":    "\x03",
+		"Ctrl+D":    "\x04",
+		"shift-tab": "\x1b[Z",
+		"f5":        "\x1b[15~",
+	}
+	for key, want := range cases {
+		got, err := KeySequence(key)
+		if err != nil || string(got) != want {
+			t.Fatalf("KeySequence(%q) = %q, %v; want %q", key, got, err, want)
+		}
+	}
+	for _, key := range []string{"", "ctrl-1", "ctrl-ab", "hyper"} {
+		if _, err := KeySequence(key); !errors.Is(err, ErrUnknownKey) {
+			t.Fatalf("KeySequence(%q) expected ErrUnknownKey, got %v", key, err)
+		}
+	}
+}
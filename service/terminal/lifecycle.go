@@ -0,0 +1,146 @@
+package terminal
+
+import (
+	"errors"
+	"os/exec"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Session lifecycle event types.
+const (
+	SessionEventCreated = "session.created"
+	SessionEventClosed  = "session.closed"
+)
+
+// SessionLifecycleEvent describes a session being created or closed. It is the
+// payload of lifecycle hooks and of the lifecycle webhook.
+type SessionLifecycleEvent struct {
+	Type       string    `json:"type"`
+	SessionID  string    `json:"sessionId"`
+	ProjectID  string    `json:"projectId"`
+	WorktreeID string    `json:"worktreeId"`
+	Title      string    `json:"title"`
+	WorkingDir string    `json:"workingDir"`
+	ShellName  string    `json:"shellName,omitempty"`
+	TaskID     string    `json:"taskId,omitempty"`
+	QuotaKey   string    `json:"quotaKey,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+	// ClosedAt / DurationSeconds 仅关闭事件有值
+	ClosedAt        *time.Time `json:"closedAt,omitempty"`
+	DurationSeconds float64    `json:"durationSeconds,omitempty"`
+	// ExitCode shell 自行退出时的退出码；会话被直接关闭、进程被杀掉时为空
+	ExitCode *int   `json:"exitCode,omitempty"`
+	Error    string `json:"error,omitempty"`
+	Restarts int64  `json:"restarts,omitempty"`
+}
+
+// SessionLifecycleHook receives session lifecycle events. Hooks run on their
+// own goroutine, so a slow or panicking hook never holds up the session.
+type SessionLifecycleHook func(event SessionLifecycleEvent)
+
+// OnSessionCreated registers a hook called after a session has started.
+func (m *Manager) OnSessionCreated(hook SessionLifecycleHook) {
+	if hook == nil {
+		return
+	}
+	m.lifecycleMu.Lock()
+	m.onSessionCreated = append(m.onSessionCreated, hook)
+	m.lifecycleMu.Unlock()
+}
+
+// OnSessionClosed registers a hook called once a session has terminated.
+func (m *Manager) OnSessionClosed(hook SessionLifecycleHook) {
+	if hook == nil {
+		return
+	}
+	m.lifecycleMu.Lock()
+	m.onSessionClosed = append(m.onSessionClosed, hook)
+	m.lifecycleMu.Unlock()
+}
+
+func (m *Manager) notifySessionCreated(session *Session) {
+	m.lifecycleMu.RLock()
+	hooks := append([]SessionLifecycleHook(nil), m.onSessionCreated...)
+	m.lifecycleMu.RUnlock()
+	if len(hooks) > 0 {
+		m.runLifecycleHooks(hooks, session.lifecycleEvent(SessionEventCreated))
+	}
+}
+
+func (m *Manager) notifySessionClosed(session *Session) {
+	m.lifecycleMu.RLock()
+	hooks := append([]SessionLifecycleHook(nil), m.onSessionClosed...)
+	m.lifecycleMu.RUnlock()
+	if len(hooks) > 0 {
+		m.runLifecycleHooks(hooks, session.lifecycleEvent(SessionEventClosed))
+	}
+}
+
+func (m *Manager) runLifecycleHooks(hooks []SessionLifecycleHook, event SessionLifecycleEvent) {
+	for _, hook := range hooks {
+		go func(hook SessionLifecycleHook) {
+			defer func() {
+				if r := recover(); r != nil {
+					m.logger.Error("session lifecycle hook panicked",
+						zap.String("event", event.Type),
+						zap.String("sessionId", event.SessionID),
+						zap.Any("panic", r),
+					)
+				}
+			}()
+			hook(event)
+		}(hook)
+	}
+}
+
+// lifecycleEvent builds the event of the given type from the current session state.
+func (s *Session) lifecycleEvent(eventType string) SessionLifecycleEvent {
+	s.mu.RLock()
+	event := SessionLifecycleEvent{
+		Type:       eventType,
+		SessionID:  s.id,
+		ProjectID:  s.projectID,
+		WorktreeID: s.worktreeID,
+		Title:      s.title,
+		WorkingDir: s.workingDir,
+		ShellName:  s.shellName,
+		CreatedAt:  s.createdAt,
+	}
+	s.mu.RUnlock()
+	event.TaskID = s.TaskID()
+	event.QuotaKey = s.QuotaKey()
+	event.Restarts = s.restart.total.Load()
+
+	if eventType == SessionEventClosed {
+		closedAt := time.Now()
+		event.ClosedAt = &closedAt
+		event.DurationSeconds = closedAt.Sub(event.CreatedAt).Seconds()
+		if code, ok := s.ExitCode(); ok {
+			event.ExitCode = &code
+		}
+		if err := s.Err(); err != nil {
+			event.Error = err.Error()
+		}
+	}
+	return event
+}
+
+// ExitCode returns the exit code of the shell once it has exited on its own.
+// It reports false while the shell runs, or when it was killed by Close before
+// its exit status was collected.
+func (s *Session) ExitCode() (int, bool) {
+	value, ok := s.err.Load().(sessionError)
+	if !ok || !s.exited.Load() {
+		return 0, false
+	}
+	if value.err == nil {
+		return 0, true
+	}
+	var exitErr *exec.ExitError
+	if errors.As(value.err, &exitErr) && exitErr.ExitCode() >= 0 {
+		return exitErr.ExitCode(), true
+	}
+	return 0, false
+}
@@ -0,0 +1,194 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+const (
+	// TerminalLinkURL marks an http(s) URL.
+	TerminalLinkURL = "url"
+	// TerminalLinkFile marks a file path that exists inside the worktree.
+	TerminalLinkFile = "file"
+
+	maxTerminalLinks   = 64
+	linkBufferMaxBytes = 64 * 1024
+)
+
+var (
+	linkURLPattern   = regexp.MustCompile(`https?://[^\s<>"'` + "`" + `]+`)
+	linkPathPattern  = regexp.MustCompile(`[\w.~@+\-/\\]+(?::\d+){0,2}`)
+	linkTrailingPunc = ".,;:!?)]}>'\""
+)
+
+// TerminalLink is a clickable candidate detected from rendered terminal output.
+type TerminalLink struct {
+	Kind   string `json:"kind"`             // url 或 file
+	Text   string `json:"text"`             // 屏幕上显示的文本
+	Target string `json:"target"`           // URL 或文件的绝对路径
+	Line   int    `json:"line,omitempty"`   // 文件路径附带的行号
+	Column int    `json:"column,omitempty"` // 文件路径附带的列号
+	Row    int    `json:"row"`              // 起始屏幕行（从 0 开始）
+	Col    int    `json:"col"`              // 起始屏幕列（从 0 开始）
+	Length int    `json:"length"`           // 文本占用的字符数
+}
+
+// detectTerminalLinks scans rendered logical lines for URLs and for file paths
+// that resolve to an existing file inside root. Relative paths are resolved
+// against workingDir first and root second.
+func detectTerminalLinks(lines []ai_assistant2.LogicalLine, root, workingDir string) []TerminalLink {
+	links := make([]TerminalLink, 0)
+	for _, line := range lines {
+		if line.Text == "" {
+			continue
+		}
+
+		urlRanges := linkURLPattern.FindAllStringIndex(line.Text, -1)
+		for _, loc := range urlRanges {
+			text := strings.TrimRight(line.Text[loc[0]:loc[1]], linkTrailingPunc)
+			if link, ok := newTerminalLink(line, loc[0], text); ok {
+				link.Kind = TerminalLinkURL
+				link.Target = text
+				links = append(links, link)
+			}
+		}
+
+		if root == "" {
+			continue
+		}
+		for _, loc := range linkPathPattern.FindAllStringIndex(line.Text, -1) {
+			if overlapsRanges(loc, urlRanges) {
+				continue
+			}
+			text := strings.TrimRight(line.Text[loc[0]:loc[1]], linkTrailingPunc)
+			target, lineNo, column, ok := resolveLinkPath(text, root, workingDir)
+			if !ok {
+				continue
+			}
+			if link, ok := newTerminalLink(line, loc[0], text); ok {
+				link.Kind = TerminalLinkFile
+				link.Target = target
+				link.Line = lineNo
+				link.Column = column
+				links = append(links, link)
+			}
+		}
+
+		if len(links) >= maxTerminalLinks {
+			return links[:maxTerminalLinks]
+		}
+	}
+	return links
+}
+
+func newTerminalLink(line ai_assistant2.LogicalLine, byteStart int, text string) (TerminalLink, bool) {
+	if text == "" {
+		return TerminalLink{}, false
+	}
+	start := utf8.RuneCountInString(line.Text[:byteStart])
+	if start >= len(line.Cells) {
+		return TerminalLink{}, false
+	}
+	cell := line.Cells[start]
+	return TerminalLink{
+		Text:   text,
+		Row:    cell.Row,
+		Col:    cell.Col,
+		Length: utf8.RuneCountInString(text),
+	}, true
+}
+
+// resolveLinkPath splits an optional :line[:column] suffix and checks the path
+// points at an existing regular file inside root.
+func resolveLinkPath(text, root, workingDir string) (string, int, int, bool) {
+	// 至少包含目录分隔符或扩展名，避免把普通单词当作路径
+	if !strings.ContainsAny(text, `/\.`) {
+		return "", 0, 0, false
+	}
+
+	pathPart := text
+	lineNo, column := 0, 0
+	if parts := strings.Split(text, ":"); len(parts) > 1 {
+		numbers := make([]int, 0, 2)
+		idx := len(parts)
+		for idx > 1 && len(numbers) < 2 {
+			value, err := strconv.Atoi(parts[idx-1])
+			if err != nil {
+				break
+			}
+			numbers = append([]int{value}, numbers...)
+			idx--
+		}
+		pathPart = strings.Join(parts[:idx], ":")
+		if len(numbers) > 0 {
+			lineNo = numbers[0]
+		}
+		if len(numbers) > 1 {
+			column = numbers[1]
+		}
+	}
+	if pathPart == "" || pathPart == "." || pathPart == ".." {
+		return "", 0, 0, false
+	}
+
+	candidates := make([]string, 0, 2)
+	if filepath.IsAbs(pathPart) {
+		candidates = append(candidates, filepath.Clean(pathPart))
+	} else {
+		if workingDir != "" {
+			candidates = append(candidates, filepath.Join(workingDir, pathPart))
+		}
+		candidates = append(candidates, filepath.Join(root, pathPart))
+	}
+
+	for _, candidate := range candidates {
+		if !pathWithinRoot(root, candidate) {
+			continue
+		}
+		info, err := os.Stat(candidate)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		return candidate, lineNo, column, true
+	}
+	return "", 0, 0, false
+}
+
+func pathWithinRoot(root, target string) bool {
+	rel, err := filepath.Rel(filepath.Clean(root), filepath.Clean(target))
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+func overlapsRanges(loc []int, ranges [][]int) bool {
+	for _, r := range ranges {
+		if loc[0] < r[1] && r[0] < loc[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// linksSignature builds a comparable key so unchanged candidates are not re-sent.
+func linksSignature(links []TerminalLink) string {
+	var b strings.Builder
+	for _, link := range links {
+		b.WriteString(link.Kind)
+		b.WriteByte('|')
+		b.WriteString(link.Target)
+		b.WriteByte('|')
+		b.WriteString(strconv.Itoa(link.Row))
+		b.WriteByte(',')
+		b.WriteString(strconv.Itoa(link.Col))
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
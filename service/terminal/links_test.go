@@ -0,0 +1,44 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+func TestDetectTerminalLinks(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "src"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	mainFile := filepath.Join(root, "src", "main.go")
+	if err := os.WriteFile(mainFile, []byte("package main\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	// 第一行在第 40 列软换行，URL 跨两行
+	data := []byte("\x1b[31merror\x1b[0m in src/main.go:12:3, see https://example.com/docs/page.\r\n" +
+		"missing.go:1 ../outside.txt\r\n")
+	lines := ai_assistant2.RenderLogicalLinesFromBuffer(data, 10, 40)
+	links := detectTerminalLinks(lines, root, root)
+
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links got %#v", links)
+	}
+
+	file := links[1]
+	if links[0].Kind != TerminalLinkURL || links[0].Target != "https://example.com/docs/page" {
+		t.Fatalf("unexpected url link %#v", links[0])
+	}
+	if links[0].Row != 0 || links[0].Col != 31 || links[0].Length != 29 {
+		t.Fatalf("unexpected url position %#v", links[0])
+	}
+	if file.Kind != TerminalLinkFile || file.Target != mainFile || file.Line != 12 || file.Column != 3 {
+		t.Fatalf("unexpected file link %#v", file)
+	}
+	if file.Row != 0 || file.Col != 9 || file.Text != "src/main.go:12:3" {
+		t.Fatalf("unexpected file position %#v", file)
+	}
+}
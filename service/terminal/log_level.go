@@ -0,0 +1,82 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// ErrInvalidLogLevel indicates the requested session log level is not a zap level.
+var ErrInvalidLogLevel = errors.New("invalid log level")
+
+// sessionLevelCore lets one session log at its own level. Without an override
+// it defers to the wrapped core; with one, entries at or above the override
+// are written to the wrapped core directly, bypassing the global level.
+type sessionLevelCore struct {
+	zapcore.Core
+	override *atomic.Pointer[zapcore.Level]
+}
+
+func (c *sessionLevelCore) Enabled(level zapcore.Level) bool {
+	if override := c.override.Load(); override != nil {
+		return level >= *override
+	}
+	return c.Core.Enabled(level)
+}
+
+func (c *sessionLevelCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sessionLevelCore{Core: c.Core.With(fields), override: c.override}
+}
+
+func (c *sessionLevelCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	override := c.override.Load()
+	if override == nil {
+		return c.Core.Check(entry, checked)
+	}
+	if entry.Level < *override {
+		return checked
+	}
+	return checked.AddCore(entry, c.Core)
+}
+
+// wrapSessionLogger routes logger through the session level override.
+func (s *Session) wrapSessionLogger(logger *zap.Logger) *zap.Logger {
+	return logger.WithOptions(zap.WrapCore(func(core zapcore.Core) zapcore.Core {
+		return &sessionLevelCore{Core: core, override: &s.logLevel}
+	}))
+}
+
+// SetLogLevel overrides the log level of this session only, e.g. "debug" to
+// trace its output, broadcasts and assistant detection while diagnosing it.
+// An empty level or "default" removes the override.
+func (s *Session) SetLogLevel(level string) error {
+	level = strings.ToLower(strings.TrimSpace(level))
+	if level == "" || level == "default" {
+		s.logLevel.Store(nil)
+		return nil
+	}
+	parsed, err := zapcore.ParseLevel(level)
+	if err != nil {
+		return fmt.Errorf("%w: %q", ErrInvalidLogLevel, level)
+	}
+	s.logLevel.Store(&parsed)
+	return nil
+}
+
+// LogLevel returns the session log level override, empty when the global
+// level applies.
+func (s *Session) LogLevel() string {
+	if level := s.logLevel.Load(); level != nil {
+		return level.String()
+	}
+	return ""
+}
+
+// debugEnabled 会话日志是否输出 debug，热路径上先判断再构造字段
+func (s *Session) debugEnabled() bool {
+	return s.logger.Core().Enabled(zapcore.DebugLevel)
+}
@@ -0,0 +1,49 @@
+package terminal
+
+import (
+	"errors"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSessionLogLevelOverride(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	global := zap.New(core)
+	session := newTestSession(t, SessionParams{Logger: global})
+
+	session.broadcast(StreamEvent{Type: StreamEventData, Data: []byte("x")})
+	if logs.Len() != 0 {
+		t.Fatalf("expected no debug logs at the global level, got %d", logs.Len())
+	}
+
+	if err := session.SetLogLevel("DEBUG"); err != nil {
+		t.Fatalf("SetLogLevel: %v", err)
+	}
+	if session.LogLevel() != "debug" {
+		t.Fatalf("unexpected log level %q", session.LogLevel())
+	}
+	session.broadcast(StreamEvent{Type: StreamEventData, Data: []byte("x")})
+	entries := logs.FilterMessage("terminal event broadcast").All()
+	if len(entries) != 1 || entries[0].ContextMap()["sessionId"] != session.ID() {
+		t.Fatalf("expected one debug broadcast entry, got %+v", logs.All())
+	}
+	// 覆盖只作用于该会话，全局日志器不受影响
+	global.Debug("global debug")
+	if logs.FilterMessage("global debug").Len() != 0 {
+		t.Fatalf("expected global logger to keep its level")
+	}
+
+	if err := session.SetLogLevel("verbose"); !errors.Is(err, ErrInvalidLogLevel) {
+		t.Fatalf("expected ErrInvalidLogLevel, got %v", err)
+	}
+	if err := session.SetLogLevel("default"); err != nil {
+		t.Fatalf("SetLogLevel: %v", err)
+	}
+	session.broadcast(StreamEvent{Type: StreamEventData, Data: []byte("x")})
+	if got := logs.FilterMessage("terminal event broadcast").Len(); got != 1 {
+		t.Fatalf("expected no more debug logs after reset, got %d", got)
+	}
+}
@@ -0,0 +1,76 @@
+package terminal
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// TaskTypeBuild 标记前台运行的是构建/测试等非 AI 长任务
+const TaskTypeBuild = "build"
+
+// longTaskTools 常见构建工具的可执行文件名（不含扩展名）
+var longTaskTools = map[string]struct{}{
+	"npm": {}, "pnpm": {}, "yarn": {}, "bun": {}, "npx": {},
+	"cargo": {}, "go": {}, "make": {}, "cmake": {}, "ninja": {},
+	"gradle": {}, "gradlew": {}, "mvn": {}, "mvnw": {}, "dotnet": {},
+	"pytest": {}, "tox": {}, "tsc": {}, "vite": {}, "webpack": {},
+}
+
+// longTaskWrappers 解释器本身不算长任务，需要看它执行的脚本
+var longTaskWrappers = map[string]struct{}{
+	"node": {}, "python": {}, "python3": {}, "sh": {}, "bash": {},
+}
+
+// DetectLongTaskType returns TaskTypeBuild when the command line runs a known
+// build tool, e.g. "npm run build", "/usr/bin/make -j8" or "node /usr/lib/npm-cli.js test".
+func DetectLongTaskType(command string) string {
+	fields := strings.Fields(command)
+	for i := 0; i < len(fields) && i < 2; i++ {
+		name := commandBaseName(fields[i])
+		if _, ok := longTaskTools[name]; ok {
+			return TaskTypeBuild
+		}
+		if _, ok := longTaskTools[strings.TrimSuffix(name, "-cli")]; ok {
+			return TaskTypeBuild
+		}
+		if _, ok := longTaskWrappers[name]; !ok {
+			break
+		}
+	}
+	return ""
+}
+
+func commandBaseName(field string) string {
+	field = strings.Trim(field, `"'`)
+	name := strings.ToLower(filepath.Base(strings.ReplaceAll(field, "\\", "/")))
+	for _, ext := range []string{".exe", ".cmd", ".bat", ".js", ".sh"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
+// applyLongTask marks metadata of a busy non-AI build command and keeps the start
+// time stable while the same command keeps running.
+func (s *Session) applyLongTask(metadata *SessionMetadata, now time.Time) {
+	taskType := ""
+	if metadata.ProcessHasChildren && metadata.AIAssistant == nil {
+		taskType = DetectLongTaskType(metadata.RunningCommand)
+	}
+
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+	if taskType == "" {
+		s.longTaskCommand = ""
+		s.longTaskStartedAt = time.Time{}
+		return
+	}
+	if s.longTaskCommand != metadata.RunningCommand || s.longTaskStartedAt.IsZero() {
+		s.longTaskCommand = metadata.RunningCommand
+		s.longTaskStartedAt = now
+	}
+	startedAt := s.longTaskStartedAt
+	metadata.TaskType = taskType
+	metadata.TaskStartedAt = &startedAt
+	metadata.TaskDurationSeconds = int64(now.Sub(startedAt) / time.Second)
+}
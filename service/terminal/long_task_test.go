@@ -0,0 +1,51 @@
+package terminal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDetectLongTaskType(t *testing.T) {
+	cases := []struct {
+		command string
+		want    string
+	}{
+		{command: "npm run build", want: TaskTypeBuild},
+		{command: "/usr/bin/make -j8", want: TaskTypeBuild},
+		{command: "go test ./...", want: TaskTypeBuild},
+		{command: `"C:\nodejs\npm.cmd" install`, want: TaskTypeBuild},
+		{command: "node /usr/lib/node_modules/npm/bin/npm-cli.js test", want: TaskTypeBuild},
+		{command: "cargo build --release", want: TaskTypeBuild},
+		{command: "vim main.go", want: ""},
+		{command: "node server.js", want: ""},
+		{command: "", want: ""},
+	}
+	for _, tc := range cases {
+		if got := DetectLongTaskType(tc.command); got != tc.want {
+			t.Fatalf("DetectLongTaskType(%q) = %q, want %q", tc.command, got, tc.want)
+		}
+	}
+}
+
+func TestSessionApplyLongTask(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	start := time.Now()
+
+	meta := &SessionMetadata{ProcessHasChildren: true, RunningCommand: "make all"}
+	session.applyLongTask(meta, start)
+	if meta.TaskType != TaskTypeBuild || meta.TaskStartedAt == nil || !meta.TaskStartedAt.Equal(start) {
+		t.Fatalf("unexpected long task metadata %+v", meta)
+	}
+
+	meta = &SessionMetadata{ProcessHasChildren: true, RunningCommand: "make all"}
+	session.applyLongTask(meta, start.Add(90*time.Second))
+	if !meta.TaskStartedAt.Equal(start) || meta.TaskDurationSeconds != 90 {
+		t.Fatalf("expected stable start time and 90s duration, got %+v", meta)
+	}
+
+	meta = &SessionMetadata{ProcessHasChildren: false}
+	session.applyLongTask(meta, start.Add(2*time.Minute))
+	if meta.TaskType != "" || meta.TaskStartedAt != nil {
+		t.Fatalf("expected long task to be cleared, got %+v", meta)
+	}
+}
@@ -0,0 +1,133 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+const macroInputMaxBytes = 4096
+
+// ErrMacroNotFound indicates no macro with the name applies to the session.
+var ErrMacroNotFound = errors.New("terminal macro not found")
+
+// macroNamePattern 宏名称会出现在 URL 路径中，只允许字母数字与 ._-
+var macroNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,63}$`)
+
+// InputMacro writes Input to the terminal in one step when executed by name.
+type InputMacro struct {
+	Name  string `json:"name" yaml:"name"`
+	Input string `json:"input" yaml:"input"`
+	// Assistant 限定宏只在该类型的 AI 助手运行时可用（如 claude-code），为空对所有会话生效
+	Assistant string `json:"assistant,omitempty" yaml:"assistant"`
+}
+
+// validateMacros checks names, inputs and scopes; a name may be defined once per scope.
+func validateMacros(macros []InputMacro) error {
+	seen := make(map[string]struct{}, len(macros))
+	for i, macro := range macros {
+		if !macroNamePattern.MatchString(macro.Name) {
+			return fmt.Errorf("macro %d: invalid name %q", i, macro.Name)
+		}
+		if macro.Input == "" {
+			return fmt.Errorf("macro %q: input is required", macro.Name)
+		}
+		if len(macro.Input) > macroInputMaxBytes {
+			return fmt.Errorf("macro %q: input exceeds %d bytes", macro.Name, macroInputMaxBytes)
+		}
+		if macro.Assistant != "" && !knownAssistantType(macro.Assistant) {
+			return fmt.Errorf("macro %q: unknown assistant %q", macro.Name, macro.Assistant)
+		}
+		key := macro.Name + "\x00" + macro.Assistant
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("macro %q: defined more than once", macro.Name)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
+func knownAssistantType(name string) bool {
+	for _, assistantType := range types.AllAssistantTypes() {
+		if string(assistantType) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// setGlobalMacros replaces the macros shared by all sessions.
+func (s *Session) setGlobalMacros(macros []InputMacro) {
+	s.macroMu.Lock()
+	s.globalMacros = append([]InputMacro(nil), macros...)
+	s.macroMu.Unlock()
+}
+
+// SetMacros replaces the session scoped macros.
+func (s *Session) SetMacros(macros []InputMacro) error {
+	if err := validateMacros(macros); err != nil {
+		return err
+	}
+	s.macroMu.Lock()
+	s.sessionMacros = append([]InputMacro(nil), macros...)
+	s.macroMu.Unlock()
+	return nil
+}
+
+// Macros returns the session scoped macros followed by the global ones.
+func (s *Session) Macros() []InputMacro {
+	s.macroMu.Lock()
+	defer s.macroMu.Unlock()
+	result := make([]InputMacro, 0, len(s.sessionMacros)+len(s.globalMacros))
+	result = append(result, s.sessionMacros...)
+	return append(result, s.globalMacros...)
+}
+
+// RunMacro writes the input of the named macro. A macro scoped to the running
+// assistant wins over an unscoped one, and session macros win over global ones.
+// The input goes through WriteInput, so it is written under the session write
+// lock and never interleaves with other input.
+func (s *Session) RunMacro(name string) (InputMacro, error) {
+	macro, ok := s.resolveMacro(name, s.currentAssistantType())
+	if !ok {
+		return InputMacro{}, fmt.Errorf("%w: %s", ErrMacroNotFound, name)
+	}
+	if err := s.WriteInput([]byte(macro.Input)); err != nil {
+		return InputMacro{}, err
+	}
+	return macro, nil
+}
+
+func (s *Session) resolveMacro(name, assistant string) (InputMacro, bool) {
+	var fallback *InputMacro
+	for _, macro := range s.Macros() {
+		if macro.Name != name {
+			continue
+		}
+		if macro.Assistant != "" {
+			if macro.Assistant == assistant {
+				return macro, true
+			}
+			continue
+		}
+		if fallback == nil {
+			fallback = &macro
+		}
+	}
+	if fallback == nil {
+		return InputMacro{}, false
+	}
+	return *fallback, true
+}
+
+// currentAssistantType returns the AI assistant reported by the last metadata, "" when none.
+func (s *Session) currentAssistantType() string {
+	s.metaMu.RLock()
+	defer s.metaMu.RUnlock()
+	if s.lastMetadata == nil || s.lastMetadata.AIAssistant == nil {
+		return ""
+	}
+	return s.lastMetadata.AIAssistant.Type
+}
@@ -0,0 +1,104 @@
+package terminal
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidateMacros(t *testing.T) {
+	invalid := [][]InputMacro{
+		{{Name: "bad name", Input: "x"}},
+		{{Name: "clear", Input: ""}},
+		{{Name: "clear", Input: "/clear\r", Assistant: "vim"}},
+		{{Name: "clear", Input: "a"}, {Name: "clear", Input: "b"}},
+	}
+	for _, macros := range invalid {
+		if err := validateMacros(macros); err == nil {
+			t.Fatalf("expected %+v to be rejected", macros)
+		}
+	}
+	if err := validateMacros([]InputMacro{
+		{Name: "clear", Input: "clear\r"},
+		{Name: "clear", Input: "/clear\r", Assistant: "claude-code"},
+	}); err != nil {
+		t.Fatalf("expected scoped duplicates to be allowed: %v", err)
+	}
+}
+
+func TestSessionResolveMacro(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	session.setGlobalMacros([]InputMacro{
+		{Name: "clear", Input: "global\r"},
+		{Name: "clear", Input: "/clear\r", Assistant: "claude-code"},
+		{Name: "compact", Input: "/compact\r", Assistant: "claude-code"},
+	})
+	if err := session.SetMacros([]InputMacro{{Name: "clear", Input: "session\r"}}); err != nil {
+		t.Fatalf("SetMacros failed: %v", err)
+	}
+
+	cases := []struct {
+		name, assistant, want string
+		found                 bool
+	}{
+		{"clear", "", "session\r", true},
+		{"clear", "claude-code", "/clear\r", true},
+		{"clear", "codex", "session\r", true},
+		{"compact", "", "", false},
+		{"compact", "claude-code", "/compact\r", true},
+		{"missing", "", "", false},
+	}
+	for _, tc := range cases {
+		macro, ok := session.resolveMacro(tc.name, tc.assistant)
+		if ok != tc.found || macro.Input != tc.want {
+			t.Fatalf("resolveMacro(%q, %q) = %q, %v; want %q, %v", tc.name, tc.assistant, macro.Input, ok, tc.want, tc.found)
+		}
+	}
+}
+
+func TestSessionRunMacro(t *testing.T) {
+	session := newTestSession(t, SessionParams{
+		Command: []string{"sh", "-c", `read answer; echo "got:$answer"; sleep 5`},
+	})
+	if err := session.SetMacros([]InputMacro{{Name: "answer", Input: "macro\r"}}); err != nil {
+		t.Fatalf("SetMacros failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := session.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+	if err := session.Start(ctx); err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer session.Close()
+
+	if _, err := session.RunMacro("missing"); !errors.Is(err, ErrMacroNotFound) {
+		t.Fatalf("expected ErrMacroNotFound, got %v", err)
+	}
+	if _, err := session.RunMacro("answer"); err != nil {
+		t.Fatalf("RunMacro failed: %v", err)
+	}
+
+	var output strings.Builder
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-stream.Events():
+			if event.Type != StreamEventData {
+				continue
+			}
+			output.Write(event.Data)
+			if strings.Contains(output.String(), "got:macro") {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("macro input was not written, output %q", output.String())
+		}
+	}
+}
@@ -4,16 +4,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
+	"math"
+	"runtime"
+	"slices"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 	"unicode/utf8"
 
 	"go.uber.org/zap"
 
+	"code-kanban/model"
+	"code-kanban/model/tables"
 	"code-kanban/utils"
 	"code-kanban/utils/ai_assistant2"
 	"code-kanban/utils/ai_assistant2/types"
+	"code-kanban/utils/cache"
+	"code-kanban/utils/model_base"
+	"code-kanban/utils/process"
 )
 
 // Config defines runtime constraints for terminal sessions.
@@ -27,6 +38,73 @@ type Config struct {
 	ScrollbackEnabled         bool
 	RenameTitleEachCommand    bool
 	AutoCreateTaskOnStartWork bool
+	DetectLinks               bool
+	// ConfirmMultilineInput 多行输入先返回预览，客户端确认后再写入
+	ConfirmMultilineInput bool
+	// AIDetectionDiagnostics 开启 AI 状态检测诊断记录
+	AIDetectionDiagnostics bool
+	// CaptureRawOutput 每个会话保留最近的原始 PTY 字节，排查乱码时开启
+	CaptureRawOutput bool
+	// CloseOnMaxLifetime 会话超过最大运行时长时自动关闭，否则只发出警告
+	CloseOnMaxLifetime bool
+	// QuotaMaxSessions / QuotaMaxMemoryBytes 按 quotaKey 聚合的上限，0 表示不限制
+	QuotaMaxSessions    int
+	QuotaMaxMemoryBytes uint64
+	// RecordEvents 事件记录模式：空不记录，memory 保存在内存，file 写入 RecordEventsDir
+	RecordEvents    string
+	RecordEventsDir string
+	// RecordEventsMaxFiles file 模式下保留事件文件的已关闭会话数，0 使用默认 50
+	RecordEventsMaxFiles int
+	// OutputTriggers 对所有会话生效的输出触发器
+	OutputTriggers []OutputTrigger
+	// Macros 对所有会话生效的输入宏，可按助手类型限定
+	Macros []InputMacro
+	// TitleRules 按输出内容设置会话标题的规则，可按助手类型限定
+	TitleRules []TitleRule
+	// HighlightRules 标注输出中关键字的规则，命中范围通过 highlights 事件下发
+	HighlightRules []HighlightRule
+	// AlertRules 对所有会话生效的告警规则，命中时生成告警记录
+	AlertRules []AlertRule
+	// OutputFilters 对所有会话生效的输出美化规则，只影响预览与导出
+	OutputFilters []OutputFilterRule
+	// OutputLog 会话输出日志，Dir 为空不写
+	OutputLog OutputLogConfig
+	// Screenshots 定时归档会话屏幕快照，Dir 为空不截图
+	Screenshots ScreenshotConfig
+	// Term 会话的 TERM，空为 xterm-256color；TermEnv 附加 COLORTERM、TERM_PROGRAM 等能力变量
+	Term    string
+	TermEnv map[string]string
+	// CompletionTemplate / ApprovalTemplate 通知展示文案模板，支持 {{project}}、{{title}}、{{assistant}}、{{input}}
+	CompletionTemplate string
+	ApprovalTemplate   string
+	// CompletionSettleDelay working 变为 waiting_input 后状态需保持的时长，之后才生成完成记录；
+	// 0 使用默认 1.5 秒，负数立即生成
+	CompletionSettleDelay time.Duration
+	// ModelPricing 每百万 token 价格，按模型名或助手类型查找，缺失时只统计 token 数
+	ModelPricing map[string]utils.ModelPrice
+	// Webhook 会话创建、关闭时 POST 的生命周期事件，URL 为空不发送
+	Webhook WebhookConfig
+	// ScrollbackSpill 超出 ScrollbackBytes 的输出写入临时文件，分页、搜索与导出仍可读取
+	ScrollbackSpill ScrollbackSpillConfig
+	// ScrollbackCollapse 折叠连续的 spinner 等原地重绘分片，默认关闭
+	ScrollbackCollapse bool
+	// ScrollbackTotalBytes 所有会话内存中 scrollback 的总预算，超出时先裁剪最久未活动的会话，0 表示不限制
+	ScrollbackTotalBytes int
+	// BinaryOutput 疑似二进制输出的处理：空不处理，truncate 截断并提示，reset 截断后再发送软复位序列
+	BinaryOutput string
+	// AIProfiles 创建会话时按 ID 选择的 AI 档案
+	AIProfiles []AIProfile
+	// ApprovalTimeout 审批提示长时间未响应时的处理策略，默认关闭
+	ApprovalTimeout ApprovalTimeoutConfig
+}
+
+// QuotaUsage reports the resources consumed by sessions sharing a quota key.
+type QuotaUsage struct {
+	Key            string `json:"key"`
+	Sessions       int    `json:"sessions"`
+	MaxSessions    int    `json:"maxSessions"`
+	MemoryBytes    uint64 `json:"memoryBytes"`
+	MaxMemoryBytes uint64 `json:"maxMemoryBytes"`
 }
 
 // CreateSessionParams describes API level inputs.
@@ -41,8 +119,41 @@ type CreateSessionParams struct {
 	Cols       int
 	Encoding   string
 	TaskID     string
+	// IdleTimeoutOverride 覆盖全局空闲超时：0 使用全局配置，负数表示永不超时
+	IdleTimeoutOverride time.Duration
+	// MaxLifetime 会话最大运行时长，0 表示不限制
+	MaxLifetime time.Duration
+	// WorktreeRoot 限定文件路径识别的范围
+	WorktreeRoot string
+	// QuotaKey 资源配额的聚合维度（如 userId），为空时不参与配额统计
+	QuotaKey string
+	// Term / TermEnv 覆盖全局配置的 TERM 与终端能力变量
+	Term    string
+	TermEnv map[string]string
+	// IdempotencyKey 客户端请求标识，有效期内相同 key 返回同一会话而不是重复创建
+	IdempotencyKey string
+	// RestartPolicy / MaxRestarts 进程退出后的重启策略与连续重启上限
+	RestartPolicy RestartPolicy
+	MaxRestarts   int
+	// ShellName 选择 terminal.shell.named 中的 shell，为空使用默认 shell
+	ShellName string
+	// ClearBeforeSend 每次提交输入前先发送清屏键
+	ClearBeforeSend bool
+	// Nice 进程调度优先级，0 不调整
+	Nice int
+	// ProfileID 套用的 AI 档案，为空不使用
+	ProfileID string
+	// ReadOnly 以只读方式打开，只展示输出、拒绝输入
+	ReadOnly bool
+	// TrackOnly 仅跟踪 AI 状态，不缓存输出
+	TrackOnly bool
+	// Source 创建来源，为空时记为 SessionSourceAPI
+	Source SessionSource
 }
 
+// idempotencyKeyTTL 幂等 key 的有效期，覆盖前端网络重试的时间窗口即可
+const idempotencyKeyTTL = 2 * time.Minute
+
 // Manager orchestrates PTY sessions.
 type Manager struct {
 	cfg           Config
@@ -53,17 +164,39 @@ type Manager struct {
 	baseCtx       context.Context
 	baseCtxMu     sync.RWMutex
 	recordManager *RecordManager
+	metrics       *assistantMetrics
+	// globalTriggers 由 sessionMu 保护
+	globalTriggers []*compiledTrigger
+	globalMacros   []InputMacro
+	titleRules     []*compiledTitleRule
+	highlightRules []*compiledHighlightRule
+	alertRules     []*compiledAlertRule
+	outputFilters  []*compiledOutputFilter
+	aiProfiles     []AIProfile
+	// worktreeConflicts 缓存最近一次状态刷新得到的冲突文件数，key 为 worktreeId
+	worktreeConflicts utils.SyncMap[string, int]
+	// idempotencyKeys 幂等 key -> sessionId，idempotencyMu 保证相同 key 的并发请求只创建一次
+	idempotencyKeys *cache.Cache
+	idempotencyMu   sync.Mutex
+	// onSessionCreated / onSessionClosed 会话生命周期回调，由 lifecycleMu 保护
+	lifecycleMu      sync.RWMutex
+	onSessionCreated []SessionLifecycleHook
+	onSessionClosed  []SessionLifecycleHook
+	// subscribersPruned 因长时间未消费被清理的订阅者总数
+	subscribersPruned atomic.Int64
+	// scrollbackBudgetTrimmed 因超出 scrollback 总预算累计裁剪的字节数
+	scrollbackBudgetTrimmed atomic.Int64
 }
 
 // NewManager builds a manager instance.
 func NewManager(cfg Config, logger *zap.Logger) *Manager {
-	cfg.Encoding = strings.ToLower(strings.TrimSpace(cfg.Encoding))
-	if cfg.ScrollbackBytes <= 0 {
-		cfg.ScrollbackBytes = 256 * 1024
-	}
 	if logger == nil {
 		logger = utils.Logger()
 	}
+	cfg.ApplyDefaults()
+	if errs := cfg.check(true); len(errs) > 0 {
+		logger.Warn("invalid terminal config, falling back to defaults", zap.Error(errors.Join(errs...)))
+	}
 
 	mgr := &Manager{
 		cfg:           cfg,
@@ -71,7 +204,54 @@ func NewManager(cfg Config, logger *zap.Logger) *Manager {
 		encoding:      cfg.Encoding,
 		baseCtx:       context.Background(),
 		recordManager: NewRecordManager(),
+		metrics:       newAssistantMetrics(),
+		// 过期的 key 由 cache 定期清理
+		idempotencyKeys: cache.NewCache(idempotencyKeyTTL),
+	}
+	if triggers, err := compileOutputTriggers(cfg.OutputTriggers); err != nil {
+		mgr.logger.Warn("ignoring invalid terminal output triggers", zap.Error(err))
+	} else {
+		mgr.globalTriggers = triggers
+	}
+	if err := validateMacros(cfg.Macros); err != nil {
+		mgr.logger.Warn("ignoring invalid terminal macros", zap.Error(err))
+	} else {
+		mgr.globalMacros = cfg.Macros
+	}
+	if err := validateAIProfiles(cfg.AIProfiles); err != nil {
+		mgr.logger.Warn("ignoring invalid terminal ai profiles", zap.Error(err))
+	} else {
+		mgr.aiProfiles = cfg.AIProfiles
+	}
+	if rules, err := compileTitleRules(cfg.TitleRules); err != nil {
+		mgr.logger.Warn("ignoring invalid terminal title rules", zap.Error(err))
+	} else {
+		mgr.titleRules = rules
+	}
+	if rules, err := compileHighlightRules(cfg.HighlightRules); err != nil {
+		mgr.logger.Warn("ignoring invalid terminal highlight rules", zap.Error(err))
+	} else {
+		mgr.highlightRules = rules
 	}
+	if rules, err := compileAlertRules(cfg.AlertRules); err != nil {
+		mgr.logger.Warn("ignoring invalid terminal alert rules", zap.Error(err))
+	} else {
+		mgr.alertRules = rules
+	}
+	if filters, err := compileOutputFilters(cfg.OutputFilters); err != nil {
+		mgr.logger.Warn("ignoring invalid terminal output filters", zap.Error(err))
+	} else {
+		mgr.outputFilters = filters
+	}
+	if err := mgr.recordManager.SetTemplates(cfg.CompletionTemplate, cfg.ApprovalTemplate); err != nil {
+		mgr.logger.Warn("invalid notification template, using default format", zap.Error(err))
+	}
+	if cfg.Webhook.URL != "" {
+		sender := newWebhookSender(cfg.Webhook, mgr.logger)
+		mgr.OnSessionCreated(sender.send)
+		mgr.OnSessionClosed(sender.send)
+	}
+	cfg.logEffective(mgr.logger)
 	return mgr
 }
 
@@ -79,23 +259,76 @@ func NewManager(cfg Config, logger *zap.Logger) *Manager {
 func (m *Manager) StartBackground(ctx context.Context) {
 	ctx = m.setBaseContext(ctx)
 	go m.reapIdleSessions(ctx)
+	go m.enforceScrollbackBudgetLoop(ctx)
+	go m.approvalTimeoutLoop(ctx)
 }
 
-// CreateSession spawns a PTY session respecting per-project limits.
+// CreateSession spawns a PTY session respecting per-project limits. Requests
+// carrying an IdempotencyKey already seen within idempotencyKeyTTL return the
+// session created by the first request while it is still alive.
 func (m *Manager) CreateSession(ctx context.Context, params CreateSessionParams) (*Session, error) {
 	if params.ProjectID == "" || params.WorktreeID == "" {
 		return nil, errors.New("projectId and worktreeId are required")
 	}
 
+	key := strings.TrimSpace(params.IdempotencyKey)
+	if key == "" {
+		return m.createSession(ctx, params)
+	}
+	// key 按 worktree 隔离，避免不同 worktree 的请求误复用
+	cacheKey := params.ProjectID + "/" + params.WorktreeID + "/" + key
+	m.idempotencyMu.Lock()
+	defer m.idempotencyMu.Unlock()
+	if cached, ok := m.idempotencyKeys.Get(cacheKey); ok {
+		session, err := m.GetSession(cached.(string))
+		if err == nil && session.Status() != SessionStatusClosed && session.Status() != SessionStatusError {
+			return session, nil
+		}
+	}
+	session, err := m.createSession(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	m.idempotencyKeys.Set(cacheKey, session.ID())
+	return session, nil
+}
+
+func (m *Manager) createSession(ctx context.Context, params CreateSessionParams) (*Session, error) {
 	if ctx != nil {
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
 		default:
 		}
+	} else {
+		ctx = context.Background()
+	}
+	// 克隆等不经过 API 校验的入口也不能在已归档的项目中新建会话；
+	// 项目查询的其他错误交给调用方处理，与 projectName 一致
+	if err := (&model.ProjectService{}).EnsureProjectActive(ctx, params.ProjectID); errors.Is(err, model.ErrProjectArchived) {
+		return nil, err
 	}
 
-	command, err := m.shellCommand()
+	var profile AIProfile
+	if strings.TrimSpace(params.ProfileID) != "" {
+		var err error
+		if profile, err = m.aiProfile(params.ProfileID); err != nil {
+			return nil, err
+		}
+		if strings.TrimSpace(params.ShellName) == "" {
+			params.ShellName = profile.ShellName
+		}
+	}
+
+	command, err := m.shellCommand(params.ShellName)
+	if err != nil {
+		return nil, err
+	}
+	term, err := resolveTermType(params.Term, m.cfg.Term)
+	if err != nil {
+		return nil, err
+	}
+	termEnv, err := buildTermEnv(m.cfg.TermEnv, params.TermEnv)
 	if err != nil {
 		return nil, err
 	}
@@ -105,18 +338,22 @@ func (m *Manager) CreateSession(ctx context.Context, params CreateSessionParams)
 	}
 
 	session, err := NewSession(SessionParams{
-		ID:              params.ID,
-		ProjectID:       params.ProjectID,
-		WorktreeID:      params.WorktreeID,
-		WorkingDir:      params.WorkingDir,
-		Title:           params.Title,
-		Command:         command,
-		Env:             params.Env,
-		Rows:            params.Rows,
-		Cols:            params.Cols,
-		Logger:          m.logger,
-		Encoding:        m.cfg.Encoding,
-		ScrollbackLimit: m.scrollbackLimit(),
+		ID:                 params.ID,
+		ProjectID:          params.ProjectID,
+		WorktreeID:         params.WorktreeID,
+		WorkingDir:         params.WorkingDir,
+		Title:              params.Title,
+		Command:            command,
+		Env:                slices.Concat(params.Env, profile.envList(), termEnv),
+		Term:               term,
+		Rows:               params.Rows,
+		Cols:               params.Cols,
+		Logger:             m.logger,
+		Encoding:           m.cfg.Encoding,
+		ScrollbackLimit:    m.scrollbackLimit(),
+		ScrollbackSpill:    m.cfg.ScrollbackSpill,
+		ScrollbackCollapse: m.cfg.ScrollbackCollapse,
+		BinaryOutput:       m.cfg.BinaryOutput,
 		GetAIConfig: func() *utils.AIAssistantStatusConfig {
 			m.sessionMu.Lock()
 			defer m.sessionMu.Unlock()
@@ -126,11 +363,42 @@ func (m *Manager) CreateSession(ctx context.Context, params CreateSessionParams)
 		TaskID:                    params.TaskID,
 		RenameTitleEachCommand:    m.cfg.RenameTitleEachCommand,
 		AutoCreateTaskOnStartWork: m.cfg.AutoCreateTaskOnStartWork,
+		IdleTimeoutOverride:       params.IdleTimeoutOverride,
+		MaxLifetime:               params.MaxLifetime,
+		CloseOnMaxLifetime:        m.cfg.CloseOnMaxLifetime,
+		DetectLinks:               m.cfg.DetectLinks,
+		AIDetectionDiagnostics:    m.cfg.AIDetectionDiagnostics,
+		CaptureRawOutput:          m.cfg.CaptureRawOutput,
+		ClearBeforeSend:           params.ClearBeforeSend,
+		WorktreeRoot:              params.WorktreeRoot,
+		QuotaKey:                  strings.TrimSpace(params.QuotaKey),
+		RecordEvents:              m.cfg.RecordEvents,
+		RecordEventsDir:           m.cfg.RecordEventsDir,
+		OutputLog:                 m.cfg.OutputLog,
+		Screenshots:               m.cfg.Screenshots,
+		ModelPricing:              m.cfg.ModelPricing,
+		RestartPolicy:             params.RestartPolicy,
+		MaxRestarts:               params.MaxRestarts,
+		ShellName:                 shellName(params.ShellName),
+		Nice:                      params.Nice,
+		ReadOnly:                  params.ReadOnly,
+		TrackOnly:                 params.TrackOnly,
+		Source:                    params.Source,
 	})
 	if err != nil {
 		return nil, err
 	}
 
+	m.sessionMu.Lock()
+	session.setGlobalTriggers(m.globalTriggers)
+	session.setGlobalMacros(m.globalMacros)
+	session.setTitleRules(m.titleRules)
+	session.setHighlightRules(m.highlightRules)
+	session.setGlobalAlertRules(m.alertRules)
+	session.setGlobalOutputFilters(m.outputFilters)
+	m.sessionMu.Unlock()
+	session.createParams = params
+
 	if err := m.addSession(session); err != nil {
 		return nil, err
 	}
@@ -149,6 +417,8 @@ func (m *Manager) CreateSession(ctx context.Context, params CreateSessionParams)
 	}
 
 	go m.watchSession(session)
+	m.notifySessionCreated(session)
+	m.runProfileInputs(session, profile)
 
 	return session, nil
 }
@@ -186,6 +456,44 @@ func (m *Manager) RenameSession(projectID, sessionID, title string) (*Session, e
 	return session, nil
 }
 
+// SetSessionIdleTimeout overrides the idle timeout of the targeted session.
+func (m *Manager) SetSessionIdleTimeout(projectID, sessionID string, timeout time.Duration) (*Session, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if projectID != "" && session.ProjectID() != projectID {
+		return nil, ErrSessionNotFound
+	}
+
+	session.SetIdleTimeoutOverride(timeout)
+	return session, nil
+}
+
+// PauseSession freezes the process tree of the targeted session.
+func (m *Manager) PauseSession(sessionID string) (*Session, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := session.Pause(); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// ResumeSession continues a session previously frozen by PauseSession.
+func (m *Manager) ResumeSession(sessionID string) (*Session, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if err := session.Resume(); err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
 // CloseSession terminates and removes the session immediately.
 func (m *Manager) CloseSession(id string) error {
 	session, err := m.GetSession(id)
@@ -215,19 +523,95 @@ func (m *Manager) UnlinkTask(sessionID string) (*Session, error) {
 	return session, nil
 }
 
-// ListSessions enumerates sessions, optionally filtering by project.
+// ListSessions enumerates sessions ordered by tab position, optionally filtering by project.
 func (m *Manager) ListSessions(projectID string) []SessionSnapshot {
+	return m.ListSessionsWith(projectID, SnapshotOptions{})
+}
+
+// ListSessionsWith is ListSessions with snapshot options.
+func (m *Manager) ListSessionsWith(projectID string, opts SnapshotOptions) []SessionSnapshot {
 	results := make([]SessionSnapshot, 0)
 	m.sessions.Range(func(_ string, session *Session) bool {
 		if projectID != "" && session.ProjectID() != projectID {
 			return true
 		}
-		results = append(results, session.Snapshot())
+		results = append(results, session.SnapshotWith(opts))
 		return true
 	})
+	sortSnapshots(results)
 	return results
 }
 
+// ProjectTokenUsage sums the token usage of the project's live sessions.
+func (m *Manager) ProjectTokenUsage(projectID string) *TokenUsage {
+	var usages []*TokenUsage
+	m.sessions.Range(func(_ string, session *Session) bool {
+		if session.ProjectID() == projectID {
+			usages = append(usages, session.AssistantTokenUsage())
+		}
+		return true
+	})
+	return sumTokenUsage(usages)
+}
+
+// ListSessionsByWorktree returns the sessions attached to the worktree.
+func (m *Manager) ListSessionsByWorktree(worktreeID string) []SessionSnapshot {
+	return m.ListSessionsByWorktreeWith(worktreeID, SnapshotOptions{})
+}
+
+// ListSessionsByWorktreeWith is ListSessionsByWorktree with snapshot options.
+func (m *Manager) ListSessionsByWorktreeWith(worktreeID string, opts SnapshotOptions) []SessionSnapshot {
+	results := make([]SessionSnapshot, 0)
+	if worktreeID == "" {
+		return results
+	}
+	m.sessions.Range(func(_ string, session *Session) bool {
+		if session.WorktreeID() == worktreeID {
+			results = append(results, session.SnapshotWith(opts))
+		}
+		return true
+	})
+	sortSnapshots(results)
+	return results
+}
+
+// HasWorktreeSessions reports whether any session is attached to the worktree.
+func (m *Manager) HasWorktreeSessions(worktreeID string) bool {
+	found := false
+	if worktreeID == "" {
+		return found
+	}
+	m.sessions.Range(func(_ string, session *Session) bool {
+		found = session.WorktreeID() == worktreeID
+		return !found
+	})
+	return found
+}
+
+// CloseWorktreeSessions closes every session attached to the worktree, e.g.
+// before the worktree directory is removed, and returns how many were closed.
+func (m *Manager) CloseWorktreeSessions(worktreeID string) int {
+	if worktreeID == "" {
+		return 0
+	}
+	sessions := make([]*Session, 0)
+	m.sessions.Range(func(_ string, session *Session) bool {
+		if session.WorktreeID() == worktreeID {
+			sessions = append(sessions, session)
+		}
+		return true
+	})
+	for _, session := range sessions {
+		if err := session.Close(); err != nil {
+			m.logger.Warn("failed to close session of deleted worktree",
+				zap.String("sessionId", session.ID()),
+				zap.String("worktreeId", worktreeID),
+				zap.Error(err))
+		}
+	}
+	return len(sessions)
+}
+
 // GetSessionDebugInfo returns comprehensive debug information for a session.
 func (m *Manager) GetSessionDebugInfo(id string) (*DebugInfo, error) {
 	session, err := m.GetSession(id)
@@ -237,6 +621,15 @@ func (m *Manager) GetSessionDebugInfo(id string) (*DebugInfo, error) {
 	return session.GetDebugInfo(), nil
 }
 
+// GetSessionDiagnostics returns subscriber and dispatch diagnostics for a session.
+func (m *Manager) GetSessionDiagnostics(id string) (*SessionDiagnostics, error) {
+	session, err := m.GetSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return session.Diagnostics(), nil
+}
+
 // CaptureChunk triggers a resize and captures the next output chunk from a session.
 func (m *Manager) CaptureChunk(ctx context.Context, id string, timeout time.Duration) (*CapturedChunk, error) {
 	session, err := m.GetSession(id)
@@ -246,34 +639,168 @@ func (m *Manager) CaptureChunk(ctx context.Context, id string, timeout time.Dura
 	return session.CaptureNextChunk(ctx, timeout)
 }
 
-func (m *Manager) shellCommand() ([]string, error) {
-	return utils.ResolveShellCommand("", m.cfg.Shell)
+// shellCommand resolves the shell to start and checks its binary with
+// exec.LookPath, so a missing binary is reported here with the configured value
+// instead of surfacing later as a bare "file not found" from exec. A named shell
+// or a platform shell set by the user is used as is; other shells are only
+// tried when the built-in default is not installed.
+func (m *Manager) shellCommand(name string) ([]string, error) {
+	name = strings.TrimSpace(name)
+	if name != "" && name != utils.DefaultShellName {
+		configured, ok := utils.NamedShell(m.cfg.Shell, name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %q", ErrUnknownShell, name)
+		}
+		command, err := utils.ResolveShellCommand(configured, m.cfg.Shell)
+		if err != nil {
+			return nil, fmt.Errorf("%w: shell %q configured %q, %v", ErrShellNotFound, name, configured, err)
+		}
+		return command, nil
+	}
+
+	command, err := utils.ResolveConfiguredShell(m.cfg.Shell)
+	if err != nil {
+		configured := utils.ConfiguredShell(m.cfg.Shell)
+		if configured == "" {
+			configured = "(empty)"
+		}
+		return nil, fmt.Errorf("%w: configured %q, %v; %s", ErrShellNotFound, configured, err, shellConfigHint())
+	}
+	return command, nil
+}
+
+// shellName 默认 shell 不记录名称
+func shellName(name string) string {
+	name = strings.TrimSpace(name)
+	if name == utils.DefaultShellName {
+		return ""
+	}
+	return name
+}
+
+// ListShells returns the shells sessions can be created with, the default first.
+func (m *Manager) ListShells() []utils.ShellOption {
+	return utils.ListShells(m.cfg.Shell)
+}
+
+// shellConfigHint 给出当前平台可用的 terminal.shell 配置示例
+func shellConfigHint() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "set terminal.shell.windows to an installed shell such as pwsh.exe, powershell.exe or cmd.exe"
+	case "darwin":
+		return "set terminal.shell.darwin to an installed shell such as /bin/zsh or /bin/bash"
+	default:
+		return "set terminal.shell.linux to an installed shell such as /bin/bash or /bin/sh"
+	}
 }
 
 func (m *Manager) watchSession(session *Session) {
 	go m.monitorAssistantRecords(session)
 	<-session.Closed()
 	m.recordManager.ClearSessionRecords(session.ID())
+	m.recordManager.ClearAlertsBySession(session.ID())
+	m.recordManager.ClearSessionNotes(session.ID())
 	m.sessions.Delete(session.ID())
+	m.pruneScreenshotDirs()
+	m.pruneEventRecordFiles()
+	m.notifySessionClosed(session)
 }
 
 func (m *Manager) addSession(session *Session) error {
-	if m.cfg.MaxSessionsPerProject <= 0 {
-		m.sessions.Store(session.ID(), session)
-		return nil
-	}
-
 	m.sessionMu.Lock()
 	defer m.sessionMu.Unlock()
 
-	if m.countByProject(session.ProjectID()) >= m.cfg.MaxSessionsPerProject {
+	if m.cfg.MaxSessionsPerProject > 0 && m.countByProject(session.ProjectID()) >= m.cfg.MaxSessionsPerProject {
 		return ErrSessionLimitReached
 	}
 
+	if key := session.QuotaKey(); key != "" {
+		usage := m.quotaUsageLocked(key)
+		if usage.MaxSessions > 0 && usage.Sessions >= usage.MaxSessions {
+			return fmt.Errorf("%w: %d/%d sessions", ErrQuotaExceeded, usage.Sessions, usage.MaxSessions)
+		}
+		if usage.MaxMemoryBytes > 0 && usage.MemoryBytes >= usage.MaxMemoryBytes {
+			return fmt.Errorf("%w: memory %d/%d bytes", ErrQuotaExceeded, usage.MemoryBytes, usage.MaxMemoryBytes)
+		}
+	}
+
+	session.SetOrderIndex(m.nextOrderIndexLocked(session.ProjectID()))
 	m.sessions.Store(session.ID(), session)
 	return nil
 }
 
+// sessionOrderStep 新会话与末尾会话之间的间隔，与 notepad 的排序方式一致，便于在两者之间插入
+const sessionOrderStep = 1000
+
+// nextOrderIndexLocked returns an order index placing a new session after every
+// existing session of the project. Caller must hold sessionMu.
+func (m *Manager) nextOrderIndexLocked(projectID string) float64 {
+	maxOrder := 0.0
+	m.sessions.Range(func(_ string, session *Session) bool {
+		if session.ProjectID() == projectID {
+			maxOrder = math.Max(maxOrder, session.OrderIndex())
+		}
+		return true
+	})
+	return maxOrder + sessionOrderStep
+}
+
+// MoveSession updates the tab position of a session; list results are ordered by it.
+func (m *Manager) MoveSession(sessionID string, orderIndex float64) (*Session, error) {
+	if math.IsNaN(orderIndex) || math.IsInf(orderIndex, 0) {
+		return nil, ErrInvalidOrderIndex
+	}
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	session.SetOrderIndex(orderIndex)
+	return session, nil
+}
+
+// sortSnapshots orders snapshots by tab position, falling back to creation time.
+func sortSnapshots(snapshots []SessionSnapshot) {
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if snapshots[i].OrderIndex != snapshots[j].OrderIndex {
+			return snapshots[i].OrderIndex < snapshots[j].OrderIndex
+		}
+		return snapshots[i].CreatedAt.Before(snapshots[j].CreatedAt)
+	})
+}
+
+// QuotaUsage returns the current session count and memory used by the quota key.
+func (m *Manager) QuotaUsage(key string) QuotaUsage {
+	m.sessionMu.Lock()
+	defer m.sessionMu.Unlock()
+	return m.quotaUsageLocked(strings.TrimSpace(key))
+}
+
+func (m *Manager) quotaUsageLocked(key string) QuotaUsage {
+	usage := QuotaUsage{
+		Key:            key,
+		MaxSessions:    m.cfg.QuotaMaxSessions,
+		MaxMemoryBytes: m.cfg.QuotaMaxMemoryBytes,
+	}
+	if key == "" {
+		return usage
+	}
+	m.sessions.Range(func(_ string, session *Session) bool {
+		if session.QuotaKey() != key {
+			return true
+		}
+		usage.Sessions++
+		session.mu.RLock()
+		pid := session.getPID()
+		session.mu.RUnlock()
+		if pid > 0 {
+			usage.MemoryBytes += process.GetProcessTreeMemory(pid)
+		}
+		return true
+	})
+	return usage
+}
+
 func (m *Manager) countByProject(projectID string) int {
 	count := 0
 	m.sessions.Range(func(_ string, session *Session) bool {
@@ -295,14 +822,12 @@ func (m *Manager) reapIdleSessions(ctx context.Context) {
 			return
 		case <-ticker.C:
 			m.cleanupIdle()
+			m.pruneStaleSubscribers(time.Now())
 		}
 	}
 }
 
 func (m *Manager) cleanupIdle() {
-	if m.cfg.IdleTimeout <= 0 {
-		return
-	}
 	now := time.Now()
 
 	sessions := make([]*Session, 0, m.sessions.Len())
@@ -312,11 +837,20 @@ func (m *Manager) cleanupIdle() {
 	})
 
 	for _, session := range sessions {
-		if now.Sub(session.LastActive()) > m.cfg.IdleTimeout {
+		// 暂停是用户主动冻结，不按空闲回收
+		if session.Status() == SessionStatusPaused {
+			continue
+		}
+		timeout := session.EffectiveIdleTimeout(m.cfg.IdleTimeout)
+		if timeout <= 0 {
+			continue
+		}
+		if now.Sub(session.LastActive()) > timeout {
 			m.logger.Info("closing idle terminal session",
 				zap.String("sessionId", session.ID()),
 				zap.String("projectId", session.ProjectID()),
 				zap.Duration("idle", now.Sub(session.LastActive())),
+				zap.Duration("timeout", timeout),
 			)
 			_ = session.Close()
 		}
@@ -410,28 +944,281 @@ func (m *Manager) UpdateAutoCreateTaskOnStartWork(enabled bool) {
 	})
 }
 
+// UpdateDetectLinks toggles URL/file path detection in terminal output.
+func (m *Manager) UpdateDetectLinks(enabled bool) {
+	m.sessionMu.Lock()
+	m.cfg.DetectLinks = enabled
+	m.sessionMu.Unlock()
+
+	m.sessions.Range(func(_ string, session *Session) bool {
+		session.SetDetectLinks(enabled)
+		return true
+	})
+}
+
+// UpdateAIDetectionDiagnostics toggles AI assistant detection diagnostics for all sessions.
+func (m *Manager) UpdateAIDetectionDiagnostics(enabled bool) {
+	m.sessionMu.Lock()
+	m.cfg.AIDetectionDiagnostics = enabled
+	m.sessionMu.Unlock()
+
+	m.sessions.Range(func(_ string, session *Session) bool {
+		session.SetAIDiagnostics(enabled)
+		return true
+	})
+}
+
+// UpdateCaptureRawOutput toggles raw PTY output capture for all sessions.
+func (m *Manager) UpdateCaptureRawOutput(enabled bool) {
+	m.sessionMu.Lock()
+	m.cfg.CaptureRawOutput = enabled
+	m.sessionMu.Unlock()
+
+	m.sessions.Range(func(_ string, session *Session) bool {
+		session.SetRawCapture(enabled)
+		return true
+	})
+}
+
+// UpdateConfirmMultilineInput toggles confirmation for multi-line websocket input.
+func (m *Manager) UpdateConfirmMultilineInput(enabled bool) {
+	m.sessionMu.Lock()
+	m.cfg.ConfirmMultilineInput = enabled
+	m.sessionMu.Unlock()
+}
+
+// ConfirmMultilineInput reports whether multi-line input requires client confirmation.
+func (m *Manager) ConfirmMultilineInput() bool {
+	m.sessionMu.Lock()
+	defer m.sessionMu.Unlock()
+	return m.cfg.ConfirmMultilineInput
+}
+
+// UpdateWorktreeConflicts records the conflicted file count of a worktree and flags
+// completion records of sessions running in that worktree.
+func (m *Manager) UpdateWorktreeConflicts(worktreeID string, conflicts int) {
+	if worktreeID == "" {
+		return
+	}
+	if conflicts > 0 {
+		m.worktreeConflicts.Store(worktreeID, conflicts)
+	} else if !m.worktreeConflicts.Delete(worktreeID) {
+		return
+	}
+
+	m.sessions.Range(func(_ string, session *Session) bool {
+		if session.WorktreeID() == worktreeID {
+			m.recordManager.SetConflictsBySession(session.ID(), conflicts)
+		}
+		return true
+	})
+}
+
+// UpdateOutputTriggers replaces the global output triggers of all sessions.
+func (m *Manager) UpdateOutputTriggers(triggers []OutputTrigger) error {
+	compiled, err := compileOutputTriggers(triggers)
+	if err != nil {
+		return err
+	}
+	m.sessionMu.Lock()
+	m.cfg.OutputTriggers = append([]OutputTrigger(nil), triggers...)
+	m.globalTriggers = compiled
+	m.sessionMu.Unlock()
+
+	m.sessions.Range(func(_ string, session *Session) bool {
+		session.setGlobalTriggers(compiled)
+		return true
+	})
+	return nil
+}
+
+// OutputTriggers returns the global output triggers.
+func (m *Manager) OutputTriggers() []OutputTrigger {
+	m.sessionMu.Lock()
+	defer m.sessionMu.Unlock()
+	return append([]OutputTrigger{}, m.cfg.OutputTriggers...)
+}
+
+// RelocateWorktree updates sessions of a worktree that has been moved on disk.
+func (m *Manager) RelocateWorktree(worktreeID, oldPath, newPath string) {
+	if worktreeID == "" {
+		return
+	}
+	m.sessions.Range(func(_ string, session *Session) bool {
+		if session.WorktreeID() == worktreeID {
+			session.RelocateWorktree(oldPath, newPath)
+		}
+		return true
+	})
+}
+
+func (m *Manager) applyWorktreeConflicts(session *Session) {
+	if conflicts, ok := m.worktreeConflicts.Load(session.WorktreeID()); ok {
+		m.recordManager.SetConflictsBySession(session.ID(), conflicts)
+	}
+}
+
+// WriteMetrics writes AI assistant and stream subscriber metrics in the
+// Prometheus text format.
+func (m *Manager) WriteMetrics(w io.Writer) error {
+	if err := m.metrics.WritePrometheus(w); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "# HELP terminal_subscribers Number of terminal stream subscribers.\n"+
+		"# TYPE terminal_subscribers gauge\n"+
+		"terminal_subscribers %d\n"+
+		"# HELP terminal_subscribers_pruned_total Number of subscribers removed because they stopped consuming events.\n"+
+		"# TYPE terminal_subscribers_pruned_total counter\n"+
+		"terminal_subscribers_pruned_total %d\n",
+		m.SubscriberCount(), m.subscribersPruned.Load())
+	return err
+}
+
 // GetRecordManager 返回记录管理器实例
 func (m *Manager) GetRecordManager() *RecordManager {
 	return m.recordManager
 }
 
+// DismissCompletion 关闭完成记录。带备注或标签的记录同时写入项目活动，
+// 并标记落库备注的关闭时间，记录被后续完成替换后备注仍可在历史中查到
+func (m *Manager) DismissCompletion(recordID string) bool {
+	record, ok := m.recordManager.dismissCompletion(recordID)
+	if !ok {
+		return false
+	}
+	if record.Dismissed || (record.Note == "" && len(record.Tags) == 0) {
+		return true
+	}
+
+	now := time.Now()
+	if err := (&model.CompletionNoteService{}).MarkCompletionNoteDismissed(context.Background(), record.noteID, now); err != nil {
+		m.logger.Debug("mark completion note dismissed failed", zap.Error(err), zap.String("recordId", recordID))
+	}
+	detail := record.Note
+	if len(record.Tags) > 0 {
+		detail = strings.TrimSpace(detail + " [" + strings.Join(record.Tags, ", ") + "]")
+	}
+	if _, err := (&model.ActivityService{}).RecordActivity(context.Background(), &model.RecordActivityRequest{
+		ProjectID: record.ProjectID,
+		Type:      model.ActivityAINoted,
+		Actor:     model.ActivityActorUser,
+		Target:    record.Title,
+		Detail:    detail,
+		Time:      now,
+	}); err != nil {
+		m.logger.Debug("record completion note activity failed", zap.Error(err), zap.String("recordId", recordID))
+	}
+	return true
+}
+
+// UpdateCompletionNote 更新完成记录的备注和标签并落库，nil 表示保持不变。
+// 落库失败只记录日志，内存中的记录仍然更新
+func (m *Manager) UpdateCompletionNote(recordID string, note *string, tags []string) (*CompletionRecord, error) {
+	record, err := m.recordManager.UpdateCompletionNote(recordID, note, tags)
+	if err != nil {
+		return nil, err
+	}
+	if err := (&model.CompletionNoteService{}).SaveCompletionNote(context.Background(), &tables.CompletionNoteTable{
+		StringPKBaseModel: model_base.StringPKBaseModel{ID: record.noteID},
+		SessionID:         record.SessionID,
+		ProjectID:         record.ProjectID,
+		Title:             record.Title,
+		Note:              record.Note,
+		Tags:              strings.Join(record.Tags, "\n"),
+	}); err != nil {
+		m.logger.Debug("save completion note failed", zap.Error(err), zap.String("recordId", recordID))
+	}
+	return record, nil
+}
+
 func (m *Manager) monitorAssistantRecords(session *Session) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	stream, err := session.Subscribe(ctx)
+	// 显式订阅 data：仅跟踪会话不向未指定类型的订阅者投递输出，审批提示的重新识别依赖 data 事件
+	stream, err := session.Subscribe(ctx, StreamEventData, StreamEventMetadata, StreamEventAlert, StreamEventExit)
 	if err != nil {
 		return
 	}
 	defer stream.Close()
 
 	lastState := string(types.StateUnknown)
+	// lastType 为空表示当前没有 AI 助手，用于维护 ai_assistant_state 指标
+	lastType := ""
+	usage := &usageRecorder{m: m, session: session}
+	defer func() {
+		m.metrics.transition(lastType, lastState, "", "")
+		usage.transition("", "", time.Now())
+	}()
+
+	// working -> waiting_input 后先挂起完成记录，状态保持 settleDelay 才生成，
+	// 期间回到 working 等状态则丢弃，避免短暂抖动产生通知
+	settleDelay := m.cfg.CompletionSettleDelay
+	var pendingCompletion *ai_assistant2.AIAssistantInfo
+	settleTimer := time.NewTimer(time.Hour)
+	settleTimer.Stop()
+	defer settleTimer.Stop()
+	cancelPending := func() {
+		pendingCompletion = nil
+		settleTimer.Stop()
+	}
+
+	// 审批态下连续出现的提示不会改变状态，输出停顿后重新识别屏幕上的提示，
+	// 换成新提示时为其创建新记录，超时从新提示出现时重新计时
+	approvalPromptID := ""
+	var approvalInfo *ai_assistant2.AIAssistantInfo
+	promptTimer := time.NewTimer(time.Hour)
+	promptTimer.Stop()
+	defer promptTimer.Stop()
+	recordApproval := func(info *ai_assistant2.AIAssistantInfo) {
+		approvalInfo = cloneAssistantInfo(info)
+		approvalPromptID = session.ApprovalPromptID()
+		m.handleSessionApprovalRecord(session, approvalInfo, approvalPromptID)
+	}
+	leaveApproval := func() {
+		m.recordManager.ClearApprovalsBySession(session.ID())
+		approvalPromptID = ""
+		approvalInfo = nil
+		promptTimer.Stop()
+	}
+
+	for {
+		var event StreamEvent
+		select {
+		case <-settleTimer.C:
+			if pendingCompletion != nil && lastState == string(types.StateWaitingInput) {
+				m.metrics.incCompletion(pendingCompletion.Type)
+				m.handleSessionCompletionRecord(session, pendingCompletion, "")
+				usage.completion(pendingCompletion.Type, time.Now())
+			}
+			pendingCompletion = nil
+			continue
+		case <-promptTimer.C:
+			if lastState == string(types.StateWaitingApproval) && approvalInfo != nil &&
+				session.ApprovalPromptID() != approvalPromptID {
+				recordApproval(approvalInfo)
+			}
+			continue
+		case next, ok := <-stream.Events():
+			if !ok {
+				return
+			}
+			event = next
+		}
 
-	for event := range stream.Events() {
 		switch event.Type {
+		case StreamEventData:
+			if lastState == string(types.StateWaitingApproval) {
+				promptTimer.Reset(approvalPromptCheckDelay)
+			}
 		case StreamEventMetadata:
 			metadata := event.Metadata
 			if metadata == nil || metadata.AIAssistant == nil {
+				m.metrics.transition(lastType, lastState, "", "")
+				usage.transition("", "", time.Now())
+				lastType = ""
+				cancelPending()
+				promptTimer.Stop()
 				// AI 助手 detach 时，清除该 session 的所有记录
 				if lastState != string(types.StateUnknown) {
 					m.recordManager.ClearSessionRecords(session.ID())
@@ -440,23 +1227,41 @@ func (m *Manager) monitorAssistantRecords(session *Session) {
 				continue
 			}
 			state := metadata.AIAssistant.State
+			m.metrics.transition(lastType, lastState, metadata.AIAssistant.Type, state)
+			usage.transition(metadata.AIAssistant.Type, state, time.Now())
+			lastType = metadata.AIAssistant.Type
 			if state == lastState && state != string(types.StateWaitingApproval) {
 				continue
 			}
 
 			// 从元数据中获取最近的用户输入（仅在 waiting_input -> working 时有值）
 			recentInput := strings.TrimSpace(metadata.AIAssistantRecentInput)
+			if state != string(types.StateWaitingInput) {
+				cancelPending()
+			}
+
+			if lastState == string(types.StateWaitingApproval) && state != lastState {
+				// 离开审批状态时清理审批记录，避免超时策略处理已答复的提示
+				leaveApproval()
+			}
 
 			switch state {
 			case string(types.StateWaitingInput):
 				// 只有从 working 状态变为 waiting_input 才算完成任务
 				// 避免在初始化时（unknown -> waiting_input）错误地创建完成记录
 				if lastState == string(types.StateWorking) {
-					m.handleSessionCompletionRecord(session, metadata.AIAssistant, "")
+					if settleDelay <= 0 {
+						m.metrics.incCompletion(metadata.AIAssistant.Type)
+						m.handleSessionCompletionRecord(session, metadata.AIAssistant, "")
+						usage.completion(metadata.AIAssistant.Type, time.Now())
+					} else {
+						pendingCompletion = cloneAssistantInfo(metadata.AIAssistant)
+						settleTimer.Reset(settleDelay)
+					}
 				}
 			case string(types.StateWaitingApproval):
-				if lastState != string(types.StateWaitingApproval) {
-					m.handleSessionApprovalRecord(session, metadata.AIAssistant)
+				if lastState != string(types.StateWaitingApproval) || session.ApprovalPromptID() != approvalPromptID {
+					recordApproval(metadata.AIAssistant)
 				}
 			case string(types.StateWorking):
 				// 确保有对应的通知，并标记为 working
@@ -468,17 +1273,11 @@ func (m *Manager) monitorAssistantRecords(session *Session) {
 				if !m.recordManager.UpdateCompletionBySession(session.ID(), "working", recentInput) {
 					m.handleSessionWorkingRecord(session, metadata.AIAssistant, recentInput)
 				}
-				if lastState == string(types.StateWaitingApproval) {
-					// 从审批状态恢复工作时也需要清理审批记录
-					m.recordManager.ClearApprovalsBySession(session.ID())
-				}
-			default:
-				if lastState == string(types.StateWaitingApproval) {
-					m.recordManager.ClearApprovalsBySession(session.ID())
-				}
 			}
 
 			lastState = state
+		case StreamEventAlert:
+			m.handleSessionAlertRecord(session, event.Alert)
 		case StreamEventExit:
 			return
 		}
@@ -500,15 +1299,36 @@ func (m *Manager) handleSessionCompletionRecord(session *Session, info *ai_assis
 		ID:            utils.NewID(),
 		SessionID:     session.ID(),
 		ProjectID:     session.ProjectID(),
+		ProjectName:   m.projectName(session.ProjectID()),
 		Title:         session.Title(),
 		Assistant:     cloneAssistantInfo(info),
 		CompletedAt:   time.Now(),
 		State:         "completed",
 		LastUserInput: lastInput,
+		Todos:         session.AssistantTodos(),
+		Duration:      int64(session.AssistantWorkDuration() / time.Second),
+		TokenUsage:    session.AssistantTokenUsage(),
+		Interrupted:   session.AssistantInterrupted(),
+	}
+	record.TurnCount, record.InterruptedTurns = session.AssistantTurnCount()
+	activityType := model.ActivityAICompleted
+	if record.Interrupted {
+		activityType = model.ActivityAIInterrupted
 	}
 
-	m.recordManager.ClearCompletionsBySession(session.ID())
 	m.recordManager.AddCompletion(record)
+	m.applyWorktreeConflicts(session)
+
+	if _, err := (&model.ActivityService{}).RecordActivity(context.Background(), &model.RecordActivityRequest{
+		ProjectID: record.ProjectID,
+		Type:      activityType,
+		Actor:     model.ActivityActorAI + ":" + info.Type,
+		Target:    record.Title,
+		Detail:    lastInput,
+		Time:      record.CompletedAt,
+	}); err != nil {
+		m.logger.Debug("record ai completion activity failed", zap.Error(err), zap.String("sessionId", session.ID()))
+	}
 }
 
 func (m *Manager) handleSessionWorkingRecord(session *Session, info *ai_assistant2.AIAssistantInfo, userInput string) {
@@ -538,11 +1358,28 @@ func (m *Manager) handleSessionWorkingRecord(session *Session, info *ai_assistan
 		LastUserInput: lastInput,
 	}
 
-	m.recordManager.ClearCompletionsBySession(session.ID())
 	m.recordManager.AddCompletion(record)
+	m.applyWorktreeConflicts(session)
 }
 
-func (m *Manager) handleSessionApprovalRecord(session *Session, info *ai_assistant2.AIAssistantInfo) {
+// projectName looks up the display name of the project for notification records,
+// returning "" when the database is unavailable or the project is gone.
+func (m *Manager) projectName(projectID string) string {
+	if projectID == "" {
+		return ""
+	}
+	q, err := model.ResolveQueries(nil)
+	if err != nil {
+		return ""
+	}
+	project, err := q.ProjectGetByID(context.Background(), projectID)
+	if err != nil {
+		return ""
+	}
+	return project.Name
+}
+
+func (m *Manager) handleSessionApprovalRecord(session *Session, info *ai_assistant2.AIAssistantInfo, promptID string) {
 	if session == nil || info == nil {
 		return
 	}
@@ -551,15 +1388,35 @@ func (m *Manager) handleSessionApprovalRecord(session *Session, info *ai_assista
 		ID:          utils.NewID(),
 		SessionID:   session.ID(),
 		ProjectID:   session.ProjectID(),
+		ProjectName: m.projectName(session.ProjectID()),
 		Title:       session.Title(),
 		Assistant:   cloneAssistantInfo(info),
 		RequestedAt: time.Now(),
+		PromptID:    promptID,
 	}
 
 	m.recordManager.ClearApprovalsBySession(session.ID())
 	m.recordManager.AddApproval(record)
 }
 
+func (m *Manager) handleSessionAlertRecord(session *Session, alert *OutputAlert) {
+	if session == nil || alert == nil {
+		return
+	}
+
+	m.recordManager.AddAlert(&AlertRecord{
+		ID:          utils.NewID(),
+		SessionID:   session.ID(),
+		ProjectID:   session.ProjectID(),
+		ProjectName: m.projectName(session.ProjectID()),
+		Title:       session.Title(),
+		Rule:        alert.Rule,
+		Line:        alert.Line,
+		Context:     alert.Context,
+		TriggeredAt: time.Now(),
+	})
+}
+
 func cloneAssistantInfo(info *ai_assistant2.AIAssistantInfo) *ai_assistant2.AIAssistantInfo {
 	if info == nil {
 		return nil
@@ -0,0 +1,262 @@
+package terminal
+
+import (
+	"context"
+	"errors"
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils"
+	"code-kanban/utils/ai_assistant2"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+func TestManagerQuotaByKey(t *testing.T) {
+	mgr := NewManager(Config{QuotaMaxSessions: 1}, zap.NewNop())
+
+	first := newTestSession(t, SessionParams{ProjectID: "p1", QuotaKey: "user-1"})
+	if err := mgr.addSession(first); err != nil {
+		t.Fatalf("addSession failed: %v", err)
+	}
+
+	second := newTestSession(t, SessionParams{ProjectID: "p2", QuotaKey: "user-1"})
+	if err := mgr.addSession(second); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("expected ErrQuotaExceeded, got %v", err)
+	}
+
+	other := newTestSession(t, SessionParams{ProjectID: "p1", QuotaKey: "user-2"})
+	if err := mgr.addSession(other); err != nil {
+		t.Fatalf("expected other quota key to be accepted, got %v", err)
+	}
+	noKey := newTestSession(t, SessionParams{ProjectID: "p1"})
+	if err := mgr.addSession(noKey); err != nil {
+		t.Fatalf("expected session without quota key to be accepted, got %v", err)
+	}
+
+	usage := mgr.QuotaUsage("user-1")
+	if usage.Sessions != 1 || usage.MaxSessions != 1 {
+		t.Fatalf("unexpected quota usage: %+v", usage)
+	}
+}
+
+func TestManagerCloseWorktreeSessions(t *testing.T) {
+	mgr := NewManager(Config{}, zap.NewNop())
+	for _, params := range []SessionParams{
+		{ID: "s1", ProjectID: "p1", WorktreeID: "wt-1"},
+		{ID: "s2", ProjectID: "p1", WorktreeID: "wt-1"},
+		{ID: "s3", ProjectID: "p1", WorktreeID: "wt-2"},
+	} {
+		if err := mgr.addSession(newTestSession(t, params)); err != nil {
+			t.Fatalf("addSession failed: %v", err)
+		}
+	}
+
+	if sessions := mgr.ListSessionsByWorktree("wt-1"); len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions in wt-1, got %d", len(sessions))
+	}
+	if closed := mgr.CloseWorktreeSessions("wt-1"); closed != 2 {
+		t.Fatalf("expected 2 sessions closed, got %d", closed)
+	}
+	other, err := mgr.GetSession("s3")
+	if err != nil {
+		t.Fatalf("expected session of other worktree to remain: %v", err)
+	}
+	if other.Snapshot().Status == SessionStatusClosed {
+		t.Fatalf("session of other worktree should not be closed")
+	}
+}
+
+func TestManagerCreateSessionIdempotent(t *testing.T) {
+	mgr := NewManager(Config{}, zap.NewNop())
+	defer mgr.CloseWorktreeSessions("wt-1")
+
+	params := CreateSessionParams{
+		ProjectID:      "p1",
+		WorktreeID:     "wt-1",
+		WorkingDir:     t.TempDir(),
+		IdempotencyKey: "req-1",
+	}
+	first, err := mgr.CreateSession(context.Background(), params)
+	if err != nil {
+		t.Skipf("pty unavailable: %v", err)
+	}
+	second, err := mgr.CreateSession(context.Background(), params)
+	if err != nil {
+		t.Fatalf("repeated create failed: %v", err)
+	}
+	if second.ID() != first.ID() {
+		t.Fatalf("expected same session for repeated key, got %s and %s", first.ID(), second.ID())
+	}
+
+	params.IdempotencyKey = "req-2"
+	third, err := mgr.CreateSession(context.Background(), params)
+	if err != nil {
+		t.Fatalf("create with new key failed: %v", err)
+	}
+	if third.ID() == first.ID() {
+		t.Fatalf("expected new session for different key")
+	}
+}
+
+func TestManagerSessionOrder(t *testing.T) {
+	mgr := NewManager(Config{}, zap.NewNop())
+	for _, id := range []string{"s1", "s2", "s3"} {
+		if err := mgr.addSession(newTestSession(t, SessionParams{ID: id, ProjectID: "p1"})); err != nil {
+			t.Fatalf("addSession failed: %v", err)
+		}
+	}
+
+	order := func() []string {
+		ids := make([]string, 0)
+		for _, snapshot := range mgr.ListSessions("p1") {
+			ids = append(ids, snapshot.ID)
+		}
+		return ids
+	}
+	if got := order(); !slices.Equal(got, []string{"s1", "s2", "s3"}) {
+		t.Fatalf("expected creation order, got %v", got)
+	}
+
+	s1, _ := mgr.GetSession("s1")
+	s2, _ := mgr.GetSession("s2")
+	if _, err := mgr.MoveSession("s3", (s1.OrderIndex()+s2.OrderIndex())/2); err != nil {
+		t.Fatalf("MoveSession failed: %v", err)
+	}
+	if got := order(); !slices.Equal(got, []string{"s1", "s3", "s2"}) {
+		t.Fatalf("expected moved order, got %v", got)
+	}
+
+	if _, err := mgr.MoveSession("s1", math.NaN()); !errors.Is(err, ErrInvalidOrderIndex) {
+		t.Fatalf("expected ErrInvalidOrderIndex, got %v", err)
+	}
+
+	// 新会话排在所有已有会话之后
+	if _, err := mgr.MoveSession("s1", 10000); err != nil {
+		t.Fatalf("MoveSession failed: %v", err)
+	}
+	if err := mgr.addSession(newTestSession(t, SessionParams{ID: "s4", ProjectID: "p1"})); err != nil {
+		t.Fatalf("addSession failed: %v", err)
+	}
+	if got := order(); !slices.Equal(got, []string{"s3", "s2", "s1", "s4"}) {
+		t.Fatalf("expected new session at the end, got %v", got)
+	}
+}
+
+func TestManagerNamedShell(t *testing.T) {
+	mgr := NewManager(Config{Shell: utils.TerminalShellConfig{
+		Linux: "/bin/sh",
+		Named: []utils.TerminalNamedShell{
+			{Name: "sh", Command: "/bin/sh -l"},
+			{Name: "missing", Command: "/no/such/shell"},
+		},
+	}}, zap.NewNop())
+
+	command, err := mgr.shellCommand("sh")
+	if err != nil {
+		t.Skipf("sh unavailable: %v", err)
+	}
+	if !slices.Equal(command, []string{"/bin/sh", "-l"}) {
+		t.Fatalf("unexpected named shell command %v", command)
+	}
+	if _, err := mgr.shellCommand("fish"); !errors.Is(err, ErrUnknownShell) {
+		t.Fatalf("expected ErrUnknownShell, got %v", err)
+	}
+	if _, err := mgr.shellCommand("missing"); !errors.Is(err, ErrShellNotFound) {
+		t.Fatalf("expected ErrShellNotFound for missing named shell, got %v", err)
+	}
+	if _, err := mgr.shellCommand(utils.DefaultShellName); err != nil {
+		t.Fatalf("expected default shell to resolve, got %v", err)
+	}
+
+	shells := mgr.ListShells()
+	if len(shells) != 3 || shells[0].Name != utils.DefaultShellName || !shells[0].Default {
+		t.Fatalf("expected default shell first, got %+v", shells)
+	}
+	if !shells[1].Available || shells[2].Available {
+		t.Fatalf("unexpected availability %+v", shells)
+	}
+}
+
+func TestManagerMissingConfiguredShell(t *testing.T) {
+	missing := "/no/such/shell -l"
+	mgr := NewManager(Config{Shell: utils.TerminalShellConfig{Linux: missing, Darwin: missing, Windows: missing}}, zap.NewNop())
+	// 用户配置的 shell 不存在时报错，不回退到平台默认的 shell
+	if _, err := mgr.shellCommand(""); !errors.Is(err, ErrShellNotFound) || !strings.Contains(err.Error(), missing) {
+		t.Fatalf("expected ErrShellNotFound with the configured shell, got %v", err)
+	}
+	if shells := mgr.ListShells(); shells[0].Available || shells[0].Command != missing {
+		t.Fatalf("expected the configured shell to be listed as unavailable, got %+v", shells[0])
+	}
+
+	// 未配置时探测平台默认的候选项
+	mgr = NewManager(Config{}, zap.NewNop())
+	if _, err := mgr.shellCommand(""); err != nil {
+		t.Skipf("no default shell available: %v", err)
+	}
+}
+
+func TestManagerCompletionSettleDelay(t *testing.T) {
+	mgr := NewManager(Config{CompletionSettleDelay: 100 * time.Millisecond}, zap.NewNop())
+	session := newTestSession(t, SessionParams{ID: "s1", ProjectID: "p1"})
+	go mgr.monitorAssistantRecords(session)
+	deadline := time.Now().Add(time.Second)
+	for session.Diagnostics().SubscriberCount == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("monitor did not subscribe")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Cleanup(func() { session.broadcast(StreamEvent{Type: StreamEventExit}) })
+
+	setState := func(state types.State) {
+		session.broadcast(StreamEvent{Type: StreamEventMetadata, Metadata: &SessionMetadata{
+			AIAssistant: &ai_assistant2.AIAssistantInfo{Type: "claude-code", Detected: true, State: string(state)},
+		}})
+	}
+	completed := func() int {
+		count := 0
+		for _, record := range mgr.GetRecordManager().GetCompletions() {
+			if record.State == "completed" {
+				count++
+			}
+		}
+		return count
+	}
+
+	setState(types.StateWorking)
+	for len(mgr.GetRecordManager().GetCompletions()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a working record")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	workingID := mgr.GetRecordManager().GetCompletions()[0].ID
+
+	// 短暂回到 waiting_input 又继续工作，不生成完成记录，也不替换 working 记录
+	setState(types.StateWaitingInput)
+	time.Sleep(20 * time.Millisecond)
+	setState(types.StateWorking)
+	time.Sleep(200 * time.Millisecond)
+	records := mgr.GetRecordManager().GetCompletions()
+	if len(records) != 1 || records[0].ID != workingID || completed() != 0 {
+		t.Fatalf("expected flapping state to keep the working record, got %+v", records)
+	}
+
+	setState(types.StateWaitingInput)
+	time.Sleep(50 * time.Millisecond)
+	if got := completed(); got != 0 {
+		t.Fatalf("expected completion to wait for the settle delay, got %d", got)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for completed() != 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected one completion after the state settled")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,90 @@
+package terminal
+
+import (
+	"encoding/json"
+	"reflect"
+)
+
+// MetadataDiffer turns a stream of metadata snapshots into JSON Merge Patches
+// (RFC 7396) for one subscriber. The first snapshot is always sent in full;
+// later ones only carry the changed fields, removed fields are set to null and
+// arrays such as todos are replaced as a whole. Each subscriber needs its own
+// differ because they attach at different times.
+type MetadataDiffer struct {
+	last map[string]any
+}
+
+// Next records meta as the latest snapshot. It reports full when the client has
+// no baseline yet and the whole snapshot must be sent; otherwise it returns the
+// merge patch against the previous snapshot, which is nil when nothing changed.
+func (d *MetadataDiffer) Next(meta *SessionMetadata) (patch json.RawMessage, full bool, err error) {
+	current, err := metadataFields(meta)
+	if err != nil {
+		return nil, false, err
+	}
+	previous := d.last
+	// 保存编码后的字段而非指针，广播出去的 metadata 可能被会话原地修改
+	d.last = current
+	if previous == nil {
+		return nil, true, nil
+	}
+
+	diff := mergePatchDiff(previous, current)
+	if len(diff) == 0 {
+		return nil, false, nil
+	}
+	patch, err = json.Marshal(diff)
+	if err != nil {
+		return nil, false, err
+	}
+	return patch, false, nil
+}
+
+// Reset drops the baseline so the next snapshot is sent in full again.
+func (d *MetadataDiffer) Reset() {
+	d.last = nil
+}
+
+func metadataFields(meta *SessionMetadata) (map[string]any, error) {
+	if meta == nil {
+		meta = &SessionMetadata{}
+	}
+	data, err := json.Marshal(meta)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]any)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	return fields, nil
+}
+
+// mergePatchDiff builds the merge patch that turns old into new.
+func mergePatchDiff(old, new map[string]any) map[string]any {
+	diff := make(map[string]any)
+	for key, oldValue := range old {
+		newValue, ok := new[key]
+		if !ok {
+			diff[key] = nil
+			continue
+		}
+		oldObject, oldIsObject := oldValue.(map[string]any)
+		newObject, newIsObject := newValue.(map[string]any)
+		if oldIsObject && newIsObject {
+			if nested := mergePatchDiff(oldObject, newObject); len(nested) > 0 {
+				diff[key] = nested
+			}
+			continue
+		}
+		if !reflect.DeepEqual(oldValue, newValue) {
+			diff[key] = newValue
+		}
+	}
+	for key, newValue := range new {
+		if _, ok := old[key]; !ok {
+			diff[key] = newValue
+		}
+	}
+	return diff
+}
@@ -0,0 +1,54 @@
+package terminal
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+func TestMetadataDifferNext(t *testing.T) {
+	var differ MetadataDiffer
+
+	first := &SessionMetadata{
+		Title:          "shell",
+		ProcessPID:     42,
+		RunningCommand: "claude",
+		AIAssistant:    &ai_assistant2.AIAssistantInfo{Type: "claude", DisplayName: "Claude Code", State: "working"},
+		Todos:          []ai_assistant2.TodoItem{{Text: "a"}},
+	}
+	if patch, full, err := differ.Next(first); err != nil || !full || patch != nil {
+		t.Fatalf("expected full snapshot first, got patch=%s full=%v err=%v", patch, full, err)
+	}
+	if patch, full, err := differ.Next(first); err != nil || full || patch != nil {
+		t.Fatalf("expected no patch for unchanged metadata, got patch=%s full=%v err=%v", patch, full, err)
+	}
+
+	second := cloneSessionMetadata(first)
+	second.RunningCommand = ""
+	second.AIAssistant.State = "waiting_input"
+	second.Todos = append(second.Todos, ai_assistant2.TodoItem{Text: "b"})
+	patch, full, err := differ.Next(second)
+	if err != nil || full {
+		t.Fatalf("unexpected result full=%v err=%v", full, err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(patch, &got); err != nil {
+		t.Fatalf("patch is not valid JSON: %v", err)
+	}
+	want := map[string]any{
+		"runningCommand": nil,
+		"aiAssistant":    map[string]any{"state": "waiting_input"},
+		"todos":          []any{map[string]any{"text": "a", "done": false}, map[string]any{"text": "b", "done": false}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected patch:\n got %s", patch)
+	}
+
+	differ.Reset()
+	if _, full, _ := differ.Next(second); !full {
+		t.Fatalf("expected full snapshot after reset")
+	}
+}
@@ -0,0 +1,108 @@
+package terminal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+type assistantStateKey struct {
+	Type  string
+	State string
+}
+
+// assistantMetrics 记录 AI 助手状态分布与完成次数，按 Prometheus 文本格式输出
+type assistantMetrics struct {
+	mu          sync.Mutex
+	states      map[assistantStateKey]int64
+	completions map[string]uint64
+}
+
+func newAssistantMetrics() *assistantMetrics {
+	return &assistantMetrics{
+		states:      make(map[assistantStateKey]int64),
+		completions: make(map[string]uint64),
+	}
+}
+
+// transition moves one session from (fromType, fromState) to (toType, toState).
+// An empty type means the session had or has no assistant attached.
+func (m *assistantMetrics) transition(fromType, fromState, toType, toState string) {
+	if m == nil || (fromType == toType && fromState == toState) {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if fromType != "" {
+		key := assistantStateKey{Type: fromType, State: fromState}
+		if m.states[key] <= 1 {
+			delete(m.states, key)
+		} else {
+			m.states[key]--
+		}
+	}
+	if toType != "" {
+		m.states[assistantStateKey{Type: toType, State: toState}]++
+	}
+}
+
+func (m *assistantMetrics) incCompletion(assistantType string) {
+	if m == nil || assistantType == "" {
+		return
+	}
+	m.mu.Lock()
+	m.completions[assistantType]++
+	m.mu.Unlock()
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *assistantMetrics) WritePrometheus(w io.Writer) error {
+	m.mu.Lock()
+	keys := make([]assistantStateKey, 0, len(m.states))
+	for key := range m.states {
+		keys = append(keys, key)
+	}
+	states := make(map[assistantStateKey]int64, len(m.states))
+	for key, value := range m.states {
+		states[key] = value
+	}
+	types := make([]string, 0, len(m.completions))
+	completions := make(map[string]uint64, len(m.completions))
+	for assistantType, value := range m.completions {
+		types = append(types, assistantType)
+		completions[assistantType] = value
+	}
+	m.mu.Unlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Type != keys[j].Type {
+			return keys[i].Type < keys[j].Type
+		}
+		return keys[i].State < keys[j].State
+	})
+	sort.Strings(types)
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# HELP ai_assistant_state Number of terminal sessions per AI assistant type and state.")
+	fmt.Fprintln(bw, "# TYPE ai_assistant_state gauge")
+	for _, key := range keys {
+		fmt.Fprintf(bw, "ai_assistant_state{type=\"%s\",state=\"%s\"} %d\n",
+			escapeMetricLabel(key.Type), escapeMetricLabel(key.State), states[key])
+	}
+	fmt.Fprintln(bw, "# HELP ai_assistant_completions_total Number of AI assistant tasks completed (working -> waiting input).")
+	fmt.Fprintln(bw, "# TYPE ai_assistant_completions_total counter")
+	for _, assistantType := range types {
+		fmt.Fprintf(bw, "ai_assistant_completions_total{type=\"%s\"} %d\n",
+			escapeMetricLabel(assistantType), completions[assistantType])
+	}
+	return bw.Flush()
+}
+
+var metricLabelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escapeMetricLabel(value string) string {
+	return metricLabelEscaper.Replace(value)
+}
@@ -0,0 +1,38 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAssistantMetrics(t *testing.T) {
+	metrics := newAssistantMetrics()
+	metrics.transition("", "", "claude-code", "working")
+	metrics.transition("", "", "claude-code", "working")
+	metrics.transition("claude-code", "working", "claude-code", "waiting_input")
+	metrics.incCompletion("claude-code")
+	metrics.transition("", "", "codex", "waiting_approval")
+	metrics.transition("codex", "waiting_approval", "", "")
+
+	var buf bytes.Buffer
+	if err := metrics.WritePrometheus(&buf); err != nil {
+		t.Fatalf("WritePrometheus failed: %v", err)
+	}
+	output := buf.String()
+
+	for _, want := range []string{
+		"# TYPE ai_assistant_state gauge",
+		`ai_assistant_state{type="claude-code",state="waiting_input"} 1`,
+		`ai_assistant_state{type="claude-code",state="working"} 1`,
+		"# TYPE ai_assistant_completions_total counter",
+		`ai_assistant_completions_total{type="claude-code"} 1`,
+	} {
+		if !strings.Contains(output, want) {
+			t.Fatalf("expected %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, `type="codex"`) {
+		t.Fatalf("expected detached assistant to be removed:\n%s", output)
+	}
+}
@@ -0,0 +1,173 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// oscPrefix 所有 OSC 序列的开头：ESC ]
+var oscPrefix = []byte("\x1b]")
+
+const (
+	// oscTitlePendingMaxBytes 跨 chunk 的未结束标题序列上限，超出视为损坏直接丢弃
+	oscTitlePendingMaxBytes = 4096
+	// oscTitleDebounce 两次按窗口标题改名的最小间隔，期间的标题合并为最后一个，
+	// 避免 spinner 或逐条命令更新标题时标签频繁跳动、刷屏广播元数据
+	oscTitleDebounce = time.Second
+)
+
+// oscTitleTracker 从输出流中解析 OSC 0 / OSC 2，记录程序设置的窗口标题
+type oscTitleTracker struct {
+	mu      sync.Mutex
+	pending []byte
+	// appliedAt 上次应用标题的时间；latest 防抖期间收到的最新标题，由 timer 到期时应用
+	appliedAt time.Time
+	latest    string
+	timer     *time.Timer
+}
+
+// observe returns the last window title set in chunk, if any. OSC 1 only sets
+// the icon name and is ignored.
+func (o *oscTitleTracker) observe(chunk []byte) (string, bool) {
+	if len(chunk) == 0 {
+		return "", false
+	}
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	data := chunk
+	if len(o.pending) > 0 {
+		data = append(o.pending, chunk...)
+		o.pending = nil
+	}
+	var title string
+	found := false
+	for {
+		idx := bytes.Index(data, oscPrefix)
+		if idx < 0 {
+			o.pending = partialPrefix(data, oscPrefix)
+			return title, found
+		}
+		body := data[idx+len(oscPrefix):]
+		end, stLen := findStringTerminator(body)
+		if end < 0 {
+			// 序列还没结束：等下一个 chunk；中途出现其他 ESC 说明已损坏
+			if bytes.IndexByte(body, 0x1b) < 0 && len(data)-idx <= oscTitlePendingMaxBytes {
+				o.pending = append([]byte(nil), data[idx:]...)
+			}
+			return title, found
+		}
+		if value, ok := parseOSCTitle(body[:end]); ok {
+			title, found = value, true
+		}
+		data = body[end+stLen:]
+	}
+}
+
+// reset drops the partial sequence and the debounced title of the previous
+// process.
+func (o *oscTitleTracker) reset() {
+	o.mu.Lock()
+	o.pending = nil
+	o.latest = ""
+	if o.timer != nil {
+		o.timer.Stop()
+		o.timer = nil
+	}
+	o.mu.Unlock()
+}
+
+// debounce reports whether title can be applied now. Otherwise it is kept as
+// the latest title and flush is scheduled for when oscTitleDebounce has passed
+// since the last applied title.
+func (o *oscTitleTracker) debounce(title string, now time.Time, flush func()) bool {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	elapsed := now.Sub(o.appliedAt)
+	if o.timer == nil && elapsed >= oscTitleDebounce {
+		o.appliedAt = now
+		return true
+	}
+	o.latest = title
+	if o.timer == nil {
+		o.timer = time.AfterFunc(oscTitleDebounce-elapsed, flush)
+	}
+	return false
+}
+
+// takeLatest returns the title kept during the debounce and marks it applied.
+func (o *oscTitleTracker) takeLatest(now time.Time) string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	title := o.latest
+	o.latest = ""
+	o.timer = nil
+	o.appliedAt = now
+	return title
+}
+
+// parseOSCTitle reads the title from the body of an OSC 0 or OSC 2 sequence.
+func parseOSCTitle(body []byte) (string, bool) {
+	code, value, ok := bytes.Cut(body, []byte{';'})
+	if !ok || (string(code) != "0" && string(code) != "2") {
+		return "", false
+	}
+	return string(value), true
+}
+
+// applyOSCTitle uses a window title set by the running program as the session
+// title, unless the user renamed the session or a linked task locked the title.
+// An empty title, which programs send to restore the default, is ignored.
+// Titles arriving within oscTitleDebounce of the last one are coalesced and
+// only the newest is applied when the interval has passed.
+func (s *Session) applyOSCTitle(raw string) {
+	title := strings.TrimSpace(truncateString(sanitizeCapturedInput(raw), maxSessionTitleLength))
+	if title == "" || !s.oscTitle.debounce(title, time.Now(), s.flushOSCTitle) {
+		return
+	}
+	s.setOSCTitle(title)
+}
+
+// flushOSCTitle applies the newest title coalesced during the debounce.
+func (s *Session) flushOSCTitle() {
+	select {
+	case <-s.closed:
+		return
+	default:
+	}
+	if title := s.oscTitle.takeLatest(time.Now()); title != "" {
+		s.setOSCTitle(title)
+	}
+}
+
+func (s *Session) setOSCTitle(title string) {
+	if !s.applyRuleTitle(title) {
+		return
+	}
+	s.logger.Debug("terminal title set by window title sequence",
+		zap.String("sessionId", s.id),
+		zap.String("title", title))
+	s.notifyTitleChanged()
+}
+
+// splitUnterminatedOSC splits off an OSC sequence left open at the end of data,
+// so it can be joined with the next chunk before ANSI sequences are stripped
+// instead of leaking its payload, e.g. a window title, into plain text.
+func splitUnterminatedOSC(data []byte) ([]byte, []byte) {
+	if idx := bytes.LastIndex(data, oscPrefix); idx >= 0 && len(data)-idx <= oscTitlePendingMaxBytes {
+		body := data[idx+len(oscPrefix):]
+		end, _ := findStringTerminator(body)
+		// 中途出现其他 ESC 说明已损坏，只有末尾的 ESC 可能是 ST 的前半
+		if esc := bytes.IndexByte(body, 0x1b); end < 0 && (esc < 0 || esc == len(body)-1) {
+			return data[:idx], data[idx:]
+		}
+	}
+	if bytes.HasSuffix(data, []byte{0x1b}) {
+		return data[:len(data)-1], data[len(data)-1:]
+	}
+	return data, nil
+}
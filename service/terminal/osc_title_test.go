@@ -0,0 +1,105 @@
+package terminal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOSCTitleTrackerObserve(t *testing.T) {
+	var tracker oscTitleTracker
+	if _, ok := tracker.observe([]byte("$ ls\r\n\x1b]7;file:///tmp\x07")); ok {
+		t.Fatalf("expected no title without OSC 0/2")
+	}
+	if _, ok := tracker.observe([]byte("\x1b]1;icon\x07")); ok {
+		t.Fatalf("expected OSC 1 icon name to be ignored")
+	}
+
+	title, ok := tracker.observe([]byte("\x1b]0;first\x07out\x1b]2;vim main.go\x07"))
+	if !ok || title != "vim main.go" {
+		t.Fatalf("expected last title in chunk, got %q %v", title, ok)
+	}
+
+	// 序列跨 chunk，使用 ESC \ 结束
+	if _, ok := tracker.observe([]byte("text\x1b")); ok {
+		t.Fatalf("expected pending sequence to report nothing")
+	}
+	if _, ok := tracker.observe([]byte("]2;build")); ok {
+		t.Fatalf("expected unterminated sequence to report nothing")
+	}
+	title, ok = tracker.observe([]byte("ing\x1b\\$ "))
+	if !ok || title != "building" {
+		t.Fatalf("expected title joined across chunks, got %q %v", title, ok)
+	}
+}
+
+func TestSessionOSCTitle(t *testing.T) {
+	session := newTestSession(t, SessionParams{Title: "shell"})
+	session.applyOSCTitle("  ")
+	if got := session.Title(); got != "shell" {
+		t.Fatalf("expected empty title to be ignored, got %q", got)
+	}
+	session.applyOSCTitle("me@host: ~/project")
+	if got := session.Title(); got != "me@host: ~/project" {
+		t.Fatalf("expected title from sequence, got %q", got)
+	}
+
+	if err := session.UpdateTitle("mine"); err != nil {
+		t.Fatalf("UpdateTitle failed: %v", err)
+	}
+	session.applyOSCTitle("htop")
+	if got := session.Title(); got != "mine" {
+		t.Fatalf("expected manual title to be kept, got %q", got)
+	}
+}
+
+func TestSessionOSCTitleDebounce(t *testing.T) {
+	session := newTestSession(t, SessionParams{Title: "shell"})
+	session.applyOSCTitle("⠋ working")
+	// 防抖期间的标题只保留最新一个，到期后应用
+	session.applyOSCTitle("⠙ working")
+	session.applyOSCTitle("⠹ working")
+	if got := session.Title(); got != "⠋ working" {
+		t.Fatalf("expected the debounced titles to wait, got %q", got)
+	}
+	session.oscTitle.mu.Lock()
+	timer, latest := session.oscTitle.timer, session.oscTitle.latest
+	session.oscTitle.mu.Unlock()
+	if timer == nil || latest != "⠹ working" {
+		t.Fatalf("expected one pending flush of the newest title, got %v %q", timer, latest)
+	}
+	timer.Stop()
+	session.flushOSCTitle()
+	if got := session.Title(); got != "⠹ working" {
+		t.Fatalf("expected the newest title after the debounce, got %q", got)
+	}
+
+	// 重启进程时丢弃未应用的标题
+	session.applyOSCTitle("stale")
+	session.oscTitle.reset()
+	session.flushOSCTitle()
+	if got := session.Title(); got != "⠹ working" {
+		t.Fatalf("expected the pending title to be dropped, got %q", got)
+	}
+}
+
+func TestSearchScrollbackIgnoresSplitOSCTitle(t *testing.T) {
+	session := newTestSession(t, SessionParams{ScrollbackLimit: 1024})
+	session.appendScrollback([]byte("ok\r\n\x1b]0;secret-ti"))
+	session.appendScrollback([]byte("tle\x07done secret\r\n"))
+
+	matches, err := session.SearchScrollback("secret", 0)
+	if err != nil {
+		t.Fatalf("SearchScrollback failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Line != "done secret" {
+		t.Fatalf("expected title sequence to be skipped, got %+v", matches)
+	}
+
+	var out bytes.Buffer
+	if err := session.WritePlainScrollback(&out); err != nil {
+		t.Fatalf("WritePlainScrollback failed: %v", err)
+	}
+	if got := out.String(); got != "ok\ndone secret\n" {
+		t.Fatalf("unexpected plain scrollback %q", got)
+	}
+}
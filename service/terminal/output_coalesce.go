@@ -0,0 +1,96 @@
+package terminal
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	// outputCoalesceWindow 输出合并窗口：距上次发送不足该时长的输出先缓存，窗口结束时合并发送
+	outputCoalesceWindow = 16 * time.Millisecond
+	// outputCoalesceMaxBytes 缓存达到该大小时立即发送，避免单条消息过大
+	outputCoalesceMaxBytes = 64 * 1024
+)
+
+// outputCoalescer merges PTY output that arrives in bursts. Output after at
+// least window of quiet is emitted right away so typing stays responsive;
+// output arriving within window of the previous emit is buffered and emitted
+// as one chunk when the window ends. Emits are serialized, so bytes keep their
+// order, and each emitted chunk becomes one scrollback chunk and one data
+// event carrying the same seq.
+type outputCoalescer struct {
+	window time.Duration
+	emit   func([]byte)
+
+	mu       sync.Mutex
+	pending  []byte
+	lastEmit time.Time
+	timer    *time.Timer
+	// stopped 之后的写入与定时刷新都被丢弃
+	stopped bool
+}
+
+func newOutputCoalescer(window time.Duration, emit func([]byte)) *outputCoalescer {
+	return &outputCoalescer{window: window, emit: emit}
+}
+
+func (c *outputCoalescer) write(data []byte) {
+	if len(data) == 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		return
+	}
+
+	now := time.Now()
+	if len(c.pending) == 0 && (c.window <= 0 || now.Sub(c.lastEmit) >= c.window) {
+		c.lastEmit = now
+		c.emit(data)
+		return
+	}
+	c.pending = append(c.pending, data...)
+	if len(c.pending) >= outputCoalesceMaxBytes {
+		c.flushLocked(now)
+		return
+	}
+	if c.timer == nil {
+		c.timer = time.AfterFunc(c.lastEmit.Add(c.window).Sub(now), c.flush)
+	}
+}
+
+// flush emits buffered output right away, e.g. before the PTY reader exits.
+func (c *outputCoalescer) flush() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.flushLocked(time.Now())
+}
+
+// stop discards buffered output and ignores everything afterwards, including a
+// timer flush already in flight. Used when the session is being closed and its
+// subscribers are going away.
+func (c *outputCoalescer) stop() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stopped = true
+	c.pending = nil
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+}
+
+func (c *outputCoalescer) flushLocked(now time.Time) {
+	if c.timer != nil {
+		c.timer.Stop()
+		c.timer = nil
+	}
+	if c.stopped || len(c.pending) == 0 {
+		return
+	}
+	data := c.pending
+	c.pending = nil
+	c.lastEmit = now
+	c.emit(data)
+}
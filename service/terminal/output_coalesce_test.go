@@ -0,0 +1,73 @@
+package terminal
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestOutputCoalescer(t *testing.T) {
+	var mu sync.Mutex
+	var emitted []string
+	coalescer := newOutputCoalescer(50*time.Millisecond, func(data []byte) {
+		mu.Lock()
+		emitted = append(emitted, string(data))
+		mu.Unlock()
+	})
+	snapshot := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), emitted...)
+	}
+
+	// 空闲后的第一个分片立即发送，窗口内的后续分片合并为一条
+	coalescer.write([]byte("a"))
+	if got := snapshot(); len(got) != 1 || got[0] != "a" {
+		t.Fatalf("expected first chunk to be emitted immediately, got %q", got)
+	}
+	coalescer.write([]byte("b"))
+	coalescer.write([]byte("c"))
+	if got := snapshot(); len(got) != 1 {
+		t.Fatalf("expected burst to be buffered, got %q", got)
+	}
+	time.Sleep(120 * time.Millisecond)
+	if got := snapshot(); len(got) != 2 || got[1] != "bc" {
+		t.Fatalf("expected merged chunk after the window, got %q", got)
+	}
+
+	coalescer.write([]byte("d"))
+	coalescer.write([]byte("e"))
+	coalescer.flush()
+	if got := snapshot(); len(got) != 4 || got[2] != "d" || got[3] != "e" {
+		t.Fatalf("expected flush to emit pending output in order, got %q", got)
+	}
+
+	big := make([]byte, outputCoalesceMaxBytes)
+	coalescer.write(big)
+	if got := snapshot(); len(got) != 5 || len(got[4]) != outputCoalesceMaxBytes {
+		t.Fatalf("expected oversized buffer to be emitted right away, got %d chunks", len(got))
+	}
+}
+
+func TestOutputCoalescerStop(t *testing.T) {
+	var mu sync.Mutex
+	var emitted []string
+	coalescer := newOutputCoalescer(50*time.Millisecond, func(data []byte) {
+		mu.Lock()
+		emitted = append(emitted, string(data))
+		mu.Unlock()
+	})
+
+	coalescer.write([]byte("a"))
+	coalescer.write([]byte("b"))
+	coalescer.stop()
+	coalescer.write([]byte("c"))
+	coalescer.flush()
+	time.Sleep(100 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(emitted) != 1 || emitted[0] != "a" {
+		t.Fatalf("expected pending output to be dropped after stop, got %q", emitted)
+	}
+}
@@ -0,0 +1,180 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// OutputFilterRule rewrites rendered output lines in derived views such as the
+// preview and the filtered export; the scrollback and live stream are untouched.
+type OutputFilterRule struct {
+	// Name 规则名称，仅用于展示
+	Name    string `json:"name,omitempty" yaml:"name"`
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// Replace 命中部分的替换文本，支持 $1 等捕获组引用，为空表示删除命中部分
+	Replace string `json:"replace,omitempty" yaml:"replace"`
+	// DropLine 为 true 时删除命中的整行，忽略 Replace
+	DropLine bool `json:"dropLine,omitempty" yaml:"dropLine"`
+}
+
+type compiledOutputFilter struct {
+	OutputFilterRule
+	re *regexp.Regexp
+}
+
+// compileOutputFilters validates output filter rules; the index of an invalid rule is reported.
+func compileOutputFilters(rules []OutputFilterRule) ([]*compiledOutputFilter, error) {
+	compiled := make([]*compiledOutputFilter, 0, len(rules))
+	for i, rule := range rules {
+		if strings.TrimSpace(rule.Pattern) == "" {
+			return nil, fmt.Errorf("output filter %d: pattern is required", i)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("output filter %d: invalid pattern: %w", i, err)
+		}
+		compiled = append(compiled, &compiledOutputFilter{OutputFilterRule: rule, re: re})
+	}
+	return compiled, nil
+}
+
+// applyOutputFilters runs every rule over every line in order. A line that
+// becomes empty only because a rule deleted its content is dropped as well, so
+// deleting a spinner frame does not leave a blank line behind.
+func applyOutputFilters(lines []string, filters []*compiledOutputFilter) []string {
+	if len(filters) == 0 {
+		return lines
+	}
+	result := make([]string, 0, len(lines))
+	for _, line := range lines {
+		filtered, keep := filterOutputLine(line, filters)
+		if keep {
+			result = append(result, filtered)
+		}
+	}
+	return result
+}
+
+func filterOutputLine(line string, filters []*compiledOutputFilter) (string, bool) {
+	original := line
+	for _, filter := range filters {
+		if !filter.re.MatchString(line) {
+			continue
+		}
+		if filter.DropLine {
+			return "", false
+		}
+		line = filter.re.ReplaceAllString(line, filter.Replace)
+	}
+	if line != original && strings.TrimSpace(line) == "" {
+		return "", false
+	}
+	return line, true
+}
+
+// setGlobalOutputFilters replaces the output filters shared by all sessions.
+func (s *Session) setGlobalOutputFilters(filters []*compiledOutputFilter) {
+	s.filterMu.Lock()
+	s.globalOutputFilters = filters
+	s.filterMu.Unlock()
+}
+
+// SetOutputFilters replaces the session scoped output filters.
+func (s *Session) SetOutputFilters(rules []OutputFilterRule) error {
+	compiled, err := compileOutputFilters(rules)
+	if err != nil {
+		return err
+	}
+	s.filterMu.Lock()
+	s.sessionOutputFilters = compiled
+	s.filterMu.Unlock()
+	return nil
+}
+
+// OutputFilters returns the session scoped output filters.
+func (s *Session) OutputFilters() []OutputFilterRule {
+	s.filterMu.Lock()
+	defer s.filterMu.Unlock()
+	result := make([]OutputFilterRule, 0, len(s.sessionOutputFilters))
+	for _, filter := range s.sessionOutputFilters {
+		result = append(result, filter.OutputFilterRule)
+	}
+	return result
+}
+
+// outputFilters returns the session rules followed by the global ones.
+func (s *Session) outputFilters() []*compiledOutputFilter {
+	s.filterMu.Lock()
+	defer s.filterMu.Unlock()
+	if len(s.sessionOutputFilters) == 0 {
+		return s.globalOutputFilters
+	}
+	filters := make([]*compiledOutputFilter, 0, len(s.sessionOutputFilters)+len(s.globalOutputFilters))
+	filters = append(filters, s.sessionOutputFilters...)
+	return append(filters, s.globalOutputFilters...)
+}
+
+// WriteFilteredScrollback is WritePlainScrollback with the output filters
+// applied line by line.
+func (s *Session) WriteFilteredScrollback(w io.Writer) error {
+	filters := s.outputFilters()
+	if len(filters) == 0 {
+		return s.WritePlainScrollback(w)
+	}
+	fw := &filteredLineWriter{w: w, filters: filters}
+	if err := s.WritePlainScrollback(fw); err != nil {
+		return err
+	}
+	return fw.flush()
+}
+
+// filteredLineWriter buffers the plain output until a line is complete so rules
+// see whole lines even when a line spans several scrollback chunks.
+type filteredLineWriter struct {
+	w       io.Writer
+	filters []*compiledOutputFilter
+	pending []byte
+}
+
+func (fw *filteredLineWriter) Write(p []byte) (int, error) {
+	fw.pending = append(fw.pending, p...)
+	for {
+		idx := bytes.IndexByte(fw.pending, '\n')
+		if idx < 0 {
+			return len(p), nil
+		}
+		if err := fw.writeLine(string(fw.pending[:idx]), true); err != nil {
+			return 0, err
+		}
+		fw.pending = fw.pending[idx+1:]
+	}
+}
+
+func (fw *filteredLineWriter) flush() error {
+	if len(fw.pending) == 0 {
+		return nil
+	}
+	line := string(fw.pending)
+	fw.pending = nil
+	return fw.writeLine(line, false)
+}
+
+func (fw *filteredLineWriter) writeLine(line string, newline bool) error {
+	// \r\n 的 \r 不参与匹配，输出时原样保留
+	body, cr := strings.CutSuffix(line, "\r")
+	filtered, keep := filterOutputLine(body, fw.filters)
+	if !keep {
+		return nil
+	}
+	if cr {
+		filtered += "\r"
+	}
+	if newline {
+		filtered += "\n"
+	}
+	_, err := io.WriteString(fw.w, filtered)
+	return err
+}
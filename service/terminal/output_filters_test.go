@@ -0,0 +1,70 @@
+package terminal
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+)
+
+func TestApplyOutputFilters(t *testing.T) {
+	filters, err := compileOutputFilters([]OutputFilterRule{
+		{Pattern: `^[⠋⠙⠹] Loading`, DropLine: true},
+		{Pattern: `token=\w+`, Replace: "token=***"},
+		{Pattern: `\s*\(\d+ms\)$`},
+	})
+	if err != nil {
+		t.Fatalf("compileOutputFilters: %v", err)
+	}
+	lines := []string{"⠋ Loading", "auth token=abc123 ok", "build done (42ms)", "", "  (7ms)", "plain"}
+	got := applyOutputFilters(lines, filters)
+	// 原本就是空行的保留，被规则删空的行去掉
+	want := []string{"auth token=*** ok", "build done", "", "plain"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected filtered lines %#v", got)
+	}
+
+	if _, err := compileOutputFilters([]OutputFilterRule{{Pattern: "("}}); err == nil {
+		t.Fatalf("expected invalid pattern error")
+	}
+}
+
+func TestSessionOutputFiltersApplyToDerivedViews(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 10, Cols: 40, ScrollbackLimit: 4096})
+	global, err := compileOutputFilters([]OutputFilterRule{{Pattern: `^spinner`, DropLine: true}})
+	if err != nil {
+		t.Fatalf("compileOutputFilters: %v", err)
+	}
+	session.setGlobalOutputFilters(global)
+	if err := session.SetOutputFilters([]OutputFilterRule{{Pattern: `secret`, Replace: "[hidden]"}}); err != nil {
+		t.Fatalf("SetOutputFilters: %v", err)
+	}
+	if rules := session.OutputFilters(); len(rules) != 1 || rules[0].Replace != "[hidden]" {
+		t.Fatalf("unexpected session filters %+v", rules)
+	}
+
+	// 一行跨两个分片时导出仍按整行匹配
+	session.appendScrollback([]byte("spinner 1\r\nthe sec"))
+	session.appendScrollback([]byte("ret value\r\nspinner 2\r\ndone"))
+
+	preview := session.OutputPreview(5)
+	if !slices.Equal(preview.Lines, []string{"the [hidden] value", "done"}) {
+		t.Fatalf("unexpected preview %#v", preview.Lines)
+	}
+
+	var filtered bytes.Buffer
+	if err := session.WriteFilteredScrollback(&filtered); err != nil {
+		t.Fatalf("WriteFilteredScrollback: %v", err)
+	}
+	if got := filtered.String(); got != "the [hidden] value\ndone" {
+		t.Fatalf("unexpected filtered export %q", got)
+	}
+
+	// 原始 scrollback 不受影响
+	var plain bytes.Buffer
+	if err := session.WritePlainScrollback(&plain); err != nil {
+		t.Fatalf("WritePlainScrollback: %v", err)
+	}
+	if got := plain.String(); got != "spinner 1\nthe secret value\nspinner 2\ndone" {
+		t.Fatalf("unexpected plain export %q", got)
+	}
+}
@@ -0,0 +1,163 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+const (
+	defaultOutputLogMaxBytes = 10 * 1024 * 1024
+	defaultOutputLogMaxFiles = 5
+)
+
+// OutputLogConfig controls persisting session output to plain text log files.
+type OutputLogConfig struct {
+	// Dir 为空时不写日志，文件名为 <sessionId>.log
+	Dir string
+	// MaxBytes 单个文件的大小上限，超过后轮转为 .1/.2...，0 使用默认 10MB
+	MaxBytes int64
+	// MaxFiles 保留的已轮转文件数，0 使用默认 5
+	MaxFiles int
+	// Raw 保留原始输出（含 ANSI 控制序列），默认去掉控制序列便于 grep
+	Raw bool
+}
+
+// outputLog appends session output to a size rotated log file. Write errors are
+// logged once and disable the log so the PTY loop is never affected.
+type outputLog struct {
+	mu       sync.Mutex
+	cfg      OutputLogConfig
+	path     string
+	file     *os.File
+	size     int64
+	disabled bool
+	logger   *zap.Logger
+}
+
+func newOutputLog(cfg OutputLogConfig, sessionID string, logger *zap.Logger) (*outputLog, error) {
+	if cfg.Dir == "" {
+		return nil, nil
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultOutputLogMaxBytes
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = defaultOutputLogMaxFiles
+	}
+	if err := os.MkdirAll(cfg.Dir, 0o755); err != nil {
+		return nil, err
+	}
+	l := &outputLog{cfg: cfg, path: filepath.Join(cfg.Dir, sessionID+".log"), logger: logger}
+	if err := l.openLocked(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (l *outputLog) openLocked() error {
+	file, err := os.OpenFile(l.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return err
+	}
+	l.file = file
+	l.size = info.Size()
+	return nil
+}
+
+// Write appends one normalized output chunk.
+func (l *outputLog) Write(chunk []byte) {
+	if l == nil || len(chunk) == 0 {
+		return
+	}
+	data := chunk
+	if !l.cfg.Raw {
+		data = plainTextOutput(chunk)
+		if len(data) == 0 {
+			return
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.disabled || l.file == nil {
+		return
+	}
+	if l.size > 0 && l.size+int64(len(data)) > l.cfg.MaxBytes {
+		if err := l.rotateLocked(); err != nil {
+			l.failLocked("rotate", err)
+			return
+		}
+	}
+	n, err := l.file.Write(data)
+	l.size += int64(n)
+	if err != nil {
+		l.failLocked("write", err)
+	}
+}
+
+// rotateLocked shifts <id>.log.N-1 -> <id>.log.N, dropping files beyond MaxFiles.
+func (l *outputLog) rotateLocked() error {
+	if err := l.file.Close(); err != nil {
+		return err
+	}
+	l.file = nil
+	_ = os.Remove(fmt.Sprintf("%s.%d", l.path, l.cfg.MaxFiles))
+	for i := l.cfg.MaxFiles - 1; i >= 1; i-- {
+		from := fmt.Sprintf("%s.%d", l.path, i)
+		if _, err := os.Stat(from); err == nil {
+			if err := os.Rename(from, fmt.Sprintf("%s.%d", l.path, i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	return l.openLocked()
+}
+
+func (l *outputLog) failLocked(op string, err error) {
+	l.disabled = true
+	if l.file != nil {
+		_ = l.file.Close()
+		l.file = nil
+	}
+	if l.logger != nil {
+		l.logger.Warn("terminal output log disabled after error",
+			zap.String("path", l.path),
+			zap.String("op", op),
+			zap.Error(err))
+	}
+}
+
+// Close flushes and closes the current log file; rotated files are kept.
+func (l *outputLog) Close() {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.disabled = true
+	if l.file != nil {
+		_ = l.file.Close()
+		l.file = nil
+	}
+}
+
+// plainTextOutput strips ANSI sequences and normalizes line endings.
+func plainTextOutput(chunk []byte) []byte {
+	text := bytes.ReplaceAll(chunk, []byte("\r\n"), []byte("\n"))
+	return []byte(types.StripANSI(string(text)))
+}
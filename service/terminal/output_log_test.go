@@ -0,0 +1,51 @@
+package terminal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOutputLogRotation(t *testing.T) {
+	dir := t.TempDir()
+	log, err := newOutputLog(OutputLogConfig{Dir: dir, MaxBytes: 16, MaxFiles: 2}, "session-1", nil)
+	if err != nil {
+		t.Fatalf("newOutputLog failed: %v", err)
+	}
+	defer log.Close()
+
+	log.Write([]byte("\x1b[32mfirst\x1b[0m\r\n"))
+	log.Write([]byte("second line\r\n"))
+	log.Write([]byte("third line\r\n"))
+	log.Write([]byte("fourth line\r\n"))
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatalf("read %s: %v", name, err)
+		}
+		return string(data)
+	}
+	if got := read("session-1.log"); got != "fourth line\n" {
+		t.Fatalf("unexpected current log %q", got)
+	}
+	if got := read("session-1.log.1"); got != "third line\n" {
+		t.Fatalf("unexpected .1 log %q", got)
+	}
+	if got := read("session-1.log.2"); got != "second line\n" {
+		t.Fatalf("unexpected .2 log %q", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "session-1.log.3")); !os.IsNotExist(err) {
+		t.Fatalf("expected only MaxFiles rotated logs to be kept, got %v", err)
+	}
+}
+
+func TestOutputLogDisabled(t *testing.T) {
+	log, err := newOutputLog(OutputLogConfig{}, "session-1", nil)
+	if err != nil || log != nil {
+		t.Fatalf("expected nil log, got %v %v", log, err)
+	}
+	log.Write([]byte("ignored"))
+	log.Close()
+}
@@ -0,0 +1,208 @@
+package terminal
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// outputSinkBufferSize sink 待写出的分片数上限，外部程序读得慢时超出部分丢弃
+	outputSinkBufferSize = 256
+	// outputSinkWriteTimeout 单次写出的超时，超时视为外部程序已卡住并断开
+	outputSinkWriteTimeout = 5 * time.Second
+	outputSinkDialTimeout  = 3 * time.Second
+	// windowsPipePrefix Windows 命名管道路径前缀
+	windowsPipePrefix = `\\.\pipe\`
+)
+
+// OutputSinkInfo describes the external program a session tees its output to.
+type OutputSinkInfo struct {
+	Target        string    `json:"target"`
+	AttachedAt    time.Time `json:"attachedAt"`
+	BytesWritten  int64     `json:"bytesWritten"`
+	DroppedChunks int64     `json:"droppedChunks"`
+}
+
+// outputSink copies session output to a local socket or named pipe. Output is
+// queued and written by its own goroutine, so a slow or dead reader never
+// blocks the PTY loop: chunks are dropped while the queue is full and the sink
+// detaches itself on the first write error.
+type outputSink struct {
+	target     string
+	conn       io.WriteCloser
+	ch         chan []byte
+	done       chan struct{}
+	once       sync.Once
+	attachedAt time.Time
+	written    atomic.Int64
+	dropped    atomic.Int64
+	onFail     func(*outputSink, error)
+}
+
+// openOutputSinkTarget connects to target, which must be an absolute path to
+// a unix socket or a named pipe. Regular files are rejected; use the output
+// log for those.
+func openOutputSinkTarget(target string) (io.WriteCloser, error) {
+	if strings.HasPrefix(target, windowsPipePrefix) {
+		return os.OpenFile(target, os.O_WRONLY, 0)
+	}
+	if !filepath.IsAbs(target) {
+		return nil, fmt.Errorf("%w: %q is not an absolute path", ErrInvalidOutputSink, target)
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidOutputSink, err)
+	}
+	switch mode := info.Mode(); {
+	case mode&os.ModeSocket != 0:
+		return net.DialTimeout("unix", target, outputSinkDialTimeout)
+	case mode&os.ModeNamedPipe != 0:
+		// 非阻塞打开：没有读端时立即失败，而不是卡住请求
+		return os.OpenFile(target, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	default:
+		return nil, fmt.Errorf("%w: %q is not a socket or named pipe", ErrInvalidOutputSink, target)
+	}
+}
+
+func newOutputSink(target string, conn io.WriteCloser, onFail func(*outputSink, error)) *outputSink {
+	sink := &outputSink{
+		target:     target,
+		conn:       conn,
+		ch:         make(chan []byte, outputSinkBufferSize),
+		done:       make(chan struct{}),
+		attachedAt: time.Now(),
+		onFail:     onFail,
+	}
+	go sink.run()
+	return sink
+}
+
+func (k *outputSink) write(chunk []byte) {
+	if k == nil || len(chunk) == 0 {
+		return
+	}
+	select {
+	case k.ch <- chunk:
+	default:
+		k.dropped.Add(1)
+	}
+}
+
+func (k *outputSink) run() {
+	deadline, hasDeadline := k.conn.(interface{ SetWriteDeadline(time.Time) error })
+	for {
+		select {
+		case <-k.done:
+			return
+		case chunk := <-k.ch:
+			if hasDeadline {
+				_ = deadline.SetWriteDeadline(time.Now().Add(outputSinkWriteTimeout))
+			}
+			n, err := k.conn.Write(chunk)
+			k.written.Add(int64(n))
+			if err != nil {
+				k.close()
+				if k.onFail != nil {
+					k.onFail(k, err)
+				}
+				return
+			}
+		}
+	}
+}
+
+func (k *outputSink) close() {
+	if k == nil {
+		return
+	}
+	k.once.Do(func() {
+		close(k.done)
+		_ = k.conn.Close()
+	})
+}
+
+func (k *outputSink) info() *OutputSinkInfo {
+	return &OutputSinkInfo{
+		Target:        k.target,
+		AttachedAt:    k.attachedAt,
+		BytesWritten:  k.written.Load(),
+		DroppedChunks: k.dropped.Load(),
+	}
+}
+
+// SetOutputSink tees the session output to target, a unix socket or named
+// pipe read by an external program, replacing any previous sink. The sink
+// receives the same decoded output as data events, ANSI sequences included.
+func (s *Session) SetOutputSink(target string) (*OutputSinkInfo, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return nil, fmt.Errorf("%w: target is required", ErrInvalidOutputSink)
+	}
+	conn, err := openOutputSinkTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	sink := newOutputSink(target, conn, s.detachFailedOutputSink)
+	if previous := s.outputSink.Swap(sink); previous != nil {
+		previous.close()
+	}
+	s.logger.Info("terminal output sink attached",
+		zap.String("sessionId", s.id),
+		zap.String("target", target))
+	return sink.info(), nil
+}
+
+// RemoveOutputSink detaches the output sink, reporting whether one was set.
+func (s *Session) RemoveOutputSink() bool {
+	sink := s.outputSink.Swap(nil)
+	if sink == nil {
+		return false
+	}
+	sink.close()
+	return true
+}
+
+// OutputSink returns the attached output sink, nil when there is none.
+func (s *Session) OutputSink() *OutputSinkInfo {
+	if sink := s.outputSink.Load(); sink != nil {
+		return sink.info()
+	}
+	return nil
+}
+
+// detachFailedOutputSink 写出失败时断开 sink，若已被替换则保留新的 sink
+func (s *Session) detachFailedOutputSink(sink *outputSink, err error) {
+	s.outputSink.CompareAndSwap(sink, nil)
+	s.logger.Warn("terminal output sink detached after write error",
+		zap.String("sessionId", s.id),
+		zap.String("target", sink.target),
+		zap.Error(err))
+}
+
+// SetOutputSink attaches an output sink to a session; see Session.SetOutputSink.
+func (m *Manager) SetOutputSink(id, target string) (*OutputSinkInfo, error) {
+	session, err := m.GetSession(id)
+	if err != nil {
+		return nil, err
+	}
+	return session.SetOutputSink(target)
+}
+
+// RemoveOutputSink detaches the output sink of a session.
+func (m *Manager) RemoveOutputSink(id string) (bool, error) {
+	session, err := m.GetSession(id)
+	if err != nil {
+		return false, err
+	}
+	return session.RemoveOutputSink(), nil
+}
@@ -0,0 +1,94 @@
+package terminal
+
+import (
+	"errors"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSessionOutputSink(t *testing.T) {
+	// unix socket 路径长度有限，不用层级较深的 t.TempDir
+	dir, err := os.MkdirTemp("", "sink")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socketPath := filepath.Join(dir, "out.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			accepted <- conn
+		}
+	}()
+
+	session := newTestSession(t, SessionParams{Logger: zap.NewNop()})
+	info, err := session.SetOutputSink(socketPath)
+	if err != nil {
+		t.Fatalf("SetOutputSink: %v", err)
+	}
+	if info.Target != socketPath {
+		t.Fatalf("unexpected sink info %+v", info)
+	}
+	conn := <-accepted
+
+	session.emitOutput([]byte("\x1b[1mhello\x1b[0m\r\n"))
+	buf := make([]byte, 64)
+	_ = conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+	n, err := io.ReadAtLeast(conn, buf, len("\x1b[1mhello\x1b[0m\r\n"))
+	if err != nil || string(buf[:n]) != "\x1b[1mhello\x1b[0m\r\n" {
+		t.Fatalf("unexpected sink output %q err=%v", buf[:n], err)
+	}
+
+	// 对端断开后写入失败，sink 自动断开且不影响会话
+	_ = conn.Close()
+	deadline := time.Now().Add(3 * time.Second)
+	for session.OutputSink() != nil && time.Now().Before(deadline) {
+		session.emitOutput([]byte("more\r\n"))
+		time.Sleep(10 * time.Millisecond)
+	}
+	if session.OutputSink() != nil {
+		t.Fatalf("expected sink to detach after the reader went away")
+	}
+	if session.RemoveOutputSink() {
+		t.Fatalf("expected no sink left to remove")
+	}
+}
+
+func TestOutputSinkRejectsRegularFiles(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "out.log")
+	if err := os.WriteFile(file, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	session := newTestSession(t, SessionParams{})
+	for _, target := range []string{file, "relative.sock", ""} {
+		if _, err := session.SetOutputSink(target); !errors.Is(err, ErrInvalidOutputSink) {
+			t.Fatalf("%q: expected ErrInvalidOutputSink, got %v", target, err)
+		}
+	}
+}
+
+func TestOutputSinkDropsWhenFull(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer reader.Close()
+	sink := newOutputSink("pipe", writer, nil)
+	defer sink.close()
+	// 读端不读取，写出 goroutine 卡住后队列写满即丢弃
+	for i := 0; i < outputSinkBufferSize+10; i++ {
+		sink.write([]byte("x"))
+	}
+	if sink.info().DroppedChunks == 0 {
+		t.Fatalf("expected chunks to be dropped once the queue is full")
+	}
+}
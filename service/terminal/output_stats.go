@@ -0,0 +1,77 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"time"
+)
+
+// OutputStats summarizes the session output for judging whether it is active.
+type OutputStats struct {
+	// TotalLines 会话启动以来输出的换行数，不受 scrollback 裁剪影响
+	TotalLines int64 `json:"totalLines"`
+	// ScreenLines 按当前尺寸渲染 scrollback 后屏幕上的非空逻辑行数
+	ScreenLines int `json:"screenLines"`
+	ScreenRows  int `json:"screenRows"`
+	ScreenCols  int `json:"screenCols"`
+	// LastOutputAt/SecondsSinceOutput 最后一次收到 PTY 输出的时间，尚无输出时为空
+	LastOutputAt       *time.Time `json:"lastOutputAt,omitempty"`
+	SecondsSinceOutput *int64     `json:"secondsSinceOutput,omitempty"`
+}
+
+// outputStatsCache 缓存屏幕渲染结果，scrollback 与尺寸不变时不再重新渲染
+type outputStatsCache struct {
+	mu          sync.Mutex
+	valid       bool
+	seq         int64
+	rows        int
+	cols        int
+	screenLines int
+}
+
+// OutputStats returns the line counts and output recency of the session. The
+// screen is rendered from the scrollback only when new output arrived or the
+// terminal was resized since the previous call.
+func (s *Session) OutputStats() OutputStats {
+	s.mu.RLock()
+	rows, cols := s.rows, s.cols
+	s.mu.RUnlock()
+
+	stats := OutputStats{
+		TotalLines:  s.stats.outputLines.Load(),
+		ScreenLines: s.screenLineCount(rows, cols),
+		ScreenRows:  rows,
+		ScreenCols:  cols,
+	}
+	if last := unixNanoTime(s.stats.lastOutputAt.Load()); last != nil {
+		since := int64(time.Since(*last) / time.Second)
+		stats.LastOutputAt = last
+		stats.SecondsSinceOutput = &since
+	}
+	return stats
+}
+
+func (s *Session) screenLineCount(rows, cols int) int {
+	cache := &s.outputStatsCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	seq := s.ScrollbackLatestSeq()
+	if cache.valid && cache.seq == seq && cache.rows == rows && cache.cols == cols {
+		return cache.screenLines
+	}
+
+	count := 0
+	if data := bytes.Join(s.Scrollback(), nil); len(data) > 0 && rows > 0 && cols > 0 {
+		for _, line := range s.renderLogicalLines(data, rows, cols) {
+			if strings.TrimSpace(line.Text) != "" {
+				count++
+			}
+		}
+	}
+	cache.valid = true
+	cache.seq, cache.rows, cache.cols = seq, rows, cols
+	cache.screenLines = count
+	return count
+}
@@ -0,0 +1,144 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// pagerCommands 直接运行的分页器（不含扩展名）
+var pagerCommands = map[string]struct{}{
+	"less": {}, "more": {}, "most": {}, "pg": {}, "moar": {}, "ov": {}, "man": {},
+}
+
+// pagingGitCommands git 默认通过分页器输出的子命令
+var pagingGitCommands = map[string]struct{}{
+	"log": {}, "diff": {}, "show": {}, "blame": {}, "reflog": {},
+	"shortlog": {}, "grep": {}, "whatchanged": {}, "help": {},
+}
+
+// altScreenEnter/altScreenLeave 切换备用屏幕的 DEC 私有模式序列
+var (
+	altScreenEnter = [][]byte{[]byte("\x1b[?1049h"), []byte("\x1b[?1047h"), []byte("\x1b[?47h")}
+	altScreenLeave = [][]byte{[]byte("\x1b[?1049l"), []byte("\x1b[?1047l"), []byte("\x1b[?47l")}
+)
+
+// pagerTailBytes 保留的最近输出，用于跨 chunk 匹配切换序列和识别底部提示行
+const pagerTailBytes = 256
+
+// IsPagerCommand reports whether the foreground command line is a pager or a
+// command that pipes its output into one, e.g. "less README.md" or "git log".
+func IsPagerCommand(command string) bool {
+	fields := strings.Fields(command)
+	if len(fields) == 0 {
+		return false
+	}
+	name := commandBaseName(fields[0])
+	if _, ok := pagerCommands[name]; ok {
+		return true
+	}
+	switch name {
+	case "git":
+		return gitSubcommandPages(fields[1:])
+	case "journalctl":
+		for _, field := range fields[1:] {
+			if field == "--no-pager" || field == "-f" || field == "--follow" {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// gitSubcommandPages skips git's global options and checks the subcommand.
+func gitSubcommandPages(args []string) bool {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--no-pager" || arg == "-P":
+			return false
+		case arg == "-C" || arg == "-c":
+			i++
+		case strings.HasPrefix(arg, "-"):
+		default:
+			_, ok := pagingGitCommands[arg]
+			return ok
+		}
+	}
+	return false
+}
+
+// pagerScreen 从输出流中跟踪备用屏幕状态和最近的输出尾部
+type pagerScreen struct {
+	mu        sync.Mutex
+	altScreen bool
+	tail      []byte
+}
+
+func (p *pagerScreen) observe(chunk []byte) {
+	if len(chunk) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	data := append(p.tail, chunk...)
+	enter, leave := lastIndexAny(data, altScreenEnter), lastIndexAny(data, altScreenLeave)
+	// 尾部中已处理过的序列会再次匹配，结果与上次一致，不影响判断
+	if enter > leave {
+		p.altScreen = true
+	} else if leave > enter {
+		p.altScreen = false
+	}
+	if len(data) > pagerTailBytes {
+		data = data[len(data)-pagerTailBytes:]
+	}
+	p.tail = append([]byte(nil), data...)
+}
+
+func (p *pagerScreen) reset() {
+	p.mu.Lock()
+	p.altScreen = false
+	p.tail = nil
+	p.mu.Unlock()
+}
+
+// waiting reports whether the screen looks like a pager waiting for a key: the
+// alternate screen is active, or the bottom line shows a pager prompt. The
+// prompt check covers less -X (git's default LESS=FRX), which keeps the main screen.
+func (p *pagerScreen) waiting() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.altScreen || isPagerPrompt(lastScreenLine(p.tail))
+}
+
+func lastIndexAny(data []byte, seqs [][]byte) int {
+	last := -1
+	for _, seq := range seqs {
+		last = max(last, bytes.LastIndex(data, seq))
+	}
+	return last
+}
+
+// lastScreenLine returns the text of the line the cursor is on, without
+// escape sequences.
+func lastScreenLine(data []byte) string {
+	if i := bytes.LastIndexByte(data, '\n'); i >= 0 {
+		data = data[i+1:]
+	}
+	return types.CleanLine(string(data))
+}
+
+func isPagerPrompt(line string) bool {
+	switch {
+	case line == ":",
+		strings.HasPrefix(line, "(END)"),
+		strings.HasPrefix(line, "--More--"),
+		strings.HasSuffix(line, "(press h for help or q to quit)"):
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package terminal
+
+import "testing"
+
+func TestIsPagerCommand(t *testing.T) {
+	cases := []struct {
+		command string
+		want    bool
+	}{
+		{command: "less README.md", want: true},
+		{command: "/usr/bin/man ls", want: true},
+		{command: "git log --oneline", want: true},
+		{command: "git -C /repo -c color.ui=always diff", want: true},
+		{command: "git --no-pager log", want: false},
+		{command: "git status", want: false},
+		{command: "journalctl -u nginx", want: true},
+		{command: "journalctl -f", want: false},
+		{command: "vim main.go", want: false},
+		{command: "", want: false},
+	}
+	for _, tc := range cases {
+		if got := IsPagerCommand(tc.command); got != tc.want {
+			t.Fatalf("IsPagerCommand(%q) = %v, want %v", tc.command, got, tc.want)
+		}
+	}
+}
+
+func TestPagerScreenWaiting(t *testing.T) {
+	var screen pagerScreen
+	screen.observe([]byte("$ less file\r\n"))
+	if screen.waiting() {
+		t.Fatalf("expected shell prompt not to look like a pager")
+	}
+
+	// 切换序列跨 chunk 也要识别
+	screen.observe([]byte("\x1b[?10"))
+	screen.observe([]byte("49hline 1\r\nline 2\r\n"))
+	if !screen.waiting() {
+		t.Fatalf("expected alternate screen to mark the pager as waiting")
+	}
+	screen.observe([]byte("\x1b[?1049l$ "))
+	if screen.waiting() {
+		t.Fatalf("expected leaving the alternate screen to clear waiting")
+	}
+
+	// less -X 不切换备用屏幕，依靠底部提示行识别
+	screen.observe([]byte("commit abc\r\n    message\r\n\x1b[7m(END)\x1b[27m\x1b[K"))
+	if !screen.waiting() {
+		t.Fatalf("expected (END) prompt to mark the pager as waiting")
+	}
+	screen.observe([]byte("\r\x1b[K$ "))
+	if screen.waiting() {
+		t.Fatalf("expected shell prompt after quitting the pager to clear waiting")
+	}
+
+	screen.observe([]byte("more text\r\n:"))
+	if !screen.waiting() {
+		t.Fatalf("expected ':' prompt to mark the pager as waiting")
+	}
+	screen.reset()
+	if screen.waiting() {
+		t.Fatalf("expected reset to clear waiting")
+	}
+}
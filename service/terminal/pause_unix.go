@@ -0,0 +1,34 @@
+//go:build !windows
+
+package terminal
+
+import (
+	"errors"
+	"slices"
+
+	"golang.org/x/sys/unix"
+
+	"code-kanban/utils/process"
+)
+
+// signalProcessTree 冻结或恢复会话的整棵进程树。暂停时先停 shell 再停子进程，
+// 避免 shell 观察到前台任务被停止而抢回终端；恢复时顺序相反。
+func signalProcessTree(pid int32, pause bool) error {
+	pids := process.GetProcessTree(pid)
+	if len(pids) == 0 {
+		return ErrSessionNotRunning
+	}
+	signal := unix.SIGSTOP
+	if !pause {
+		signal = unix.SIGCONT
+		slices.Reverse(pids)
+	}
+	var errs []error
+	for _, target := range pids {
+		// 遍历期间退出的进程忽略即可
+		if err := unix.Kill(int(target), signal); err != nil && !errors.Is(err, unix.ESRCH) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
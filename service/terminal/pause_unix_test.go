@@ -0,0 +1,68 @@
+//go:build !windows
+
+package terminal
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+func waitProcessStopped(t *testing.T, pid int32, stopped bool) {
+	t.Helper()
+	proc, err := process.NewProcess(pid)
+	if err != nil {
+		t.Fatalf("inspect process %d: %v", pid, err)
+	}
+	deadline := time.Now().Add(3 * time.Second)
+	var status []string
+	for time.Now().Before(deadline) {
+		status, _ = proc.Status()
+		if slices.Contains(status, process.Stop) == stopped {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("expected process %d stopped=%v, got %v", pid, stopped, status)
+}
+
+func TestSessionPauseResume(t *testing.T) {
+	session := newTestSession(t, SessionParams{
+		ID:      "pause-session",
+		Command: []string{"sh", "-c", "sleep 30"},
+	})
+	if err := session.Pause(); !errors.Is(err, ErrSessionNotRunning) {
+		t.Fatalf("expected ErrSessionNotRunning before start, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := session.Start(ctx); err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer session.Close()
+
+	pid := session.getPID()
+	if err := session.Pause(); err != nil {
+		t.Fatalf("Pause failed: %v", err)
+	}
+	if session.Status() != SessionStatusPaused {
+		t.Fatalf("expected paused status, got %s", session.Status())
+	}
+	waitProcessStopped(t, pid, true)
+	if err := session.Pause(); err != nil {
+		t.Fatalf("expected repeated Pause to be a no-op, got %v", err)
+	}
+
+	if err := session.Resume(); err != nil {
+		t.Fatalf("Resume failed: %v", err)
+	}
+	if session.Status() != SessionStatusRunning {
+		t.Fatalf("expected running status after resume, got %s", session.Status())
+	}
+	waitProcessStopped(t, pid, false)
+}
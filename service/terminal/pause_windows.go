@@ -0,0 +1,8 @@
+//go:build windows
+
+package terminal
+
+// signalProcessTree Windows 没有 SIGSTOP/SIGCONT，挂起线程容易让 ConPTY 卡死，暂不支持
+func signalProcessTree(int32, bool) error {
+	return ErrPauseUnsupported
+}
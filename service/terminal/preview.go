@@ -0,0 +1,189 @@
+package terminal
+
+import (
+	"bytes"
+	"hash/fnv"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+const (
+	defaultPreviewLines = 5
+	maxPreviewLines     = 50
+	// previewRenderMaxBytes 预览只重放 scrollback 末尾的这些输出，足以覆盖最后一屏，
+	// 避免每次有新输出后都重放全部历史
+	previewRenderMaxBytes = 128 * 1024
+)
+
+var (
+	autoWrapOffSeq = []byte("\x1b[?7l")
+	autoWrapOnSeq  = []byte("\x1b[?7h")
+)
+
+// autoWrapTracker 跟踪输出中最近一次 DECAWM 开关，只渲染最近一段输出的检测据此补上之前关闭的自动换行
+type autoWrapTracker struct {
+	mu   sync.Mutex
+	off  bool
+	tail []byte
+}
+
+func (t *autoWrapTracker) observe(data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	buf := append(t.tail, data...)
+	off, on := bytes.LastIndex(buf, autoWrapOffSeq), bytes.LastIndex(buf, autoWrapOnSeq)
+	if off != on {
+		t.off = off > on
+	}
+	// 保留不足一个完整序列的末尾字节，拼接跨分片的开关序列
+	keep := min(len(buf), len(autoWrapOffSeq)-1)
+	t.tail = append([]byte(nil), buf[len(buf)-keep:]...)
+}
+
+func (t *autoWrapTracker) autoWrapOff() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.off
+}
+
+// renderLogicalLines renders recent output into logical lines. The buffers
+// handed in hold only the latest output, so when the program turned autowrap
+// off before them the switch is prepended and wrapped rows are kept apart.
+func (s *Session) renderLogicalLines(data []byte, rows, cols int) []ai_assistant2.LogicalLine {
+	if len(data) > 0 && s.autoWrap.autoWrapOff() {
+		data = append(append([]byte(nil), autoWrapOffSeq...), data...)
+	}
+	return ai_assistant2.RenderLogicalLinesFromBuffer(data, rows, cols)
+}
+
+// OutputPreview is the plain text tail of the rendered screen, used for
+// thumbnails that do not subscribe to the output stream.
+type OutputPreview struct {
+	Lines []string `json:"lines"`
+	// ETag 由预览内容计算，内容不变时保持不变
+	ETag string `json:"etag"`
+	// AutoWrap 终端当前是否开启自动换行（DECAWM），关闭时每个屏幕行单独成行
+	AutoWrap bool `json:"autoWrap"`
+}
+
+// previewCache 缓存渲染后的逻辑行，scrollback 与尺寸不变时不再重新渲染
+type previewCache struct {
+	mu       sync.Mutex
+	valid    bool
+	seq      int64
+	rows     int
+	cols     int
+	lines    []string
+	autoWrap bool
+}
+
+// OutputPreview renders the tail of the scrollback at the current size and
+// returns the last n non-trailing-blank lines without control sequences, after
+// the output filters were applied. n is clamped to [1, maxPreviewLines], 0 uses
+// defaultPreviewLines. Rendering only happens when new output arrived or the
+// terminal was resized since the previous call.
+func (s *Session) OutputPreview(n int) OutputPreview {
+	if n <= 0 {
+		n = defaultPreviewLines
+	}
+	n = min(n, maxPreviewLines)
+
+	s.mu.RLock()
+	rows, cols := s.rows, s.cols
+	s.mu.RUnlock()
+
+	lines, autoWrap := s.previewLines(rows, cols)
+	lines = applyOutputFilters(lines, s.outputFilters())
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	lines = append([]string{}, lines...)
+	return OutputPreview{Lines: lines, ETag: previewETag(lines), AutoWrap: autoWrap}
+}
+
+// AutoWrap reports whether the terminal has autowrap (DECAWM) on after the
+// output in the scrollback. It shares the preview's render cache.
+func (s *Session) AutoWrap() bool {
+	s.mu.RLock()
+	rows, cols := s.rows, s.cols
+	s.mu.RUnlock()
+
+	_, autoWrap := s.previewLines(rows, cols)
+	return autoWrap
+}
+
+func (s *Session) previewLines(rows, cols int) ([]string, bool) {
+	cache := &s.previewCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	seq := s.ScrollbackLatestSeq()
+	if cache.valid && cache.seq == seq && cache.rows == rows && cache.cols == cols {
+		return cache.lines, cache.autoWrap
+	}
+
+	var lines []string
+	// 没有输出时终端处于初始状态，自动换行默认开启
+	autoWrap := true
+	if data := s.scrollbackTail(previewRenderMaxBytes); len(data) > 0 && rows > 0 && cols > 0 {
+		var logical []ai_assistant2.LogicalLine
+		logical, autoWrap = ai_assistant2.RenderLogicalLinesWithWrapMode(data, rows, cols)
+		for _, line := range logical {
+			lines = append(lines, line.Text)
+		}
+	}
+	// 光标下方的空行不属于内容
+	for len(lines) > 0 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	cache.valid = true
+	cache.seq, cache.rows, cache.cols = seq, rows, cols
+	cache.lines, cache.autoWrap = lines, autoWrap
+	return lines, autoWrap
+}
+
+// scrollbackTail returns the trailing scrollback chunks holding at least
+// maxBytes of output, or all of it. When older chunks are left out and the last
+// autowrap switch among them turned DECAWM off, that switch is prepended so the
+// rendered mode matches the full output.
+func (s *Session) scrollbackTail(maxBytes int) []byte {
+	s.scrollMu.RLock()
+	defer s.scrollMu.RUnlock()
+
+	start, size := len(s.scrollback), 0
+	for start > 0 && size < maxBytes {
+		start--
+		size += len(s.scrollback[start])
+	}
+	var data []byte
+	for i := start - 1; i >= 0; i-- {
+		chunk := s.scrollback[i]
+		off, on := bytes.LastIndex(chunk, autoWrapOffSeq), bytes.LastIndex(chunk, autoWrapOnSeq)
+		if off < 0 && on < 0 {
+			continue
+		}
+		if off > on {
+			data = append(data, autoWrapOffSeq...)
+		}
+		break
+	}
+	data = slices.Grow(data, size)
+	for _, chunk := range s.scrollback[start:] {
+		data = append(data, chunk...)
+	}
+	return data
+}
+
+// previewETag hashes the preview lines into a strong ETag.
+func previewETag(lines []string) string {
+	hash := fnv.New64a()
+	for _, line := range lines {
+		hash.Write([]byte(line))
+		hash.Write([]byte{'\n'})
+	}
+	return `"` + strconv.FormatUint(hash.Sum64(), 16) + `"`
+}
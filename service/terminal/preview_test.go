@@ -0,0 +1,107 @@
+package terminal
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSessionOutputPreview(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 10, Cols: 20, ScrollbackLimit: 4096})
+	empty := session.OutputPreview(0)
+	if empty.Lines == nil || len(empty.Lines) != 0 {
+		t.Fatalf("expected empty preview, got %#v", empty.Lines)
+	}
+
+	session.appendScrollback([]byte("\x1b[32mone\x1b[0m\r\ntwo\r\n\r\nfour\r\n"))
+	preview := session.OutputPreview(2)
+	if !slices.Equal(preview.Lines, []string{"", "four"}) {
+		t.Fatalf("unexpected preview lines %#v", preview.Lines)
+	}
+	if preview.ETag == "" || preview.ETag == empty.ETag {
+		t.Fatalf("expected etag to follow content, got %q", preview.ETag)
+	}
+	if again := session.OutputPreview(2); again.ETag != preview.ETag {
+		t.Fatalf("expected stable etag, got %q and %q", preview.ETag, again.ETag)
+	}
+
+	// 新输出不改变最后几行时 ETag 不变
+	session.appendScrollback([]byte("\x1b[0m"))
+	if again := session.OutputPreview(2); again.ETag != preview.ETag {
+		t.Fatalf("expected unchanged etag for unchanged lines, got %q", again.ETag)
+	}
+
+	session.appendScrollback([]byte("five"))
+	if changed := session.OutputPreview(2); !slices.Equal(changed.Lines, []string{"four", "five"}) || changed.ETag == preview.ETag {
+		t.Fatalf("unexpected preview after new output %#v", changed)
+	}
+}
+
+func TestSessionPreviewFollowsAutoWrapMode(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 10, Cols: 10, ScrollbackLimit: 4096})
+	if !session.AutoWrap() {
+		t.Fatalf("expected autowrap on for a fresh terminal")
+	}
+
+	session.appendScrollback([]byte("abcdefghijklmno"))
+	preview := session.OutputPreview(5)
+	if !preview.AutoWrap || !slices.Equal(preview.Lines, []string{"abcdefghijklmno"}) {
+		t.Fatalf("expected wrapped rows to be joined, got %#v", preview)
+	}
+
+	// 程序关闭 DECAWM 后按屏幕行输出，之前的折行标记不再合并
+	session.appendScrollback([]byte("\x1b[?7l"))
+	preview = session.OutputPreview(5)
+	if preview.AutoWrap || !slices.Equal(preview.Lines, []string{"abcdefghij", "klmno"}) {
+		t.Fatalf("expected rows kept apart with autowrap off, got %#v", preview)
+	}
+	if session.AutoWrap() {
+		t.Fatalf("expected session to report autowrap off")
+	}
+}
+
+func TestSessionPreviewRendersBoundedTail(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 10, Cols: 10, ScrollbackLimit: 4 * previewRenderMaxBytes})
+	session.appendScrollback([]byte("\x1b[?7l"))
+	filler := []byte(strings.Repeat("x", 1023) + "\n")
+	for range 2 * previewRenderMaxBytes / len(filler) {
+		session.appendScrollback(filler)
+	}
+	session.appendScrollback([]byte("\r\nabcdefghijklmno"))
+
+	tail := session.scrollbackTail(previewRenderMaxBytes)
+	if len(tail) > previewRenderMaxBytes+len(filler)+len(autoWrapOffSeq) {
+		t.Fatalf("expected a bounded tail, got %d bytes", len(tail))
+	}
+	// 被略过的输出中关闭了自动换行，渲染尾部时仍然生效
+	preview := session.OutputPreview(1)
+	if preview.AutoWrap || !slices.Equal(preview.Lines, []string{"abcdefghio"}) {
+		t.Fatalf("expected autowrap to stay off, got %#v", preview)
+	}
+
+	session.appendScrollback([]byte("\x1b[?7h"))
+	if !session.AutoWrap() {
+		t.Fatalf("expected autowrap back on")
+	}
+}
+
+func TestSessionRenderLogicalLinesKeepsAutoWrapOff(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 10, Cols: 10, ScrollbackLimit: 4096})
+	tail := []byte("abcdefghijklmno")
+	if lines := session.renderLogicalLines(tail, 10, 10); len(lines) == 0 || lines[0].Text != "abcdefghijklmno" {
+		t.Fatalf("expected wrapped rows to be joined, got %#v", lines)
+	}
+
+	// 开关序列跨分片到达，之后只渲染不含该序列的最近输出时仍按关闭处理：不折行，行尾字符被覆盖
+	session.emitOutput([]byte("\x1b[?"))
+	session.emitOutput([]byte("7l"))
+	lines := session.renderLogicalLines(tail, 10, 10)
+	if len(lines) == 0 || lines[0].Text != "abcdefghio" {
+		t.Fatalf("expected no wrapping after autowrap was turned off, got %#v", lines)
+	}
+
+	session.emitOutput([]byte("\x1b[?7h"))
+	if lines := session.renderLogicalLines(tail, 10, 10); len(lines) == 0 || lines[0].Text != "abcdefghijklmno" {
+		t.Fatalf("expected rows joined again after autowrap was turned on, got %#v", lines)
+	}
+}
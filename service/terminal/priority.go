@@ -0,0 +1,32 @@
+package terminal
+
+import (
+	"go.uber.org/zap"
+)
+
+// nice 值的合法范围，与 Unix setpriority 一致
+const (
+	minNice = -20
+	maxNice = 19
+)
+
+// validNice reports whether nice is within the Unix nice range.
+func validNice(nice int) bool {
+	return nice >= minNice && nice <= maxNice
+}
+
+// applyPriority lowers or raises the scheduling priority of the freshly started
+// shell. Commands the shell starts later inherit it. Failures, including
+// platforms without support, are logged and the session keeps running at the
+// default priority.
+func (s *Session) applyPriority(pid int) {
+	if s.nice == 0 {
+		return
+	}
+	if err := setProcessNice(pid, s.nice); err != nil {
+		s.logger.Warn("failed to set terminal process priority",
+			zap.String("sessionId", s.id),
+			zap.Int("nice", s.nice),
+			zap.Error(err))
+	}
+}
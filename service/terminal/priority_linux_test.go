@@ -0,0 +1,44 @@
+package terminal
+
+import (
+	"context"
+	"testing"
+
+	"golang.org/x/sys/unix"
+)
+
+func TestSessionNice(t *testing.T) {
+	if invalid := newTestSession(t, SessionParams{Nice: 40}); invalid.nice != 0 {
+		t.Fatalf("expected out-of-range nice to be ignored, got %d", invalid.nice)
+	}
+
+	current, err := unix.Getpriority(unix.PRIO_PROCESS, 0)
+	if err != nil {
+		t.Fatalf("getpriority: %v", err)
+	}
+	// Linux 的 getpriority 系统调用返回 20 - nice
+	base := 20 - current
+	if base >= maxNice {
+		t.Skipf("test process already runs at nice %d", base)
+	}
+
+	session := newTestSession(t, SessionParams{
+		ID:      "nice-session",
+		Command: []string{"sh", "-c", "sleep 30"},
+		Nice:    maxNice,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := session.Start(ctx); err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer session.Close()
+
+	priority, err := unix.Getpriority(unix.PRIO_PROCESS, int(session.getPID()))
+	if err != nil {
+		t.Fatalf("getpriority: %v", err)
+	}
+	if nice := 20 - priority; nice != maxNice {
+		t.Fatalf("expected nice %d, got %d", maxNice, nice)
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package terminal
+
+import "golang.org/x/sys/unix"
+
+// setProcessNice 设置进程的 nice 值，降低优先级无需特权，提高优先级通常需要 root
+func setProcessNice(pid, nice int) error {
+	return unix.Setpriority(unix.PRIO_PROCESS, pid, nice)
+}
@@ -0,0 +1,30 @@
+//go:build windows
+
+package terminal
+
+import "golang.org/x/sys/windows"
+
+// setProcessNice Windows 没有 nice 值，按区间映射到进程优先级类
+func setProcessNice(pid, nice int) error {
+	handle, err := windows.OpenProcess(windows.PROCESS_SET_INFORMATION, false, uint32(pid))
+	if err != nil {
+		return err
+	}
+	defer windows.CloseHandle(handle)
+	return windows.SetPriorityClass(handle, priorityClassForNice(nice))
+}
+
+func priorityClassForNice(nice int) uint32 {
+	switch {
+	case nice <= -15:
+		return windows.HIGH_PRIORITY_CLASS
+	case nice < 0:
+		return windows.ABOVE_NORMAL_PRIORITY_CLASS
+	case nice == 0:
+		return windows.NORMAL_PRIORITY_CLASS
+	case nice < 10:
+		return windows.BELOW_NORMAL_PRIORITY_CLASS
+	default:
+		return windows.IDLE_PRIORITY_CLASS
+	}
+}
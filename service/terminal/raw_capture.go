@@ -0,0 +1,100 @@
+package terminal
+
+import (
+	"sync"
+)
+
+// rawCaptureMaxBytes 原始输出环形缓冲的容量，只用于排查乱码
+const rawCaptureMaxBytes = 64 * 1024
+
+// rawCapture 保留最近的 PTY 原始字节（解码前），默认关闭
+type rawCapture struct {
+	mu      sync.Mutex
+	enabled bool
+	buf     []byte
+	// total 开启以来收到的原始字节数，超过 len(buf) 说明早期内容已被丢弃
+	total int64
+}
+
+// RawOutputSnapshot is the most recent raw PTY output and its normalized form.
+// Raw and Normalized are base64 encoded in JSON.
+type RawOutputSnapshot struct {
+	Enabled  bool   `json:"enabled"`
+	Capacity int    `json:"capacity"`
+	Encoding string `json:"encoding"`
+	// TotalBytes 开启采集以来收到的原始字节数
+	TotalBytes int64 `json:"totalBytes"`
+	// Truncated 返回的原始字节不是开启以来的全部输出
+	Truncated  bool   `json:"truncated"`
+	Raw        []byte `json:"raw"`
+	Normalized []byte `json:"normalized"`
+}
+
+func (c *rawCapture) setEnabled(enabled bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.enabled == enabled {
+		return
+	}
+	c.enabled = enabled
+	c.buf = nil
+	c.total = 0
+}
+
+func (c *rawCapture) write(data []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.enabled || len(data) == 0 {
+		return
+	}
+	c.total += int64(len(data))
+	c.buf = append(c.buf, data...)
+	if overflow := len(c.buf) - rawCaptureMaxBytes; overflow > 0 {
+		c.buf = append([]byte(nil), c.buf[overflow:]...)
+	}
+}
+
+// snapshot returns the last n captured bytes; n <= 0 returns everything kept.
+func (c *rawCapture) snapshot(n int) (bool, []byte, int64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	data := c.buf
+	if n > 0 && n < len(data) {
+		data = data[len(data)-n:]
+	}
+	return c.enabled, cloneBytes(data), c.total
+}
+
+// SetRawCapture toggles keeping the most recent raw PTY bytes. Toggling drops
+// what was captured before.
+func (s *Session) SetRawCapture(enabled bool) {
+	s.rawCapture.setEnabled(enabled)
+}
+
+// RawOutput returns the last n raw PTY bytes (all kept bytes when n <= 0) and
+// the same bytes decoded with the current encoding. The comparison uses a fresh
+// decoder, so a multibyte character cut at the start of the window may decode
+// differently from the live stream.
+func (s *Session) RawOutput(n int) RawOutputSnapshot {
+	enabled, raw, total := s.rawCapture.snapshot(n)
+
+	s.decodeMu.Lock()
+	enc, encName := s.encoding, s.encName
+	s.decodeMu.Unlock()
+
+	normalized := cloneBytes(raw)
+	if enc != nil && encName != "utf-8" && len(raw) > 0 {
+		decoded, tail := decodeChunk(enc.NewDecoder(), nil, raw)
+		normalized = append(decoded, tail...)
+	}
+
+	return RawOutputSnapshot{
+		Enabled:    enabled,
+		Capacity:   rawCaptureMaxBytes,
+		Encoding:   encName,
+		TotalBytes: total,
+		Truncated:  total > int64(len(raw)),
+		Raw:        raw,
+		Normalized: normalized,
+	}
+}
@@ -0,0 +1,47 @@
+package terminal
+
+import (
+	"bytes"
+	"testing"
+
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+)
+
+func TestSessionRawOutputCapture(t *testing.T) {
+	session := newTestSession(t, SessionParams{Encoding: "gbk"})
+	encoded, _, err := transform.Bytes(simplifiedchinese.GBK.NewEncoder(), []byte("中文"))
+	if err != nil {
+		t.Fatalf("encode gbk: %v", err)
+	}
+
+	// 默认关闭，不保留任何字节
+	session.rawCapture.write(encoded)
+	if snapshot := session.RawOutput(0); snapshot.Enabled || len(snapshot.Raw) != 0 {
+		t.Fatalf("expected capture disabled by default, got %+v", snapshot)
+	}
+
+	session.SetRawCapture(true)
+	session.rawCapture.write(encoded)
+	snapshot := session.RawOutput(0)
+	if !snapshot.Enabled || !bytes.Equal(snapshot.Raw, encoded) || string(snapshot.Normalized) != "中文" {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+	if snapshot.Encoding != "gbk" || snapshot.TotalBytes != int64(len(encoded)) || snapshot.Truncated {
+		t.Fatalf("unexpected snapshot metadata %+v", snapshot)
+	}
+
+	if tail := session.RawOutput(2); !bytes.Equal(tail.Raw, encoded[len(encoded)-2:]) || !tail.Truncated {
+		t.Fatalf("unexpected tail snapshot %+v", tail)
+	}
+
+	session.rawCapture.write(bytes.Repeat([]byte{'x'}, rawCaptureMaxBytes))
+	if full := session.RawOutput(0); len(full.Raw) != rawCaptureMaxBytes || !full.Truncated {
+		t.Fatalf("expected ring buffer to keep %d bytes, got %d", rawCaptureMaxBytes, len(full.Raw))
+	}
+
+	session.SetRawCapture(false)
+	if snapshot := session.RawOutput(0); snapshot.Enabled || len(snapshot.Raw) != 0 || snapshot.TotalBytes != 0 {
+		t.Fatalf("expected capture cleared when disabled, got %+v", snapshot)
+	}
+}
@@ -0,0 +1,125 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+// 默认的通知文案，配置的模板非法时回退到这里
+const (
+	defaultCompletionTemplate = "[{{project}}] {{title}} · {{assistant}} 已完成"
+	defaultApprovalTemplate   = "[{{project}}] {{title}} · {{assistant}} 等待审批"
+	// defaultInterruptedTemplate 用户打断的记录使用固定文案，避免与完成通知混淆
+	defaultInterruptedTemplate = "[{{project}}] {{title}} · {{assistant}} 任务被中断"
+)
+
+// recordTemplatePlaceholders 模板中允许出现的占位符
+var recordTemplatePlaceholders = map[string]struct{}{
+	"project":   {},
+	"title":     {},
+	"assistant": {},
+	"input":     {},
+}
+
+// recordTemplate is a parsed notification text template made of literal text
+// and {{name}} placeholders.
+type recordTemplate struct {
+	segments []templateSegment
+}
+
+type templateSegment struct {
+	text        string
+	placeholder bool
+}
+
+// recordTemplateVars are the values substituted into a record template.
+type recordTemplateVars struct {
+	Project   string
+	Title     string
+	Assistant string
+	Input     string
+}
+
+// parseRecordTemplate parses text, rejecting unknown placeholders and unbalanced braces.
+func parseRecordTemplate(text string) (*recordTemplate, error) {
+	tmpl := &recordTemplate{}
+	rest := text
+	for rest != "" {
+		start := strings.Index(rest, "{{")
+		if end := strings.Index(rest, "}}"); end >= 0 && (start < 0 || end < start) {
+			return nil, fmt.Errorf("unexpected }} in template %q", text)
+		}
+		if start < 0 {
+			tmpl.segments = append(tmpl.segments, templateSegment{text: rest})
+			break
+		}
+		if start > 0 {
+			tmpl.segments = append(tmpl.segments, templateSegment{text: rest[:start]})
+		}
+		rest = rest[start+2:]
+		end := strings.Index(rest, "}}")
+		if end < 0 {
+			return nil, fmt.Errorf("unclosed {{ in template %q", text)
+		}
+		name := strings.TrimSpace(rest[:end])
+		if _, ok := recordTemplatePlaceholders[name]; !ok {
+			return nil, fmt.Errorf("unknown placeholder {{%s}}, only project, title, assistant and input are supported", name)
+		}
+		tmpl.segments = append(tmpl.segments, templateSegment{text: name, placeholder: true})
+		rest = rest[end+2:]
+	}
+	return tmpl, nil
+}
+
+// resolveRecordTemplate parses text, falling back to fallback when text is empty or invalid.
+func resolveRecordTemplate(text, fallback string) (*recordTemplate, error) {
+	if strings.TrimSpace(text) != "" {
+		tmpl, err := parseRecordTemplate(text)
+		if err == nil {
+			return tmpl, nil
+		}
+		defaultTmpl, _ := parseRecordTemplate(fallback)
+		return defaultTmpl, err
+	}
+	return parseRecordTemplate(fallback)
+}
+
+func (t *recordTemplate) render(vars recordTemplateVars) string {
+	if t == nil {
+		return ""
+	}
+	var builder strings.Builder
+	for _, segment := range t.segments {
+		if !segment.placeholder {
+			builder.WriteString(segment.text)
+			continue
+		}
+		switch segment.text {
+		case "project":
+			builder.WriteString(vars.Project)
+		case "title":
+			builder.WriteString(vars.Title)
+		case "assistant":
+			builder.WriteString(vars.Assistant)
+		case "input":
+			builder.WriteString(vars.Input)
+		}
+	}
+	return strings.TrimSpace(builder.String())
+}
+
+func newRecordTemplateVars(projectID, projectName, title string, assistant *ai_assistant2.AIAssistantInfo, input string) recordTemplateVars {
+	vars := recordTemplateVars{Project: projectName, Title: title, Input: input}
+	if vars.Project == "" {
+		vars.Project = projectID
+	}
+	if assistant != nil {
+		vars.Assistant = assistant.DisplayName
+		if vars.Assistant == "" {
+			vars.Assistant = assistant.Type
+		}
+	}
+	return vars
+}
@@ -0,0 +1,197 @@
+package terminal
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// RestartPolicy decides whether a session relaunches its shell after the process exits.
+type RestartPolicy string
+
+const (
+	RestartNever RestartPolicy = "never"
+	// RestartOnFailure 仅在进程以非零状态退出时重启
+	RestartOnFailure RestartPolicy = "on-failure"
+	RestartAlways    RestartPolicy = "always"
+)
+
+const (
+	// defaultMaxRestarts 未指定上限时允许的连续重启次数
+	defaultMaxRestarts = 5
+	// restartBackoffBase/restartBackoffMax 重启前的等待时间，每次连续重启翻倍
+	restartBackoffBase = time.Second
+	restartBackoffMax  = 30 * time.Second
+	// restartStableAfter 进程运行超过该时长视为稳定，连续重启计数与退避清零
+	restartStableAfter = time.Minute
+	// restartDrainQuiet/restartDrainTimeout 关闭旧 PTY 前等待剩余输出读完：
+	// 连续静默 restartDrainQuiet 即认为读完，最多等待 restartDrainTimeout
+	restartDrainQuiet   = 100 * time.Millisecond
+	restartDrainTimeout = time.Second
+)
+
+// restartState 只在 launch 与 wait 中访问，二者对同一会话串行执行
+type restartState struct {
+	policy      RestartPolicy
+	maxRestarts int
+	// attempts 连续重启次数，launchedAt 为当前进程的启动时间
+	attempts   int
+	launchedAt time.Time
+	// total 会话生命周期内的重启总数，快照读取
+	total atomic.Int64
+}
+
+// ParseRestartPolicy validates a restart policy; an empty value means RestartNever.
+func ParseRestartPolicy(raw string) (RestartPolicy, error) {
+	switch policy := RestartPolicy(strings.ToLower(strings.TrimSpace(raw))); policy {
+	case "":
+		return RestartNever, nil
+	case RestartNever, RestartOnFailure, RestartAlways:
+		return policy, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidRestartPolicy, raw)
+}
+
+func (p RestartPolicy) allows(exitErr error) bool {
+	switch p {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return exitErr != nil
+	}
+	return false
+}
+
+// restartBackoff returns the delay before the attempt-th consecutive restart.
+func restartBackoff(attempt int) time.Duration {
+	delay := restartBackoffBase
+	for i := 1; i < attempt && delay < restartBackoffMax; i++ {
+		delay *= 2
+	}
+	return min(delay, restartBackoffMax)
+}
+
+// restartAfterExit relaunches the shell when the restart policy allows it and
+// reports whether the session was kept alive. The session ID, scrollback and
+// subscribers are kept; a notice line marks the restart in the output. Exits
+// caused by Close or by the manager shutting down never restart.
+func (s *Session) restartAfterExit(ctx context.Context, exitErr error) bool {
+	state := &s.restart
+	if ctx.Err() != nil || !state.policy.allows(exitErr) {
+		return false
+	}
+	s.drainOutput()
+	if time.Since(state.launchedAt) >= restartStableAfter {
+		state.attempts = 0
+	}
+	if state.attempts >= state.maxRestarts {
+		s.logger.Warn("terminal session restart limit reached",
+			zap.String("sessionId", s.id),
+			zap.Int("maxRestarts", state.maxRestarts),
+			zap.Error(exitErr))
+		s.appendNotice(fmt.Sprintf("[process exited (%s), restart limit of %d reached]", describeExit(exitErr), state.maxRestarts))
+		return false
+	}
+	state.attempts++
+	delay := restartBackoff(state.attempts)
+
+	// wait 已把状态设为 running 或 error；Close 先改状态再取消上下文，这里不能覆盖 closed
+	prev := s.Status()
+	if prev == SessionStatusClosed || !s.status.CompareAndSwap(prev, SessionStatusRestarting) {
+		return false
+	}
+	s.releaseProcess()
+	s.appendNotice(fmt.Sprintf("[process exited (%s), restarting in %s (%d/%d)]", describeExit(exitErr), delay, state.attempts, state.maxRestarts))
+	s.logger.Info("terminal session restarting",
+		zap.String("sessionId", s.id),
+		zap.String("policy", string(state.policy)),
+		zap.Int("attempt", state.attempts),
+		zap.Duration("delay", delay),
+		zap.Error(exitErr))
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-s.closed:
+		return true
+	case <-timer.C:
+	}
+
+	if err := s.launch(SessionStatusRestarting); err != nil {
+		if s.Status() == SessionStatusClosed {
+			return true
+		}
+		s.logger.Warn("failed to restart terminal session",
+			zap.String("sessionId", s.id),
+			zap.Error(err))
+		s.err.Store(sessionError{err: err})
+		return false
+	}
+	s.err.Store(sessionError{})
+	s.exited.Store(false)
+	state.total.Add(1)
+	return true
+}
+
+// drainOutput waits until the output the exited process left in the PTY is read
+// and emits what the coalescer still buffers, so the restart notice or the exit
+// event follows it.
+func (s *Session) drainOutput() {
+	defer func() {
+		if output := s.output.Load(); output != nil {
+			output.flush()
+		}
+	}()
+	// 父进程仍持有 slave 端，进程退出后读取不会返回 EOF，只能按静默时间判断
+	exitedAt := time.Now()
+	for time.Since(exitedAt) < restartDrainTimeout {
+		last := exitedAt
+		if ts := s.stats.lastOutputAt.Load(); ts > last.UnixNano() {
+			last = time.Unix(0, ts)
+		}
+		if time.Since(last) >= restartDrainQuiet {
+			break
+		}
+		time.Sleep(restartDrainQuiet / 4)
+	}
+}
+
+// releaseProcess closes the PTY of the exited process and buffers input until
+// the next launch flushes it.
+func (s *Session) releaseProcess() {
+	s.pager.reset()
+	s.cwd.reset()
+	s.oscTitle.reset()
+	s.pendingMu.Lock()
+	s.inputReady = false
+	s.pendingMu.Unlock()
+
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	if s.pty != nil {
+		_ = s.pty.Close()
+		s.pty = nil
+	}
+	s.mu.Unlock()
+}
+
+// appendNotice writes a dimmed line into the output stream as if the PTY printed it.
+func (s *Session) appendNotice(text string) {
+	data := []byte("\r\n\x1b[2m" + text + "\x1b[0m\r\n")
+	seq := s.appendScrollback(data)
+	s.outputLog.Write(data)
+	s.broadcast(StreamEvent{Type: StreamEventData, Data: data, Seq: seq})
+}
+
+func describeExit(err error) string {
+	if err == nil {
+		return "exit status 0"
+	}
+	return err.Error()
+}
@@ -0,0 +1,95 @@
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRestartPolicy(t *testing.T) {
+	cases := map[string]RestartPolicy{
+		"":           RestartNever,
+		"never":      RestartNever,
+		"On-Failure": RestartOnFailure,
+		" always ":   RestartAlways,
+	}
+	for raw, want := range cases {
+		got, err := ParseRestartPolicy(raw)
+		if err != nil || got != want {
+			t.Fatalf("ParseRestartPolicy(%q) = %q, %v; want %q", raw, got, err, want)
+		}
+	}
+	if _, err := ParseRestartPolicy("sometimes"); !errors.Is(err, ErrInvalidRestartPolicy) {
+		t.Fatalf("expected ErrInvalidRestartPolicy, got %v", err)
+	}
+}
+
+func TestRestartBackoff(t *testing.T) {
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second}
+	for i, expected := range want {
+		if got := restartBackoff(i + 1); got != expected {
+			t.Fatalf("attempt %d: expected %v got %v", i+1, expected, got)
+		}
+	}
+	if got := restartBackoff(20); got != restartBackoffMax {
+		t.Fatalf("expected backoff capped at %v, got %v", restartBackoffMax, got)
+	}
+}
+
+func TestSessionRestartsOnFailureUntilLimit(t *testing.T) {
+	session := newTestSession(t, SessionParams{
+		ID:              "restart-session",
+		Command:         []string{"sh", "-c", "echo started; exit 3"},
+		ScrollbackLimit: 64 * 1024,
+		RestartPolicy:   RestartOnFailure,
+		MaxRestarts:     1,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := session.Start(ctx); err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer session.Close()
+
+	select {
+	case <-session.Closed():
+	case <-time.After(10 * time.Second):
+		t.Fatalf("expected session to close after reaching the restart limit, status %s", session.Status())
+	}
+
+	if got := session.Snapshot().Restarts; got != 1 {
+		t.Fatalf("expected 1 restart, got %d", got)
+	}
+	output := bytes.Join(session.Scrollback(), nil)
+	if n := bytes.Count(output, []byte("started")); n != 2 {
+		t.Fatalf("expected the command to run twice, got %d runs in %q", n, output)
+	}
+	for _, notice := range []string{"restarting in 1s (1/1)", "restart limit of 1 reached"} {
+		if !bytes.Contains(output, []byte(notice)) {
+			t.Fatalf("expected notice %q in scrollback %q", notice, output)
+		}
+	}
+}
+
+func TestSessionCloseDoesNotRestart(t *testing.T) {
+	session := newTestSession(t, SessionParams{
+		Command:       []string{"sh", "-c", "sleep 30"},
+		RestartPolicy: RestartAlways,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := session.Start(ctx); err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	_ = session.Close()
+
+	time.Sleep(200 * time.Millisecond)
+	if status := session.Status(); status == SessionStatusRunning || status == SessionStatusRestarting {
+		t.Fatalf("expected closed session to stay stopped, got %s", status)
+	}
+	if got := session.Snapshot().Restarts; got != 0 {
+		t.Fatalf("expected no restart after Close, got %d", got)
+	}
+}
@@ -0,0 +1,298 @@
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+const (
+	defaultScreenshotInterval = 30 * time.Second
+	defaultScreenshotMaxFiles = 20
+	// defaultScreenshotMaxSessions 保留快照目录的已关闭会话数
+	defaultScreenshotMaxSessions = 20
+	// screenshotBufferMaxBytes 渲染屏幕用的最近输出，与 scrollback 是否开启无关
+	screenshotBufferMaxBytes = 256 * 1024
+	// screenshotTimeLayout 文件名中的时间，按字典序即按时间排序
+	screenshotTimeLayout = "20060102-150405.000"
+)
+
+// ScreenshotConfig controls periodically archiving the rendered screen of each
+// session as text, for tracking down screens that stop refreshing.
+type ScreenshotConfig struct {
+	// Dir 为空时不截图，快照保存在 <Dir>/<sessionId>/<时间>.txt
+	Dir string
+	// Interval 截图间隔，0 使用默认 30s；屏幕没有新输出时跳过
+	Interval time.Duration
+	// MaxFiles 每个会话保留的最近快照数，0 使用默认 20
+	MaxFiles int
+	// MaxSessions 保留快照目录的已关闭会话数，0 使用默认 20；会话关闭时删除更早关闭的会话目录
+	MaxSessions int
+}
+
+// Screenshot is one archived screen frame.
+type Screenshot struct {
+	Name       string    `json:"name"`
+	CapturedAt time.Time `json:"capturedAt"`
+	Text       string    `json:"text"`
+}
+
+// screenshotter keeps recent output and writes the rendered screen at each tick.
+// Write errors are logged once and disable it so the PTY loop is never affected.
+type screenshotter struct {
+	cfg    ScreenshotConfig
+	dir    string
+	logger *zap.Logger
+
+	mu       sync.Mutex
+	buffer   []byte
+	dirty    bool
+	disabled bool
+}
+
+func newScreenshotter(cfg ScreenshotConfig, sessionID string, logger *zap.Logger) (*screenshotter, error) {
+	if cfg.Dir == "" {
+		return nil, nil
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = defaultScreenshotInterval
+	}
+	if cfg.MaxFiles <= 0 {
+		cfg.MaxFiles = defaultScreenshotMaxFiles
+	}
+	dir := filepath.Join(cfg.Dir, sessionID)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &screenshotter{cfg: cfg, dir: dir, logger: logger}, nil
+}
+
+// Write appends one normalized output chunk.
+func (sc *screenshotter) Write(chunk []byte) {
+	if sc == nil || len(chunk) == 0 {
+		return
+	}
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.disabled {
+		return
+	}
+	sc.buffer = append(sc.buffer, chunk...)
+	// 超出两倍上限时才整体前移一次，避免每个分块都复制整个缓冲
+	if len(sc.buffer) > 2*screenshotBufferMaxBytes {
+		n := copy(sc.buffer, sc.buffer[len(sc.buffer)-screenshotBufferMaxBytes:])
+		sc.buffer = sc.buffer[:n]
+	}
+	sc.dirty = true
+}
+
+// capture writes the screen when output arrived since the previous frame and
+// drops frames beyond MaxFiles.
+func (sc *screenshotter) capture(rows, cols int, now time.Time) {
+	sc.mu.Lock()
+	if sc.disabled || !sc.dirty {
+		sc.mu.Unlock()
+		return
+	}
+	data := sc.buffer
+	if len(data) > screenshotBufferMaxBytes {
+		data = data[len(data)-screenshotBufferMaxBytes:]
+	}
+	data = append([]byte(nil), data...)
+	sc.dirty = false
+	sc.mu.Unlock()
+
+	text := renderScreenText(data, rows, cols)
+	name := now.Format(screenshotTimeLayout) + ".txt"
+	if err := os.WriteFile(filepath.Join(sc.dir, name), []byte(text), 0o644); err != nil {
+		sc.fail("write", err)
+		return
+	}
+	if err := sc.prune(); err != nil {
+		sc.fail("prune", err)
+	}
+}
+
+func (sc *screenshotter) prune() error {
+	names, err := screenshotNames(sc.dir)
+	if err != nil {
+		return err
+	}
+	for len(names) > sc.cfg.MaxFiles {
+		if err := os.Remove(filepath.Join(sc.dir, names[0])); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		names = names[1:]
+	}
+	return nil
+}
+
+func (sc *screenshotter) fail(op string, err error) {
+	sc.mu.Lock()
+	sc.disabled = true
+	sc.buffer = nil
+	sc.mu.Unlock()
+	if sc.logger != nil {
+		sc.logger.Warn("terminal screenshots disabled after error",
+			zap.String("dir", sc.dir),
+			zap.String("op", op),
+			zap.Error(err))
+	}
+}
+
+// runScreenshots captures the screen every interval until the session closes.
+func (s *Session) runScreenshots(ctx context.Context) {
+	sc := s.screenshots
+	if sc == nil {
+		return
+	}
+	ticker := time.NewTicker(sc.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.closed:
+			return
+		case now := <-ticker.C:
+			s.mu.RLock()
+			rows, cols := s.rows, s.cols
+			s.mu.RUnlock()
+			sc.capture(rows, cols, now)
+		}
+	}
+}
+
+// renderScreenText renders data on a rows x cols terminal and returns the
+// screen as text with trailing spaces removed from each row.
+func renderScreenText(data []byte, rows, cols int) string {
+	grid := ai_assistant2.RenderGlyphGridFromBuffer(data, rows, cols)
+	var builder strings.Builder
+	for _, row := range grid {
+		var line strings.Builder
+		for _, glyph := range row {
+			if glyph.Char == 0 {
+				line.WriteByte(' ')
+				continue
+			}
+			line.WriteRune(glyph.Char)
+		}
+		builder.WriteString(strings.TrimRight(line.String(), " "))
+		builder.WriteByte('\n')
+	}
+	return builder.String()
+}
+
+// screenshotNames lists the frames in dir, oldest first.
+func screenshotNames(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() && strings.HasSuffix(entry.Name(), ".txt") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// pruneScreenshotDirs removes the frame directories of closed sessions beyond
+// MaxSessions, oldest first by the time of their last frame. Directories of
+// live sessions are kept.
+func (m *Manager) pruneScreenshotDirs() {
+	root := m.cfg.Screenshots.Dir
+	if root == "" {
+		return
+	}
+	limit := m.cfg.Screenshots.MaxSessions
+	if limit <= 0 {
+		limit = defaultScreenshotMaxSessions
+	}
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			m.logger.Debug("list screenshot dirs failed", zap.String("dir", root), zap.Error(err))
+		}
+		return
+	}
+
+	type closedDir struct {
+		name    string
+		modTime time.Time
+	}
+	var closed []closedDir
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		if _, ok := m.sessions.Load(entry.Name()); ok {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		closed = append(closed, closedDir{name: entry.Name(), modTime: info.ModTime()})
+	}
+	if len(closed) <= limit {
+		return
+	}
+	sort.Slice(closed, func(i, j int) bool { return closed[i].modTime.After(closed[j].modTime) })
+	for _, dir := range closed[limit:] {
+		if err := os.RemoveAll(filepath.Join(root, dir.name)); err != nil {
+			m.logger.Debug("remove screenshot dir failed", zap.String("dir", dir.name), zap.Error(err))
+		}
+	}
+}
+
+// ListScreenshots returns the archived screen frames of a session, oldest
+// first. Frames are kept on disk after the session closes, so recently closed
+// sessions can still be inspected.
+func (m *Manager) ListScreenshots(sessionID string) ([]Screenshot, error) {
+	if m.cfg.Screenshots.Dir == "" {
+		return nil, ErrScreenshotsDisabled
+	}
+	if sessionID == "" || filepath.Base(sessionID) != sessionID || strings.HasPrefix(sessionID, ".") {
+		return nil, ErrSessionNotFound
+	}
+	dir := filepath.Join(m.cfg.Screenshots.Dir, sessionID)
+	names, err := screenshotNames(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrSessionNotFound
+		}
+		return nil, err
+	}
+
+	shots := make([]Screenshot, 0, len(names))
+	for _, name := range names {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// 读取期间被轮转删除
+				continue
+			}
+			return nil, err
+		}
+		capturedAt, _ := time.ParseInLocation(screenshotTimeLayout, strings.TrimSuffix(name, ".txt"), time.Local)
+		shots = append(shots, Screenshot{
+			Name:       name,
+			CapturedAt: capturedAt,
+			Text:       string(bytes.TrimRight(data, "\n")),
+		})
+	}
+	return shots, nil
+}
@@ -0,0 +1,110 @@
+package terminal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestScreenshotterKeepsRecentFrames(t *testing.T) {
+	dir := t.TempDir()
+	sc, err := newScreenshotter(ScreenshotConfig{Dir: dir, MaxFiles: 2}, "session-1", nil)
+	if err != nil {
+		t.Fatalf("newScreenshotter failed: %v", err)
+	}
+
+	start := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+	for i, chunk := range []string{"first\r\n", "\x1b[2J\x1b[Hsecond\r\n", "\x1b[2J\x1b[H\x1b[31mthird\x1b[0m  \r\n"} {
+		sc.Write([]byte(chunk))
+		sc.capture(4, 20, start.Add(time.Duration(i)*time.Second))
+	}
+	// 没有新输出时不产生重复帧
+	sc.capture(4, 20, start.Add(10*time.Second))
+
+	mgr := NewManager(Config{Screenshots: ScreenshotConfig{Dir: dir}}, zap.NewNop())
+	shots, err := mgr.ListScreenshots("session-1")
+	if err != nil {
+		t.Fatalf("ListScreenshots failed: %v", err)
+	}
+	if len(shots) != 2 {
+		t.Fatalf("expected the 2 most recent frames, got %+v", shots)
+	}
+	if shots[0].Text != "second" || shots[1].Text != "third" {
+		t.Fatalf("unexpected frames %q, %q", shots[0].Text, shots[1].Text)
+	}
+	if !shots[1].CapturedAt.Equal(start.Add(2 * time.Second)) {
+		t.Fatalf("unexpected capture time %v", shots[1].CapturedAt)
+	}
+
+	if _, err := mgr.ListScreenshots("../session-1"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound for path-like id, got %v", err)
+	}
+	if _, err := NewManager(Config{}, zap.NewNop()).ListScreenshots("session-1"); !errors.Is(err, ErrScreenshotsDisabled) {
+		t.Fatalf("expected ErrScreenshotsDisabled, got %v", err)
+	}
+}
+
+func TestScreenshotterBoundsBuffer(t *testing.T) {
+	sc, err := newScreenshotter(ScreenshotConfig{Dir: t.TempDir()}, "session-1", nil)
+	if err != nil {
+		t.Fatalf("newScreenshotter failed: %v", err)
+	}
+	chunk := []byte(strings.Repeat("x", 4096))
+	for range 3 * screenshotBufferMaxBytes / len(chunk) {
+		sc.Write(chunk)
+		if len(sc.buffer) > 2*screenshotBufferMaxBytes {
+			t.Fatalf("buffer grew to %d bytes", len(sc.buffer))
+		}
+	}
+	sc.Write([]byte("\x1b[2J\x1b[Htail"))
+	sc.capture(2, 20, time.Now())
+	names, err := screenshotNames(sc.dir)
+	if err != nil || len(names) != 1 {
+		t.Fatalf("expected one frame, got %v err=%v", names, err)
+	}
+	data, err := os.ReadFile(filepath.Join(sc.dir, names[0]))
+	if err != nil || !strings.HasPrefix(string(data), "tail") {
+		t.Fatalf("expected the latest output to be rendered, got %q err=%v", data, err)
+	}
+}
+
+func TestManagerPruneScreenshotDirs(t *testing.T) {
+	dir := t.TempDir()
+	mgr := NewManager(Config{Screenshots: ScreenshotConfig{Dir: dir, MaxSessions: 2}}, zap.NewNop())
+	live := newTestSession(t, SessionParams{ID: "live", Rows: 4, Cols: 20, Logger: zap.NewNop()})
+	if err := mgr.addSession(live); err != nil {
+		t.Fatalf("addSession failed: %v", err)
+	}
+
+	// live 最早，其余按 closed-0 到 closed-3 依次更新
+	base := time.Now().Add(-time.Hour)
+	for i, name := range []string{"live", "closed-0", "closed-1", "closed-2", "closed-3"} {
+		path := filepath.Join(dir, name)
+		if err := os.Mkdir(path, 0o755); err != nil {
+			t.Fatalf("mkdir %s: %v", name, err)
+		}
+		modTime := base.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatalf("chtimes %s: %v", name, err)
+		}
+	}
+
+	mgr.pruneScreenshotDirs()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatalf("read dir: %v", err)
+	}
+	var got []string
+	for _, entry := range entries {
+		got = append(got, entry.Name())
+	}
+	if want := []string{"closed-2", "closed-3", "live"}; !slices.Equal(got, want) {
+		t.Fatalf("expected %v to remain, got %v", want, got)
+	}
+}
@@ -0,0 +1,164 @@
+package terminal
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// scrollbackBudgetInterval 检查 scrollback 总预算的间隔
+	scrollbackBudgetInterval = 5 * time.Second
+	// scrollbackBudgetFloor 超出预算时每个会话至少保留的字节数，大致是最后一屏输出
+	scrollbackBudgetFloor = 16 * 1024
+)
+
+// ScrollbackUsage reports the memory held by the scrollback of all sessions
+// against the configured total budget.
+type ScrollbackUsage struct {
+	// TotalBytes 内存中 scrollback 的总字节数，不含已溢写到磁盘的部分
+	TotalBytes int64 `json:"totalBytes"`
+	// BudgetBytes 总预算，0 表示不限制
+	BudgetBytes int64 `json:"budgetBytes"`
+	// TrimmedBytes 因超出预算累计裁剪的字节数
+	TrimmedBytes int64                    `json:"trimmedBytes"`
+	Sessions     []SessionScrollbackUsage `json:"sessions"`
+}
+
+// SessionScrollbackUsage is the scrollback memory of one session.
+type SessionScrollbackUsage struct {
+	SessionID    string    `json:"sessionId"`
+	ProjectID    string    `json:"projectId"`
+	Title        string    `json:"title"`
+	Bytes        int64     `json:"bytes"`
+	LimitBytes   int64     `json:"limitBytes"`
+	TrimmedBytes int64     `json:"trimmedBytes"`
+	LastActive   time.Time `json:"lastActive"`
+}
+
+// trimScrollbackTo drops the oldest chunks until the in-memory scrollback fits
+// in maxBytes, spilling them to disk when spill is enabled. The most recent
+// chunk is always kept. It returns the number of bytes released.
+func (s *Session) trimScrollbackTo(maxBytes int) int {
+	s.scrollMu.Lock()
+	defer s.scrollMu.Unlock()
+	before := s.scrollbackSize
+	for s.scrollbackSize > maxBytes && len(s.scrollback) > 1 {
+		s.scrollbackSpill.append(s.scrollbackBaseSeq, s.scrollback[0], s.scrollbackTimestamps[0])
+		s.scrollbackSize -= len(s.scrollback[0])
+		s.scrollback = s.scrollback[1:]
+		s.scrollbackTimestamps = s.scrollbackTimestamps[1:]
+		s.scrollbackBaseSeq++
+	}
+	released := before - s.scrollbackSize
+	if released > 0 {
+		s.scrollbackRev++
+	}
+	s.scrollbackBudgetTrimmed.Add(int64(released))
+	return released
+}
+
+func (m *Manager) enforceScrollbackBudgetLoop(ctx context.Context) {
+	if m.cfg.ScrollbackTotalBytes <= 0 {
+		return
+	}
+	ticker := time.NewTicker(scrollbackBudgetInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.enforceScrollbackBudget()
+		}
+	}
+}
+
+// enforceScrollbackBudget trims the scrollback of the least recently active
+// sessions first until the total fits the budget. Each session keeps at least
+// scrollbackBudgetFloor bytes, so the budget may stay exceeded when there are
+// many sessions; their own limit is untouched and they grow back once memory
+// is available.
+func (m *Manager) enforceScrollbackBudget() int64 {
+	budget := int64(m.cfg.ScrollbackTotalBytes)
+	if budget <= 0 {
+		return 0
+	}
+	type entry struct {
+		session    *Session
+		bytes      int
+		lastActive time.Time
+	}
+	var total int64
+	entries := make([]entry, 0, m.sessions.Len())
+	m.sessions.Range(func(_ string, session *Session) bool {
+		size := session.ScrollbackBytes()
+		total += int64(size)
+		entries = append(entries, entry{session: session, bytes: size, lastActive: session.LastActive()})
+		return true
+	})
+	if total <= budget {
+		return 0
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].lastActive.Before(entries[j].lastActive)
+	})
+
+	var released int64
+	for _, item := range entries {
+		excess := total - released - budget
+		if excess <= 0 {
+			break
+		}
+		if item.bytes <= scrollbackBudgetFloor {
+			continue
+		}
+		target := max(int64(item.bytes)-excess, scrollbackBudgetFloor)
+		released += int64(item.session.trimScrollbackTo(int(target)))
+	}
+	m.scrollbackBudgetTrimmed.Add(released)
+	if total-released > budget {
+		m.logger.Warn("terminal scrollback still exceeds total budget",
+			zap.Int64("totalBytes", total-released),
+			zap.Int64("budgetBytes", budget),
+			zap.Int("sessions", len(entries)))
+	} else {
+		m.logger.Debug("terminal scrollback trimmed to total budget",
+			zap.Int64("releasedBytes", released),
+			zap.Int64("budgetBytes", budget))
+	}
+	return released
+}
+
+// ScrollbackUsage returns the scrollback memory of every session, largest
+// first, together with the total budget.
+func (m *Manager) ScrollbackUsage() *ScrollbackUsage {
+	usage := &ScrollbackUsage{
+		BudgetBytes:  int64(m.cfg.ScrollbackTotalBytes),
+		TrimmedBytes: m.scrollbackBudgetTrimmed.Load(),
+		Sessions:     make([]SessionScrollbackUsage, 0, m.sessions.Len()),
+	}
+	m.sessions.Range(func(_ string, session *Session) bool {
+		session.scrollMu.RLock()
+		size, limit := session.scrollbackSize, session.scrollbackLimit
+		session.scrollMu.RUnlock()
+		usage.TotalBytes += int64(size)
+		usage.Sessions = append(usage.Sessions, SessionScrollbackUsage{
+			SessionID:    session.ID(),
+			ProjectID:    session.ProjectID(),
+			Title:        session.Title(),
+			Bytes:        int64(size),
+			LimitBytes:   int64(limit),
+			TrimmedBytes: session.scrollbackBudgetTrimmed.Load(),
+			LastActive:   session.LastActive(),
+		})
+		return true
+	})
+	sort.Slice(usage.Sessions, func(i, j int) bool {
+		return usage.Sessions[i].Bytes > usage.Sessions[j].Bytes
+	})
+	return usage
+}
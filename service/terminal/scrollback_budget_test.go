@@ -0,0 +1,55 @@
+package terminal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestManagerEnforceScrollbackBudget(t *testing.T) {
+	mgr := NewManager(Config{ScrollbackTotalBytes: 112 * 1024}, zap.NewNop())
+
+	chunk := bytes.Repeat([]byte("x"), 8*1024)
+	now := time.Now()
+	sessions := make([]*Session, 0, 3)
+	for i := 0; i < 3; i++ {
+		session := newTestSession(t, SessionParams{ProjectID: "p1", ScrollbackLimit: 1 << 20, Logger: zap.NewNop()})
+		for j := 0; j < 8; j++ {
+			session.appendScrollback(chunk)
+		}
+		// 第 0 个会话最久未活动
+		session.lastActive.Store(now.Add(time.Duration(i) * time.Minute).UnixNano())
+		if err := mgr.addSession(session); err != nil {
+			t.Fatalf("addSession failed: %v", err)
+		}
+		sessions = append(sessions, session)
+	}
+
+	released := mgr.enforceScrollbackBudget()
+	if released != 80*1024 {
+		t.Fatalf("expected 80KB released, got %d", released)
+	}
+	if got := sessions[0].ScrollbackBytes(); got != scrollbackBudgetFloor {
+		t.Fatalf("expected least recently active session trimmed to floor, got %d", got)
+	}
+	if got := sessions[1].ScrollbackBytes(); got != 32*1024 {
+		t.Fatalf("expected second session trimmed to 32KB, got %d", got)
+	}
+	if got := sessions[2].ScrollbackBytes(); got != 64*1024 {
+		t.Fatalf("expected most recent session untouched, got %d", got)
+	}
+
+	usage := mgr.ScrollbackUsage()
+	if usage.TotalBytes != 112*1024 || usage.BudgetBytes != 112*1024 || usage.TrimmedBytes != 80*1024 {
+		t.Fatalf("unexpected usage: %+v", usage)
+	}
+	if len(usage.Sessions) != 3 || usage.Sessions[0].SessionID != sessions[2].ID() {
+		t.Fatalf("expected sessions sorted by size, got %+v", usage.Sessions)
+	}
+
+	if released := mgr.enforceScrollbackBudget(); released != 0 {
+		t.Fatalf("expected nothing released within budget, got %d", released)
+	}
+}
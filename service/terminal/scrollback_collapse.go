@@ -0,0 +1,83 @@
+package terminal
+
+import (
+	"bytes"
+	"hash/fnv"
+	"unicode/utf8"
+)
+
+// redrawChunkMaxBytes 超过该大小的分片不视为原地重绘，避免误折叠大段输出
+const redrawChunkMaxBytes = 4 * 1024
+
+// eraseLineSequence 是 Ink 等 TUI 重绘多行区域前清除整行的序列
+var eraseLineSequence = []byte("\x1b[2K")
+
+// redrawFingerprint reports whether chunk looks like an in-place redraw, such as a
+// spinner frame, and fingerprints it with digits and spinner glyphs removed from
+// the printable text. Escape sequences are kept whole, so frames that move the
+// cursor by a different number of lines never match.
+// A redraw starts by moving the cursor (a lone \r or an escape sequence) and
+// either stays on one line or erases the lines it rewrites. Ordinary output,
+// including repeated lines and prompts printed after \r\n, never qualifies.
+func redrawFingerprint(chunk []byte) (uint64, bool) {
+	if len(chunk) == 0 || len(chunk) > redrawChunkMaxBytes {
+		return 0, false
+	}
+	switch {
+	case chunk[0] == '\x1b':
+	case chunk[0] == '\r' && (len(chunk) == 1 || chunk[1] != '\n'):
+	default:
+		return 0, false
+	}
+	if bytes.IndexByte(chunk, '\n') >= 0 && !bytes.Contains(chunk, eraseLineSequence) {
+		return 0, false
+	}
+
+	hash := fnv.New64a()
+	for i := 0; i < len(chunk); {
+		if chunk[i] == 0x1b {
+			end, _ := appendOnlyEscape(chunk, i)
+			hash.Write(chunk[i : end+1])
+			i = end + 1
+			continue
+		}
+		r, size := utf8.DecodeRune(chunk[i:])
+		if !isSpinnerRune(r) {
+			hash.Write(chunk[i : i+size])
+		}
+		i += size
+	}
+	return hash.Sum64(), true
+}
+
+// isSpinnerRune reports runes that change between otherwise identical frames:
+// elapsed time digits, braille spinners and the glyphs AI CLIs animate.
+func isSpinnerRune(r rune) bool {
+	switch {
+	case r >= '0' && r <= '9':
+		return true
+	case r >= 0x2800 && r <= 0x28FF:
+		return true
+	case r >= 0x2700 && r <= 0x27BF: // Dingbats，如 ✢✳✶✻✽
+		return true
+	}
+	switch r {
+	case '·', '*', '|', '/', '-', '\\', '◐', '◓', '◑', '◒':
+		return true
+	}
+	return false
+}
+
+// collapseRedrawLocked empties the newest chunk when chunk redraws the same
+// content in place, so spinner frames do not push real output out of the
+// scrollback. The emptied slot keeps its sequence number and timestamp, which
+// keeps sequence numbers contiguous for replay and paging.
+func (s *Session) collapseRedrawLocked(chunk []byte) {
+	fingerprint, ok := redrawFingerprint(chunk)
+	if ok && s.redrawSet && fingerprint == s.redrawFingerprint && len(s.scrollback) > 0 {
+		last := len(s.scrollback) - 1
+		s.scrollbackSize -= len(s.scrollback[last])
+		s.scrollback[last] = nil
+	}
+	s.redrawFingerprint, s.redrawSet = fingerprint, ok
+}
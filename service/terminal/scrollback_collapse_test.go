@@ -0,0 +1,73 @@
+package terminal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRedrawFingerprint(t *testing.T) {
+	a, ok := redrawFingerprint([]byte("\r⠋ Thinking 3s"))
+	if !ok {
+		t.Fatalf("expected spinner frame to be a redraw")
+	}
+	if b, _ := redrawFingerprint([]byte("\r⠙ Thinking 4s")); a != b {
+		t.Fatalf("expected frames differing only in spinner and digits to match")
+	}
+	if c, _ := redrawFingerprint([]byte("\r⠙ Compiling 4s")); a == c {
+		t.Fatalf("expected different text to differ")
+	}
+
+	for _, chunk := range []string{"ok\r\n", "\r\n$ ", "\x1b[1mbold\x1b[0m\nnext line", ""} {
+		if _, ok := redrawFingerprint([]byte(chunk)); ok {
+			t.Fatalf("expected %q not to be a redraw", chunk)
+		}
+	}
+	if _, ok := redrawFingerprint([]byte("\x1b[2K\x1b[1A\x1b[2K\r✻ Working (12s)\nesc to interrupt")); !ok {
+		t.Fatalf("expected multi-line redraw with erase sequences to qualify")
+	}
+
+	// 数字只在可见文本中忽略，光标移动的行数不同不是同一帧
+	up1, _ := redrawFingerprint([]byte("\x1b[1A\x1b[2K\r✻ Working (12s)\nesc to interrupt"))
+	up2, _ := redrawFingerprint([]byte("\x1b[2A\x1b[2K\r✻ Working (13s)\nesc to interrupt"))
+	if up1 == up2 {
+		t.Fatalf("expected different CSI parameters to differ")
+	}
+	if again, _ := redrawFingerprint([]byte("\x1b[1A\x1b[2K\r✶ Working (15s)\nesc to interrupt")); again != up1 {
+		t.Fatalf("expected frames with the same cursor moves to match")
+	}
+	red, _ := redrawFingerprint([]byte("\x1b[31m\r⠋ Thinking 3s"))
+	green, _ := redrawFingerprint([]byte("\x1b[32m\r⠋ Thinking 3s"))
+	if red == green {
+		t.Fatalf("expected different colors to differ")
+	}
+}
+
+func TestSessionScrollbackCollapse(t *testing.T) {
+	session := newTestSession(t, SessionParams{ScrollbackLimit: 4096, ScrollbackCollapse: true})
+	session.appendScrollback([]byte("build\r\n"))
+	session.appendScrollback([]byte("\r⠋ Loading 1s"))
+	session.appendScrollback([]byte("\r⠙ Loading 2s"))
+	last := session.appendScrollback([]byte("\r⠹ Loading 3s"))
+	session.appendScrollback([]byte("\r\ndone\r\n"))
+	session.appendScrollback([]byte("done\r\n"))
+
+	// 只折叠重绘帧，序号保持连续，普通的重复行保留
+	if last != 4 {
+		t.Fatalf("expected contiguous seq 4, got %d", last)
+	}
+	data := bytes.Join(session.Scrollback(), nil)
+	if want := "build\r\n\r⠹ Loading 3s\r\ndone\r\ndone\r\n"; string(data) != want {
+		t.Fatalf("unexpected scrollback %q", data)
+	}
+	chunks, latest := session.ScrollbackSince(0)
+	if latest != 6 || len(chunks) != 6 || len(chunks[1].Data) != 0 || len(chunks[2].Data) != 0 {
+		t.Fatalf("unexpected chunks %+v latest=%d", chunks, latest)
+	}
+
+	plain := newTestSession(t, SessionParams{ScrollbackLimit: 4096})
+	plain.appendScrollback([]byte("\r⠋ Loading 1s"))
+	plain.appendScrollback([]byte("\r⠙ Loading 2s"))
+	if data := bytes.Join(plain.Scrollback(), nil); string(data) != "\r⠋ Loading 1s\r⠙ Loading 2s" {
+		t.Fatalf("expected no collapse by default, got %q", data)
+	}
+}
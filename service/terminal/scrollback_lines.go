@@ -0,0 +1,163 @@
+package terminal
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultScrollbackLinesLimit = 200
+	maxScrollbackLinesLimit     = 2000
+)
+
+// ScrollbackLinesPage is a range of the scrollback split into plain text lines.
+type ScrollbackLinesPage struct {
+	Lines  []string `json:"lines"`
+	Offset int      `json:"offset"`
+	// Total 当前保留的逻辑行总数，前端据此计算页数与滚动条
+	Total int `json:"total"`
+	// LatestSeq 取行时的最新分片序号，变化说明有新输出
+	LatestSeq int64 `json:"latestSeq"`
+}
+
+// scrollbackLine 一个逻辑行及其起始分片序号，分片被裁剪后据此丢弃对应的行
+type scrollbackLine struct {
+	seq  int64
+	text string
+}
+
+// scrollbackLinesCache 增量维护 scrollback 的逻辑行，新输出只处理新增的分片
+type scrollbackLinesCache struct {
+	mu      sync.Mutex
+	valid   bool
+	rewrite uint64
+	// seq 已处理到的分片序号
+	seq   int64
+	lines []scrollbackLine
+	// partial 末尾尚未换行的部分，partialSeq 为其起始分片序号
+	partial    []byte
+	partialSeq int64
+	// open 跨分片未结束的 OSC 序列
+	open []byte
+}
+
+// ScrollbackLines returns limit logical lines of the in-memory scrollback
+// starting at offset. limit is clamped to [1, maxScrollbackLinesLimit], 0 uses
+// defaultScrollbackLinesLimit. Lines are split at newlines with escape
+// sequences stripped, as for scrollback search, instead of rendering the whole
+// history on a virtual terminal; cursor positioning counts as a line break and
+// carriage returns overwrite the start of the line. The split is kept between
+// calls and only new output is processed, so paging through history is cheap.
+func (s *Session) ScrollbackLines(offset, limit int) ScrollbackLinesPage {
+	if limit <= 0 {
+		limit = defaultScrollbackLinesLimit
+	}
+	limit = min(limit, maxScrollbackLinesLimit)
+	offset = max(offset, 0)
+
+	lines, seq := s.scrollbackTextLines()
+	page := ScrollbackLinesPage{Lines: []string{}, Offset: offset, Total: len(lines), LatestSeq: seq}
+	if offset < len(lines) {
+		page.Lines = append(page.Lines, lines[offset:min(offset+limit, len(lines))]...)
+	}
+	return page
+}
+
+// scrollbackTextLines 返回内存中 scrollback 的全部逻辑行及最新分片序号，末尾未换行的部分单独成行
+func (s *Session) scrollbackTextLines() ([]string, int64) {
+	cache := &s.scrollbackLinesCache
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	s.scrollMu.RLock()
+	base := s.scrollbackBaseSeq
+	latest := base + int64(len(s.scrollback)) - 1
+	// 编码切换会改写已有分片，被裁剪掉的分片尚未处理时也无法续接，这两种情况以及历史被清空时从头重建
+	if !cache.valid || cache.rewrite != s.scrollbackRewrites || cache.seq+1 < base || len(s.scrollback) == 0 {
+		cache.reset()
+		cache.rewrite = s.scrollbackRewrites
+		cache.valid = true
+	}
+	start := int(max(cache.seq-base+1, 0))
+	chunks := s.scrollbackChunksLocked(min(start, len(s.scrollback)), len(s.scrollback))
+	s.scrollMu.RUnlock()
+
+	for _, chunk := range chunks {
+		cache.appendChunk(chunk)
+	}
+	cache.dropBefore(base)
+
+	lines := make([]string, 0, len(cache.lines)+1)
+	for _, line := range cache.lines {
+		lines = append(lines, line.text)
+	}
+	if tail := overwriteCarriageReturns(string(cache.partial)); strings.TrimSpace(tail) != "" {
+		lines = append(lines, tail)
+	}
+	return lines, latest
+}
+
+func (c *scrollbackLinesCache) reset() {
+	c.seq = 0
+	c.lines = nil
+	c.partial = nil
+	c.partialSeq = 0
+	c.open = nil
+}
+
+func (c *scrollbackLinesCache) appendChunk(chunk ScrollbackChunk) {
+	c.seq = chunk.Seq
+	var raw []byte
+	raw, c.open = splitUnterminatedOSC(append(c.open, chunk.Data...))
+	c.open = append([]byte(nil), c.open...)
+	raw = outputLineBreakPattern.ReplaceAll(raw, []byte{'\n'})
+
+	if len(c.partial) == 0 {
+		c.partialSeq = chunk.Seq
+	}
+	data := append(c.partial, plainTextOutput(raw)...)
+	for {
+		idx := bytes.IndexByte(data, '\n')
+		if idx < 0 {
+			break
+		}
+		c.lines = append(c.lines, scrollbackLine{seq: c.partialSeq, text: overwriteCarriageReturns(string(data[:idx]))})
+		c.partialSeq = chunk.Seq
+		data = data[idx+1:]
+	}
+	if len(data) > scrollbackSearchMaxLineBytes {
+		data = data[len(data)-scrollbackSearchMaxLineBytes:]
+	}
+	c.partial = append([]byte(nil), data...)
+}
+
+// dropBefore 丢弃起始分片已被裁剪的行
+func (c *scrollbackLinesCache) dropBefore(base int64) {
+	n := 0
+	for n < len(c.lines) && c.lines[n].seq < base {
+		n++
+	}
+	if n > 0 {
+		c.lines = append([]scrollbackLine(nil), c.lines[n:]...)
+	}
+}
+
+// overwriteCarriageReturns 按回车覆盖的效果合并一行，如进度条 "50%\r100%" 只保留 "100%"
+func overwriteCarriageReturns(line string) string {
+	line = strings.TrimRight(line, "\r")
+	if !strings.Contains(line, "\r") {
+		return line
+	}
+	var out []rune
+	for _, segment := range strings.Split(line, "\r") {
+		for i, r := range []rune(segment) {
+			if i < len(out) {
+				out[i] = r
+			} else {
+				out = append(out, r)
+			}
+		}
+	}
+	return string(out)
+}
@@ -0,0 +1,89 @@
+package terminal
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestSessionScrollbackLines(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 5, Cols: 10, ScrollbackLimit: 64 * 1024})
+	if empty := session.ScrollbackLines(0, 10); empty.Total != 0 || len(empty.Lines) != 0 {
+		t.Fatalf("expected empty page, got %+v", empty)
+	}
+
+	// 远超屏幕高度的历史也能按行取回，超过宽度的长行仍算作一个逻辑行
+	for i := 0; i < 30; i++ {
+		session.appendScrollback([]byte(fmt.Sprintf("line %d\r\n", i)))
+	}
+	session.appendScrollback([]byte("abcdefghijklmno\r\n"))
+
+	page := session.ScrollbackLines(0, 3)
+	if page.Total != 31 || !slices.Equal(page.Lines, []string{"line 0", "line 1", "line 2"}) {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	last := session.ScrollbackLines(29, 10)
+	if last.Offset != 29 || !slices.Equal(last.Lines, []string{"line 29", "abcdefghijklmno"}) {
+		t.Fatalf("unexpected last page %+v", last)
+	}
+	if beyond := session.ScrollbackLines(100, 10); beyond.Total != 31 || len(beyond.Lines) != 0 {
+		t.Fatalf("expected empty page past the end, got %+v", beyond)
+	}
+
+	session.appendScrollback([]byte("new\r\n"))
+	if updated := session.ScrollbackLines(31, 10); updated.Total != 32 || !slices.Equal(updated.Lines, []string{"new"}) || updated.LatestSeq == page.LatestSeq {
+		t.Fatalf("expected cache to refresh after new output, got %+v", updated)
+	}
+}
+
+func TestSessionScrollbackLinesControls(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 5, Cols: 10, ScrollbackLimit: 64 * 1024})
+	// 颜色被剥离，回车覆盖行首，光标定位视为换行，未结束的末行也能取回
+	session.appendScrollback([]byte("\x1b[31mred\x1b[0m\r\n"))
+	session.appendScrollback([]byte("50%\r100%\r\n"))
+	session.appendScrollback([]byte("top\x1b[3;1Hbottom\r\n"))
+	session.appendScrollback([]byte("\x1b]0;title\x07$ "))
+	page := session.ScrollbackLines(0, 10)
+	want := []string{"red", "100%", "top", "bottom", "$ "}
+	if !slices.Equal(page.Lines, want) {
+		t.Fatalf("expected %q, got %q", want, page.Lines)
+	}
+
+	// 跨分片的行在补全后只算一行
+	session.appendScrollback([]byte("ls\r\n"))
+	if page := session.ScrollbackLines(4, 10); page.Total != 5 || !slices.Equal(page.Lines, []string{"$ ls"}) {
+		t.Fatalf("expected the prompt line to be completed, got %+v", page)
+	}
+}
+
+func TestSessionScrollbackLinesTrimmed(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 5, Cols: 10, ScrollbackLimit: 32})
+	for i := 0; i < 3; i++ {
+		session.appendScrollback([]byte(fmt.Sprintf("line %d\r\n", i)))
+	}
+	if page := session.ScrollbackLines(0, 10); page.Total != 3 {
+		t.Fatalf("expected 3 lines, got %+v", page)
+	}
+
+	// 超出上限后被裁剪分片中的行随之丢弃
+	for i := 3; i < 6; i++ {
+		session.appendScrollback([]byte(fmt.Sprintf("line %d\r\n", i)))
+	}
+	page := session.ScrollbackLines(0, 10)
+	var want []string
+	for _, chunk := range session.Scrollback() {
+		want = append(want, strings.TrimSuffix(string(chunk), "\r\n"))
+	}
+	if len(want) >= 6 {
+		t.Fatalf("expected the scrollback limit to trim chunks, got %d", len(want))
+	}
+	if !slices.Equal(page.Lines, want) {
+		t.Fatalf("expected %q after trimming, got %q", want, page.Lines)
+	}
+
+	session.UpdateScrollbackLimit(0)
+	if page := session.ScrollbackLines(0, 10); page.Total != 0 {
+		t.Fatalf("expected no lines once scrollback is disabled, got %+v", page)
+	}
+}
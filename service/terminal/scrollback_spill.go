@@ -0,0 +1,434 @@
+package terminal
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultScrollbackSpillMaxBytes = 64 * 1024 * 1024
+	// scrollbackSpillPendingMaxBytes 等待写入磁盘的分片上限，磁盘跟不上时丢弃最早的分片
+	scrollbackSpillPendingMaxBytes = 8 * 1024 * 1024
+	// defaultScrollbackSearchLimit 搜索默认返回的最近匹配行数
+	defaultScrollbackSearchLimit = 200
+	// scrollbackSearchMaxLineBytes 搜索时单行保留的长度上限，防止没有换行的输出无限累积
+	scrollbackSearchMaxLineBytes = 64 * 1024
+)
+
+// ScrollbackSpillConfig keeps chunks trimmed from the in-memory scrollback in a
+// per-session temporary file, so paging, search and export still see the early
+// output of long running sessions.
+type ScrollbackSpillConfig struct {
+	Enabled bool
+	// Dir 临时文件目录，为空使用系统临时目录
+	Dir string
+	// MaxBytes 每个会话的磁盘上限，0 使用默认 64MB；超出后丢弃最早的一半
+	MaxBytes int64
+}
+
+type spillEntry struct {
+	seq    int64
+	offset int64
+	size   int
+	at     time.Time
+}
+
+type spillSegment struct {
+	file    *os.File
+	entries []spillEntry
+	size    int64
+}
+
+// pendingSpill is an evicted chunk waiting for the background writer.
+type pendingSpill struct {
+	seq  int64
+	data []byte
+	at   time.Time
+}
+
+// scrollbackSpill appends evicted chunks to at most two temporary segment files
+// of MaxBytes/2 each; when both are full the older one is deleted. Chunks are
+// queued by the output path and written by a background goroutine, so disk I/O
+// never runs under the scrollback lock; queued chunks are served from memory
+// until written. The file is created on the first spilled chunk and removed
+// when the session closes. I/O errors disable spilling, they never affect the
+// session.
+type scrollbackSpill struct {
+	mu        sync.Mutex
+	cfg       ScrollbackSpillConfig
+	sessionID string
+	logger    *zap.Logger
+	// segments 旧段在前，最多两段
+	segments []*spillSegment
+	// pending 等待后台写入的分片，序号都大于已写入的分片
+	pending      []pendingSpill
+	pendingBytes int
+	// wake 通知后台写入协程，第一次有分片时才启动协程
+	wake     chan struct{}
+	disabled bool
+}
+
+func newScrollbackSpill(cfg ScrollbackSpillConfig, sessionID string, logger *zap.Logger) *scrollbackSpill {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.MaxBytes <= 0 {
+		cfg.MaxBytes = defaultScrollbackSpillMaxBytes
+	}
+	return &scrollbackSpill{cfg: cfg, sessionID: sessionID, logger: logger}
+}
+
+// append queues one evicted chunk for the background writer. Chunks arrive in
+// sequence order. When the writer falls behind by more than MaxBytes, which
+// would be rotated out anyway, or scrollbackSpillPendingMaxBytes, the oldest
+// queued chunks are dropped.
+func (p *scrollbackSpill) append(seq int64, data []byte, at time.Time) {
+	if p == nil || len(data) == 0 {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.disabled {
+		return
+	}
+
+	p.pending = append(p.pending, pendingSpill{seq: seq, data: data, at: at})
+	p.pendingBytes += len(data)
+	limit := min(p.cfg.MaxBytes, scrollbackSpillPendingMaxBytes)
+	for len(p.pending) > 1 && int64(p.pendingBytes) > limit {
+		p.pendingBytes -= len(p.pending[0].data)
+		p.pending = p.pending[1:]
+	}
+	if p.wake == nil {
+		p.wake = make(chan struct{}, 1)
+		go p.run(p.wake)
+	}
+	select {
+	case p.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run writes queued chunks until the spill is closed or disabled.
+func (p *scrollbackSpill) run(wake <-chan struct{}) {
+	for range wake {
+		for p.writeNext() {
+		}
+	}
+}
+
+// writeNext writes the oldest queued chunk and reports whether more work may be
+// left. Only this goroutine adds segments or entries, so the file write itself
+// happens without holding mu.
+func (p *scrollbackSpill) writeNext() bool {
+	p.mu.Lock()
+	if p.disabled || len(p.pending) == 0 {
+		p.mu.Unlock()
+		return false
+	}
+	chunk := p.pending[0]
+	current := p.currentLocked()
+	if current == nil || (current.size > 0 && current.size+int64(len(chunk.data)) > p.cfg.MaxBytes/2) {
+		segment, err := p.openSegmentLocked()
+		if err != nil {
+			p.failLocked("create", err)
+			p.mu.Unlock()
+			return false
+		}
+		current = segment
+	}
+	offset := current.size
+	p.mu.Unlock()
+
+	_, err := current.file.WriteAt(chunk.data, offset)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.disabled {
+		return false
+	}
+	if err != nil {
+		p.failLocked("write", err)
+		return false
+	}
+	current.entries = append(current.entries, spillEntry{seq: chunk.seq, offset: offset, size: len(chunk.data), at: chunk.at})
+	current.size += int64(len(chunk.data))
+	// 写入期间排队的分片可能因积压被丢弃，只有仍在队首时才出队
+	if len(p.pending) > 0 && p.pending[0].seq == chunk.seq {
+		p.pendingBytes -= len(chunk.data)
+		p.pending = p.pending[1:]
+	}
+	return true
+}
+
+func (p *scrollbackSpill) currentLocked() *spillSegment {
+	if len(p.segments) == 0 {
+		return nil
+	}
+	return p.segments[len(p.segments)-1]
+}
+
+func (p *scrollbackSpill) openSegmentLocked() (*spillSegment, error) {
+	dir := p.cfg.Dir
+	if dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, err
+		}
+	}
+	file, err := os.CreateTemp(dir, "code-kanban-scrollback-"+p.sessionID+"-*")
+	if err != nil {
+		return nil, err
+	}
+	if len(p.segments) == 2 {
+		removeSpillSegment(p.segments[0])
+		p.segments = p.segments[1:]
+	}
+	segment := &spillSegment{file: file}
+	p.segments = append(p.segments, segment)
+	return segment, nil
+}
+
+func (p *scrollbackSpill) failLocked(op string, err error) {
+	p.stopLocked()
+	if p.logger != nil {
+		p.logger.Warn("terminal scrollback spill disabled after error",
+			zap.String("sessionId", p.sessionID),
+			zap.String("op", op),
+			zap.Error(err))
+	}
+}
+
+// stopLocked disables the spill, deletes the files and ends the writer.
+func (p *scrollbackSpill) stopLocked() {
+	if p.disabled {
+		return
+	}
+	p.disabled = true
+	for _, segment := range p.segments {
+		removeSpillSegment(segment)
+	}
+	p.segments = nil
+	p.pending = nil
+	p.pendingBytes = 0
+	if p.wake != nil {
+		close(p.wake)
+	}
+}
+
+func removeSpillSegment(segment *spillSegment) {
+	name := segment.file.Name()
+	_ = segment.file.Close()
+	_ = os.Remove(name)
+}
+
+// chunkAfter returns the oldest spilled chunk with a sequence number greater
+// than seq and lower than before.
+func (p *scrollbackSpill) chunkAfter(seq, before int64) (ScrollbackChunk, bool) {
+	if p == nil {
+		return ScrollbackChunk{}, false
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, segment := range p.segments {
+		i := sort.Search(len(segment.entries), func(i int) bool { return segment.entries[i].seq > seq })
+		if i == len(segment.entries) {
+			continue
+		}
+		if segment.entries[i].seq >= before {
+			return ScrollbackChunk{}, false
+		}
+		return p.readLocked(segment, segment.entries[i])
+	}
+	for _, chunk := range p.pending {
+		if chunk.seq <= seq {
+			continue
+		}
+		if chunk.seq >= before {
+			break
+		}
+		return chunk.scrollbackChunk(), true
+	}
+	return ScrollbackChunk{}, false
+}
+
+// chunksBefore returns up to limit of the newest spilled chunks with a sequence
+// number lower than before, oldest first.
+func (p *scrollbackSpill) chunksBefore(before int64, limit int) []ScrollbackChunk {
+	if p == nil || limit <= 0 {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var result []ScrollbackChunk
+	for i := len(p.pending) - 1; i >= 0 && len(result) < limit; i-- {
+		if p.pending[i].seq < before {
+			result = append(result, p.pending[i].scrollbackChunk())
+		}
+	}
+	for s := len(p.segments) - 1; s >= 0 && len(result) < limit; s-- {
+		segment := p.segments[s]
+		end := sort.Search(len(segment.entries), func(i int) bool { return segment.entries[i].seq >= before })
+		for i := end - 1; i >= 0 && len(result) < limit; i-- {
+			chunk, ok := p.readLocked(segment, segment.entries[i])
+			if !ok {
+				return reverseChunks(result)
+			}
+			result = append(result, chunk)
+		}
+	}
+	return reverseChunks(result)
+}
+
+func (p *scrollbackSpill) readLocked(segment *spillSegment, entry spillEntry) (ScrollbackChunk, bool) {
+	data := make([]byte, entry.size)
+	if _, err := segment.file.ReadAt(data, entry.offset); err != nil {
+		if p.logger != nil {
+			p.logger.Warn("failed to read spilled scrollback",
+				zap.String("sessionId", p.sessionID),
+				zap.Error(err))
+		}
+		return ScrollbackChunk{}, false
+	}
+	return ScrollbackChunk{Seq: entry.seq, Data: data, Timestamp: entry.at}, true
+}
+
+func (c pendingSpill) scrollbackChunk() ScrollbackChunk {
+	return ScrollbackChunk{Seq: c.seq, Data: cloneBytes(c.data), Timestamp: c.at}
+}
+
+// close deletes the spill files and drops queued chunks.
+func (p *scrollbackSpill) close() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.stopLocked()
+}
+
+func reverseChunks(chunks []ScrollbackChunk) []ScrollbackChunk {
+	for i, j := 0, len(chunks)-1; i < j; i, j = i+1, j-1 {
+		chunks[i], chunks[j] = chunks[j], chunks[i]
+	}
+	return chunks
+}
+
+// forEachScrollbackChunk walks the spilled chunks followed by the in-memory
+// ones, oldest first. The memory part is copied up front and spilled chunks are
+// read one at a time, so a slow fn never blocks the output loop. Chunks dropped
+// from the disk limit while walking are skipped.
+func (s *Session) forEachScrollbackChunk(fn func(chunk ScrollbackChunk) error) error {
+	s.scrollMu.RLock()
+	memory := s.scrollbackChunksLocked(0, len(s.scrollback))
+	baseSeq := s.scrollbackBaseSeq
+	s.scrollMu.RUnlock()
+
+	for seq := int64(0); ; {
+		chunk, ok := s.scrollbackSpill.chunkAfter(seq, baseSeq)
+		if !ok {
+			break
+		}
+		if err := fn(chunk); err != nil {
+			return err
+		}
+		seq = chunk.Seq
+	}
+	for _, chunk := range memory {
+		if err := fn(chunk); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteScrollback writes the whole retained output, spilled part included, to w.
+func (s *Session) WriteScrollback(w io.Writer) error {
+	return s.forEachScrollbackChunk(func(chunk ScrollbackChunk) error {
+		_, err := w.Write(chunk.Data)
+		return err
+	})
+}
+
+// WritePlainScrollback is WriteScrollback with ANSI sequences stripped.
+func (s *Session) WritePlainScrollback(w io.Writer) error {
+	// 末尾仍未结束的序列不会显示，直接丢弃
+	var open []byte
+	return s.forEachScrollbackChunk(func(chunk ScrollbackChunk) error {
+		var data []byte
+		data, open = splitUnterminatedOSC(append(open, chunk.Data...))
+		open = append([]byte(nil), open...)
+		_, err := w.Write(plainTextOutput(data))
+		return err
+	})
+}
+
+// ScrollbackMatch is one output line containing the searched text.
+type ScrollbackMatch struct {
+	// Seq 该行结束所在分片的序号
+	Seq  int64  `json:"seq"`
+	Line string `json:"line"`
+}
+
+// SearchScrollback returns the newest limit lines of the retained output, spilled
+// part included, that contain query case-insensitively. Lines are compared with
+// ANSI sequences stripped; limit <= 0 uses the default of 200.
+func (s *Session) SearchScrollback(query string, limit int) ([]ScrollbackMatch, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return []ScrollbackMatch{}, nil
+	}
+	if limit <= 0 {
+		limit = defaultScrollbackSearchLimit
+	}
+
+	matches := make([]ScrollbackMatch, 0)
+	var partial []byte
+	check := func(seq int64, line []byte) {
+		text := strings.TrimRight(string(line), "\r")
+		if !strings.Contains(strings.ToLower(text), query) {
+			return
+		}
+		matches = append(matches, ScrollbackMatch{Seq: seq, Line: text})
+		if len(matches) > limit {
+			matches = matches[1:]
+		}
+	}
+	var lastSeq int64
+	var open []byte
+	err := s.forEachScrollbackChunk(func(chunk ScrollbackChunk) error {
+		lastSeq = chunk.Seq
+		// 跨分片的 OSC 标题等序列拼完整后再剥离，避免其内容被当作输出匹配
+		var raw []byte
+		raw, open = splitUnterminatedOSC(append(open, chunk.Data...))
+		open = append([]byte(nil), open...)
+		data := append(partial, plainTextOutput(raw)...)
+		for {
+			idx := bytes.IndexByte(data, '\n')
+			if idx < 0 {
+				break
+			}
+			check(chunk.Seq, data[:idx])
+			data = data[idx+1:]
+		}
+		// 分片末尾未换行的部分留到下一个分片拼成完整的行
+		if len(data) > scrollbackSearchMaxLineBytes {
+			data = data[len(data)-scrollbackSearchMaxLineBytes:]
+		}
+		partial = append([]byte(nil), data...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(partial) > 0 {
+		check(lastSeq, partial)
+	}
+	return matches, nil
+}
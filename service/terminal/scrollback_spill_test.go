@@ -0,0 +1,124 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestScrollbackSpill(t *testing.T) {
+	dir := t.TempDir()
+	session := newTestSession(t, SessionParams{
+		ScrollbackLimit: 10,
+		ScrollbackSpill: ScrollbackSpillConfig{Enabled: true, Dir: dir, MaxBytes: 40},
+	})
+	for i := 1; i <= 10; i++ {
+		session.appendScrollback([]byte(fmt.Sprintf("line-%02d\n", i)))
+	}
+
+	// 内存只保留最后一个分片，磁盘限 40 字节：两段各 2 个分片，最早的段已删除
+	if got := len(session.Scrollback()); got != 1 {
+		t.Fatalf("expected 1 chunk in memory, got %d", got)
+	}
+	// 分片由后台协程写入磁盘
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		entries, err := os.ReadDir(dir)
+		if err == nil && len(entries) == 2 && spillIdle(session.scrollbackSpill) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected 2 spill segments, got %d (%v)", len(entries), err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	page := session.ScrollbackBefore(0, 3)
+	if len(page) != 3 || page[0].Seq != 8 || page[2].Seq != 10 || string(page[0].Data) != "line-08\n" {
+		t.Fatalf("unexpected merged page %+v", page)
+	}
+	if older := session.ScrollbackBefore(8, 5); len(older) != 1 || older[0].Seq != 7 {
+		t.Fatalf("expected only chunk 7 before 8, got %+v", older)
+	}
+	if since, _ := session.ScrollbackSince(0); len(since) != 1 {
+		t.Fatalf("expected replay to use memory only, got %d chunks", len(since))
+	}
+
+	var exported bytes.Buffer
+	if err := session.WriteScrollback(&exported); err != nil {
+		t.Fatalf("WriteScrollback failed: %v", err)
+	}
+	if want := "line-07\nline-08\nline-09\nline-10\n"; exported.String() != want {
+		t.Fatalf("unexpected export %q", exported.String())
+	}
+
+	matches, err := session.SearchScrollback("LINE-0", 2)
+	if err != nil {
+		t.Fatalf("SearchScrollback failed: %v", err)
+	}
+	if len(matches) != 2 || matches[0].Line != "line-08" || matches[1].Seq != 9 {
+		t.Fatalf("unexpected matches %+v", matches)
+	}
+
+	_ = session.Close()
+	if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+		t.Fatalf("expected spill files removed on close, got %d", len(entries))
+	}
+}
+
+func TestSearchScrollbackJoinsLinesAcrossChunks(t *testing.T) {
+	session := newTestSession(t, SessionParams{ScrollbackLimit: 1024})
+	session.appendScrollback([]byte("\x1b[32mbuild fin"))
+	session.appendScrollback([]byte("ished\x1b[0m\r\nnext"))
+
+	matches, err := session.SearchScrollback("build finished", 0)
+	if err != nil {
+		t.Fatalf("SearchScrollback failed: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Line != "build finished" || matches[0].Seq != 2 {
+		t.Fatalf("unexpected matches %+v", matches)
+	}
+}
+
+func spillIdle(p *scrollbackSpill) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.pending) == 0
+}
+
+func TestScrollbackSpillQueue(t *testing.T) {
+	spill := newScrollbackSpill(ScrollbackSpillConfig{Enabled: true, Dir: t.TempDir(), MaxBytes: 8}, "s1", nil)
+	// 预先占用 wake，不启动写入协程，分片停留在队列中
+	spill.wake = make(chan struct{}, 1)
+	for i := 1; i <= 4; i++ {
+		spill.append(int64(i), []byte(fmt.Sprintf("c%d-\n", i)), time.Now())
+	}
+
+	// 积压超过 MaxBytes 时丢弃最早的分片，未写入的分片仍可读取
+	page := spill.chunksBefore(10, 5)
+	if len(page) != 2 || page[0].Seq != 3 || string(page[1].Data) != "c4-\n" {
+		t.Fatalf("unexpected queued chunks %+v", page)
+	}
+	if chunk, ok := spill.chunkAfter(0, 10); !ok || chunk.Seq != 3 {
+		t.Fatalf("expected chunk 3 first, got %+v %v", chunk, ok)
+	}
+	if _, ok := spill.chunkAfter(3, 4); ok {
+		t.Fatalf("expected no chunk between 3 and 4")
+	}
+
+	for spill.writeNext() {
+	}
+	if !spillIdle(spill) || len(spill.segments) != 2 || spill.segments[1].entries[0].seq != 4 {
+		t.Fatalf("expected queued chunks written to two segments, got %+v", spill.segments)
+	}
+	if page := spill.chunksBefore(10, 5); len(page) != 2 || page[0].Seq != 3 || page[1].Seq != 4 {
+		t.Fatalf("unexpected written chunks %+v", page)
+	}
+	spill.close()
+	spill.append(5, []byte("late"), time.Now())
+	if page := spill.chunksBefore(10, 5); len(page) != 0 {
+		t.Fatalf("expected closed spill to drop chunks, got %+v", page)
+	}
+}
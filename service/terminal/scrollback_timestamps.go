@@ -0,0 +1,97 @@
+package terminal
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+)
+
+// scrollbackTimestampLayout 导出时每行前缀的时间格式，精确到毫秒
+const scrollbackTimestampLayout = "2006-01-02T15:04:05.000Z07:00"
+
+// WriteTimestampedScrollback is WritePlainScrollback with every line prefixed
+// by the time it was received. Chunks are not lines: a chunk may hold several
+// lines and a line may span chunks, so each line takes the timestamp of the
+// chunk its first byte arrived in. With filtered set the output filters are
+// applied to the line text before the prefix is added.
+func (s *Session) WriteTimestampedScrollback(w io.Writer, filtered bool) error {
+	tw := &timestampedLineWriter{w: w}
+	if filtered {
+		tw.filters = s.outputFilters()
+	}
+	// 末尾仍未结束的序列不会显示，直接丢弃
+	var open []byte
+	err := s.forEachScrollbackChunk(func(chunk ScrollbackChunk) error {
+		var data []byte
+		data, open = splitUnterminatedOSC(append(open, chunk.Data...))
+		open = append([]byte(nil), open...)
+		return tw.write(chunk.Timestamp, plainTextOutput(data))
+	})
+	if err != nil {
+		return err
+	}
+	return tw.flush()
+}
+
+// timestampedLineWriter buffers plain output until a line is complete and
+// writes it with the time at which the line started.
+type timestampedLineWriter struct {
+	w       io.Writer
+	filters []*compiledOutputFilter
+	pending []byte
+	started time.Time
+}
+
+func (tw *timestampedLineWriter) write(at time.Time, p []byte) error {
+	for len(p) > 0 {
+		if len(tw.pending) == 0 {
+			tw.started = at
+		}
+		idx := bytes.IndexByte(p, '\n')
+		if idx < 0 {
+			tw.pending = append(tw.pending, p...)
+			return nil
+		}
+		tw.pending = append(tw.pending, p[:idx]...)
+		p = p[idx+1:]
+		if err := tw.writeLine(true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (tw *timestampedLineWriter) flush() error {
+	if len(tw.pending) == 0 {
+		return nil
+	}
+	return tw.writeLine(false)
+}
+
+func (tw *timestampedLineWriter) writeLine(newline bool) error {
+	// \r\n 的 \r 不参与过滤，输出时原样保留
+	body, cr := strings.CutSuffix(string(tw.pending), "\r")
+	tw.pending = tw.pending[:0]
+	if len(tw.filters) > 0 {
+		filtered, keep := filterOutputLine(body, tw.filters)
+		if !keep {
+			return nil
+		}
+		body = filtered
+	}
+
+	var line strings.Builder
+	line.WriteByte('[')
+	line.WriteString(tw.started.Format(scrollbackTimestampLayout))
+	line.WriteString("] ")
+	line.WriteString(body)
+	if cr {
+		line.WriteByte('\r')
+	}
+	if newline {
+		line.WriteByte('\n')
+	}
+	_, err := io.WriteString(tw.w, line.String())
+	return err
+}
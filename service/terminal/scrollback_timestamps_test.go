@@ -0,0 +1,48 @@
+package terminal
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestSessionWriteTimestampedScrollback(t *testing.T) {
+	session := newTestSession(t, SessionParams{ScrollbackLimit: 4096})
+	session.appendScrollback([]byte("\x1b[1mstart\x1b[0m\r\nthinking"))
+	session.appendScrollback([]byte(" done\r\nspinner\r\n"))
+	session.appendScrollback([]byte("tail"))
+
+	base := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	session.scrollMu.Lock()
+	for i := range session.scrollbackTimestamps {
+		session.scrollbackTimestamps[i] = base.Add(time.Duration(i) * 1500 * time.Millisecond)
+	}
+	session.scrollMu.Unlock()
+
+	// 跨分片的行取首个字节所在分片的时间
+	var out bytes.Buffer
+	if err := session.WriteTimestampedScrollback(&out, false); err != nil {
+		t.Fatalf("WriteTimestampedScrollback: %v", err)
+	}
+	want := "[2026-01-02T03:04:05.000Z] start\n" +
+		"[2026-01-02T03:04:05.000Z] thinking done\n" +
+		"[2026-01-02T03:04:06.500Z] spinner\n" +
+		"[2026-01-02T03:04:08.000Z] tail"
+	if got := out.String(); got != want {
+		t.Fatalf("unexpected export\n%s\nwant\n%s", got, want)
+	}
+
+	if err := session.SetOutputFilters([]OutputFilterRule{{Pattern: `^spinner`, DropLine: true}}); err != nil {
+		t.Fatalf("SetOutputFilters: %v", err)
+	}
+	out.Reset()
+	if err := session.WriteTimestampedScrollback(&out, true); err != nil {
+		t.Fatalf("WriteTimestampedScrollback: %v", err)
+	}
+	want = "[2026-01-02T03:04:05.000Z] start\n" +
+		"[2026-01-02T03:04:05.000Z] thinking done\n" +
+		"[2026-01-02T03:04:08.000Z] tail"
+	if got := out.String(); got != want {
+		t.Fatalf("unexpected filtered export\n%s\nwant\n%s", got, want)
+	}
+}
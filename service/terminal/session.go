@@ -1,19 +1,24 @@
 package terminal
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+	"unicode/utf8"
 
 	"github.com/charmbracelet/x/xpty"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/simplifiedchinese"
 	"golang.org/x/text/transform"
@@ -33,6 +38,10 @@ const (
 	SessionStatusRunning  SessionStatus = "running"
 	SessionStatusClosed   SessionStatus = "closed"
 	SessionStatusError    SessionStatus = "error"
+	// SessionStatusPaused 进程树已被 SIGSTOP 冻结
+	SessionStatusPaused SessionStatus = "paused"
+	// SessionStatusRestarting 进程已退出，正按重启策略等待退避后重新启动
+	SessionStatusRestarting SessionStatus = "restarting"
 )
 
 // ErrInvalidEncoding indicates an unsupported encoding setting.
@@ -44,13 +53,27 @@ type SessionSnapshot struct {
 	ProjectID  string
 	WorktreeID string
 	WorkingDir string
+	// CurrentDir shell 通过 OSC 7 上报的当前目录，未上报时与 WorkingDir 相同
+	CurrentDir string
 	Title      string
+	OrderIndex float64
 	CreatedAt  time.Time
 	LastActive time.Time
 	Status     SessionStatus
 	Rows       int
 	Cols       int
 	Encoding   string
+	Term       string
+	// ShellName 创建时选择的命名 shell，默认 shell 为空
+	ShellName string
+	// IdleTimeoutOverride 为 0 时使用全局配置，负数表示永不超时
+	IdleTimeoutOverride time.Duration
+	// MaxLifetime 为 0 表示不限制运行时长，Deadline 在会话启动后才有值
+	MaxLifetime time.Duration
+	Deadline    time.Time
+	// RestartPolicy 进程退出后的重启策略，Restarts 为已重启的总次数
+	RestartPolicy RestartPolicy
+	Restarts      int64
 	// Process information
 	ProcessPID         int32  `json:"processPid,omitempty"`
 	ProcessStatus      string `json:"processStatus,omitempty"`
@@ -59,6 +82,24 @@ type SessionSnapshot struct {
 	// AI Assistant information
 	AIAssistant *ai_assistant2.AIAssistantInfo `json:"aiAssistant"`
 	TaskID      string                         `json:"taskId,omitempty"`
+	// Long running non-AI task information
+	TaskType      string
+	TaskStartedAt *time.Time
+	// TokenUsage AI 助手累计的 token 用量与估算成本，未统计到时为空
+	TokenUsage *TokenUsage
+	// TurnCount AI 助手本次运行完成的对话轮次，InterruptedTurns 为被打断的轮次
+	TurnCount        int
+	InterruptedTurns int
+	// Annotations 通过 API 附加的自定义键值
+	Annotations map[string]string
+	// ReadOnly 只读会话只展示输出，拒绝写入输入
+	ReadOnly bool
+	// TrackOnly 仅跟踪 AI 状态，不缓存 scrollback，也不向普通订阅者转发输出
+	TrackOnly bool
+	// Source 会话的创建来源
+	Source SessionSource
+	// LogLevel 会话级日志级别覆盖，为空沿用全局级别
+	LogLevel string
 }
 
 type StreamEventType string
@@ -67,13 +108,59 @@ const (
 	StreamEventData     StreamEventType = "data"
 	StreamEventExit     StreamEventType = "exit"
 	StreamEventMetadata StreamEventType = "metadata"
+	StreamEventLinks    StreamEventType = "links"
+	// StreamEventHighlights 按配置的高亮规则标注的输出范围，纯文本 data 流不变
+	StreamEventHighlights StreamEventType = "highlights"
+	// StreamEventAlert 输出命中告警规则，Alert 为命中行及其上下文
+	StreamEventAlert StreamEventType = "alert"
+	// StreamEventLifetimeExceeded 会话达到最大运行时长
+	StreamEventLifetimeExceeded StreamEventType = "lifetime-exceeded"
+	// StreamEventWorktreeMoved 会话所属 worktree 已被移动，Data 为新的工作目录
+	StreamEventWorktreeMoved StreamEventType = "worktree-moved"
+	// StreamEventEncodingChanged 会话编码已切换，Data 为按新编码重新解码的 scrollback，未重新解码时为空
+	StreamEventEncodingChanged StreamEventType = "encoding-changed"
 )
 
+var streamEventTypes = []StreamEventType{
+	StreamEventData,
+	StreamEventExit,
+	StreamEventMetadata,
+	StreamEventLinks,
+	StreamEventHighlights,
+	StreamEventAlert,
+	StreamEventLifetimeExceeded,
+	StreamEventWorktreeMoved,
+	StreamEventEncodingChanged,
+}
+
+// ParseStreamEventTypes parses a comma separated event type list such as
+// "metadata,exit". An empty value returns nil, meaning all events.
+func ParseStreamEventTypes(value string) ([]StreamEventType, error) {
+	var types []StreamEventType
+	for _, part := range strings.Split(value, ",") {
+		name := StreamEventType(strings.ToLower(strings.TrimSpace(part)))
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(streamEventTypes, name) {
+			return nil, fmt.Errorf("unknown event type %q", name)
+		}
+		types = append(types, name)
+	}
+	return types, nil
+}
+
 type StreamEvent struct {
 	Type     StreamEventType
 	Data     []byte
+	Seq      int64 // scrollback 分片序号，仅 data 事件且启用 scrollback 时有值
 	Err      error
 	Metadata *SessionMetadata
+	Links    []TerminalLink
+	// Highlights 仅 highlights 事件有值
+	Highlights []TerminalHighlight
+	// Alert 仅 alert 事件有值
+	Alert *OutputAlert
 }
 
 type SessionMetadata struct {
@@ -85,6 +172,18 @@ type SessionMetadata struct {
 	AIAssistant            *ai_assistant2.AIAssistantInfo `json:"aiAssistant,omitempty"`
 	TaskID                 string                         `json:"taskId,omitempty"`
 	AIAssistantRecentInput string                         `json:"aiAssistantRecentInput,omitempty"`
+	// TaskType 非 AI 长任务的类型（如 build），TaskStartedAt 为识别到该命令的时间
+	TaskType            string     `json:"taskType,omitempty"`
+	TaskStartedAt       *time.Time `json:"taskStartedAt,omitempty"`
+	TaskDurationSeconds int64      `json:"taskDurationSeconds,omitempty"`
+	// EchoOff 前台程序关闭了回显（如密码输入），前端可切换为密码输入样式
+	EchoOff bool `json:"echoOff,omitempty"`
+	// WaitingPager 前台命令停在分页器（less、git log 等）等待按键，前端可提示按 q 退出
+	WaitingPager bool `json:"waitingPager,omitempty"`
+	// Todos AI 助手最近显示的任务清单
+	Todos []ai_assistant2.TodoItem `json:"todos,omitempty"`
+	// ContextLeftPercent AI 助手显示的上下文余量（0-100），助手未显示时为空
+	ContextLeftPercent *int `json:"contextLeftPercent,omitempty"`
 }
 
 type SessionStream struct {
@@ -112,12 +211,34 @@ type sessionSubscriber struct {
 	ch     chan StreamEvent
 	cancel context.CancelFunc
 	once   sync.Once
+	// types 订阅的事件类型，为空表示全部；exit 事件总会投递
+	types map[StreamEventType]struct{}
+	// lastDeliveredAt 最近一次成功投递的时间；fullSince 从第一次因 channel 满而丢弃起计时，
+	// 投递成功后清零，用于识别没有被消费的泄漏订阅，见 pruneStaleSubscribers
+	lastDeliveredAt atomic.Int64
+	fullSince       atomic.Int64
+}
+
+func (sub *sessionSubscriber) accepts(eventType StreamEventType) bool {
+	if len(sub.types) == 0 || eventType == StreamEventExit {
+		return true
+	}
+	_, ok := sub.types[eventType]
+	return ok
 }
 
 const (
 	subscriberBufferSize     = 128
 	assistantOutputBufferLen = 32
 	maxSessionTitleLength    = 64
+	// processBusyConfirmations 进程 busy/idle 变化需连续观测到的次数
+	processBusyConfirmations = 2
+	// assistantStateDebounceWindow 相同 AI 状态的重复变化在该窗口内只广播最后一次
+	assistantStateDebounceWindow = 500 * time.Millisecond
+	// pendingInputMaxBytes 会话启动完成前缓冲输入的上限，超出部分丢弃
+	pendingInputMaxBytes = 64 * 1024
+	// maxDecodeTailBytes 跨 chunk 保留的未完成字节上限，GB18030 最长 4 字节
+	maxDecodeTailBytes = 4
 )
 
 // Session encapsulates a PTY-backed terminal command.
@@ -125,47 +246,118 @@ type Session struct {
 	id         string
 	projectID  string
 	worktreeID string
+	quotaKey   string
 	workingDir string
 	title      string
+	// orderIndex 标签页顺序，由 mu 保护，Manager 在加入时分配到末尾
+	orderIndex float64
 	command    []string
+	shellName  string
 	env        []string
+	term       string
 	rows       int
 	cols       int
+	// nice 启动（含重启）时设置给 shell 的优先级，0 不调整
+	nice int
 
 	createdAt  time.Time
 	lastActive atomic.Int64
 	status     atomic.Value
+	// idleTimeoutOverride 以纳秒保存：0 使用全局配置，负数表示永不超时
+	idleTimeoutOverride atomic.Int64
+	// maxLifetime 是会话的硬性运行上限，与空闲超时无关；deadline 在 Start 时确定
+	maxLifetime        time.Duration
+	closeOnMaxLifetime bool
+	deadline           time.Time
 
 	cmd    *exec.Cmd
 	pty    xpty.Pty
 	cancel context.CancelFunc
+	// startCtx 是 Start 传入的上下文，重启时在它之下创建新的进程上下文
+	startCtx context.Context
+	restart  restartState
 
 	closeOnce sync.Once
 	closed    chan struct{}
 	err       atomic.Value
+	// exited shell 自行退出且 err 已记录退出状态，重启后重置
+	exited atomic.Bool
 
-	logger   *zap.Logger
+	logger *zap.Logger
+	// encoding 与 encName 可在运行中切换，由 decodeMu 保护
 	encoding encoding.Encoding
 	encName  string
-
-	assistantTracker  *ai_assistant2.StatusTracker
+	// decoder 跨 chunk 流式解码输出，decodeTail 保存被 chunk 边界截断的多字节字符
+	decodeMu   sync.Mutex
+	decoder    transform.Transformer
+	decodeTail []byte
+
+	assistantTracker *ai_assistant2.StatusTracker
+	// assistantBanner 从启动输出中提取 AI 助手的模型与版本
+	assistantBanner   *ai_assistant2.BannerExtractor
 	getAIConfig       func() *utils.AIAssistantStatusConfig
 	assistantOutputCh chan []byte
+	// modelPricing 估算 token 成本用的价格表，只读
+	modelPricing map[string]utils.ModelPrice
 
-	associatedTaskID          string
-	lockedTitle               string
+	associatedTaskID string
+	lockedTitle      string
+	// manualTitle 用户手动改过名，标题规则不再覆盖
+	manualTitle               bool
 	lastRecentInput           string
 	renameTitleEachCommand    atomic.Bool
 	autoCreateTaskOnStartWork atomic.Bool
-	autoTitleAssigned         atomic.Bool
+	// echoOff 最近一次读取的 PTY 回显状态，输出到达时检查
+	echoOff atomic.Bool
+	// inputHidden 最近一次读取时输入是否不可见（关闭回显的行缓冲模式），输入历史据此跳过敏感输入
+	inputHidden       atomic.Bool
+	autoTitleAssigned atomic.Bool
+	// pager 跟踪备用屏幕与底部提示行，识别分页器等待翻页
+	pager pagerScreen
+	// cwd 通过 OSC 7 跟踪 shell 的实际工作目录
+	cwd cwdTracker
+	// oscTitle 解析程序通过 OSC 0/2 设置的窗口标题
+	oscTitle oscTitleTracker
+	// autoWrap 跟踪程序最近一次开关自动换行（DECAWM）
+	autoWrap autoWrapTracker
+	// output 当前 PTY 读取协程的输出合并器，进程退出后据此发出缓存的最后一段输出
+	output atomic.Pointer[outputCoalescer]
+	// inputHistory 用户按回车提交的输入，随会话保存
+	inputHistory inputHistory
 
 	mu sync.RWMutex
+	// writeMu 串行化写入 PTY：WS 输入、触发器、input-file 等并发写入时，每个 payload 都完整写出后才轮到下一个
+	writeMu sync.Mutex
+	// 启动完成前的输入先缓冲，PTY 就绪后按序写入；inputReady 之后直接写 PTY
+	pendingMu    sync.Mutex
+	pendingInput [][]byte
+	pendingSize  int
+	inputReady   bool
 
 	scrollMu             sync.RWMutex
 	scrollback           [][]byte
 	scrollbackTimestamps []time.Time
 	scrollbackSize       int
 	scrollbackLimit      int
+	// scrollbackBaseSeq 是 scrollback[0] 的序号，序号从 1 开始单调递增
+	scrollbackBaseSeq int64
+	// scrollbackSpill 可选地把移出内存的分片追加到临时文件，为空时直接丢弃
+	scrollbackSpill *scrollbackSpill
+	// scrollbackCollapse 开启时连续的同形重绘分片（spinner 帧）只保留最新一份，
+	// redrawFingerprint 为上一个分片的指纹，redrawSet 表示上一个分片是重绘
+	scrollbackCollapse bool
+	redrawFingerprint  uint64
+	redrawSet          bool
+	// scrollbackBudgetTrimmed 因超出全局 scrollback 预算被裁剪的字节数
+	scrollbackBudgetTrimmed atomic.Int64
+	// scrollbackRev 每次 scrollback 内容变化时递增，用于判断渲染缓存是否过期
+	scrollbackRev uint64
+	// scrollbackRewrites 已有分片被整体改写（如切换编码后重新解码）的次数，逻辑行缓存据此重建
+	scrollbackRewrites uint64
+	// approvalCache 缓存审批提示识别用的屏幕渲染结果
+	approvalCache approvalScreenCache
+	// binaryGuard 截断疑似二进制的输出，未开启时为空
+	binaryGuard *binaryGuard
 
 	subMu       sync.RWMutex
 	subscribers map[string]*sessionSubscriber
@@ -173,26 +365,158 @@ type Session struct {
 
 	metaMu       sync.RWMutex
 	lastMetadata *SessionMetadata
+	// 元数据去抖：busy/idle 需连续确认才切换；相同 AI 状态在窗口内合并为最后一次
+	busyKnown          bool
+	busyConfirmed      bool
+	busyFlipHits       int
+	assistantLastState string
+	assistantLastAt    time.Time
+	assistantPending   *time.Timer
+	// assistantDebounce 相同状态重复广播的去抖窗口，0 使用 assistantStateDebounceWindow
+	assistantDebounce time.Duration
+	// assistantBusyAt/assistantRechecked AI 状态与进程活动的校准，见 calibrateAssistantState
+	assistantBusyAt    time.Time
+	assistantRechecked bool
+	longTaskCommand    string
+	longTaskStartedAt  time.Time
+
+	// 链接识别：保留最近输出，在元数据轮询时渲染成逻辑行后识别
+	detectLinks  atomic.Bool
+	worktreeRoot string
+	linkMu       sync.Mutex
+	linkBuffer   []byte
+	linkDirty    bool
+	lastLinksSig string
+
+	// clearBeforeSend 提交输入前先发送清屏键，见 Send
+	clearBeforeSend atomic.Bool
+	// readOnly 创建时确定，只读会话拒绝一切输入，见 ErrSessionReadOnly
+	readOnly bool
+	// trackOnly 创建时确定，关闭 scrollback，data 事件只投递给显式订阅 data 的订阅者
+	trackOnly bool
+	// logLevel 会话级日志级别覆盖，nil 时沿用全局级别，见 SetLogLevel
+	logLevel atomic.Pointer[zapcore.Level]
+	// outputSink 输出旁路到外部程序，nil 表示未设置，见 SetOutputSink
+	outputSink atomic.Pointer[outputSink]
+	// source 创建来源，创建后不再变化
+	source SessionSource
+
+	// 关键字高亮：规则来自配置，与链接识别一样在元数据轮询时渲染最近输出后标注
+	highlightMu       sync.Mutex
+	highlightRules    []*compiledHighlightRule
+	highlightBuffer   []byte
+	highlightDirty    bool
+	lastHighlightsSig string
+
+	// recorder 可选地记录所有流事件，用于 JSONL 导出
+	recorder *eventRecorder
+	stats    sessionStats
+	// outputStatsCache 缓存屏幕非空行数，避免每次诊断都重新渲染
+	outputStatsCache outputStatsCache
+	// previewCache 缓存缩略预览的渲染结果
+	previewCache previewCache
+	// scrollbackLinesCache 增量维护按逻辑行分页用的历史文本
+	scrollbackLinesCache scrollbackLinesCache
+	// rawCapture 调试用的原始 PTY 字节缓冲，默认关闭
+	rawCapture rawCapture
+	// outputLog 可选地把输出写入按大小轮转的文本日志
+	outputLog *outputLog
+	// screenshots 可选地定时把渲染后的屏幕存为文本快照
+	screenshots *screenshotter
+
+	// 输出触发器：全局规则来自配置，会话规则通过 API 设置
+	triggerMu       sync.Mutex
+	globalTriggers  []*compiledTrigger
+	sessionTriggers []*compiledTrigger
+	triggerBuffer   []byte
+
+	// 告警规则：全局规则来自配置，会话规则通过 API 设置，命中时广播 alert 事件
+	alertMu           sync.Mutex
+	globalAlertRules  []*compiledAlertRule
+	sessionAlertRules []*compiledAlertRule
+	alertBuffer       []byte
+
+	// 输出美化规则：全局规则来自配置，会话规则通过 API 设置，只作用于预览与导出等派生视图
+	filterMu             sync.Mutex
+	globalOutputFilters  []*compiledOutputFilter
+	sessionOutputFilters []*compiledOutputFilter
+
+	// 输入宏：全局宏来自配置，会话宏通过 API 设置
+	macroMu       sync.Mutex
+	globalMacros  []InputMacro
+	sessionMacros []InputMacro
+
+	// annotations 上层集成附加的自定义键值，后端只存储不解释
+	annotationMu sync.Mutex
+	annotations  map[string]string
+
+	// 标题规则：来自配置，按输出内容设置标题，titleRuleAppliedAt 用于防抖
+	titleRuleMu        sync.Mutex
+	titleRules         []*compiledTitleRule
+	titleRuleBuffer    []byte
+	titleRuleAppliedAt time.Time
+	// titleRuleChecked titleRuleBuffer 中已做过预筛的字节数
+	titleRuleChecked int
+
+	// createParams 创建会话时的参数，克隆会话时复用；由 Manager 在创建时设置，之后只读
+	createParams CreateSessionParams
 }
 
 // SessionParams collects the data required to bootstrap a session.
 type SessionParams struct {
-	ID                        string
-	ProjectID                 string
-	WorktreeID                string
-	WorkingDir                string
-	Title                     string
-	Command                   []string
-	Env                       []string
+	ID         string
+	ProjectID  string
+	WorktreeID string
+	WorkingDir string
+	Title      string
+	Command    []string
+	// ShellName Command 对应的命名 shell，仅用于展示
+	ShellName string
+	Env       []string
+	// Term 写入 TERM 环境变量，空为 DefaultTermType
+	Term                      string
 	Rows                      int
 	Cols                      int
 	Logger                    *zap.Logger
 	Encoding                  string
 	ScrollbackLimit           int
+	ScrollbackSpill           ScrollbackSpillConfig
+	ScrollbackCollapse        bool
+	BinaryOutput              string
 	GetAIConfig               func() *utils.AIAssistantStatusConfig
 	TaskID                    string
 	RenameTitleEachCommand    bool
 	AutoCreateTaskOnStartWork bool
+	IdleTimeoutOverride       time.Duration
+	MaxLifetime               time.Duration
+	CloseOnMaxLifetime        bool
+	DetectLinks               bool
+	AIDetectionDiagnostics    bool
+	CaptureRawOutput          bool
+	ClearBeforeSend           bool
+	WorktreeRoot              string
+	QuotaKey                  string
+	// RecordEvents 事件记录模式（EventRecordMemory / EventRecordFile），为空不记录
+	RecordEvents    string
+	RecordEventsDir string
+	// OutputLog 输出日志配置，Dir 为空不写
+	OutputLog OutputLogConfig
+	// Screenshots 屏幕快照归档配置，Dir 为空不截图
+	Screenshots ScreenshotConfig
+	// ModelPricing 按模型或助手类型配置的 token 价格
+	ModelPricing map[string]utils.ModelPrice
+	// RestartPolicy 进程退出后的重启策略，为空等同 RestartNever
+	RestartPolicy RestartPolicy
+	// MaxRestarts 连续重启次数上限，<= 0 使用 defaultMaxRestarts
+	MaxRestarts int
+	// Nice 进程调度优先级（-20 到 19，越大越低），0 不调整，超出范围忽略
+	Nice int
+	// ReadOnly 只展示输出、拒绝输入，用于查看只读 worktree
+	ReadOnly bool
+	// TrackOnly 仅跟踪 AI 状态的监控会话：不保留 scrollback，普通订阅者收不到输出
+	TrackOnly bool
+	// Source 创建来源，为空记为 SessionSourceAPI
+	Source SessionSource
 }
 
 // sessionError provides a non-nil wrapper so atomic.Value never stores nil.
@@ -210,8 +534,17 @@ func NewSession(params SessionParams) (*Session, error) {
 		params.ID = utils.NewID()
 	}
 
+	restartPolicy, err := ParseRestartPolicy(string(params.RestartPolicy))
+	if err != nil {
+		return nil, err
+	}
+	maxRestarts := params.MaxRestarts
+	if maxRestarts <= 0 {
+		maxRestarts = defaultMaxRestarts
+	}
+
 	scrollbackLimit := params.ScrollbackLimit
-	if scrollbackLimit < 0 {
+	if scrollbackLimit < 0 || params.TrackOnly {
 		scrollbackLimit = 0
 	}
 
@@ -231,32 +564,54 @@ func NewSession(params SessionParams) (*Session, error) {
 	}
 
 	session := &Session{
-		id:               params.ID,
-		projectID:        params.ProjectID,
-		worktreeID:       params.WorktreeID,
-		workingDir:       params.WorkingDir,
-		title:            params.Title,
-		command:          append([]string{}, params.Command...),
-		env:              append([]string{}, params.Env...),
-		rows:             rows,
-		cols:             cols,
-		createdAt:        time.Now(),
-		closed:           make(chan struct{}),
-		logger:           params.Logger,
-		encoding:         enc,
-		encName:          encName,
-		scrollbackLimit:  scrollbackLimit,
-		subscribers:      make(map[string]*sessionSubscriber),
-		assistantTracker: ai_assistant2.NewStatusTracker(),
-		getAIConfig:      params.GetAIConfig,
-		associatedTaskID: params.TaskID,
-	}
+		id:                params.ID,
+		projectID:         params.ProjectID,
+		worktreeID:        params.WorktreeID,
+		workingDir:        params.WorkingDir,
+		title:             params.Title,
+		command:           append([]string{}, params.Command...),
+		shellName:         params.ShellName,
+		env:               append([]string{}, params.Env...),
+		term:              params.Term,
+		rows:              rows,
+		cols:              cols,
+		createdAt:         time.Now(),
+		closed:            make(chan struct{}),
+		logger:            params.Logger,
+		encoding:          enc,
+		encName:           encName,
+		scrollbackLimit:   scrollbackLimit,
+		scrollbackBaseSeq: 1,
+		subscribers:       make(map[string]*sessionSubscriber),
+		assistantTracker:  ai_assistant2.NewStatusTracker(),
+		assistantBanner:   ai_assistant2.NewBannerExtractor(),
+		getAIConfig:       params.GetAIConfig,
+		modelPricing:      params.ModelPricing,
+		associatedTaskID:  params.TaskID,
+		worktreeRoot:      params.WorktreeRoot,
+		maxLifetime:       params.MaxLifetime,
+		quotaKey:          params.QuotaKey,
+		restart:           restartState{policy: restartPolicy, maxRestarts: maxRestarts},
+	}
+	session.closeOnMaxLifetime = params.CloseOnMaxLifetime
 	session.renameTitleEachCommand.Store(params.RenameTitleEachCommand)
 	session.autoCreateTaskOnStartWork.Store(params.AutoCreateTaskOnStartWork)
+	session.idleTimeoutOverride.Store(int64(params.IdleTimeoutOverride))
+	session.detectLinks.Store(params.DetectLinks)
+	session.clearBeforeSend.Store(params.ClearBeforeSend)
+	session.readOnly = params.ReadOnly
+	session.trackOnly = params.TrackOnly
+	session.source = params.Source
+	if session.source == "" {
+		session.source = SessionSourceAPI
+	}
 
 	session.assistantTracker.SetCaptureFunc(session.captureTerminalLines)
 	// Set state change callback for periodic checking
 	session.assistantTracker.SetStateChangeCallback(session.handleStateChangeFromTracker)
+	session.assistantTracker.SetDiagnosticsHook(session.logDetectionRecord)
+	session.assistantTracker.SetDiagnostics(params.AIDetectionDiagnostics)
+	session.rawCapture.setEnabled(params.CaptureRawOutput)
 
 	if session.title == "" {
 		session.title = session.id
@@ -265,6 +620,41 @@ func NewSession(params SessionParams) (*Session, error) {
 	if session.logger == nil {
 		session.logger = utils.Logger()
 	}
+	session.logger = session.wrapSessionLogger(session.logger)
+
+	recorder, err := newEventRecorder(params.RecordEvents, params.RecordEventsDir, session.id)
+	if err != nil {
+		session.logger.Warn("failed to enable terminal event recording",
+			zap.String("sessionId", session.id),
+			zap.Error(err))
+	}
+	session.recorder = recorder
+
+	outputLog, err := newOutputLog(params.OutputLog, session.id, session.logger)
+	if err != nil {
+		session.logger.Warn("failed to enable terminal output log",
+			zap.String("sessionId", session.id),
+			zap.Error(err))
+	}
+	session.outputLog = outputLog
+
+	screenshots, err := newScreenshotter(params.Screenshots, session.id, session.logger)
+	if err != nil {
+		session.logger.Warn("failed to enable terminal screenshots",
+			zap.String("sessionId", session.id),
+			zap.Error(err))
+	}
+	session.screenshots = screenshots
+	session.scrollbackSpill = newScrollbackSpill(params.ScrollbackSpill, session.id, session.logger)
+	session.scrollbackCollapse = params.ScrollbackCollapse
+	session.binaryGuard = newBinaryGuard(params.BinaryOutput)
+	if validNice(params.Nice) {
+		session.nice = params.Nice
+	} else {
+		session.logger.Warn("ignoring invalid terminal nice value",
+			zap.String("sessionId", session.id),
+			zap.Int("nice", params.Nice))
+	}
 
 	session.status.Store(SessionStatusStarting)
 	session.err.Store(sessionError{})
@@ -279,11 +669,40 @@ func (s *Session) Start(ctx context.Context) error {
 		ctx = context.Background()
 	}
 
+	s.mu.Lock()
+	s.startCtx = ctx
+	if s.maxLifetime > 0 {
+		s.deadline = time.Now().Add(s.maxLifetime)
+	}
+	s.mu.Unlock()
+
+	s.assistantOutputCh = make(chan []byte, assistantOutputBufferLen)
+	if err := s.launch(SessionStatusStarting); err != nil {
+		s.setStatus(SessionStatusError)
+		return err
+	}
+	// 以下协程服务整个会话，跨重启保留，Close 后退出
+	go s.monitorMetadata(ctx)
+	go s.processAssistantOutput(ctx)
+	if s.maxLifetime > 0 {
+		go s.watchLifetime(ctx)
+	}
+	go s.runScreenshots(ctx)
+	return nil
+}
+
+// launch starts the shell process on a new PTY and the goroutines serving it.
+// Restarts call it again with the same session state; from is the status the
+// session must still be in, so a concurrent Close is never overwritten.
+func (s *Session) launch(from SessionStatus) error {
+	s.mu.RLock()
+	ctx := s.startCtx
 	rows := s.rows
+	cols := s.cols
+	s.mu.RUnlock()
 	if rows <= 0 {
 		rows = 24
 	}
-	cols := s.cols
 	if cols <= 0 {
 		cols = 80
 	}
@@ -297,33 +716,42 @@ func (s *Session) Start(ctx context.Context) error {
 	cmd := exec.CommandContext(sessionCtx, s.command[0], s.command[1:]...)
 	cmd.Dir = s.workingDir
 
+	term := s.term
+	if term == "" {
+		term = DefaultTermType
+	}
 	env := append([]string{}, s.env...)
-	env = append(env, "TERM=xterm-256color")
+	env = append(env, "TERM="+term)
 	cmd.Env = append(os.Environ(), env...)
 
 	if err := ptyDevice.Start(cmd); err != nil {
 		cancel()
 		_ = ptyDevice.Close()
-		s.setStatus(SessionStatusError)
 		return err
 	}
 
 	s.mu.Lock()
+	if !s.status.CompareAndSwap(from, SessionStatusRunning) {
+		// 启动期间会话已被关闭
+		s.mu.Unlock()
+		cancel()
+		_ = cmd.Process.Kill()
+		_ = ptyDevice.Close()
+		return ErrSessionNotRunning
+	}
 	s.cmd = cmd
 	s.pty = ptyDevice
 	s.cancel = cancel
 	s.rows = rows
 	s.cols = cols
+	s.restart.launchedAt = time.Now()
 	s.mu.Unlock()
 
-	s.setStatus(SessionStatusRunning)
-
-	s.assistantOutputCh = make(chan []byte, assistantOutputBufferLen)
-
-	go s.wait(sessionCtx)
+	s.applyPriority(cmd.Process.Pid)
+	go s.wait(sessionCtx, cmd)
 	go s.consumePTY(sessionCtx)
-	go s.monitorMetadata(sessionCtx)
-	go s.processAssistantOutput(sessionCtx)
+	// 输出读取已启动，回显不会把 PTY 写满
+	s.flushPendingInput(ptyDevice)
 
 	return nil
 }
@@ -335,6 +763,17 @@ func (s *Session) consumePTY(ctx context.Context) {
 	}
 
 	buffer := make([]byte, 32*1024)
+	// 输出高峰时合并短时间内的多个分片，减少广播的小消息
+	output := newOutputCoalescer(outputCoalesceWindow, s.emitOutput)
+	s.output.Store(output)
+	defer func() {
+		// 会话被关闭时订阅者正在移除，剩余输出不再广播；进程自行退出时 wait 已在关闭前读完并发出最后的输出
+		if ctx.Err() != nil {
+			output.stop()
+			return
+		}
+		output.flush()
+	}()
 
 	for {
 		select {
@@ -346,19 +785,54 @@ func (s *Session) consumePTY(ctx context.Context) {
 		n, err := reader.Read(buffer)
 		if n > 0 {
 			s.Touch()
-			normalized := s.NormalizeOutput(buffer[:n])
-			if len(normalized) > 0 {
-				s.appendScrollback(normalized)
-				s.broadcast(StreamEvent{Type: StreamEventData, Data: normalized})
-				s.enqueueAssistantOutput(normalized)
+			s.stats.lastOutputAt.Store(time.Now().UnixNano())
+			s.rawCapture.write(buffer[:n])
+			normalized, truncated := s.binaryGuard.filter(s.NormalizeOutput(buffer[:n]))
+			if truncated {
+				s.logger.Info("binary terminal output truncated",
+					zap.String("sessionId", s.id),
+					zap.String("projectId", s.projectID))
+			}
+			if s.debugEnabled() {
+				s.logger.Debug("terminal output read",
+					zap.String("sessionId", s.id),
+					zap.Int("bytes", n),
+					zap.Int("normalizedBytes", len(normalized)))
 			}
+			output.write(normalized)
+			s.checkEchoMode()
 		}
 		if err != nil {
+			if s.debugEnabled() {
+				s.logger.Debug("terminal output reader stopped",
+					zap.String("sessionId", s.id),
+					zap.Error(err))
+			}
 			return
 		}
 	}
 }
 
+// emitOutput stores a chunk of output in the scrollback, feeds the observers
+// and broadcasts it as one data event.
+func (s *Session) emitOutput(normalized []byte) {
+	s.stats.outputLines.Add(int64(bytes.Count(normalized, []byte{'\n'})))
+	seq := s.appendScrollback(normalized)
+	s.appendLinkBuffer(normalized)
+	s.appendHighlightBuffer(normalized)
+	s.pager.observe(normalized)
+	s.cwd.observe(normalized)
+	s.autoWrap.observe(normalized)
+	if title, ok := s.oscTitle.observe(normalized); ok {
+		s.applyOSCTitle(title)
+	}
+	s.outputLog.Write(normalized)
+	s.outputSink.Load().write(normalized)
+	s.screenshots.Write(normalized)
+	s.broadcast(StreamEvent{Type: StreamEventData, Data: normalized, Seq: seq})
+	s.enqueueAssistantOutput(normalized)
+}
+
 func (s *Session) monitorMetadata(ctx context.Context) {
 	ticker := time.NewTicker(2 * time.Second)
 	defer ticker.Stop()
@@ -367,12 +841,52 @@ func (s *Session) monitorMetadata(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case <-s.closed:
+			return
 		case <-ticker.C:
+			// 暂停期间进程树不会变化，跳过轮询
+			if s.Status() == SessionStatusPaused {
+				continue
+			}
 			s.checkAndBroadcastMetadata()
+			s.checkAndBroadcastLinks()
+			s.checkAndBroadcastHighlights()
 		}
 	}
 }
 
+// watchLifetime 在到达最大运行时长时通知订阅者，并按配置关闭会话
+func (s *Session) watchLifetime(ctx context.Context) {
+	deadline := s.Deadline()
+	if deadline.IsZero() {
+		return
+	}
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return
+	case <-s.closed:
+		return
+	case <-timer.C:
+	}
+
+	s.logger.Warn("terminal session exceeded max lifetime",
+		zap.String("sessionId", s.id),
+		zap.String("projectId", s.projectID),
+		zap.Duration("maxLifetime", s.maxLifetime),
+		zap.Bool("autoClose", s.closeOnMaxLifetime),
+	)
+	s.broadcast(StreamEvent{
+		Type: StreamEventLifetimeExceeded,
+		Data: []byte(fmt.Sprintf("session exceeded max lifetime of %s", s.maxLifetime)),
+	})
+	if s.closeOnMaxLifetime {
+		_ = s.Close()
+	}
+}
+
 func (s *Session) enqueueAssistantOutput(chunk []byte) {
 	if len(chunk) == 0 {
 		return
@@ -388,6 +902,7 @@ func (s *Session) enqueueAssistantOutput(chunk []byte) {
 	case ch <- chunk:
 	default:
 		// Drop if processor is backed up to avoid blocking PTY reader
+		s.stats.assistantDrops.Add(1)
 	}
 }
 
@@ -401,6 +916,8 @@ func (s *Session) processAssistantOutput(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
+		case <-s.closed:
+			return
 		case chunk := <-ch:
 			s.handleAssistantOutput(chunk)
 		}
@@ -408,21 +925,34 @@ func (s *Session) processAssistantOutput(ctx context.Context) {
 }
 
 func (s *Session) checkAndBroadcastMetadata() {
-	pid := s.getPID()
+	pid := s.processPID()
 	if pid <= 0 {
 		return
 	}
 
+	rawBusy := process.IsProcessBusy(pid)
+	busy := s.confirmProcessBusy(rawBusy)
 	metadata := &SessionMetadata{
 		ProcessPID:         pid,
-		ProcessStatus:      process.GetProcessStatus(pid),
-		ProcessHasChildren: process.IsProcessBusy(pid),
+		ProcessStatus:      debouncedProcessStatus(process.GetProcessStatus(pid), busy),
+		ProcessHasChildren: busy,
 		TaskID:             s.TaskID(),
 		Title:              s.Title(),
 	}
 
 	tracker := s.assistantTracker
-	if metadata.ProcessHasChildren {
+	if busy && !rawBusy {
+		// 等待 idle 确认期间沿用上一次的前台命令，避免抖动时 AI 助手被反复 detach
+		s.metaMu.RLock()
+		if last := s.lastMetadata; last != nil {
+			metadata.RunningCommand = last.RunningCommand
+			if last.AIAssistant != nil {
+				info := *last.AIAssistant
+				metadata.AIAssistant = &info
+			}
+		}
+		s.metaMu.RUnlock()
+	} else if metadata.ProcessHasChildren {
 		if cmd := process.GetForegroundCommand(pid); cmd != "" {
 			metadata.RunningCommand = cmd
 
@@ -435,6 +965,15 @@ func (s *Session) checkAndBroadcastMetadata() {
 	} else if tracker != nil {
 		tracker.Deactivate()
 	}
+	s.applyLongTask(metadata, time.Now())
+	metadata.EchoOff = s.echoOff.Load()
+	metadata.WaitingPager = metadata.AIAssistant == nil && IsPagerCommand(metadata.RunningCommand) && s.pager.waiting()
+	if metadata.AIAssistant != nil {
+		metadata.Todos = s.AssistantTodos()
+		if percent, ok := s.AssistantContextLeft(); ok {
+			metadata.ContextLeftPercent = &percent
+		}
+	}
 
 	// Check if metadata changed
 	s.metaMu.RLock()
@@ -452,6 +991,44 @@ func (s *Session) checkAndBroadcastMetadata() {
 			Metadata: metadata,
 		})
 	}
+
+	// 校准通过状态回调广播，放在本次元数据发送之后
+	if rawBusy && metadata.AIAssistant != nil {
+		s.calibrateAssistantState(pid, time.Now())
+	}
+}
+
+// confirmProcessBusy 对进程 busy/idle 观测去抖：首次观测直接采用，之后需连续
+// processBusyConfirmations 次观测到相反值才切换。
+func (s *Session) confirmProcessBusy(raw bool) bool {
+	s.metaMu.Lock()
+	defer s.metaMu.Unlock()
+
+	if !s.busyKnown {
+		s.busyKnown = true
+		s.busyConfirmed = raw
+		return raw
+	}
+	if raw == s.busyConfirmed {
+		s.busyFlipHits = 0
+		return s.busyConfirmed
+	}
+	s.busyFlipHits++
+	if s.busyFlipHits >= processBusyConfirmations {
+		s.busyConfirmed = raw
+		s.busyFlipHits = 0
+	}
+	return s.busyConfirmed
+}
+
+func debouncedProcessStatus(status string, busy bool) string {
+	if status != "busy" && status != "idle" {
+		return status
+	}
+	if busy {
+		return "busy"
+	}
+	return "idle"
 }
 
 func (s *Session) metadataChanged(old, new *SessionMetadata) bool {
@@ -467,7 +1044,12 @@ func (s *Session) metadataChanged(old, new *SessionMetadata) bool {
 		old.ProcessStatus != new.ProcessStatus ||
 		old.ProcessHasChildren != new.ProcessHasChildren ||
 		old.RunningCommand != new.RunningCommand ||
-		old.TaskID != new.TaskID {
+		old.TaskID != new.TaskID ||
+		old.TaskType != new.TaskType ||
+		old.EchoOff != new.EchoOff ||
+		old.WaitingPager != new.WaitingPager ||
+		!slices.Equal(old.Todos, new.Todos) ||
+		!equalIntPtr(old.ContextLeftPercent, new.ContextLeftPercent) {
 		return true
 	}
 
@@ -480,6 +1062,9 @@ func (s *Session) metadataChanged(old, new *SessionMetadata) bool {
 			old.AIAssistant.DisplayName != new.AIAssistant.DisplayName ||
 			old.AIAssistant.Command != new.AIAssistant.Command ||
 			old.AIAssistant.State != new.AIAssistant.State ||
+			old.AIAssistant.StateConfidence != new.AIAssistant.StateConfidence ||
+			old.AIAssistant.Model != new.AIAssistant.Model ||
+			old.AIAssistant.CliVersion != new.AIAssistant.CliVersion ||
 			!old.AIAssistant.StateUpdatedAt.Equal(new.AIAssistant.StateUpdatedAt) {
 			return true
 		}
@@ -488,6 +1073,13 @@ func (s *Session) metadataChanged(old, new *SessionMetadata) bool {
 	return false
 }
 
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
 // Reader exposes the PTY reader interface.
 func (s *Session) Reader() io.Reader {
 	s.mu.RLock()
@@ -495,23 +1087,138 @@ func (s *Session) Reader() io.Reader {
 	return s.pty
 }
 
-// Writer exposes the PTY writer interface.
-func (s *Session) Writer() io.Writer {
+// ptyWriter returns the PTY for writing. Writes must hold writeMu, so callers
+// outside Write/WriteInput/flushPendingInput should use those instead.
+func (s *Session) ptyWriter() io.Writer {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.pty
 }
 
-// Write writes bytes to the PTY, updating last activity timestamp.
+// Write writes bytes to the PTY, updating last activity timestamp. The whole
+// payload is written before any concurrent write starts, even when the PTY
+// accepts it in several short writes. Only input written here, the interactive
+// keystrokes, is kept in the input history.
 func (s *Session) Write(p []byte) (int, error) {
-	writer := s.Writer()
+	return s.writeInteractive(p, false)
+}
+
+func (s *Session) writeInteractive(p []byte, clear bool) (int, error) {
+	if s.readOnly {
+		return 0, ErrSessionReadOnly
+	}
+	s.inputHistory.record(p, s.inputHidden.Load(), time.Now())
+	submitted := isSubmittedInput(p)
+	if submitted {
+		s.resetAssistantStability()
+	}
+	if s.bufferPendingInput(p) {
+		return len(p), nil
+	}
+	writer := s.ptyWriter()
 	if writer == nil {
 		return 0, io.EOF
 	}
 
 	payload := s.prepareInput(p)
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
 	s.Touch()
-	return writer.Write(payload)
+	if clear && submitted {
+		if err := s.writePayloadLocked(writer, clearScreenKey); err != nil {
+			return 0, err
+		}
+	}
+	if err := s.writePayloadLocked(writer, payload); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// WriteInput writes a complete payload to the PTY while holding the write lock,
+// so large inputs are not interleaved with interactive keystrokes.
+func (s *Session) WriteInput(data []byte) error {
+	if s.readOnly {
+		return ErrSessionReadOnly
+	}
+	if s.bufferPendingInput(data) {
+		return nil
+	}
+	writer := s.ptyWriter()
+	if writer == nil {
+		return io.EOF
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.writePayloadLocked(writer, s.prepareInput(data))
+}
+
+func (s *Session) writePayloadLocked(writer io.Writer, payload []byte) error {
+	for len(payload) > 0 {
+		n, err := writer.Write(payload)
+		if err != nil {
+			return err
+		}
+		if n <= 0 {
+			return io.ErrShortWrite
+		}
+		payload = payload[n:]
+		s.Touch()
+	}
+	return nil
+}
+
+// bufferPendingInput queues input that arrives while the session is still starting.
+// It returns false once the PTY is ready so the caller writes directly. Input beyond
+// pendingInputMaxBytes is dropped with a warning.
+func (s *Session) bufferPendingInput(data []byte) bool {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	if s.inputReady {
+		return false
+	}
+	if status := s.Status(); status != SessionStatusStarting && status != SessionStatusRunning && status != SessionStatusRestarting {
+		return false
+	}
+	if len(data) == 0 {
+		return true
+	}
+	if s.pendingSize+len(data) > pendingInputMaxBytes {
+		s.logger.Warn("terminal input dropped before session ready",
+			zap.String("sessionId", s.id),
+			zap.Int("bytes", len(data)),
+			zap.Int("buffered", s.pendingSize))
+		return true
+	}
+	s.pendingInput = append(s.pendingInput, cloneBytes(data))
+	s.pendingSize += len(data)
+	return true
+}
+
+// flushPendingInput writes buffered input to the PTY in order and switches writes
+// to the PTY. pendingMu is held throughout so later input cannot overtake it.
+func (s *Session) flushPendingInput(writer io.Writer) {
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	pending := s.pendingInput
+	s.pendingInput = nil
+	s.pendingSize = 0
+	s.inputReady = true
+	if len(pending) == 0 {
+		return
+	}
+
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	for _, data := range pending {
+		if err := s.writePayloadLocked(writer, s.prepareInput(data)); err != nil {
+			s.logger.Warn("failed to flush buffered terminal input",
+				zap.String("sessionId", s.id),
+				zap.Error(err))
+			return
+		}
+	}
 }
 
 // Resize updates the PTY window size.
@@ -548,8 +1255,11 @@ func (s *Session) Resize(cols, rows int) error {
 	return nil
 }
 
-// Subscribe registers a stream subscriber that receives PTY output events.
-func (s *Session) Subscribe(ctx context.Context) (*SessionStream, error) {
+// Subscribe registers a stream subscriber that receives PTY output events. When
+// types is given only those events are delivered, except exit which always is.
+// Track-only sessions deliver data events only to subscribers that list
+// StreamEventData explicitly.
+func (s *Session) Subscribe(ctx context.Context, types ...StreamEventType) (*SessionStream, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -559,6 +1269,12 @@ func (s *Session) Subscribe(ctx context.Context) (*SessionStream, error) {
 		ch:     make(chan StreamEvent, subscriberBufferSize),
 		cancel: cancel,
 	}
+	if len(types) > 0 {
+		subscriber.types = make(map[StreamEventType]struct{}, len(types))
+		for _, eventType := range types {
+			subscriber.types[eventType] = struct{}{}
+		}
+	}
 
 	s.subMu.Lock()
 	if s.subscribers == nil {
@@ -593,16 +1309,159 @@ func (s *Session) Scrollback() [][]byte {
 	return result
 }
 
+// ScrollbackChunk is a scrollback fragment with its monotonically increasing sequence number.
+type ScrollbackChunk struct {
+	Seq       int64     `json:"seq"`
+	Data      []byte    `json:"data"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// ScrollbackSince returns the retained chunks with a sequence number greater than
+// seq, together with the latest sequence number which serves as the next cursor.
+// Only in-memory chunks are returned, so reconnect replays stay bounded.
+func (s *Session) ScrollbackSince(seq int64) ([]ScrollbackChunk, int64) {
+	s.scrollMu.RLock()
+	defer s.scrollMu.RUnlock()
+	latest := s.scrollbackBaseSeq + int64(len(s.scrollback)) - 1
+	start := seq - s.scrollbackBaseSeq + 1
+	if start < 0 {
+		start = 0
+	}
+	return s.scrollbackChunksLocked(int(min(start, int64(len(s.scrollback)))), len(s.scrollback)), latest
+}
+
+// ScrollbackResume returns the chunks after seq for a reconnecting client and
+// reports whether some chunks following seq were already trimmed, in which
+// case the client misses output and should redraw from the returned chunks.
+func (s *Session) ScrollbackResume(seq int64) ([]ScrollbackChunk, bool) {
+	s.scrollMu.RLock()
+	gap := seq+1 < s.scrollbackBaseSeq
+	s.scrollMu.RUnlock()
+	chunks, _ := s.ScrollbackSince(seq)
+	return chunks, gap
+}
+
+// ScrollbackLatestSeq returns the sequence number of the newest retained chunk.
+func (s *Session) ScrollbackLatestSeq() int64 {
+	s.scrollMu.RLock()
+	defer s.scrollMu.RUnlock()
+	return s.scrollbackBaseSeq + int64(len(s.scrollback)) - 1
+}
+
+// ScrollbackBefore returns up to limit chunks preceding seq, used to page history
+// backwards; seq <= 0 returns the most recent chunks. With a positive limit,
+// chunks spilled to disk fill up the page once the in-memory ones run out.
+func (s *Session) ScrollbackBefore(seq int64, limit int) []ScrollbackChunk {
+	s.scrollMu.RLock()
+	defer s.scrollMu.RUnlock()
+	end := len(s.scrollback)
+	if seq > 0 {
+		end = int(max(min(seq-s.scrollbackBaseSeq, int64(end)), 0))
+	}
+	start := 0
+	if limit > 0 && end-limit > 0 {
+		start = end - limit
+	}
+	chunks := s.scrollbackChunksLocked(start, end)
+	// 内存中的分片不够时从磁盘补齐更早的历史，不限数量时只返回内存部分
+	if start == 0 && limit > 0 && len(chunks) < limit && s.scrollbackSpill != nil {
+		before := s.scrollbackBaseSeq
+		if seq > 0 && seq < before {
+			before = seq
+		}
+		chunks = append(s.scrollbackSpill.chunksBefore(before, limit-len(chunks)), chunks...)
+	}
+	return chunks
+}
+
+func (s *Session) scrollbackChunksLocked(start, end int) []ScrollbackChunk {
+	if start >= end {
+		return nil
+	}
+	chunks := make([]ScrollbackChunk, 0, end-start)
+	for i := start; i < end; i++ {
+		chunk := ScrollbackChunk{
+			Seq:  s.scrollbackBaseSeq + int64(i),
+			Data: cloneBytes(s.scrollback[i]),
+		}
+		if i < len(s.scrollbackTimestamps) {
+			chunk.Timestamp = s.scrollbackTimestamps[i]
+		}
+		chunks = append(chunks, chunk)
+	}
+	return chunks
+}
+
+// ScrollbackFrame is the terminal output replayed up to a given scrollback chunk.
+type ScrollbackFrame struct {
+	Data      []byte
+	Index     int       // 1-based index of the last included chunk, 0 when nothing is included
+	Total     int       // number of chunks currently retained
+	Timestamp time.Time // timestamp of the last included chunk
+}
+
+// ScrollbackFrameAt replays the first index chunks of the scrollback. Index is
+// clamped to the retained range; a non-positive index yields an empty frame.
+func (s *Session) ScrollbackFrameAt(index int) *ScrollbackFrame {
+	s.scrollMu.RLock()
+	defer s.scrollMu.RUnlock()
+	return s.scrollbackFrameLocked(index)
+}
+
+// ScrollbackFrameUntil replays every chunk recorded at or before upto.
+func (s *Session) ScrollbackFrameUntil(upto time.Time) *ScrollbackFrame {
+	s.scrollMu.RLock()
+	defer s.scrollMu.RUnlock()
+	index := 0
+	for i, ts := range s.scrollbackTimestamps {
+		if ts.After(upto) {
+			break
+		}
+		index = i + 1
+	}
+	return s.scrollbackFrameLocked(index)
+}
+
+func (s *Session) scrollbackFrameLocked(index int) *ScrollbackFrame {
+	total := len(s.scrollback)
+	if index > total {
+		index = total
+	}
+	if index < 0 {
+		index = 0
+	}
+	frame := &ScrollbackFrame{Index: index, Total: total}
+	if index == 0 {
+		return frame
+	}
+	size := 0
+	for _, chunk := range s.scrollback[:index] {
+		size += len(chunk)
+	}
+	frame.Data = make([]byte, 0, size)
+	for _, chunk := range s.scrollback[:index] {
+		frame.Data = append(frame.Data, chunk...)
+	}
+	if index <= len(s.scrollbackTimestamps) {
+		frame.Timestamp = s.scrollbackTimestamps[index-1]
+	}
+	return frame
+}
+
 // Close terminates the session and underlying process.
 func (s *Session) Close() error {
 	var closeErr error
 	s.closeOnce.Do(func() {
+		if s.Status() == SessionStatusPaused {
+			// 先恢复被冻结的子进程，否则 shell 被杀掉后它们会一直停在后台
+			_ = signalProcessTree(s.processPID(), false)
+		}
 		s.setStatus(SessionStatusClosed)
 
+		s.mu.Lock()
 		if s.cancel != nil {
 			s.cancel()
 		}
-		s.mu.Lock()
 		if s.cmd != nil && s.cmd.Process != nil {
 			_ = s.cmd.Process.Kill()
 		}
@@ -613,10 +1472,25 @@ func (s *Session) Close() error {
 		s.mu.Unlock()
 		close(s.closed)
 		s.notifyExit(s.Err())
+		s.recorder.Close()
+		s.outputLog.Close()
+		s.RemoveOutputSink()
+		s.scrollbackSpill.close()
 	})
 	return closeErr
 }
 
+// RecordsEvents reports whether stream events are recorded for export.
+func (s *Session) RecordsEvents() bool {
+	return s.recorder != nil
+}
+
+// ExportEvents writes the recorded stream events as JSONL to w.
+func (s *Session) ExportEvents(w io.Writer) error {
+	_, err := s.recorder.WriteTo(w)
+	return err
+}
+
 // Closed channel closes once the session fully terminates.
 func (s *Session) Closed() <-chan struct{} {
 	return s.closed
@@ -667,6 +1541,11 @@ func (s *Session) ClearTaskAssociation() bool {
 	return true
 }
 
+// QuotaKey returns the resource quota dimension of the session, empty if none.
+func (s *Session) QuotaKey() string {
+	return s.quotaKey
+}
+
 // WorktreeID returns the associated worktree identifier.
 func (s *Session) WorktreeID() string {
 	return s.worktreeID
@@ -674,9 +1553,35 @@ func (s *Session) WorktreeID() string {
 
 // WorkingDir exposes the shell working directory.
 func (s *Session) WorkingDir() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 	return s.workingDir
 }
 
+// RelocateWorktree rewrites the recorded paths after the worktree moved from
+// oldRoot to newRoot. The running shell stays in its old directory, so clients are
+// notified with StreamEventWorktreeMoved and may open a new session.
+func (s *Session) RelocateWorktree(oldRoot, newRoot string) {
+	s.mu.Lock()
+	if pathWithinRoot(oldRoot, s.workingDir) {
+		if rel, err := filepath.Rel(filepath.Clean(oldRoot), filepath.Clean(s.workingDir)); err == nil {
+			s.workingDir = filepath.Join(newRoot, rel)
+		}
+	}
+	if s.worktreeRoot != "" {
+		s.worktreeRoot = newRoot
+	}
+	workingDir := s.workingDir
+	s.mu.Unlock()
+
+	s.logger.Info("terminal session worktree moved",
+		zap.String("sessionId", s.id),
+		zap.String("from", oldRoot),
+		zap.String("to", newRoot),
+	)
+	s.broadcast(StreamEvent{Type: StreamEventWorktreeMoved, Data: []byte(workingDir)})
+}
+
 // Title returns the display name.
 func (s *Session) Title() string {
 	s.mu.RLock()
@@ -684,7 +1589,8 @@ func (s *Session) Title() string {
 	return s.title
 }
 
-// UpdateTitle mutates the tab label in a threadsafe manner.
+// UpdateTitle mutates the tab label in a threadsafe manner. It is used for
+// manual renames, after which output title rules no longer apply.
 func (s *Session) UpdateTitle(title string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -692,12 +1598,27 @@ func (s *Session) UpdateTitle(title string) error {
 		return ErrSessionTitleLocked
 	}
 	s.title = title
+	s.manualTitle = true
 	if s.associatedTaskID != "" && s.lockedTitle == "" {
 		s.lockedTitle = title
 	}
 	return nil
 }
 
+// OrderIndex returns the tab position of the session.
+func (s *Session) OrderIndex() float64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.orderIndex
+}
+
+// SetOrderIndex updates the tab position of the session.
+func (s *Session) SetOrderIndex(orderIndex float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.orderIndex = orderIndex
+}
+
 // SetRenameTitleEachCommand toggles whether AI input renames should run on each instruction.
 func (s *Session) SetRenameTitleEachCommand(enabled bool) {
 	s.renameTitleEachCommand.Store(enabled)
@@ -708,6 +1629,43 @@ func (s *Session) SetAutoCreateTaskOnStartWork(enabled bool) {
 	s.autoCreateTaskOnStartWork.Store(enabled)
 }
 
+// IdleTimeoutOverride returns the per-session idle timeout. Zero means the
+// global timeout applies and a negative value disables idle cleanup.
+func (s *Session) IdleTimeoutOverride() time.Duration {
+	return time.Duration(s.idleTimeoutOverride.Load())
+}
+
+// MaxLifetime returns the hard runtime limit of the session, zero means unlimited.
+func (s *Session) MaxLifetime() time.Duration {
+	return s.maxLifetime
+}
+
+// Deadline returns when the session will hit its max lifetime, zero if unlimited or not started.
+func (s *Session) Deadline() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.deadline
+}
+
+// SetIdleTimeoutOverride replaces the per-session idle timeout.
+func (s *Session) SetIdleTimeoutOverride(timeout time.Duration) {
+	s.idleTimeoutOverride.Store(int64(timeout))
+}
+
+// EffectiveIdleTimeout resolves the timeout used for idle cleanup against the
+// global value. A non-positive result means the session never expires.
+func (s *Session) EffectiveIdleTimeout(global time.Duration) time.Duration {
+	override := s.IdleTimeoutOverride()
+	switch {
+	case override < 0:
+		return 0
+	case override > 0:
+		return override
+	default:
+		return global
+	}
+}
+
 // LastRecentInput returns the last user input captured by the AI assistant.
 func (s *Session) LastRecentInput() string {
 	s.mu.RLock()
@@ -738,30 +1696,63 @@ func (s *Session) Touch() {
 	s.lastActive.Store(time.Now().UnixNano())
 }
 
+// SnapshotOptions skips the expensive parts of a snapshot when the caller does
+// not need them.
+type SnapshotOptions struct {
+	// SkipProcess 跳过进程状态、前台命令与 AI 助手识别，这些字段保持零值（PID 仍会填充）
+	SkipProcess bool
+	// SkipTokenUsage 跳过 token 用量汇总
+	SkipTokenUsage bool
+}
+
 // Snapshot copies current state for API responses.
 func (s *Session) Snapshot() SessionSnapshot {
+	return s.SnapshotWith(SnapshotOptions{})
+}
+
+// SnapshotWith copies current state, leaving out the parts opts skips.
+func (s *Session) SnapshotWith(opts SnapshotOptions) SessionSnapshot {
 	s.mu.RLock()
 	snapshot := SessionSnapshot{
 		ID:         s.id,
 		ProjectID:  s.projectID,
 		WorktreeID: s.worktreeID,
 		WorkingDir: s.workingDir,
+		CurrentDir: s.workingDir,
 		Title:      s.title,
+		OrderIndex: s.orderIndex,
 		CreatedAt:  s.createdAt,
 		LastActive: s.LastActive(),
 		Status:     s.Status(),
 		Rows:       s.rows,
 		Cols:       s.cols,
-		Encoding:   s.encName,
+		Encoding:   s.Encoding(),
+		Term:       s.term,
+		ShellName:  s.shellName,
+
+		IdleTimeoutOverride: s.IdleTimeoutOverride(),
+		MaxLifetime:         s.maxLifetime,
+		Deadline:            s.deadline,
+		RestartPolicy:       s.restart.policy,
+		Restarts:            s.restart.total.Load(),
+		ReadOnly:            s.readOnly,
+		TrackOnly:           s.trackOnly,
+		LogLevel:            s.LogLevel(),
+		Source:              s.source,
 	}
 	pid := s.getPID()
 	rows := s.rows
 	cols := s.cols
 	s.mu.RUnlock()
+	if dir := s.cwd.current(); dir != "" {
+		snapshot.CurrentDir = dir
+	}
 
 	// Get process information
 	if pid > 0 {
 		snapshot.ProcessPID = pid
+	}
+	if pid > 0 && !opts.SkipProcess {
 		snapshot.ProcessStatus = process.GetProcessStatus(pid)
 		snapshot.ProcessHasChildren = process.IsProcessBusy(pid)
 
@@ -775,11 +1766,32 @@ func (s *Session) Snapshot() SessionSnapshot {
 	}
 
 	snapshot.TaskID = s.TaskID()
+	snapshot.TurnCount, snapshot.InterruptedTurns = s.AssistantTurnCount()
+	snapshot.Annotations = s.Annotations()
+	if !opts.SkipTokenUsage {
+		snapshot.TokenUsage = s.AssistantTokenUsage()
+	}
+
+	s.metaMu.RLock()
+	if last := s.lastMetadata; last != nil && last.TaskType != "" {
+		snapshot.TaskType = last.TaskType
+		snapshot.TaskStartedAt = last.TaskStartedAt
+	}
+	s.metaMu.RUnlock()
 
 	return snapshot
 }
 
+// processPID returns the PID of the current shell process; it changes when the
+// session restarts.
+func (s *Session) processPID() int32 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.getPID()
+}
+
 // getPID returns the shell process PID, or 0 if not available.
+// It requires s.mu to be held.
 func (s *Session) getPID() int32 {
 	if s.cmd != nil && s.cmd.Process != nil {
 		return int32(s.cmd.Process.Pid)
@@ -791,6 +1803,54 @@ func (s *Session) setStatus(status SessionStatus) {
 	s.status.Store(status)
 }
 
+// Pause freezes the whole process tree of the session with SIGSTOP. Pausing an
+// already paused session is a no-op.
+func (s *Session) Pause() error {
+	if s.Status() == SessionStatusPaused {
+		return nil
+	}
+	if !s.status.CompareAndSwap(SessionStatusRunning, SessionStatusPaused) {
+		return ErrSessionNotRunning
+	}
+	if err := signalProcessTree(s.processPID(), true); err != nil {
+		// 部分进程可能已被冻结，尽量恢复后再报告错误
+		_ = signalProcessTree(s.processPID(), false)
+		s.status.CompareAndSwap(SessionStatusPaused, SessionStatusRunning)
+		return err
+	}
+	s.logger.Info("terminal session paused", zap.String("sessionId", s.id))
+	return nil
+}
+
+// Resume continues a paused session with SIGCONT. Resuming a running session is a no-op.
+func (s *Session) Resume() error {
+	switch s.Status() {
+	case SessionStatusRunning:
+		return nil
+	case SessionStatusPaused:
+	default:
+		return ErrSessionNotRunning
+	}
+	if err := signalProcessTree(s.processPID(), false); err != nil {
+		return err
+	}
+	s.status.CompareAndSwap(SessionStatusPaused, SessionStatusRunning)
+	s.Touch()
+	s.logger.Info("terminal session resumed", zap.String("sessionId", s.id))
+	return nil
+}
+
+// ReadOnly reports whether the session rejects input.
+func (s *Session) ReadOnly() bool {
+	return s.readOnly
+}
+
+// TrackOnly reports whether the session only tracks the assistant state and
+// keeps no scrollback.
+func (s *Session) TrackOnly() bool {
+	return s.trackOnly
+}
+
 // Err returns the last process error, if any.
 func (s *Session) Err() error {
 	if value, ok := s.err.Load().(sessionError); ok {
@@ -800,36 +1860,69 @@ func (s *Session) Err() error {
 }
 
 // NormalizeOutput converts PTY output to UTF-8 based on the configured encoding.
+// The decoder state is kept across calls, so a multibyte character split between
+// two chunks is held back and decoded together with the next chunk.
 func (s *Session) NormalizeOutput(data []byte) []byte {
 	if len(data) == 0 {
 		return nil
 	}
+
+	s.decodeMu.Lock()
+	defer s.decodeMu.Unlock()
 	if s.encoding == nil || s.encName == "utf-8" {
 		return cloneBytes(data)
 	}
-	decoded, _, err := transform.Bytes(s.encoding.NewDecoder(), data)
-	if err != nil {
-		return cloneBytes(data)
+	if s.decoder == nil {
+		s.decoder = s.encoding.NewDecoder()
 	}
-	return decoded
+	var dst []byte
+	dst, s.decodeTail = decodeChunk(s.decoder, s.decodeTail, data)
+	return dst
+}
+
+// decodeChunk decodes tail+data with a streaming decoder. An incomplete
+// multibyte character at the end is returned as the new tail.
+func decodeChunk(decoder transform.Transformer, tail, data []byte) ([]byte, []byte) {
+	src := append(tail, data...)
+	dst := make([]byte, 0, len(src)*2)
+	buf := make([]byte, len(src)*3+utf8.UTFMax)
+	for len(src) > 0 {
+		nDst, nSrc, err := decoder.Transform(buf, src, false)
+		dst = append(dst, buf[:nDst]...)
+		src = src[nSrc:]
+		switch {
+		case err == nil:
+		case errors.Is(err, transform.ErrShortSrc) && len(src) <= maxDecodeTailBytes:
+			return dst, cloneBytes(src)
+		case errors.Is(err, transform.ErrShortDst) && nDst > 0:
+		default:
+			// 无法解码时原样输出剩余字节，并重置解码器避免状态错乱
+			decoder.Reset()
+			return append(dst, src...), nil
+		}
+	}
+	return dst, nil
 }
 
 func (s *Session) prepareInput(data []byte) []byte {
 	if len(data) == 0 {
 		return nil
 	}
-	if s.encoding == nil || s.encName == "utf-8" {
+	s.decodeMu.Lock()
+	enc, encName := s.encoding, s.encName
+	s.decodeMu.Unlock()
+	if enc == nil || encName == "utf-8" {
 		return cloneBytes(data)
 	}
-	encoded, _, err := transform.Bytes(s.encoding.NewEncoder(), data)
+	encoded, _, err := transform.Bytes(enc.NewEncoder(), data)
 	if err != nil {
 		return cloneBytes(data)
 	}
 	return encoded
 }
 
-func (s *Session) wait(ctx context.Context) {
-	err := xpty.WaitProcess(ctx, s.cmd)
+func (s *Session) wait(ctx context.Context, cmd *exec.Cmd) {
+	err := xpty.WaitProcess(ctx, cmd)
 	if err != nil {
 		s.err.Store(sessionError{err: err})
 		s.setStatus(SessionStatusError)
@@ -842,37 +1935,60 @@ func (s *Session) wait(ctx context.Context) {
 			s.logger.Debug("terminal session exited normally")
 		}
 	}
+	s.exited.Store(true)
+	if s.restartAfterExit(ctx, err) {
+		return
+	}
+	// 进程自行退出时先读完 PTY 中剩余的输出再关闭，关闭会取消读取并丢弃缓存的输出
+	if ctx.Err() == nil {
+		s.drainOutput()
+	}
 	_ = s.Close()
 }
 
-func (s *Session) appendScrollback(chunk []byte) {
+// appendScrollback stores chunk and returns its sequence number, 0 when scrollback is disabled.
+func (s *Session) appendScrollback(chunk []byte) int64 {
 	if len(chunk) == 0 || s.scrollbackLimit <= 0 {
-		return
+		return 0
 	}
 	data := cloneBytes(chunk)
 	timestamp := time.Now()
 
 	s.scrollMu.Lock()
+	if s.scrollbackCollapse {
+		s.collapseRedrawLocked(data)
+	}
 	s.scrollback = append(s.scrollback, data)
 	s.scrollbackTimestamps = append(s.scrollbackTimestamps, timestamp)
 	s.scrollbackSize += len(data)
+	s.scrollbackRev++
+	seq := s.scrollbackBaseSeq + int64(len(s.scrollback)) - 1
+	s.trimScrollbackLocked()
+	s.scrollMu.Unlock()
+	return seq
+}
+
+func (s *Session) trimScrollbackLocked() {
 	for s.scrollbackSize > s.scrollbackLimit && len(s.scrollback) > 0 {
+		s.scrollbackSpill.append(s.scrollbackBaseSeq, s.scrollback[0], s.scrollbackTimestamps[0])
 		s.scrollbackSize -= len(s.scrollback[0])
 		s.scrollback = s.scrollback[1:]
 		s.scrollbackTimestamps = s.scrollbackTimestamps[1:]
+		s.scrollbackBaseSeq++
 	}
-	s.scrollMu.Unlock()
 }
 
 // UpdateScrollbackLimit toggles scrollback buffering and trims existing data accordingly.
 func (s *Session) UpdateScrollbackLimit(limit int) {
-	if limit < 0 {
+	if limit < 0 || s.trackOnly {
 		limit = 0
 	}
 
 	s.scrollMu.Lock()
 	s.scrollbackLimit = limit
+	s.scrollbackRev++
 	if limit == 0 {
+		s.scrollbackBaseSeq += int64(len(s.scrollback))
 		s.scrollback = nil
 		s.scrollbackTimestamps = nil
 		s.scrollbackSize = 0
@@ -880,26 +1996,47 @@ func (s *Session) UpdateScrollbackLimit(limit int) {
 		return
 	}
 
-	for s.scrollbackSize > s.scrollbackLimit && len(s.scrollback) > 0 {
-		s.scrollbackSize -= len(s.scrollback[0])
-		s.scrollback = s.scrollback[1:]
-		s.scrollbackTimestamps = s.scrollbackTimestamps[1:]
-	}
+	s.trimScrollbackLocked()
 	s.scrollMu.Unlock()
 }
 
 func (s *Session) broadcast(event StreamEvent) {
-	listeners := s.snapshotSubscribers()
-	for _, sub := range listeners {
+	now := time.Now()
+	s.recorder.Record(event, now)
+	dropped := 0
+	// 持有读锁发送，removeSubscriber 持写锁关闭 channel，二者互斥，避免向已关闭的 channel 发送
+	s.subMu.RLock()
+	listeners := len(s.subscribers)
+	for _, sub := range s.subscribers {
+		if !sub.accepts(event.Type) {
+			continue
+		}
+		if s.trackOnly && event.Type == StreamEventData && len(sub.types) == 0 {
+			continue
+		}
 		select {
 		case sub.ch <- event:
+			sub.markDelivered(now)
 		default:
+			dropped++
+			sub.markFull(now)
 			if s.logger != nil {
 				s.logger.Debug("dropping terminal event for slow subscriber",
 					zap.String("sessionId", s.id))
 			}
 		}
 	}
+	s.subMu.RUnlock()
+	s.stats.recordBroadcast(now, dropped)
+	if s.debugEnabled() {
+		s.logger.Debug("terminal event broadcast",
+			zap.String("sessionId", s.id),
+			zap.String("type", string(event.Type)),
+			zap.Int64("seq", event.Seq),
+			zap.Int("bytes", len(event.Data)),
+			zap.Int("subscribers", listeners),
+			zap.Int("dropped", dropped))
+	}
 }
 
 func (s *Session) snapshotSubscribers() []*sessionSubscriber {
@@ -918,38 +2055,200 @@ func (s *Session) snapshotSubscribers() []*sessionSubscriber {
 func (s *Session) notifyExit(err error) {
 	s.exitOnce.Do(func() {
 		event := StreamEvent{Type: StreamEventExit, Err: err}
-		for _, sub := range s.snapshotSubscribers() {
+		s.recorder.Record(event, time.Now())
+		s.subMu.RLock()
+		cancels := make([]context.CancelFunc, 0, len(s.subscribers))
+		for _, sub := range s.subscribers {
 			select {
 			case sub.ch <- event:
 			default:
 			}
 			if sub.cancel != nil {
-				sub.cancel()
+				cancels = append(cancels, sub.cancel)
 			}
 		}
+		s.subMu.RUnlock()
+		for _, cancel := range cancels {
+			cancel()
+		}
 	})
 }
 
+// removeSubscriber closes the channel while holding subMu, so it never races
+// the sends in broadcast and notifyExit.
 func (s *Session) removeSubscriber(id string) {
 	s.subMu.Lock()
+	defer s.subMu.Unlock()
 	sub, ok := s.subscribers[id]
-	if ok {
-		delete(s.subscribers, id)
-	}
-	s.subMu.Unlock()
-	if ok {
-		sub.once.Do(func() {
-			close(sub.ch)
-		})
+	if !ok {
+		return
 	}
+	delete(s.subscribers, id)
+	sub.once.Do(func() {
+		close(sub.ch)
+	})
 }
 
 func (s *Session) handleAssistantOutput(chunk []byte) {
+	s.checkOutputTriggers(chunk)
+	s.checkTitleRules(chunk)
+	s.checkAlertRules(chunk)
+	if s.assistantBanner != nil {
+		s.assistantBanner.Feed(chunk)
+	}
 	tracker := s.assistantTracker
 	if len(chunk) == 0 || tracker == nil {
 		return
 	}
 	tracker.ProcessChunkInvoke(chunk)
+	if s.debugEnabled() {
+		state, _ := tracker.State()
+		confidence, match := tracker.StateConfidence()
+		s.logger.Debug("assistant tracker processed output",
+			zap.String("sessionId", s.id),
+			zap.Int("bytes", len(chunk)),
+			zap.String("state", string(state)),
+			zap.String("confidence", string(confidence)),
+			zap.String("match", match))
+	}
+}
+
+// AssistantTodos returns the todo list last shown by the AI assistant, nil when none.
+func (s *Session) AssistantTodos() []ai_assistant2.TodoItem {
+	if s.assistantTracker == nil {
+		return nil
+	}
+	return s.assistantTracker.Todos()
+}
+
+// AssistantTurnCount returns the finished and interrupted turns of the current
+// AI assistant run.
+func (s *Session) AssistantTurnCount() (turns, interrupted int) {
+	if s.assistantTracker == nil {
+		return 0, 0
+	}
+	return s.assistantTracker.TurnCount()
+}
+
+// AssistantContextLeft returns the context window percentage left as shown by
+// the AI assistant. ok is false when the assistant does not show it.
+func (s *Session) AssistantContextLeft() (percent int, ok bool) {
+	if s.assistantTracker == nil {
+		return 0, false
+	}
+	return s.assistantTracker.ContextLeft()
+}
+
+// AssistantWorkDuration returns the duration of the last finished assistant turn, 0 when unknown.
+func (s *Session) AssistantWorkDuration() time.Duration {
+	if s.assistantTracker == nil {
+		return 0
+	}
+	return s.assistantTracker.WorkDuration()
+}
+
+// AssistantInterrupted reports whether the last assistant turn was cancelled by the user.
+func (s *Session) AssistantInterrupted() bool {
+	if s.assistantTracker == nil {
+		return false
+	}
+	return s.assistantTracker.Interrupted()
+}
+
+// AssistantTokenUsage returns the token usage accumulated by every assistant run
+// in the session, nil when none was reported.
+func (s *Session) AssistantTokenUsage() *TokenUsage {
+	if s.assistantTracker == nil {
+		return nil
+	}
+	return estimateTokenUsage(s.assistantTracker.TokenUsage(), s.modelPricing)
+}
+
+// SetAIDiagnostics toggles recording of AI assistant detection decisions.
+func (s *Session) SetAIDiagnostics(enabled bool) {
+	s.assistantTracker.SetDiagnostics(enabled)
+}
+
+// AIDiagnostics returns the recent AI assistant detection decisions, empty when diagnostics are off.
+func (s *Session) AIDiagnostics() []ai_assistant2.DetectionRecord {
+	return s.assistantTracker.DiagnosticRecords()
+}
+
+func (s *Session) logDetectionRecord(record ai_assistant2.DetectionRecord) {
+	// 每次检测都会调用，只在 debug 级别输出
+	s.logger.Debug("ai assistant detection",
+		zap.String("sessionId", s.id),
+		zap.String("source", record.Source),
+		zap.String("assistant", string(record.AssistantType)),
+		zap.String("match", record.Match),
+		zap.String("detected", string(record.DetectedState)),
+		zap.Bool("actuallyDetected", record.ActuallyDetected),
+		zap.String("previous", string(record.PreviousState)),
+		zap.String("state", string(record.State)),
+		zap.Int64("sinceLastDetectedMs", record.SinceLastDetectedMs),
+		zap.Strings("lines", record.Lines))
+}
+
+// SetDetectLinks toggles URL/file path detection for this session.
+func (s *Session) SetDetectLinks(enabled bool) {
+	s.detectLinks.Store(enabled)
+	if enabled {
+		return
+	}
+	s.linkMu.Lock()
+	s.linkBuffer = nil
+	s.linkDirty = false
+	s.lastLinksSig = ""
+	s.linkMu.Unlock()
+}
+
+func (s *Session) appendLinkBuffer(chunk []byte) {
+	if !s.detectLinks.Load() || len(chunk) == 0 {
+		return
+	}
+	s.linkMu.Lock()
+	s.linkBuffer = append(s.linkBuffer, chunk...)
+	if overflow := len(s.linkBuffer) - linkBufferMaxBytes; overflow > 0 {
+		s.linkBuffer = append([]byte(nil), s.linkBuffer[overflow:]...)
+	}
+	s.linkDirty = true
+	s.linkMu.Unlock()
+}
+
+// checkAndBroadcastLinks renders recent output into logical lines and sends the
+// detected link candidates when they differ from the last broadcast.
+func (s *Session) checkAndBroadcastLinks() {
+	if !s.detectLinks.Load() {
+		return
+	}
+
+	s.linkMu.Lock()
+	if !s.linkDirty {
+		s.linkMu.Unlock()
+		return
+	}
+	data := append([]byte(nil), s.linkBuffer...)
+	s.linkDirty = false
+	s.linkMu.Unlock()
+
+	s.mu.RLock()
+	rows, cols := s.rows, s.cols
+	root, workingDir := s.worktreeRoot, s.workingDir
+	s.mu.RUnlock()
+
+	lines := s.renderLogicalLines(data, rows, cols)
+	links := detectTerminalLinks(lines, root, workingDir)
+	signature := linksSignature(links)
+
+	s.linkMu.Lock()
+	if signature == s.lastLinksSig {
+		s.linkMu.Unlock()
+		return
+	}
+	s.lastLinksSig = signature
+	s.linkMu.Unlock()
+
+	s.broadcast(StreamEvent{Type: StreamEventLinks, Links: links})
 }
 
 func (s *Session) captureTerminalLines(rows, cols int) ([]string, error) {
@@ -983,6 +2282,15 @@ func (s *Session) handleStateChangeFromTracker(event ai_assistant2.StateChangeEv
 }
 
 func (s *Session) applyAssistantState(event ai_assistant2.StateChangeEvent) {
+	if s.debugEnabled() {
+		s.logger.Debug("assistant state changed",
+			zap.String("sessionId", s.id),
+			zap.String("from", string(event.PreviousState)),
+			zap.String("to", string(event.State)),
+			zap.String("confidence", string(event.Confidence)),
+			zap.String("match", event.Match),
+			zap.Bool("interrupted", event.Interrupted))
+	}
 	s.metaMu.Lock()
 	if s.lastMetadata == nil || s.lastMetadata.AIAssistant == nil {
 		s.metaMu.Unlock()
@@ -990,6 +2298,7 @@ func (s *Session) applyAssistantState(event ai_assistant2.StateChangeEvent) {
 	}
 	metadata := cloneSessionMetadata(s.lastMetadata)
 	ai_assistant2.SetState(metadata.AIAssistant, event.State, event.Timestamp)
+	ai_assistant2.SetStateConfidence(metadata.AIAssistant, event.Confidence, event.Match)
 	metadata.TaskID = s.TaskID()
 	metadata.AIAssistantRecentInput = ""
 	if event.PreviousState == types.StateWaitingInput &&
@@ -1000,9 +2309,51 @@ func (s *Session) applyAssistantState(event ai_assistant2.StateChangeEvent) {
 	s.lastMetadata = metadata
 	s.metaMu.Unlock()
 
+	s.broadcastAssistantState(metadata)
+}
+
+// broadcastAssistantState 广播 AI 状态变化。状态切换立即发送；与上次广播相同
+// 的状态若在去抖窗口内重复出现，则推迟到窗口结束只发送最后一次。
+func (s *Session) broadcastAssistantState(metadata *SessionMetadata) {
+	state := metadata.AIAssistant.State
+	now := time.Now()
+
+	s.metaMu.Lock()
+	if s.assistantPending != nil {
+		s.assistantPending.Stop()
+		s.assistantPending = nil
+	}
+	window := s.assistantDebounce
+	if window <= 0 {
+		window = assistantStateDebounceWindow
+	}
+	if state == s.assistantLastState && now.Sub(s.assistantLastAt) < window {
+		s.assistantPending = time.AfterFunc(window, s.flushAssistantState)
+		s.metaMu.Unlock()
+		return
+	}
+	s.assistantLastState = state
+	s.assistantLastAt = now
+	s.metaMu.Unlock()
+
 	s.broadcast(StreamEvent{Type: StreamEventMetadata, Metadata: metadata})
 }
 
+func (s *Session) flushAssistantState() {
+	select {
+	case <-s.closed:
+		return
+	default:
+	}
+
+	s.metaMu.Lock()
+	s.assistantPending = nil
+	s.assistantLastAt = time.Now()
+	s.metaMu.Unlock()
+
+	s.broadcastMetadataSnapshot()
+}
+
 func (s *Session) handleRecentInput(event ai_assistant2.StateChangeEvent) {
 	input := strings.TrimSpace(event.RecentInput)
 	if input == "" {
@@ -1101,7 +2452,7 @@ func (s *Session) buildAutoTaskDescription(input string, ts time.Time) string {
 	}
 
 	var meta []string
-	if dir := strings.TrimSpace(s.workingDir); dir != "" {
+	if dir := strings.TrimSpace(s.CurrentDir()); dir != "" {
 		meta = append(meta, fmt.Sprintf("Working directory: %s", dir))
 	}
 	if wt := strings.TrimSpace(s.worktreeID); wt != "" {
@@ -1257,18 +2608,38 @@ func (s *Session) enrichAssistantInfo(info *types.AssistantInfo) *ai_assistant2.
 	return s.enrichAssistantInfoWithSize(info, rows, cols)
 }
 
+// applyDetectionParams 把预设展开后的检测参数下发给 tracker，并更新状态广播的去抖窗口
+func (s *Session) applyDetectionParams(params utils.AIDetectionParams) {
+	if tracker := s.assistantTracker; tracker != nil {
+		tracker.SetDetectionParams(ai_assistant2.DetectionParams{
+			ProcessInterval:     time.Duration(params.ProcessIntervalMs) * time.Millisecond,
+			IdleCheckInterval:   time.Duration(params.IdleCheckIntervalMs) * time.Millisecond,
+			WorkingExitInterval: time.Duration(params.WorkingExitMs) * time.Millisecond,
+		})
+	}
+	s.metaMu.Lock()
+	s.assistantDebounce = time.Duration(params.DebounceMs) * time.Millisecond
+	s.metaMu.Unlock()
+}
+
 func (s *Session) enrichAssistantInfoWithSize(info *types.AssistantInfo, rows, cols int) *ai_assistant2.AIAssistantInfo {
 	tracker := s.assistantTracker
 	if info == nil {
 		if tracker != nil {
 			tracker.Deactivate()
 		}
+		if s.assistantBanner != nil {
+			s.assistantBanner.SetAssistant(types.AssistantTypeUnknown)
+		}
 		return nil
 	}
 
 	// Check if this assistant type is enabled in config
 	if s.getAIConfig != nil {
 		config := s.getAIConfig()
+		if config != nil {
+			s.applyDetectionParams(config.DetectionParams())
+		}
 		if config != nil && !config.IsEnabled(string(info.Type)) {
 			// Config disabled this assistant type, deactivate tracker
 			if tracker != nil {
@@ -1277,12 +2648,14 @@ func (s *Session) enrichAssistantInfoWithSize(info *types.AssistantInfo, rows, c
 			// Return AIAssistantInfo with unknown state to indicate it's disabled
 			aiInfo := ai_assistant2.ToAIAssistantInfo(info)
 			ai_assistant2.SetState(aiInfo, types.StateUnknown, time.Now())
+			s.applyAssistantBanner(aiInfo, info.Type)
 			return aiInfo
 		}
 	}
 
 	// Convert to AIAssistantInfo
 	aiInfo := ai_assistant2.ToAIAssistantInfo(info)
+	s.applyAssistantBanner(aiInfo, info.Type)
 
 	// Activate tracker with terminal size
 	if tracker != nil {
@@ -1291,26 +2664,47 @@ func (s *Session) enrichAssistantInfoWithSize(info *types.AssistantInfo, rows, c
 		// Get current state
 		if state, ts := tracker.State(); state != types.StateUnknown {
 			ai_assistant2.SetState(aiInfo, state, ts)
+			confidence, match := tracker.StateConfidence()
+			ai_assistant2.SetStateConfidence(aiInfo, confidence, match)
 		}
+		aiInfo.TrackingPaused = tracker.Paused()
 	}
 
 	return aiInfo
 }
 
+// applyAssistantBanner fills the model and CLI version parsed from the assistant banner.
+func (s *Session) applyAssistantBanner(aiInfo *ai_assistant2.AIAssistantInfo, assistantType types.AssistantType) {
+	if s.assistantBanner == nil || aiInfo == nil {
+		return
+	}
+	s.assistantBanner.SetAssistant(assistantType)
+	banner := s.assistantBanner.Info()
+	aiInfo.Model = banner.Model
+	aiInfo.CliVersion = banner.CliVersion
+	if banner.Model != "" && s.assistantTracker != nil {
+		s.assistantTracker.SetTokenModel(banner.Model)
+	}
+}
+
 // DebugInfo collects comprehensive debug information about the session.
 type DebugInfo struct {
-	SessionID                 string                         `json:"sessionId"`
-	ProjectID                 string                         `json:"projectId"`
-	WorktreeID                string                         `json:"worktreeId"`
-	Status                    SessionStatus                  `json:"status"`
-	Rows                      int                            `json:"rows"`
-	Cols                      int                            `json:"cols"`
-	ScrollbackChunks          []string                       `json:"scrollbackChunks"`
-	ScrollbackChunksTimestamp []time.Time                    `json:"scrollbackChunksTimestamp"`
-	ScrollbackSize            int                            `json:"scrollbackSize"`
-	ScrollbackLimit           int                            `json:"scrollbackLimit"`
-	AIAssistant               *ai_assistant2.AIAssistantInfo `json:"aiAssistant,omitempty"`
-	AIChunkCount              int64                          `json:"aiChunkCount,omitempty"`
+	SessionID                 string                          `json:"sessionId"`
+	ProjectID                 string                          `json:"projectId"`
+	WorktreeID                string                          `json:"worktreeId"`
+	Status                    SessionStatus                   `json:"status"`
+	Rows                      int                             `json:"rows"`
+	Cols                      int                             `json:"cols"`
+	ScrollbackChunks          []string                        `json:"scrollbackChunks"`
+	ScrollbackChunksTimestamp []time.Time                     `json:"scrollbackChunksTimestamp"`
+	ScrollbackSize            int                             `json:"scrollbackSize"`
+	ScrollbackLimit           int                             `json:"scrollbackLimit"`
+	AIAssistant               *ai_assistant2.AIAssistantInfo  `json:"aiAssistant,omitempty"`
+	AIChunkCount              int64                           `json:"aiChunkCount,omitempty"`
+	AIDiagnostics             []ai_assistant2.DetectionRecord `json:"aiDiagnostics,omitempty"`
+	// RawCaptureEnabled/RawCapturedBytes 原始 PTY 字节采集状态，内容通过 debug/raw 获取
+	RawCaptureEnabled bool `json:"rawCaptureEnabled"`
+	RawCapturedBytes  int  `json:"rawCapturedBytes"`
 }
 
 // GetDebugInfo returns comprehensive debugging information about the session.
@@ -1359,8 +2753,14 @@ func (s *Session) GetDebugInfo() *DebugInfo {
 
 	if s.assistantTracker != nil {
 		info.AIChunkCount = s.assistantTracker.ChunkCount()
+		info.AIDiagnostics = s.assistantTracker.DiagnosticRecords()
 	}
 
+	s.rawCapture.mu.Lock()
+	info.RawCaptureEnabled = s.rawCapture.enabled
+	info.RawCapturedBytes = len(s.rawCapture.buf)
+	s.rawCapture.mu.Unlock()
+
 	return info
 }
 
@@ -1382,8 +2782,8 @@ func (s *Session) CaptureNextChunk(ctx context.Context, timeout time.Duration) (
 		timeout = 1 * time.Second
 	}
 
-	// Subscribe to output stream
-	stream, err := s.Subscribe(ctx)
+	// Subscribe to output stream, explicitly so track-only sessions deliver it too
+	stream, err := s.Subscribe(ctx, StreamEventData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to subscribe to session: %w", err)
 	}
@@ -1432,6 +2832,11 @@ func cloneSessionMetadata(meta *SessionMetadata) *SessionMetadata {
 		infoCopy := *meta.AIAssistant
 		copyMeta.AIAssistant = &infoCopy
 	}
+	copyMeta.Todos = slices.Clone(meta.Todos)
+	if meta.ContextLeftPercent != nil {
+		percent := *meta.ContextLeftPercent
+		copyMeta.ContextLeftPercent = &percent
+	}
 	return &copyMeta
 }
 
@@ -1472,3 +2877,30 @@ func resolveEncoding(name string) (encoding.Encoding, string, error) {
 		return nil, normalized, ErrInvalidEncoding
 	}
 }
+
+// checkEchoMode polls the PTY termios after output arrives and broadcasts
+// metadata when the foreground program toggles echo.
+func (s *Session) checkEchoMode() {
+	s.mu.RLock()
+	device := s.pty
+	s.mu.RUnlock()
+	if device == nil {
+		return
+	}
+	echo, ok := ptyEchoState(device)
+	if !ok {
+		return
+	}
+	s.inputHidden.Store(echo.hidesInput())
+	echoOff := echo.echoOff
+	if s.echoOff.Swap(echoOff) == echoOff {
+		return
+	}
+
+	s.metaMu.Lock()
+	if s.lastMetadata != nil {
+		s.lastMetadata.EchoOff = echoOff
+	}
+	s.metaMu.Unlock()
+	s.broadcastMetadataSnapshot()
+}
@@ -0,0 +1,48 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SessionSource records how a session was created, for telling manual use
+// apart from automation.
+type SessionSource string
+
+const (
+	// SessionSourceManual 用户在界面上手动创建
+	SessionSourceManual SessionSource = "manual"
+	// SessionSourceAPI 脚本或集成直接调用接口创建，也是未指定来源时的默认值
+	SessionSourceAPI      SessionSource = "api"
+	SessionSourceClone    SessionSource = "clone"
+	SessionSourcePlaybook SessionSource = "playbook"
+)
+
+// ParseSessionSource validates a session source; an empty value returns "".
+func ParseSessionSource(raw string) (SessionSource, error) {
+	switch source := SessionSource(strings.ToLower(strings.TrimSpace(raw))); source {
+	case "", SessionSourceManual, SessionSourceAPI, SessionSourceClone, SessionSourcePlaybook:
+		return source, nil
+	}
+	return "", fmt.Errorf("%w: %q", ErrInvalidSessionSource, raw)
+}
+
+// Source returns how the session was created.
+func (s *Session) Source() SessionSource {
+	return s.source
+}
+
+// FilterSnapshotsBySource keeps the snapshots created from source; an empty
+// source keeps all of them.
+func FilterSnapshotsBySource(snapshots []SessionSnapshot, source SessionSource) []SessionSnapshot {
+	if source == "" {
+		return snapshots
+	}
+	filtered := make([]SessionSnapshot, 0, len(snapshots))
+	for _, snapshot := range snapshots {
+		if snapshot.Source == source {
+			filtered = append(filtered, snapshot)
+		}
+	}
+	return filtered
+}
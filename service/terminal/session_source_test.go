@@ -0,0 +1,35 @@
+package terminal
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseSessionSource(t *testing.T) {
+	if source, err := ParseSessionSource(" Playbook "); err != nil || source != SessionSourcePlaybook {
+		t.Fatalf("unexpected source %q err=%v", source, err)
+	}
+	if source, err := ParseSessionSource(""); err != nil || source != "" {
+		t.Fatalf("expected empty source, got %q err=%v", source, err)
+	}
+	if _, err := ParseSessionSource("cron"); !errors.Is(err, ErrInvalidSessionSource) {
+		t.Fatalf("expected ErrInvalidSessionSource, got %v", err)
+	}
+}
+
+func TestSessionSourceDefaultsAndFilter(t *testing.T) {
+	if source := newTestSession(t, SessionParams{}).Source(); source != SessionSourceAPI {
+		t.Fatalf("expected sessions without source to count as api, got %q", source)
+	}
+	snapshots := []SessionSnapshot{
+		{ID: "a", Source: SessionSourceManual},
+		{ID: "b", Source: SessionSourceAPI},
+		{ID: "c", Source: SessionSourceManual},
+	}
+	if got := FilterSnapshotsBySource(snapshots, SessionSourceManual); len(got) != 2 || got[1].ID != "c" {
+		t.Fatalf("unexpected filtered snapshots %+v", got)
+	}
+	if got := FilterSnapshotsBySource(snapshots, ""); len(got) != 3 {
+		t.Fatalf("expected empty source to keep all snapshots, got %+v", got)
+	}
+}
@@ -0,0 +1,781 @@
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"math"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/x/xpty"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/transform"
+
+	"code-kanban/utils"
+	"code-kanban/utils/ai_assistant2"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+func newTestSession(t *testing.T, params SessionParams) *Session {
+	t.Helper()
+	if len(params.Command) == 0 {
+		params.Command = []string{"sh"}
+	}
+	session, err := NewSession(params)
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+	return session
+}
+
+func TestSessionEffectiveIdleTimeout(t *testing.T) {
+	cases := []struct {
+		name     string
+		override time.Duration
+		global   time.Duration
+		want     time.Duration
+	}{
+		{name: "use global", override: 0, global: 10 * time.Minute, want: 10 * time.Minute},
+		{name: "override global", override: 2 * time.Minute, global: 10 * time.Minute, want: 2 * time.Minute},
+		{name: "override without global", override: time.Hour, global: 0, want: time.Hour},
+		{name: "never expire", override: -1, global: 10 * time.Minute, want: 0},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			session := newTestSession(t, SessionParams{IdleTimeoutOverride: tc.override})
+			if got := session.EffectiveIdleTimeout(tc.global); got != tc.want {
+				t.Fatalf("expected %v got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestSessionScrollbackFrames(t *testing.T) {
+	session := newTestSession(t, SessionParams{ScrollbackLimit: 1024})
+	session.appendScrollback([]byte("one "))
+	session.appendScrollback([]byte("two "))
+	session.appendScrollback([]byte("three"))
+
+	frame := session.ScrollbackFrameAt(2)
+	if frame.Index != 2 || frame.Total != 3 {
+		t.Fatalf("unexpected frame position: %+v", frame)
+	}
+	if string(frame.Data) != "one two " {
+		t.Fatalf("unexpected frame data %q", frame.Data)
+	}
+
+	if frame := session.ScrollbackFrameAt(10); frame.Index != 3 || string(frame.Data) != "one two three" {
+		t.Fatalf("expected frame to clamp to last chunk, got %+v", frame)
+	}
+	if frame := session.ScrollbackFrameUntil(time.Time{}); frame.Index != 0 || len(frame.Data) != 0 {
+		t.Fatalf("expected empty frame before first chunk, got %+v", frame)
+	}
+
+	second := session.ScrollbackFrameAt(2).Timestamp
+	if frame := session.ScrollbackFrameUntil(second); frame.Index < 2 {
+		t.Fatalf("expected frame to include chunk at %v, got %+v", second, frame)
+	}
+}
+
+func TestSessionMaxLifetime(t *testing.T) {
+	session := newTestSession(t, SessionParams{
+		MaxLifetime:        100 * time.Millisecond,
+		CloseOnMaxLifetime: true,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := session.Start(ctx); err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer session.Close()
+
+	if session.Deadline().IsZero() {
+		t.Fatalf("expected deadline to be set after start")
+	}
+
+	stream, err := session.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event, ok := <-stream.Events():
+			if !ok {
+				t.Fatalf("stream closed before lifetime-exceeded event")
+			}
+			if event.Type != StreamEventLifetimeExceeded {
+				continue
+			}
+			select {
+			case <-session.Closed():
+			case <-timeout:
+				t.Fatalf("expected session to close after exceeding max lifetime")
+			}
+			return
+		case <-timeout:
+			t.Fatalf("timed out waiting for lifetime-exceeded event")
+		}
+	}
+}
+
+func TestSessionConfirmProcessBusy(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+
+	steps := []struct {
+		raw  bool
+		want bool
+	}{
+		{raw: false, want: false},
+		{raw: true, want: false},
+		{raw: false, want: false},
+		{raw: true, want: false},
+		{raw: true, want: true},
+		{raw: false, want: true},
+		{raw: false, want: false},
+	}
+	for i, step := range steps {
+		if got := session.confirmProcessBusy(step.raw); got != step.want {
+			t.Fatalf("step %d: expected %v got %v", i, step.want, got)
+		}
+	}
+}
+
+func TestSessionAssistantStateDebounce(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	session.lastMetadata = &SessionMetadata{
+		AIAssistant: &ai_assistant2.AIAssistantInfo{Type: string(types.AssistantTypeClaudeCode)},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := session.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+
+	for i := 0; i < 3; i++ {
+		session.applyAssistantState(ai_assistant2.StateChangeEvent{
+			State:     types.StateWorking,
+			Timestamp: time.Now(),
+		})
+	}
+
+	received := 0
+	timeout := time.After(assistantStateDebounceWindow * 3)
+	for {
+		select {
+		case event := <-stream.Events():
+			if event.Type == StreamEventMetadata {
+				received++
+			}
+		case <-timeout:
+			// 第一次立即发送，其余两次合并为窗口结束后的一次
+			if received != 2 {
+				t.Fatalf("expected 2 metadata events got %d", received)
+			}
+			return
+		}
+	}
+}
+
+func TestSessionAssistantStateConfidence(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	session.lastMetadata = &SessionMetadata{
+		AIAssistant: &ai_assistant2.AIAssistantInfo{Type: string(types.AssistantTypeCodex)},
+	}
+
+	session.applyAssistantState(ai_assistant2.StateChangeEvent{
+		State:      types.StateWaitingInput,
+		Timestamp:  time.Now(),
+		Confidence: types.ConfidenceLow,
+		Match:      "default-waiting",
+	})
+	info := session.lastMetadata.AIAssistant
+	if info.StateConfidence != string(types.ConfidenceLow) || info.StateMatch != "default-waiting" {
+		t.Fatalf("expected low confidence from default rule, got %q %q", info.StateConfidence, info.StateMatch)
+	}
+
+	session.applyAssistantState(ai_assistant2.StateChangeEvent{
+		State:      types.StateWorking,
+		Timestamp:  time.Now(),
+		Confidence: types.ConfidenceHigh,
+		Match:      "working-line",
+	})
+	if info := session.lastMetadata.AIAssistant; info.StateConfidence != string(types.ConfidenceHigh) || info.StateMatch != "working-line" {
+		t.Fatalf("expected high confidence after working line, got %q %q", info.StateConfidence, info.StateMatch)
+	}
+
+	old := &SessionMetadata{AIAssistant: &ai_assistant2.AIAssistantInfo{State: "working", StateConfidence: "low"}}
+	updated := &SessionMetadata{AIAssistant: &ai_assistant2.AIAssistantInfo{State: "working", StateConfidence: "high"}}
+	if !session.metadataChanged(old, updated) {
+		t.Fatalf("expected confidence change to be broadcast")
+	}
+}
+
+func TestSessionScrollbackCursor(t *testing.T) {
+	session := newTestSession(t, SessionParams{ScrollbackLimit: 8})
+	for _, chunk := range []string{"aaa", "bbb", "ccc"} {
+		session.appendScrollback([]byte(chunk))
+	}
+
+	// limit 8 只保留最后两个分片，序号不因裁剪而重排
+	chunks, latest := session.ScrollbackSince(0)
+	if latest != 3 || len(chunks) != 2 || chunks[0].Seq != 2 || string(chunks[1].Data) != "ccc" {
+		t.Fatalf("unexpected chunks %+v latest=%d", chunks, latest)
+	}
+	if chunks, latest := session.ScrollbackSince(latest); len(chunks) != 0 || latest != 3 {
+		t.Fatalf("expected no new chunks, got %+v latest=%d", chunks, latest)
+	}
+
+	if seq := session.appendScrollback([]byte("dd")); seq != 4 {
+		t.Fatalf("expected seq 4 got %d", seq)
+	}
+	if chunks, _ := session.ScrollbackSince(3); len(chunks) != 1 || chunks[0].Seq != 4 {
+		t.Fatalf("unexpected incremental chunks %+v", chunks)
+	}
+	// 分片 1 已被裁剪：从 0 续传有缺口，从 1 续传则完整
+	if chunks, gap := session.ScrollbackResume(0); !gap || len(chunks) != 3 || chunks[0].Seq != 2 {
+		t.Fatalf("expected gap resuming from 0, got %+v gap=%v", chunks, gap)
+	}
+	if chunks, gap := session.ScrollbackResume(1); gap || len(chunks) != 3 {
+		t.Fatalf("expected no gap resuming from 1, got %+v gap=%v", chunks, gap)
+	}
+
+	before := session.ScrollbackBefore(4, 1)
+	if len(before) != 1 || before[0].Seq != 3 {
+		t.Fatalf("unexpected history page %+v", before)
+	}
+	if tail := session.ScrollbackBefore(0, 2); len(tail) != 2 || tail[1].Seq != 4 {
+		t.Fatalf("unexpected tail %+v", tail)
+	}
+
+	session.UpdateScrollbackLimit(0)
+	session.UpdateScrollbackLimit(8)
+	if seq := session.appendScrollback([]byte("e")); seq <= 4 {
+		t.Fatalf("expected seq to keep increasing after reset, got %d", seq)
+	}
+}
+
+func TestSessionBuffersInputBeforeStart(t *testing.T) {
+	session := newTestSession(t, SessionParams{ScrollbackLimit: 256})
+
+	if err := session.WriteInput(make([]byte, pendingInputMaxBytes+1)); err != nil {
+		t.Fatalf("oversized input should be dropped, got %v", err)
+	}
+	if _, err := session.Write([]byte("echo pending-$((1+2))\r")); err != nil {
+		t.Fatalf("Write before start failed: %v", err)
+	}
+	if session.pendingSize == 0 || len(session.pendingInput) != 1 {
+		t.Fatalf("expected only the small input buffered, got %d chunks", len(session.pendingInput))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := session.Start(ctx); err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer session.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if strings.Contains(string(bytes.Join(session.Scrollback(), nil)), "pending-3") {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("buffered input was not flushed to the shell")
+}
+
+func TestSessionNormalizeOutputAcrossChunks(t *testing.T) {
+	session := newTestSession(t, SessionParams{Encoding: "gbk"})
+	encoded, _, err := transform.Bytes(simplifiedchinese.GBK.NewEncoder(), []byte("中文输出 ok"))
+	if err != nil {
+		t.Fatalf("encode gbk: %v", err)
+	}
+
+	var decoded []byte
+	for _, split := range []int{1, 3, 4} {
+		decoded = append(decoded, session.NormalizeOutput(encoded[:split])...)
+		encoded = encoded[split:]
+	}
+	decoded = append(decoded, session.NormalizeOutput(encoded)...)
+	if got := string(decoded); got != "中文输出 ok" {
+		t.Fatalf("unexpected decoded output %q", got)
+	}
+}
+
+func TestSessionDetectsEchoOff(t *testing.T) {
+	session := newTestSession(t, SessionParams{
+		ID:      "echo-session",
+		Command: []string{"sh", "-c", "stty -echo; echo password:; sleep 5"},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := session.Start(ctx); err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer session.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if session.echoOff.Load() {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("expected echo to be reported off after stty -echo")
+}
+
+func TestSessionSubscribeEventFilter(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	stream, err := session.Subscribe(context.Background(), StreamEventMetadata)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+
+	session.broadcast(StreamEvent{Type: StreamEventData, Data: []byte("x")})
+	session.broadcast(StreamEvent{Type: StreamEventMetadata, Metadata: &SessionMetadata{Title: "t"}})
+	session.notifyExit(nil)
+
+	var got []StreamEventType
+	for event := range stream.Events() {
+		got = append(got, event.Type)
+	}
+	if len(got) != 2 || got[0] != StreamEventMetadata || got[1] != StreamEventExit {
+		t.Fatalf("expected metadata and exit only, got %v", got)
+	}
+
+	info := session.Diagnostics()
+	if len(info.Subscribers) != 0 {
+		t.Fatalf("expected subscriber to be removed after exit, got %+v", info.Subscribers)
+	}
+}
+
+func TestParseStreamEventTypes(t *testing.T) {
+	types, err := ParseStreamEventTypes(" Metadata, exit ,")
+	if err != nil || len(types) != 2 || types[0] != StreamEventMetadata || types[1] != StreamEventExit {
+		t.Fatalf("unexpected result %v, %v", types, err)
+	}
+	if types, err := ParseStreamEventTypes(""); err != nil || types != nil {
+		t.Fatalf("expected nil for empty value, got %v, %v", types, err)
+	}
+	if _, err := ParseStreamEventTypes("metadata,bogus"); err == nil {
+		t.Fatalf("expected error for unknown event type")
+	}
+}
+
+func TestSessionExtractsAssistantBanner(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	info := &types.AssistantInfo{Type: types.AssistantTypeClaudeCode, Name: "claude-code", Detected: true}
+
+	// 横幅在助手被识别之前输出，且被拆成两个 chunk
+	session.handleAssistantOutput([]byte("\x1b[1m ▐▛███▜▌\x1b[0m   Claude Code v2.0.1"))
+	session.handleAssistantOutput([]byte("4\r\n▝▜█████▛▘  Sonnet 4.5 · Claude Max\r\n"))
+
+	aiInfo := session.enrichAssistantInfoWithSize(info, 24, 80)
+	if aiInfo.Model != "Sonnet 4.5" || aiInfo.CliVersion != "2.0.14" {
+		t.Fatalf("unexpected banner info model=%q version=%q", aiInfo.Model, aiInfo.CliVersion)
+	}
+
+	// 结果被缓存，后续输出不会覆盖
+	session.handleAssistantOutput([]byte("Opus 4.1 · switched\r\n"))
+	if aiInfo = session.enrichAssistantInfoWithSize(info, 24, 80); aiInfo.Model != "Sonnet 4.5" {
+		t.Fatalf("expected cached model, got %q", aiInfo.Model)
+	}
+
+	codex := &types.AssistantInfo{Type: types.AssistantTypeCodex, Name: "codex", Detected: true}
+	session.handleAssistantOutput([]byte(">_ OpenAI Codex (v0.46.0)\r\n\r\nmodel:     gpt-5-codex high   /model to change\r\n"))
+	aiInfo = session.enrichAssistantInfoWithSize(codex, 24, 80)
+	if aiInfo.Model != "gpt-5-codex" || aiInfo.CliVersion != "0.46.0" {
+		t.Fatalf("unexpected codex banner info model=%q version=%q", aiInfo.Model, aiInfo.CliVersion)
+	}
+}
+
+func TestSessionAssistantTodos(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	tracker := session.assistantTracker
+	tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+	tracker.Activate(types.AssistantTypeClaudeCode, 24, 60)
+	defer tracker.Deactivate()
+
+	screen := "⏺ Update Todos\r\n" +
+		"  ⎿  ☒ Read the config loader\r\n" +
+		"     ◼ Implement validation for the terminal\r\n" +
+		"       section\r\n" +
+		"     ☐ Write tests\r\n" +
+		"\r\n" +
+		"> \r\n"
+	tracker.ProcessChunk([]byte(screen))
+
+	deadline := time.Now().Add(3 * time.Second)
+	var todos []ai_assistant2.TodoItem
+	for time.Now().Before(deadline) {
+		if todos = session.AssistantTodos(); len(todos) > 0 {
+			break
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	want := []ai_assistant2.TodoItem{
+		{Text: "Read the config loader", Done: true},
+		{Text: "Implement validation for the terminal section", InProgress: true},
+		{Text: "Write tests"},
+	}
+	if len(todos) != len(want) {
+		t.Fatalf("expected %d todos, got %+v", len(want), todos)
+	}
+	for i := range want {
+		if todos[i] != want[i] {
+			t.Fatalf("todo %d: expected %+v, got %+v", i, want[i], todos[i])
+		}
+	}
+}
+
+func TestSessionAssistantWorkDuration(t *testing.T) {
+	cases := []struct {
+		text string
+		want time.Duration
+	}{
+		{"2m15s", 2*time.Minute + 15*time.Second},
+		{"1h 2m 3s", time.Hour + 2*time.Minute + 3*time.Second},
+		{"45s", 45 * time.Second},
+		{"a while", 0},
+	}
+	for _, tc := range cases {
+		session := newTestSession(t, SessionParams{})
+		tracker := session.assistantTracker
+		tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+		tracker.Activate(types.AssistantTypeCodex, 24, 60)
+
+		// codex 把完成行画满整行宽度
+		worked := "─ Worked for " + tc.text + " "
+		worked += strings.Repeat("─", 60-utf8.RuneCountInString(worked))
+		screen := "• Done.\r\n" +
+			"\r\n" +
+			worked + "\r\n" +
+			"\r\n" +
+			"› \r\n"
+		tracker.ProcessChunk([]byte(screen))
+
+		var got time.Duration
+		if tc.want > 0 {
+			deadline := time.Now().Add(3 * time.Second)
+			for time.Now().Before(deadline) {
+				if got = session.AssistantWorkDuration(); got > 0 {
+					break
+				}
+				time.Sleep(50 * time.Millisecond)
+			}
+		} else {
+			time.Sleep(200 * time.Millisecond)
+			got = session.AssistantWorkDuration()
+		}
+		tracker.Deactivate()
+		if got != tc.want {
+			t.Fatalf("Worked for %q: expected %s, got %s", tc.text, tc.want, got)
+		}
+	}
+}
+
+// chunkedPty accepts at most chunk bytes per Write and yields in between, so
+// unserialized writers would interleave.
+type chunkedPty struct {
+	xpty.Pty
+	chunk int
+	mu    sync.Mutex
+	out   bytes.Buffer
+}
+
+func (p *chunkedPty) Write(data []byte) (int, error) {
+	if len(data) > p.chunk {
+		data = data[:p.chunk]
+	}
+	p.mu.Lock()
+	p.out.Write(data)
+	p.mu.Unlock()
+	runtime.Gosched()
+	return len(data), nil
+}
+
+func TestSessionConcurrentWritesAreAtomic(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	device := &chunkedPty{chunk: 3}
+	session.pty = device
+	session.inputReady = true
+	session.setStatus(SessionStatusRunning)
+
+	payloads := make([]string, 0, 8)
+	for i := 0; i < 8; i++ {
+		payloads = append(payloads, "<"+strings.Repeat(string(rune('a'+i)), 40)+">")
+	}
+	var wg sync.WaitGroup
+	for i, payload := range payloads {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var err error
+			if i%2 == 0 {
+				_, err = session.Write([]byte(payload))
+			} else {
+				err = session.WriteInput([]byte(payload))
+			}
+			if err != nil {
+				t.Errorf("write failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	output := device.out.String()
+	if len(output) != len(payloads)*len(payloads[0]) {
+		t.Fatalf("expected every payload to be written completely, got %d bytes", len(output))
+	}
+	for _, payload := range payloads {
+		if !strings.Contains(output, payload) {
+			t.Fatalf("payload %q was interleaved in %q", payload, output)
+		}
+	}
+}
+
+func TestSessionAssistantContextLeft(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	if _, ok := session.AssistantContextLeft(); ok {
+		t.Fatalf("expected unknown context left before the assistant runs")
+	}
+	tracker := session.assistantTracker
+	tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+	tracker.Activate(types.AssistantTypeCodex, 24, 80)
+	tracker.ProcessChunk([]byte("› \r\n\r\n  42% context left\r\n"))
+
+	deadline := time.Now().Add(3 * time.Second)
+	percent, ok := session.AssistantContextLeft()
+	for !ok && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		percent, ok = session.AssistantContextLeft()
+	}
+	if !ok || percent != 42 {
+		t.Fatalf("expected 42%% context left, got %d/%v", percent, ok)
+	}
+
+	tracker.Deactivate()
+	if _, ok := session.AssistantContextLeft(); ok {
+		t.Fatalf("expected context left to be cleared after the assistant exits")
+	}
+}
+
+func TestSessionAssistantTurnCount(t *testing.T) {
+	session := newTestSession(t, SessionParams{})
+	tracker := session.assistantTracker
+	tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+	tracker.Activate(types.AssistantTypeCodex, 24, 80)
+	defer tracker.Deactivate()
+
+	tracker.Calibrate(types.StateWorking, "test")
+	tracker.Calibrate(types.StateWaitingInput, "test")
+	// 没有经过 working 的状态变化不算一轮
+	tracker.Calibrate(types.StateWaitingApproval, "test")
+	tracker.Calibrate(types.StateWaitingInput, "test")
+	if turns, interrupted := session.AssistantTurnCount(); turns != 1 || interrupted != 0 {
+		t.Fatalf("expected 1 finished turn, got %d/%d", turns, interrupted)
+	}
+
+	tracker.Calibrate(types.StateWorking, "test")
+	tracker.ProcessChunk([]byte("■ Conversation interrupted\r\n\r\n› \r\n\r\n  90% context left\r\n"))
+	deadline := time.Now().Add(3 * time.Second)
+	turns, interrupted := session.AssistantTurnCount()
+	for interrupted == 0 && time.Now().Before(deadline) {
+		time.Sleep(100 * time.Millisecond)
+		tracker.Recheck()
+		turns, interrupted = session.AssistantTurnCount()
+	}
+	if turns != 1 || interrupted != 1 {
+		t.Fatalf("expected the interrupted turn to be counted separately, got %d/%d", turns, interrupted)
+	}
+	if snapshot := session.Snapshot(); snapshot.TurnCount != 1 || snapshot.InterruptedTurns != 1 {
+		t.Fatalf("unexpected snapshot turn counts %d/%d", snapshot.TurnCount, snapshot.InterruptedTurns)
+	}
+}
+
+func TestSessionAssistantTokenUsage(t *testing.T) {
+	session := newTestSession(t, SessionParams{
+		ModelPricing: map[string]utils.ModelPrice{
+			"gpt-5-codex": {InputPerMillion: 1.25, OutputPerMillion: 10},
+			"codex":       {InputPerMillion: 2, OutputPerMillion: 8},
+		},
+	})
+	tracker := session.assistantTracker
+	tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+
+	runs := []struct {
+		model string
+		line  string
+	}{
+		{"gpt-5-codex", "Token usage: total=1,500 input=1,000 (+ 200 cached) output=500"},
+		// 未识别模型时按助手类型计价
+		{"", "Token usage: total=3,000 input=2,000 output=1,000"},
+	}
+	for i, run := range runs {
+		tracker.Activate(types.AssistantTypeCodex, 24, 80)
+		if run.model != "" {
+			tracker.SetTokenModel(run.model)
+		}
+		tracker.ProcessChunk([]byte("\r\n" + run.line + "\r\n"))
+		deadline := time.Now().Add(3 * time.Second)
+		for time.Now().Before(deadline) && len(tracker.TokenUsage()) <= i {
+			time.Sleep(50 * time.Millisecond)
+		}
+		tracker.Deactivate()
+	}
+
+	usage := session.AssistantTokenUsage()
+	if usage == nil || usage.InputTokens != 3000 || usage.OutputTokens != 1500 {
+		t.Fatalf("unexpected token usage: %+v", usage)
+	}
+	if usage.EstimatedCostUSD == nil || math.Abs(*usage.EstimatedCostUSD-0.01825) > 1e-9 {
+		t.Fatalf("expected estimated cost 0.01825, got %v", usage.EstimatedCostUSD)
+	}
+
+	session.modelPricing = map[string]utils.ModelPrice{"gpt-5-codex": {InputPerMillion: 1.25, OutputPerMillion: 10}}
+	if usage := session.AssistantTokenUsage(); usage == nil || usage.EstimatedCostUSD != nil {
+		t.Fatalf("expected no cost when a model lacks pricing, got %+v", usage)
+	}
+}
+
+func TestSessionReadOnlyRejectsInput(t *testing.T) {
+	session := newTestSession(t, SessionParams{ReadOnly: true})
+	device := &chunkedPty{chunk: 64}
+	session.pty = device
+	session.inputReady = true
+	session.setStatus(SessionStatusRunning)
+
+	if _, err := session.Write([]byte("ls\r")); !errors.Is(err, ErrSessionReadOnly) {
+		t.Fatalf("expected Write to be rejected, got %v", err)
+	}
+	if err := session.WriteInput([]byte("rm -rf .\r")); !errors.Is(err, ErrSessionReadOnly) {
+		t.Fatalf("expected WriteInput to be rejected, got %v", err)
+	}
+	if err := session.WriteKey("enter"); !errors.Is(err, ErrSessionReadOnly) {
+		t.Fatalf("expected WriteKey to be rejected, got %v", err)
+	}
+	if device.out.Len() != 0 {
+		t.Fatalf("expected nothing written, got %q", device.out.String())
+	}
+	if !session.SnapshotWith(SnapshotOptions{SkipProcess: true, SkipTokenUsage: true}).ReadOnly {
+		t.Fatalf("expected snapshot to report read-only")
+	}
+}
+
+func TestSessionTrackOnly(t *testing.T) {
+	session := newTestSession(t, SessionParams{TrackOnly: true, ScrollbackLimit: 4096})
+	tracker := session.assistantTracker
+	tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+	tracker.Activate(types.AssistantTypeCodex, 24, 80)
+	defer tracker.Deactivate()
+
+	plain, err := session.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer plain.Close()
+	explicit, err := session.Subscribe(context.Background(), StreamEventData)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer explicit.Close()
+
+	session.emitOutput([]byte("› \r\n\r\n  42% context left\r\n"))
+
+	if got := session.Scrollback(); len(got) != 0 {
+		t.Fatalf("expected no scrollback, got %q", got)
+	}
+	session.UpdateScrollbackLimit(1024)
+	session.emitOutput([]byte("more\r\n"))
+	if got := session.ScrollbackBytes(); got != 0 {
+		t.Fatalf("expected scrollback to stay disabled, got %d bytes", got)
+	}
+
+	select {
+	case event := <-explicit.Events():
+		if event.Type != StreamEventData {
+			t.Fatalf("expected data event, got %s", event.Type)
+		}
+	default:
+		t.Fatalf("expected explicit data subscriber to receive output")
+	}
+	for drained := false; !drained; {
+		select {
+		case event := <-plain.Events():
+			if event.Type == StreamEventData {
+				t.Fatalf("expected plain subscriber to receive no output, got %q", event.Data)
+			}
+		default:
+			drained = true
+		}
+	}
+
+	// 输出仍喂给 tracker
+	deadline := time.Now().Add(3 * time.Second)
+	percent, ok := session.AssistantContextLeft()
+	for !ok && time.Now().Before(deadline) {
+		time.Sleep(50 * time.Millisecond)
+		percent, ok = session.AssistantContextLeft()
+	}
+	if !ok || percent != 42 {
+		t.Fatalf("expected tracker to see 42%% context left, got %d/%v", percent, ok)
+	}
+}
+
+func TestSessionExitKeepsLastOutput(t *testing.T) {
+	// 连续输出让最后一段落在合并窗口内，进程随即退出
+	session := newTestSession(t, SessionParams{
+		Command:         []string{"sh", "-c", "i=0; while [ $i -lt 200 ]; do printf x; i=$((i+1)); done; printf LAST-CHUNK"},
+		ScrollbackLimit: 64 * 1024,
+	})
+	stream, err := session.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := session.Start(ctx); err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer session.Close()
+
+	var received bytes.Buffer
+	timeout := time.After(5 * time.Second)
+	for exited := false; !exited; {
+		select {
+		case event, ok := <-stream.Events():
+			if !ok {
+				exited = true
+				break
+			}
+			switch event.Type {
+			case StreamEventData:
+				received.Write(event.Data)
+			case StreamEventExit:
+				exited = true
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for exit, received %q", received.String())
+		}
+	}
+	if !strings.HasSuffix(received.String(), "LAST-CHUNK") {
+		t.Fatalf("expected the last chunk before the exit event, got %q", received.String())
+	}
+	if output := bytes.Join(session.Scrollback(), nil); !bytes.HasSuffix(output, []byte("LAST-CHUNK")) {
+		t.Fatalf("expected the last chunk in the scrollback, got %q", output)
+	}
+}
@@ -0,0 +1,82 @@
+package terminal
+
+import "time"
+
+// 状态流跟踪的字段
+const (
+	StateFieldAIAssistant    = "aiAssistant"
+	StateFieldAIState        = "aiState"
+	StateFieldProcessStatus  = "processStatus"
+	StateFieldRunningCommand = "runningCommand"
+)
+
+// SessionState is the coarse state of a session shown on a state timeline.
+type SessionState struct {
+	// AIAssistant 识别到的助手类型，AIState 为其状态，未识别到助手时均为空
+	AIAssistant    string    `json:"aiAssistant,omitempty"`
+	AIState        string    `json:"aiState,omitempty"`
+	ProcessStatus  string    `json:"processStatus,omitempty"`
+	RunningCommand string    `json:"runningCommand,omitempty"`
+	At             time.Time `json:"at"`
+}
+
+// SessionStateChange is one field of SessionState changing value.
+type SessionStateChange struct {
+	Field string    `json:"field"`
+	From  string    `json:"from"`
+	To    string    `json:"to"`
+	At    time.Time `json:"at"`
+}
+
+// SessionStateOf extracts the tracked state from a metadata event.
+func SessionStateOf(meta *SessionMetadata, at time.Time) SessionState {
+	state := SessionState{At: at}
+	if meta == nil {
+		return state
+	}
+	state.ProcessStatus = meta.ProcessStatus
+	state.RunningCommand = meta.RunningCommand
+	if meta.AIAssistant != nil {
+		state.AIAssistant = meta.AIAssistant.Type
+		state.AIState = meta.AIAssistant.State
+	}
+	return state
+}
+
+// SessionStateOfSnapshot extracts the tracked state from a session snapshot.
+func SessionStateOfSnapshot(snapshot SessionSnapshot, at time.Time) SessionState {
+	return SessionStateOf(&SessionMetadata{
+		ProcessStatus:  snapshot.ProcessStatus,
+		RunningCommand: snapshot.RunningCommand,
+		AIAssistant:    snapshot.AIAssistant,
+	}, at)
+}
+
+// StateChangeDiffer turns successive states of one session into field changes.
+// Metadata events also fire for titles, todos and other fields, so most of them
+// produce no change at all.
+type StateChangeDiffer struct {
+	last *SessionState
+}
+
+// Next records state as the latest one and returns the fields that differ from
+// the previous state. The first call only sets the baseline.
+func (d *StateChangeDiffer) Next(state SessionState) []SessionStateChange {
+	previous := d.last
+	d.last = &state
+	if previous == nil {
+		return nil
+	}
+
+	var changes []SessionStateChange
+	add := func(field, from, to string) {
+		if from != to {
+			changes = append(changes, SessionStateChange{Field: field, From: from, To: to, At: state.At})
+		}
+	}
+	add(StateFieldProcessStatus, previous.ProcessStatus, state.ProcessStatus)
+	add(StateFieldRunningCommand, previous.RunningCommand, state.RunningCommand)
+	add(StateFieldAIAssistant, previous.AIAssistant, state.AIAssistant)
+	add(StateFieldAIState, previous.AIState, state.AIState)
+	return changes
+}
@@ -0,0 +1,46 @@
+package terminal
+
+import (
+	"testing"
+	"time"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+func TestStateChangeDiffer(t *testing.T) {
+	var differ StateChangeDiffer
+	start := time.Now()
+	if changes := differ.Next(SessionStateOf(&SessionMetadata{ProcessStatus: "running"}, start)); changes != nil {
+		t.Fatalf("expected baseline without changes, got %+v", changes)
+	}
+
+	// 只变化标题等其他字段时不产生状态变化
+	if changes := differ.Next(SessionStateOf(&SessionMetadata{ProcessStatus: "running", Title: "new"}, start)); len(changes) != 0 {
+		t.Fatalf("expected no changes, got %+v", changes)
+	}
+
+	at := start.Add(time.Second)
+	changes := differ.Next(SessionStateOf(&SessionMetadata{
+		ProcessStatus:  "running",
+		RunningCommand: "claude",
+		AIAssistant:    &ai_assistant2.AIAssistantInfo{Type: "claude-code", State: "working"},
+	}, at))
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	if c := changes[0]; c.Field != StateFieldRunningCommand || c.From != "" || c.To != "claude" || !c.At.Equal(at) {
+		t.Fatalf("unexpected command change %+v", c)
+	}
+	if c := changes[2]; c.Field != StateFieldAIState || c.To != "working" {
+		t.Fatalf("unexpected ai state change %+v", c)
+	}
+
+	changes = differ.Next(SessionStateOf(&SessionMetadata{
+		ProcessStatus:  "running",
+		RunningCommand: "claude",
+		AIAssistant:    &ai_assistant2.AIAssistantInfo{Type: "claude-code", State: "waiting_input"},
+	}, at))
+	if len(changes) != 1 || changes[0].From != "working" || changes[0].To != "waiting_input" {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+}
@@ -0,0 +1,99 @@
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/model/tables"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+const (
+	// stateCorrectionMatch 用户纠正后状态的命中规则，便于与检测器结果区分
+	stateCorrectionMatch = "user-correction"
+	// stateCorrectionOutputBytes 纠正记录保存的最近原始输出上限
+	stateCorrectionOutputBytes = 64 * 1024
+)
+
+// correctableStates 用户可以纠正为的状态
+var correctableStates = map[types.State]struct{}{
+	types.StateWorking:         {},
+	types.StateWaitingInput:    {},
+	types.StateWaitingApproval: {},
+}
+
+// CorrectAssistantState overrides a misdetected assistant state with the one the
+// user reports and returns a feedback entry with what the detector saw. Like
+// process calibration, the override holds until new output arrives and the
+// detector runs again.
+func (s *Session) CorrectAssistantState(state types.State) (*tables.AIStateCorrectionTable, error) {
+	if _, ok := correctableStates[state]; !ok {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidAssistantState, state)
+	}
+	tracker := s.assistantTracker
+	if tracker == nil {
+		return nil, ErrNoAssistantTracked
+	}
+	assistant := tracker.AssistantType()
+	if assistant == "" || assistant == types.AssistantTypeUnknown {
+		return nil, ErrNoAssistantTracked
+	}
+	detected, _ := tracker.State()
+	if detected == state {
+		return nil, fmt.Errorf("%w: already %s", ErrInvalidAssistantState, state)
+	}
+	confidence, match := tracker.StateConfidence()
+
+	s.mu.RLock()
+	rows, cols := s.rows, s.cols
+	s.mu.RUnlock()
+	data := bytes.Join(s.Scrollback(), nil)
+	output := data
+	if overflow := len(output) - stateCorrectionOutputBytes; overflow > 0 {
+		output = output[overflow:]
+	}
+	entry := &tables.AIStateCorrectionTable{
+		SessionID:          s.id,
+		ProjectID:          s.projectID,
+		Assistant:          string(assistant),
+		DetectedState:      string(detected),
+		DetectedMatch:      match,
+		DetectedConfidence: string(confidence),
+		CorrectState:       string(state),
+		Screen:             renderScreenText(data, rows, cols),
+		Output:             append([]byte(nil), output...),
+		Rows:               rows,
+		Cols:               cols,
+	}
+
+	if !tracker.Calibrate(state, stateCorrectionMatch) {
+		return nil, ErrNoAssistantTracked
+	}
+	s.logger.Info("assistant state corrected by user",
+		zap.String("sessionId", s.id),
+		zap.String("from", entry.DetectedState),
+		zap.String("to", entry.CorrectState))
+	return entry, nil
+}
+
+// CorrectAssistantState applies a user correction to a session and stores it as
+// detector feedback. Failing to store the feedback does not undo the correction.
+func (m *Manager) CorrectAssistantState(sessionID string, state types.State, note string) (*tables.AIStateCorrectionTable, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	entry, err := session.CorrectAssistantState(state)
+	if err != nil {
+		return nil, err
+	}
+	entry.Note = note
+	if err := (&model.AIStateCorrectionService{}).RecordAIStateCorrection(context.Background(), entry); err != nil {
+		m.logger.Debug("record ai state correction failed", zap.Error(err), zap.String("sessionId", sessionID))
+	}
+	return entry, nil
+}
@@ -0,0 +1,49 @@
+package terminal
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"code-kanban/utils/ai_assistant2"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+func TestSessionCorrectAssistantState(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 10, Cols: 40, ScrollbackLimit: 4096})
+	if _, err := session.CorrectAssistantState(types.StateWaitingInput); !errors.Is(err, ErrNoAssistantTracked) {
+		t.Fatalf("expected ErrNoAssistantTracked, got %v", err)
+	}
+
+	tracker := session.assistantTracker
+	tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+	tracker.Activate(types.AssistantTypeCodex, 10, 40)
+	defer tracker.Deactivate()
+	tracker.Calibrate(types.StateWorking, "test")
+	session.appendScrollback([]byte("› Ready for input\r\n"))
+
+	if _, err := session.CorrectAssistantState(types.StateUnknown); !errors.Is(err, ErrInvalidAssistantState) {
+		t.Fatalf("expected ErrInvalidAssistantState for unknown, got %v", err)
+	}
+	if _, err := session.CorrectAssistantState(types.StateWorking); !errors.Is(err, ErrInvalidAssistantState) {
+		t.Fatalf("expected ErrInvalidAssistantState for current state, got %v", err)
+	}
+
+	entry, err := session.CorrectAssistantState(types.StateWaitingInput)
+	if err != nil {
+		t.Fatalf("CorrectAssistantState: %v", err)
+	}
+	if entry.Assistant != string(types.AssistantTypeCodex) || entry.DetectedState != "working" ||
+		entry.DetectedMatch != "test" || entry.CorrectState != "waiting_input" || entry.Rows != 10 || entry.Cols != 40 {
+		t.Fatalf("unexpected correction %+v", entry)
+	}
+	if !strings.Contains(entry.Screen, "Ready for input") || string(entry.Output) != "› Ready for input\r\n" {
+		t.Fatalf("unexpected captured screen %q output %q", entry.Screen, entry.Output)
+	}
+	if state, _ := tracker.State(); state != types.StateWaitingInput {
+		t.Fatalf("expected corrected state, got %s", state)
+	}
+	if _, match := tracker.StateConfidence(); match != stateCorrectionMatch {
+		t.Fatalf("expected correction match, got %q", match)
+	}
+}
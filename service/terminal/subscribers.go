@@ -0,0 +1,80 @@
+package terminal
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// subscriberStaleAfter channel 持续已满超过该时长的订阅者视为泄漏（没有 Close 也不再读取）
+const subscriberStaleAfter = 2 * time.Minute
+
+func (sub *sessionSubscriber) markDelivered(now time.Time) {
+	sub.lastDeliveredAt.Store(now.UnixNano())
+	sub.fullSince.Store(0)
+}
+
+func (sub *sessionSubscriber) markFull(now time.Time) {
+	sub.fullSince.CompareAndSwap(0, now.UnixNano())
+}
+
+// stale reports whether the channel is still full and has been dropping events
+// for at least after. A slow consumer that drained part of its backlog since
+// the last drop is not stale.
+func (sub *sessionSubscriber) stale(now time.Time, after time.Duration) bool {
+	since := sub.fullSince.Load()
+	if since == 0 || len(sub.ch) < cap(sub.ch) {
+		return false
+	}
+	return now.Sub(time.Unix(0, since)) >= after
+}
+
+// SubscriberCount returns the number of active stream subscribers.
+func (s *Session) SubscriberCount() int {
+	s.subMu.RLock()
+	defer s.subMu.RUnlock()
+	return len(s.subscribers)
+}
+
+// pruneStaleSubscribers removes subscribers whose channel has stayed full for
+// longer than after, which usually means the owner forgot to Close the stream.
+// Their channel is closed so a consumer that is merely stuck sees the stream end.
+func (s *Session) pruneStaleSubscribers(now time.Time, after time.Duration) int {
+	pruned := 0
+	for _, sub := range s.snapshotSubscribers() {
+		if !sub.stale(now, after) {
+			continue
+		}
+		s.logger.Warn("removing terminal subscriber that stopped consuming events",
+			zap.String("sessionId", s.id),
+			zap.String("subscriberId", sub.id),
+			zap.Time("fullSince", time.Unix(0, sub.fullSince.Load())),
+			zap.Int("backlog", len(sub.ch)))
+		s.removeSubscriber(sub.id)
+		if sub.cancel != nil {
+			sub.cancel()
+		}
+		pruned++
+	}
+	return pruned
+}
+
+// SubscriberCount returns the number of stream subscribers across all sessions.
+func (m *Manager) SubscriberCount() int {
+	count := 0
+	m.sessions.Range(func(_ string, session *Session) bool {
+		count += session.SubscriberCount()
+		return true
+	})
+	return count
+}
+
+// pruneStaleSubscribers runs the leak sweep on every session.
+func (m *Manager) pruneStaleSubscribers(now time.Time) {
+	m.sessions.Range(func(_ string, session *Session) bool {
+		if pruned := session.pruneStaleSubscribers(now, subscriberStaleAfter); pruned > 0 {
+			m.subscribersPruned.Add(int64(pruned))
+		}
+		return true
+	})
+}
@@ -0,0 +1,78 @@
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestSessionPruneStaleSubscribers(t *testing.T) {
+	mgr := NewManager(Config{}, zap.NewNop())
+	session := newTestSession(t, SessionParams{ID: "s1", ProjectID: "p1"})
+	if err := mgr.addSession(session); err != nil {
+		t.Fatalf("addSession failed: %v", err)
+	}
+
+	leaked, err := session.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	active, err := session.Subscribe(context.Background())
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer active.Close()
+	if got := mgr.SubscriberCount(); got != 2 {
+		t.Fatalf("expected 2 subscribers, got %d", got)
+	}
+
+	for i := 0; i < subscriberBufferSize+1; i++ {
+		session.broadcast(StreamEvent{Type: StreamEventData, Data: []byte("x")})
+	}
+	// active 读走一条后不再是满的，不应被清理
+	<-active.Events()
+
+	later := time.Now().Add(subscriberStaleAfter)
+	if pruned := session.pruneStaleSubscribers(time.Now(), subscriberStaleAfter); pruned != 0 {
+		t.Fatalf("expected nothing pruned before the threshold, got %d", pruned)
+	}
+	mgr.pruneStaleSubscribers(later)
+	if got := session.SubscriberCount(); got != 1 {
+		t.Fatalf("expected leaked subscriber to be removed, got %d subscribers", got)
+	}
+	for range leaked.Events() {
+	}
+
+	var buf bytes.Buffer
+	if err := mgr.WriteMetrics(&buf); err != nil {
+		t.Fatalf("WriteMetrics failed: %v", err)
+	}
+	if !strings.Contains(buf.String(), "terminal_subscribers 1\n") ||
+		!strings.Contains(buf.String(), "terminal_subscribers_pruned_total 1\n") {
+		t.Fatalf("unexpected metrics output:\n%s", buf.String())
+	}
+}
+
+func TestSessionRemoveSubscriberDuringBroadcast(t *testing.T) {
+	session := newTestSession(t, SessionParams{ID: "s1", ProjectID: "p1"})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 2000; i++ {
+			session.broadcast(StreamEvent{Type: StreamEventData, Data: []byte("x")})
+		}
+	}()
+	// 关闭与发送并发进行，向已关闭的 channel 发送会直接 panic
+	for i := 0; i < 200; i++ {
+		stream, err := session.Subscribe(context.Background())
+		if err != nil {
+			t.Fatalf("Subscribe failed: %v", err)
+		}
+		session.removeSubscriber(stream.id)
+	}
+	<-done
+}
@@ -0,0 +1,93 @@
+package terminal
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// SessionTaskDraft 由终端会话生成看板任务卡片所需的内容
+type SessionTaskDraft struct {
+	Title         string     `json:"title"`
+	Description   string     `json:"description"`
+	AssistantType string     `json:"assistantType,omitempty"`
+	LastUserInput string     `json:"lastUserInput,omitempty"`
+	CompletedAt   *time.Time `json:"completedAt,omitempty"`
+}
+
+// SessionTaskDraft builds a task card from the session title, last user input,
+// assistant type and the time the assistant last completed.
+func (m *Manager) SessionTaskDraft(sessionID string) (*SessionTaskDraft, error) {
+	session, err := m.GetSession(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var completedAt *time.Time
+	if record := m.recordManager.LastCompletionBySession(sessionID, "completed"); record != nil {
+		ts := record.CompletedAt
+		completedAt = &ts
+	}
+	return session.buildTaskDraft(completedAt), nil
+}
+
+func (s *Session) buildTaskDraft(completedAt *time.Time) *SessionTaskDraft {
+	input := sanitizeCapturedInput(s.LastRecentInput())
+	draft := &SessionTaskDraft{
+		AssistantType: s.assistantTypeName(),
+		LastUserInput: input,
+		CompletedAt:   completedAt,
+	}
+
+	title := strings.TrimSpace(s.Title())
+	if title == "" {
+		title = input
+	}
+	if title == "" {
+		title = "AI task"
+	}
+	draft.Title = truncateString(title, 100)
+
+	sessionLabel := strings.TrimSpace(s.Title())
+	if sessionLabel == "" {
+		sessionLabel = s.id
+	}
+	lines := []string{fmt.Sprintf(`Archived from terminal session "%s".`, sessionLabel)}
+	if input != "" {
+		lines = append(lines, fmt.Sprintf("Last input: %s", input))
+	}
+	var meta []string
+	if draft.AssistantType != "" {
+		meta = append(meta, fmt.Sprintf("Assistant: %s", s.assistantDisplayName()))
+	}
+	if completedAt != nil {
+		meta = append(meta, fmt.Sprintf("Completed at: %s", completedAt.Format(time.RFC3339)))
+	}
+	if dir := strings.TrimSpace(s.CurrentDir()); dir != "" {
+		meta = append(meta, fmt.Sprintf("Working directory: %s", dir))
+	}
+	if len(meta) > 0 {
+		lines = append(lines, strings.Join(meta, " | "))
+	}
+	draft.Description = strings.Join(lines, "\n")
+	return draft
+}
+
+// assistantTypeName returns the detected assistant type, empty when no assistant ran in the session.
+func (s *Session) assistantTypeName() string {
+	s.metaMu.RLock()
+	if s.lastMetadata != nil && s.lastMetadata.AIAssistant != nil && s.lastMetadata.AIAssistant.Type != "" {
+		assistantType := s.lastMetadata.AIAssistant.Type
+		s.metaMu.RUnlock()
+		return assistantType
+	}
+	s.metaMu.RUnlock()
+
+	if tracker := s.assistantTracker; tracker != nil {
+		if assistantType := tracker.AssistantType(); assistantType != "" && assistantType != types.AssistantTypeUnknown {
+			return assistantType.String()
+		}
+	}
+	return ""
+}
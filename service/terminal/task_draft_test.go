@@ -0,0 +1,54 @@
+package terminal
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+func TestSessionBuildTaskDraft(t *testing.T) {
+	session := newTestSession(t, SessionParams{ID: "session-1", Title: "Refactor parser", WorkingDir: t.TempDir()})
+	session.mu.Lock()
+	session.lastRecentInput = "  split   the lexer\ninto its own file "
+	session.mu.Unlock()
+	session.metaMu.Lock()
+	session.lastMetadata = &SessionMetadata{AIAssistant: &ai_assistant2.AIAssistantInfo{Type: "claude-code", DisplayName: "Claude Code"}}
+	session.metaMu.Unlock()
+
+	completedAt := time.Date(2025, 1, 2, 3, 4, 5, 0, time.UTC)
+	draft := session.buildTaskDraft(&completedAt)
+
+	if draft.Title != "Refactor parser" {
+		t.Fatalf("unexpected title %q", draft.Title)
+	}
+	if draft.AssistantType != "claude-code" {
+		t.Fatalf("unexpected assistant type %q", draft.AssistantType)
+	}
+	for _, want := range []string{
+		"Last input: split the lexer into its own file",
+		"Assistant: Claude Code",
+		"Completed at: 2025-01-02T03:04:05Z",
+	} {
+		if !strings.Contains(draft.Description, want) {
+			t.Fatalf("description %q missing %q", draft.Description, want)
+		}
+	}
+}
+
+func TestSessionBuildTaskDraftFallsBackToInput(t *testing.T) {
+	session := newTestSession(t, SessionParams{ID: "session-2"})
+	session.mu.Lock()
+	session.title = ""
+	session.lastRecentInput = "fix flaky test"
+	session.mu.Unlock()
+
+	draft := session.buildTaskDraft(nil)
+	if draft.Title != "fix flaky test" {
+		t.Fatalf("expected title from last input, got %q", draft.Title)
+	}
+	if draft.CompletedAt != nil || strings.Contains(draft.Description, "Completed at") {
+		t.Fatalf("unexpected completion time in draft %+v", draft)
+	}
+}
@@ -0,0 +1,83 @@
+package terminal
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DefaultTermType is exported as TERM when neither the config nor the session overrides it.
+const DefaultTermType = "xterm-256color"
+
+// ErrInvalidTermEnv indicates an unsupported TERM value or terminal capability variable.
+var ErrInvalidTermEnv = errors.New("invalid terminal environment")
+
+// termTypePattern terminfo 名称只包含字母数字与 ._+-，同时排除换行、空格等可注入的字符
+var termTypePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]{0,63}$`)
+
+// termEnvAllowed 允许随 TERM 一起覆盖的终端能力变量，其他变量（如 PATH、LD_PRELOAD）一律拒绝
+var termEnvAllowed = map[string]struct{}{
+	"COLORTERM":            {},
+	"TERM_PROGRAM":         {},
+	"TERM_PROGRAM_VERSION": {},
+}
+
+const termEnvValueMaxLen = 64
+
+// resolveTermType returns the TERM to use, falling back to fallback and then DefaultTermType.
+func resolveTermType(term, fallback string) (string, error) {
+	term = strings.TrimSpace(term)
+	if term == "" {
+		term = strings.TrimSpace(fallback)
+	}
+	if term == "" {
+		return DefaultTermType, nil
+	}
+	if !termTypePattern.MatchString(term) {
+		return "", fmt.Errorf("%w: TERM %q", ErrInvalidTermEnv, term)
+	}
+	return term, nil
+}
+
+// buildTermEnv merges the configured and session capability variables (session
+// values win) into sorted KEY=VALUE pairs after validating keys and values.
+func buildTermEnv(base, override map[string]string) ([]string, error) {
+	merged := make(map[string]string, len(base)+len(override))
+	for _, vars := range []map[string]string{base, override} {
+		for key, value := range vars {
+			key = strings.ToUpper(strings.TrimSpace(key))
+			if _, ok := termEnvAllowed[key]; !ok {
+				return nil, fmt.Errorf("%w: %s is not allowed, only COLORTERM, TERM_PROGRAM and TERM_PROGRAM_VERSION", ErrInvalidTermEnv, key)
+			}
+			if !validTermEnvValue(value) {
+				return nil, fmt.Errorf("%w: invalid value for %s", ErrInvalidTermEnv, key)
+			}
+			merged[key] = value
+		}
+	}
+
+	keys := make([]string, 0, len(merged))
+	for key := range merged {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	env := make([]string, 0, len(keys))
+	for _, key := range keys {
+		env = append(env, key+"="+merged[key])
+	}
+	return env, nil
+}
+
+func validTermEnvValue(value string) bool {
+	if len(value) > termEnvValueMaxLen {
+		return false
+	}
+	for _, r := range value {
+		if r < 0x20 || r == 0x7f {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,54 @@
+package terminal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestResolveTermType(t *testing.T) {
+	cases := []struct {
+		term, fallback, want string
+		wantErr              bool
+	}{
+		{"", "", DefaultTermType, false},
+		{"", "tmux-256color", "tmux-256color", false},
+		{" xterm-kitty ", "tmux-256color", "xterm-kitty", false},
+		{"xterm\nLD_PRELOAD=/tmp/x.so", "", "", true},
+		{"xterm 256", "", "", true},
+		{"-xterm", "", "", true},
+	}
+	for _, tc := range cases {
+		got, err := resolveTermType(tc.term, tc.fallback)
+		if tc.wantErr {
+			if !errors.Is(err, ErrInvalidTermEnv) {
+				t.Fatalf("resolveTermType(%q) expected ErrInvalidTermEnv, got %q %v", tc.term, got, err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Fatalf("resolveTermType(%q, %q) = %q, %v; want %q", tc.term, tc.fallback, got, err, tc.want)
+		}
+	}
+}
+
+func TestBuildTermEnv(t *testing.T) {
+	env, err := buildTermEnv(
+		map[string]string{"COLORTERM": "truecolor", "TERM_PROGRAM": "vscode"},
+		map[string]string{"term_program": "WezTerm"},
+	)
+	if err != nil {
+		t.Fatalf("buildTermEnv failed: %v", err)
+	}
+	want := []string{"COLORTERM=truecolor", "TERM_PROGRAM=WezTerm"}
+	if !reflect.DeepEqual(env, want) {
+		t.Fatalf("unexpected env %v, want %v", env, want)
+	}
+
+	if _, err := buildTermEnv(nil, map[string]string{"PATH": "/tmp"}); !errors.Is(err, ErrInvalidTermEnv) {
+		t.Fatalf("expected PATH to be rejected, got %v", err)
+	}
+	if _, err := buildTermEnv(nil, map[string]string{"COLORTERM": "truecolor\x1b]0;x\x07"}); !errors.Is(err, ErrInvalidTermEnv) {
+		t.Fatalf("expected control characters to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package terminal
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+const (
+	// titleRuleBufferMaxBytes 标题规则只检查最近的输出
+	titleRuleBufferMaxBytes = 8 * 1024
+	// titleRuleDebounce 两次按规则改名的最小间隔，避免输出刷屏时标签频繁跳动
+	titleRuleDebounce = 5 * time.Second
+)
+
+// TitleRule sets the session title from output: when a rendered line matches
+// Pattern, capture group Group becomes the title.
+type TitleRule struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	// Group 作为标题的捕获组，0 表示第一个捕获组，没有捕获组时使用整个匹配
+	Group int `json:"group,omitempty" yaml:"group"`
+	// Assistant 限定规则只在该类型的 AI 助手运行时生效，为空对所有会话生效
+	Assistant string `json:"assistant,omitempty" yaml:"assistant"`
+}
+
+type compiledTitleRule struct {
+	TitleRule
+	re    *regexp.Regexp
+	group int
+}
+
+// compileTitleRules validates title rules; the index of an invalid rule is reported.
+func compileTitleRules(rules []TitleRule) ([]*compiledTitleRule, error) {
+	compiled := make([]*compiledTitleRule, 0, len(rules))
+	for i, rule := range rules {
+		if strings.TrimSpace(rule.Pattern) == "" {
+			return nil, fmt.Errorf("title rule %d: pattern is required", i)
+		}
+		re, err := regexp.Compile(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("title rule %d: invalid pattern: %w", i, err)
+		}
+		group := rule.Group
+		if group < 0 || group > re.NumSubexp() {
+			return nil, fmt.Errorf("title rule %d: group %d does not exist in pattern", i, rule.Group)
+		}
+		if group == 0 && re.NumSubexp() > 0 {
+			group = 1
+		}
+		if rule.Assistant != "" && !knownAssistantType(rule.Assistant) {
+			return nil, fmt.Errorf("title rule %d: unknown assistant %q", i, rule.Assistant)
+		}
+		compiled = append(compiled, &compiledTitleRule{TitleRule: rule, re: re, group: group})
+	}
+	return compiled, nil
+}
+
+// setTitleRules replaces the title rules of the session.
+func (s *Session) setTitleRules(rules []*compiledTitleRule) {
+	s.titleRuleMu.Lock()
+	s.titleRules = rules
+	if len(rules) == 0 {
+		s.titleRuleBuffer = nil
+		s.titleRuleChecked = 0
+	}
+	s.titleRuleMu.Unlock()
+}
+
+// checkTitleRules renders recent output and applies the title from the newest
+// matching line. Manually set and task locked titles are never replaced. The
+// buffer is cleared after a rename so the same line is not matched again; within
+// titleRuleDebounce of the last rename the buffer keeps accumulating and the
+// newest match is applied once the interval has passed. Output not checked yet
+// is first matched without rendering, and the screen is only rendered when a
+// rule matches it there.
+func (s *Session) checkTitleRules(chunk []byte) {
+	s.titleRuleMu.Lock()
+	if len(s.titleRules) == 0 {
+		s.titleRuleMu.Unlock()
+		return
+	}
+	// 预筛从未检查输出所在行的行首开始，跨分片的行也能命中
+	start := bytes.LastIndexByte(s.titleRuleBuffer[:s.titleRuleChecked], '\n') + 1
+	s.titleRuleBuffer = append(s.titleRuleBuffer, chunk...)
+	if overflow := len(s.titleRuleBuffer) - titleRuleBufferMaxBytes; overflow > 0 {
+		s.titleRuleBuffer = append([]byte(nil), s.titleRuleBuffer[overflow:]...)
+		start = max(start-overflow, 0)
+		s.titleRuleChecked = max(s.titleRuleChecked-overflow, 0)
+	}
+	now := time.Now()
+	if now.Sub(s.titleRuleAppliedAt) < titleRuleDebounce {
+		s.titleRuleMu.Unlock()
+		return
+	}
+	pending := outputPreMatchLines(s.titleRuleBuffer[start:])
+	s.titleRuleChecked = len(s.titleRuleBuffer)
+	if !slices.ContainsFunc(s.titleRules, func(rule *compiledTitleRule) bool { return preMatchLines(rule.re, pending) }) {
+		s.titleRuleMu.Unlock()
+		return
+	}
+	data := append([]byte(nil), s.titleRuleBuffer...)
+	rules := s.titleRules
+	s.titleRuleMu.Unlock()
+
+	s.mu.RLock()
+	rows, cols := s.rows, s.cols
+	s.mu.RUnlock()
+
+	title, rule := matchTitleRules(rules, s.renderLogicalLines(data, rows, cols), s.currentAssistantType())
+	if title == "" {
+		return
+	}
+
+	s.titleRuleMu.Lock()
+	s.titleRuleBuffer = nil
+	s.titleRuleChecked = 0
+	s.titleRuleMu.Unlock()
+	if !s.applyRuleTitle(title) {
+		return
+	}
+	s.titleRuleMu.Lock()
+	s.titleRuleAppliedAt = now
+	s.titleRuleMu.Unlock()
+
+	s.logger.Debug("terminal title set by output rule",
+		zap.String("sessionId", s.id),
+		zap.String("pattern", rule.Pattern),
+		zap.String("title", title))
+	s.notifyTitleChanged()
+}
+
+// matchTitleRules returns the title extracted from the last line matched by a
+// rule applying to assistant; rules earlier in the list win on the same line.
+func matchTitleRules(rules []*compiledTitleRule, lines []ai_assistant2.LogicalLine, assistant string) (string, *compiledTitleRule) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		text := lines[i].Text
+		if text == "" {
+			continue
+		}
+		for _, rule := range rules {
+			if rule.Assistant != "" && rule.Assistant != assistant {
+				continue
+			}
+			match := rule.re.FindStringSubmatch(text)
+			if match == nil {
+				continue
+			}
+			title := strings.TrimSpace(truncateString(sanitizeCapturedInput(match[rule.group]), maxSessionTitleLength))
+			if title != "" {
+				return title, rule
+			}
+		}
+	}
+	return "", nil
+}
+
+// applyRuleTitle sets a title found by an output rule unless the user renamed
+// the session or a linked task locked the title. It reports whether the title
+// changed.
+func (s *Session) applyRuleTitle(title string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.manualTitle || s.lockedTitle != "" || s.title == title {
+		return false
+	}
+	s.title = title
+	return true
+}
@@ -0,0 +1,80 @@
+package terminal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCompileTitleRules(t *testing.T) {
+	if _, err := compileTitleRules([]TitleRule{{Pattern: `Task: (.+)`, Group: 2}}); err == nil {
+		t.Fatalf("expected missing group to fail")
+	}
+	if _, err := compileTitleRules([]TitleRule{{Pattern: `Task`, Assistant: "nope"}}); err == nil {
+		t.Fatalf("expected unknown assistant to fail")
+	}
+	compiled, err := compileTitleRules([]TitleRule{{Pattern: `Task: (.+)`}, {Pattern: `^# .+`}})
+	if err != nil {
+		t.Fatalf("compileTitleRules failed: %v", err)
+	}
+	if compiled[0].group != 1 || compiled[1].group != 0 {
+		t.Fatalf("unexpected default groups %d %d", compiled[0].group, compiled[1].group)
+	}
+}
+
+func TestSessionTitleRules(t *testing.T) {
+	session := newTestSession(t, SessionParams{Title: "shell"})
+	rules, err := compileTitleRules([]TitleRule{
+		{Pattern: `Plan: (.+)`, Assistant: "claude-code"},
+		{Pattern: `Task: (.+)`},
+	})
+	if err != nil {
+		t.Fatalf("compileTitleRules failed: %v", err)
+	}
+	session.setTitleRules(rules)
+
+	// 限定助手类型的规则在没有助手时不生效
+	session.checkTitleRules([]byte("Plan: ignored\r\n"))
+	if got := session.Title(); got != "shell" {
+		t.Fatalf("expected assistant scoped rule to be skipped, got %q", got)
+	}
+
+	session.checkTitleRules([]byte("Task: Fix   login\r\nworking...\r\n"))
+	if got := session.Title(); got != "Fix login" {
+		t.Fatalf("expected title from output, got %q", got)
+	}
+
+	// 防抖期间不改名，过后使用最新的匹配
+	session.checkTitleRules([]byte("Task: Second\r\n"))
+	if got := session.Title(); got != "Fix login" {
+		t.Fatalf("expected debounced rename, got %q", got)
+	}
+	session.titleRuleMu.Lock()
+	session.titleRuleAppliedAt = time.Now().Add(-titleRuleDebounce)
+	session.titleRuleMu.Unlock()
+	session.checkTitleRules([]byte("Task: Third\r\n"))
+	if got := session.Title(); got != "Third" {
+		t.Fatalf("expected newest match after debounce, got %q", got)
+	}
+
+	// 防抖期间到达的匹配在之后不含匹配的输出到来时仍会被应用
+	session.checkTitleRules([]byte("Task: Fifth\r\n"))
+	session.titleRuleMu.Lock()
+	session.titleRuleAppliedAt = time.Now().Add(-titleRuleDebounce)
+	session.titleRuleMu.Unlock()
+	session.checkTitleRules([]byte("idle\r\n"))
+	if got := session.Title(); got != "Fifth" {
+		t.Fatalf("expected the match seen during the debounce, got %q", got)
+	}
+
+	// 手动改名后规则不再覆盖
+	if err := session.UpdateTitle("mine"); err != nil {
+		t.Fatalf("UpdateTitle failed: %v", err)
+	}
+	session.titleRuleMu.Lock()
+	session.titleRuleAppliedAt = time.Time{}
+	session.titleRuleMu.Unlock()
+	session.checkTitleRules([]byte("Task: Fourth\r\n"))
+	if got := session.Title(); got != "mine" {
+		t.Fatalf("expected manual title to stay, got %q", got)
+	}
+}
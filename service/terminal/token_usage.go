@@ -0,0 +1,70 @@
+package terminal
+
+import (
+	"code-kanban/utils"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// TokenUsage 是会话或项目累计的 token 用量，缺少任一模型的价格时不给出估算金额
+type TokenUsage struct {
+	InputTokens      int64    `json:"inputTokens"`
+	OutputTokens     int64    `json:"outputTokens"`
+	EstimatedCostUSD *float64 `json:"estimatedCostUSD,omitempty"`
+}
+
+// estimateTokenUsage sums the runs and prices them with pricing, looked up by
+// model first and then by assistant type. It returns nil when nothing was used.
+func estimateTokenUsage(runs []types.TokenUsage, pricing map[string]utils.ModelPrice) *TokenUsage {
+	if len(runs) == 0 {
+		return nil
+	}
+	usage := &TokenUsage{}
+	cost, priced := 0.0, true
+	for _, run := range runs {
+		usage.InputTokens += run.InputTokens
+		usage.OutputTokens += run.OutputTokens
+		price, ok := lookupModelPrice(pricing, run)
+		if !ok {
+			priced = false
+			continue
+		}
+		cost += float64(run.InputTokens)*price.InputPerMillion/1e6 + float64(run.OutputTokens)*price.OutputPerMillion/1e6
+	}
+	if priced {
+		usage.EstimatedCostUSD = &cost
+	}
+	return usage
+}
+
+func lookupModelPrice(pricing map[string]utils.ModelPrice, run types.TokenUsage) (utils.ModelPrice, bool) {
+	if run.Model != "" {
+		if price, ok := pricing[run.Model]; ok {
+			return price, true
+		}
+	}
+	price, ok := pricing[string(run.Assistant)]
+	return price, ok
+}
+
+// sumTokenUsage aggregates usages of several sessions; the total has a cost only
+// when every non-empty usage has one.
+func sumTokenUsage(usages []*TokenUsage) *TokenUsage {
+	total := &TokenUsage{}
+	cost, priced := 0.0, true
+	for _, usage := range usages {
+		if usage == nil {
+			continue
+		}
+		total.InputTokens += usage.InputTokens
+		total.OutputTokens += usage.OutputTokens
+		if usage.EstimatedCostUSD == nil {
+			priced = false
+			continue
+		}
+		cost += *usage.EstimatedCostUSD
+	}
+	if priced {
+		total.EstimatedCostUSD = &cost
+	}
+	return total
+}
@@ -0,0 +1,58 @@
+package terminal
+
+import (
+	"go.uber.org/zap"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// AssistantTracking reports whether assistant state detection is paused and
+// the state it holds.
+type AssistantTracking struct {
+	Paused bool   `json:"paused"`
+	State  string `json:"state"`
+}
+
+// SetAssistantTrackingPaused pauses or resumes state detection of the tracked
+// assistant, e.g. while the user takes over the terminal for unrelated work so
+// the detector does not report bogus states or completions. Pausing a paused
+// session or resuming a running one is a no-op.
+func (s *Session) SetAssistantTrackingPaused(paused bool) (AssistantTracking, error) {
+	tracker := s.assistantTracker
+	if tracker == nil {
+		return AssistantTracking{}, ErrNoAssistantTracked
+	}
+	assistant := tracker.AssistantType()
+	if assistant == "" || assistant == types.AssistantTypeUnknown {
+		return AssistantTracking{}, ErrNoAssistantTracked
+	}
+
+	var changed bool
+	if paused {
+		changed = tracker.Pause()
+	} else {
+		changed = tracker.Resume()
+	}
+	state, _ := tracker.State()
+	result := AssistantTracking{Paused: tracker.Paused(), State: string(state)}
+	if !changed {
+		return result, nil
+	}
+
+	s.logger.Info("assistant tracking toggled",
+		zap.String("sessionId", s.id),
+		zap.Bool("paused", result.Paused))
+	s.metaMu.Lock()
+	if s.lastMetadata == nil || s.lastMetadata.AIAssistant == nil {
+		s.metaMu.Unlock()
+		return result, nil
+	}
+	metadata := cloneSessionMetadata(s.lastMetadata)
+	metadata.AIAssistant.TrackingPaused = result.Paused
+	metadata.TaskID = s.TaskID()
+	s.lastMetadata = metadata
+	s.metaMu.Unlock()
+
+	s.broadcast(StreamEvent{Type: StreamEventMetadata, Metadata: metadata})
+	return result, nil
+}
@@ -0,0 +1,46 @@
+package terminal
+
+import (
+	"errors"
+	"testing"
+
+	"code-kanban/utils/ai_assistant2"
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+func TestSessionAssistantTrackingPause(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 12, Cols: 60, ScrollbackLimit: 4096})
+	if _, err := session.SetAssistantTrackingPaused(true); !errors.Is(err, ErrNoAssistantTracked) {
+		t.Fatalf("expected ErrNoAssistantTracked, got %v", err)
+	}
+
+	tracker := session.assistantTracker
+	tracker.SetTrackingMode(ai_assistant2.TrackingModeVirtualTerminal)
+	tracker.Activate(types.AssistantTypeCodex, 12, 60)
+	defer tracker.Deactivate()
+	tracker.Calibrate(types.StateWorking, "test")
+
+	result, err := session.SetAssistantTrackingPaused(true)
+	if err != nil || !result.Paused || result.State != string(types.StateWorking) {
+		t.Fatalf("unexpected pause result %+v err=%v", result, err)
+	}
+	if again, err := session.SetAssistantTrackingPaused(true); err != nil || !again.Paused {
+		t.Fatalf("expected pausing twice to be a no-op, got %+v err=%v", again, err)
+	}
+
+	// 暂停期间校准与重新检测都不改变状态
+	if tracker.Calibrate(types.StateWaitingInput, "test") || tracker.Recheck() {
+		t.Fatalf("expected paused tracker to ignore calibration and rechecks")
+	}
+	if state, _ := tracker.State(); state != types.StateWorking {
+		t.Fatalf("expected state to be held while paused, got %s", state)
+	}
+
+	result, err = session.SetAssistantTrackingPaused(false)
+	if err != nil || result.Paused || tracker.Paused() {
+		t.Fatalf("unexpected resume result %+v err=%v", result, err)
+	}
+	if !tracker.Calibrate(types.StateWaitingInput, "test") {
+		t.Fatalf("expected calibration to work after resume")
+	}
+}
@@ -0,0 +1,173 @@
+package terminal
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/utils/ai_assistant2"
+)
+
+const (
+	// triggerBufferMaxBytes 触发器只检查最近的输出，足够覆盖一屏提示
+	triggerBufferMaxBytes  = 8 * 1024
+	defaultTriggerCooldown = 3 * time.Second
+)
+
+// OutputTrigger writes Input to the terminal when a rendered line matches Pattern.
+type OutputTrigger struct {
+	Pattern string `json:"pattern" yaml:"pattern"`
+	Input   string `json:"input" yaml:"input"`
+	// CooldownSeconds 同一触发器两次执行的最小间隔，0 使用默认 3 秒
+	CooldownSeconds int `json:"cooldownSeconds,omitempty" yaml:"cooldownSeconds"`
+}
+
+type compiledTrigger struct {
+	OutputTrigger
+	re        *regexp.Regexp
+	cooldown  time.Duration
+	lastFired time.Time
+}
+
+// compileOutputTriggers validates trigger patterns; the index of an invalid trigger is reported.
+func compileOutputTriggers(triggers []OutputTrigger) ([]*compiledTrigger, error) {
+	compiled := make([]*compiledTrigger, 0, len(triggers))
+	for i, trigger := range triggers {
+		if strings.TrimSpace(trigger.Pattern) == "" {
+			return nil, fmt.Errorf("trigger %d: pattern is required", i)
+		}
+		if trigger.Input == "" {
+			return nil, fmt.Errorf("trigger %d: input is required", i)
+		}
+		re, err := regexp.Compile(trigger.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("trigger %d: invalid pattern: %w", i, err)
+		}
+		cooldown := time.Duration(trigger.CooldownSeconds) * time.Second
+		if cooldown <= 0 {
+			cooldown = defaultTriggerCooldown
+		}
+		compiled = append(compiled, &compiledTrigger{OutputTrigger: trigger, re: re, cooldown: cooldown})
+	}
+	return compiled, nil
+}
+
+// setGlobalTriggers replaces the triggers shared by all sessions.
+func (s *Session) setGlobalTriggers(triggers []*compiledTrigger) {
+	s.triggerMu.Lock()
+	s.globalTriggers = cloneCompiledTriggers(triggers)
+	s.resetTriggerBufferLocked()
+	s.triggerMu.Unlock()
+}
+
+// SetOutputTriggers replaces the session scoped triggers.
+func (s *Session) SetOutputTriggers(triggers []OutputTrigger) error {
+	compiled, err := compileOutputTriggers(triggers)
+	if err != nil {
+		return err
+	}
+	s.triggerMu.Lock()
+	s.sessionTriggers = compiled
+	s.resetTriggerBufferLocked()
+	s.triggerMu.Unlock()
+	return nil
+}
+
+// OutputTriggers returns the session scoped triggers.
+func (s *Session) OutputTriggers() []OutputTrigger {
+	s.triggerMu.Lock()
+	defer s.triggerMu.Unlock()
+	result := make([]OutputTrigger, 0, len(s.sessionTriggers))
+	for _, trigger := range s.sessionTriggers {
+		result = append(result, trigger.OutputTrigger)
+	}
+	return result
+}
+
+func (s *Session) resetTriggerBufferLocked() {
+	if len(s.globalTriggers) == 0 && len(s.sessionTriggers) == 0 {
+		s.triggerBuffer = nil
+	}
+}
+
+// checkOutputTriggers renders recent output and writes the input of the first
+// matching trigger in the background. The buffer is cleared after firing so the
+// same prompt is not matched again, and each trigger additionally honours its
+// cooldown.
+func (s *Session) checkOutputTriggers(chunk []byte) {
+	s.triggerMu.Lock()
+	if len(s.globalTriggers) == 0 && len(s.sessionTriggers) == 0 {
+		s.triggerMu.Unlock()
+		return
+	}
+	s.triggerBuffer = append(s.triggerBuffer, chunk...)
+	if overflow := len(s.triggerBuffer) - triggerBufferMaxBytes; overflow > 0 {
+		s.triggerBuffer = append([]byte(nil), s.triggerBuffer[overflow:]...)
+	}
+	data := append([]byte(nil), s.triggerBuffer...)
+	triggers := make([]*compiledTrigger, 0, len(s.sessionTriggers)+len(s.globalTriggers))
+	triggers = append(triggers, s.sessionTriggers...)
+	triggers = append(triggers, s.globalTriggers...)
+	s.triggerMu.Unlock()
+
+	s.mu.RLock()
+	rows, cols := s.rows, s.cols
+	s.mu.RUnlock()
+
+	lines := s.renderLogicalLines(data, rows, cols)
+	now := time.Now()
+	for _, trigger := range triggers {
+		if !trigger.matches(lines) {
+			continue
+		}
+
+		s.triggerMu.Lock()
+		if now.Sub(trigger.lastFired) < trigger.cooldown {
+			s.triggerMu.Unlock()
+			continue
+		}
+		trigger.lastFired = now
+		s.triggerBuffer = nil
+		s.triggerMu.Unlock()
+
+		// 输出路径上不能等待写入：程序不读取输入时 PTY 缓冲写满会阻塞读取
+		go s.writeTriggerInput(trigger.Pattern, trigger.Input)
+		return
+	}
+}
+
+func (s *Session) writeTriggerInput(pattern, input string) {
+	if err := s.WriteInput([]byte(input)); err != nil {
+		s.logger.Warn("failed to write trigger input",
+			zap.String("sessionId", s.id),
+			zap.String("pattern", pattern),
+			zap.Error(err))
+		return
+	}
+	s.logger.Debug("terminal output trigger fired",
+		zap.String("sessionId", s.id),
+		zap.String("pattern", pattern))
+}
+
+func (t *compiledTrigger) matches(lines []ai_assistant2.LogicalLine) bool {
+	for _, line := range lines {
+		if line.Text != "" && t.re.MatchString(line.Text) {
+			return true
+		}
+	}
+	return false
+}
+
+// cloneCompiledTriggers gives every session its own cooldown state for shared triggers.
+func cloneCompiledTriggers(triggers []*compiledTrigger) []*compiledTrigger {
+	result := make([]*compiledTrigger, 0, len(triggers))
+	for _, trigger := range triggers {
+		copied := *trigger
+		copied.lastFired = time.Time{}
+		result = append(result, &copied)
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+package terminal
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/x/xpty"
+	"go.uber.org/zap"
+)
+
+func TestCompileOutputTriggers(t *testing.T) {
+	if _, err := compileOutputTriggers([]OutputTrigger{{Pattern: "(", Input: "y"}}); err == nil {
+		t.Fatalf("expected invalid pattern to fail")
+	}
+	if _, err := compileOutputTriggers([]OutputTrigger{{Pattern: "continue", Input: ""}}); err == nil {
+		t.Fatalf("expected empty input to fail")
+	}
+	compiled, err := compileOutputTriggers([]OutputTrigger{{Pattern: "continue", Input: "\r"}})
+	if err != nil || len(compiled) != 1 || compiled[0].cooldown != defaultTriggerCooldown {
+		t.Fatalf("unexpected compile result %+v %v", compiled, err)
+	}
+}
+
+func TestSessionOutputTriggerFires(t *testing.T) {
+	session := newTestSession(t, SessionParams{
+		Command: []string{"sh", "-c", `printf 'Press any key to continue'; read answer; echo "got:$answer"; sleep 5`},
+	})
+	if err := session.SetOutputTriggers([]OutputTrigger{{Pattern: `Press any key`, Input: "ok\r"}}); err != nil {
+		t.Fatalf("SetOutputTriggers failed: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := session.Subscribe(ctx)
+	if err != nil {
+		t.Fatalf("Subscribe failed: %v", err)
+	}
+	defer stream.Close()
+	if err := session.Start(ctx); err != nil {
+		t.Skipf("pty not available: %v", err)
+	}
+	defer session.Close()
+
+	var output strings.Builder
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case event := <-stream.Events():
+			if event.Type != StreamEventData {
+				continue
+			}
+			output.Write(event.Data)
+			if strings.Contains(output.String(), "got:ok") {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("trigger did not answer the prompt, output %q", output.String())
+		}
+	}
+}
+
+// blockingPty blocks writes until release is closed.
+type blockingPty struct {
+	xpty.Pty
+	release chan struct{}
+	written chan []byte
+}
+
+func (p *blockingPty) Write(data []byte) (int, error) {
+	<-p.release
+	p.written <- append([]byte(nil), data...)
+	return len(data), nil
+}
+
+func TestOutputTriggerDoesNotBlockOutput(t *testing.T) {
+	session := newTestSession(t, SessionParams{Rows: 5, Cols: 40})
+	device := &blockingPty{release: make(chan struct{}), written: make(chan []byte, 1)}
+	session.pty = device
+	session.inputReady = true
+	session.setStatus(SessionStatusRunning)
+	if err := session.SetOutputTriggers([]OutputTrigger{{Pattern: `continue\?`, Input: "y\r"}}); err != nil {
+		t.Fatalf("SetOutputTriggers failed: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		session.checkOutputTriggers([]byte("continue? "))
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("trigger blocked the output path while the pty was not accepting input")
+	}
+
+	close(device.release)
+	select {
+	case data := <-device.written:
+		if string(data) != "y\r" {
+			t.Fatalf("unexpected trigger input %q", data)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("trigger input was not written")
+	}
+}
+
+func TestManagerUpdateOutputTriggers(t *testing.T) {
+	mgr := NewManager(Config{}, zap.NewNop())
+	session := newTestSession(t, SessionParams{ID: "triggers", Rows: 4, Cols: 20, Logger: zap.NewNop()})
+	if err := mgr.addSession(session); err != nil {
+		t.Fatalf("addSession failed: %v", err)
+	}
+
+	if err := mgr.UpdateOutputTriggers([]OutputTrigger{{Pattern: `continue\?`, Input: "y\r"}}); err != nil {
+		t.Fatalf("UpdateOutputTriggers failed: %v", err)
+	}
+	session.triggerMu.Lock()
+	applied := len(session.globalTriggers)
+	session.triggerMu.Unlock()
+	if applied != 1 || len(mgr.OutputTriggers()) != 1 {
+		t.Fatalf("expected the trigger on the session and manager, got %d and %d", applied, len(mgr.OutputTriggers()))
+	}
+
+	// 非法正则整体拒绝，保留原有触发器
+	if err := mgr.UpdateOutputTriggers([]OutputTrigger{{Pattern: "(", Input: "y"}}); err == nil {
+		t.Fatalf("expected invalid pattern to be rejected")
+	}
+	if got := mgr.OutputTriggers(); len(got) != 1 || got[0].Pattern != `continue\?` {
+		t.Fatalf("expected the previous triggers to remain, got %+v", got)
+	}
+}
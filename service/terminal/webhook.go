@@ -0,0 +1,125 @@
+package terminal
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	defaultWebhookTimeout    = 10 * time.Second
+	defaultWebhookMaxRetries = 3
+	// webhookRetryBaseDelay 第一次重试前的等待，之后每次翻倍
+	webhookRetryBaseDelay = time.Second
+)
+
+// WebhookConfig posts session lifecycle events to an external URL. URL empty
+// disables the webhook.
+type WebhookConfig struct {
+	URL     string
+	Headers map[string]string
+	// Timeout 单次请求超时，0 使用默认 10s
+	Timeout time.Duration
+	// MaxRetries 失败后的重试次数，0 使用默认 3，负数不重试
+	MaxRetries int
+}
+
+// checkWebhookURL accepts absolute http and https URLs.
+func checkWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return err
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%q must be an absolute http or https URL", raw)
+	}
+	return nil
+}
+
+// webhookSender delivers lifecycle events as JSON POST requests. Non-2xx
+// responses and transport errors are retried with exponential backoff; the final
+// failure is only logged.
+type webhookSender struct {
+	cfg        WebhookConfig
+	client     *http.Client
+	logger     *zap.Logger
+	retryDelay time.Duration
+}
+
+func newWebhookSender(cfg WebhookConfig, logger *zap.Logger) *webhookSender {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultWebhookTimeout
+	}
+	switch {
+	case cfg.MaxRetries == 0:
+		cfg.MaxRetries = defaultWebhookMaxRetries
+	case cfg.MaxRetries < 0:
+		cfg.MaxRetries = 0
+	}
+	return &webhookSender{
+		cfg:        cfg,
+		client:     &http.Client{Timeout: cfg.Timeout},
+		logger:     logger.Named("session-webhook"),
+		retryDelay: webhookRetryBaseDelay,
+	}
+}
+
+// send delivers event, blocking until it succeeds or retries run out. Hooks
+// already run on their own goroutine.
+func (w *webhookSender) send(event SessionLifecycleEvent) {
+	body, err := json.Marshal(event)
+	if err != nil {
+		w.logger.Warn("failed to encode session webhook event", zap.Error(err))
+		return
+	}
+
+	delay := w.retryDelay
+	for attempt := 0; ; attempt++ {
+		err = w.post(body)
+		if err == nil {
+			return
+		}
+		if attempt >= w.cfg.MaxRetries {
+			break
+		}
+		time.Sleep(delay)
+		delay *= 2
+	}
+	w.logger.Warn("session webhook delivery failed",
+		zap.String("event", event.Type),
+		zap.String("sessionId", event.SessionID),
+		zap.Int("attempts", w.cfg.MaxRetries+1),
+		zap.Error(err),
+	)
+}
+
+func (w *webhookSender) post(body []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), w.cfg.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.cfg.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range w.cfg.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
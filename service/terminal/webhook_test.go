@@ -0,0 +1,100 @@
+package terminal
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestWebhookSenderRetries(t *testing.T) {
+	var attempts atomic.Int32
+	received := make(chan SessionLifecycleEvent, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			t.Errorf("missing configured header")
+		}
+		if attempts.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var event SessionLifecycleEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			t.Errorf("decode event: %v", err)
+		}
+		received <- event
+	}))
+	defer server.Close()
+
+	sender := newWebhookSender(WebhookConfig{
+		URL:     server.URL,
+		Headers: map[string]string{"Authorization": "Bearer token"},
+	}, zap.NewNop())
+	sender.retryDelay = time.Millisecond
+	sender.send(SessionLifecycleEvent{Type: SessionEventClosed, SessionID: "s1"})
+
+	select {
+	case event := <-received:
+		if event.Type != SessionEventClosed || event.SessionID != "s1" {
+			t.Fatalf("unexpected event %+v", event)
+		}
+	default:
+		t.Fatalf("expected delivery after retries, got %d attempts", attempts.Load())
+	}
+
+	// 重试用尽后放弃，不再继续请求
+	attempts.Store(0)
+	sender.cfg.MaxRetries = 1
+	sender.send(SessionLifecycleEvent{Type: SessionEventCreated})
+	if got := attempts.Load(); got != 2 {
+		t.Fatalf("expected 2 attempts, got %d", got)
+	}
+}
+
+func TestManagerSessionLifecycleHooks(t *testing.T) {
+	mgr := NewManager(Config{}, zap.NewNop())
+	events := make(chan SessionLifecycleEvent, 2)
+	mgr.OnSessionCreated(func(event SessionLifecycleEvent) { events <- event })
+	mgr.OnSessionClosed(func(event SessionLifecycleEvent) { events <- event })
+	mgr.OnSessionClosed(func(SessionLifecycleEvent) { panic("hook failure must not affect the session") })
+
+	session, err := mgr.CreateSession(context.Background(), CreateSessionParams{
+		ProjectID:  "p1",
+		WorktreeID: "wt-1",
+		WorkingDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Skipf("pty unavailable: %v", err)
+	}
+
+	next := func() SessionLifecycleEvent {
+		t.Helper()
+		select {
+		case event := <-events:
+			return event
+		case <-time.After(5 * time.Second):
+			t.Fatal("timed out waiting for lifecycle event")
+			return SessionLifecycleEvent{}
+		}
+	}
+	created := next()
+	if created.Type != SessionEventCreated || created.SessionID != session.ID() || created.WorktreeID != "wt-1" || created.ClosedAt != nil {
+		t.Fatalf("unexpected created event %+v", created)
+	}
+
+	if err := session.WriteInput([]byte("exit 3\r")); err != nil {
+		t.Fatalf("WriteInput failed: %v", err)
+	}
+	closed := next()
+	if closed.Type != SessionEventClosed || closed.ClosedAt == nil || closed.DurationSeconds <= 0 {
+		t.Fatalf("unexpected closed event %+v", closed)
+	}
+	if closed.ExitCode == nil || *closed.ExitCode != 3 {
+		t.Fatalf("expected exit code 3, got %+v", closed.ExitCode)
+	}
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/utils"
+	"code-kanban/utils/git"
+)
+
+var syncWorktreeBranch atomic.Bool
+
+// SetWorktreeBranchSync controls whether a status refresh that finds the
+// worktree on another branch also updates the recorded branch name.
+func SetWorktreeBranchSync(enabled bool) {
+	syncWorktreeBranch.Store(enabled)
+}
+
+// worktreeBranchCheck is the outcome of comparing the recorded branch with HEAD.
+type worktreeBranchCheck struct {
+	// Branch 本次刷新后记录的分支名，自动同步时为实际分支
+	Branch   string
+	Actual   string
+	Mismatch bool
+	Synced   bool
+	// Switched 本次刷新首次发现分支被切换（或切到了另一个分支）
+	Switched bool
+}
+
+// checkWorktreeBranch compares the recorded branch with the branch checked out in
+// the worktree. Detached HEAD is reported as orphaned instead, and bare entries
+// have no HEAD to compare.
+func checkWorktreeBranch(worktree *model.Worktree, status *git.WorktreeStatus) worktreeBranchCheck {
+	check := worktreeBranchCheck{Branch: worktree.BranchName}
+	actual := strings.TrimSpace(status.Branch)
+	if worktree.IsBare || status.Detached || actual == "" || actual == worktree.BranchName {
+		return check
+	}
+
+	check.Actual = actual
+	previous := ""
+	if worktree.BranchMismatch && worktree.ActualBranch != nil {
+		previous = *worktree.ActualBranch
+	}
+	check.Switched = previous != actual
+	if syncWorktreeBranch.Load() {
+		check.Branch = actual
+		check.Synced = true
+		return check
+	}
+	check.Mismatch = true
+	return check
+}
+
+// recordWorktreeBranchSwitch logs a branch switch made outside the app, once per switch.
+func recordWorktreeBranchSwitch(ctx context.Context, worktree *model.Worktree, check worktreeBranchCheck) {
+	if !check.Switched {
+		return
+	}
+	utils.Logger().Info("worktree branch switched outside of kanban",
+		zap.String("worktreeId", worktree.Id),
+		zap.String("recorded", worktree.BranchName),
+		zap.String("actual", check.Actual),
+		zap.Bool("synced", check.Synced),
+	)
+	recordActivity(ctx, model.RecordActivityRequest{
+		ProjectID: worktree.ProjectId,
+		Type:      model.ActivityWorktreeBranchSwitched,
+		Actor:     model.ActivityActorUser,
+		Target:    check.Actual,
+		Detail:    worktree.BranchName + " -> " + check.Actual,
+	})
+}
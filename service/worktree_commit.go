@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync/atomic"
+
+	"code-kanban/model"
+	"code-kanban/utils/git"
+)
+
+// CommitPolicy 提交消息的校验规则与预填模板，来自配置
+type CommitPolicy struct {
+	Rule     *git.CommitMessageRule
+	Template string
+}
+
+var commitPolicy atomic.Pointer[CommitPolicy]
+
+// SetCommitPolicy replaces the commit policy used by CommitWorktree; nil disables validation.
+func SetCommitPolicy(policy *CommitPolicy) {
+	commitPolicy.Store(policy)
+}
+
+func currentCommitRule() *git.CommitMessageRule {
+	if policy := commitPolicy.Load(); policy != nil {
+		return policy.Rule
+	}
+	return nil
+}
+
+// CommitTemplate is returned to the frontend to prefill the commit dialog.
+type CommitTemplate struct {
+	Template string `json:"template"`
+	// Source 为 config 或 repository（仓库的 commit.template），没有模板时为空
+	Source  string `json:"source,omitempty"`
+	Pattern string `json:"pattern,omitempty"`
+	Hint    string `json:"hint,omitempty"`
+}
+
+// GetCommitTemplate returns the configured commit template of the project,
+// falling back to the repository commit.template.
+func GetCommitTemplate(ctx context.Context, projectID string) (*CommitTemplate, error) {
+	q, err := model.ResolveQueries(nil)
+	if err != nil {
+		return nil, err
+	}
+	project, err := q.ProjectGetByID(ensureContext(ctx), projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrProjectNotFound
+		}
+		return nil, err
+	}
+
+	result := &CommitTemplate{}
+	policy := commitPolicy.Load()
+	if policy != nil {
+		result.Pattern = policy.Rule.Pattern()
+		result.Hint = policy.Rule.Hint()
+	}
+	if policy != nil && strings.TrimSpace(policy.Template) != "" {
+		result.Template = policy.Template
+		result.Source = "config"
+		return result, nil
+	}
+
+	repo, err := git.DetectRepository(project.Path)
+	if err != nil {
+		// 非 git 项目没有仓库模板
+		return result, nil
+	}
+	template, err := repo.CommitTemplate()
+	if err != nil {
+		return nil, err
+	}
+	if template != "" {
+		result.Template = template
+		result.Source = "repository"
+	}
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"code-kanban/model"
+	"code-kanban/utils/cache"
+	"code-kanban/utils/git"
+)
+
+// diffSummaryCache 缓存 diff 聚合结果，review 时前端会频繁请求。
+// 每个 worktree 和基准只保留一份，过期时间限制了已删除 worktree 残留的条目
+var diffSummaryCache = cache.NewCache(30 * time.Second)
+
+// diffSummaryEntry 记录统计时 worktree 的状态戳，状态戳变化即视为失效
+type diffSummaryEntry struct {
+	stamp   string
+	summary *git.DiffSummary
+}
+
+// diffSummaryStamp 由 HEAD 提交和状态刷新时间组成：提交会改变 HEAD，
+// 未提交的改动经 watcher 或手动刷新更新 status_updated_at
+func diffSummaryStamp(worktree *model.Worktree) string {
+	head := ""
+	if worktree.HeadCommit != nil {
+		head = *worktree.HeadCommit
+	}
+	refreshed := int64(0)
+	if worktree.StatusUpdatedAt != nil {
+		refreshed = worktree.StatusUpdatedAt.UnixNano()
+	}
+	return fmt.Sprintf("%s:%d", head, refreshed)
+}
+
+// DiffSummary aggregates `git diff --numstat` of a worktree against base (HEAD
+// when empty) by file extension. The result is cached until the worktree's
+// HEAD or status refresh time changes, at most 30 seconds; forceRefresh skips
+// the cache.
+func (s *WorktreeService) DiffSummary(ctx context.Context, id, base string, forceRefresh bool) (*git.DiffSummary, error) {
+	worktree, err := s.GetWorktree(ensureContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if _, statErr := os.Stat(worktree.Path); os.IsNotExist(statErr) {
+		return nil, model.ErrWorktreeNotFound
+	}
+
+	base = strings.TrimSpace(base)
+	if base == "" {
+		base = "HEAD"
+	}
+	key := fmt.Sprintf("diff-summary:%s:%s", worktree.Id, base)
+	stamp := diffSummaryStamp(worktree)
+	if !forceRefresh {
+		if value, ok := diffSummaryCache.Get(key); ok {
+			if entry, ok := value.(*diffSummaryEntry); ok && entry.stamp == stamp {
+				return entry.summary, nil
+			}
+		}
+	}
+
+	stats, err := git.DiffNumstat(worktree.Path, base)
+	if err != nil {
+		return nil, err
+	}
+	summary := git.SummarizeDiff(base, stats)
+	diffSummaryCache.Set(key, &diffSummaryEntry{stamp: stamp, summary: summary})
+	return summary, nil
+}
+
+// CommitDetail returns a commit of the worktree with its changed files. When
+// withDiff is set, the patch of limit files starting at offset is included.
+func (s *WorktreeService) CommitDetail(ctx context.Context, id, sha string, withDiff bool, offset, limit int) (*git.CommitDetail, error) {
+	worktree, err := s.GetWorktree(ensureContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if _, statErr := os.Stat(worktree.Path); os.IsNotExist(statErr) {
+		return nil, model.ErrWorktreeNotFound
+	}
+
+	detail, err := git.GetCommitDetail(worktree.Path, sha)
+	if err != nil {
+		return nil, err
+	}
+	if withDiff {
+		if detail.Diff, err = git.GetCommitDiff(worktree.Path, detail, offset, limit); err != nil {
+			return nil, err
+		}
+	}
+	return detail, nil
+}
+
+// Reflog returns the newest limit entries of the worktree's HEAD reflog.
+func (s *WorktreeService) Reflog(ctx context.Context, id string, limit int) ([]git.ReflogEntry, error) {
+	worktree, err := s.GetWorktree(ensureContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if _, statErr := os.Stat(worktree.Path); os.IsNotExist(statErr) {
+		return nil, model.ErrWorktreeNotFound
+	}
+	return git.GetReflog(worktree.Path, limit)
+}
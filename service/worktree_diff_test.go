@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"code-kanban/model"
+)
+
+func TestWorktreeServiceDiffSummaryCache(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	project, err := (&model.ProjectService{}).CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Diff Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/diff", "main", true, false)
+	if err != nil {
+		t.Fatalf("CreateWorktree returned error: %v", err)
+	}
+	readme := filepath.Join(worktree.Path, "README.md")
+	writeReadme := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(readme, []byte(content), 0o644); err != nil {
+			t.Fatalf("write readme: %v", err)
+		}
+	}
+
+	writeReadme("demo\none\n")
+	first, err := svc.DiffSummary(ctx, worktree.Id, "", false)
+	if err != nil {
+		t.Fatalf("DiffSummary failed: %v", err)
+	}
+	if first.Files != 1 {
+		t.Fatalf("expected one changed file, got %+v", first)
+	}
+
+	// 状态未刷新前命中缓存，刷新后重新统计
+	writeReadme("demo\none\ntwo\nthree\n")
+	if cached, err := svc.DiffSummary(ctx, worktree.Id, "", false); err != nil || cached != first {
+		t.Fatalf("expected the cached summary, got %+v (%v)", cached, err)
+	}
+	if _, err := svc.RefreshWorktreeStatus(ctx, worktree.Id); err != nil {
+		t.Fatalf("RefreshWorktreeStatus failed: %v", err)
+	}
+	refreshed, err := svc.DiffSummary(ctx, worktree.Id, "", false)
+	if err != nil {
+		t.Fatalf("DiffSummary failed: %v", err)
+	}
+	if refreshed == first || refreshed.Additions <= first.Additions {
+		t.Fatalf("expected a new summary after the status refresh, got %+v after %+v", refreshed, first)
+	}
+
+	// refresh 跳过缓存
+	writeReadme("demo\n")
+	forced, err := svc.DiffSummary(ctx, worktree.Id, "", true)
+	if err != nil {
+		t.Fatalf("DiffSummary failed: %v", err)
+	}
+	if forced.Additions >= refreshed.Additions {
+		t.Fatalf("expected forced refresh to recount, got %+v", forced)
+	}
+}
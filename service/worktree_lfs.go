@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+	"os"
+
+	"code-kanban/model"
+	"code-kanban/utils/git"
+)
+
+// LFSStatus reports git-lfs usage of a worktree and the files still checked
+// out as pointers. It is computed on demand because `git lfs ls-files` is too
+// slow for every status refresh.
+func (s *WorktreeService) LFSStatus(ctx context.Context, id string) (*git.LFSStatus, error) {
+	worktree, err := s.GetWorktree(ensureContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if _, statErr := os.Stat(worktree.Path); os.IsNotExist(statErr) {
+		return nil, model.ErrWorktreeNotFound
+	}
+	return git.GetLFSStatus(worktree.Path)
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"maps"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"code-kanban/model"
+	"code-kanban/utils/git"
+)
+
+// WorktreeStatusObserver is notified after a worktree's git status has been refreshed.
+type WorktreeStatusObserver func(worktree *model.Worktree, status *git.WorktreeStatus)
+
+// WorktreeMoveObserver is notified after a worktree has been moved to a new path.
+type WorktreeMoveObserver func(worktree *model.Worktree, oldPath string)
+
+// WorktreeSessionChecker reports whether terminal sessions are open in a worktree.
+type WorktreeSessionChecker func(worktreeID string) bool
+
+var worktreeSessionChecker atomic.Pointer[WorktreeSessionChecker]
+
+var (
+	worktreeStatusObserversMu sync.RWMutex
+	worktreeStatusObservers   []WorktreeStatusObserver
+	worktreeMoveObservers     map[int]WorktreeMoveObserver
+	worktreeMoveObserverSeq   int
+)
+
+// RegisterWorktreeStatusObserver adds an observer for worktree status refreshes,
+// e.g. so terminal sessions can surface unresolved conflicts on notification cards.
+func RegisterWorktreeStatusObserver(observer WorktreeStatusObserver) {
+	if observer == nil {
+		return
+	}
+	worktreeStatusObserversMu.Lock()
+	worktreeStatusObservers = append(worktreeStatusObservers, observer)
+	worktreeStatusObserversMu.Unlock()
+}
+
+func notifyWorktreeStatus(worktree *model.Worktree, status *git.WorktreeStatus) {
+	if worktree == nil || status == nil {
+		return
+	}
+	worktreeStatusObserversMu.RLock()
+	observers := append([]WorktreeStatusObserver(nil), worktreeStatusObservers...)
+	worktreeStatusObserversMu.RUnlock()
+
+	for _, observer := range observers {
+		observer(worktree, status)
+	}
+}
+
+// RegisterWorktreeMoveObserver adds an observer for worktree moves, e.g. so terminal
+// sessions still running in the old directory can be told about the new path.
+// The returned function removes the observer again.
+func RegisterWorktreeMoveObserver(observer WorktreeMoveObserver) (unregister func()) {
+	if observer == nil {
+		return func() {}
+	}
+	worktreeStatusObserversMu.Lock()
+	if worktreeMoveObservers == nil {
+		worktreeMoveObservers = make(map[int]WorktreeMoveObserver)
+	}
+	worktreeMoveObserverSeq++
+	id := worktreeMoveObserverSeq
+	worktreeMoveObservers[id] = observer
+	worktreeStatusObserversMu.Unlock()
+
+	return func() {
+		worktreeStatusObserversMu.Lock()
+		delete(worktreeMoveObservers, id)
+		worktreeStatusObserversMu.Unlock()
+	}
+}
+
+func notifyWorktreeMoved(worktree *model.Worktree, oldPath string) {
+	if worktree == nil {
+		return
+	}
+	worktreeStatusObserversMu.RLock()
+	observers := make([]WorktreeMoveObserver, 0, len(worktreeMoveObservers))
+	// 按注册顺序通知
+	for _, id := range slices.Sorted(maps.Keys(worktreeMoveObservers)) {
+		observers = append(observers, worktreeMoveObservers[id])
+	}
+	worktreeStatusObserversMu.RUnlock()
+
+	for _, observer := range observers {
+		observer(worktree, oldPath)
+	}
+}
+
+// SetWorktreeSessionChecker lets batch operations such as DeleteMergedBranches
+// leave worktrees with open terminal sessions alone; nil disables the check.
+func SetWorktreeSessionChecker(checker WorktreeSessionChecker) {
+	if checker == nil {
+		worktreeSessionChecker.Store(nil)
+		return
+	}
+	worktreeSessionChecker.Store(&checker)
+}
+
+func worktreeHasSessions(worktreeID string) bool {
+	checker := worktreeSessionChecker.Load()
+	return checker != nil && (*checker)(worktreeID)
+}
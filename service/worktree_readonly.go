@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/utils"
+)
+
+// ensureWorktreeWritable rejects operations that change the contents of a
+// read-only worktree, such as commit, merge, rebase and reset.
+func ensureWorktreeWritable(worktree *model.Worktree) error {
+	if worktree.ReadOnly {
+		return model.ErrWorktreeReadOnly
+	}
+	return nil
+}
+
+// SetWorktreeReadOnly toggles the read-only mode of a worktree. Unlike
+// deletion guards it protects the contents: while enabled, operations that
+// commit, merge, rebase, reset or discard changes are refused.
+func (s *WorktreeService) SetWorktreeReadOnly(ctx context.Context, id string, readOnly bool) (*model.Worktree, error) {
+	ctx = ensureContext(ctx)
+	worktree, err := s.GetWorktree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if worktree.ReadOnly == readOnly {
+		return worktree, nil
+	}
+
+	q, err := model.ResolveQueries(nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.WorktreeUpdateReadOnly(ctx, &model.WorktreeUpdateReadOnlyParams{
+		UpdatedAt: time.Now(),
+		ReadOnly:  readOnly,
+		Id:        worktree.Id,
+	}); err != nil {
+		return nil, err
+	}
+
+	detail := "off"
+	if readOnly {
+		detail = "on"
+	}
+	recordActivity(ctx, model.RecordActivityRequest{
+		ProjectID: worktree.ProjectId,
+		Type:      model.ActivityWorktreeReadOnly,
+		Actor:     model.ActivityActorUser,
+		Target:    worktree.BranchName,
+		Detail:    detail,
+	})
+	utils.LoggerFromContext(ctx).Info("worktree read-only mode changed",
+		zap.String("worktreeId", worktree.Id),
+		zap.Bool("readOnly", readOnly),
+	)
+	return s.GetWorktree(ctx, worktree.Id)
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"code-kanban/model"
+)
+
+func TestWorktreeServiceReadOnly(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	project, err := (&model.ProjectService{}).CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Read Only Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/review", "main", true, false)
+	if err != nil {
+		t.Fatalf("CreateWorktree returned error: %v", err)
+	}
+	if worktree.ReadOnly {
+		t.Fatalf("expected new worktree to be writable")
+	}
+	updated, err := svc.SetWorktreeReadOnly(ctx, worktree.Id, true)
+	if err != nil {
+		t.Fatalf("SetWorktreeReadOnly returned error: %v", err)
+	}
+	if !updated.ReadOnly {
+		t.Fatalf("expected worktree to be read-only")
+	}
+
+	targetFile := filepath.Join(worktree.Path, "review.txt")
+	if err := os.WriteFile(targetFile, []byte("content"), 0o644); err != nil {
+		t.Fatalf("failed to write file in worktree: %v", err)
+	}
+	if _, err := svc.CommitWorktree(ctx, worktree.Id, "feat: add review file", false); !errors.Is(err, model.ErrWorktreeReadOnly) {
+		t.Fatalf("expected commit to be refused, got %v", err)
+	}
+	if _, err := svc.StageFiles(ctx, worktree.Id, []string{"review.txt"}); !errors.Is(err, model.ErrWorktreeReadOnly) {
+		t.Fatalf("expected staging to be refused, got %v", err)
+	}
+	if _, err := svc.ResetWorktree(ctx, worktree.Id, "HEAD", "hard", true, true); !errors.Is(err, model.ErrWorktreeReadOnly) {
+		t.Fatalf("expected reset to be refused, got %v", err)
+	}
+	branches := NewBranchService()
+	if _, err := branches.MergeBranch(ctx, worktree.Id, "main", model.MergeBranchOptions{}); !errors.Is(err, model.ErrWorktreeReadOnly) {
+		t.Fatalf("expected merge to be refused, got %v", err)
+	}
+	if _, err := os.Stat(targetFile); err != nil {
+		t.Fatalf("expected refused operations to leave the file untouched: %v", err)
+	}
+
+	// 只读模式不影响查看
+	if _, err := svc.Changes(ctx, worktree.Id); err != nil {
+		t.Fatalf("Changes returned error: %v", err)
+	}
+
+	if _, err := svc.SetWorktreeReadOnly(ctx, worktree.Id, false); err != nil {
+		t.Fatalf("SetWorktreeReadOnly returned error: %v", err)
+	}
+	if _, err := svc.CommitWorktree(ctx, worktree.Id, "feat: add review file", false); err != nil {
+		t.Fatalf("expected commit after disabling read-only, got %v", err)
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/utils"
+	"code-kanban/utils/git"
+)
+
+// refBackups 保存每个 worktree 最近一次破坏性操作前的 HEAD，只保留在进程内
+var refBackups = struct {
+	sync.Mutex
+	items map[string]model.RefBackup
+}{items: make(map[string]model.RefBackup)}
+
+// backupWorktreeHead records the HEAD of the worktree before a destructive
+// operation so the operation can be undone with RevertToRef. A worktree
+// without commits has nothing to restore and records nothing. It returns the
+// recorded commit.
+func backupWorktreeHead(ctx context.Context, worktree *model.Worktree, operation string) string {
+	head, err := git.ResolveCommit(worktree.Path, "HEAD")
+	if err != nil {
+		utils.LoggerFromContext(ctx).Debug("skip ref backup",
+			zap.Error(err),
+			zap.String("worktreeId", worktree.Id),
+			zap.String("operation", operation),
+		)
+		return ""
+	}
+	refBackups.Lock()
+	refBackups.items[worktree.Id] = model.RefBackup{
+		WorktreeID: worktree.Id,
+		Operation:  operation,
+		Head:       head,
+		Branch:     worktree.BranchName,
+		CreatedAt:  time.Now(),
+	}
+	refBackups.Unlock()
+	return head
+}
+
+// LastRefBackup returns the HEAD recorded before the latest merge, rebase or
+// reset in the worktree.
+func (s *WorktreeService) LastRefBackup(ctx context.Context, id string) (*model.RefBackup, error) {
+	worktree, err := s.GetWorktree(ensureContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	refBackups.Lock()
+	backup, ok := refBackups.items[worktree.Id]
+	refBackups.Unlock()
+	if !ok {
+		return nil, model.ErrNoRefBackup
+	}
+	return &backup, nil
+}
+
+// RevertToRef restores the worktree to ref, typically the HEAD recorded before
+// a merge, rebase or reset that failed or is regretted. A rebase stopped on
+// conflicts is aborted first so the branch, not the detached rebase HEAD, is
+// moved; the worktree is then hard reset, which also drops a stopped merge
+// and any local changes, so confirm is required.
+func (s *WorktreeService) RevertToRef(ctx context.Context, id, ref string, confirm bool) (*model.Worktree, error) {
+	if !confirm {
+		return nil, model.ErrResetConfirmRequired
+	}
+	ctx = ensureContext(ctx)
+	worktree, repo, err := s.openWorktreeRepo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureWorktreeWritable(worktree); err != nil {
+		return nil, err
+	}
+	target, err := git.ResolveCommit(worktree.Path, ref)
+	if err != nil {
+		return nil, err
+	}
+	if repo.IsRebaseInProgress(worktree.Path) {
+		if err := repo.RebaseAbort(worktree.Path); err != nil {
+			return nil, err
+		}
+	}
+	if err := repo.Reset(worktree.Path, target, git.ResetModeHard); err != nil {
+		return nil, err
+	}
+
+	recordActivity(ctx, model.RecordActivityRequest{
+		ProjectID: worktree.ProjectId,
+		Type:      model.ActivityWorktreeReverted,
+		Actor:     model.ActivityActorUser,
+		Target:    worktree.BranchName,
+		Detail:    fmt.Sprintf("revert %s to %s", worktree.Path, target),
+	})
+	utils.LoggerFromContext(ctx).Info("worktree reverted",
+		zap.String("worktreeId", worktree.Id),
+		zap.String("ref", target),
+	)
+	return s.RefreshWorktreeStatus(ctx, worktree.Id)
+}
+
+// UndoLastOperation reverts the worktree to the HEAD recorded before its latest
+// merge, rebase or reset and drops that backup. Like RevertToRef it discards
+// local changes and requires confirm.
+func (s *WorktreeService) UndoLastOperation(ctx context.Context, id string, confirm bool) (*model.Worktree, *model.RefBackup, error) {
+	backup, err := s.LastRefBackup(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	worktree, err := s.RevertToRef(ctx, id, backup.Head, confirm)
+	if err != nil {
+		return nil, nil, err
+	}
+	refBackups.Lock()
+	if current, ok := refBackups.items[backup.WorktreeID]; ok && current.CreatedAt.Equal(backup.CreatedAt) {
+		delete(refBackups.items, backup.WorktreeID)
+	}
+	refBackups.Unlock()
+	return worktree, backup, nil
+}
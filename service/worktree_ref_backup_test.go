@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"code-kanban/model"
+	"code-kanban/utils/git"
+)
+
+func TestWorktreeServiceUndoLastOperation(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	project, err := (&model.ProjectService{}).CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Undo Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/undo", "main", true, false)
+	if err != nil {
+		t.Fatalf("CreateWorktree returned error: %v", err)
+	}
+	if _, err := svc.LastRefBackup(ctx, worktree.Id); !errors.Is(err, model.ErrNoRefBackup) {
+		t.Fatalf("expected ErrNoRefBackup, got %v", err)
+	}
+
+	targetFile := filepath.Join(worktree.Path, "undo.txt")
+	if err := os.WriteFile(targetFile, []byte("content"), 0o644); err != nil {
+		t.Fatalf("failed to write file in worktree: %v", err)
+	}
+	if _, err := svc.CommitWorktree(ctx, worktree.Id, "feat: add undo file", false); err != nil {
+		t.Fatalf("CommitWorktree returned error: %v", err)
+	}
+	head, err := git.ResolveCommit(worktree.Path, "HEAD")
+	if err != nil {
+		t.Fatalf("ResolveCommit returned error: %v", err)
+	}
+
+	if _, err := svc.ResetWorktree(ctx, worktree.Id, "HEAD~1", "hard", true, false); err != nil {
+		t.Fatalf("hard reset returned error: %v", err)
+	}
+	backup, err := svc.LastRefBackup(ctx, worktree.Id)
+	if err != nil {
+		t.Fatalf("LastRefBackup returned error: %v", err)
+	}
+	if backup.Operation != "reset" || backup.Head != head || backup.Branch != "feature/undo" {
+		t.Fatalf("unexpected backup %+v, head %s", backup, head)
+	}
+
+	if _, _, err := svc.UndoLastOperation(ctx, worktree.Id, false); !errors.Is(err, model.ErrResetConfirmRequired) {
+		t.Fatalf("expected ErrResetConfirmRequired, got %v", err)
+	}
+	if _, _, err := svc.UndoLastOperation(ctx, worktree.Id, true); err != nil {
+		t.Fatalf("UndoLastOperation returned error: %v", err)
+	}
+	if restored, _ := git.ResolveCommit(worktree.Path, "HEAD"); restored != head {
+		t.Fatalf("expected HEAD %s after undo, got %s", head, restored)
+	}
+	if _, err := os.Stat(targetFile); err != nil {
+		t.Fatalf("expected undo to restore undo.txt: %v", err)
+	}
+	if _, err := svc.LastRefBackup(ctx, worktree.Id); !errors.Is(err, model.ErrNoRefBackup) {
+		t.Fatalf("expected backup to be dropped after undo, got %v", err)
+	}
+
+	if _, err := svc.RevertToRef(ctx, worktree.Id, "missing-ref", true); err == nil {
+		t.Fatalf("expected unknown ref to fail")
+	}
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"code-kanban/model"
+	"code-kanban/utils/git"
+)
+
+// FetchWorktree runs `git fetch` in the worktree (all remotes when remote is
+// empty), reporting progress through onProgress, then refreshes its status so
+// ahead/behind counts reflect the fetched refs. A credential set with
+// ProjectService.SetCredential is sent to the remote.
+func (s *WorktreeService) FetchWorktree(ctx context.Context, id, remote string, onProgress git.ProgressFunc) (*model.Worktree, error) {
+	ctx = ensureContext(ctx)
+	worktree, repo, err := s.openWorktreeRepo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ctx = model.WithProjectCredential(ctx, worktree.ProjectId)
+	if err := repo.Fetch(ctx, worktree.Path, remote, onProgress); err != nil {
+		return nil, err
+	}
+	return s.RefreshWorktreeStatus(ctx, id)
+}
+
+// PullWorktree fast-forwards the worktree branch from its upstream, reporting progress through onProgress.
+func (s *WorktreeService) PullWorktree(ctx context.Context, id string, onProgress git.ProgressFunc) (*model.Worktree, error) {
+	ctx = ensureContext(ctx)
+	worktree, repo, err := s.openWorktreeRepo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureWorktreeWritable(worktree); err != nil {
+		return nil, err
+	}
+	ctx = model.WithProjectCredential(ctx, worktree.ProjectId)
+	if err := repo.Pull(ctx, worktree.Path, onProgress); err != nil {
+		return nil, err
+	}
+	return s.RefreshWorktreeStatus(ctx, id)
+}
+
+// CloneRepository clones url into dest, which must be an absolute path that does not exist yet.
+func CloneRepository(ctx context.Context, url, dest string, onProgress git.ProgressFunc) (string, error) {
+	dest = strings.TrimSpace(dest)
+	if dest == "" || !filepath.IsAbs(dest) {
+		return "", fmt.Errorf("clone destination must be an absolute path")
+	}
+	dest = filepath.Clean(dest)
+	if _, err := os.Stat(dest); err == nil {
+		return "", fmt.Errorf("clone destination %s already exists", dest)
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+	// 目标目录此前不存在，失败时 git.Clone 会清理残留
+	if err := git.Clone(ensureContext(ctx), url, dest, git.CloneOptions{OnProgress: onProgress}); err != nil {
+		return "", err
+	}
+	return dest, nil
+}
+
+func (s *WorktreeService) openWorktreeRepo(ctx context.Context, id string) (*model.Worktree, *git.GitRepo, error) {
+	q, err := model.ResolveQueries(nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	worktree, err := s.GetWorktree(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, statErr := os.Stat(worktree.Path); os.IsNotExist(statErr) {
+		return nil, nil, model.ErrWorktreeNotFound
+	}
+	project, err := q.ProjectGetByID(ctx, worktree.ProjectId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, model.ErrProjectNotFound
+		}
+		return nil, nil, err
+	}
+	repo, err := git.DetectRepository(project.Path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return worktree, repo, nil
+}
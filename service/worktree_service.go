@@ -38,12 +38,16 @@ func (s *WorktreeService) AsyncRefresh(enabled bool) {
 }
 
 // CreateWorktree provisions a new git worktree and persists its metadata.
+// With detach the worktree is checked out with a detached HEAD at baseBranch,
+// which may be any commit (SHA, tag or branch); no branch is created or
+// recorded and branchName is ignored.
 func (s *WorktreeService) CreateWorktree(
 	ctx context.Context,
 	projectID string,
 	branchName string,
 	baseBranch string,
 	createBranch bool,
+	detach bool,
 ) (*model.Worktree, error) {
 	if ctx == nil {
 		ctx = context.Background()
@@ -56,7 +60,10 @@ func (s *WorktreeService) CreateWorktree(
 	if strings.TrimSpace(projectID) == "" {
 		return nil, fmt.Errorf("project id is required")
 	}
-	if strings.TrimSpace(branchName) == "" {
+	if detach && strings.TrimSpace(baseBranch) == "" {
+		return nil, fmt.Errorf("base commit is required for a detached worktree")
+	}
+	if !detach && strings.TrimSpace(branchName) == "" {
 		return nil, fmt.Errorf("branch name is required")
 	}
 
@@ -67,14 +74,41 @@ func (s *WorktreeService) CreateWorktree(
 		}
 		return nil, err
 	}
+	if project.Archived {
+		return nil, model.ErrProjectArchived
+	}
 
 	gitRepo, err := git.DetectRepository(project.Path)
 	if err != nil {
 		return nil, err
 	}
 
+	// 按步骤记录补偿操作，任一步失败时逆序回滚已完成的步骤
+	var rollbacks []func()
+	rollback := func(cause error) {
+		for i := len(rollbacks) - 1; i >= 0; i-- {
+			rollbacks[i]()
+		}
+		utils.Logger().Warn("worktree creation failed, rolled back",
+			zap.Error(cause),
+			zap.String("projectId", projectID),
+			zap.String("branch", branchName),
+		)
+	}
+
 	targetBranch := strings.TrimSpace(branchName)
-	if createBranch {
+	// activityTarget 活动记录与目录名使用的名称，detached 时为短 SHA
+	activityTarget := targetBranch
+	detachedCommit := ""
+	if detach {
+		sha, err := gitRepo.ResolveCommit(baseBranch)
+		if err != nil {
+			return nil, err
+		}
+		detachedCommit = sha
+		targetBranch = ""
+		activityTarget = "detached-" + sha[:min(len(sha), 12)]
+	} else if createBranch {
 		refBranch := strings.TrimSpace(baseBranch)
 		if refBranch == "" {
 			if project.DefaultBranch != nil && *project.DefaultBranch != "" {
@@ -83,19 +117,63 @@ func (s *WorktreeService) CreateWorktree(
 				refBranch = "main"
 			}
 		}
-		if err := gitRepo.CreateBranch(targetBranch, refBranch); err != nil {
+		// 基于远程分支时创建跟踪分支，便于后续 pull/push
+		createFn := gitRepo.CreateBranch
+		if isRemote, _ := gitRepo.RemoteBranchExists(refBranch); isRemote {
+			createFn = gitRepo.CreateTrackingBranch
+		}
+		if err := createFn(targetBranch, refBranch); err != nil {
 			return nil, err
 		}
+		rollbacks = append(rollbacks, deleteBranchRollback(gitRepo, targetBranch))
+	} else {
+		// 直接选择远程分支（origin/feature-x）时，检出为同名本地跟踪分支
+		localBranch, created, err := ensureLocalBranchForRemote(gitRepo, targetBranch)
+		if err != nil {
+			return nil, err
+		}
+		targetBranch = localBranch
+		if created {
+			rollbacks = append(rollbacks, deleteBranchRollback(gitRepo, targetBranch))
+		}
 	}
 
-	worktreePath, err := s.resolveWorktreePath(project, targetBranch)
+	worktreePath, err := s.resolveWorktreePath(project, activityTarget)
 	if err != nil {
+		rollback(err)
 		return nil, err
 	}
 
-	if err := gitRepo.AddWorktree(worktreePath, targetBranch, false); err != nil {
+	// 仅删除本次创建出来的目录，避免误删已有内容
+	if _, statErr := os.Stat(worktreePath); os.IsNotExist(statErr) {
+		rollbacks = append(rollbacks, func() {
+			if err := os.RemoveAll(worktreePath); err != nil {
+				utils.Logger().Warn("rollback: failed to remove worktree directory",
+					zap.Error(err),
+					zap.String("path", worktreePath),
+				)
+			}
+		})
+	}
+
+	if detach {
+		_, err = gitRepo.AddDetachedWorktree(worktreePath, detachedCommit)
+	} else {
+		err = gitRepo.AddWorktree(worktreePath, targetBranch, false)
+	}
+	if err != nil {
+		rollback(err)
 		return nil, err
 	}
+	rollbacks = append(rollbacks, func() {
+		if err := gitRepo.RemoveWorktree(worktreePath, true); err != nil {
+			utils.Logger().Warn("rollback: failed to remove git worktree",
+				zap.Error(err),
+				zap.String("path", worktreePath),
+			)
+		}
+		_, _ = gitRepo.PruneWorktrees()
+	})
 
 	now := time.Now()
 	idVal := utils.NewID()
@@ -117,11 +195,18 @@ func (s *WorktreeService) CreateWorktree(
 		StatusUntracked: &zeroVal,
 		StatusConflicts: &zeroVal,
 		StatusUpdatedAt: nil,
+		IsDetached:      detach,
 	})
 	if err != nil {
-		_ = gitRepo.RemoveWorktree(worktreePath, true)
+		rollback(err)
 		return nil, err
 	}
+	recordActivity(ctx, model.RecordActivityRequest{
+		ProjectID: projectID,
+		Type:      model.ActivityWorktreeCreated,
+		Target:    activityTarget,
+		Detail:    worktreePath,
+	})
 
 	// 同步刷新状态，确保返回的 worktree 包含最新的 git 状态信息
 	refreshed, err := s.RefreshWorktreeStatus(ctx, worktree.Id)
@@ -137,6 +222,42 @@ func (s *WorktreeService) CreateWorktree(
 	return refreshed, nil
 }
 
+func deleteBranchRollback(gitRepo *git.GitRepo, branch string) func() {
+	return func() {
+		if err := gitRepo.DeleteBranch(branch, true); err != nil {
+			utils.Logger().Warn("rollback: failed to delete branch",
+				zap.Error(err),
+				zap.String("branch", branch),
+			)
+		}
+	}
+}
+
+// ensureLocalBranchForRemote maps a remote branch name to its local tracking branch,
+// creating the branch when missing. Names that are local branches are returned as is.
+func ensureLocalBranchForRemote(gitRepo *git.GitRepo, branch string) (string, bool, error) {
+	if exists, err := gitRepo.BranchExists(branch); err != nil || exists {
+		return branch, false, err
+	}
+	isRemote, err := gitRepo.RemoteBranchExists(branch)
+	if err != nil || !isRemote {
+		return branch, false, err
+	}
+
+	localBranch := git.RemoteBranchLocalName(branch)
+	exists, err := gitRepo.BranchExists(localBranch)
+	if err != nil {
+		return "", false, err
+	}
+	if exists {
+		return localBranch, false, nil
+	}
+	if err := gitRepo.CreateTrackingBranch(localBranch, branch); err != nil {
+		return "", false, err
+	}
+	return localBranch, true, nil
+}
+
 // ListWorktrees returns worktrees for a project ordered by main flag then creation.
 func (s *WorktreeService) ListWorktrees(ctx context.Context, projectID string) ([]*model.Worktree, error) {
 	if ctx == nil {
@@ -199,6 +320,23 @@ func (s *WorktreeService) DeleteWorktree(ctx context.Context, id string, force,
 	if taskCount > 0 && !force {
 		return model.ErrWorktreeHasTasks
 	}
+	if !force {
+		if _, statErr := os.Stat(worktree.Path); statErr == nil {
+			var commits []git.CommitInfo
+			var err error
+			branch := worktree.BranchName
+			if worktree.IsDetached {
+				// detached HEAD 上的提交不属于任何分支，移除 worktree 后将无法找回
+				branch = "detached HEAD"
+				commits, err = git.GetUnreferencedCommits(worktree.Path)
+			} else {
+				commits, err = git.GetUnpushedCommits(worktree.Path)
+			}
+			if err := checkUnpushedCommits(ctx, branch, commits, err); err != nil {
+				return err
+			}
+		}
+	}
 
 	project, err := q.ProjectGetByID(ctx, worktree.ProjectId)
 	if err != nil {
@@ -243,8 +381,15 @@ func (s *WorktreeService) DeleteWorktree(ctx context.Context, id string, force,
 		}
 	}
 
-	if deleteBranch && gitRepo != nil {
-		if err := gitRepo.DeleteBranch(worktree.BranchName, force); err != nil {
+	if deleteBranch && gitRepo != nil && !worktree.IsDetached {
+		// 受保护的分支只移除 worktree，保留分支
+		if err := checkBranchProtection(project, currentBranch(gitRepo), worktree.BranchName, model.BranchOperationDelete); err != nil {
+			utils.Logger().Info("keeping protected branch of deleted worktree",
+				zap.Error(err),
+				zap.String("branch", worktree.BranchName),
+				zap.String("projectId", project.Id),
+			)
+		} else if err := gitRepo.DeleteBranch(worktree.BranchName, force); err != nil {
 			utils.Logger().Warn("failed to delete branch",
 				zap.Error(err),
 				zap.String("branch", worktree.BranchName),
@@ -254,12 +399,20 @@ func (s *WorktreeService) DeleteWorktree(ctx context.Context, id string, force,
 	}
 
 	now := time.Now()
-	_, err = q.WorktreeSoftDelete(ctx, &model.WorktreeSoftDeleteParams{
+	if _, err := q.WorktreeSoftDelete(ctx, &model.WorktreeSoftDeleteParams{
 		DeletedAt: &now,
 		UpdatedAt: now,
 		Id:        id,
+	}); err != nil {
+		return err
+	}
+	recordActivity(ctx, model.RecordActivityRequest{
+		ProjectID: project.Id,
+		Type:      model.ActivityWorktreeDeleted,
+		Target:    worktree.BranchName,
+		Detail:    worktree.Path,
 	})
-	return err
+	return nil
 }
 
 // RefreshWorktreeStatus updates cached status fields for a worktree and returns the refreshed record.
@@ -278,11 +431,26 @@ func (s *WorktreeService) RefreshWorktreeStatus(ctx context.Context, id string)
 		return nil, err
 	}
 
+	// 目录已被删除时无法获取 git 状态，直接标记为孤立，便于前端提示清理
+	if _, statErr := os.Stat(worktree.Path); os.IsNotExist(statErr) {
+		return s.markWorktreeOrphan(ctx, worktree, model.WorktreeOrphanPathMissing)
+	}
+
 	status, err := git.GetWorktreeStatus(worktree.Path)
 	if err != nil {
 		return nil, err
 	}
 
+	branchCheck := checkWorktreeBranch(worktree, status)
+	orphanReason := ""
+	if !worktree.IsMain && !worktree.IsBare && !worktree.IsDetached {
+		orphanReason = detectWorktreeOrphan(worktree.Path, branchCheck.Branch, status.Detached)
+	}
+	var orphanReasonPtr *string
+	if orphanReason != "" {
+		orphanReasonPtr = &orphanReason
+	}
+
 	now := time.Now()
 	var headPtr *string
 	var headMessagePtr *string
@@ -300,6 +468,14 @@ func (s *WorktreeService) RefreshWorktreeStatus(ctx context.Context, id string)
 		}
 	}
 
+	var actualBranchPtr, branchNamePtr *string
+	if branchCheck.Mismatch {
+		actualBranchPtr = &branchCheck.Actual
+	}
+	if branchCheck.Synced {
+		branchNamePtr = &branchCheck.Branch
+	}
+
 	aheadVal := int64(status.Ahead)
 	behindVal := int64(status.Behind)
 	modifiedVal := int64(status.Modified)
@@ -316,6 +492,11 @@ func (s *WorktreeService) RefreshWorktreeStatus(ctx context.Context, id string)
 		StatusUntracked:   &untrackedVal,
 		StatusConflicts:   &conflictsVal,
 		StatusUpdatedAt:   &now,
+		IsOrphaned:        orphanReason != "",
+		OrphanReason:      orphanReasonPtr,
+		BranchMismatch:    branchCheck.Mismatch,
+		ActualBranch:      actualBranchPtr,
+		BranchName:        branchNamePtr,
 		HeadCommit:        headPtr,
 		HeadCommitMessage: headMessagePtr,
 		HeadCommitDate:    headDatePtr,
@@ -325,9 +506,58 @@ func (s *WorktreeService) RefreshWorktreeStatus(ctx context.Context, id string)
 		return nil, err
 	}
 
+	recordWorktreeBranchSwitch(ctx, worktree, branchCheck)
+	notifyWorktreeStatus(updated, status)
 	return updated, nil
 }
 
+// markWorktreeOrphan persists the orphan flag for a worktree and returns the updated record.
+func (s *WorktreeService) markWorktreeOrphan(ctx context.Context, worktree *model.Worktree, reason string) (*model.Worktree, error) {
+	q, err := model.ResolveQueries(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var reasonPtr *string
+	if reason != "" {
+		reasonPtr = &reason
+	}
+	if err := q.WorktreeUpdateOrphan(ctx, &model.WorktreeUpdateOrphanParams{
+		UpdatedAt:    time.Now(),
+		IsOrphaned:   reason != "",
+		OrphanReason: reasonPtr,
+		Id:           worktree.Id,
+	}); err != nil {
+		return nil, err
+	}
+	return s.GetWorktree(ctx, worktree.Id)
+}
+
+// detectWorktreeOrphan 检查 worktree 是否处于 detached HEAD 或记录的分支已不存在，
+// 返回孤立原因，正常时返回空字符串。
+func detectWorktreeOrphan(path, branchName string, detached bool) string {
+	if detached {
+		return model.WorktreeOrphanDetachedHead
+	}
+	branch := strings.TrimSpace(branchName)
+	if branch == "" {
+		return model.WorktreeOrphanBranchMissing
+	}
+	exists, err := git.BranchExists(path, branch)
+	if err != nil {
+		utils.Logger().Warn("failed to check worktree branch",
+			zap.Error(err),
+			zap.String("path", path),
+			zap.String("branch", branch),
+		)
+		return ""
+	}
+	if !exists {
+		return model.WorktreeOrphanBranchMissing
+	}
+	return ""
+}
+
 // RefreshAllWorktrees refreshes status for every worktree belonging to a project.
 func (s *WorktreeService) RefreshAllWorktrees(ctx context.Context, projectID string) (updated, failed int, err error) {
 	if ctx == nil {
@@ -367,21 +597,33 @@ func (s *WorktreeService) RefreshWorktreeCommitInfo(ctx context.Context, project
 
 // SyncWorktrees ensures git worktrees and the database remain aligned.
 func (s *WorktreeService) SyncWorktrees(ctx context.Context, projectID string) error {
+	_, err := s.PruneWorktrees(ctx, projectID)
+	return err
+}
+
+// PruneWorktrees runs `git worktree prune` for the project, then syncs the
+// database so records of the pruned worktrees are soft deleted.
+func (s *WorktreeService) PruneWorktrees(ctx context.Context, projectID string) (*model.WorktreePruneResult, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
 
+	result := &model.WorktreePruneResult{
+		Pruned:         make([]string, 0),
+		RemovedRecords: make([]string, 0),
+	}
+
 	q, err := model.ResolveQueries(nil)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	project, err := q.ProjectGetByID(ctx, projectID)
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
-			return model.ErrWorktreeNotFound
+			return nil, model.ErrWorktreeNotFound
 		}
-		return err
+		return nil, err
 	}
 
 	// 如果不是 git 仓库，直接返回成功（非 git 目录没有 worktrees 需要同步）
@@ -392,17 +634,32 @@ func (s *WorktreeService) SyncWorktrees(ctx context.Context, projectID string) e
 			zap.String("path", project.Path),
 			zap.Error(err),
 		)
-		return nil
+		return result, nil
+	}
+
+	// 手动删除目录后 git 会保留失效的 worktree 引用，先清理再同步
+	pruned, err := gitRepo.PruneWorktrees()
+	if err != nil {
+		utils.Logger().Warn("failed to prune worktrees before sync",
+			zap.Error(err),
+			zap.String("projectId", projectID),
+		)
+	} else if len(pruned) > 0 {
+		result.Pruned = pruned
+		utils.Logger().Info("pruned stale git worktrees",
+			zap.String("projectId", projectID),
+			zap.Strings("entries", pruned),
+		)
 	}
 
 	gitWorktrees, err := gitRepo.ListWorktrees()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	dbWorktrees, err := s.ListWorktrees(ctx, projectID)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	gitByPath := make(map[string]git.WorktreeInfo, len(gitWorktrees))
@@ -437,7 +694,24 @@ func (s *WorktreeService) SyncWorktrees(ctx context.Context, projectID string) e
 				IsBare:     gitWT.IsBare,
 				Id:         existing.Id,
 			}); err != nil {
-				return err
+				return nil, err
+			}
+
+			orphanReason := ""
+			if !gitWT.IsMain && !gitWT.IsBare && !existing.IsDetached {
+				orphanReason = detectWorktreeOrphan(gitWT.Path, branchName, gitWT.Detached)
+			}
+			if orphanReason != "" || existing.IsOrphaned {
+				if orphanReason != "" {
+					utils.Logger().Warn("worktree is orphaned",
+						zap.String("worktreeId", existing.Id),
+						zap.String("path", existing.Path),
+						zap.String("reason", orphanReason),
+					)
+				}
+				if _, err := s.markWorktreeOrphan(ctx, existing, orphanReason); err != nil {
+					return nil, err
+				}
 			}
 			continue
 		}
@@ -448,7 +722,7 @@ func (s *WorktreeService) SyncWorktrees(ctx context.Context, projectID string) e
 			headPtr = &commit
 		}
 		zeroVal := int64(0)
-		if _, err := q.WorktreeCreate(ctx, &model.WorktreeCreateParams{
+		created, err := q.WorktreeCreate(ctx, &model.WorktreeCreateParams{
 			Id:              utils.NewID(),
 			CreatedAt:       now,
 			UpdatedAt:       now,
@@ -465,8 +739,16 @@ func (s *WorktreeService) SyncWorktrees(ctx context.Context, projectID string) e
 			StatusUntracked: &zeroVal,
 			StatusConflicts: &zeroVal,
 			StatusUpdatedAt: nil,
-		}); err != nil {
-			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		if !gitWT.IsMain && !gitWT.IsBare {
+			if reason := detectWorktreeOrphan(gitWT.Path, gitWT.Branch, gitWT.Detached); reason != "" {
+				if _, err := s.markWorktreeOrphan(ctx, created, reason); err != nil {
+					return nil, err
+				}
+			}
 		}
 	}
 
@@ -479,15 +761,17 @@ func (s *WorktreeService) SyncWorktrees(ctx context.Context, projectID string) e
 			UpdatedAt: now,
 			Id:        dbWT.Id,
 		}); err != nil {
-			return err
+			return nil, err
 		}
+		result.RemovedRecords = append(result.RemovedRecords, dbWT.Id)
 	}
 
-	return nil
+	return result, nil
 }
 
 // CommitWorktree stages all changes within the worktree and creates a commit with the provided message.
-func (s *WorktreeService) CommitWorktree(ctx context.Context, id, message string) (*model.Worktree, error) {
+// When sign is true the commit is signed (-S); git.ErrCommitSigningFailed is returned if no key is usable.
+func (s *WorktreeService) CommitWorktree(ctx context.Context, id, message string, sign bool) (*model.Worktree, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
@@ -496,6 +780,9 @@ func (s *WorktreeService) CommitWorktree(ctx context.Context, id, message string
 	if trimmedMessage == "" {
 		return nil, fmt.Errorf("commit message is required")
 	}
+	if err := currentCommitRule().Validate(trimmedMessage); err != nil {
+		return nil, err
+	}
 
 	q, err := model.ResolveQueries(nil)
 	if err != nil {
@@ -506,6 +793,9 @@ func (s *WorktreeService) CommitWorktree(ctx context.Context, id, message string
 	if err != nil {
 		return nil, err
 	}
+	if err := ensureWorktreeWritable(worktree); err != nil {
+		return nil, err
+	}
 
 	project, err := q.ProjectGetByID(ctx, worktree.ProjectId)
 	if err != nil {
@@ -531,12 +821,23 @@ func (s *WorktreeService) CommitWorktree(ctx context.Context, id, message string
 	if err := repo.AddAll(worktree.Path); err != nil {
 		return nil, err
 	}
-	if err := repo.Commit(worktree.Path, trimmedMessage); err != nil {
+	if err := repo.Commit(worktree.Path, trimmedMessage, sign); err != nil {
 		if strings.Contains(err.Error(), "nothing to commit") {
 			return nil, model.ErrWorktreeClean
 		}
 		return nil, err
 	}
+	// detached worktree 的提交不在任何分支上，需要用户自行建分支保留
+	target := worktree.BranchName
+	if worktree.IsDetached {
+		target = "detached HEAD"
+	}
+	recordActivity(ctx, model.RecordActivityRequest{
+		ProjectID: project.Id,
+		Type:      model.ActivityCommitCreated,
+		Target:    target,
+		Detail:    trimmedMessage,
+	})
 
 	updated, err := s.RefreshWorktreeStatus(ctx, id)
 	if err != nil {
@@ -545,7 +846,332 @@ func (s *WorktreeService) CommitWorktree(ctx context.Context, id, message string
 	return updated, nil
 }
 
-func (s *WorktreeService) resolveWorktreePath(project *model.Project, branchName string) (string, error) {
+// ResetWorktree resets the worktree HEAD to ref. Hard resets require confirm, and are
+// refused on a dirty worktree unless force is set because local changes would be lost.
+func (s *WorktreeService) ResetWorktree(ctx context.Context, worktreeID, ref, mode string, confirm, force bool) (*model.Worktree, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	resetMode := strings.ToLower(strings.TrimSpace(mode))
+	if resetMode == "" {
+		resetMode = git.ResetModeMixed
+	}
+	switch resetMode {
+	case git.ResetModeSoft, git.ResetModeMixed, git.ResetModeHard:
+	default:
+		return nil, fmt.Errorf("unsupported reset mode: %s", mode)
+	}
+	if resetMode == git.ResetModeHard && !confirm {
+		return nil, model.ErrResetConfirmRequired
+	}
+
+	q, err := model.ResolveQueries(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := s.GetWorktree(ctx, worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureWorktreeWritable(worktree); err != nil {
+		return nil, err
+	}
+
+	project, err := q.ProjectGetByID(ctx, worktree.ProjectId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrProjectNotFound
+		}
+		return nil, err
+	}
+
+	repo, err := git.DetectRepository(project.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if resetMode == git.ResetModeHard && !force {
+		status, err := repo.GetWorktreeStatus(worktree.Path)
+		if err != nil {
+			return nil, err
+		}
+		if status.Modified > 0 || status.Staged > 0 || status.Conflicted > 0 {
+			return nil, model.ErrWorktreeDirty
+		}
+	}
+
+	backup := backupWorktreeHead(ctx, worktree, "reset")
+	if err := repo.Reset(worktree.Path, ref, resetMode); err != nil {
+		return nil, err
+	}
+
+	detail := fmt.Sprintf("reset --%s to %s", resetMode, ref)
+	if backup != "" {
+		detail += ", before " + backup
+	}
+	recordActivity(ctx, model.RecordActivityRequest{
+		ProjectID: worktree.ProjectId,
+		Type:      model.ActivityWorktreeReset,
+		Actor:     model.ActivityActorUser,
+		Target:    worktree.BranchName,
+		Detail:    detail,
+	})
+
+	utils.Logger().Info("worktree reset",
+		zap.String("worktreeId", worktree.Id),
+		zap.String("ref", ref),
+		zap.String("mode", resetMode),
+	)
+
+	return s.RefreshWorktreeStatus(ctx, worktreeID)
+}
+
+// MoveWorktree moves a linked worktree to newPath, updates the stored path and
+// notifies observers such as terminal sessions still running in the old directory.
+// The target must not exist and its parent directory must be writable; when the
+// database update fails the worktree is moved back.
+func (s *WorktreeService) MoveWorktree(ctx context.Context, worktreeID, newPath string) (*model.Worktree, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	target := strings.TrimSpace(newPath)
+	if target == "" {
+		return nil, errors.New("new path is required")
+	}
+	target, err := filepath.Abs(target)
+	if err != nil {
+		return nil, err
+	}
+
+	q, err := model.ResolveQueries(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	worktree, err := s.GetWorktree(ctx, worktreeID)
+	if err != nil {
+		return nil, err
+	}
+	if worktree.IsMain {
+		return nil, errors.New("cannot move main worktree")
+	}
+	oldPath := worktree.Path
+	if model.NormalizePathCase(oldPath) == model.NormalizePathCase(target) {
+		return worktree, nil
+	}
+	if err := checkMoveTarget(target); err != nil {
+		return nil, err
+	}
+
+	project, err := q.ProjectGetByID(ctx, worktree.ProjectId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrProjectNotFound
+		}
+		return nil, err
+	}
+
+	repo, err := git.DetectRepository(project.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := repo.MoveWorktree(oldPath, target); err != nil {
+		return nil, err
+	}
+
+	if err := q.WorktreeUpdatePath(ctx, &model.WorktreeUpdatePathParams{
+		UpdatedAt: time.Now(),
+		Path:      target,
+		Id:        worktree.Id,
+	}); err != nil {
+		if rollbackErr := repo.MoveWorktree(target, oldPath); rollbackErr != nil {
+			utils.Logger().Error("failed to roll back worktree move",
+				zap.Error(rollbackErr),
+				zap.String("worktreeId", worktree.Id),
+				zap.String("from", target),
+				zap.String("to", oldPath),
+			)
+		}
+		return nil, err
+	}
+
+	utils.Logger().Info("worktree moved",
+		zap.String("worktreeId", worktree.Id),
+		zap.String("from", oldPath),
+		zap.String("to", target),
+	)
+
+	updated, err := s.GetWorktree(ctx, worktree.Id)
+	if err != nil {
+		return nil, err
+	}
+	notifyWorktreeMoved(updated, oldPath)
+	return updated, nil
+}
+
+// checkMoveTarget ensures target does not exist and its parent directory is writable.
+func checkMoveTarget(target string) error {
+	if _, err := os.Stat(target); err == nil {
+		return model.ErrWorktreePathConflict
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	parent := filepath.Dir(target)
+	info, err := os.Stat(parent)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return fmt.Errorf("parent directory does not exist: %s", parent)
+		}
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("parent path is not a directory: %s", parent)
+	}
+	probe, err := os.CreateTemp(parent, ".codekanban-move-*")
+	if err != nil {
+		return fmt.Errorf("parent directory is not writable: %s", parent)
+	}
+	probe.Close()
+	_ = os.Remove(probe.Name())
+	return nil
+}
+
+// CleanupOrphans removes worktrees whose database record and disk state disagree:
+// records pointing at missing directories are soft deleted, and git worktrees under
+// the project's worktree base path without a record (e.g. leftovers from a failed
+// creation) are removed from disk. Such worktrees with uncommitted or untracked
+// changes are never removed; they are reported in SkippedWorktrees instead.
+func (s *WorktreeService) CleanupOrphans(ctx context.Context, projectID string) (*model.WorktreeCleanupResult, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	q, err := model.ResolveQueries(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	project, err := q.ProjectGetByID(ctx, projectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, model.ErrProjectNotFound
+		}
+		return nil, err
+	}
+
+	result := &model.WorktreeCleanupResult{
+		RemovedRecords:   make([]string, 0),
+		RemovedWorktrees: make([]string, 0),
+		SkippedWorktrees: make([]string, 0),
+	}
+
+	gitRepo, err := git.DetectRepository(project.Path)
+	if err != nil {
+		utils.Logger().Debug("project is not a git repository, skip orphan cleanup",
+			zap.String("projectId", projectID),
+			zap.Error(err),
+		)
+		return result, nil
+	}
+
+	// 先清理 git 中目录已不存在的 worktree 记录
+	if _, err := gitRepo.PruneWorktrees(); err != nil {
+		utils.Logger().Warn("failed to prune worktrees before cleanup",
+			zap.Error(err),
+			zap.String("projectId", projectID),
+		)
+	}
+
+	gitWorktrees, err := gitRepo.ListWorktrees()
+	if err != nil {
+		return nil, err
+	}
+	dbWorktrees, err := s.ListWorktrees(ctx, projectID)
+	if err != nil {
+		return nil, err
+	}
+
+	gitByPath := make(map[string]git.WorktreeInfo, len(gitWorktrees))
+	for _, wt := range gitWorktrees {
+		gitByPath[model.NormalizePathCase(wt.Path)] = wt
+	}
+
+	now := time.Now()
+	dbByPath := make(map[string]*model.Worktree, len(dbWorktrees))
+	for _, wt := range dbWorktrees {
+		normPath := model.NormalizePathCase(wt.Path)
+		dbByPath[normPath] = wt
+		if wt.IsMain {
+			continue
+		}
+		if _, ok := gitByPath[normPath]; ok {
+			continue
+		}
+		if _, statErr := os.Stat(wt.Path); !os.IsNotExist(statErr) {
+			continue
+		}
+		if _, err := q.WorktreeSoftDelete(ctx, &model.WorktreeSoftDeleteParams{
+			DeletedAt: &now,
+			UpdatedAt: now,
+			Id:        wt.Id,
+		}); err != nil {
+			return nil, err
+		}
+		result.RemovedRecords = append(result.RemovedRecords, wt.Id)
+	}
+
+	basePath := model.NormalizePathCase(worktreeBasePath(project))
+	for normPath, gitWT := range gitByPath {
+		if gitWT.IsMain || gitWT.IsBare {
+			continue
+		}
+		if _, ok := dbByPath[normPath]; ok {
+			continue
+		}
+		// 只处理由本系统管理的目录，用户自行创建的 worktree 交给 SyncWorktrees 导入
+		if rel, err := filepath.Rel(basePath, normPath); err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		// 有未提交或未跟踪的改动时保留目录，交给用户处理
+		if changes, err := git.GetFileChanges(gitWT.Path); err != nil || len(changes) > 0 {
+			utils.Logger().Info("skip orphan worktree with local changes",
+				zap.Error(err),
+				zap.String("path", gitWT.Path),
+				zap.Int("changes", len(changes)),
+				zap.String("projectId", projectID),
+			)
+			result.SkippedWorktrees = append(result.SkippedWorktrees, gitWT.Path)
+			continue
+		}
+		if err := gitRepo.RemoveWorktree(gitWT.Path, false); err != nil {
+			utils.Logger().Warn("failed to remove orphan worktree",
+				zap.Error(err),
+				zap.String("path", gitWT.Path),
+				zap.String("projectId", projectID),
+			)
+			continue
+		}
+		result.RemovedWorktrees = append(result.RemovedWorktrees, gitWT.Path)
+	}
+
+	if len(result.RemovedRecords) > 0 || len(result.RemovedWorktrees) > 0 || len(result.SkippedWorktrees) > 0 {
+		utils.Logger().Info("cleaned up orphan worktrees",
+			zap.String("projectId", projectID),
+			zap.Int("records", len(result.RemovedRecords)),
+			zap.Int("worktrees", len(result.RemovedWorktrees)),
+			zap.Int("skipped", len(result.SkippedWorktrees)),
+		)
+	}
+
+	return result, nil
+}
+
+func worktreeBasePath(project *model.Project) string {
 	basePath := ""
 	if project.WorktreeBasePath != nil && strings.TrimSpace(*project.WorktreeBasePath) != "" {
 		basePath = *project.WorktreeBasePath
@@ -555,6 +1181,11 @@ func (s *WorktreeService) resolveWorktreePath(project *model.Project, branchName
 	if !filepath.IsAbs(basePath) {
 		basePath = filepath.Join(project.Path, basePath)
 	}
+	return basePath
+}
+
+func (s *WorktreeService) resolveWorktreePath(project *model.Project, branchName string) (string, error) {
+	basePath := worktreeBasePath(project)
 	if err := os.MkdirAll(basePath, 0o755); err != nil {
 		return "", err
 	}
@@ -33,7 +33,7 @@ func TestWorktreeServiceCreateAndRefresh(t *testing.T) {
 	svc.AsyncRefresh(false)
 	ctx := context.Background()
 
-	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/testing", "main", true)
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/testing", "main", true, false)
 	if err != nil {
 		t.Fatalf("CreateWorktree returned error: %v", err)
 	}
@@ -87,7 +87,7 @@ func TestWorktreeServiceDeleteAndSync(t *testing.T) {
 	svc.AsyncRefresh(false)
 	ctx := context.Background()
 
-	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/delete", "main", true)
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/delete", "main", true, false)
 	if err != nil {
 		t.Fatalf("CreateWorktree returned error: %v", err)
 	}
@@ -122,6 +122,55 @@ func TestWorktreeServiceDeleteAndSync(t *testing.T) {
 	}
 }
 
+func TestWorktreeServicePruneWorktrees(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Prune Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/prune", "main", true, false)
+	if err != nil {
+		t.Fatalf("CreateWorktree returned error: %v", err)
+	}
+	if err := os.RemoveAll(worktree.Path); err != nil {
+		t.Fatalf("remove worktree dir failed: %v", err)
+	}
+
+	result, err := svc.PruneWorktrees(ctx, project.Id)
+	if err != nil {
+		t.Fatalf("PruneWorktrees returned error: %v", err)
+	}
+	if len(result.Pruned) != 1 || !strings.Contains(result.Pruned[0], "prune") {
+		t.Fatalf("expected one pruned entry, got %v", result.Pruned)
+	}
+	if len(result.RemovedRecords) != 1 || result.RemovedRecords[0] != worktree.Id {
+		t.Fatalf("expected record %s to be removed, got %v", worktree.Id, result.RemovedRecords)
+	}
+	if _, err := svc.GetWorktree(ctx, worktree.Id); err == nil {
+		t.Fatalf("expected pruned worktree record to be deleted")
+	}
+
+	result, err = svc.PruneWorktrees(ctx, project.Id)
+	if err != nil {
+		t.Fatalf("second PruneWorktrees returned error: %v", err)
+	}
+	if len(result.Pruned) != 0 || len(result.RemovedRecords) != 0 {
+		t.Fatalf("expected nothing left to prune, got %+v", result)
+	}
+}
+
 func TestWorktreeServiceRefreshAll(t *testing.T) {
 	cleanup := initTestDB(t)
 	defer cleanup()
@@ -140,7 +189,7 @@ func TestWorktreeServiceRefreshAll(t *testing.T) {
 	svc.AsyncRefresh(false)
 	ctx := context.Background()
 
-	if _, err := svc.CreateWorktree(ctx, project.Id, "feature/all", "main", true); err != nil {
+	if _, err := svc.CreateWorktree(ctx, project.Id, "feature/all", "main", true, false); err != nil {
 		t.Fatalf("CreateWorktree returned error: %v", err)
 	}
 
@@ -180,7 +229,7 @@ func TestWorktreeServiceCommit(t *testing.T) {
 	svc.AsyncRefresh(false)
 	ctx := context.Background()
 
-	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/commit", "main", true)
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/commit", "main", true, false)
 	if err != nil {
 		t.Fatalf("CreateWorktree returned error: %v", err)
 	}
@@ -190,7 +239,7 @@ func TestWorktreeServiceCommit(t *testing.T) {
 		t.Fatalf("failed to write file in worktree: %v", err)
 	}
 
-	updated, err := svc.CommitWorktree(ctx, worktree.Id, "feat: add commit file")
+	updated, err := svc.CommitWorktree(ctx, worktree.Id, "feat: add commit file", false)
 	if err != nil {
 		t.Fatalf("CommitWorktree returned error: %v", err)
 	}
@@ -198,11 +247,225 @@ func TestWorktreeServiceCommit(t *testing.T) {
 		t.Fatalf("expected updated worktree after commit")
 	}
 
-	if _, err := svc.CommitWorktree(ctx, worktree.Id, "noop"); !errors.Is(err, model.ErrWorktreeClean) {
+	if _, err := svc.CommitWorktree(ctx, worktree.Id, "noop", false); !errors.Is(err, model.ErrWorktreeClean) {
 		t.Fatalf("expected ErrWorktreeClean, got %v", err)
 	}
 }
 
+func TestWorktreeServiceCommitPolicy(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Commit Policy Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	rule, err := git.NewCommitMessageRule(`^(feat|fix|chore)(\(.+\))?: .+`, "use conventional commits")
+	if err != nil {
+		t.Fatalf("NewCommitMessageRule failed: %v", err)
+	}
+	SetCommitPolicy(&CommitPolicy{Rule: rule, Template: "feat: "})
+	defer SetCommitPolicy(nil)
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/policy", "main", true, false)
+	if err != nil {
+		t.Fatalf("CreateWorktree returned error: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(worktree.Path, "policy.txt"), []byte("content"), 0o644); err != nil {
+		t.Fatalf("failed to write file in worktree: %v", err)
+	}
+
+	if _, err := svc.CommitWorktree(ctx, worktree.Id, "add policy file", false); !errors.Is(err, git.ErrCommitMessageInvalid) {
+		t.Fatalf("expected ErrCommitMessageInvalid, got %v", err)
+	}
+	if _, err := svc.CommitWorktree(ctx, worktree.Id, "feat: add policy file", false); err != nil {
+		t.Fatalf("CommitWorktree returned error: %v", err)
+	}
+
+	template, err := GetCommitTemplate(ctx, project.Id)
+	if err != nil {
+		t.Fatalf("GetCommitTemplate returned error: %v", err)
+	}
+	if template.Template != "feat: " || template.Source != "config" || template.Hint != "use conventional commits" {
+		t.Fatalf("unexpected template %+v", template)
+	}
+	if _, err := GetCommitTemplate(ctx, "missing"); !errors.Is(err, model.ErrProjectNotFound) {
+		t.Fatalf("expected ErrProjectNotFound, got %v", err)
+	}
+}
+
+func TestWorktreeServiceReset(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Reset Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/reset", "main", true, false)
+	if err != nil {
+		t.Fatalf("CreateWorktree returned error: %v", err)
+	}
+
+	targetFile := filepath.Join(worktree.Path, "reset.txt")
+	if err := os.WriteFile(targetFile, []byte("content"), 0o644); err != nil {
+		t.Fatalf("failed to write file in worktree: %v", err)
+	}
+	if _, err := svc.CommitWorktree(ctx, worktree.Id, "feat: add reset file", false); err != nil {
+		t.Fatalf("CommitWorktree returned error: %v", err)
+	}
+
+	if _, err := svc.ResetWorktree(ctx, worktree.Id, "HEAD~1", "hard", false, false); !errors.Is(err, model.ErrResetConfirmRequired) {
+		t.Fatalf("expected ErrResetConfirmRequired, got %v", err)
+	}
+
+	reset, err := svc.ResetWorktree(ctx, worktree.Id, "HEAD~1", "soft", false, false)
+	if err != nil {
+		t.Fatalf("soft reset returned error: %v", err)
+	}
+	if reset.StatusStaged == nil || *reset.StatusStaged == 0 {
+		t.Fatalf("expected soft reset to keep changes staged")
+	}
+
+	if _, err := svc.ResetWorktree(ctx, worktree.Id, "HEAD", "hard", true, false); !errors.Is(err, model.ErrWorktreeDirty) {
+		t.Fatalf("expected ErrWorktreeDirty, got %v", err)
+	}
+	if _, err := svc.ResetWorktree(ctx, worktree.Id, "HEAD", "hard", true, true); err != nil {
+		t.Fatalf("forced hard reset returned error: %v", err)
+	}
+	if _, err := os.Stat(targetFile); !os.IsNotExist(err) {
+		t.Fatalf("expected hard reset to discard reset.txt, stat err=%v", err)
+	}
+
+	if _, err := svc.ResetWorktree(ctx, worktree.Id, "HEAD", "keep", false, false); err == nil {
+		t.Fatalf("expected unsupported mode to fail")
+	}
+}
+
+func TestWorktreeServiceCreateRollback(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Rollback Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+
+	// 目标路径被普通文件占用，git worktree add 会失败
+	blocked := filepath.Join(repoPath, ".worktrees", "feature__blocked")
+	if err := os.MkdirAll(filepath.Dir(blocked), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	if err := os.WriteFile(blocked, []byte("occupied"), 0o644); err != nil {
+		t.Fatalf("write blocker: %v", err)
+	}
+
+	if _, err := svc.CreateWorktree(ctx, project.Id, "feature/blocked", "main", true, false); err == nil {
+		t.Fatalf("expected CreateWorktree to fail")
+	}
+	exists, err := git.BranchExists(repoPath, "feature/blocked")
+	if err != nil {
+		t.Fatalf("BranchExists failed: %v", err)
+	}
+	if exists {
+		t.Fatalf("expected created branch to be rolled back")
+	}
+	if data, err := os.ReadFile(blocked); err != nil || string(data) != "occupied" {
+		t.Fatalf("expected pre-existing path to be left untouched, err=%v", err)
+	}
+}
+
+func TestWorktreeServiceCleanupOrphans(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Cleanup Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+
+	tracked, err := svc.CreateWorktree(ctx, project.Id, "feature/tracked", "main", true, false)
+	if err != nil {
+		t.Fatalf("CreateWorktree returned error: %v", err)
+	}
+	if err := os.RemoveAll(tracked.Path); err != nil {
+		t.Fatalf("remove tracked worktree dir: %v", err)
+	}
+
+	leftover := filepath.Join(repoPath, ".worktrees", "leftover")
+	runGitCommand(t, repoPath, "worktree", "add", "-b", "feature/leftover", leftover)
+	dirty := filepath.Join(repoPath, ".worktrees", "dirty")
+	runGitCommand(t, repoPath, "worktree", "add", "-b", "feature/dirty", dirty)
+	if err := os.WriteFile(filepath.Join(dirty, "wip.txt"), []byte("work in progress"), 0o644); err != nil {
+		t.Fatalf("write untracked file: %v", err)
+	}
+
+	result, err := svc.CleanupOrphans(ctx, project.Id)
+	if err != nil {
+		t.Fatalf("CleanupOrphans returned error: %v", err)
+	}
+	if len(result.RemovedRecords) != 1 || result.RemovedRecords[0] != tracked.Id {
+		t.Fatalf("expected tracked record to be removed, got %#v", result.RemovedRecords)
+	}
+	if len(result.RemovedWorktrees) != 1 {
+		t.Fatalf("expected leftover worktree to be removed, got %#v", result.RemovedWorktrees)
+	}
+	if _, err := os.Stat(leftover); !os.IsNotExist(err) {
+		t.Fatalf("expected leftover directory to be removed, stat err=%v", err)
+	}
+	// 有本地改动的无记录 worktree 保留
+	if len(result.SkippedWorktrees) != 1 || model.NormalizePathCase(result.SkippedWorktrees[0]) != model.NormalizePathCase(dirty) {
+		t.Fatalf("expected dirty worktree to be skipped, got %#v", result.SkippedWorktrees)
+	}
+	if _, err := os.Stat(filepath.Join(dirty, "wip.txt")); err != nil {
+		t.Fatalf("expected dirty worktree to be kept: %v", err)
+	}
+	if _, err := svc.GetWorktree(ctx, tracked.Id); !errors.Is(err, model.ErrWorktreeNotFound) {
+		t.Fatalf("expected tracked worktree to be deleted, got %v", err)
+	}
+
+	if _, err := svc.CleanupOrphans(ctx, "missing-project"); !errors.Is(err, model.ErrProjectNotFound) {
+		t.Fatalf("expected ErrProjectNotFound for a missing project, got %v", err)
+	}
+}
+
 func initTestDB(t *testing.T) func() {
 	t.Helper()
 	dsn := "file:" + t.Name() + "?mode=memory&cache=shared"
@@ -258,3 +521,290 @@ func runGitCommand(t *testing.T, dir string, args ...string) {
 		t.Fatalf("git %s failed: %v\n%s", strings.Join(args, " "), err, output)
 	}
 }
+
+func TestWorktreeServiceMove(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Move Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/move", "main", true, false)
+	if err != nil {
+		t.Fatalf("CreateWorktree returned error: %v", err)
+	}
+	oldPath := worktree.Path
+
+	var movedFrom string
+	unregister := RegisterWorktreeMoveObserver(func(moved *model.Worktree, from string) {
+		if moved.Id == worktree.Id {
+			movedFrom = from
+		}
+	})
+	defer unregister()
+
+	occupied := t.TempDir()
+	if _, err := svc.MoveWorktree(ctx, worktree.Id, occupied); !errors.Is(err, model.ErrWorktreePathConflict) {
+		t.Fatalf("expected ErrWorktreePathConflict, got %v", err)
+	}
+	if _, err := svc.MoveWorktree(ctx, worktree.Id, filepath.Join(t.TempDir(), "missing", "target")); err == nil {
+		t.Fatalf("expected missing parent directory to fail")
+	}
+
+	target := filepath.Join(t.TempDir(), "moved")
+	moved, err := svc.MoveWorktree(ctx, worktree.Id, target)
+	if err != nil {
+		t.Fatalf("MoveWorktree returned error: %v", err)
+	}
+	if moved.Path != target {
+		t.Fatalf("expected path %s got %s", target, moved.Path)
+	}
+	if movedFrom != oldPath {
+		t.Fatalf("expected observer to receive old path %s got %s", oldPath, movedFrom)
+	}
+	if _, err := os.Stat(oldPath); !os.IsNotExist(err) {
+		t.Fatalf("expected old path to be gone, stat err=%v", err)
+	}
+	if _, err := svc.RefreshWorktreeStatus(ctx, worktree.Id); err != nil {
+		t.Fatalf("RefreshWorktreeStatus after move returned error: %v", err)
+	}
+}
+
+func TestWorktreeServiceCreateFromRemoteBranch(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	runGitCommand(t, repoPath, "remote", "add", "origin", "git@example.com:repo.git")
+	runGitCommand(t, repoPath, "update-ref", "refs/remotes/origin/feature-x", "HEAD")
+	runGitCommand(t, repoPath, "update-ref", "refs/remotes/origin/feature-y", "HEAD")
+
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Remote Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "origin/feature-x", "", false, false)
+	if err != nil {
+		t.Fatalf("CreateWorktree from remote branch failed: %v", err)
+	}
+	if worktree.BranchName != "feature-x" {
+		t.Fatalf("expected local branch feature-x, got %s", worktree.BranchName)
+	}
+
+	if _, err := svc.CreateWorktree(ctx, project.Id, "review-y", "origin/feature-y", true, false); err != nil {
+		t.Fatalf("CreateWorktree with remote base failed: %v", err)
+	}
+
+	for branch, upstream := range map[string]string{"feature-x": "origin/feature-x", "review-y": "origin/feature-y"} {
+		cmd := exec.Command("git", "rev-parse", "--abbrev-ref", branch+"@{upstream}")
+		cmd.Dir = repoPath
+		cmd.Env = append(os.Environ(), testGitEnv()...)
+		output, err := cmd.Output()
+		if err != nil {
+			t.Fatalf("expected %s to track a remote branch: %v", branch, err)
+		}
+		if got := strings.TrimSpace(string(output)); got != upstream {
+			t.Fatalf("expected %s upstream %s, got %s", branch, upstream, got)
+		}
+	}
+
+	repo, err := git.DetectRepository(repoPath)
+	if err != nil {
+		t.Fatalf("DetectRepository failed: %v", err)
+	}
+	_, remote, err := repo.ListBranches()
+	if err != nil {
+		t.Fatalf("ListBranches failed: %v", err)
+	}
+	for _, branch := range remote {
+		if branch.Name == "origin/feature-x" && !branch.HasLocalBranch {
+			t.Fatalf("expected origin/feature-x to be marked as having a local branch")
+		}
+		if branch.Name == "origin/feature-y" && branch.HasLocalBranch {
+			t.Fatalf("expected origin/feature-y to have no local branch")
+		}
+	}
+}
+
+func TestWorktreeServiceBranchMismatch(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Mismatch Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/recorded", "main", true, false)
+	if err != nil {
+		t.Fatalf("CreateWorktree returned error: %v", err)
+	}
+
+	// 模拟用户在终端里手动切换分支
+	runGitCommand(t, worktree.Path, "checkout", "-b", "feature/manual")
+
+	refreshed, err := svc.RefreshWorktreeStatus(ctx, worktree.Id)
+	if err != nil {
+		t.Fatalf("RefreshWorktreeStatus failed: %v", err)
+	}
+	if !refreshed.BranchMismatch || refreshed.ActualBranch == nil || *refreshed.ActualBranch != "feature/manual" {
+		t.Fatalf("expected mismatch with actual branch feature/manual, got %v %v", refreshed.BranchMismatch, refreshed.ActualBranch)
+	}
+	if refreshed.BranchName != "feature/recorded" || refreshed.IsOrphaned {
+		t.Fatalf("expected recorded branch to be kept without orphan flag, got %+v", refreshed)
+	}
+
+	SetWorktreeBranchSync(true)
+	defer SetWorktreeBranchSync(false)
+	synced, err := svc.RefreshWorktreeStatus(ctx, worktree.Id)
+	if err != nil {
+		t.Fatalf("RefreshWorktreeStatus failed: %v", err)
+	}
+	if synced.BranchMismatch || synced.ActualBranch != nil || synced.BranchName != "feature/manual" {
+		t.Fatalf("expected branch to be synced, got mismatch=%v actual=%v branch=%s", synced.BranchMismatch, synced.ActualBranch, synced.BranchName)
+	}
+}
+
+func TestWorktreeServiceDetached(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Detached Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+
+	if _, err := svc.CreateWorktree(ctx, project.Id, "", "no-such-commit", false, true); !errors.Is(err, git.ErrCommitNotFound) {
+		t.Fatalf("expected ErrCommitNotFound, got %v", err)
+	}
+
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "", "main", false, true)
+	if err != nil {
+		t.Fatalf("CreateWorktree returned error: %v", err)
+	}
+	if !worktree.IsDetached || worktree.BranchName != "" || worktree.IsOrphaned {
+		t.Fatalf("expected detached worktree without branch, got %+v", worktree)
+	}
+	if !strings.HasPrefix(filepath.Base(worktree.Path), "detached-") {
+		t.Fatalf("unexpected detached worktree path %s", worktree.Path)
+	}
+
+	branches := NewBranchService()
+	if _, err := branches.MergeBranch(ctx, worktree.Id, "main", model.MergeBranchOptions{}); !errors.Is(err, model.ErrWorktreeDetached) {
+		t.Fatalf("expected ErrWorktreeDetached from merge, got %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(worktree.Path, "try.txt"), []byte("try"), 0o644); err != nil {
+		t.Fatalf("failed to write file in worktree: %v", err)
+	}
+	if _, err := svc.CommitWorktree(ctx, worktree.Id, "feat: experiment", false); err != nil {
+		t.Fatalf("CommitWorktree returned error: %v", err)
+	}
+
+	// 提交只存在于 detached HEAD 上，非强制删除需要确认
+	if err := svc.DeleteWorktree(ctx, worktree.Id, false, true); !errors.Is(err, model.ErrUnpushedCommits) {
+		t.Fatalf("expected ErrUnpushedCommits for unreferenced commits, got %v", err)
+	}
+	if err := svc.DeleteWorktree(ctx, worktree.Id, true, true); err != nil {
+		t.Fatalf("DeleteWorktree returned error: %v", err)
+	}
+}
+
+func TestWorktreeServiceStageFiles(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Stage Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	svc := NewWorktreeService()
+	svc.AsyncRefresh(false)
+	ctx := context.Background()
+
+	worktree, err := svc.CreateWorktree(ctx, project.Id, "feature/stage", "main", true, false)
+	if err != nil {
+		t.Fatalf("CreateWorktree returned error: %v", err)
+	}
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(worktree.Path, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("failed to write file in worktree: %v", err)
+		}
+	}
+
+	staged, err := svc.StageFiles(ctx, worktree.Id, []string{"a.txt"})
+	if err != nil {
+		t.Fatalf("StageFiles returned error: %v", err)
+	}
+	if staged.Worktree.StatusStaged == nil || *staged.Worktree.StatusStaged != 1 || len(staged.Files) != 2 {
+		t.Fatalf("unexpected changes after staging: %+v", staged.Files)
+	}
+	if len(staged.Groups.Staged) != 1 || staged.Groups.Staged[0].Path != "a.txt" || len(staged.Groups.Untracked) != 1 {
+		t.Fatalf("unexpected grouped changes after staging: %+v", staged.Groups)
+	}
+
+	unstaged, err := svc.UnstageFiles(ctx, worktree.Id, []string{"a.txt"})
+	if err != nil {
+		t.Fatalf("UnstageFiles returned error: %v", err)
+	}
+	if unstaged.Worktree.StatusStaged != nil && *unstaged.Worktree.StatusStaged != 0 {
+		t.Fatalf("expected nothing staged after unstaging")
+	}
+
+	if _, err := svc.DiscardChanges(ctx, worktree.Id, []string{"b.txt"}, false); !errors.Is(err, model.ErrDiscardConfirmRequired) {
+		t.Fatalf("expected ErrDiscardConfirmRequired, got %v", err)
+	}
+	discarded, err := svc.DiscardChanges(ctx, worktree.Id, []string{"b.txt"}, true)
+	if err != nil {
+		t.Fatalf("DiscardChanges returned error: %v", err)
+	}
+	if len(discarded.Files) != 1 || discarded.Files[0].Path != "a.txt" {
+		t.Fatalf("expected only a.txt to remain, got %+v", discarded.Files)
+	}
+	if _, err := os.Stat(filepath.Join(worktree.Path, "b.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected b.txt to be deleted, stat err=%v", err)
+	}
+}
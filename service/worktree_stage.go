@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+
+	"code-kanban/model"
+	"code-kanban/utils/git"
+)
+
+// WorktreeChanges is the worktree status together with its changed files,
+// returned after file level stage operations. Groups holds the same files
+// split into staged, unstaged and untracked with line counts.
+type WorktreeChanges struct {
+	Worktree *model.Worktree   `json:"worktree"`
+	Files    []git.FileChange  `json:"files"`
+	Groups   *git.ChangeGroups `json:"groups"`
+}
+
+// Changes lists the changed files of a worktree.
+func (s *WorktreeService) Changes(ctx context.Context, id string) (*WorktreeChanges, error) {
+	ctx = ensureContext(ctx)
+	worktree, _, err := s.openWorktreeRepo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return loadWorktreeChanges(worktree)
+}
+
+// loadWorktreeChanges reads the changed files of worktree and groups them.
+func loadWorktreeChanges(worktree *model.Worktree) (*WorktreeChanges, error) {
+	files, err := git.GetFileChanges(worktree.Path)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := git.GroupFileChanges(worktree.Path, files)
+	if err != nil {
+		return nil, err
+	}
+	return &WorktreeChanges{Worktree: worktree, Files: files, Groups: groups}, nil
+}
+
+// StageFiles stages the given files of a worktree.
+func (s *WorktreeService) StageFiles(ctx context.Context, id string, files []string) (*WorktreeChanges, error) {
+	return s.applyFileChanges(ctx, id, func(repo *git.GitRepo, path string) error {
+		return repo.StageFiles(path, files)
+	})
+}
+
+// UnstageFiles removes the given files from the index of a worktree.
+func (s *WorktreeService) UnstageFiles(ctx context.Context, id string, files []string) (*WorktreeChanges, error) {
+	return s.applyFileChanges(ctx, id, func(repo *git.GitRepo, path string) error {
+		return repo.UnstageFiles(path, files)
+	})
+}
+
+// DiscardChanges drops the unstaged changes of the given files and deletes the
+// untracked ones. The changes cannot be recovered, so confirm is required.
+func (s *WorktreeService) DiscardChanges(ctx context.Context, id string, files []string, confirm bool) (*WorktreeChanges, error) {
+	if !confirm {
+		return nil, model.ErrDiscardConfirmRequired
+	}
+	return s.applyFileChanges(ctx, id, func(repo *git.GitRepo, path string) error {
+		return repo.DiscardChanges(path, files)
+	})
+}
+
+// applyFileChanges runs a file level git operation, then refreshes the stored
+// worktree status and returns it with the remaining changed files.
+func (s *WorktreeService) applyFileChanges(ctx context.Context, id string, apply func(repo *git.GitRepo, path string) error) (*WorktreeChanges, error) {
+	ctx = ensureContext(ctx)
+	worktree, repo, err := s.openWorktreeRepo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := ensureWorktreeWritable(worktree); err != nil {
+		return nil, err
+	}
+	if err := apply(repo, worktree.Path); err != nil {
+		return nil, err
+	}
+	updated, err := s.RefreshWorktreeStatus(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return loadWorktreeChanges(updated)
+}
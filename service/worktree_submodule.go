@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"os"
+
+	"code-kanban/model"
+	"code-kanban/utils/git"
+)
+
+// Submodules lists the submodules of a worktree with their checkout state.
+func (s *WorktreeService) Submodules(ctx context.Context, id string) ([]git.Submodule, error) {
+	worktree, err := s.GetWorktree(ensureContext(ctx), id)
+	if err != nil {
+		return nil, err
+	}
+	if _, statErr := os.Stat(worktree.Path); os.IsNotExist(statErr) {
+		return nil, model.ErrWorktreeNotFound
+	}
+	return git.GetSubmodules(worktree.Path)
+}
+
+// UpdateSubmodules initializes and checks out the submodules of a worktree at
+// the recorded commits, then refreshes the worktree status.
+func (s *WorktreeService) UpdateSubmodules(ctx context.Context, id string, recursive bool) ([]git.Submodule, error) {
+	ctx = ensureContext(ctx)
+	worktree, err := s.GetWorktree(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if _, statErr := os.Stat(worktree.Path); os.IsNotExist(statErr) {
+		return nil, model.ErrWorktreeNotFound
+	}
+	if err := ensureWorktreeWritable(worktree); err != nil {
+		return nil, err
+	}
+	if err := git.UpdateSubmodules(worktree.Path, recursive); err != nil {
+		return nil, err
+	}
+	if _, err := s.RefreshWorktreeStatus(ctx, id); err != nil {
+		return nil, err
+	}
+	return git.GetSubmodules(worktree.Path)
+}
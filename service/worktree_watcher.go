@@ -0,0 +1,439 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"go.uber.org/zap"
+
+	"code-kanban/model"
+	"code-kanban/utils"
+)
+
+const (
+	defaultWorktreeWatchMax       = 32
+	defaultWorktreeWatchDebounce  = time.Second
+	defaultWorktreeWatchReconcile = 15 * time.Second
+	// worktreeWatchMaxDirs 单个 worktree 最多监听的目录数，fsnotify 不支持递归，大仓库只覆盖前面的目录
+	worktreeWatchMaxDirs = 512
+)
+
+// ErrWorktreeWatchLimit is returned when the watcher already tracks the maximum number of worktrees.
+var ErrWorktreeWatchLimit = errors.New("worktree watch limit reached")
+
+// worktreeWatchSkipDirs 依赖与缓存目录变化频繁且与 git 状态无关
+var worktreeWatchSkipDirs = map[string]struct{}{
+	".git": {}, "node_modules": {}, ".venv": {}, "__pycache__": {}, ".idea": {}, ".cache": {},
+}
+
+// worktreeWatchGitFiles gitdir 中会影响状态的文件：提交、暂存、切换分支、合并与拉取
+var worktreeWatchGitFiles = map[string]struct{}{
+	"HEAD": {}, "index": {}, "ORIG_HEAD": {}, "MERGE_HEAD": {}, "FETCH_HEAD": {},
+}
+
+// WorktreeWatcherConfig controls limits and timing of the worktree watcher.
+type WorktreeWatcherConfig struct {
+	MaxWatchers int
+	Debounce    time.Duration
+	// ReconcileInterval 定期与数据库同步监听列表，覆盖新建与删除的 worktree
+	ReconcileInterval time.Duration
+	// IsActive 只监听返回 true 的 worktree（如有打开的终端），不再活跃的在下次同步时停止监听；
+	// 为空时监听所有 worktree
+	IsActive func(worktreeID string) bool
+}
+
+// WorktreeWatcher refreshes worktree status when files in the worktree or its
+// gitdir change. Refreshes go through RefreshWorktreeStatus, so status observers
+// receive the new status as usual.
+type WorktreeWatcher struct {
+	cfg     WorktreeWatcherConfig
+	watcher *fsnotify.Watcher
+	refresh func(ctx context.Context, id string) error
+
+	mu          sync.Mutex
+	entries     map[string]*watchedWorktree
+	dirs        map[string]*watchedWorktree
+	limitWarned bool
+	closed      bool
+}
+
+type watchedWorktree struct {
+	id      string
+	path    string
+	gitDirs map[string]struct{}
+	dirs    []string
+
+	timer      *time.Timer
+	refreshing bool
+	pending    bool
+}
+
+// NewWorktreeWatcher creates a watcher; call Start to begin processing events.
+func NewWorktreeWatcher(cfg WorktreeWatcherConfig) (*WorktreeWatcher, error) {
+	if cfg.MaxWatchers <= 0 {
+		cfg.MaxWatchers = defaultWorktreeWatchMax
+	}
+	if cfg.Debounce <= 0 {
+		cfg.Debounce = defaultWorktreeWatchDebounce
+	}
+	if cfg.ReconcileInterval <= 0 {
+		cfg.ReconcileInterval = defaultWorktreeWatchReconcile
+	}
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	svc := NewWorktreeService()
+	return &WorktreeWatcher{
+		cfg:     cfg,
+		watcher: watcher,
+		refresh: func(ctx context.Context, id string) error {
+			_, err := svc.RefreshWorktreeStatus(ctx, id)
+			return err
+		},
+		entries: make(map[string]*watchedWorktree),
+		dirs:    make(map[string]*watchedWorktree),
+	}, nil
+}
+
+// Start processes file events and periodically reconciles the watch list with
+// the database until ctx is cancelled.
+func (w *WorktreeWatcher) Start(ctx context.Context) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	go w.run(ctx)
+}
+
+func (w *WorktreeWatcher) run(ctx context.Context) {
+	defer w.Close()
+
+	if err := w.Reconcile(ctx); err != nil {
+		utils.Logger().Warn("worktree watcher reconcile failed", zap.Error(err))
+	}
+	ticker := time.NewTicker(w.cfg.ReconcileInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := w.Reconcile(ctx); err != nil {
+				utils.Logger().Debug("worktree watcher reconcile failed", zap.Error(err))
+			}
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+			w.handleEvent(ctx, event)
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+			utils.Logger().Debug("worktree watcher error", zap.Error(err))
+		}
+	}
+}
+
+// Reconcile watches every live worktree in the database (up to the limit) and
+// drops watches of worktrees that were deleted, moved or, with IsActive set,
+// became inactive.
+func (w *WorktreeWatcher) Reconcile(ctx context.Context) error {
+	q, err := model.ResolveQueries(nil)
+	if err != nil {
+		return err
+	}
+	projects, err := q.ProjectList(ctx)
+	if err != nil {
+		return err
+	}
+
+	desired := make(map[string]*model.Worktree)
+	order := make([]*model.Worktree, 0)
+	for _, project := range projects {
+		worktrees, err := q.WorktreeListByProject(ctx, project.Id)
+		if err != nil {
+			return err
+		}
+		for _, worktree := range worktrees {
+			if worktree.IsBare || (w.cfg.IsActive != nil && !w.cfg.IsActive(worktree.Id)) {
+				continue
+			}
+			desired[worktree.Id] = worktree
+			order = append(order, worktree)
+		}
+	}
+
+	w.mu.Lock()
+	stale := make([]string, 0)
+	for id, entry := range w.entries {
+		if worktree, ok := desired[id]; !ok || filepath.Clean(worktree.Path) != entry.path {
+			stale = append(stale, id)
+		}
+	}
+	w.mu.Unlock()
+	for _, id := range stale {
+		w.Unwatch(id)
+	}
+
+	for _, worktree := range order {
+		if err := w.Watch(worktree); err != nil {
+			if errors.Is(err, ErrWorktreeWatchLimit) {
+				break
+			}
+			utils.Logger().Debug("failed to watch worktree",
+				zap.String("worktreeId", worktree.Id),
+				zap.String("path", worktree.Path),
+				zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// Watch starts watching a worktree. Watching an already watched worktree at the
+// same path is a no-op; a changed path replaces the previous watch.
+func (w *WorktreeWatcher) Watch(worktree *model.Worktree) error {
+	if worktree == nil {
+		return errors.New("worktree is required")
+	}
+	path := filepath.Clean(worktree.Path)
+	if info, err := os.Stat(path); err != nil {
+		return err
+	} else if !info.IsDir() {
+		return errors.New("worktree path is not a directory")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		return errors.New("worktree watcher is closed")
+	}
+	if entry, ok := w.entries[worktree.Id]; ok {
+		if entry.path == path {
+			return nil
+		}
+		w.removeEntryLocked(entry)
+	}
+	if len(w.entries) >= w.cfg.MaxWatchers {
+		if !w.limitWarned {
+			w.limitWarned = true
+			utils.Logger().Warn("worktree watch limit reached, remaining worktrees need manual refresh",
+				zap.Int("maxWatchers", w.cfg.MaxWatchers))
+		}
+		return ErrWorktreeWatchLimit
+	}
+
+	entry := &watchedWorktree{
+		id:      worktree.Id,
+		path:    path,
+		gitDirs: make(map[string]struct{}),
+	}
+	w.entries[worktree.Id] = entry
+	w.addTreeLocked(entry, path)
+	if gitDir := resolveWorktreeGitDir(path); gitDir != "" {
+		for _, dir := range []string{gitDir, filepath.Join(gitDir, "logs")} {
+			if w.addDirLocked(entry, dir) {
+				entry.gitDirs[dir] = struct{}{}
+			}
+		}
+	}
+	return nil
+}
+
+// Unwatch stops watching a worktree.
+func (w *WorktreeWatcher) Unwatch(id string) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if entry, ok := w.entries[id]; ok {
+		w.removeEntryLocked(entry)
+	}
+}
+
+// WatchedCount returns the number of watched worktrees.
+func (w *WorktreeWatcher) WatchedCount() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.entries)
+}
+
+// Close stops all watches and pending refreshes.
+func (w *WorktreeWatcher) Close() {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return
+	}
+	w.closed = true
+	for _, entry := range w.entries {
+		if entry.timer != nil {
+			entry.timer.Stop()
+		}
+	}
+	w.entries = make(map[string]*watchedWorktree)
+	w.dirs = make(map[string]*watchedWorktree)
+	w.mu.Unlock()
+	_ = w.watcher.Close()
+}
+
+// addTreeLocked watches root and its sub directories, skipping dependency/cache
+// directories and stopping at worktreeWatchMaxDirs.
+func (w *WorktreeWatcher) addTreeLocked(entry *watchedWorktree, root string) {
+	_ = filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && skipWatchedDir(d.Name()) {
+			return filepath.SkipDir
+		}
+		if len(entry.dirs) >= worktreeWatchMaxDirs {
+			return filepath.SkipAll
+		}
+		w.addDirLocked(entry, path)
+		return nil
+	})
+}
+
+func (w *WorktreeWatcher) addDirLocked(entry *watchedWorktree, dir string) bool {
+	if _, ok := w.dirs[dir]; ok {
+		return false
+	}
+	if err := w.watcher.Add(dir); err != nil {
+		return false
+	}
+	w.dirs[dir] = entry
+	entry.dirs = append(entry.dirs, dir)
+	return true
+}
+
+func (w *WorktreeWatcher) removeEntryLocked(entry *watchedWorktree) {
+	if entry.timer != nil {
+		entry.timer.Stop()
+	}
+	for _, dir := range entry.dirs {
+		if w.dirs[dir] == entry {
+			delete(w.dirs, dir)
+			_ = w.watcher.Remove(dir)
+		}
+	}
+	delete(w.entries, entry.id)
+	if len(w.entries) < w.cfg.MaxWatchers {
+		w.limitWarned = false
+	}
+}
+
+func (w *WorktreeWatcher) handleEvent(ctx context.Context, event fsnotify.Event) {
+	if event.Op == fsnotify.Chmod {
+		return
+	}
+	name := filepath.Clean(event.Name)
+	dir := filepath.Dir(name)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	entry, ok := w.dirs[dir]
+	if !ok {
+		// 被监听的目录自身被删除或重命名
+		entry, ok = w.dirs[name]
+		if !ok {
+			return
+		}
+	}
+
+	if _, isGitDir := entry.gitDirs[dir]; isGitDir {
+		// git status 自身会刷新 index，刷新期间的 gitdir 事件忽略，避免循环触发
+		if _, tracked := worktreeWatchGitFiles[filepath.Base(name)]; !tracked || entry.refreshing {
+			return
+		}
+	} else if event.Has(fsnotify.Create) && !skipWatchedDir(filepath.Base(name)) &&
+		len(entry.dirs) < worktreeWatchMaxDirs {
+		if info, err := os.Stat(name); err == nil && info.IsDir() {
+			w.addTreeLocked(entry, name)
+		}
+	}
+
+	w.scheduleLocked(ctx, entry)
+}
+
+// scheduleLocked debounces refreshes: every event restarts the timer, and events
+// arriving during a refresh trigger one more refresh afterwards.
+func (w *WorktreeWatcher) scheduleLocked(ctx context.Context, entry *watchedWorktree) {
+	if entry.refreshing {
+		entry.pending = true
+		return
+	}
+	if entry.timer != nil {
+		entry.timer.Stop()
+	}
+	entry.timer = time.AfterFunc(w.cfg.Debounce, func() {
+		w.runRefresh(ctx, entry)
+	})
+}
+
+func (w *WorktreeWatcher) runRefresh(ctx context.Context, entry *watchedWorktree) {
+	w.mu.Lock()
+	if w.closed || w.entries[entry.id] != entry || ctx.Err() != nil {
+		w.mu.Unlock()
+		return
+	}
+	entry.refreshing = true
+	entry.pending = false
+	w.mu.Unlock()
+
+	err := w.refresh(ctx, entry.id)
+
+	w.mu.Lock()
+	entry.refreshing = false
+	pending := entry.pending
+	if pending && !w.closed && w.entries[entry.id] == entry {
+		w.scheduleLocked(ctx, entry)
+	}
+	w.mu.Unlock()
+
+	if err != nil {
+		utils.Logger().Debug("worktree watcher refresh failed",
+			zap.String("worktreeId", entry.id),
+			zap.Error(err))
+	}
+}
+
+func skipWatchedDir(name string) bool {
+	_, ok := worktreeWatchSkipDirs[name]
+	return ok
+}
+
+// resolveWorktreeGitDir returns the gitdir of a worktree: the .git directory of a
+// main worktree or the directory referenced by the .git file of a linked worktree.
+func resolveWorktreeGitDir(worktreePath string) string {
+	dotGit := filepath.Join(worktreePath, ".git")
+	info, err := os.Stat(dotGit)
+	if err != nil {
+		return ""
+	}
+	if info.IsDir() {
+		return dotGit
+	}
+	content, err := os.ReadFile(dotGit)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "gitdir:") {
+			continue
+		}
+		gitDir := strings.TrimSpace(strings.TrimPrefix(line, "gitdir:"))
+		if !filepath.IsAbs(gitDir) {
+			gitDir = filepath.Join(worktreePath, gitDir)
+		}
+		return filepath.Clean(gitDir)
+	}
+	return ""
+}
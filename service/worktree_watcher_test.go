@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"code-kanban/model"
+)
+
+func waitForCount(t *testing.T, counter *atomic.Int32, want int32, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if counter.Load() >= want {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Fatalf("expected at least %d refreshes, got %d", want, counter.Load())
+}
+
+func TestWorktreeWatcherDebouncesRefresh(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	projectService := &model.ProjectService{}
+	project, err := projectService.CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Watch Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+
+	watcher, err := NewWorktreeWatcher(WorktreeWatcherConfig{Debounce: 100 * time.Millisecond})
+	if err != nil {
+		t.Skipf("fsnotify unavailable: %v", err)
+	}
+	var refreshed atomic.Int32
+	refreshedIDs := make(chan string, 8)
+	watcher.refresh = func(ctx context.Context, id string) error {
+		refreshed.Add(1)
+		refreshedIDs <- id
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := watcher.Reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if watcher.WatchedCount() != 1 {
+		t.Fatalf("expected main worktree to be watched, got %d", watcher.WatchedCount())
+	}
+	watcher.Start(ctx)
+
+	for i := 0; i < 5; i++ {
+		if err := os.WriteFile(filepath.Join(repoPath, "README.md"), []byte("change"+string(rune('a'+i))), 0o644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+	}
+	waitForCount(t, &refreshed, 1, 3*time.Second)
+	time.Sleep(300 * time.Millisecond)
+	if got := refreshed.Load(); got != 1 {
+		t.Fatalf("expected burst of writes to be debounced into 1 refresh, got %d", got)
+	}
+
+	worktrees, err := NewWorktreeService().ListWorktrees(ctx, project.Id)
+	if err != nil || len(worktrees) == 0 {
+		t.Fatalf("list worktrees failed: %v", err)
+	}
+	if id := <-refreshedIDs; id != worktrees[0].Id {
+		t.Fatalf("expected refresh of %s, got %s", worktrees[0].Id, id)
+	}
+
+	// 暂存只改变 .git/index，同样需要刷新
+	runGitCommand(t, repoPath, "add", "README.md")
+	waitForCount(t, &refreshed, 2, 3*time.Second)
+}
+
+func TestWorktreeWatcherLimit(t *testing.T) {
+	watcher, err := NewWorktreeWatcher(WorktreeWatcherConfig{MaxWatchers: 1})
+	if err != nil {
+		t.Skipf("fsnotify unavailable: %v", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Watch(&model.Worktree{Id: "wt-1", Path: t.TempDir()}); err != nil {
+		t.Fatalf("watch failed: %v", err)
+	}
+	if err := watcher.Watch(&model.Worktree{Id: "wt-2", Path: t.TempDir()}); !errors.Is(err, ErrWorktreeWatchLimit) {
+		t.Fatalf("expected ErrWorktreeWatchLimit, got %v", err)
+	}
+
+	watcher.Unwatch("wt-1")
+	if err := watcher.Watch(&model.Worktree{Id: "wt-2", Path: t.TempDir()}); err != nil {
+		t.Fatalf("watch after unwatch failed: %v", err)
+	}
+	if watcher.WatchedCount() != 1 {
+		t.Fatalf("expected 1 watched worktree, got %d", watcher.WatchedCount())
+	}
+}
+
+func TestWorktreeWatcherActiveOnly(t *testing.T) {
+	cleanup := initTestDB(t)
+	defer cleanup()
+
+	repoPath := createProjectTestRepo(t)
+	project, err := (&model.ProjectService{}).CreateProject(context.Background(), model.CreateProjectParams{
+		Name: "Active Watch Project",
+		Path: repoPath,
+	})
+	if err != nil {
+		t.Fatalf("create project failed: %v", err)
+	}
+	ctx := context.Background()
+	worktrees, err := NewWorktreeService().ListWorktrees(ctx, project.Id)
+	if err != nil || len(worktrees) == 0 {
+		t.Fatalf("list worktrees failed: %v", err)
+	}
+
+	var active atomic.Bool
+	watcher, err := NewWorktreeWatcher(WorktreeWatcherConfig{
+		IsActive: func(id string) bool { return id == worktrees[0].Id && active.Load() },
+	})
+	if err != nil {
+		t.Skipf("fsnotify unavailable: %v", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if watcher.WatchedCount() != 0 {
+		t.Fatalf("expected inactive worktree not to be watched, got %d", watcher.WatchedCount())
+	}
+
+	active.Store(true)
+	if err := watcher.Reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if watcher.WatchedCount() != 1 {
+		t.Fatalf("expected active worktree to be watched, got %d", watcher.WatchedCount())
+	}
+
+	active.Store(false)
+	if err := watcher.Reconcile(ctx); err != nil {
+		t.Fatalf("reconcile failed: %v", err)
+	}
+	if watcher.WatchedCount() != 0 {
+		t.Fatalf("expected watch to stop once the worktree is inactive, got %d", watcher.WatchedCount())
+	}
+}
@@ -0,0 +1,150 @@
+package ai_assistant2
+
+import (
+	"regexp"
+	"sync"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+const (
+	// bannerWindowBytes 保留最近的输出，助手往往在被识别之前就已经打印了启动横幅
+	bannerWindowBytes = 16 * 1024
+	// bannerScanLimit 识别到助手后最多再扫描的字节数，超出仍未匹配则放弃
+	bannerScanLimit = 64 * 1024
+)
+
+// bannerPatterns 启动横幅中的模型与版本规则，按顺序取第一个匹配。
+// ANSI 光标移动会被去掉而不是替换为空格，因此单词之间用 \s* 匹配。
+type bannerPatterns struct {
+	model   []*regexp.Regexp
+	version []*regexp.Regexp
+}
+
+var assistantBannerPatterns = map[types.AssistantType]bannerPatterns{
+	types.AssistantTypeClaudeCode: {
+		model: []*regexp.Regexp{
+			regexp.MustCompile(`\b(claude-(?:opus|sonnet|haiku)-\d[\w.-]*)`),
+			regexp.MustCompile(`\b((?:Opus|Sonnet|Haiku)\s*\d+(?:\.\d+)?)\s*·`),
+		},
+		version: []*regexp.Regexp{
+			regexp.MustCompile(`Claude\s*Code\s*v(\d+\.\d+\.\d+[\w.-]*)`),
+		},
+	},
+	types.AssistantTypeCodex: {
+		model: []*regexp.Regexp{
+			regexp.MustCompile(`model:\s*([A-Za-z0-9][\w.:-]*)`),
+		},
+		version: []*regexp.Regexp{
+			regexp.MustCompile(`OpenAI\s*Codex\s*\(v(\d+\.\d+\.\d+[\w.-]*)\)`),
+		},
+	},
+	types.AssistantTypeGemini: {
+		model: []*regexp.Regexp{
+			regexp.MustCompile(`\b(gemini-\d[\w.-]*)`),
+		},
+	},
+	types.AssistantTypeQwenCode: {
+		model: []*regexp.Regexp{
+			regexp.MustCompile(`\b(qwen\d*(?:-[\w.]+)+)`),
+		},
+	},
+}
+
+// BannerInfo is the model and CLI version printed by an assistant at startup.
+type BannerInfo struct {
+	Model      string
+	CliVersion string
+}
+
+// BannerExtractor extracts BannerInfo from the early output of an assistant.
+// Extraction runs once per detected assistant and the result is cached; a
+// banner that never matches simply leaves the fields empty.
+type BannerExtractor struct {
+	mu            sync.Mutex
+	assistantType types.AssistantType
+	window        []byte
+	scanned       int
+	done          bool
+	info          BannerInfo
+}
+
+// NewBannerExtractor creates an extractor with an empty output window.
+func NewBannerExtractor() *BannerExtractor {
+	return &BannerExtractor{}
+}
+
+// Feed records a chunk of terminal output and retries extraction while an
+// assistant is active and its banner has not been resolved yet.
+func (e *BannerExtractor) Feed(chunk []byte) {
+	if len(chunk) == 0 {
+		return
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.window = append(e.window, chunk...)
+	if overflow := len(e.window) - bannerWindowBytes; overflow > 0 {
+		e.window = append([]byte(nil), e.window[overflow:]...)
+	}
+	if e.assistantType == "" || e.done {
+		return
+	}
+	e.scanned += len(chunk)
+	e.extractLocked()
+}
+
+// SetAssistant switches the assistant whose banner is extracted. A different
+// type, including none, discards the cached result.
+func (e *BannerExtractor) SetAssistant(assistantType types.AssistantType) {
+	if assistantType == types.AssistantTypeUnknown {
+		assistantType = ""
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.assistantType == assistantType {
+		return
+	}
+	e.assistantType = assistantType
+	e.info = BannerInfo{}
+	e.scanned = 0
+	e.done = assistantType == ""
+	if !e.done {
+		e.extractLocked()
+	}
+}
+
+// Info returns the extracted banner info, empty fields when not found.
+func (e *BannerExtractor) Info() BannerInfo {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.info
+}
+
+func (e *BannerExtractor) extractLocked() {
+	patterns, ok := assistantBannerPatterns[e.assistantType]
+	if !ok {
+		e.done = true
+		return
+	}
+	text := types.StripANSI(string(e.window))
+	if e.info.Model == "" {
+		e.info.Model = firstSubmatch(patterns.model, text)
+	}
+	if e.info.CliVersion == "" {
+		e.info.CliVersion = firstSubmatch(patterns.version, text)
+	}
+	modelResolved := e.info.Model != "" || len(patterns.model) == 0
+	versionResolved := e.info.CliVersion != "" || len(patterns.version) == 0
+	if (modelResolved && versionResolved) || e.scanned >= bannerScanLimit {
+		e.done = true
+	}
+}
+
+func firstSubmatch(patterns []*regexp.Regexp, text string) string {
+	for _, pattern := range patterns {
+		if match := pattern.FindStringSubmatch(text); match != nil {
+			return match[1]
+		}
+	}
+	return ""
+}
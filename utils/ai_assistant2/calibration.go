@@ -0,0 +1,66 @@
+package ai_assistant2
+
+import (
+	"time"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// Recheck runs the detector on the current screen right away, without waiting
+// for the periodic check. Callers use it when process information disagrees with
+// the tracked state. It reports whether the state changed.
+func (t *StatusTracker) Recheck() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.active || t.detector == nil || t.paused {
+		return false
+	}
+	t.calibrated = false
+	return t.detectScreenLocked(time.Now(), DetectionSourceRecheck)
+}
+
+// Calibrate overrides the tracked state with one derived from process
+// information, with low confidence and match as the rule. Periodic detection is
+// suspended until new output arrives so the same screen cannot undo it. It
+// reports whether the state changed.
+func (t *StatusTracker) Calibrate(state types.State, match string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.active || t.detector == nil || t.paused || state == types.StateUnknown || state == t.lastState {
+		return false
+	}
+
+	now := time.Now()
+	prevState := t.lastState
+	t.countTurnLocked(prevState, state)
+	t.lastState = state
+	t.lastChangedAt = now
+	t.recentUpdatedAt = now
+	t.stateConfidence = types.ConfidenceLow
+	t.stateMatch = match
+	t.calibrated = true
+	if t.diagnostics {
+		t.recordDetectionLocked(DetectionRecord{
+			Time:          now,
+			Source:        DetectionSourceCalibration,
+			AssistantType: t.assistantType,
+			Match:         match,
+			DetectedState: state,
+			PreviousState: prevState,
+			State:         state,
+			Changed:       true,
+			Confidence:    types.ConfidenceLow,
+		})
+	}
+
+	t.emitStateChangeLocked(StateChangeEvent{
+		State:         state,
+		PreviousState: prevState,
+		Timestamp:     now,
+		Confidence:    types.ConfidenceLow,
+		Match:         match,
+	})
+	return true
+}
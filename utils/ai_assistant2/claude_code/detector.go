@@ -14,6 +14,10 @@ import (
 type StatusDetector struct {
 	recentInput  string
 	recentInput2 string
+	// lastMatch 记录最近一次判定命中的规则，供诊断模式使用
+	lastMatch string
+	// lowConfidence 最近一次判定只是没找到工作中特征时的兜底，而非明确的界面特征
+	lowConfidence bool
 }
 
 // NewStatusDetector creates a new Claude Code state detector
@@ -26,6 +30,8 @@ func NewStatusDetector() *StatusDetector {
 func (d *StatusDetector) DetectStateFromLines(lines []string, raw [][]vt10x.Glyph, cols int, timestamp time.Time, currentState types.State, lastDetectedAt time.Time, cursorX int, cursorY int) (types.State, bool) {
 	// Claude Code doesn't need stability checking like Codex
 	// Its UI is more stable and reliable
+	d.lastMatch = ""
+	d.lowConfidence = false
 	s := d.detectStateWorkingAndWaiting(lines, cols)
 	if s == types.StateUnknown {
 		s = d.detectStateApproval(lines, cols)
@@ -58,6 +64,20 @@ func (d *StatusDetector) isSeparatorLine(line string, cols int) bool {
 	return line == chatBoxBorder
 }
 
+// LastMatch returns the rule that decided the last detection.
+func (d *StatusDetector) LastMatch() string {
+	return d.lastMatch
+}
+
+// LastConfidence reports whether the last detection matched a Claude Code UI
+// element or only fell back to waiting input because no working task was found.
+func (d *StatusDetector) LastConfidence() types.Confidence {
+	if d.lowConfidence || d.lastMatch == "" {
+		return types.ConfidenceLow
+	}
+	return types.ConfidenceHigh
+}
+
 func (d *StatusDetector) GetRecentInput() string {
 	if d.recentInput == "" {
 		return d.recentInput2
@@ -0,0 +1,114 @@
+package claude_code
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// withCRLF 模拟 Windows 下每行残留的 \r
+func withCRLF(lines []string) []string {
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		out[i] = line + "\r"
+	}
+	return out
+}
+
+func TestParseTodos(t *testing.T) {
+	detector := NewStatusDetector()
+	lines := []string{
+		"✔ Build succeeded",
+		"⏺ Update Todos",
+		"  ⎿  ☒ Read the file",
+		"     ◼ Implement the feature with a description",
+		"       that wraps onto the next row",
+		"     ☐ Write tests",
+		"  ✓ unrelated check mark",
+		"> ",
+	}
+	want := []types.TodoItem{
+		{Text: "Read the file", Done: true},
+		{Text: "Implement the feature with a description that wraps onto the next row", InProgress: true},
+		{Text: "Write tests"},
+	}
+	for _, variant := range [][]string{lines, withCRLF(lines)} {
+		got := detector.ParseTodos(variant)
+		if len(got) != len(want) {
+			t.Fatalf("expected %d todos, got %+v", len(want), got)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("todo %d: expected %+v, got %+v", i, want[i], got[i])
+			}
+		}
+	}
+
+	// 不在 ⎿ 之下的标记不构成清单，行中间的 ⎿ 也不算
+	for _, stray := range [][]string{
+		{"✔ tests passed", "☐ not a todo"},
+		{"  output ⎿  ☐ not a todo"},
+		{"         ⎿  ☐ indented too far"},
+	} {
+		if got := detector.ParseTodos(stray); len(got) != 0 {
+			t.Fatalf("expected no todos for %q, got %+v", stray, got)
+		}
+	}
+
+	// 屏幕上有多个清单时取最后一个
+	got := detector.ParseTodos([]string{
+		"  ⎿  ☐ Old item",
+		"⏺ Update Todos",
+		"  ⎿  ☒ New item",
+	})
+	if len(got) != 1 || got[0].Text != "New item" || !got[0].Done {
+		t.Fatalf("expected the last list, got %+v", got)
+	}
+}
+
+func TestParseInterrupted(t *testing.T) {
+	sep := strings.Repeat("─", 40)
+	cases := []struct {
+		name  string
+		lines []string
+		want  bool
+	}{
+		{"interrupted", []string{"> fix the bug", "  ⎿  Interrupted · What should Claude do instead?", "", sep, "> ", sep}, true},
+		{"legacy marker", []string{"> fix the bug", "  ⎿  Interrupted by user", sep, "> ", sep}, true},
+		{"later reply", []string{"  ⎿  Interrupted by user", "⏺ Done.", "", sep, "> ", sep}, false},
+		{"working", []string{"  ⎿  Interrupted by user", "✻ Reading files… (esc to interrupt)", sep, "> ", sep}, false},
+		{"no input box", []string{"  ⎿  Interrupted by user", ""}, false},
+	}
+	detector := NewStatusDetector()
+	for _, tc := range cases {
+		if got := detector.ParseInterrupted(tc.lines); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestLastConfidence(t *testing.T) {
+	const cols = 40
+	sep := strings.Repeat("─", cols)
+	cases := []struct {
+		name  string
+		lines []string
+		want  types.Confidence
+	}{
+		{"working task", []string{"✻ Reading files… (esc to interrupt)", sep, "> ", sep}, types.ConfidenceHigh},
+		{"approval", []string{" Do you want to proceed?", " ❯ 1. Yes", "   2. No", " Esc to exit", ""}, types.ConfidenceHigh},
+		// 只找到输入框、上方没有工作中特征时判为等待输入，属于兜底
+		{"input box only", []string{"⏺ Done.", sep, "> ", sep}, types.ConfidenceLow},
+		{"undetected", []string{"$ ls"}, types.ConfidenceLow},
+	}
+	now := time.Now()
+	for _, tc := range cases {
+		detector := NewStatusDetector()
+		detector.DetectStateFromLines(tc.lines, nil, cols, now, types.StateUnknown, time.Time{}, 0, 0)
+		if got := detector.LastConfidence(); got != tc.want {
+			t.Errorf("%s: expected %s confidence (match %q), got %s", tc.name, tc.want, detector.LastMatch(), got)
+		}
+	}
+}
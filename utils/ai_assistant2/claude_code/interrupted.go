@@ -0,0 +1,53 @@
+package claude_code
+
+import "strings"
+
+// interruptedMarkers 用户按 ESC 打断后 Claude Code 在输入框上方打印的提示，兼容新旧版本
+var interruptedMarkers = []string{
+	"Interrupted · What should Claude do instead",
+	"Interrupted by user",
+}
+
+// ParseInterrupted reports whether the last turn was cancelled by the user, i.e.
+// the message right above the input box is the interruption notice:
+//
+//	  ⎿  Interrupted · What should Claude do instead?
+//
+//	──────────────
+//	>
+//	──────────────
+func (d *StatusDetector) ParseInterrupted(lines []string) bool {
+	cols := 0
+	for _, line := range lines {
+		if strings.Trim(line, "─") == "" && len([]rune(line)) > cols {
+			cols = len([]rune(line))
+		}
+	}
+	if cols == 0 {
+		return false
+	}
+
+	separators := 0
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		if separators < 2 {
+			if d.isSeparatorLine(line, cols) {
+				separators++
+			}
+			continue
+		}
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if d.isWorkingTaskLine(line) {
+			return false
+		}
+		for _, marker := range interruptedMarkers {
+			if strings.Contains(line, marker) {
+				return true
+			}
+		}
+		return false
+	}
+	return false
+}
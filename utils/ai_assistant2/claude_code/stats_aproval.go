@@ -38,6 +38,7 @@ func (d *StatusDetector) detectStateApproval(lines []string, cols int) types.Sta
 						if appovalPattern.MatchString(lines[k]) {
 							// Found approval flag, check if previous line is separator
 							if k > 0 && d.isSeparatorLine(lines[k-1], cols) {
+								d.lastMatch = "approval-select-menu"
 								return types.StateWaitingApproval
 							}
 						}
@@ -54,6 +55,7 @@ func (d *StatusDetector) detectStateApproval(lines []string, cols int) types.Sta
 				if appovalPattern.MatchString(lines[k]) {
 					// Found approval flag, check if previous line is separator
 					if k > 0 && d.isSeparatorLine(lines[k-1], cols) {
+						d.lastMatch = "approval-submit-answers"
 						return types.StateWaitingApproval
 					}
 				}
@@ -64,6 +66,7 @@ func (d *StatusDetector) detectStateApproval(lines []string, cols int) types.Sta
 		if strings.HasPrefix(line, " Do you want to ") && i < totalLines-1 {
 			for k := i - 1; k >= 0; k-- {
 				if arrowPattern.MatchString(lines[k]) {
+					d.lastMatch = "approval-do-you-want"
 					return types.StateWaitingApproval
 				}
 			}
@@ -73,6 +76,7 @@ func (d *StatusDetector) detectStateApproval(lines []string, cols int) types.Sta
 		if strings.HasPrefix(line, " Esc to exit") && i < totalLines-1 {
 			for k := i - 1; k >= 0; k-- {
 				if strings.HasPrefix(lines[k], " Do you want to proceed?") {
+					d.lastMatch = "approval-proceed"
 					return types.StateWaitingApproval
 				}
 			}
@@ -81,3 +85,13 @@ func (d *StatusDetector) detectStateApproval(lines []string, cols int) types.Sta
 
 	return types.StateUnknown
 }
+
+// ApprovalInput answers the "❯ 1. Yes" menu of a permission prompt.
+func (d *StatusDetector) ApprovalInput(lines []string, approve bool) ([]string, bool) {
+	return types.ApprovalMenuInput(types.ParseApprovalMenu(lines, "❯"), approve)
+}
+
+// ApprovalPromptID identifies the permission prompt shown in lines.
+func (d *StatusDetector) ApprovalPromptID(lines []string) string {
+	return types.ApprovalPromptID(lines, "❯")
+}
@@ -61,14 +61,18 @@ func (d *StatusDetector) detectStateWorkingAndWaiting(lines []string, cols int)
 			fmt.Println(firstSepIdx, secondSepIdx, d.isWorkingTaskLine(lines[currentLine-1]))
 
 			if currentLine > 0 && d.isWorkingTaskLine(lines[currentLine-1]) {
+				d.lastMatch = "tip-after-working-task"
 				return types.StateWorking
 			}
 		}
 		if d.isWorkingTaskLine(line) {
+			d.lastMatch = "working-task-line"
 			return types.StateWorking
 		}
 	}
 
 	// No Tip line found = waiting for input
+	d.lastMatch = "input-box"
+	d.lowConfidence = true
 	return types.StateWaitingInput
 }
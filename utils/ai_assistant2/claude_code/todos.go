@@ -0,0 +1,107 @@
+package claude_code
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode/utf8"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// todo 列表标记，兼容新旧版本：☒/☐ 以及 ✔/◼/◻
+var (
+	todoDoneMarkers       = []string{"☒", "✔", "✓", "☑"}
+	todoInProgressMarkers = []string{"◼", "■"}
+	todoPendingMarkers    = []string{"☐", "◻", "□"}
+)
+
+var (
+	todoMarkerGroup = "(" + strings.Join(slices.Concat(todoDoneMarkers, todoInProgressMarkers, todoPendingMarkers), "|") + ")"
+	// todoListStartPattern 清单第一项挂在行首附近的 ⎿ 之下，只从这里开始识别，避免把普通输出里的 ✔ 等符号当成清单
+	todoListStartPattern = regexp.MustCompile(`^( {0,4}⎿ +)` + todoMarkerGroup + ` +(\S.*)$`)
+	// todoItemPattern 后续各项只由空格缩进，标记需与第一项对齐
+	todoItemPattern = regexp.MustCompile(`^( +)` + todoMarkerGroup + ` +(\S.*)$`)
+)
+
+// maxTodoItems 防止异常输出产生过长的清单
+const maxTodoItems = 50
+
+// ParseTodos extracts the last todo list visible on screen, e.g.
+//
+//	⏺ Update Todos
+//	  ⎿  ☒ Read the file
+//	     ◼ Implement feature
+//	     ☐ Write tests
+//
+// A list starts at a marker under ⎿ and continues with markers aligned to the
+// first one. Wrapped item text is joined back; other lines end the list.
+func (d *StatusDetector) ParseTodos(lines []string) []types.TodoItem {
+	var (
+		current      []types.TodoItem
+		last         []types.TodoItem
+		markerColumn int
+		textColumn   int
+	)
+	flush := func() {
+		if len(current) > 0 {
+			last = current
+		}
+		current = nil
+	}
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r")
+		if item, marker, text, ok := parseTodoLine(todoListStartPattern, line); ok {
+			flush()
+			current = append(current, item)
+			markerColumn, textColumn = marker, text
+			continue
+		}
+		if len(current) == 0 {
+			continue
+		}
+		if item, marker, text, ok := parseTodoLine(todoItemPattern, line); ok && marker == markerColumn {
+			if len(current) < maxTodoItems {
+				current = append(current, item)
+				textColumn = text
+			}
+			continue
+		}
+		if isTodoContinuation(line, textColumn) {
+			prev := &current[len(current)-1]
+			prev.Text += " " + strings.TrimSpace(line)
+			continue
+		}
+		flush()
+	}
+	flush()
+	return last
+}
+
+// parseTodoLine matches line against pattern and returns the item with the
+// rune columns of its marker and of its text.
+func parseTodoLine(pattern *regexp.Regexp, line string) (types.TodoItem, int, int, bool) {
+	match := pattern.FindStringSubmatch(line)
+	if match == nil {
+		return types.TodoItem{}, 0, 0, false
+	}
+	prefix, marker, text := match[1], match[2], match[3]
+	item := types.TodoItem{
+		Text:       strings.TrimSpace(text),
+		Done:       slices.Contains(todoDoneMarkers, marker),
+		InProgress: slices.Contains(todoInProgressMarkers, marker),
+	}
+	markerColumn := utf8.RuneCountInString(prefix)
+	textColumn := utf8.RuneCountInString(line) - utf8.RuneCountInString(text)
+	return item, markerColumn, textColumn, true
+}
+
+// isTodoContinuation reports whether line is the wrapped tail of the previous item,
+// i.e. indented at least up to the item text.
+func isTodoContinuation(line string, textColumn int) bool {
+	if strings.TrimSpace(line) == "" || textColumn <= 0 {
+		return false
+	}
+	indent := len(line) - len(strings.TrimLeft(line, " "))
+	return indent >= textColumn
+}
@@ -2,6 +2,7 @@ package codex
 
 import (
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 
@@ -12,8 +13,20 @@ import (
 
 var contextLeftLinePattern = regexp.MustCompile(`^  \d+% context left`)
 
+// contextLeftPercentPattern 匹配状态栏中的上下文余量，如 "100% context left"、
+// "  57% context left · ? for shortcuts"，兼容小数、大小写及 "context window left"。
+// 百分比须位于行首缩进之后或状态栏多个空格分隔的列首，其后只能是行尾或 " · " 分隔的其他提示，
+// 避免把对话内容中提到的 "context left" 当作状态栏
+var contextLeftPercentPattern = regexp.MustCompile(`(?i)^\s*(?:.*\S {2,})?(\d{1,3})(?:\.\d+)?\s*%\s*(?:of\s+)?context(?:\s+window)?\s+left\s*(?:·.*)?$`)
+
+// workedDurationPattern 匹配 "Worked for" 之后的时长片段，如 1h 2m 3s、2m15s、45s
+var workedDurationPattern = regexp.MustCompile(`(\d+)\s*([hms])`)
+
+// tokenUsagePattern 匹配退出时的用量行，如 "Token usage: total=1,234 input=1,000 (+ 512 cached) output=234"
+var tokenUsagePattern = regexp.MustCompile(`^\s*Token usage:.*?\binput=([\d,]+).*?\boutput=([\d,]+)`)
+
 const (
-	// minWorkingExitInterval is the minimum time required to exit from working state
+	// minWorkingExitInterval is the default minimum time required to exit from working state
 	// This prevents false negatives when working indicator temporarily disappears between chunks
 	minWorkingExitInterval = 1000 * time.Millisecond
 )
@@ -21,6 +34,8 @@ const (
 const (
 	codexInputPrompt  = "› "
 	codexIndentPrefix = "  "
+	// codexInterruptedPrefix 用户按 ESC 打断后 Codex 打印的提示
+	codexInterruptedPrefix = "■ Conversation interrupted"
 )
 
 // StatusDetector implements state detection for Codex
@@ -33,8 +48,15 @@ type StatusDetector struct {
 	// Selection arrow pattern for approval
 	selectionPattern *regexp.Regexp
 
+	// workingExitInterval 离开 working 前需要持续看不到工作提示的时长
+	workingExitInterval time.Duration
+
 	recentInput  string
 	recentInput2 string
+	// lastMatch 记录最近一次判定命中的规则，供诊断模式使用
+	lastMatch string
+	// lowConfidence 最近一次判定来自兜底规则或防抖，而非明确的界面特征
+	lowConfidence bool
 }
 
 // NewStatusDetector creates a new Codex state detector
@@ -46,16 +68,27 @@ func NewStatusDetector() *StatusDetector {
 
 		// Selection arrow: › followed by digit and dot
 		selectionPattern: regexp.MustCompile(`^› \d+\. `),
+
+		workingExitInterval: minWorkingExitInterval,
 	}
 }
 
+// SetWorkingExitInterval overrides minWorkingExitInterval; non-positive values restore it.
+func (d *StatusDetector) SetWorkingExitInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = minWorkingExitInterval
+	}
+	d.workingExitInterval = interval
+}
+
 // DetectStateFromLines analyzes multiple lines and returns the detected state.
 // The raw glyph grid is currently unused but provided for future heuristics.
 func (d *StatusDetector) DetectStateFromLines(lines []string, raw [][]vt10x.Glyph, cols int, timestamp time.Time, currentState types.State, lastDetectedAt time.Time, cursorX int, cursorY int) (types.State, bool) {
+	d.lastMatch = ""
+	d.lowConfidence = false
 	if len(lines) == 0 {
 		return types.StateUnknown, true
 	}
-
 	// Detect new state from current display
 	newState := d.detectFromDisplay(lines, raw)
 
@@ -72,7 +105,9 @@ func (d *StatusDetector) DetectStateFromLines(lines []string, raw [][]vt10x.Glyp
 
 		// If less than minimum interval, ignore this detection
 		// Return StateUnknown to indicate we should keep the current state without updating recentUpdatedAt
-		if timeSinceLastDetection < minWorkingExitInterval {
+		if timeSinceLastDetection < d.workingExitInterval {
+			d.lastMatch += " (working-exit-debounce)"
+			d.lowConfidence = true
 			return currentState, false
 		}
 	}
@@ -92,11 +127,13 @@ func (d *StatusDetector) detectFromDisplay(lines []string, raw [][]vt10x.Glyph)
 		line := lines[i]
 
 		if d.isWorkedLine(line) {
+			d.lastMatch = "worked-line"
 			return types.StateWaitingInput
 		}
 
 		// Check for working state (fast path first)
 		if d.isWorkingLine(line) {
+			d.lastMatch = "working-line"
 			return types.StateWorking
 		}
 
@@ -105,15 +142,29 @@ func (d *StatusDetector) detectFromDisplay(lines []string, raw [][]vt10x.Glyph)
 			// Search upward for selection arrow
 			for j := i - 1; j >= 0; j-- {
 				if d.selectionPattern.MatchString(lines[j]) {
+					d.lastMatch = "approval-confirm"
 					return types.StateWaitingApproval
 				}
 			}
 		}
 	}
 
+	d.lastMatch = "default-waiting"
+	d.lowConfidence = true
 	return types.StateWaitingInput
 }
 
+// ApprovalInput answers the "› 1. Yes, proceed" menu shown before running a
+// command or applying a patch.
+func (d *StatusDetector) ApprovalInput(lines []string, approve bool) ([]string, bool) {
+	return types.ApprovalMenuInput(types.ParseApprovalMenu(lines, "›"), approve)
+}
+
+// ApprovalPromptID identifies the approval prompt shown in lines.
+func (d *StatusDetector) ApprovalPromptID(lines []string) string {
+	return types.ApprovalPromptID(lines, "›")
+}
+
 func (d *StatusDetector) isWorkedLine(line string) bool {
 	if strings.HasPrefix(line, "─ Worked for ") && strings.HasSuffix(line, "─────────") {
 		return true
@@ -121,6 +172,138 @@ func (d *StatusDetector) isWorkedLine(line string) bool {
 	return false
 }
 
+// ParseWorkDuration reads the duration from the bottom-most "─ Worked for …" line.
+// A working indicator below that line means a new turn is running and the old
+// duration no longer applies.
+func (d *StatusDetector) ParseWorkDuration(lines []string) (time.Duration, bool) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		if d.isWorkingLine(line) {
+			return 0, false
+		}
+		if d.isWorkedLine(line) {
+			return parseWorkedDuration(line)
+		}
+	}
+	return 0, false
+}
+
+// ParseInterrupted reports whether the latest turn ended with the
+// "■ Conversation interrupted" notice Codex prints after ESC. The search stops at
+// a working indicator, a "─ Worked for …" line or an earlier user prompt, since
+// anything above those belongs to a previous turn.
+func (d *StatusDetector) ParseInterrupted(lines []string) bool {
+	composerSeen := false
+	for i := len(lines) - 1; i >= 0; i-- {
+		line := lines[i]
+		if d.isWorkingLine(line) || d.isWorkedLine(line) {
+			return false
+		}
+		if strings.HasPrefix(line, codexInterruptedPrefix) {
+			return true
+		}
+		// 最下面的 "› " 是输入框本身，再往上的是上一轮用户输入
+		if strings.HasPrefix(line, codexInputPrompt) {
+			if composerSeen {
+				return false
+			}
+			composerSeen = true
+		}
+	}
+	return false
+}
+
+// parseWorkedDuration parses "─ Worked for 2m 15s ───" style lines. Units may be
+// combined with or without spaces; any leftover text makes the parse fail.
+func parseWorkedDuration(line string) (time.Duration, bool) {
+	text := strings.TrimPrefix(line, "─ Worked for ")
+	text = strings.TrimSpace(strings.TrimRight(text, "─ "))
+	if text == "" {
+		return 0, false
+	}
+
+	matches := workedDurationPattern.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	var total time.Duration
+	pos := 0
+	seen := make(map[byte]bool, 3)
+	for _, match := range matches {
+		if strings.TrimSpace(text[pos:match[0]]) != "" {
+			return 0, false
+		}
+		value, err := strconv.Atoi(text[match[2]:match[3]])
+		if err != nil {
+			return 0, false
+		}
+		unit := text[match[4]]
+		if seen[unit] {
+			return 0, false
+		}
+		seen[unit] = true
+		switch unit {
+		case 'h':
+			total += time.Duration(value) * time.Hour
+		case 'm':
+			total += time.Duration(value) * time.Minute
+		case 's':
+			total += time.Duration(value) * time.Second
+		}
+		pos = match[1]
+	}
+	if strings.TrimSpace(text[pos:]) != "" {
+		return 0, false
+	}
+	return total, true
+}
+
+// ParseTokenUsage reads the bottom-most "Token usage:" line Codex prints on exit.
+// The model is left empty; the tracker fills it from the startup banner.
+func (d *StatusDetector) ParseTokenUsage(lines []string) (types.TokenUsage, bool) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		match := tokenUsagePattern.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		input, err := parseTokenCount(match[1])
+		if err != nil {
+			return types.TokenUsage{}, false
+		}
+		output, err := parseTokenCount(match[2])
+		if err != nil {
+			return types.TokenUsage{}, false
+		}
+		return types.TokenUsage{
+			Assistant:    types.AssistantTypeCodex,
+			InputTokens:  input,
+			OutputTokens: output,
+		}, true
+	}
+	return types.TokenUsage{}, false
+}
+
+// ParseContextLeft reads the percentage from the bottom-most "NN% context left"
+// indicator of the Codex status bar.
+func (d *StatusDetector) ParseContextLeft(lines []string) (int, bool) {
+	for i := len(lines) - 1; i >= 0; i-- {
+		match := contextLeftPercentPattern.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		percent, err := strconv.Atoi(match[1])
+		if err != nil || percent > 100 {
+			return 0, false
+		}
+		return percent, true
+	}
+	return 0, false
+}
+
+func parseTokenCount(text string) (int64, error) {
+	return strconv.ParseInt(strings.ReplaceAll(text, ",", ""), 10, 64)
+}
+
 // isWorkingLine checks if a line indicates Codex is working
 func (d *StatusDetector) isWorkingLine(line string) bool {
 	if line == "" {
@@ -152,6 +335,20 @@ func DetectStateFromLines(lines []string, raw [][]vt10x.Glyph, cols int, timesta
 	return defaultDetector.DetectStateFromLines(lines, raw, cols, timestamp, currentState, lastDetectedAt, cursorX, cursorY)
 }
 
+// LastMatch returns the rule that decided the last detection.
+func (d *StatusDetector) LastMatch() string {
+	return d.lastMatch
+}
+
+// LastConfidence reports whether the last detection matched a Codex UI element
+// or only fell back to the default waiting state.
+func (d *StatusDetector) LastConfidence() types.Confidence {
+	if d.lowConfidence || d.lastMatch == "" {
+		return types.ConfidenceLow
+	}
+	return types.ConfidenceHigh
+}
+
 func (d *StatusDetector) GetRecentInput() string {
 	if d.recentInput == "" {
 		return d.recentInput2
@@ -169,7 +366,8 @@ func (d *StatusDetector) detectStateWorkingAndWaiting(lines []string, raw [][]vt
 		d.captureRecentInput(lines[startIdx:endIdx], isEmpty)
 	}
 
-	if startIdx == -1 {
+	windowFound := startIdx != -1
+	if !windowFound {
 		startIdx = len(lines)
 	}
 
@@ -177,10 +375,14 @@ func (d *StatusDetector) detectStateWorkingAndWaiting(lines []string, raw [][]vt
 	for ; currentLine >= 0; currentLine-- {
 		line := lines[currentLine]
 		if d.isWorkingLine(line) {
+			d.lastMatch = "input-window-working"
 			return types.StateWorking
 		}
 	}
 
+	d.lastMatch = "input-window-idle"
+	// 没找到输入框时只是没看到工作行，不能确定助手已空闲
+	d.lowConfidence = !windowFound
 	return types.StateWaitingInput
 }
 
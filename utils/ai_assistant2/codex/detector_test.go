@@ -0,0 +1,52 @@
+package codex
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseContextLeft(t *testing.T) {
+	cases := []struct {
+		line string
+		want int
+		ok   bool
+	}{
+		{"  100% context left", 100, true},
+		{"  57% context left · ? for shortcuts", 57, true},
+		{"  ⏎ send   52.3K tokens used   95.5% context left", 95, true},
+		{"  12 % Context Window Left", 12, true},
+		{"  250% context left", 0, false},
+		{"  1250% context left", 0, false},
+		{"  context left unknown", 0, false},
+		// 对话内容中提到的余量不是状态栏
+		{"• The status bar shows 40% context left right now", 0, false},
+		{"  about 30% context left before compaction", 0, false},
+	}
+	detector := NewStatusDetector()
+	for _, tc := range cases {
+		got, ok := detector.ParseContextLeft([]string{"› ", "", tc.line})
+		if got != tc.want || ok != tc.ok {
+			t.Fatalf("%q: expected %d/%v, got %d/%v", tc.line, tc.want, tc.ok, got, ok)
+		}
+	}
+}
+
+func TestParseInterrupted(t *testing.T) {
+	cases := []struct {
+		name  string
+		lines []string
+		want  bool
+	}{
+		{"interrupted", []string{"› fix the bug", "■ Conversation interrupted - tell the model what to do differently", "", "› ", "", "  100% context left"}, true},
+		{"earlier turn", []string{"■ Conversation interrupted - tell the model what to do differently", "› try again", "• Done.", "", "› "}, false},
+		{"working", []string{"■ Conversation interrupted", "• Working (3s • esc to interrupt)", "", "› "}, false},
+		{"worked", []string{"■ Conversation interrupted", "─ Worked for 12s " + strings.Repeat("─", 20), "", "› "}, false},
+		{"no notice", []string{"• Done.", "", "› "}, false},
+	}
+	detector := NewStatusDetector()
+	for _, tc := range cases {
+		if got := detector.ParseInterrupted(tc.lines); got != tc.want {
+			t.Errorf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
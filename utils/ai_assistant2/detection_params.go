@@ -0,0 +1,55 @@
+package ai_assistant2
+
+import (
+	"time"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// DetectionParams tunes how often the tracker runs its detector and how long
+// detectors hold the working state. Zero fields keep the built-in defaults.
+type DetectionParams struct {
+	// ProcessInterval 持续输出时两次检测的最小间隔
+	ProcessInterval time.Duration
+	// IdleCheckInterval 没有新输出时定时复查屏幕的间隔
+	IdleCheckInterval time.Duration
+	// WorkingExitInterval 工作提示消失多久后才离开 working，只对支持的检测器生效
+	WorkingExitInterval time.Duration
+}
+
+// SetDetectionParams applies params to the tracker and its current detector.
+// A changed idle check interval restarts the periodic check of an active tracker.
+func (t *StatusTracker) SetDetectionParams(params DetectionParams) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	restart := t.active && t.idleCheckIntervalLocked() != orDefault(params.IdleCheckInterval, periodicCheckInterval)
+	t.params = params
+	t.applyWorkingExitLocked()
+	if restart {
+		t.startPeriodicCheckLocked()
+	}
+}
+
+func (t *StatusTracker) processIntervalLocked() time.Duration {
+	return orDefault(t.params.ProcessInterval, minProcessInterval)
+}
+
+func (t *StatusTracker) idleCheckIntervalLocked() time.Duration {
+	return orDefault(t.params.IdleCheckInterval, periodicCheckInterval)
+}
+
+// applyWorkingExitLocked passes the working exit interval to detectors that
+// support it; zero restores their own default.
+func (t *StatusTracker) applyWorkingExitLocked() {
+	if configurer, ok := t.detector.(types.WorkingExitConfigurer); ok {
+		configurer.SetWorkingExitInterval(t.params.WorkingExitInterval)
+	}
+}
+
+func orDefault(value, fallback time.Duration) time.Duration {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
@@ -52,6 +52,37 @@ var defaultRules = []DetectionRule{
 	},
 }
 
+// AssistantCapability describes an assistant type and what the tracker supports for it
+type AssistantCapability struct {
+	Type                     string   `json:"type"`
+	DisplayName              string   `json:"displayName"`
+	SupportsProgressTracking bool     `json:"supportsProgressTracking"`
+	SupportsTokenUsage       bool     `json:"supportsTokenUsage"`
+	CommandPatterns          []string `json:"commandPatterns"`
+}
+
+// ListAssistantCapabilities returns metadata for every known assistant type
+func ListAssistantCapabilities() []AssistantCapability {
+	assistantTypes := types.AllAssistantTypes()
+	result := make([]AssistantCapability, 0, len(assistantTypes))
+	for _, assistantType := range assistantTypes {
+		patterns := make([]string, 0)
+		for _, rule := range defaultRules {
+			if rule.Type == assistantType {
+				patterns = append(patterns, rule.Patterns...)
+			}
+		}
+		result = append(result, AssistantCapability{
+			Type:                     assistantType.String(),
+			DisplayName:              assistantType.DisplayName(),
+			SupportsProgressTracking: assistantType.SupportsProgressTracking(),
+			SupportsTokenUsage:       assistantType.SupportsTokenUsage(),
+			CommandPatterns:          patterns,
+		})
+	}
+	return result
+}
+
 // Match checks if the command matches this rule
 func (r *DetectionRule) Match(command string) bool {
 	if command == "" {
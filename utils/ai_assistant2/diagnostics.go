@@ -0,0 +1,104 @@
+package ai_assistant2
+
+import (
+	"time"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// diagnosticsBufferSize 诊断模式下保留的最近判定记录条数
+const diagnosticsBufferSize = 64
+
+// Detection sources recorded in DetectionRecord.Source.
+const (
+	DetectionSourceChunk    = "chunk"
+	DetectionSourcePeriodic = "periodic"
+	// DetectionSourceRecheck 进程信息与状态不一致时立即重新检测
+	DetectionSourceRecheck = "recheck"
+	// DetectionSourceCalibration 根据进程信息校准状态，不经过检测器
+	DetectionSourceCalibration = "calibration"
+)
+
+// DetectionRecord describes one detector decision, recorded only in diagnostics mode.
+type DetectionRecord struct {
+	Time          time.Time           `json:"time"`
+	Source        string              `json:"source"`
+	AssistantType types.AssistantType `json:"assistantType"`
+	Lines         []string            `json:"lines"`
+	CursorX       int                 `json:"cursorX"`
+	CursorY       int                 `json:"cursorY"`
+	// Match 为检测器报告的命中规则，检测器未实现 types.MatchReporter 时为空
+	Match            string      `json:"match,omitempty"`
+	DetectedState    types.State `json:"detectedState"`
+	ActuallyDetected bool        `json:"actuallyDetected"`
+	PreviousState    types.State `json:"previousState"`
+	State            types.State `json:"state"`
+	Changed          bool        `json:"changed"`
+	// SinceLastDetectedMs 距上次确认当前状态的时间，即防抖判断依据
+	SinceLastDetectedMs int64 `json:"sinceLastDetectedMs"`
+	// Confidence 为检测器报告的置信度，检测器未实现 types.ConfidenceReporter 时为空
+	Confidence types.Confidence `json:"confidence,omitempty"`
+}
+
+// DiagnosticsHook receives every record while diagnostics mode is on. It is called
+// with the tracker lock held and must not call back into the tracker.
+type DiagnosticsHook func(record DetectionRecord)
+
+// SetDiagnostics toggles diagnostics mode. It is off by default because every
+// detection is recorded including the visible lines.
+func (t *StatusTracker) SetDiagnostics(enabled bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.diagnostics = enabled
+	if !enabled {
+		t.diagRecords = nil
+		t.diagNext = 0
+	}
+}
+
+// DiagnosticsEnabled reports whether diagnostics mode is on.
+func (t *StatusTracker) DiagnosticsEnabled() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.diagnostics
+}
+
+// SetDiagnosticsHook configures a hook, typically a structured logger, for detection records.
+func (t *StatusTracker) SetDiagnosticsHook(hook DiagnosticsHook) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.diagHook = hook
+}
+
+// DiagnosticRecords returns the recorded decisions from oldest to newest.
+func (t *StatusTracker) DiagnosticRecords() []DetectionRecord {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.diagRecords) < diagnosticsBufferSize {
+		return append([]DetectionRecord(nil), t.diagRecords...)
+	}
+	result := make([]DetectionRecord, 0, len(t.diagRecords))
+	result = append(result, t.diagRecords[t.diagNext:]...)
+	return append(result, t.diagRecords[:t.diagNext]...)
+}
+
+func (t *StatusTracker) recordDetectionLocked(record DetectionRecord) {
+	if len(t.diagRecords) < diagnosticsBufferSize {
+		t.diagRecords = append(t.diagRecords, record)
+	} else {
+		t.diagRecords[t.diagNext] = record
+		t.diagNext = (t.diagNext + 1) % diagnosticsBufferSize
+	}
+	if t.diagHook != nil {
+		t.diagHook(record)
+	}
+}
+
+// trimTrailingBlankLines drops empty rows at the bottom of the viewport to keep records small.
+func trimTrailingBlankLines(lines []string) []string {
+	end := len(lines)
+	for end > 0 && lines[end-1] == "" {
+		end--
+	}
+	return append([]string(nil), lines[:end]...)
+}
@@ -0,0 +1,59 @@
+package ai_assistant2
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestDiagnosticRecordsWraparound(t *testing.T) {
+	tracker := NewStatusTracker()
+	tracker.SetDiagnostics(true)
+	hooked := 0
+	tracker.SetDiagnosticsHook(func(DetectionRecord) { hooked++ })
+
+	record := func(i int) {
+		tracker.mu.Lock()
+		tracker.recordDetectionLocked(DetectionRecord{Match: strconv.Itoa(i)})
+		tracker.mu.Unlock()
+	}
+	assertRange := func(first, last int) {
+		t.Helper()
+		records := tracker.DiagnosticRecords()
+		if len(records) != last-first+1 {
+			t.Fatalf("expected %d records, got %d", last-first+1, len(records))
+		}
+		for i, r := range records {
+			if r.Match != strconv.Itoa(first+i) {
+				t.Fatalf("record %d: expected %d, got %s", i, first+i, r.Match)
+			}
+		}
+	}
+
+	// 未写满时按写入顺序返回
+	for i := range 3 {
+		record(i)
+	}
+	assertRange(0, 2)
+
+	// 恰好写满与回绕后都从最旧的一条开始
+	for i := 3; i < diagnosticsBufferSize; i++ {
+		record(i)
+	}
+	assertRange(0, diagnosticsBufferSize-1)
+	for i := diagnosticsBufferSize; i < 2*diagnosticsBufferSize+5; i++ {
+		record(i)
+	}
+	assertRange(diagnosticsBufferSize+5, 2*diagnosticsBufferSize+4)
+	if hooked != 2*diagnosticsBufferSize+5 {
+		t.Fatalf("expected the hook for every record, got %d", hooked)
+	}
+
+	// 关闭后清空，重新开启时从头记录
+	tracker.SetDiagnostics(false)
+	if records := tracker.DiagnosticRecords(); len(records) != 0 {
+		t.Fatalf("expected records to be dropped, got %d", len(records))
+	}
+	tracker.SetDiagnostics(true)
+	record(7)
+	assertRange(7, 7)
+}
@@ -9,6 +9,9 @@ import (
 // AIAssistantInfo is exported from types package for convenience
 type AIAssistantInfo = types.AIAssistantInfo
 
+// TodoItem is exported from types package for convenience
+type TodoItem = types.TodoItem
+
 // ToAIAssistantInfo converts AssistantInfo to AIAssistantInfo for API responses
 func ToAIAssistantInfo(info *types.AssistantInfo) *AIAssistantInfo {
 	if info == nil {
@@ -33,3 +36,13 @@ func SetState(info *AIAssistantInfo, state types.State, timestamp time.Time) {
 	info.State = string(state)
 	info.StateUpdatedAt = timestamp
 }
+
+// SetStateConfidence records how certain the detector is about the current state
+func SetStateConfidence(info *AIAssistantInfo, confidence types.Confidence, match string) {
+	if info == nil {
+		return
+	}
+
+	info.StateConfidence = string(confidence)
+	info.StateMatch = match
+}
@@ -1,8 +1,11 @@
 package ai_assistant2
 
 import (
+	"bytes"
 	"sync"
 
+	"code-kanban/utils/ai_assistant2/types"
+
 	"github.com/tuzig/vt10x"
 )
 
@@ -12,7 +15,34 @@ var captureTerminalPool = sync.Pool{
 	},
 }
 
-var captureClearSequence = []byte("\x1b[2J\x1b[H")
+// captureResetSequence 把池中取出的终端恢复到初始状态，依次：
+//   - ESC \ 结束上次输出残留的未完成序列（OSC/DCS 字符串、CSI 参数），避免吞掉后面的序列
+//   - 进入再退出备用屏幕：无论之前是否在备用屏幕，都会清空备用屏幕并回到主屏幕
+//   - RIS 重置光标、属性、滚动区、制表位与模式
+//   - vt10x 的 RIS 把行列参数传反，只清空了前 rows 列，再用 ED 2 清空整个主屏幕
+var captureResetSequence = []byte("\x1b\\\x1b[?1049h\x1b[?1049l\x1bc\x1b[2J\x1b[H")
+
+// captureColorOverride OSC 4 修改的调色板无法通过序列整体恢复，写入过的终端不放回池中
+var captureColorOverride = []byte("\x1b]4;")
+
+// acquireCaptureTerminal takes a terminal from the pool and resets it to the
+// initial state at the given size, so nothing written by a previous capture
+// leaks into this one.
+func acquireCaptureTerminal(rows, cols int) vt10x.Terminal {
+	term := captureTerminalPool.Get().(vt10x.Terminal)
+	term.Resize(cols, rows)
+	_, _ = term.Write(captureResetSequence)
+	return term
+}
+
+// releaseCaptureTerminal returns term to the pool unless data changed state
+// the reset sequence cannot undo.
+func releaseCaptureTerminal(term vt10x.Terminal, data []byte) {
+	if bytes.Contains(data, captureColorOverride) {
+		return
+	}
+	captureTerminalPool.Put(term)
+}
 
 // getVisibleLinesLocked extracts visible lines from the emulator (must be called with lock held)
 func getVisibleLinesLocked(t *StatusTracker) ([]string, [][]vt10x.Glyph) {
@@ -26,6 +56,8 @@ func getVisibleLinesLocked(t *StatusTracker) ([]string, [][]vt10x.Glyph) {
 }
 
 // renderLinesFromTerminal captures terminal contents and optionally copies glyphs into the provided raw grid.
+// Every detector input is rendered here, so CRLF leftovers are stripped once
+// for live output, replays and buffer renders alike.
 func renderLinesFromTerminal(term vt10x.Terminal, raw [][]vt10x.Glyph, rows, cols int) ([]string, [][]vt10x.Glyph) {
 	if term == nil || rows <= 0 || cols <= 0 {
 		return nil, raw
@@ -70,7 +102,7 @@ func renderLinesFromTerminal(term vt10x.Terminal, raw [][]vt10x.Glyph, rows, col
 		lines = append(lines, string(runes))
 	}
 
-	return lines, raw
+	return types.NormalizeLineEndings(lines), raw
 }
 
 // RenderLinesFromBuffer feeds data into a pooled terminal and returns visible rows.
@@ -79,11 +111,8 @@ func RenderLinesFromBuffer(data []byte, rows, cols int) []string {
 		return nil
 	}
 
-	term := captureTerminalPool.Get().(vt10x.Terminal)
-	defer captureTerminalPool.Put(term)
-
-	term.Resize(cols, rows)
-	_, _ = term.Write(captureClearSequence)
+	term := acquireCaptureTerminal(rows, cols)
+	defer releaseCaptureTerminal(term, data)
 	_, _ = term.Write(data)
 
 	lines, _ := renderLinesFromTerminal(term, nil, rows, cols)
@@ -92,18 +121,22 @@ func RenderLinesFromBuffer(data []byte, rows, cols int) []string {
 
 // RenderGlyphGridFromBuffer feeds data into a pooled terminal and returns the raw glyph grid.
 func RenderGlyphGridFromBuffer(data []byte, rows, cols int) [][]vt10x.Glyph {
+	grid, _ := renderGlyphGridWithMode(data, rows, cols)
+	return grid
+}
+
+// renderGlyphGridWithMode is RenderGlyphGridFromBuffer that also returns the
+// terminal mode left by data, e.g. whether autowrap (DECAWM) is still on.
+func renderGlyphGridWithMode(data []byte, rows, cols int) ([][]vt10x.Glyph, vt10x.ModeFlag) {
 	if len(data) == 0 || rows <= 0 || cols <= 0 {
-		return nil
+		return nil, vt10x.ModeWrap
 	}
 
-	term := captureTerminalPool.Get().(vt10x.Terminal)
-	defer captureTerminalPool.Put(term)
-
-	term.Resize(cols, rows)
-	_, _ = term.Write(captureClearSequence)
+	term := acquireCaptureTerminal(rows, cols)
+	defer releaseCaptureTerminal(term, data)
 	_, _ = term.Write(data)
 
-	return renderRawFromTerminal(term, rows, cols)
+	return renderRawFromTerminal(term, rows, cols), term.Mode()
 }
 
 func renderRawFromTerminal(term vt10x.Terminal, rows, cols int) [][]vt10x.Glyph {
@@ -163,3 +196,79 @@ func ensureGlyphGrid(raw [][]vt10x.Glyph, rows, cols int) [][]vt10x.Glyph {
 
 	return raw
 }
+
+// LineCell records the screen position of a rune within a logical line.
+type LineCell struct {
+	Row int
+	Col int
+}
+
+// LogicalLine is a screen line with soft-wrapped continuation rows joined back together.
+// Cells holds the screen position of each rune in Text.
+type LogicalLine struct {
+	Text  string
+	Cells []LineCell
+}
+
+// RenderLogicalLinesFromBuffer feeds data into a pooled terminal and returns visible
+// content as logical lines, joining rows the emulator marked as soft-wrapped.
+func RenderLogicalLinesFromBuffer(data []byte, rows, cols int) []LogicalLine {
+	lines, _ := RenderLogicalLinesWithWrapMode(data, rows, cols)
+	return lines
+}
+
+// RenderLogicalLinesWithWrapMode is RenderLogicalLinesFromBuffer that also reports
+// whether autowrap (DECAWM) is on after data. Rows are only joined while it is
+// on: with autowrap off every screen row is a line of its own, and wrap marks
+// left from before the program turned it off no longer describe the screen.
+func RenderLogicalLinesWithWrapMode(data []byte, rows, cols int) ([]LogicalLine, bool) {
+	grid, mode := renderGlyphGridWithMode(data, rows, cols)
+	autoWrap := mode&vt10x.ModeWrap != 0
+	if len(grid) == 0 {
+		return nil, autoWrap
+	}
+	return logicalLinesFromGrid(grid, autoWrap), autoWrap
+}
+
+// logicalLinesFromGrid joins soft-wrapped rows when autoWrap is set and keeps
+// every row separate otherwise.
+func logicalLinesFromGrid(grid [][]vt10x.Glyph, autoWrap bool) []LogicalLine {
+	lines := make([]LogicalLine, 0, len(grid))
+	runes := make([]rune, 0)
+	cells := make([]LineCell, 0)
+
+	flush := func() {
+		// 去除行尾空白，避免影响后续匹配
+		end := len(runes)
+		for end > 0 && runes[end-1] == ' ' {
+			end--
+		}
+		lines = append(lines, LogicalLine{
+			Text:  string(runes[:end]),
+			Cells: append([]LineCell(nil), cells[:end]...),
+		})
+		runes = runes[:0]
+		cells = cells[:0]
+	}
+
+	for row, glyphs := range grid {
+		wrapped := false
+		for col, cell := range glyphs {
+			if autoWrap && cell.Mode&vt10x.AttrWrap != 0 {
+				wrapped = true
+			}
+			if cell.Char == 0 {
+				continue
+			}
+			runes = append(runes, cell.Char)
+			cells = append(cells, LineCell{Row: row, Col: col})
+		}
+		if !wrapped {
+			flush()
+		}
+	}
+	if len(runes) > 0 {
+		flush()
+	}
+	return lines
+}
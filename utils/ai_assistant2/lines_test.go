@@ -0,0 +1,147 @@
+package ai_assistant2
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tuzig/vt10x"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+func renderedText(data string) []string {
+	return renderedTextAt(data, 24, 80)
+}
+
+func renderedTextAt(data string, rows, cols int) []string {
+	lines := RenderLogicalLinesFromBuffer([]byte(data), rows, cols)
+	texts := make([]string, 0, len(lines))
+	for _, line := range lines {
+		if line.Text != "" {
+			texts = append(texts, line.Text)
+		}
+	}
+	return texts
+}
+
+// 渲染共用 vt10x 终端池，上一次渲染留下的备用屏幕、滚动区、未完成序列不能影响下一次
+func TestRenderDoesNotInheritPooledTerminalState(t *testing.T) {
+	cases := []struct {
+		name    string
+		residue string
+		// residueCols 非 0 时残留内容按 10 行该列数渲染，模拟不同尺寸的会话
+		residueCols int
+		data        string
+		want        []string
+	}{
+		{
+			name:        "narrower terminal",
+			residue:     strings.Repeat("x", 120) + "\r\n" + strings.Repeat("y", 40),
+			residueCols: 40,
+			data:        "new",
+			want:        []string{"new"},
+		},
+		{
+			name:    "alternate screen",
+			residue: "\x1b[?1049hALT-RESIDUE",
+			data:    "\x1b[?1049hfresh",
+			want:    []string{"fresh"},
+		},
+		{
+			name:    "unterminated osc",
+			residue: "old\x1b]0;half a title",
+			data:    "new",
+			want:    []string{"new"},
+		},
+		{
+			name:    "scroll region",
+			residue: "\x1b[3;5r",
+			data:    strings.Repeat("row\r\n", 30) + "last",
+			want:    append(slices.Repeat([]string{"row"}, 23), "last"),
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				if tc.residueCols > 0 {
+					renderedTextAt(tc.residue, 10, tc.residueCols)
+				} else {
+					renderedText(tc.residue)
+				}
+				got := renderedText(tc.data)
+				if strings.Join(got, "\n") != strings.Join(tc.want, "\n") {
+					t.Fatalf("unexpected lines %q", got)
+				}
+			}
+		})
+	}
+}
+
+func TestRenderConcurrentSessionsDoNotMix(t *testing.T) {
+	const workers = 16
+	const rounds = 200
+
+	var wg sync.WaitGroup
+	errs := make(chan string, workers)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			token := fmt.Sprintf("session-%02d", w)
+			// 每个会话的输出都带有会残留状态的序列
+			data := fmt.Sprintf("\x1b[?1049h\x1b[2;6r\x1b[4%dm%s\r\n%s done\x1b]0;%s", w%8, token, token, token)
+			for i := 0; i < rounds; i++ {
+				for _, line := range renderedText(data) {
+					if !strings.Contains(line, token) {
+						errs <- fmt.Sprintf("%s rendered foreign line %q", token, line)
+						return
+					}
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// linesDetector 记录检测器收到的每一行
+type linesDetector struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (d *linesDetector) DetectStateFromLines(lines []string, raw [][]vt10x.Glyph, cols int, timestamp time.Time, currentState types.State, lastDetectedAt time.Time, cursorX int, cursorY int) (types.State, bool) {
+	d.mu.Lock()
+	d.lines = append(d.lines, lines...)
+	d.mu.Unlock()
+	return currentState, false
+}
+
+func (d *linesDetector) GetRecentInput() string { return "" }
+
+// 检测器的输入都来自渲染出的屏幕行，CRLF 输出在这里已经是干净的行
+func TestDetectorLinesHaveNoCarriageReturns(t *testing.T) {
+	detector := &linesDetector{}
+	ReplayChunks(detector, [][]byte{
+		[]byte("first\r\n"),
+		[]byte("second\r\n\r\n> "),
+	}, time.Second)
+	if len(detector.lines) == 0 {
+		t.Fatalf("expected the detector to see rendered lines")
+	}
+	for _, line := range detector.lines {
+		if strings.Contains(line, "\r") {
+			t.Fatalf("detector saw a carriage return in %q", line)
+		}
+	}
+	if !slices.ContainsFunc(detector.lines, func(line string) bool { return strings.TrimSpace(line) == "second" }) {
+		t.Fatalf("expected the rendered output to reach the detector")
+	}
+}
@@ -0,0 +1,219 @@
+package ai_assistant2
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// 回放默认的终端尺寸，与录制尺寸无关的 fixture 使用
+const (
+	DefaultReplayRows = 40
+	DefaultReplayCols = 120
+)
+
+// replayIdleTail 最后一个 chunk 之后继续模拟的空闲时间，让依赖稳定时长的状态切换得以发生
+const replayIdleTail = 2 * time.Second
+
+// StateTransition is a state change observed while replaying recorded output.
+type StateTransition struct {
+	// Chunk 触发切换时最后写入的 chunk 下标，第一个 chunk 之前为 -1
+	Chunk  int           `json:"chunk"`
+	Offset time.Duration `json:"offset"` // 相对回放开始的模拟时间
+	Source string        `json:"source"` // DetectionSourceChunk 或 DetectionSourcePeriodic
+	From   types.State   `json:"from"`
+	To     types.State   `json:"to"`
+	// Confidence 检测器对新状态的置信度，便于在 fixture 中发现勉强判定的切换
+	Confidence types.Confidence `json:"confidence,omitempty"`
+}
+
+// ReplayChunk is recorded output with the time it arrived relative to the start.
+type ReplayChunk struct {
+	Offset time.Duration
+	Data   []byte
+}
+
+// CastRecording is the output of an asciinema recording.
+type CastRecording struct {
+	Rows   int
+	Cols   int
+	Chunks []ReplayChunk
+}
+
+// ReplayChunks feeds chunks to detector one interval apart on a
+// DefaultReplayRows x DefaultReplayCols virtual terminal and returns the state
+// transitions the tracker would report. Time is simulated, so the replay runs
+// as fast as the detector allows and always produces the same result.
+func ReplayChunks(detector types.StatusDetector, chunks [][]byte, interval time.Duration) []StateTransition {
+	timed := make([]ReplayChunk, len(chunks))
+	for i, data := range chunks {
+		timed[i] = ReplayChunk{Offset: time.Duration(i) * interval, Data: data}
+	}
+	return ReplayTimedChunks(detector, timed, DefaultReplayRows, DefaultReplayCols)
+}
+
+// ReplayCast replays an asciinema recording with its original timing and size.
+func ReplayCast(detector types.StatusDetector, cast *CastRecording) []StateTransition {
+	if cast == nil {
+		return make([]StateTransition, 0)
+	}
+	return ReplayTimedChunks(detector, cast.Chunks, cast.Rows, cast.Cols)
+}
+
+// ReplayTimedChunks replays chunks at their offsets the same way StatusTracker
+// processes live output: detection is throttled to minProcessInterval, and
+// periodic checks run while no output arrives for periodicCheckInterval.
+func ReplayTimedChunks(detector types.StatusDetector, chunks []ReplayChunk, rows, cols int) []StateTransition {
+	transitions := make([]StateTransition, 0)
+	if detector == nil || rows <= 0 || cols <= 0 {
+		return transitions
+	}
+
+	// 固定起点，保证 fixture 每次回放结果一致
+	start := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	t := &StatusTracker{
+		active:          true,
+		lastState:       types.StateWaitingInput,
+		lastChangedAt:   start,
+		recentUpdatedAt: start,
+		lastProcessTime: start,
+		rows:            rows,
+		cols:            cols,
+		trackingMode:    TrackingModeVirtualTerminal,
+		detector:        detector,
+	}
+	t.ensureEmulatorSizeLocked(cols, rows)
+
+	chunkIndex := -1
+	detect := func(now time.Time, source string) {
+		lines, raw := getVisibleLinesLocked(t)
+		if len(lines) == 0 {
+			return
+		}
+		prevState := t.lastState
+		state, _, changed := t.detectStateFromLinesLocked(lines, raw, now, t.emulator.Cursor(), source)
+		if changed {
+			transitions = append(transitions, StateTransition{
+				Chunk:      chunkIndex,
+				Offset:     now.Sub(start),
+				Source:     source,
+				From:       prevState,
+				To:         state,
+				Confidence: t.stateConfidence,
+			})
+		}
+	}
+	idleUntil := func(until time.Time) {
+		for tick := t.lastProcessTime.Add(periodicCheckInterval); tick.Before(until); tick = tick.Add(periodicCheckInterval) {
+			detect(tick, DetectionSourcePeriodic)
+		}
+	}
+
+	for i, chunk := range chunks {
+		now := start.Add(chunk.Offset)
+		idleUntil(now)
+		if len(chunk.Data) == 0 {
+			continue
+		}
+		chunkIndex = i
+		t.emulator.Write(chunk.Data)
+		if now.Sub(t.lastProcessTime) < minProcessInterval {
+			continue
+		}
+		t.lastProcessTime = now
+		detect(now, DetectionSourceChunk)
+	}
+	idleUntil(t.lastProcessTime.Add(replayIdleTail + time.Nanosecond))
+	return transitions
+}
+
+// castHeader covers the header fields of asciicast v2 and v3.
+type castHeader struct {
+	Version int `json:"version"`
+	Width   int `json:"width"`
+	Height  int `json:"height"`
+	Term    struct {
+		Cols int `json:"cols"`
+		Rows int `json:"rows"`
+	} `json:"term"`
+}
+
+// LoadCastFile reads an asciinema recording from path, see LoadCast.
+func LoadCastFile(path string) (*CastRecording, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return LoadCast(file)
+}
+
+// LoadCast parses an asciicast v2 or v3 recording and keeps its output ("o")
+// events; input, marker and resize events are skipped.
+func LoadCast(r io.Reader) (*CastRecording, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	if !scanner.Scan() {
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, errors.New("cast: missing header")
+	}
+
+	var header castHeader
+	if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+		return nil, fmt.Errorf("cast: invalid header: %w", err)
+	}
+	cast := &CastRecording{Cols: header.Width, Rows: header.Height}
+	switch header.Version {
+	case 2:
+	case 3:
+		cast.Cols, cast.Rows = header.Term.Cols, header.Term.Rows
+	default:
+		return nil, fmt.Errorf("cast: unsupported version %d", header.Version)
+	}
+	if cast.Cols <= 0 || cast.Rows <= 0 {
+		return nil, errors.New("cast: header has no terminal size")
+	}
+
+	var elapsed float64
+	for line := 2; scanner.Scan(); line++ {
+		raw := scanner.Bytes()
+		// v3 允许以 # 开头的注释行
+		if len(raw) == 0 || raw[0] == '#' {
+			continue
+		}
+		var event []json.RawMessage
+		if err := json.Unmarshal(raw, &event); err != nil || len(event) < 3 {
+			return nil, fmt.Errorf("cast: line %d is not an event", line)
+		}
+		var at float64
+		var code, data string
+		if json.Unmarshal(event[0], &at) != nil || json.Unmarshal(event[1], &code) != nil || json.Unmarshal(event[2], &data) != nil {
+			return nil, fmt.Errorf("cast: line %d is not an event", line)
+		}
+		// v2 记录绝对时间，v3 记录与上一个事件的间隔
+		if header.Version == 3 {
+			elapsed += at
+		} else {
+			elapsed = at
+		}
+		if code != "o" {
+			continue
+		}
+		cast.Chunks = append(cast.Chunks, ReplayChunk{
+			Offset: time.Duration(elapsed * float64(time.Second)),
+			Data:   []byte(data),
+		})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return cast, nil
+}
@@ -0,0 +1,112 @@
+package ai_assistant2
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tuzig/vt10x"
+
+	"code-kanban/utils/ai_assistant2/types"
+)
+
+// busyDetector 屏幕上出现 busy 时判定为工作中，busy 消失且超过 1 秒未再确认后回到等待输入
+type busyDetector struct{}
+
+func (busyDetector) DetectStateFromLines(lines []string, raw [][]vt10x.Glyph, cols int, timestamp time.Time, currentState types.State, lastDetectedAt time.Time, cursorX int, cursorY int) (types.State, bool) {
+	for _, line := range lines {
+		if strings.Contains(line, "busy") {
+			return types.StateWorking, true
+		}
+	}
+	if currentState == types.StateWorking && timestamp.Sub(lastDetectedAt) >= time.Second {
+		return types.StateWaitingInput, false
+	}
+	return currentState, false
+}
+
+func (busyDetector) GetRecentInput() string { return "" }
+
+func TestReplayChunks(t *testing.T) {
+	chunks := [][]byte{
+		[]byte("hello\r\n"),
+		[]byte("busy\r\n"),
+		[]byte("\x1b[2J\x1b[Hdone\r\n"),
+	}
+	got := ReplayChunks(busyDetector{}, chunks, 200*time.Millisecond)
+	// 第一个 chunk 与回放起点重合被节流；busy 消失后由周期检测切回等待输入
+	want := []StateTransition{
+		{Chunk: 1, Offset: 200 * time.Millisecond, Source: DetectionSourceChunk, From: types.StateWaitingInput, To: types.StateWorking},
+		{Chunk: 2, Offset: 1400 * time.Millisecond, Source: DetectionSourcePeriodic, From: types.StateWorking, To: types.StateWaitingInput},
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("unexpected transitions\ngot  %+v\nwant %+v", got, want)
+	}
+	if again := ReplayChunks(busyDetector{}, chunks, 200*time.Millisecond); !slices.Equal(again, got) {
+		t.Fatalf("replay is not deterministic: %+v", again)
+	}
+
+	if got := ReplayTimedChunks(nil, nil, 10, 10); got == nil || len(got) != 0 {
+		t.Fatalf("expected an empty result without a detector, got %#v", got)
+	}
+	if got := ReplayCast(busyDetector{}, nil); got == nil || len(got) != 0 {
+		t.Fatalf("expected an empty result without a recording, got %#v", got)
+	}
+}
+
+func TestLoadCast(t *testing.T) {
+	v2 := `{"version": 2, "width": 80, "height": 24}
+[0.5, "o", "a"]
+[0.7, "i", "typed"]
+[1.25, "o", "b\r\n"]
+`
+	cast, err := LoadCast(strings.NewReader(v2))
+	if err != nil {
+		t.Fatalf("LoadCast v2: %v", err)
+	}
+	want := []ReplayChunk{
+		{Offset: 500 * time.Millisecond, Data: []byte("a")},
+		{Offset: 1250 * time.Millisecond, Data: []byte("b\r\n")},
+	}
+	if cast.Cols != 80 || cast.Rows != 24 || !slices.EqualFunc(cast.Chunks, want, equalReplayChunk) {
+		t.Fatalf("unexpected v2 recording %+v", cast)
+	}
+
+	// v3 记录与上一个事件的间隔，允许注释行
+	v3 := `{"version": 3, "term": {"cols": 100, "rows": 30}}
+# comment
+[0.5, "o", "a"]
+[0.25, "m", ""]
+[0.5, "o", "b"]
+`
+	cast, err = LoadCast(strings.NewReader(v3))
+	if err != nil {
+		t.Fatalf("LoadCast v3: %v", err)
+	}
+	want = []ReplayChunk{
+		{Offset: 500 * time.Millisecond, Data: []byte("a")},
+		{Offset: 1250 * time.Millisecond, Data: []byte("b")},
+	}
+	if cast.Cols != 100 || cast.Rows != 30 || !slices.EqualFunc(cast.Chunks, want, equalReplayChunk) {
+		t.Fatalf("unexpected v3 recording %+v", cast)
+	}
+
+	invalid := map[string]string{
+		"empty":       "",
+		"bad header":  "not json\n",
+		"version":     `{"version": 1, "width": 80, "height": 24}` + "\n",
+		"no size":     `{"version": 2}` + "\n",
+		"bad event":   `{"version": 2, "width": 80, "height": 24}` + "\n[0.5, \"o\"]\n",
+		"event types": `{"version": 2, "width": 80, "height": 24}` + "\n[\"x\", \"o\", \"a\"]\n",
+	}
+	for name, input := range invalid {
+		if _, err := LoadCast(strings.NewReader(input)); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func equalReplayChunk(a, b ReplayChunk) bool {
+	return a.Offset == b.Offset && string(a.Data) == string(b.Data)
+}
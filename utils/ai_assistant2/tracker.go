@@ -43,6 +43,11 @@ type StateChangeEvent struct {
 	PreviousState types.State
 	Timestamp     time.Time
 	RecentInput   string
+	// Confidence/Match 新状态的置信度与命中规则，检测器未提供时为空
+	Confidence types.Confidence
+	Match      string
+	// Interrupted 本轮被用户打断（ESC）而不是正常完成，仅对离开 working 的事件有意义
+	Interrupted bool
 }
 
 // StateChangeCallback is called when state changes are detected
@@ -57,6 +62,14 @@ type StatusTracker struct {
 	lastChangedAt   time.Time // Time when state changed to a different state
 	recentUpdatedAt time.Time // Time when the same state was last detected (updated every chunk)
 	lastProcessTime time.Time // Time when ProcessChunk was last called
+	// stateConfidence/stateMatch 最近一次确认当前状态时检测器给出的置信度与规则
+	stateConfidence types.Confidence
+	stateMatch      string
+	// calibrated 状态被进程信息校准过，屏幕没有新输出前不再做定时检测，
+	// 避免冻结的工作提示把状态改回 working
+	calibrated bool
+	// paused 用户手动接管时暂停检测，状态保持暂停前的值
+	paused bool
 
 	// Virtual terminal emulator for display simulation
 	emulator     vt10x.Terminal
@@ -69,6 +82,27 @@ type StatusTracker struct {
 
 	// Status detector for the current assistant
 	detector types.StatusDetector
+	// params 检测节奏与稳定时间，零值字段使用默认值
+	params DetectionParams
+	// todos 最近一次在屏幕上看到的任务清单，清单滚出屏幕后保留
+	todos []types.TodoItem
+	// workDuration 最近一轮完成时助手显示的耗时，新一轮开始工作时清空
+	workDuration time.Duration
+	// interrupted 最近一轮是否被用户打断，新一轮开始工作时清空
+	interrupted bool
+	// turns/interruptedTurns 本次运行中 working→waiting_input 完整循环的次数，被打断的轮次单独计数
+	turns            int
+	interruptedTurns int
+	// contextLeft 最近一次在屏幕上看到的上下文余量百分比，contextLeftSet 为 false 表示未知
+	contextLeft    int
+	contextLeftSet bool
+	// tokenRuns 之前几次助手运行的 token 用量；tokenCurrent 为当前运行最近一次解析的累计值，
+	// 助手退出或切换时并入 tokenRuns，因此 reset 不会清空 token 统计
+	tokenRuns       []types.TokenUsage
+	tokenCurrent    types.TokenUsage
+	tokenCurrentSet bool
+	// tokenModel 当前运行的模型，来自启动横幅
+	tokenModel string
 
 	// Cached glyph grid reused across detections
 	raw     [][]vt10x.Glyph
@@ -79,6 +113,12 @@ type StatusTracker struct {
 	checkCtx    context.Context
 	checkCancel context.CancelFunc
 	callback    StateChangeCallback
+
+	// 诊断模式：记录每次判定的输入与结果，默认关闭
+	diagnostics bool
+	diagRecords []DetectionRecord
+	diagNext    int
+	diagHook    DiagnosticsHook
 }
 
 // NewStatusTracker creates a new status tracker
@@ -150,6 +190,7 @@ func (t *StatusTracker) Activate(assistantType types.AssistantType, rows, cols i
 	}
 
 	// Create new emulator and detector for this assistant
+	t.foldTokenUsageLocked()
 	t.assistantType = assistantType
 	t.active = true
 	t.rows = rows
@@ -165,7 +206,8 @@ func (t *StatusTracker) Activate(assistantType types.AssistantType, rows, cols i
 		t.rawCols = 0
 		t.rawRows = 0
 	}
-	t.detector = createDetector(assistantType)
+	t.detector = NewDetector(assistantType)
+	t.applyWorkingExitLocked()
 
 	// Initialize state and timestamps
 	now := time.Now()
@@ -186,8 +228,9 @@ func (t *StatusTracker) Activate(assistantType types.AssistantType, rows, cols i
 	t.startPeriodicCheckLocked()
 }
 
-// createDetector creates a status detector for the given assistant type
-func createDetector(assistantType types.AssistantType) types.StatusDetector {
+// NewDetector creates the status detector the tracker uses for the given
+// assistant type, nil when the assistant has no detector yet.
+func NewDetector(assistantType types.AssistantType) types.StatusDetector {
 	switch assistantType {
 	case types.AssistantTypeClaudeCode:
 		return claude_code.NewStatusDetector()
@@ -236,9 +279,13 @@ func (t *StatusTracker) ProcessChunk(chunk []byte) (types.State, time.Time, bool
 		return types.StateUnknown, time.Time{}, false
 	}
 	t.emulator.Write(chunk)
+	t.calibrated = false
+	if t.paused {
+		return types.StateUnknown, time.Time{}, false
+	}
 
 	// 节流，但必须确保写入chunk
-	if !t.lastProcessTime.IsZero() && now.Sub(t.lastProcessTime) < minProcessInterval {
+	if !t.lastProcessTime.IsZero() && now.Sub(t.lastProcessTime) < t.processIntervalLocked() {
 		return types.StateUnknown, time.Time{}, false
 	}
 
@@ -250,36 +297,99 @@ func (t *StatusTracker) ProcessChunk(chunk []byte) (types.State, time.Time, bool
 	}
 
 	prevState := t.lastState
-	state, ts, changed := t.detectStateFromLinesLocked(lines, raw, now, t.emulator.Cursor())
+	state, ts, changed := t.detectStateFromLinesLocked(lines, raw, now, t.emulator.Cursor(), DetectionSourceChunk)
 	if changed {
 		t.emitStateChangeLocked(StateChangeEvent{
 			State:         state,
 			PreviousState: prevState,
 			Timestamp:     ts,
 			RecentInput:   t.getRecentInputForTransitionLocked(prevState, state),
+			Confidence:    t.stateConfidence,
+			Match:         t.stateMatch,
+			Interrupted:   t.interrupted,
 		})
 	}
 	return state, ts, changed
 }
 
-func (t *StatusTracker) detectStateFromLinesLocked(lines []string, raw [][]vt10x.Glyph, now time.Time, cursor vt10x.Cursor) (types.State, time.Time, bool) {
+func (t *StatusTracker) detectStateFromLinesLocked(lines []string, raw [][]vt10x.Glyph, now time.Time, cursor vt10x.Cursor, source string) (types.State, time.Time, bool) {
 	if t.detector == nil || len(lines) == 0 {
 		return types.StateUnknown, time.Time{}, false
 	}
 
+	if parser, ok := t.detector.(types.TodoParser); ok {
+		if todos := parser.ParseTodos(lines); len(todos) > 0 {
+			t.todos = todos
+		}
+	}
+	if parser, ok := t.detector.(types.WorkDurationParser); ok {
+		if duration, found := parser.ParseWorkDuration(lines); found {
+			t.workDuration = duration
+		}
+	}
+	if parser, ok := t.detector.(types.InterruptParser); ok {
+		t.interrupted = parser.ParseInterrupted(lines)
+	}
+	if parser, ok := t.detector.(types.ContextLeftParser); ok {
+		if percent, found := parser.ParseContextLeft(lines); found {
+			t.contextLeft = percent
+			t.contextLeftSet = true
+		}
+	}
+	if parser, ok := t.detector.(types.TokenUsageParser); ok {
+		if usage, found := parser.ParseTokenUsage(lines); found {
+			if usage.Model == "" {
+				usage.Model = t.tokenModel
+			}
+			t.tokenCurrent = usage
+			t.tokenCurrentSet = true
+		}
+	}
+
+	prevState := t.lastState
+	sinceLastDetected := now.Sub(t.recentUpdatedAt)
 	detectedState, changeRecentUpdate := t.detector.DetectStateFromLines(lines, raw, t.cols, now, t.lastState, t.recentUpdatedAt, cursor.X, cursor.Y)
+	if t.diagnostics {
+		defer func() {
+			record := DetectionRecord{
+				Time:                now,
+				Source:              source,
+				AssistantType:       t.assistantType,
+				Lines:               trimTrailingBlankLines(lines),
+				CursorX:             cursor.X,
+				CursorY:             cursor.Y,
+				DetectedState:       detectedState,
+				ActuallyDetected:    changeRecentUpdate,
+				PreviousState:       prevState,
+				State:               t.lastState,
+				Changed:             t.lastState != prevState,
+				SinceLastDetectedMs: sinceLastDetected.Milliseconds(),
+			}
+			record.Confidence, record.Match = detectionConfidence(t.detector)
+			t.recordDetectionLocked(record)
+		}()
+	}
 
 	if changeRecentUpdate {
 		t.recentUpdatedAt = now
 	}
+	// 防抖保留旧状态时不覆盖之前确认该状态的置信度
+	if detectedState != types.StateUnknown && (changeRecentUpdate || detectedState != t.lastState) {
+		t.stateConfidence, t.stateMatch = detectionConfidence(t.detector)
+	}
 
 	if detectedState == types.StateUnknown {
 		return types.StateUnknown, time.Time{}, false
 	}
 
 	if detectedState != t.lastState {
+		t.countTurnLocked(t.lastState, detectedState)
 		t.lastState = detectedState
 		t.lastChangedAt = now
+		if detectedState == types.StateWorking {
+			t.workDuration = 0
+			t.interrupted = false
+		}
 		return detectedState, now, true
 	}
 
@@ -293,6 +403,129 @@ func (t *StatusTracker) State() (types.State, time.Time) {
 	return t.lastState, t.lastChangedAt
 }
 
+// StateConfidence returns the confidence and the rule of the detection that last
+// confirmed the current state, both empty when the detector reports neither.
+func (t *StatusTracker) StateConfidence() (types.Confidence, string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.stateConfidence, t.stateMatch
+}
+
+func detectionConfidence(detector types.StatusDetector) (types.Confidence, string) {
+	var confidence types.Confidence
+	var match string
+	if reporter, ok := detector.(types.ConfidenceReporter); ok {
+		confidence = reporter.LastConfidence()
+	}
+	if reporter, ok := detector.(types.MatchReporter); ok {
+		match = reporter.LastMatch()
+	}
+	return confidence, match
+}
+
+// Todos returns a copy of the last todo list shown by the assistant.
+func (t *StatusTracker) Todos() []types.TodoItem {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.todos) == 0 {
+		return nil
+	}
+	return append([]types.TodoItem(nil), t.todos...)
+}
+
+// WorkDuration returns how long the last finished turn took as printed by the
+// assistant, 0 when unknown.
+func (t *StatusTracker) WorkDuration() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.workDuration
+}
+
+// Interrupted reports whether the last turn was cancelled by the user rather
+// than finished, as shown on screen. It is false while a new turn is running.
+func (t *StatusTracker) Interrupted() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.interrupted
+}
+
+// countTurnLocked counts a finished turn when the assistant goes from working
+// back to waiting for input.
+func (t *StatusTracker) countTurnLocked(prev, next types.State) {
+	if prev != types.StateWorking || next != types.StateWaitingInput {
+		return
+	}
+	if t.interrupted {
+		t.interruptedTurns++
+	} else {
+		t.turns++
+	}
+}
+
+// TurnCount returns how many turns the current assistant run finished and how
+// many of them the user interrupted, counted separately.
+func (t *StatusTracker) TurnCount() (turns, interrupted int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.turns, t.interruptedTurns
+}
+
+// ContextLeft returns the percentage of the context window left as last shown
+// by the assistant. ok is false when the assistant never showed it.
+func (t *StatusTracker) ContextLeft() (percent int, ok bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.contextLeft, t.contextLeftSet
+}
+
+// TokenUsage returns the token usage of every assistant run seen by the tracker,
+// merged per assistant and model.
+func (t *StatusTracker) TokenUsage() []types.TokenUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	runs := t.tokenRuns
+	if t.tokenCurrentSet {
+		runs = mergeTokenUsage(append([]types.TokenUsage(nil), runs...), t.tokenCurrent)
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+	return append([]types.TokenUsage(nil), runs...)
+}
+
+// SetTokenModel records the model of the current run so its token usage can be
+// priced per model.
+func (t *StatusTracker) SetTokenModel(model string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.tokenModel = model
+	if t.tokenCurrentSet && t.tokenCurrent.Model == "" {
+		t.tokenCurrent.Model = model
+	}
+}
+
+// foldTokenUsageLocked moves the usage of the current run into tokenRuns.
+func (t *StatusTracker) foldTokenUsageLocked() {
+	t.tokenModel = ""
+	if !t.tokenCurrentSet {
+		return
+	}
+	t.tokenRuns = mergeTokenUsage(t.tokenRuns, t.tokenCurrent)
+	t.tokenCurrent = types.TokenUsage{}
+	t.tokenCurrentSet = false
+}
+
+func mergeTokenUsage(runs []types.TokenUsage, usage types.TokenUsage) []types.TokenUsage {
+	for i := range runs {
+		if runs[i].Assistant == usage.Assistant && runs[i].Model == usage.Model {
+			runs[i].InputTokens += usage.InputTokens
+			runs[i].OutputTokens += usage.OutputTokens
+			return runs
+		}
+	}
+	return append(runs, usage)
+}
+
 // ChunkCount returns how many chunks have been processed.
 func (t *StatusTracker) ChunkCount() int64 {
 	t.mu.Lock()
@@ -314,6 +547,51 @@ func (t *StatusTracker) AssistantType() types.AssistantType {
 	return t.assistantType
 }
 
+// ApprovalInput returns the keys that approve or reject the approval prompt
+// shown in lines. ok is false unless the tracker is waiting for approval and
+// its detector recognizes the prompt.
+func (t *StatusTracker) ApprovalInput(lines []string, approve bool) ([]string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.active || t.detector == nil || t.lastState != types.StateWaitingApproval {
+		return nil, false
+	}
+	responder, ok := t.detector.(types.ApprovalResponder)
+	if !ok {
+		return nil, false
+	}
+	return responder.ApprovalInput(lines, approve)
+}
+
+// ApprovalPromptID identifies the approval prompt shown in lines. It returns ""
+// unless the tracker is waiting for approval and its detector recognizes the
+// prompt.
+func (t *StatusTracker) ApprovalPromptID(lines []string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.active || t.detector == nil || t.lastState != types.StateWaitingApproval {
+		return ""
+	}
+	responder, ok := t.detector.(types.ApprovalResponder)
+	if !ok {
+		return ""
+	}
+	return responder.ApprovalPromptID(lines)
+}
+
+// ScreenLines returns the lines of the emulated screen the detector reads, nil
+// before the tracker has seen any output.
+func (t *StatusTracker) ScreenLines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.active || t.emulator == nil {
+		return nil
+	}
+	t.ensureEmulatorSizeLocked(t.cols, t.rows)
+	lines, _ := getVisibleLinesLocked(t)
+	return lines
+}
+
 // startPeriodicCheckLocked starts a goroutine that periodically checks state
 // Must be called with lock held
 func (t *StatusTracker) startPeriodicCheckLocked() {
@@ -325,7 +603,7 @@ func (t *StatusTracker) startPeriodicCheckLocked() {
 	t.checkCtx = ctx
 	t.checkCancel = cancel
 
-	go t.periodicCheckLoop(ctx)
+	go t.periodicCheckLoop(ctx, t.idleCheckIntervalLocked())
 }
 
 // stopPeriodicCheckLocked stops the periodic check goroutine
@@ -339,8 +617,8 @@ func (t *StatusTracker) stopPeriodicCheckLocked() {
 }
 
 // periodicCheckLoop runs in a goroutine and checks state periodically
-func (t *StatusTracker) periodicCheckLoop(ctx context.Context) {
-	ticker := time.NewTicker(periodicCheckInterval)
+func (t *StatusTracker) periodicCheckLoop(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -358,37 +636,47 @@ func (t *StatusTracker) checkStateIfIdle() {
 	t.mu.Lock()
 	defer t.mu.Unlock()
 
-	if !t.active || t.detector == nil {
+	if !t.active || t.detector == nil || t.calibrated || t.paused {
 		return
 	}
 
 	now := time.Now()
 
-	// Only check if ProcessChunk hasn't been called for periodicCheckInterval
-	if now.Sub(t.lastProcessTime) < periodicCheckInterval {
+	// Only check if ProcessChunk hasn't been called for the idle check interval
+	if now.Sub(t.lastProcessTime) < t.idleCheckIntervalLocked() {
 		return
 	}
 
+	t.detectScreenLocked(now, DetectionSourcePeriodic)
+}
+
+// detectScreenLocked runs the detector on the current emulator screen and emits
+// the change, if any.
+func (t *StatusTracker) detectScreenLocked(now time.Time, source string) bool {
 	if t.emulator == nil {
-		return
+		return false
 	}
 	t.ensureEmulatorSizeLocked(t.cols, t.rows)
 	lines, raw := getVisibleLinesLocked(t)
 
 	if len(lines) == 0 {
-		return
+		return false
 	}
 
 	prevState := t.lastState
-	state, ts, changed := t.detectStateFromLinesLocked(lines, raw, now, t.emulator.Cursor())
+	state, ts, changed := t.detectStateFromLinesLocked(lines, raw, now, t.emulator.Cursor(), source)
 	if changed {
 		t.emitStateChangeLocked(StateChangeEvent{
 			State:         state,
 			PreviousState: prevState,
 			Timestamp:     ts,
 			RecentInput:   t.getRecentInputForTransitionLocked(prevState, state),
+			Confidence:    t.stateConfidence,
+			Match:         t.stateMatch,
+			Interrupted:   t.interrupted,
 		})
 	}
+	return changed
 }
 
 // ensureEmulatorSizeLocked lazily creates or resizes the vt10x emulator to match the current terminal size.
@@ -416,6 +704,18 @@ func (t *StatusTracker) ensureEmulatorSizeLocked(cols, rows int) {
 	}
 }
 
+// ResetStability drops the debounce, throttle and calibration state carried
+// over from the previous turn. It is called when the user submits a new prompt
+// so leftovers of the last round do not delay detecting the new one. The
+// current state and the emulated screen are kept.
+func (t *StatusTracker) ResetStability() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.recentUpdatedAt = time.Time{}
+	t.lastProcessTime = time.Time{}
+	t.calibrated = false
+}
+
 func (t *StatusTracker) resetLocked() {
 	t.stopPeriodicCheckLocked()
 	t.active = false
@@ -424,8 +724,20 @@ func (t *StatusTracker) resetLocked() {
 	t.lastChangedAt = time.Time{}
 	t.recentUpdatedAt = time.Time{}
 	t.lastProcessTime = time.Time{}
+	t.stateConfidence = ""
+	t.stateMatch = ""
+	t.calibrated = false
+	t.paused = false
 	t.emulator = nil
 	t.detector = nil
+	t.todos = nil
+	t.workDuration = 0
+	t.interrupted = false
+	t.turns = 0
+	t.interruptedTurns = 0
+	t.contextLeft = 0
+	t.contextLeftSet = false
+	t.foldTokenUsageLocked()
 	t.rows = 0
 	t.cols = 0
 	t.captureBusy = false
@@ -0,0 +1,43 @@
+package ai_assistant2
+
+import "time"
+
+// Pause suspends state detection while the user operates the terminal by hand.
+// Output keeps feeding the emulator so the screen stays in sync, but neither
+// chunks nor periodic checks, rechecks or calibrations change the state, which
+// stays at the last detected one. It reports whether tracking was running.
+func (t *StatusTracker) Pause() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.active || t.paused {
+		return false
+	}
+	t.paused = true
+	return true
+}
+
+// Resume restarts state detection after Pause. The debounce, throttle and
+// calibration state are dropped so the screen is judged afresh instead of
+// against what was seen before the pause. It reports whether tracking was
+// paused.
+func (t *StatusTracker) Resume() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if !t.paused {
+		return false
+	}
+	t.paused = false
+	t.recentUpdatedAt = time.Time{}
+	t.lastProcessTime = time.Time{}
+	t.calibrated = false
+	return true
+}
+
+// Paused reports whether state detection is paused.
+func (t *StatusTracker) Paused() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.paused
+}
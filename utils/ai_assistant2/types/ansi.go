@@ -7,8 +7,9 @@ import (
 
 // ANSI escape sequence patterns
 var (
-	// Matches ANSI escape sequences including CSI, OSC, and simple escapes
-	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]|\x1b\][^\x07]*\x07|\x1b[>=\[\]]|\x1b[()][AB012]`)
+	// Matches ANSI escape sequences including CSI, OSC, and simple escapes.
+	// OSC may end with BEL or ST (ESC \), OSC 8 hyperlinks usually use the latter.
+	ansiPattern = regexp.MustCompile(`\x1b\[[0-9;]*[a-zA-Z]|\x1b\][^\x07\x1b]*(?:\x07|\x1b\\)|\x1b[>=\[\]]|\x1b[()][AB012]`)
 
 	// Matches control characters (except newline, tab, and carriage return)
 	controlCharsPattern = regexp.MustCompile(`[\x00-\x08\x0B-\x0C\x0E-\x1F\x7F]`)
@@ -40,6 +41,9 @@ func handleCarriageReturns(text string) string {
 
 	lines := strings.Split(text, "\n")
 	for i, line := range lines {
+		// \r\n 的 \r 只是换行的一部分，不能当作覆盖整行
+		line = strings.TrimRight(line, "\r")
+		lines[i] = line
 		if strings.Contains(line, "\r") {
 			// Split by \r and keep only the last segment (simulating overwrite)
 			segments := strings.Split(line, "\r")
@@ -50,6 +54,28 @@ func handleCarriageReturns(text string) string {
 	return strings.Join(lines, "\n")
 }
 
+// NormalizeLineEndings strips trailing carriage returns left by CRLF output
+// so detectors see the same lines on Windows as on Unix. The input slice is
+// returned as is when no line needs changing.
+func NormalizeLineEndings(lines []string) []string {
+	var out []string
+	for i, line := range lines {
+		trimmed := strings.TrimRight(line, "\r")
+		if out == nil {
+			if len(trimmed) == len(line) {
+				continue
+			}
+			out = make([]string, len(lines))
+			copy(out, lines[:i])
+		}
+		out[i] = trimmed
+	}
+	if out == nil {
+		return lines
+	}
+	return out
+}
+
 // CleanLine removes ANSI sequences and trims whitespace from a single line
 func CleanLine(line string) string {
 	cleaned := StripANSI(line)
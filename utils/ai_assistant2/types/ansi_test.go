@@ -0,0 +1,22 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripANSIKeepsCRLFLines(t *testing.T) {
+	if got := StripANSI("\x1b[1mabc\x1b[0m\r\ndef\r\nold\rnew\r\n"); got != "abc\ndef\nnew\n" {
+		t.Fatalf("unexpected stripped text %q", got)
+	}
+}
+
+func TestNormalizeLineEndings(t *testing.T) {
+	lines := []string{"a", "b"}
+	if got := NormalizeLineEndings(lines); &got[0] != &lines[0] {
+		t.Fatalf("expected unchanged lines to be returned as is")
+	}
+	if got := NormalizeLineEndings([]string{"a\r", "b", "c\r\r"}); strings.Join(got, "|") != "a|b|c" {
+		t.Fatalf("unexpected normalized lines %q", got)
+	}
+}
@@ -0,0 +1,174 @@
+package types
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// 审批菜单中移动光标与确认、取消使用的按键
+const (
+	keyArrowUp   = "\x1b[A"
+	keyArrowDown = "\x1b[B"
+	keyEnter     = "\r"
+	keyEscape    = "\x1b"
+)
+
+// approvalPromptContextLines 计算审批提示标识时取菜单上方的非空行数，覆盖问题与待执行的命令
+const approvalPromptContextLines = 6
+
+// approvalOptionPattern 匹配审批菜单的编号选项，可选的光标标记在编号之前
+var approvalOptionPattern = regexp.MustCompile(`^\s*(\S\s+)?(\d+)\.\s+(.*)$`)
+
+// ApprovalOption is one numbered choice of an approval menu.
+type ApprovalOption struct {
+	Number   int
+	Text     string
+	Selected bool
+}
+
+// ParseApprovalMenu returns the options of the bottom-most numbered menu whose
+// current choice is marked with cursor, e.g. "❯ 1. Yes". Wrapped option text on
+// indented lines is skipped. It returns nil unless the options are numbered
+// 1..n without gaps, so an unrelated numbered list is never mistaken for a menu.
+func ParseApprovalMenu(lines []string, cursor string) []ApprovalOption {
+	start, end, ok := approvalMenuBounds(lines, cursor)
+	if !ok {
+		return nil
+	}
+	return parseApprovalOptions(lines[start:end+1], cursor)
+}
+
+// ApprovalPromptID identifies the approval prompt shown in lines: the option
+// texts of its menu and the non-blank lines just above it, which hold the
+// question and the command or file being approved. Moving the cursor keeps the
+// identity. It returns "" when no menu is recognized.
+func ApprovalPromptID(lines []string, cursor string) string {
+	start, end, ok := approvalMenuBounds(lines, cursor)
+	if !ok {
+		return ""
+	}
+	options := parseApprovalOptions(lines[start:end+1], cursor)
+	if len(options) == 0 {
+		return ""
+	}
+
+	var above []string
+	for i := start - 1; i >= 0 && len(above) < approvalPromptContextLines; i-- {
+		if line := strings.TrimSpace(lines[i]); line != "" {
+			above = append(above, line)
+		}
+	}
+	hash := sha256.New()
+	for i := len(above) - 1; i >= 0; i-- {
+		hash.Write([]byte(above[i]))
+		hash.Write([]byte{'\n'})
+	}
+	for _, option := range options {
+		fmt.Fprintf(hash, "%d. %s\n", option.Number, option.Text)
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// approvalMenuBounds locates the bottom-most menu whose current choice is
+// marked with cursor, including wrapped option lines.
+func approvalMenuBounds(lines []string, cursor string) (start, end int, ok bool) {
+	selected := -1
+	for i := len(lines) - 1; i >= 0; i-- {
+		if match := approvalOptionPattern.FindStringSubmatch(lines[i]); match != nil &&
+			strings.TrimSpace(match[1]) == cursor {
+			selected = i
+			break
+		}
+	}
+	if selected < 0 {
+		return 0, 0, false
+	}
+
+	start = selected
+	for start > 0 && isApprovalMenuLine(lines[start-1]) {
+		start--
+	}
+	end = selected
+	for end+1 < len(lines) && isApprovalMenuLine(lines[end+1]) {
+		end++
+	}
+	return start, end, true
+}
+
+func parseApprovalOptions(lines []string, cursor string) []ApprovalOption {
+	var options []ApprovalOption
+	for _, line := range lines {
+		match := approvalOptionPattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		marker := strings.TrimSpace(match[1])
+		if marker != "" && marker != cursor {
+			continue
+		}
+		number, err := strconv.Atoi(match[2])
+		if err != nil || number != len(options)+1 {
+			return nil
+		}
+		options = append(options, ApprovalOption{
+			Number:   number,
+			Text:     strings.TrimSpace(match[3]),
+			Selected: marker == cursor,
+		})
+	}
+	return options
+}
+
+// isApprovalMenuLine reports option lines and the indented continuation of a
+// wrapped option.
+func isApprovalMenuLine(line string) bool {
+	if strings.TrimSpace(line) == "" {
+		return false
+	}
+	return approvalOptionPattern.MatchString(line) || strings.HasPrefix(line, "   ")
+}
+
+// ApprovalMenuInput answers a parsed menu. Approving moves the cursor to the
+// first option starting with "Yes" and presses enter; rejecting presses Esc,
+// which the supported assistants treat as declining. ok is false when the menu
+// has no current choice or, when approving, no "Yes" option.
+func ApprovalMenuInput(options []ApprovalOption, approve bool) ([]string, bool) {
+	current, target := -1, -1
+	for i, option := range options {
+		if option.Selected {
+			current = i
+		}
+		if target < 0 && strings.HasPrefix(strings.ToLower(option.Text), "yes") {
+			target = i
+		}
+	}
+	if current < 0 {
+		return nil, false
+	}
+	if !approve {
+		return []string{keyEscape}, true
+	}
+	if target < 0 {
+		return nil, false
+	}
+
+	keys := make([]string, 0, abs(target-current)+1)
+	for i := current; i > target; i-- {
+		keys = append(keys, keyArrowUp)
+	}
+	for i := current; i < target; i++ {
+		keys = append(keys, keyArrowDown)
+	}
+	return append(keys, keyEnter), true
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
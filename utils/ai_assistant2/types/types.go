@@ -17,6 +17,16 @@ const (
 	AssistantTypeGemini     AssistantType = "gemini"
 )
 
+// AllAssistantTypes returns every known assistant type, excluding unknown
+func AllAssistantTypes() []AssistantType {
+	return []AssistantType{
+		AssistantTypeClaudeCode,
+		AssistantTypeCodex,
+		AssistantTypeQwenCode,
+		AssistantTypeGemini,
+	}
+}
+
 // State represents the current state of an AI assistant
 type State string
 
@@ -27,6 +37,14 @@ const (
 	StateWaitingInput    State = "waiting_input"    // Waiting for user input
 )
 
+// Confidence describes how certain a detector is about the state it reported
+type Confidence string
+
+const (
+	ConfidenceHigh Confidence = "high" // 命中了明确的界面特征
+	ConfidenceLow  Confidence = "low"  // 没有命中特征时的兜底判断，或防抖保留的旧状态
+)
+
 // AssistantInfo contains information about a detected AI assistant
 type AssistantInfo struct {
 	Type        AssistantType
@@ -45,6 +63,14 @@ type AIAssistantInfo struct {
 	Command        string    `json:"command,omitempty"`
 	State          string    `json:"state,omitempty"`
 	StateUpdatedAt time.Time `json:"stateUpdatedAt,omitempty"`
+	// StateConfidence/StateMatch 当前状态的置信度与命中的检测规则，检测器未提供时为空
+	StateConfidence string `json:"stateConfidence,omitempty"`
+	StateMatch      string `json:"stateMatch,omitempty"`
+	// Model/CliVersion 从启动横幅中提取，未识别时为空
+	Model      string `json:"model,omitempty"`
+	CliVersion string `json:"cliVersion,omitempty"`
+	// TrackingPaused 用户暂停了状态检测，State 为暂停前最后检测到的状态
+	TrackingPaused bool `json:"trackingPaused,omitempty"`
 }
 
 // String returns the string representation of the assistant type
@@ -78,6 +104,12 @@ func (t AssistantType) SupportsProgressTracking() bool {
 	}
 }
 
+// SupportsTokenUsage reports whether token usage statistics are collected for this assistant
+func (t AssistantType) SupportsTokenUsage() bool {
+	// 目前只有 Codex 会在屏幕上打印 token 用量
+	return t == AssistantTypeCodex
+}
+
 // StatusDetector is an interface for detecting AI assistant states from terminal output
 type StatusDetector interface {
 	// DetectStateFromLines analyzes multiple lines and returns the detected state.
@@ -93,3 +125,80 @@ type StatusDetector interface {
 
 	GetRecentInput() string
 }
+
+// TodoItem 是 AI 助手任务清单中的一项
+type TodoItem struct {
+	Text       string `json:"text"`
+	Done       bool   `json:"done"`
+	InProgress bool   `json:"inProgress,omitempty"`
+}
+
+// TodoParser is optionally implemented by a StatusDetector that can read the
+// assistant todo list from the visible lines. It returns nil when no list is shown.
+type TodoParser interface {
+	ParseTodos(lines []string) []TodoItem
+}
+
+// WorkDurationParser is optionally implemented by a StatusDetector whose assistant
+// prints how long the finished turn took. ok is false when no such line is shown.
+type WorkDurationParser interface {
+	ParseWorkDuration(lines []string) (time.Duration, bool)
+}
+
+// InterruptParser is optionally implemented by a StatusDetector that can tell a
+// turn the user cancelled (ESC) from one that finished normally. It only looks at
+// the visible lines and is meaningful while the assistant is waiting for input.
+type InterruptParser interface {
+	ParseInterrupted(lines []string) bool
+}
+
+// TokenUsage 是助手一次运行中累计消耗的 token 数，Model 未知时为空
+type TokenUsage struct {
+	Assistant    AssistantType `json:"assistant"`
+	Model        string        `json:"model,omitempty"`
+	InputTokens  int64         `json:"inputTokens"`
+	OutputTokens int64         `json:"outputTokens"`
+}
+
+// TokenUsageParser is optionally implemented by a StatusDetector whose assistant
+// prints its cumulative token usage. ok is false when no usage line is shown.
+type TokenUsageParser interface {
+	ParseTokenUsage(lines []string) (TokenUsage, bool)
+}
+
+// ContextLeftParser is optionally implemented by a StatusDetector whose assistant
+// shows how much of its context window is left. ok is false when no such
+// indicator is visible.
+type ContextLeftParser interface {
+	ParseContextLeft(lines []string) (percent int, ok bool)
+}
+
+// ApprovalResponder is optionally implemented by a StatusDetector that knows
+// how its assistant's approval prompt is answered. It returns the keys, each to
+// be written separately, that approve or reject the prompt shown in lines; ok
+// is false when no prompt it understands is shown. ApprovalPromptID identifies
+// the prompt shown in lines, "" when none is recognized, so an answer meant for
+// one prompt is never typed into the next.
+type ApprovalResponder interface {
+	ApprovalInput(lines []string, approve bool) (keys []string, ok bool)
+	ApprovalPromptID(lines []string) string
+}
+
+// WorkingExitConfigurer is optionally implemented by a StatusDetector that keeps
+// the working state for a while after the working indicator disappears. A
+// non-positive interval restores the detector's default.
+type WorkingExitConfigurer interface {
+	SetWorkingExitInterval(interval time.Duration)
+}
+
+// MatchReporter is optionally implemented by a StatusDetector to report which rule
+// decided the last detection, used by tracker diagnostics.
+type MatchReporter interface {
+	LastMatch() string
+}
+
+// ConfidenceReporter is optionally implemented by a StatusDetector to report how
+// certain the last detection was.
+type ConfidenceReporter interface {
+	LastConfidence() Confidence
+}
@@ -2,7 +2,11 @@ package utils
 
 import (
 	"fmt"
+	"maps"
+	"net/url"
 	"os"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/knadh/koanf/parsers/yaml"
@@ -25,12 +29,81 @@ type TerminalShellConfig struct {
 	Windows string `json:"windows" yaml:"windows"`
 	Linux   string `json:"linux" yaml:"linux"`
 	Darwin  string `json:"darwin" yaml:"darwin"`
+	// Named 可供创建会话时按名称选择的 shell，上面按平台配置的 shell 作为 "default"
+	Named []TerminalNamedShell `json:"named" yaml:"named"`
+}
+
+// TerminalNamedShell 命名 shell，Command 可带参数，如 "fish -l"
+type TerminalNamedShell struct {
+	Name    string `json:"name" yaml:"name"`
+	Command string `json:"command" yaml:"command"`
+}
+
+// TerminalOutputTrigger 终端输出匹配 Pattern（正则）时自动写入 Input
+type TerminalOutputTrigger struct {
+	Pattern         string `json:"pattern" yaml:"pattern"`
+	Input           string `json:"input" yaml:"input"`
+	CooldownSeconds int    `json:"cooldownSeconds" yaml:"cooldownSeconds"`
+}
+
+// TerminalInputMacro 按名称执行的一步输入，Assistant 非空时只在该类型的 AI 助手运行时可用
+type TerminalInputMacro struct {
+	Name      string `json:"name" yaml:"name"`
+	Input     string `json:"input" yaml:"input"`
+	Assistant string `json:"assistant" yaml:"assistant"`
+}
+
+// TerminalAIProfile AI 档案：创建会话时通过 profileId 选择，一次套用 shell、环境变量与启动命令
+type TerminalAIProfile struct {
+	ID           string            `json:"id" yaml:"id"`
+	Name         string            `json:"name" yaml:"name"`
+	Assistant    string            `json:"assistant" yaml:"assistant"`       // 助手类型，如 claude-code、codex，仅用于展示
+	Command      string            `json:"command" yaml:"command"`           // 启动 AI CLI 的命令，如 "claude --model opus"
+	Env          map[string]string `json:"env" yaml:"env"`                   // 附加的环境变量，如 ANTHROPIC_BASE_URL、OPENAI_API_KEY
+	InitCommands []string          `json:"initCommands" yaml:"initCommands"` // 在 command 之前依次执行的命令
+	ShellName    string            `json:"shellName" yaml:"shellName"`       // 使用的命名 shell，留空使用默认 shell
+}
+
+// TerminalTitleRule 输出中匹配 Pattern（正则）时用捕获组 Group 设置会话标题，手动改名后不再生效
+type TerminalTitleRule struct {
+	Pattern   string `json:"pattern" yaml:"pattern"`
+	Group     int    `json:"group" yaml:"group"`         // 0 表示第一个捕获组
+	Assistant string `json:"assistant" yaml:"assistant"` // 为空对所有会话生效
+}
+
+// TerminalHighlightRule 输出中命中 Keyword（字面）或 Pattern（正则）的范围标注为 Category，供前端着色
+type TerminalHighlightRule struct {
+	Keyword  string `json:"keyword" yaml:"keyword"`
+	Pattern  string `json:"pattern" yaml:"pattern"`
+	Category string `json:"category" yaml:"category"`
+}
+
+// TerminalOutputFilterRule 预览与导出时把输出行中匹配 Pattern（正则）的部分替换为 Replace，DropLine 删除整行；
+// 原始 scrollback 与实时输出不受影响
+type TerminalOutputFilterRule struct {
+	Name     string `json:"name" yaml:"name"`
+	Pattern  string `json:"pattern" yaml:"pattern"`
+	Replace  string `json:"replace" yaml:"replace"` // 为空表示删除命中部分，支持 $1 等捕获组引用
+	DropLine bool   `json:"dropLine" yaml:"dropLine"`
+}
+
+// TerminalAlertRule 输出中匹配 Pattern（正则）时生成告警通知，CooldownSeconds 内同一规则不重复告警
+type TerminalAlertRule struct {
+	Name            string `json:"name" yaml:"name"`
+	Pattern         string `json:"pattern" yaml:"pattern"`
+	CooldownSeconds int    `json:"cooldownSeconds" yaml:"cooldownSeconds"` // 0 表示默认 60 秒
 }
 
 type DeveloperConfig struct {
 	EnableTerminalScrollback      bool `json:"enableTerminalScrollback" yaml:"enableTerminalScrollback"`
 	RenameSessionTitleEachCommand bool `json:"renameSessionTitleEachCommand" yaml:"renameSessionTitleEachCommand"`
 	AutoCreateTaskOnStartWork     bool `json:"autoCreateTaskOnStartWork" yaml:"autoCreateTaskOnStartWork"`
+	DetectTerminalLinks           bool `json:"detectTerminalLinks" yaml:"detectTerminalLinks"`
+	ConfirmMultilineInput         bool `json:"confirmMultilineInput" yaml:"confirmMultilineInput"`
+	// AIDetectionDiagnostics 记录 AI 助手状态检测的每次判定过程，排查误判时开启，默认关闭
+	AIDetectionDiagnostics bool `json:"aiDetectionDiagnostics" yaml:"aiDetectionDiagnostics"`
+	// CaptureRawOutput 每个终端保留最近 64KB 解码前的原始输出，排查乱码时开启，默认关闭
+	CaptureRawOutput bool `json:"captureRawOutput" yaml:"captureRawOutput"`
 }
 
 type AIAssistantStatusConfig struct {
@@ -40,16 +113,147 @@ type AIAssistantStatusConfig struct {
 	Gemini     bool `json:"gemini" yaml:"gemini"`         // 未充分测试，默认禁用
 	Cursor     bool `json:"cursor" yaml:"cursor"`         // 未充分测试，默认禁用
 	Copilot    bool `json:"copilot" yaml:"copilot"`       // 未充分测试，默认禁用
+	// Preset 检测参数预设：sensitive / balanced / conservative，空为 balanced
+	Preset string `json:"preset,omitempty" yaml:"preset"`
+	// Detection 覆盖预设中的个别参数，为 0 的字段沿用预设
+	Detection AIDetectionParams `json:"detection,omitempty" yaml:"detection"`
+}
+
+// AI 状态检测参数预设，sensitive 状态切换更快但更容易抖动，conservative 相反
+const (
+	AIDetectionPresetSensitive    = "sensitive"
+	AIDetectionPresetBalanced     = "balanced"
+	AIDetectionPresetConservative = "conservative"
+)
+
+// AIDetectionParams AI 状态检测的调优参数，单位毫秒
+type AIDetectionParams struct {
+	ProcessIntervalMs   int `json:"processIntervalMs,omitempty" yaml:"processIntervalMs"`     // 持续输出时两次检测的最小间隔
+	IdleCheckIntervalMs int `json:"idleCheckIntervalMs,omitempty" yaml:"idleCheckIntervalMs"` // 没有新输出时复查屏幕的间隔
+	WorkingExitMs       int `json:"workingExitMs,omitempty" yaml:"workingExitMs"`             // 工作提示消失多久后才离开 working（稳定时间）
+	DebounceMs          int `json:"debounceMs,omitempty" yaml:"debounceMs"`                   // 相同状态的重复变化在该窗口内只广播一次
+}
+
+// aiDetectionPresets balanced 与内置默认值一致
+var aiDetectionPresets = map[string]AIDetectionParams{
+	AIDetectionPresetSensitive:    {ProcessIntervalMs: 50, IdleCheckIntervalMs: 250, WorkingExitMs: 500, DebounceMs: 200},
+	AIDetectionPresetBalanced:     {ProcessIntervalMs: 100, IdleCheckIntervalMs: 500, WorkingExitMs: 1000, DebounceMs: 500},
+	AIDetectionPresetConservative: {ProcessIntervalMs: 200, IdleCheckIntervalMs: 1000, WorkingExitMs: 2000, DebounceMs: 1000},
+}
+
+// TerminalNotificationConfig 完成/审批通知的展示文案模板，留空使用默认格式。
+// 支持 {{project}}、{{title}}、{{assistant}}、{{input}} 占位符，非法模板回退到默认格式
+type TerminalNotificationConfig struct {
+	CompletionTemplate string `json:"completionTemplate" yaml:"completionTemplate"`
+	ApprovalTemplate   string `json:"approvalTemplate" yaml:"approvalTemplate"`
+	// CompletionDelayMs AI 助手回到等待输入后需保持多少毫秒才生成完成通知，0 使用默认 1500，负数立即通知
+	CompletionDelayMs int `json:"completionDelayMs" yaml:"completionDelayMs"`
+	// ApprovalTimeout 审批提示长时间未响应时的处理，默认关闭
+	ApprovalTimeout TerminalApprovalTimeoutConfig `json:"approvalTimeout" yaml:"approvalTimeout"`
+}
+
+// TerminalApprovalTimeoutPolicy 审批提示超过 timeout 未响应时：escalate（默认）只升级为告警通知；
+// reject 在仍能识别审批菜单时替用户选择拒绝并记入项目活动，timeout 至少 1m
+type TerminalApprovalTimeoutPolicy struct {
+	Timeout string `json:"timeout" yaml:"timeout"` // 如 10m，为空或 0 关闭
+	Action  string `json:"action" yaml:"action"`   // escalate / reject
+}
+
+// TerminalApprovalTimeoutConfig 全局审批超时策略，assistants 按助手类型（如 claude-code、codex）覆盖
+type TerminalApprovalTimeoutConfig struct {
+	Timeout    string                                   `json:"timeout" yaml:"timeout"`
+	Action     string                                   `json:"action" yaml:"action"`
+	Assistants map[string]TerminalApprovalTimeoutPolicy `json:"assistants" yaml:"assistants"`
+}
+
+// ModelPrice 模型每百万 token 的美元价格，用于估算 AI 会话成本
+type ModelPrice struct {
+	InputPerMillion  float64 `json:"inputPerMillion" yaml:"inputPerMillion"`
+	OutputPerMillion float64 `json:"outputPerMillion" yaml:"outputPerMillion"`
+}
+
+// TerminalOutputLogConfig 会话完整输出写入 <dir>/<sessionId>.log，按大小轮转
+type TerminalOutputLogConfig struct {
+	Enabled   bool   `json:"enabled" yaml:"enabled"`
+	Dir       string `json:"dir" yaml:"dir"`
+	MaxSizeMB int    `json:"maxSizeMB" yaml:"maxSizeMB"` // 单个文件上限，超过后轮转
+	MaxFiles  int    `json:"maxFiles" yaml:"maxFiles"`   // 保留的已轮转文件数
+	Raw       bool   `json:"raw" yaml:"raw"`             // 保留 ANSI 控制序列，默认输出纯文本
+}
+
+// TerminalSpillConfig 超出 scrollbackBytes 的输出追加到 <dir> 下的临时文件，会话关闭后删除
+type TerminalSpillConfig struct {
+	Enabled   bool   `json:"enabled" yaml:"enabled"`
+	Dir       string `json:"dir" yaml:"dir"`             // 为空使用系统临时目录
+	MaxSizeMB int    `json:"maxSizeMB" yaml:"maxSizeMB"` // 每个会话的磁盘上限，默认 64，超出后丢弃最早的一半
+}
+
+// TerminalScreenshotConfig 定时把会话屏幕渲染为文本存入 <dir>/<sessionId>/，每个会话保留最近 maxFiles 张
+type TerminalScreenshotConfig struct {
+	Enabled     bool   `json:"enabled" yaml:"enabled"`
+	Dir         string `json:"dir" yaml:"dir"`
+	Interval    string `json:"interval" yaml:"interval"` // 截图间隔，如 30s；屏幕无新输出时跳过
+	MaxFiles    int    `json:"maxFiles" yaml:"maxFiles"`
+	MaxSessions int    `json:"maxSessions" yaml:"maxSessions"` // 保留快照目录的已关闭会话数，会话关闭时删除更早的目录
+}
+
+// TerminalWebhookConfig 会话创建、关闭时向 url POST JSON 事件，失败按指数退避重试，不影响会话本身
+type TerminalWebhookConfig struct {
+	URL        string            `json:"url" yaml:"url"`
+	Headers    map[string]string `json:"headers" yaml:"headers"`       // 附加请求头，如 Authorization
+	Timeout    string            `json:"timeout" yaml:"timeout"`       // 单次请求超时，默认 10s
+	MaxRetries int               `json:"maxRetries" yaml:"maxRetries"` // 失败后的重试次数，默认 3，负数不重试
+}
+
+// WorktreeWatchConfig 监听 worktree 目录与 .git 关键文件，变化后自动刷新状态
+type WorktreeWatchConfig struct {
+	Enabled     bool `json:"enabled" yaml:"enabled"`
+	MaxWatchers int  `json:"maxWatchers" yaml:"maxWatchers"` // 同时监听的 worktree 数量上限，超出的只能手动刷新
+	DebounceMs  int  `json:"debounceMs" yaml:"debounceMs"`   // 高频变化合并为一次刷新的等待时间
+	ActiveOnly  bool `json:"activeOnly" yaml:"activeOnly"`   // 只监听有打开终端的 worktree，终端全部关闭后停止监听
+}
+
+// GitConfig 提交消息规范，CommitMessagePattern 为空时不校验
+type GitConfig struct {
+	CommitMessagePattern string `json:"commitMessagePattern" yaml:"commitMessagePattern"` // 提交消息需匹配的正则，如 conventional commits
+	CommitMessageHint    string `json:"commitMessageHint" yaml:"commitMessageHint"`       // 校验失败时返回给用户的提示
+	CommitTemplate       string `json:"commitTemplate" yaml:"commitTemplate"`             // 前端预填的提交模板，为空时使用仓库的 commit.template
+	SyncBranchOnSwitch   bool   `json:"syncBranchOnSwitch" yaml:"syncBranchOnSwitch"`     // 在终端里切换 worktree 分支后，刷新状态时自动把记录的分支同步为实际分支
 }
 
 type TerminalConfig struct {
-	Shell                 TerminalShellConfig     `json:"shell" yaml:"shell"`
-	IdleTimeout           string                  `json:"idleTimeout" yaml:"idleTimeout"`
-	MaxSessionsPerProject int                     `json:"maxSessionsPerProject" yaml:"maxSessionsPerProject"`
-	AllowedRoots          []string                `json:"allowedRoots" yaml:"allowedRoots"`
-	Encoding              string                  `json:"encoding" yaml:"encoding"`
-	ScrollbackBytes       int                     `json:"scrollbackBytes" yaml:"scrollbackBytes"`
-	AIAssistantStatus     AIAssistantStatusConfig `json:"aiAssistantStatus" yaml:"aiAssistantStatus"`
+	Shell                 TerminalShellConfig        `json:"shell" yaml:"shell"`
+	IdleTimeout           string                     `json:"idleTimeout" yaml:"idleTimeout"`
+	MaxSessionsPerProject int                        `json:"maxSessionsPerProject" yaml:"maxSessionsPerProject"`
+	AllowedRoots          []string                   `json:"allowedRoots" yaml:"allowedRoots"`           // 非空时终端工作目录必须位于其中之一，支持 ~ 与 $HOME 等环境变量
+	DeniedWorkingDirs     []string                   `json:"deniedWorkingDirs" yaml:"deniedWorkingDirs"` // 禁止作为终端工作目录的路径，如 /、~、C:\Windows，只匹配目录本身
+	Encoding              string                     `json:"encoding" yaml:"encoding"`
+	ScrollbackBytes       int                        `json:"scrollbackBytes" yaml:"scrollbackBytes"`
+	ScrollbackSpill       TerminalSpillConfig        `json:"scrollbackSpill" yaml:"scrollbackSpill"`       // 长会话的早期输出落盘，内存只保留 scrollbackBytes
+	ScrollbackCollapse    bool                       `json:"scrollbackCollapse" yaml:"scrollbackCollapse"` // 连续的 spinner 重绘分片只保留最新一份，默认关闭
+	ScrollbackTotalMB     int                        `json:"scrollbackTotalMB" yaml:"scrollbackTotalMB"`   // 所有会话 scrollback 的内存总预算（MB），超出时先裁剪最久未活动的会话，0 不限制
+	BinaryOutput          string                     `json:"binaryOutput" yaml:"binaryOutput"`             // 疑似二进制输出：空不处理，truncate 截断并提示，reset 截断后再复位终端
+	CloseOnMaxLifetime    bool                       `json:"closeOnMaxLifetime" yaml:"closeOnMaxLifetime"`
+	QuotaMaxSessions      int                        `json:"quotaMaxSessions" yaml:"quotaMaxSessions"`         // 单个 quotaKey 的会话总数上限，0 不限制
+	QuotaMaxMemoryMB      int                        `json:"quotaMaxMemoryMB" yaml:"quotaMaxMemoryMB"`         // 单个 quotaKey 的内存总量上限（MB），0 不限制
+	RecordEvents          string                     `json:"recordEvents" yaml:"recordEvents"`                 // 事件记录模式：空/memory/file
+	RecordEventsDir       string                     `json:"recordEventsDir" yaml:"recordEventsDir"`           // file 模式下 JSONL 的存放目录
+	RecordEventsMaxFiles  int                        `json:"recordEventsMaxFiles" yaml:"recordEventsMaxFiles"` // 保留事件文件的已关闭会话数，0 使用默认 50
+	OutputTriggers        []TerminalOutputTrigger    `json:"outputTriggers" yaml:"outputTriggers"`             // 对所有会话生效的输出触发器
+	Macros                []TerminalInputMacro       `json:"macros" yaml:"macros"`                             // 对所有会话生效的输入宏
+	TitleRules            []TerminalTitleRule        `json:"titleRules" yaml:"titleRules"`                     // 按输出内容设置会话标题
+	HighlightRules        []TerminalHighlightRule    `json:"highlightRules" yaml:"highlightRules"`             // 输出关键字高亮，命中范围通过 highlights 事件下发
+	AlertRules            []TerminalAlertRule        `json:"alertRules" yaml:"alertRules"`                     // 对所有会话生效的输出关键字告警
+	OutputFilters         []TerminalOutputFilterRule `json:"outputFilters" yaml:"outputFilters"`               // 预览与导出时过滤 spinner 等输出噪声
+	OutputLog             TerminalOutputLogConfig    `json:"outputLog" yaml:"outputLog"`                       // 会话输出落盘为纯文本日志
+	Screenshots           TerminalScreenshotConfig   `json:"screenshots" yaml:"screenshots"`                   // 定时归档屏幕快照，排查画面不刷新
+	AIAssistantStatus     AIAssistantStatusConfig    `json:"aiAssistantStatus" yaml:"aiAssistantStatus"`
+	Term                  string                     `json:"term" yaml:"term"`       // 会话的 TERM，默认 xterm-256color
+	TermEnv               map[string]string          `json:"termEnv" yaml:"termEnv"` // 附加的 COLORTERM / TERM_PROGRAM / TERM_PROGRAM_VERSION
+	Notifications         TerminalNotificationConfig `json:"notifications" yaml:"notifications"`
+	ModelPricing          map[string]ModelPrice      `json:"modelPricing" yaml:"modelPricing"` // 按模型名（或助手类型，如 codex）配置的 token 价格
+	Webhook               TerminalWebhookConfig      `json:"webhook" yaml:"webhook"`           // 会话生命周期通知，对接计费或监控
+	AIProfiles            []TerminalAIProfile        `json:"aiProfiles" yaml:"aiProfiles"`     // 创建会话时可选的 AI 档案
 
 	idleDuration time.Duration
 }
@@ -95,29 +299,63 @@ func (c *AIAssistantStatusConfig) IsEnabled(assistantType string) bool {
 	}
 }
 
+// ValidAIDetectionPreset reports whether name is empty or a built-in preset.
+func ValidAIDetectionPreset(name string) bool {
+	if name == "" {
+		return true
+	}
+	_, ok := aiDetectionPresets[name]
+	return ok
+}
+
+// DetectionParams expands Preset into concrete parameters and applies the
+// non-zero fields of Detection on top. Unknown presets fall back to balanced.
+func (c *AIAssistantStatusConfig) DetectionParams() AIDetectionParams {
+	params, ok := aiDetectionPresets[strings.ToLower(strings.TrimSpace(c.Preset))]
+	if !ok {
+		params = aiDetectionPresets[AIDetectionPresetBalanced]
+	}
+	override := c.Detection
+	if override.ProcessIntervalMs > 0 {
+		params.ProcessIntervalMs = override.ProcessIntervalMs
+	}
+	if override.IdleCheckIntervalMs > 0 {
+		params.IdleCheckIntervalMs = override.IdleCheckIntervalMs
+	}
+	if override.WorkingExitMs > 0 {
+		params.WorkingExitMs = override.WorkingExitMs
+	}
+	if override.DebounceMs > 0 {
+		params.DebounceMs = override.DebounceMs
+	}
+	return params
+}
+
 type AppConfig struct {
-	ServeAt                string           `json:"serveAt" yaml:"serveAt"`
-	Domain                 string           `json:"domain" yaml:"domain"`
-	RegisterOpen           bool             `json:"registerOpen" yaml:"registerOpen"`
-	WebUrl                 string           `json:"webUrl" yaml:"webUrl"`
-	AttachmentSizeLimit    int64            `json:"attachmentSizeLimit" yaml:"attachmentSizeLimit"`
-	ImageCompress          bool             `json:"imageCompress" yaml:"imageCompress"`
-	LogFile                string           `json:"logFile" yaml:"logFile"`
-	LogLevel               string           `json:"logLevel" yaml:"logLevel"`
-	DBLogLevel             int              `json:"dbLogLevel" yaml:"dbLogLevel"`
-	CorsAllowOrigins       string           `json:"corsAllowOrigins" yaml:"corsAllowOrigins"`
-	UIOverwrite            string           `json:"uiOverwrite" yaml:"uiOverwrite"`
-	AutoMigrate            bool             `json:"autoMigrate" yaml:"autoMigrate"`
-	OpenAPIEnabled         bool             `json:"openapiEnabled" yaml:"openapiEnabled"`
-	DocsPath               string           `json:"docsPath" yaml:"docsPath"`
-	APITitle               string           `json:"apiTitle" yaml:"apiTitle"`
-	APIVersion             string           `json:"apiVersion" yaml:"apiVersion"`
-	AttachmentConfig       AttachmentConfig `json:"attachmentConfig" yaml:"attachmentConfig"`
-	DSN                    string           `json:"dbUrl" yaml:"dbUrl"`
-	PrintConfig            bool             `json:"printConfig" yaml:"printConfig"`
-	DisableAutoOpenBrowser bool             `json:"disableAutoOpenBrowser" yaml:"disableAutoOpenBrowser"`
-	Terminal               TerminalConfig   `json:"terminal" yaml:"terminal"`
-	Developer              DeveloperConfig  `json:"developer" yaml:"developer"`
+	ServeAt                string              `json:"serveAt" yaml:"serveAt"`
+	Domain                 string              `json:"domain" yaml:"domain"`
+	RegisterOpen           bool                `json:"registerOpen" yaml:"registerOpen"`
+	WebUrl                 string              `json:"webUrl" yaml:"webUrl"`
+	AttachmentSizeLimit    int64               `json:"attachmentSizeLimit" yaml:"attachmentSizeLimit"`
+	ImageCompress          bool                `json:"imageCompress" yaml:"imageCompress"`
+	LogFile                string              `json:"logFile" yaml:"logFile"`
+	LogLevel               string              `json:"logLevel" yaml:"logLevel"`
+	DBLogLevel             int                 `json:"dbLogLevel" yaml:"dbLogLevel"`
+	CorsAllowOrigins       string              `json:"corsAllowOrigins" yaml:"corsAllowOrigins"`
+	UIOverwrite            string              `json:"uiOverwrite" yaml:"uiOverwrite"`
+	AutoMigrate            bool                `json:"autoMigrate" yaml:"autoMigrate"`
+	OpenAPIEnabled         bool                `json:"openapiEnabled" yaml:"openapiEnabled"`
+	DocsPath               string              `json:"docsPath" yaml:"docsPath"`
+	APITitle               string              `json:"apiTitle" yaml:"apiTitle"`
+	APIVersion             string              `json:"apiVersion" yaml:"apiVersion"`
+	AttachmentConfig       AttachmentConfig    `json:"attachmentConfig" yaml:"attachmentConfig"`
+	DSN                    string              `json:"dbUrl" yaml:"dbUrl"`
+	PrintConfig            bool                `json:"printConfig" yaml:"printConfig"`
+	DisableAutoOpenBrowser bool                `json:"disableAutoOpenBrowser" yaml:"disableAutoOpenBrowser"`
+	Terminal               TerminalConfig      `json:"terminal" yaml:"terminal"`
+	Developer              DeveloperConfig     `json:"developer" yaml:"developer"`
+	WorktreeWatch          WorktreeWatchConfig `json:"worktreeWatch" yaml:"worktreeWatch"`
+	Git                    GitConfig           `json:"git" yaml:"git"`
 }
 
 var configStore = koanf.New(".")
@@ -174,15 +412,29 @@ func ReadConfig() *AppConfig {
 		DisableAutoOpenBrowser: false,
 		Terminal: TerminalConfig{
 			Shell: TerminalShellConfig{
-				Windows: "pwsh.exe -NoLogo",
-				Linux:   "/bin/bash",
-				Darwin:  "/bin/zsh",
+				Windows: builtinWindowsShell,
+				Linux:   builtinLinuxShell,
+				Darwin:  builtinDarwinShell,
 			},
 			IdleTimeout:           "0s",
 			MaxSessionsPerProject: 12,
 			AllowedRoots:          []string{},
 			Encoding:              "utf-8",
+			Term:                  "xterm-256color",
 			ScrollbackBytes:       262144,
+			CloseOnMaxLifetime:    true,
+			RecordEventsDir:       fmt.Sprintf("%s/terminal-events", dataDir),
+			OutputLog: TerminalOutputLogConfig{
+				Dir:       fmt.Sprintf("%s/terminal-logs", dataDir),
+				MaxSizeMB: 10,
+				MaxFiles:  5,
+			},
+			Screenshots: TerminalScreenshotConfig{
+				Dir:         fmt.Sprintf("%s/terminal-screenshots", dataDir),
+				Interval:    "30s",
+				MaxFiles:    20,
+				MaxSessions: 20,
+			},
 			AIAssistantStatus: AIAssistantStatusConfig{
 				ClaudeCode: true,  // 状态监测准确
 				Codex:      true,  // 默认启用
@@ -197,6 +449,12 @@ func ReadConfig() *AppConfig {
 			RenameSessionTitleEachCommand: false,
 			AutoCreateTaskOnStartWork:     true,
 		},
+		WorktreeWatch: WorktreeWatchConfig{
+			Enabled:     true,
+			MaxWatchers: 32,
+			DebounceMs:  1000,
+			ActiveOnly:  true,
+		},
 	}
 
 	lo.Must0(configStore.Load(structs.Provider(&defaults, "yaml"), nil))
@@ -222,6 +480,11 @@ func ReadConfig() *AppConfig {
 
 	// Normalize derived values to avoid redundant calculations.
 	_ = config.Terminal.IdleDuration()
+	status := &config.Terminal.AIAssistantStatus
+	status.Preset = strings.ToLower(strings.TrimSpace(status.Preset))
+	if !ValidAIDetectionPreset(status.Preset) {
+		fmt.Printf("Unknown AI detection preset %q, using %s\n", status.Preset, AIDetectionPresetBalanced)
+	}
 
 	if config.PrintConfig {
 		configStore.Print()
@@ -230,6 +493,79 @@ func ReadConfig() *AppConfig {
 	return &config
 }
 
+// ActiveConfigPath 返回启动时实际加载的配置文件路径，尚未加载时为空。
+func ActiveConfigPath() string {
+	return activeConfigPath
+}
+
+// redactedValue 替换敏感字段的占位符，原值为空时保持为空
+const redactedValue = "******"
+
+// RedactedConfig 返回去掉密钥、token 等敏感信息的配置副本，用于对外展示。
+func RedactedConfig(config *AppConfig) AppConfig {
+	result := *config
+	redact := func(value string) string {
+		if value == "" {
+			return ""
+		}
+		return redactedValue
+	}
+	result.AttachmentConfig.AccessKey = redact(result.AttachmentConfig.AccessKey)
+	result.AttachmentConfig.SecretKey = redact(result.AttachmentConfig.SecretKey)
+	result.AttachmentConfig.Token = redact(result.AttachmentConfig.Token)
+	if parsed, err := url.Parse(result.DSN); err == nil && parsed.User != nil {
+		result.DSN = parsed.Redacted()
+	}
+	result.Terminal.Webhook.URL = redactURL(result.Terminal.Webhook.URL)
+	if len(result.Terminal.Webhook.Headers) > 0 {
+		headers := maps.Clone(result.Terminal.Webhook.Headers)
+		for key, value := range headers {
+			headers[key] = redact(value)
+		}
+		result.Terminal.Webhook.Headers = headers
+	}
+	if len(result.Terminal.AIProfiles) > 0 {
+		// AI 档案的环境变量常用于传递 API Key，只保留变量名
+		profiles := slices.Clone(result.Terminal.AIProfiles)
+		for i := range profiles {
+			env := maps.Clone(profiles[i].Env)
+			for key, value := range env {
+				env[key] = redact(value)
+			}
+			profiles[i].Env = env
+		}
+		result.Terminal.AIProfiles = profiles
+	}
+	return result
+}
+
+// redactURL 隐藏 URL 中的用户信息与查询参数值，webhook 地址常以这两种方式携带 token；无法解析时整体隐藏
+func redactURL(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return redactedValue
+	}
+	hasUser := parsed.User != nil
+	parsed.User = nil
+	if parsed.RawQuery != "" {
+		query := parsed.Query()
+		parts := make([]string, 0, len(query))
+		for _, key := range slices.Sorted(maps.Keys(query)) {
+			parts = append(parts, url.QueryEscape(key)+"="+redactedValue)
+		}
+		parsed.RawQuery = strings.Join(parts, "&")
+	}
+	result := parsed.String()
+	if hasUser {
+		// 占位符直接拼接，避免被转义为 %2A
+		result = strings.Replace(result, "//", "//"+redactedValue+"@", 1)
+	}
+	return result
+}
+
 // WriteConfig 会将当前配置写回磁盘，写入的是启动时实际加载的配置文件路径。
 func WriteConfig(config *AppConfig) {
 	// Use the config path that was actually loaded during ReadConfig
@@ -3,6 +3,7 @@ package git
 import (
 	"errors"
 	"fmt"
+	"os/exec"
 	"strings"
 
 	goGit "github.com/go-git/go-git/v5"
@@ -17,6 +18,8 @@ type BranchInfo struct {
 	HeadCommit        string `json:"headCommit"`
 	HeadCommitMessage string `json:"headCommitMessage"`
 	HasWorktree       bool   `json:"hasWorktree"`
+	// HasLocalBranch 仅用于远程分支，表示已存在同名（去掉远程名前缀）的本地分支
+	HasLocalBranch bool `json:"hasLocalBranch"`
 }
 
 // ListBranches returns local and remote branches present in the repository.
@@ -55,16 +58,24 @@ func (r *GitRepo) ListBranches() (local []BranchInfo, remote []BranchInfo, err e
 	}
 	defer refIter.Close()
 
+	localNames := make(map[string]struct{}, len(local))
+	for _, branch := range local {
+		localNames[branch.Name] = struct{}{}
+	}
+
 	remote = make([]BranchInfo, 0)
 	err = refIter.ForEach(func(ref *plumbing.Reference) error {
 		if !ref.Name().IsRemote() {
 			return nil
 		}
+		name := ref.Name().Short()
+		_, hasLocal := localNames[RemoteBranchLocalName(name)]
 		remote = append(remote, BranchInfo{
-			Name:              ref.Name().Short(),
+			Name:              name,
 			IsRemote:          true,
 			HeadCommit:        shortHash(ref.Hash()),
 			HeadCommitMessage: resolveCommitMessage(r.Repository, ref.Hash()),
+			HasLocalBranch:    hasLocal,
 		})
 		return nil
 	})
@@ -89,11 +100,71 @@ func (r *GitRepo) CreateBranch(name, base string) error {
 
 	cmd := newGitCommand(r.Path, args...)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("create branch failed: %s", strings.TrimSpace(string(output)))
+		return gitFailure("create branch", string(output))
+	}
+	return nil
+}
+
+// CreateTrackingBranch creates a local branch that tracks the remote branch (e.g. origin/feature-x).
+func (r *GitRepo) CreateTrackingBranch(name, remoteBranch string) error {
+	if r == nil {
+		return errors.New("git repository is not initialized")
+	}
+	branch := strings.TrimSpace(name)
+	if branch == "" {
+		return errors.New("branch name is required")
+	}
+	upstream := strings.TrimSpace(remoteBranch)
+	if upstream == "" {
+		return errors.New("remote branch is required")
+	}
+
+	cmd := newGitCommand(r.Path, "branch", "--track", branch, upstream)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return gitFailure("create tracking branch", string(output))
 	}
 	return nil
 }
 
+// RemoteBranchLocalName strips the remote name from a remote branch, "origin/feature-x" -> "feature-x".
+func RemoteBranchLocalName(remoteBranch string) string {
+	if _, local, ok := strings.Cut(strings.TrimSpace(remoteBranch), "/"); ok {
+		return local
+	}
+	return remoteBranch
+}
+
+// ListMergedBranches returns the local branches already merged into into (HEAD
+// when empty), as reported by `git branch --merged`. into itself is included.
+func (r *GitRepo) ListMergedBranches(into string) ([]string, error) {
+	if r == nil {
+		return nil, errors.New("git repository is not initialized")
+	}
+	target := strings.TrimSpace(into)
+	if target == "" {
+		target = "HEAD"
+	}
+	if strings.HasPrefix(target, "-") {
+		return nil, fmt.Errorf("invalid branch %q", target)
+	}
+
+	cmd := newGitCommand(r.Path, "branch", "--format=%(refname:short)", "--merged", target)
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("list merged branches failed: %s", strings.TrimSpace(string(output)))
+	}
+	branches := make([]string, 0)
+	for _, line := range strings.Split(string(output), "\n") {
+		name := strings.TrimSpace(line)
+		// 分离 HEAD 显示为 "(HEAD detached at ...)"
+		if name == "" || strings.HasPrefix(name, "(") {
+			continue
+		}
+		branches = append(branches, name)
+	}
+	return branches, nil
+}
+
 // DeleteBranch removes a local branch. Force controls the -D flag.
 func (r *GitRepo) DeleteBranch(name string, force bool) error {
 	if r == nil {
@@ -113,7 +184,7 @@ func (r *GitRepo) DeleteBranch(name string, force bool) error {
 
 	cmd := newGitCommand(r.Path, args...)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("delete branch failed: %s", strings.TrimSpace(string(output)))
+		return gitFailure("delete branch", string(output))
 	}
 	return nil
 }
@@ -130,11 +201,50 @@ func (r *GitRepo) CheckoutBranch(name string) error {
 
 	cmd := newGitCommand(r.Path, "checkout", branch)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("checkout failed: %s", strings.TrimSpace(string(output)))
+		return gitFailure("checkout", string(output))
 	}
 	return nil
 }
 
+// BranchExists reports whether a local branch exists in the repository.
+func (r *GitRepo) BranchExists(name string) (bool, error) {
+	if r == nil {
+		return false, errors.New("git repository is not initialized")
+	}
+	return BranchExists(r.Path, name)
+}
+
+// BranchExists reports whether the local branch exists, resolving refs from
+// the repository (or any of its worktrees) located at path.
+func BranchExists(path, name string) (bool, error) {
+	branch := strings.TrimSpace(name)
+	if branch == "" {
+		return false, errors.New("branch name is required")
+	}
+
+	return refExists(path, "refs/heads/"+branch)
+}
+
+func refExists(path, ref string) (bool, error) {
+	cmd := newGitCommand(path, "show-ref", "--verify", "--quiet", ref)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return false, nil
+		}
+		return false, fmt.Errorf("check branch failed: %s", strings.TrimSpace(string(output)))
+	}
+	return true, nil
+}
+
+// RemoteBranchExists reports whether name (e.g. origin/feature-x) is a remote-tracking branch.
+func (r *GitRepo) RemoteBranchExists(name string) (bool, error) {
+	if r == nil {
+		return false, errors.New("git repository is not initialized")
+	}
+	return refExists(r.Path, "refs/remotes/"+strings.TrimSpace(name))
+}
+
 func shortHash(hash plumbing.Hash) string {
 	value := hash.String()
 	if len(value) > 7 {
@@ -0,0 +1,127 @@
+package git
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// untrackedLineCountLimit 未跟踪文件超过该大小时不统计行数
+	untrackedLineCountLimit = 1 << 20
+	// binarySniffBytes 与 git 一样，前 8000 字节含 NUL 视为二进制
+	binarySniffBytes = 8000
+)
+
+// ChangedFile is one file in a ChangeGroups group. Status is the porcelain
+// status code of the side the group describes: the index code (X) for staged
+// files, the worktree code (Y) for unstaged ones and "?" for untracked ones.
+type ChangedFile struct {
+	Path       string `json:"path"`
+	OrigPath   string `json:"origPath,omitempty"`
+	Status     string `json:"status"`
+	Additions  int    `json:"additions"`
+	Deletions  int    `json:"deletions"`
+	Binary     bool   `json:"binary,omitempty"`
+	Conflicted bool   `json:"conflicted,omitempty"`
+}
+
+// ChangeGroups splits the changes of a worktree the way IDEs show them. A file
+// with both staged and unstaged changes appears in both groups, each with the
+// line counts of its own side. Conflicted files are listed as unstaged.
+type ChangeGroups struct {
+	Staged    []ChangedFile `json:"staged"`
+	Unstaged  []ChangedFile `json:"unstaged"`
+	Untracked []ChangedFile `json:"untracked"`
+}
+
+// GroupFileChanges groups changes of the worktree at path and fills in line
+// counts from `git diff --cached --numstat` and `git diff --numstat`. Untracked
+// files count every line as added, except binary or very large files.
+func GroupFileChanges(path string, changes []FileChange) (*ChangeGroups, error) {
+	groups := &ChangeGroups{
+		Staged:    make([]ChangedFile, 0),
+		Unstaged:  make([]ChangedFile, 0),
+		Untracked: make([]ChangedFile, 0),
+	}
+	var hasStaged, hasUnstaged bool
+	for _, change := range changes {
+		hasStaged = hasStaged || (change.Staged && !change.Conflicted)
+		hasUnstaged = hasUnstaged || (change.Unstaged && !change.Untracked)
+	}
+
+	var stagedStats, unstagedStats map[string]DiffFileStat
+	if hasStaged {
+		output, err := runGitOutput(path, "diff", "--cached", "--numstat", "-z", "-M", "--")
+		if err != nil {
+			return nil, err
+		}
+		stagedStats = numstatByPath(output)
+	}
+	if hasUnstaged {
+		output, err := runGitOutput(path, "diff", "--numstat", "-z", "--")
+		if err != nil {
+			return nil, err
+		}
+		unstagedStats = numstatByPath(output)
+	}
+
+	for _, change := range changes {
+		switch {
+		case change.Untracked:
+			file := ChangedFile{Path: change.Path, Status: "?"}
+			file.Additions, file.Binary = countUntrackedLines(filepath.Join(path, filepath.FromSlash(change.Path)))
+			groups.Untracked = append(groups.Untracked, file)
+		case change.Conflicted:
+			file := withStat(ChangedFile{Path: change.Path, Status: change.Index + change.Worktree, Conflicted: true}, unstagedStats)
+			groups.Unstaged = append(groups.Unstaged, file)
+		default:
+			if change.Staged {
+				groups.Staged = append(groups.Staged, withStat(ChangedFile{Path: change.Path, OrigPath: change.OrigPath, Status: change.Index}, stagedStats))
+			}
+			if change.Unstaged {
+				groups.Unstaged = append(groups.Unstaged, withStat(ChangedFile{Path: change.Path, Status: change.Worktree}, unstagedStats))
+			}
+		}
+	}
+	return groups, nil
+}
+
+func numstatByPath(output string) map[string]DiffFileStat {
+	stats := parseNumstatOutput(output)
+	byPath := make(map[string]DiffFileStat, len(stats))
+	for _, stat := range stats {
+		byPath[stat.Path] = stat
+	}
+	return byPath
+}
+
+func withStat(file ChangedFile, stats map[string]DiffFileStat) ChangedFile {
+	if stat, ok := stats[file.Path]; ok {
+		file.Additions = stat.Additions
+		file.Deletions = stat.Deletions
+		file.Binary = stat.Binary
+	}
+	return file
+}
+
+// countUntrackedLines counts the lines of an untracked file, a last line
+// without newline included. Binary files report no lines.
+func countUntrackedLines(path string) (int, bool) {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > untrackedLineCountLimit {
+		return 0, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, false
+	}
+	if bytes.IndexByte(data[:min(len(data), binarySniffBytes)], 0) >= 0 {
+		return 0, true
+	}
+	lines := bytes.Count(data, []byte{'\n'})
+	if len(data) > 0 && data[len(data)-1] != '\n' {
+		lines++
+	}
+	return lines, false
+}
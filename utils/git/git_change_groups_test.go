@@ -0,0 +1,55 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGroupFileChanges(t *testing.T) {
+	dir := initTestRepo(t)
+	write := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	// README 先暂存一处改动，再在工作区追加一行
+	write("README.md", "# Test Repo\nstaged\n")
+	runGit(t, dir, "add", "README.md")
+	write("README.md", "# Test Repo\nstaged\nunstaged\n")
+	write("notes.txt", "one\ntwo\nthree")
+	write("blob.bin", "a\x00b\n")
+
+	changes, err := GetFileChanges(dir)
+	if err != nil {
+		t.Fatalf("GetFileChanges: %v", err)
+	}
+	groups, err := GroupFileChanges(dir, changes)
+	if err != nil {
+		t.Fatalf("GroupFileChanges: %v", err)
+	}
+
+	if len(groups.Staged) != 1 {
+		t.Fatalf("expected 1 staged file, got %+v", groups.Staged)
+	}
+	if f := groups.Staged[0]; f.Path != "README.md" || f.Status != "M" || f.Additions != 1 || f.Deletions != 0 {
+		t.Fatalf("unexpected staged entry %+v", f)
+	}
+	if len(groups.Unstaged) != 1 {
+		t.Fatalf("expected 1 unstaged file, got %+v", groups.Unstaged)
+	}
+	if f := groups.Unstaged[0]; f.Path != "README.md" || f.Status != "M" || f.Additions != 1 {
+		t.Fatalf("unexpected unstaged entry %+v", f)
+	}
+	untracked := make(map[string]ChangedFile)
+	for _, f := range groups.Untracked {
+		untracked[f.Path] = f
+	}
+	if f := untracked["notes.txt"]; f.Status != "?" || f.Additions != 3 {
+		t.Fatalf("unexpected untracked text entry %+v", f)
+	}
+	if f := untracked["blob.bin"]; !f.Binary || f.Additions != 0 {
+		t.Fatalf("unexpected untracked binary entry %+v", f)
+	}
+}
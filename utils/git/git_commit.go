@@ -16,7 +16,7 @@ func (r *GitRepo) runInWorktree(path string, args ...string) error {
 	}
 	cmd := newGitCommand(target, args...)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("git %s failed: %s", strings.Join(args, " "), strings.TrimSpace(string(output)))
+		return gitFailure("git "+strings.Join(args, " "), string(output))
 	}
 	return nil
 }
@@ -26,11 +26,79 @@ func (r *GitRepo) AddAll(worktreePath string) error {
 	return r.runInWorktree(worktreePath, "add", "--all")
 }
 
+// ErrCommitSigningFailed 签名提交失败，通常是未配置签名密钥或 gpg/ssh-keygen 不可用
+var ErrCommitSigningFailed = errors.New("commit signing failed: configure user.signingkey (and gpg.format=ssh for SSH keys) and make sure gpg or ssh-keygen is available")
+
+// commitSigningFailureMarkers 是 git 签名失败时输出中出现的关键字
+var commitSigningFailureMarkers = []string{
+	"gpg failed to sign",
+	"cannot run gpg",
+	"no secret key",
+	"signing failed",
+	"user.signingkey",
+	"ssh-keygen",
+	"couldn't load public key",
+}
+
 // Commit creates a commit with the provided message at the given worktree path.
-func (r *GitRepo) Commit(worktreePath, message string) error {
+// When sign is true the commit is signed with -S using the configured gpg/ssh key.
+func (r *GitRepo) Commit(worktreePath, message string, sign bool) error {
 	trimmed := strings.TrimSpace(message)
 	if trimmed == "" {
 		return errors.New("commit message is required")
 	}
-	return r.runInWorktree(worktreePath, "commit", "-m", trimmed)
+	args := []string{"commit", "-m", trimmed}
+	if sign {
+		args = append(args, "-S")
+	}
+	err := r.runInWorktree(worktreePath, args...)
+	if err != nil && sign && isCommitSigningFailure(err.Error()) {
+		return fmt.Errorf("%w: %s", ErrCommitSigningFailed, err.Error())
+	}
+	return err
+}
+
+func isCommitSigningFailure(output string) bool {
+	lower := strings.ToLower(output)
+	for _, marker := range commitSigningFailureMarkers {
+		if strings.Contains(lower, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reset modes accepted by Reset.
+const (
+	ResetModeSoft  = "soft"
+	ResetModeMixed = "mixed"
+	ResetModeHard  = "hard"
+)
+
+// Reset moves HEAD of the worktree at path to ref using the given mode (soft/mixed/hard).
+// An empty ref defaults to HEAD and an empty mode defaults to mixed.
+func (r *GitRepo) Reset(path, ref, mode string) error {
+	if r == nil {
+		return errors.New("git repository is not initialized")
+	}
+	target := strings.TrimSpace(ref)
+	if target == "" {
+		target = "HEAD"
+	}
+	resetMode := strings.ToLower(strings.TrimSpace(mode))
+	if resetMode == "" {
+		resetMode = ResetModeMixed
+	}
+	switch resetMode {
+	case ResetModeSoft, ResetModeMixed, ResetModeHard:
+	default:
+		return fmt.Errorf("unsupported reset mode: %s", mode)
+	}
+	if strings.HasPrefix(target, "-") {
+		return fmt.Errorf("invalid reset ref: %s", target)
+	}
+	if err := r.runInWorktree(path, "rev-parse", "--verify", "--quiet", target+"^{commit}"); err != nil {
+		return fmt.Errorf("unknown reset ref: %s", target)
+	}
+	return r.runInWorktree(path, "reset", "--"+resetMode, target, "--")
 }
@@ -0,0 +1,240 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// ErrCommitNotFound indicates the revision does not name a commit of the repository.
+var ErrCommitNotFound = errors.New("commit not found")
+
+const (
+	// CommitDiffDefaultFiles/CommitDiffMaxFiles 每页 diff 覆盖的文件数
+	CommitDiffDefaultFiles = 20
+	CommitDiffMaxFiles     = 100
+	// commitDiffMaxBytes 单页 patch 的字节上限，超出时在行边界截断
+	commitDiffMaxBytes = 512 * 1024
+)
+
+// CommitFileChange 提交中单个文件的变更；Status 为 --name-status 的首字母（A/M/D/R/C/T），
+// 重命名与复制时 OldPath 为原路径
+type CommitFileChange struct {
+	Status    string `json:"status"`
+	Path      string `json:"path"`
+	OldPath   string `json:"oldPath,omitempty"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+// CommitDetail describes a commit and the files it changed. Merge commits are
+// compared with their first parent.
+type CommitDetail struct {
+	SHA            string             `json:"sha"`
+	ShortSHA       string             `json:"shortSha"`
+	Parents        []string           `json:"parents"`
+	Author         string             `json:"author"`
+	AuthorEmail    string             `json:"authorEmail"`
+	AuthorDate     time.Time          `json:"authorDate"`
+	Committer      string             `json:"committer"`
+	CommitterEmail string             `json:"committerEmail"`
+	CommitDate     time.Time          `json:"commitDate"`
+	Subject        string             `json:"subject"`
+	Body           string             `json:"body,omitempty"`
+	Files          []CommitFileChange `json:"files"`
+	Additions      int                `json:"additions"`
+	Deletions      int                `json:"deletions"`
+	// SignatureStatus 为 git 的 %G? 结果：G 有效、U 有效但信任未知、N 未签名，其余为无效或无法校验
+	SignatureStatus string `json:"signatureStatus"`
+	// SignatureValid 仅在签名可被验证（G/U）时为 true
+	SignatureValid bool `json:"signatureValid"`
+	// Diff 按文件分页的 patch，只在请求时填充
+	Diff *CommitDiffPage `json:"diff,omitempty"`
+}
+
+// CommitDiffPage is the patch of Files[Offset:Offset+Count] of a commit.
+type CommitDiffPage struct {
+	Offset  int    `json:"offset"`
+	Count   int    `json:"count"`
+	Total   int    `json:"total"`
+	HasMore bool   `json:"hasMore"`
+	Patch   string `json:"patch"`
+	// Truncated patch 超过单页字节上限被截断，可缩小 limit 重新获取
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// GetCommitDetail returns the metadata and changed files of the commit named by
+// rev in the repository at path, without the patch; see GetCommitDiff.
+func GetCommitDetail(path, rev string) (*CommitDetail, error) {
+	sha, err := resolveCommit(path, rev)
+	if err != nil {
+		return nil, err
+	}
+
+	// 签名校验需要调用 gpg/ssh-keygen，只在查看单个提交时进行，不放在状态刷新里
+	output, err := commitOutput(path, "show", "-s", "--format=%H%x00%P%x00%an%x00%ae%x00%aI%x00%cn%x00%ce%x00%cI%x00%G?%x00%B", sha)
+	if err != nil {
+		return nil, err
+	}
+	parts := bytes.SplitN(output, []byte{0}, 10)
+	if len(parts) < 10 {
+		return nil, errors.New("unexpected git show output")
+	}
+	subject, body, _ := strings.Cut(strings.TrimSpace(string(parts[9])), "\n")
+	signature := strings.TrimSpace(string(parts[8]))
+	detail := &CommitDetail{
+		SHA:             strings.TrimSpace(string(parts[0])),
+		Parents:         strings.Fields(string(parts[1])),
+		Author:          strings.TrimSpace(string(parts[2])),
+		AuthorEmail:     strings.TrimSpace(string(parts[3])),
+		AuthorDate:      parseGitTime(string(parts[4])),
+		Committer:       strings.TrimSpace(string(parts[5])),
+		CommitterEmail:  strings.TrimSpace(string(parts[6])),
+		CommitDate:      parseGitTime(string(parts[7])),
+		Subject:         strings.TrimSpace(subject),
+		Body:            strings.TrimSpace(body),
+		SignatureStatus: signature,
+		SignatureValid:  signature == "G" || signature == "U",
+	}
+	detail.ShortSHA = shortCommit(detail.SHA)
+
+	nameStatus, err := commitOutput(path, "show", "--format=", "--name-status", "-z", "-M", "--diff-merges=first-parent", sha)
+	if err != nil {
+		return nil, err
+	}
+	numstat, err := commitOutput(path, "show", "--format=", "--numstat", "-z", "-M", "--diff-merges=first-parent", sha)
+	if err != nil {
+		return nil, err
+	}
+	detail.Files = parseNameStatusOutput(string(nameStatus))
+	stats := make(map[string]DiffFileStat)
+	for _, stat := range parseNumstatOutput(string(numstat)) {
+		stats[stat.Path] = stat
+	}
+	for i := range detail.Files {
+		stat := stats[detail.Files[i].Path]
+		detail.Files[i].Additions = stat.Additions
+		detail.Files[i].Deletions = stat.Deletions
+		detail.Files[i].Binary = stat.Binary
+		detail.Additions += stat.Additions
+		detail.Deletions += stat.Deletions
+	}
+	return detail, nil
+}
+
+// GetCommitDiff returns the patch of up to limit files of detail starting at
+// offset, so large commits can be paged instead of returned in one response.
+// limit <= 0 uses CommitDiffDefaultFiles and is capped at CommitDiffMaxFiles.
+func GetCommitDiff(path string, detail *CommitDetail, offset, limit int) (*CommitDiffPage, error) {
+	if detail == nil {
+		return nil, errors.New("commit detail is required")
+	}
+	if limit <= 0 {
+		limit = CommitDiffDefaultFiles
+	}
+	limit = min(limit, CommitDiffMaxFiles)
+	total := len(detail.Files)
+	offset = min(max(offset, 0), total)
+	end := min(offset+limit, total)
+
+	page := &CommitDiffPage{Offset: offset, Count: end - offset, Total: total, HasMore: end < total}
+	if page.Count == 0 {
+		return page, nil
+	}
+
+	// 重命名需要同时给出新旧路径，git 才能识别为同一文件
+	args := []string{"--literal-pathspecs", "show", "--format=", "--patch", "-M", "--diff-merges=first-parent", detail.SHA, "--"}
+	for _, file := range detail.Files[offset:end] {
+		args = append(args, file.Path)
+		if file.OldPath != "" {
+			args = append(args, file.OldPath)
+		}
+	}
+	output, err := commitOutput(path, args...)
+	if err != nil {
+		return nil, err
+	}
+	if len(output) > commitDiffMaxBytes {
+		cut := bytes.LastIndexByte(output[:commitDiffMaxBytes], '\n')
+		if cut < 0 {
+			cut = commitDiffMaxBytes
+		}
+		output = output[:cut+1]
+		page.Truncated = true
+	}
+	page.Patch = string(output)
+	return page, nil
+}
+
+// ResolveCommit resolves rev (a SHA, tag or branch) to the full SHA of a commit
+// in the repository. ErrCommitNotFound is returned when rev names no commit.
+func (r *GitRepo) ResolveCommit(rev string) (string, error) {
+	if r == nil {
+		return "", errors.New("git repository is not initialized")
+	}
+	return resolveCommit(r.Path, rev)
+}
+
+func resolveCommit(path, rev string) (string, error) {
+	rev = strings.TrimSpace(rev)
+	if rev == "" {
+		return "", errors.New("commit is required")
+	}
+	if strings.HasPrefix(rev, "-") {
+		return "", fmt.Errorf("invalid commit %q", rev)
+	}
+	output, err := newGitCommand(path, "rev-parse", "--verify", "--quiet", rev+"^{commit}").Output()
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrCommitNotFound, rev)
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+func commitOutput(path string, args ...string) ([]byte, error) {
+	output, err := newGitCommand(path, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git %s failed: %s", args[0], strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return output, nil
+}
+
+// parseNameStatusOutput parses `--name-status -z`. Renames and copies are written
+// as "R100\0old\0new\0", other changes as "M\0path\0".
+func parseNameStatusOutput(output string) []CommitFileChange {
+	tokens := strings.Split(output, "\x00")
+	changes := make([]CommitFileChange, 0)
+	for i := 0; i < len(tokens); i++ {
+		status := strings.TrimSpace(tokens[i])
+		if status == "" || i+1 >= len(tokens) {
+			continue
+		}
+		change := CommitFileChange{Status: status[:1], Path: tokens[i+1]}
+		i++
+		if change.Status == "R" || change.Status == "C" {
+			if i+1 >= len(tokens) {
+				break
+			}
+			change.OldPath = change.Path
+			change.Path = tokens[i+1]
+			i++
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func parseGitTime(value string) time.Time {
+	timestamp, err := time.Parse(time.RFC3339, strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}
+	}
+	return timestamp
+}
@@ -0,0 +1,82 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseNameStatusOutput(t *testing.T) {
+	changes := parseNameStatusOutput("M\x00main.go\x00R087\x00old.md\x00docs/new.md\x00A\x00logo.png\x00")
+	if len(changes) != 3 {
+		t.Fatalf("expected 3 changes, got %+v", changes)
+	}
+	if changes[0] != (CommitFileChange{Status: "M", Path: "main.go"}) {
+		t.Fatalf("unexpected change %+v", changes[0])
+	}
+	if changes[1].Status != "R" || changes[1].OldPath != "old.md" || changes[1].Path != "docs/new.md" {
+		t.Fatalf("unexpected rename %+v", changes[1])
+	}
+	if changes[2].Status != "A" || changes[2].Path != "logo.png" {
+		t.Fatalf("unexpected change %+v", changes[2])
+	}
+}
+
+func TestGetCommitDetail(t *testing.T) {
+	repoDir := initTestRepo(t)
+	runGit(t, repoDir, "config", "core.autocrlf", "false")
+
+	for i, name := range []string{"a.go", "b.go", "c.go"} {
+		content := "package main\n" + strings.Repeat("// line\n", i+1)
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	runGit(t, repoDir, "mv", "README.md", "GUIDE.md")
+	runGit(t, repoDir, "add", "-A")
+	runGit(t, repoDir, "commit", "-m", "add sources\n\nlonger description")
+
+	detail, err := GetCommitDetail(repoDir, "HEAD")
+	if err != nil {
+		t.Fatalf("GetCommitDetail failed: %v", err)
+	}
+	if detail.Subject != "add sources" || detail.Body != "longer description" || len(detail.Parents) != 1 {
+		t.Fatalf("unexpected commit metadata %+v", detail)
+	}
+	if detail.Author != "Test User" || detail.AuthorDate.IsZero() || len(detail.SHA) != 40 {
+		t.Fatalf("unexpected author info %+v", detail)
+	}
+	if len(detail.Files) != 4 || detail.Additions != 9 {
+		t.Fatalf("unexpected files %+v", detail.Files)
+	}
+	if rename := detail.Files[0]; rename.Status != "R" || rename.OldPath != "README.md" || rename.Path != "GUIDE.md" {
+		t.Fatalf("expected rename first, got %+v", detail.Files)
+	}
+
+	page, err := GetCommitDiff(repoDir, detail, 0, 2)
+	if err != nil {
+		t.Fatalf("GetCommitDiff failed: %v", err)
+	}
+	if page.Count != 2 || page.Total != 4 || !page.HasMore {
+		t.Fatalf("unexpected first page %+v", page)
+	}
+	if !strings.Contains(page.Patch, "rename to GUIDE.md") || !strings.Contains(page.Patch, "a.go") || strings.Contains(page.Patch, "b.go") {
+		t.Fatalf("unexpected first page patch %q", page.Patch)
+	}
+	page, err = GetCommitDiff(repoDir, detail, 2, 2)
+	if err != nil {
+		t.Fatalf("GetCommitDiff failed: %v", err)
+	}
+	if page.Count != 2 || page.HasMore || !strings.Contains(page.Patch, "c.go") {
+		t.Fatalf("unexpected last page %+v", page)
+	}
+
+	if _, err := GetCommitDetail(repoDir, "0123456789abcdef"); !errors.Is(err, ErrCommitNotFound) {
+		t.Fatalf("expected ErrCommitNotFound, got %v", err)
+	}
+	if _, err := GetCommitDetail(repoDir, "--all"); err == nil {
+		t.Fatalf("expected option-like revision to be rejected")
+	}
+}
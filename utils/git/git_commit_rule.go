@@ -0,0 +1,106 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// ErrCommitMessageInvalid 提交消息不符合配置的规范
+var ErrCommitMessageInvalid = errors.New("commit message does not match the required format")
+
+// commitTemplateMaxBytes 读取 commit.template 文件的上限
+const commitTemplateMaxBytes = 64 * 1024
+
+// CommitMessageRule validates commit messages against a configured pattern,
+// e.g. conventional commits `^(feat|fix|docs|refactor|test|chore)(\(.+\))?!?: .+`.
+type CommitMessageRule struct {
+	pattern *regexp.Regexp
+	hint    string
+}
+
+// NewCommitMessageRule compiles pattern; an empty pattern returns a nil rule,
+// which accepts every message.
+func NewCommitMessageRule(pattern, hint string) (*CommitMessageRule, error) {
+	pattern = strings.TrimSpace(pattern)
+	if pattern == "" {
+		return nil, nil
+	}
+	compiled, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid commit message pattern: %w", err)
+	}
+	return &CommitMessageRule{pattern: compiled, hint: strings.TrimSpace(hint)}, nil
+}
+
+// Pattern returns the configured pattern, empty for a nil rule.
+func (r *CommitMessageRule) Pattern() string {
+	if r == nil {
+		return ""
+	}
+	return r.pattern.String()
+}
+
+// Hint returns the message shown when validation fails.
+func (r *CommitMessageRule) Hint() string {
+	if r == nil {
+		return ""
+	}
+	return r.hint
+}
+
+// Validate checks the whole trimmed message; the pattern can use (?m) to match per line.
+func (r *CommitMessageRule) Validate(message string) error {
+	if r == nil {
+		return nil
+	}
+	if r.pattern.MatchString(strings.TrimSpace(message)) {
+		return nil
+	}
+	if r.hint != "" {
+		return fmt.Errorf("%w: %s", ErrCommitMessageInvalid, r.hint)
+	}
+	return fmt.Errorf("%w: %s", ErrCommitMessageInvalid, r.pattern.String())
+}
+
+// CommitTemplate reads the file configured as commit.template for the
+// repository, returning an empty string when none is configured.
+func (r *GitRepo) CommitTemplate() (string, error) {
+	if r == nil {
+		return "", errors.New("git repository is not initialized")
+	}
+	output, err := newGitCommand(r.Path, "config", "--path", "--get", "commit.template").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		// 未配置时 git config 以 1 退出
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		return "", err
+	}
+	path := strings.TrimSpace(string(output))
+	if path == "" {
+		return "", nil
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(r.Path, path)
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	defer file.Close()
+	content, err := io.ReadAll(io.LimitReader(file, commitTemplateMaxBytes))
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
@@ -0,0 +1,57 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCommitMessageRule(t *testing.T) {
+	rule, err := NewCommitMessageRule("", "")
+	if err != nil || rule != nil {
+		t.Fatalf("expected nil rule for empty pattern, got %v, %v", rule, err)
+	}
+	if err := rule.Validate("anything goes"); err != nil {
+		t.Fatalf("nil rule must accept every message, got %v", err)
+	}
+
+	if _, err := NewCommitMessageRule("(feat", ""); err == nil {
+		t.Fatalf("expected invalid pattern error")
+	}
+
+	rule, err = NewCommitMessageRule(`^(feat|fix|docs|chore)(\([\w-]+\))?!?: \S`, "use conventional commits, e.g. feat(api): add route")
+	if err != nil {
+		t.Fatalf("NewCommitMessageRule failed: %v", err)
+	}
+	for _, message := range []string{"feat: add route", "fix(api)!: drop legacy field\n\nbody", "  chore: trim\n"} {
+		if err := rule.Validate(message); err != nil {
+			t.Fatalf("expected %q to pass, got %v", message, err)
+		}
+	}
+	err = rule.Validate("update stuff")
+	if !errors.Is(err, ErrCommitMessageInvalid) || !strings.Contains(err.Error(), "conventional commits") {
+		t.Fatalf("expected ErrCommitMessageInvalid with hint, got %v", err)
+	}
+}
+
+func TestCommitTemplate(t *testing.T) {
+	repoDir := initTestRepo(t)
+	repo, err := DetectRepository(repoDir)
+	if err != nil {
+		t.Fatalf("DetectRepository returned error: %v", err)
+	}
+	if template, err := repo.CommitTemplate(); err != nil || template != "" {
+		t.Fatalf("expected empty template, got %q, %v", template, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(repoDir, ".gitmessage"), []byte("feat: \n\n# why\n"), 0o644); err != nil {
+		t.Fatalf("write template: %v", err)
+	}
+	runGit(t, repoDir, "config", "commit.template", ".gitmessage")
+	template, err := repo.CommitTemplate()
+	if err != nil || template != "feat: \n\n# why\n" {
+		t.Fatalf("unexpected template %q, %v", template, err)
+	}
+}
@@ -0,0 +1,44 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCommitSigning(t *testing.T) {
+	repoDir := initTestRepo(t)
+	runGit(t, repoDir, "config", "user.name", "Test User")
+	runGit(t, repoDir, "config", "user.email", "test@example.com")
+	runGit(t, repoDir, "config", "gpg.program", filepath.Join(repoDir, "missing-gpg"))
+
+	repo, err := DetectRepository(repoDir)
+	if err != nil {
+		t.Fatalf("DetectRepository returned error: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(repoDir, "signed.txt"), []byte("signed"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := repo.AddAll(repoDir); err != nil {
+		t.Fatalf("AddAll failed: %v", err)
+	}
+	if err := repo.Commit(repoDir, "feat: signed", true); !errors.Is(err, ErrCommitSigningFailed) {
+		t.Fatalf("expected ErrCommitSigningFailed, got %v", err)
+	}
+
+	if err := repo.Commit(repoDir, "feat: unsigned", false); err != nil {
+		t.Fatalf("unsigned commit failed: %v", err)
+	}
+	detail, err := GetCommitDetail(repoDir, "HEAD")
+	if err != nil {
+		t.Fatalf("GetCommitDetail failed: %v", err)
+	}
+	if detail.Subject != "feat: unsigned" {
+		t.Fatalf("unexpected commit subject %q", detail.Subject)
+	}
+	if detail.SignatureStatus != "N" || detail.SignatureValid {
+		t.Fatalf("expected unsigned commit, got %+v", detail)
+	}
+}
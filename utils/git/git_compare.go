@@ -0,0 +1,132 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// ErrRefNotFound 分支或提交不存在
+var ErrRefNotFound = errors.New("git ref not found")
+
+// compareCommitsMax 比较分支时每侧列出的提交上限，Ahead/Behind 仍为完整数量
+const compareCommitsMax = 200
+
+// BranchComparison describes what merging Head into Base would bring in.
+type BranchComparison struct {
+	Base       string `json:"base"`
+	Head       string `json:"head"`
+	BaseCommit string `json:"baseCommit"`
+	HeadCommit string `json:"headCommit"`
+	// MergeBase 两者的共同祖先，历史不相关时为空，此时文件差异直接比较两端
+	MergeBase string `json:"mergeBase,omitempty"`
+	Ahead     int    `json:"ahead"`
+	Behind    int    `json:"behind"`
+	// AheadCommits Head 上有而 Base 上没有的提交，BehindCommits 反之；均为最新在前，最多 200 条
+	AheadCommits  []CommitInfo `json:"aheadCommits"`
+	BehindCommits []CommitInfo `json:"behindCommits"`
+	// Files 为 git diff base...head --numstat，即 Head 相对共同祖先的改动
+	Files     []DiffFileStat `json:"files"`
+	Additions int            `json:"additions"`
+	Deletions int            `json:"deletions"`
+}
+
+// ResolveCommit returns the full commit SHA ref points to, wrapping
+// ErrRefNotFound when it does not name a commit.
+func ResolveCommit(path, ref string) (string, error) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" {
+		return "", errors.New("ref is required")
+	}
+	if strings.HasPrefix(ref, "-") {
+		return "", fmt.Errorf("invalid ref %q", ref)
+	}
+	output, err := newGitCommand(path, "rev-parse", "--verify", "--quiet", ref+"^{commit}").Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", fmt.Errorf("%w: %s", ErrRefNotFound, ref)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// CompareBranches compares head against base: the commits on either side that
+// the other lacks and the per-file stats of git diff base...head.
+func CompareBranches(path, base, head string) (*BranchComparison, error) {
+	baseCommit, err := ResolveCommit(path, base)
+	if err != nil {
+		return nil, err
+	}
+	headCommit, err := ResolveCommit(path, head)
+	if err != nil {
+		return nil, err
+	}
+	result := &BranchComparison{
+		Base:       strings.TrimSpace(base),
+		Head:       strings.TrimSpace(head),
+		BaseCommit: baseCommit,
+		HeadCommit: headCommit,
+	}
+
+	// 历史不相关时 merge-base 以 1 退出，不视为错误
+	if output, err := newGitCommand(path, "merge-base", baseCommit, headCommit).Output(); err == nil {
+		result.MergeBase = strings.TrimSpace(string(output))
+	}
+
+	counts, err := runGitOutput(path, "rev-list", "--left-right", "--count", baseCommit+"..."+headCommit)
+	if err != nil {
+		return nil, err
+	}
+	if fields := strings.Fields(counts); len(fields) == 2 {
+		result.Behind, _ = strconv.Atoi(fields[0])
+		result.Ahead, _ = strconv.Atoi(fields[1])
+	}
+
+	if result.AheadCommits, err = listRangeCommits(path, baseCommit, headCommit); err != nil {
+		return nil, err
+	}
+	if result.BehindCommits, err = listRangeCommits(path, headCommit, baseCommit); err != nil {
+		return nil, err
+	}
+
+	diffRange := baseCommit + "..." + headCommit
+	if result.MergeBase == "" {
+		diffRange = baseCommit + ".." + headCommit
+	}
+	numstat, err := runGitOutput(path, "diff", "--numstat", "-z", "-M", diffRange, "--")
+	if err != nil {
+		return nil, err
+	}
+	result.Files = parseNumstatOutput(numstat)
+	for _, file := range result.Files {
+		result.Additions += file.Additions
+		result.Deletions += file.Deletions
+	}
+	return result, nil
+}
+
+// listRangeCommits lists commits reachable from to but not from, newest first.
+func listRangeCommits(path, from, to string) ([]CommitInfo, error) {
+	output, err := runGitOutput(path, "log", "-n", strconv.Itoa(compareCommitsMax),
+		"--pretty=format:%H%x00%an%x00%ad%x00%s%x1e", "--date=iso-strict", from+".."+to, "--")
+	if err != nil {
+		return nil, err
+	}
+	return parseCommitRecords(output), nil
+}
+
+func runGitOutput(path string, args ...string) (string, error) {
+	output, err := newGitCommand(path, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return "", gitFailure("git "+args[0], string(exitErr.Stderr))
+		}
+		return "", err
+	}
+	return string(output), nil
+}
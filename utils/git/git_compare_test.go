@@ -0,0 +1,56 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompareBranches(t *testing.T) {
+	dir := initTestRepo(t)
+	commitFile := func(name, content, message string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+		runGit(t, dir, "add", name)
+		runGit(t, dir, "commit", "-m", message)
+	}
+
+	runGit(t, dir, "checkout", "-b", "feature")
+	commitFile("feature.txt", "one\ntwo\n", "add feature")
+	commitFile("README.md", "# Test Repo\nmore\n", "update readme")
+	runGit(t, dir, "checkout", "main")
+	commitFile("main.txt", "main\n", "main only")
+
+	result, err := CompareBranches(dir, "main", "feature")
+	if err != nil {
+		t.Fatalf("CompareBranches: %v", err)
+	}
+	if result.Ahead != 2 || result.Behind != 1 || result.MergeBase == "" {
+		t.Fatalf("unexpected counts %+v", result)
+	}
+	if len(result.AheadCommits) != 2 || result.AheadCommits[0].Message != "update readme" {
+		t.Fatalf("unexpected ahead commits %+v", result.AheadCommits)
+	}
+	if len(result.BehindCommits) != 1 || result.BehindCommits[0].Message != "main only" {
+		t.Fatalf("unexpected behind commits %+v", result.BehindCommits)
+	}
+	// 三点 diff 只包含 feature 上的改动，不含 main 新增的文件
+	if len(result.Files) != 2 || result.Additions != 3 {
+		t.Fatalf("unexpected diff stats %+v", result.Files)
+	}
+	for _, file := range result.Files {
+		if file.Path == "main.txt" {
+			t.Fatalf("diff should not include changes only on base: %+v", result.Files)
+		}
+	}
+
+	if _, err := CompareBranches(dir, "main", "missing"); !errors.Is(err, ErrRefNotFound) {
+		t.Fatalf("expected ErrRefNotFound, got %v", err)
+	}
+	if _, err := CompareBranches(dir, "--output=x", "main"); err == nil {
+		t.Fatalf("expected option-like ref to be rejected")
+	}
+}
@@ -0,0 +1,173 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"os/exec"
+	"regexp"
+	"strings"
+)
+
+var (
+	// ErrInvalidConfigKey 配置项名称不符合 section[.subsection].name 格式
+	ErrInvalidConfigKey = errors.New("git config key is invalid")
+	// ErrInvalidConfigValue 配置值包含换行或不在常用配置项允许的取值内
+	ErrInvalidConfigValue = errors.New("git config value is invalid")
+	// ErrConfigKeyNotAllowed 只能写入常用配置项，core.fsmonitor、alias.* 等可执行命令的配置一律拒绝
+	ErrConfigKeyNotAllowed = errors.New("git config key is not allowed")
+)
+
+// redactedConfigValue 替换凭据类配置值的占位符
+const redactedConfigValue = "******"
+
+// configKeyPattern 对应 git 的规则：section 与变量名以字母开头，只含字母数字和 -，
+// 中间的 subsection 可以是除换行外的任意字符
+var configKeyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9-]*(\.[^\n\x00]+)?\.[A-Za-z][A-Za-z0-9-]*$`)
+
+// ConfigKey describes a commonly edited git config key.
+type ConfigKey struct {
+	Key string `json:"key"`
+	// Values 非空时只能取其中的值，前端据此渲染为下拉框
+	Values []string `json:"values,omitempty"`
+}
+
+var commonConfigKeys = []ConfigKey{
+	{Key: "user.name"},
+	{Key: "user.email"},
+	{Key: "core.autocrlf", Values: []string{"true", "false", "input"}},
+	{Key: "core.filemode", Values: []string{"true", "false"}},
+	{Key: "pull.rebase", Values: []string{"true", "false", "merges"}},
+	{Key: "push.default", Values: []string{"simple", "current", "upstream", "matching", "nothing"}},
+	{Key: "fetch.prune", Values: []string{"true", "false"}},
+	{Key: "rebase.autostash", Values: []string{"true", "false"}},
+	{Key: "merge.ff", Values: []string{"true", "false", "only"}},
+}
+
+// CommonConfigKeys returns the config keys a form can offer for editing.
+func CommonConfigKeys() []ConfigKey {
+	keys := make([]ConfigKey, len(commonConfigKeys))
+	for i, key := range commonConfigKeys {
+		keys[i] = ConfigKey{Key: key.Key, Values: append([]string(nil), key.Values...)}
+	}
+	return keys
+}
+
+// ValidateConfigKey checks that key has the section[.subsection].name form git accepts.
+func ValidateConfigKey(key string) error {
+	if !configKeyPattern.MatchString(key) {
+		return fmt.Errorf("%w: %q", ErrInvalidConfigKey, key)
+	}
+	return nil
+}
+
+// ValidateConfigKeyAllowed checks the key format and that key is one of the
+// common keys. Other keys are refused because many of them (core.fsmonitor,
+// core.sshCommand, alias.*, filter.*) make git run commands, and git runs in the
+// background on every status refresh.
+func ValidateConfigKeyAllowed(key string) error {
+	if err := ValidateConfigKey(key); err != nil {
+		return err
+	}
+	name := strings.ToLower(key)
+	for _, common := range commonConfigKeys {
+		if common.Key == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %q", ErrConfigKeyNotAllowed, key)
+}
+
+// ValidateConfigValue rejects values git cannot store on one line and values
+// outside the choices of a common key.
+func ValidateConfigValue(key, value string) error {
+	if strings.ContainsAny(value, "\n\x00") {
+		return fmt.Errorf("%w: value of %s must be a single line", ErrInvalidConfigValue, key)
+	}
+	name := strings.ToLower(key)
+	for _, common := range commonConfigKeys {
+		if common.Key != name || len(common.Values) == 0 {
+			continue
+		}
+		for _, allowed := range common.Values {
+			if strings.EqualFold(value, allowed) {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: %s must be one of %s", ErrInvalidConfigValue, key, strings.Join(common.Values, ", "))
+	}
+	return nil
+}
+
+// ListConfig returns the repository-local config (.git/config) with lower-cased
+// keys. Keys with several values keep the last one, like ConfigValue. Remote
+// URLs carrying credentials and *.extraheader values are redacted.
+func (r *GitRepo) ListConfig() (map[string]string, error) {
+	if r == nil {
+		return nil, errors.New("git repository is not initialized")
+	}
+	output, err := newGitCommand(r.Path, "config", "--local", "--list", "-z").Output()
+	if err != nil {
+		// 没有任何本地配置时 git config --list 返回 1，其他失败照常报错
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return map[string]string{}, nil
+		}
+		message := err.Error()
+		if exitErr != nil && len(exitErr.Stderr) > 0 {
+			message = string(exitErr.Stderr)
+		}
+		return nil, gitFailure("list config", message)
+	}
+
+	entries := make(map[string]string)
+	for _, entry := range strings.Split(string(output), "\x00") {
+		if entry == "" {
+			continue
+		}
+		// -z 模式下名称与值以换行分隔，没有值的布尔项只有名称
+		key, value, _ := strings.Cut(entry, "\n")
+		key = strings.ToLower(key)
+		entries[key] = redactConfigValue(key, value)
+	}
+	return entries, nil
+}
+
+// redactConfigValue hides credentials: extra HTTP headers usually carry tokens,
+// and URLs may embed user:password.
+func redactConfigValue(key, value string) string {
+	if strings.HasSuffix(key, ".extraheader") {
+		return redactedConfigValue
+	}
+	if strings.HasSuffix(key, "url") && strings.Contains(value, "@") {
+		if parsed, err := url.Parse(value); err == nil && parsed.User != nil {
+			// 用户名本身也可能是 token，整段 userinfo 都隐去
+			parsed.User = nil
+			return strings.Replace(parsed.String(), "://", "://"+redactedConfigValue+"@", 1)
+		}
+	}
+	return value
+}
+
+// SetConfig writes one of the common keys to the repository-local config after
+// validating its value.
+func (r *GitRepo) SetConfig(key, value string) error {
+	if r == nil {
+		return errors.New("git repository is not initialized")
+	}
+	key = strings.TrimSpace(key)
+	if err := ValidateConfigKeyAllowed(key); err != nil {
+		return err
+	}
+	if err := ValidateConfigValue(key, value); err != nil {
+		return err
+	}
+
+	if output, err := newGitCommand(r.Path, "config", "--local", key, value).CombinedOutput(); err != nil {
+		return gitFailure("set config", string(output))
+	}
+	if r.Config != nil {
+		r.Config[strings.ToLower(key)] = value
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package git
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+type credentialKey struct{}
+
+// WithCredential attaches a token to ctx; git commands started with the
+// returned context send it to the HTTP remotes configured in the repository
+// they run in, through http.<remote url>.extraHeader, so redirects and other
+// hosts never see it. The header
+// is passed with GIT_CONFIG_* environment variables, so the token never reaches
+// the command line, the repository config or the remote URL, and nothing needs
+// cleaning up afterwards. A token in user:password form is sent as Basic auth,
+// any other token as a Bearer token. An empty token leaves ctx unchanged.
+func WithCredential(ctx context.Context, token string) context.Context {
+	token = strings.TrimSpace(token)
+	if token == "" {
+		return ctx
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return context.WithValue(ctx, credentialKey{}, token)
+}
+
+func credentialFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	token, _ := ctx.Value(credentialKey{}).(string)
+	return token
+}
+
+// credentialHeader builds the Authorization header for token.
+func credentialHeader(token string) string {
+	if strings.Contains(token, ":") {
+		return "Authorization: Basic " + base64.StdEncoding.EncodeToString([]byte(token))
+	}
+	return "Authorization: Bearer " + token
+}
+
+// credentialRemoteURLs lists the HTTP(S) remote URLs of the repository at dir,
+// without userinfo so the scoped header matches whatever user the URL carries.
+func credentialRemoteURLs(dir string) []string {
+	if dir == "" {
+		return nil
+	}
+	output, err := newGitCommand(dir, "config", "--get-regexp", `^remote\..*\.(url|pushurl)$`).Output()
+	if err != nil {
+		return nil
+	}
+	var urls []string
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(string(output), "\n") {
+		_, raw, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		parsed, err := url.Parse(strings.TrimSpace(raw))
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			continue
+		}
+		parsed.User = nil
+		if scoped := parsed.String(); !seen[scoped] {
+			seen[scoped] = true
+			urls = append(urls, scoped)
+		}
+	}
+	return urls
+}
+
+// credentialEnv returns the variables injecting the header of token for each of
+// urls, appended after the config entries env may already pass through
+// GIT_CONFIG_COUNT. No urls means no variables.
+func credentialEnv(env []string, token string, urls []string) []string {
+	if len(urls) == 0 {
+		return nil
+	}
+	index := 0
+	for _, entry := range env {
+		if value, ok := strings.CutPrefix(entry, "GIT_CONFIG_COUNT="); ok {
+			// 重复的变量以最后一个为准
+			index, _ = strconv.Atoi(value)
+			index = max(index, 0)
+		}
+	}
+	result := []string{"GIT_CONFIG_COUNT=" + strconv.Itoa(index+len(urls))}
+	header := credentialHeader(token)
+	for i, remoteURL := range urls {
+		suffix := strconv.Itoa(index + i)
+		result = append(result,
+			"GIT_CONFIG_KEY_"+suffix+"=http."+remoteURL+".extraHeader",
+			"GIT_CONFIG_VALUE_"+suffix+"="+header,
+		)
+	}
+	return result
+}
@@ -0,0 +1,64 @@
+package git
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCredentialHeader(t *testing.T) {
+	if got := credentialHeader("abc123"); got != "Authorization: Bearer abc123" {
+		t.Fatalf("unexpected bearer header %q", got)
+	}
+	// base64("oauth2:abc") = b2F1dGgyOmFiYw==
+	if got := credentialHeader("oauth2:abc"); got != "Authorization: Basic b2F1dGgyOmFiYw==" {
+		t.Fatalf("unexpected basic header %q", got)
+	}
+}
+
+func TestCredentialEnvKeepsExistingConfig(t *testing.T) {
+	env := credentialEnv([]string{"GIT_CONFIG_COUNT=2", "GIT_CONFIG_KEY_0=a.b"}, "t", []string{"https://a.example.com/r.git", "https://b.example.com/r.git"})
+	want := []string{
+		"GIT_CONFIG_COUNT=4",
+		"GIT_CONFIG_KEY_2=http.https://a.example.com/r.git.extraHeader", "GIT_CONFIG_VALUE_2=Authorization: Bearer t",
+		"GIT_CONFIG_KEY_3=http.https://b.example.com/r.git.extraHeader", "GIT_CONFIG_VALUE_3=Authorization: Bearer t",
+	}
+	if strings.Join(env, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected env %v", env)
+	}
+	if env := credentialEnv(nil, "t", nil); env != nil {
+		t.Fatalf("expected no env without http remotes, got %v", env)
+	}
+}
+
+func TestWithCredentialInjectsExtraHeader(t *testing.T) {
+	SetTestEnvOverride(testGitEnv())
+	t.Cleanup(func() { SetTestEnvOverride(nil) })
+
+	repoDir := initTestRepo(t)
+	runGit(t, repoDir, "remote", "add", "internal", "https://bot@git.internal.example.com/team/repo.git")
+	ctx := WithCredential(context.Background(), "secret-token")
+	cmd := newGitCommandContext(ctx, repoDir, "config", "--get-urlmatch", "http.extraHeader", "https://git.internal.example.com/team/repo.git/info/refs")
+	output, err := cmd.Output()
+	if err != nil {
+		t.Fatalf("git config failed: %v", err)
+	}
+	if got := strings.TrimSpace(string(output)); got != "Authorization: Bearer secret-token" {
+		t.Fatalf("unexpected extra header %q", got)
+	}
+	// 其他主机拿不到凭据
+	other := newGitCommandContext(ctx, repoDir, "config", "--get-urlmatch", "http.extraHeader", "https://evil.example.com/team/repo.git")
+	if output, err := other.Output(); err == nil {
+		t.Fatalf("credential leaked to another host: %q", output)
+	}
+	for _, arg := range cmd.Args {
+		if strings.Contains(arg, "secret-token") {
+			t.Fatalf("token leaked into command line: %v", cmd.Args)
+		}
+	}
+
+	// 没有凭据的命令不带该配置
+	if output, err := newGitCommandContext(context.Background(), repoDir, "config", "--get-urlmatch", "http.extraHeader", "https://git.internal.example.com/team/repo.git").Output(); err == nil {
+		t.Fatalf("expected no extra header without credential, got %q", output)
+	}
+}
@@ -0,0 +1,127 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DiffFileStat 是 git diff --numstat 中单个文件的增删行数，二进制文件没有行数
+type DiffFileStat struct {
+	Path      string `json:"path"`
+	OldPath   string `json:"oldPath,omitempty"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+	Binary    bool   `json:"binary,omitempty"`
+}
+
+// DiffExtensionStat aggregates diff stats of all files sharing an extension.
+type DiffExtensionStat struct {
+	Extension string `json:"extension"`
+	Files     int    `json:"files"`
+	Additions int    `json:"additions"`
+	Deletions int    `json:"deletions"`
+}
+
+// DiffSummary 按扩展名聚合的 diff 统计
+type DiffSummary struct {
+	Base        string              `json:"base"`
+	Files       int                 `json:"files"`
+	Additions   int                 `json:"additions"`
+	Deletions   int                 `json:"deletions"`
+	BinaryFiles int                 `json:"binaryFiles"`
+	Extensions  []DiffExtensionStat `json:"extensions"`
+}
+
+// DiffNumstat returns per-file line stats of the working tree at path against base
+// (HEAD when empty), covering both staged and unstaged changes of tracked files.
+func DiffNumstat(path, base string) ([]DiffFileStat, error) {
+	base = strings.TrimSpace(base)
+	if base == "" {
+		base = "HEAD"
+	}
+	if strings.HasPrefix(base, "-") {
+		return nil, fmt.Errorf("invalid diff base %q", base)
+	}
+	cmd := newGitCommand(path, "diff", "--numstat", "-z", base, "--")
+	// stderr 可能带有换行符转换等警告，不能混入解析结果
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git diff --numstat failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return parseNumstatOutput(string(output)), nil
+}
+
+// parseNumstatOutput parses `git diff --numstat -z`. Renamed files are written as
+// "added\tdeleted\t\0old\0new\0", other files as "added\tdeleted\tpath\0".
+func parseNumstatOutput(output string) []DiffFileStat {
+	tokens := strings.Split(output, "\x00")
+	stats := make([]DiffFileStat, 0)
+	for i := 0; i < len(tokens); i++ {
+		fields := strings.SplitN(tokens[i], "\t", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		stat := DiffFileStat{Path: fields[2]}
+		if fields[0] == "-" && fields[1] == "-" {
+			stat.Binary = true
+		} else {
+			stat.Additions, _ = strconv.Atoi(fields[0])
+			stat.Deletions, _ = strconv.Atoi(fields[1])
+		}
+		if stat.Path == "" {
+			if i+2 >= len(tokens) {
+				break
+			}
+			stat.OldPath = tokens[i+1]
+			stat.Path = tokens[i+2]
+			i += 2
+		}
+		stats = append(stats, stat)
+	}
+	return stats
+}
+
+// SummarizeDiff groups file stats by lower-cased extension, ordered by changed
+// lines descending. Files without an extension are grouped under "".
+func SummarizeDiff(base string, stats []DiffFileStat) *DiffSummary {
+	summary := &DiffSummary{Base: base, Extensions: make([]DiffExtensionStat, 0)}
+	index := make(map[string]int)
+	for _, stat := range stats {
+		summary.Files++
+		summary.Additions += stat.Additions
+		summary.Deletions += stat.Deletions
+		if stat.Binary {
+			summary.BinaryFiles++
+		}
+
+		ext := strings.ToLower(filepath.Ext(stat.Path))
+		pos, ok := index[ext]
+		if !ok {
+			pos = len(summary.Extensions)
+			index[ext] = pos
+			summary.Extensions = append(summary.Extensions, DiffExtensionStat{Extension: ext})
+		}
+		summary.Extensions[pos].Files++
+		summary.Extensions[pos].Additions += stat.Additions
+		summary.Extensions[pos].Deletions += stat.Deletions
+	}
+
+	sort.SliceStable(summary.Extensions, func(i, j int) bool {
+		left := summary.Extensions[i].Additions + summary.Extensions[i].Deletions
+		right := summary.Extensions[j].Additions + summary.Extensions[j].Deletions
+		if left != right {
+			return left > right
+		}
+		return summary.Extensions[i].Extension < summary.Extensions[j].Extension
+	})
+	return summary
+}
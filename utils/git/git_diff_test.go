@@ -0,0 +1,53 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseNumstatOutput(t *testing.T) {
+	output := "10\t2\tmain.go\x00-\t-\tlogo.png\x003\t1\t\x00old.md\x00docs/new.md\x00"
+	stats := parseNumstatOutput(output)
+	if len(stats) != 3 {
+		t.Fatalf("expected 3 stats, got %+v", stats)
+	}
+	if stats[0] != (DiffFileStat{Path: "main.go", Additions: 10, Deletions: 2}) {
+		t.Fatalf("unexpected stat %+v", stats[0])
+	}
+	if !stats[1].Binary || stats[1].Path != "logo.png" {
+		t.Fatalf("expected binary stat, got %+v", stats[1])
+	}
+	if stats[2].OldPath != "old.md" || stats[2].Path != "docs/new.md" || stats[2].Additions != 3 {
+		t.Fatalf("unexpected rename stat %+v", stats[2])
+	}
+}
+
+func TestDiffNumstatSummary(t *testing.T) {
+	repoDir := initTestRepo(t)
+	runGit(t, repoDir, "config", "core.autocrlf", "false")
+
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Test Repo\nmore\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "main.go"), []byte("package main\n\nfunc main() {}\n"), 0o644); err != nil {
+		t.Fatalf("write main.go: %v", err)
+	}
+	runGit(t, repoDir, "add", "main.go")
+
+	stats, err := DiffNumstat(repoDir, "")
+	if err != nil {
+		t.Fatalf("DiffNumstat failed: %v", err)
+	}
+	summary := SummarizeDiff("HEAD", stats)
+	if summary.Files != 2 || summary.Additions != 4 || summary.Deletions != 0 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	if len(summary.Extensions) != 2 || summary.Extensions[0].Extension != ".go" || summary.Extensions[0].Additions != 3 {
+		t.Fatalf("unexpected extension stats %+v", summary.Extensions)
+	}
+
+	if _, err := DiffNumstat(repoDir, "--output=x"); err == nil {
+		t.Fatalf("expected option-like base to be rejected")
+	}
+}
@@ -0,0 +1,112 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// git 命令失败的分类，命令输出命中对应特征时错误会包装这些哨兵，调用方用 errors.Is 判断
+var (
+	// ErrAuthFailed 远端拒绝或缺少凭据
+	ErrAuthFailed = errors.New("git authentication failed")
+	// ErrRepositoryLocked 另一个 git 进程持有 index.lock 等锁文件
+	ErrRepositoryLocked = errors.New("git repository is locked by another process")
+	// ErrNonFastForward 远端或本地分支已分叉，无法快进
+	ErrNonFastForward = errors.New("update is not a fast-forward")
+	// ErrMergeConflict 合并、cherry-pick 等操作因冲突停止
+	ErrMergeConflict = errors.New("git operation stopped on conflicts")
+	// ErrNetwork 无法连接远端
+	ErrNetwork = errors.New("git remote is unreachable")
+)
+
+// gitFailureMarkers 按顺序匹配小写后的命令输出，靠前的分类优先：
+// 例如 ssh 认证失败同时会输出 "could not read from remote repository"
+var gitFailureMarkers = []struct {
+	kind    error
+	markers []string
+}{
+	{ErrAuthFailed, []string{
+		"could not read username",
+		"could not read password",
+		"authentication failed",
+		"invalid username or password",
+		"permission denied (publickey",
+		"terminal prompts disabled",
+		"http basic: access denied",
+		"the requested url returned error: 401",
+		"the requested url returned error: 403",
+	}},
+	{ErrRepositoryLocked, []string{
+		"index.lock",
+		".lock': file exists",
+		"another git process seems to be running",
+	}},
+	{ErrNonFastForward, []string{
+		"non-fast-forward",
+		"(fetch first)",
+		"not possible to fast-forward",
+		"diverging branches can't be fast-forwarded",
+	}},
+	{ErrMergeConflict, []string{
+		"conflict (",
+		"automatic merge failed",
+		"could not apply",
+		"you need to resolve your current index first",
+		"needs merge",
+	}},
+	{ErrNetwork, []string{
+		"could not resolve host",
+		"could not resolve hostname",
+		"failed to connect to",
+		"connection refused",
+		"connection timed out",
+		"operation timed out",
+		"network is unreachable",
+		"the remote end hung up unexpectedly",
+		"could not read from remote repository",
+	}},
+}
+
+// classifyGitOutput returns the failure sentinel matching the output of a
+// failed git command, or nil when the failure is not recognised.
+func classifyGitOutput(output string) error {
+	lower := strings.ToLower(output)
+	for _, group := range gitFailureMarkers {
+		for _, marker := range group.markers {
+			if strings.Contains(lower, marker) {
+				return group.kind
+			}
+		}
+	}
+	return nil
+}
+
+// gitFailure builds the error of a failed git command as "<action> failed: <output>",
+// wrapping the classification sentinel when the output matches one.
+func gitFailure(action, output string) error {
+	message := strings.TrimSpace(output)
+	if kind := classifyGitOutput(message); kind != nil {
+		return fmt.Errorf("%s failed: %w: %s", action, kind, message)
+	}
+	return fmt.Errorf("%s failed: %s", action, message)
+}
+
+// FailureKind returns a short name for the classification of err: auth, locked,
+// non_fast_forward, conflict or network, and "" for other errors.
+func FailureKind(err error) string {
+	switch {
+	case errors.Is(err, ErrAuthFailed):
+		return "auth"
+	case errors.Is(err, ErrRepositoryLocked):
+		return "locked"
+	case errors.Is(err, ErrNonFastForward):
+		return "non_fast_forward"
+	case errors.Is(err, ErrMergeConflict), errors.Is(err, ErrRebaseConflict):
+		return "conflict"
+	case errors.Is(err, ErrNetwork):
+		return "network"
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,90 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestClassifyGitOutput(t *testing.T) {
+	cases := []struct {
+		output string
+		want   error
+	}{
+		{"fatal: could not read Username for 'https://github.com': terminal prompts disabled", ErrAuthFailed},
+		{"git@github.com: Permission denied (publickey).\nfatal: Could not read from remote repository.", ErrAuthFailed},
+		{"fatal: Unable to create '/repo/.git/index.lock': File exists.", ErrRepositoryLocked},
+		{" ! [rejected]        main -> main (non-fast-forward)", ErrNonFastForward},
+		{"fatal: Not possible to fast-forward, aborting.", ErrNonFastForward},
+		{"CONFLICT (content): Merge conflict in a.txt\nAutomatic merge failed; fix conflicts and then commit the result.", ErrMergeConflict},
+		{"fatal: unable to access 'https://example.invalid/': Could not resolve host: example.invalid", ErrNetwork},
+		{"fatal: not a git repository", nil},
+	}
+	for _, tc := range cases {
+		if got := classifyGitOutput(tc.output); got != tc.want {
+			t.Fatalf("classifyGitOutput(%q) = %v, want %v", tc.output, got, tc.want)
+		}
+	}
+
+	err := gitFailure("git push", "fatal: could not read Username")
+	if !errors.Is(err, ErrAuthFailed) || FailureKind(err) != "auth" {
+		t.Fatalf("expected auth failure, got %v", err)
+	}
+	if err := gitFailure("git status", "fatal: bad object"); FailureKind(err) != "" {
+		t.Fatalf("expected unclassified failure, got %v", err)
+	}
+}
+
+func TestGitFailureLockedAndConflict(t *testing.T) {
+	SetTestEnvOverride(testGitEnv())
+	t.Cleanup(func() { SetTestEnvOverride(nil) })
+	dir := initTestRepo(t)
+	repo, err := DetectRepository(dir)
+	if err != nil {
+		t.Fatalf("DetectRepository: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("changed\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	lock := filepath.Join(dir, ".git", "index.lock")
+	if err := os.WriteFile(lock, nil, 0o644); err != nil {
+		t.Fatalf("create index.lock: %v", err)
+	}
+	if err := repo.StageFiles(dir, []string{"README.md"}); !errors.Is(err, ErrRepositoryLocked) {
+		t.Fatalf("expected ErrRepositoryLocked, got %v", err)
+	}
+	if err := os.Remove(lock); err != nil {
+		t.Fatalf("remove index.lock: %v", err)
+	}
+
+	runGit(t, dir, "checkout", "-q", "-b", "feature")
+	runGit(t, dir, "commit", "-q", "-am", "feature change")
+	runGit(t, dir, "checkout", "-q", "main")
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("main change\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, dir, "commit", "-q", "-am", "main change")
+	if err := repo.MergeBranch(dir, "feature", MergeStrategyMerge); !errors.Is(err, ErrMergeConflict) {
+		t.Fatalf("expected ErrMergeConflict, got %v", err)
+	}
+}
+
+// git 的错误输出按英文识别，用户 locale 不能影响命令的输出语言
+func TestGitCommandUsesCLocale(t *testing.T) {
+	SetTestEnvOverride([]string{"LANG=de_DE.UTF-8", "LC_MESSAGES=de_DE.UTF-8"})
+	defer SetTestEnvOverride(nil)
+
+	cmd := newGitCommand("", "version")
+	locale := ""
+	for _, entry := range cmd.Env {
+		if value, ok := strings.CutPrefix(entry, "LC_ALL="); ok {
+			locale = value
+		}
+	}
+	if locale != "C" {
+		t.Fatalf("expected LC_ALL=C, got %q in %v", locale, cmd.Env)
+	}
+}
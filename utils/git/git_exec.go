@@ -1,6 +1,7 @@
 package git
 
 import (
+	"context"
 	"os"
 	"os/exec"
 	"sync"
@@ -15,6 +16,9 @@ var (
 func buildGitCommandEnv() []string {
 	env := os.Environ()
 	env = append(env,
+		// 错误分类、worktree prune 输出与 reflog 的 unknown revision 都按英文 stderr 识别，
+		// 固定为 C locale，避免本地化的 git 输出导致识别失败
+		"LC_ALL=C",
 		"GIT_TERMINAL_PROMPT=0",
 		"GIT_MERGE_AUTOEDIT=no",
 		"GIT_ASKPASS=",
@@ -32,7 +36,12 @@ func SetTestEnvOverride(env []string) {
 }
 
 func newGitCommand(dir string, args ...string) *exec.Cmd {
-	cmd := exec.Command("git", args...)
+	return newGitCommandContext(context.Background(), dir, args...)
+}
+
+// newGitCommandContext builds a git command that is killed when ctx is cancelled.
+func newGitCommandContext(ctx context.Context, dir string, args ...string) *exec.Cmd {
+	cmd := exec.CommandContext(ctx, "git", args...)
 	cmd.Env = append([]string(nil), gitCommandEnv...)
 
 	testEnvOverrideMu.RLock()
@@ -41,6 +50,9 @@ func newGitCommand(dir string, args ...string) *exec.Cmd {
 	}
 	testEnvOverrideMu.RUnlock()
 
+	if token := credentialFromContext(ctx); token != "" {
+		cmd.Env = append(cmd.Env, credentialEnv(cmd.Env, token, credentialRemoteURLs(dir))...)
+	}
 	if dir != "" {
 		cmd.Dir = dir
 	}
@@ -0,0 +1,119 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	// looseObjectsGCThreshold 与 git 的 gc.auto 默认值一致
+	looseObjectsGCThreshold = 6700
+	// packsGCThreshold 与 git 的 gc.autoPackLimit 默认值一致
+	packsGCThreshold = 50
+)
+
+// RepoHealth summarizes `git count-objects -v`. Sizes are in bytes.
+type RepoHealth struct {
+	LooseObjects  int64 `json:"looseObjects"`
+	LooseSize     int64 `json:"looseSize"`
+	PackedObjects int64 `json:"packedObjects"`
+	Packs         int64 `json:"packs"`
+	PackSize      int64 `json:"packSize"`
+	// PrunePackable 已打包但仍保留松散副本的对象数，gc 后可删除
+	PrunePackable int64 `json:"prunePackable"`
+	Garbage       int64 `json:"garbage"`
+	GarbageSize   int64 `json:"garbageSize"`
+	// SuggestGC 为 true 时 Reasons 说明原因
+	SuggestGC bool     `json:"suggestGc"`
+	Reasons   []string `json:"reasons,omitempty"`
+}
+
+// AnalyzeRepo counts the objects of the repository at path and decides whether
+// `git gc` is worth running, using the same limits as `git gc --auto`.
+func AnalyzeRepo(path string) (*RepoHealth, error) {
+	output, err := newGitCommand(path, "count-objects", "-v").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("count objects failed: %s", strings.TrimSpace(string(output)))
+	}
+	health, err := parseCountObjects(string(output))
+	if err != nil {
+		return nil, err
+	}
+
+	if health.LooseObjects > looseObjectsGCThreshold {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("%d loose objects exceed %d", health.LooseObjects, looseObjectsGCThreshold))
+	}
+	if health.Packs > packsGCThreshold {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("%d packfiles exceed %d", health.Packs, packsGCThreshold))
+	}
+	if health.PrunePackable > 0 {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("%d loose objects are already packed", health.PrunePackable))
+	}
+	if health.Garbage > 0 {
+		health.Reasons = append(health.Reasons, fmt.Sprintf("%d garbage files in the object directory", health.Garbage))
+	}
+	health.SuggestGC = len(health.Reasons) > 0
+	return health, nil
+}
+
+// parseCountObjects parses `git count-objects -v`, whose sizes are in KiB.
+func parseCountObjects(output string) (*RepoHealth, error) {
+	health := &RepoHealth{}
+	fields := map[string]*int64{
+		"count":          &health.LooseObjects,
+		"size":           &health.LooseSize,
+		"in-pack":        &health.PackedObjects,
+		"packs":          &health.Packs,
+		"size-pack":      &health.PackSize,
+		"prune-packable": &health.PrunePackable,
+		"garbage":        &health.Garbage,
+		"size-garbage":   &health.GarbageSize,
+	}
+	kib := map[string]bool{"size": true, "size-pack": true, "size-garbage": true}
+
+	found := false
+	scanner := bufio.NewScanner(strings.NewReader(output))
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key = strings.TrimSpace(key)
+		target, known := fields[key]
+		if !known {
+			continue
+		}
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse count-objects %s: %w", key, err)
+		}
+		if kib[key] {
+			n *= 1024
+		}
+		*target = n
+		found = true
+	}
+	if !found {
+		return nil, errors.New("unexpected count-objects output")
+	}
+	return health, nil
+}
+
+// RunGC runs `git gc` in the repository; ctx bounds how long it may take.
+func RunGC(ctx context.Context, path string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	output, err := newGitCommandContext(ctx, path, "gc", "--quiet").CombinedOutput()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return fmt.Errorf("git gc stopped: %w", ctxErr)
+		}
+		return fmt.Errorf("git gc failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
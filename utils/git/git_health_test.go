@@ -0,0 +1,47 @@
+package git
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseCountObjects(t *testing.T) {
+	output := "count: 12\nsize: 48\nin-pack: 300\npacks: 2\nsize-pack: 1024\nprune-packable: 0\ngarbage: 0\nsize-garbage: 0\n"
+	health, err := parseCountObjects(output)
+	if err != nil {
+		t.Fatalf("parseCountObjects returned error: %v", err)
+	}
+	if health.LooseObjects != 12 || health.LooseSize != 48*1024 || health.PackedObjects != 300 ||
+		health.Packs != 2 || health.PackSize != 1024*1024 {
+		t.Fatalf("unexpected health: %+v", health)
+	}
+
+	if _, err := parseCountObjects("fatal: not a git repository"); err == nil {
+		t.Fatal("expected error for unexpected output")
+	}
+}
+
+func TestAnalyzeRepoAndRunGC(t *testing.T) {
+	SetTestEnvOverride(testGitEnv())
+	t.Cleanup(func() { SetTestEnvOverride(nil) })
+
+	repoDir := initTestRepo(t)
+	health, err := AnalyzeRepo(repoDir)
+	if err != nil {
+		t.Fatalf("AnalyzeRepo returned error: %v", err)
+	}
+	if health.LooseObjects == 0 || health.SuggestGC {
+		t.Fatalf("expected a few loose objects without gc suggestion, got %+v", health)
+	}
+
+	if err := RunGC(context.Background(), repoDir); err != nil {
+		t.Fatalf("RunGC returned error: %v", err)
+	}
+	health, err = AnalyzeRepo(repoDir)
+	if err != nil {
+		t.Fatalf("AnalyzeRepo after gc returned error: %v", err)
+	}
+	if health.LooseObjects != 0 || health.Packs == 0 {
+		t.Fatalf("expected objects to be packed after gc, got %+v", health)
+	}
+}
@@ -0,0 +1,135 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	lfsPointerHeader = "version https://git-lfs.github.com/spec/v1"
+	// lfsPointerMaxSize 指针文件通常只有一百多字节，超过该大小的文件不可能是指针
+	lfsPointerMaxSize = 1024
+)
+
+// LFSStatus describes git-lfs usage of a worktree.
+type LFSStatus struct {
+	Enabled   bool     `json:"enabled"`   // .gitattributes 中存在 filter=lfs
+	Installed bool     `json:"installed"` // 本机是否安装了 git-lfs
+	Patterns  []string `json:"patterns,omitempty"`
+	// Pointers 列出仍是指针文件（内容未下载）的路径
+	Pointers []string `json:"pointers,omitempty"`
+}
+
+// GetLFSStatus detects whether the worktree at path uses git-lfs and lists tracked
+// files that are still pointer files. When git-lfs is installed `git lfs ls-files`
+// is used, otherwise tracked files are inspected for the pointer header.
+func GetLFSStatus(path string) (*LFSStatus, error) {
+	patterns, err := readLFSPatterns(path)
+	if err != nil {
+		return nil, err
+	}
+	status := &LFSStatus{
+		Enabled:  len(patterns) > 0,
+		Patterns: patterns,
+	}
+	if !status.Enabled {
+		return status, nil
+	}
+
+	if output, err := newGitCommand(path, "lfs", "ls-files").Output(); err == nil {
+		status.Installed = true
+		status.Pointers = parseLFSFiles(string(output))
+		return status, nil
+	}
+
+	pointers, err := scanLFSPointers(path)
+	if err != nil {
+		return nil, err
+	}
+	status.Pointers = pointers
+	return status, nil
+}
+
+// readLFSPatterns returns the patterns in the root .gitattributes that use filter=lfs.
+func readLFSPatterns(path string) ([]string, error) {
+	data, err := os.ReadFile(filepath.Join(path, ".gitattributes"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	patterns := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		for _, attr := range fields[1:] {
+			if attr == "filter=lfs" {
+				patterns = append(patterns, fields[0])
+				break
+			}
+		}
+	}
+	return patterns, scanner.Err()
+}
+
+// parseLFSFiles parses `git lfs ls-files` output. A "-" after the OID marks a
+// pointer whose content has not been downloaded, "*" marks a materialized file.
+func parseLFSFiles(output string) []string {
+	pointers := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(strings.TrimSpace(line), " ", 3)
+		if len(fields) < 3 {
+			continue
+		}
+		if fields[1] == "-" {
+			pointers = append(pointers, strings.TrimSpace(fields[2]))
+		}
+	}
+	return pointers
+}
+
+func scanLFSPointers(path string) ([]string, error) {
+	output, err := newGitCommand(path, "ls-files", "-z").Output()
+	if err != nil {
+		return nil, err
+	}
+
+	pointers := make([]string, 0)
+	for _, file := range strings.Split(string(output), "\x00") {
+		if file == "" {
+			continue
+		}
+		if isLFSPointerFile(filepath.Join(path, file)) {
+			pointers = append(pointers, file)
+		}
+	}
+	return pointers, nil
+}
+
+func isLFSPointerFile(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil || !info.Mode().IsRegular() || info.Size() > lfsPointerMaxSize {
+		return false
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	header := make([]byte, len(lfsPointerHeader))
+	if _, err := io.ReadFull(file, header); err != nil {
+		return false
+	}
+	return string(header) == lfsPointerHeader
+}
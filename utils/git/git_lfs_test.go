@@ -0,0 +1,51 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseLFSFiles(t *testing.T) {
+	output := "4d7a214614 * assets/logo.png\n8c2e1f9a77 - models/weights.bin\nbad line\n"
+	got := parseLFSFiles(output)
+	if len(got) != 1 || got[0] != "models/weights.bin" {
+		t.Fatalf("unexpected pointers: %#v", got)
+	}
+}
+
+func TestGetLFSStatus(t *testing.T) {
+	SetTestEnvOverride(testGitEnv())
+	t.Cleanup(func() { SetTestEnvOverride(nil) })
+
+	repoDir := initTestRepo(t)
+	status, err := GetLFSStatus(repoDir)
+	if err != nil {
+		t.Fatalf("GetLFSStatus returned error: %v", err)
+	}
+	if status.Enabled {
+		t.Fatalf("expected LFS to be disabled without .gitattributes")
+	}
+
+	attrs := "# lfs\n*.bin filter=lfs diff=lfs merge=lfs -text\n*.txt text\n"
+	if err := os.WriteFile(filepath.Join(repoDir, ".gitattributes"), []byte(attrs), 0o644); err != nil {
+		t.Fatalf("write .gitattributes: %v", err)
+	}
+	pointer := "version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize 12\n"
+	if err := os.WriteFile(filepath.Join(repoDir, "weights.bin"), []byte(pointer), 0o644); err != nil {
+		t.Fatalf("write pointer: %v", err)
+	}
+	runGit(t, repoDir, "-c", "filter.lfs.required=false", "add", ".")
+	runGit(t, repoDir, "-c", "filter.lfs.required=false", "commit", "-m", "add lfs pointer")
+
+	status, err = GetLFSStatus(repoDir)
+	if err != nil {
+		t.Fatalf("GetLFSStatus returned error: %v", err)
+	}
+	if !status.Enabled || len(status.Patterns) != 1 || status.Patterns[0] != "*.bin" {
+		t.Fatalf("unexpected LFS patterns: %+v", status)
+	}
+	if !status.Installed && (len(status.Pointers) != 1 || status.Pointers[0] != "weights.bin") {
+		t.Fatalf("expected weights.bin to be reported as pointer, got %+v", status)
+	}
+}
@@ -3,7 +3,9 @@ package git
 import (
 	"errors"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"strings"
 )
 
@@ -38,7 +40,7 @@ func (r *GitRepo) MergeBranch(worktreePath, sourceBranch string, strategy MergeS
 
 	output, err := cmd.CombinedOutput()
 	if err != nil {
-		return fmt.Errorf("merge failed: %s", strings.TrimSpace(string(output)))
+		return gitFailure("merge", string(output))
 	}
 	return nil
 }
@@ -87,3 +89,201 @@ func IsConflictError(err error) bool {
 		strings.Contains(msg, "Merge conflict") ||
 		strings.Contains(strings.ToLower(msg), "conflict")
 }
+
+// MergePreviewFile describes a file that would change when merging.
+type MergePreviewFile struct {
+	Path   string `json:"path"`
+	Status string `json:"status"`
+}
+
+// MergePreview describes the outcome of a dry-run merge.
+type MergePreview struct {
+	Files     []MergePreviewFile `json:"files"`
+	Conflicts []string           `json:"conflicts"`
+	UpToDate  bool               `json:"upToDate"`
+}
+
+// PreviewMerge dry-runs integrating sourceBranch into the worktree with the
+// given strategy and collects the files that would change and any conflicts.
+// Merge and squash previews run inside the worktree and are undone afterwards,
+// so the worktree must be clean beforehand. Rebase previews replay the commits
+// in a temporary detached worktree and leave the worktree untouched.
+func (r *GitRepo) PreviewMerge(worktreePath, sourceBranch string, strategy MergeStrategy) (*MergePreview, error) {
+	if r == nil {
+		return nil, errors.New("git repository is not initialized")
+	}
+	path := strings.TrimSpace(worktreePath)
+	if path == "" {
+		path = r.Path
+	}
+	source := strings.TrimSpace(sourceBranch)
+	if source == "" {
+		return nil, errors.New("source branch is required")
+	}
+
+	switch strategy {
+	case MergeStrategyRebase:
+		return r.previewRebase(path, source)
+	case MergeStrategySquash:
+		return r.previewSquash(path, source)
+	default:
+		return r.previewMerge(path, source)
+	}
+}
+
+// previewMerge performs `git merge --no-commit --no-ff` and aborts it.
+func (r *GitRepo) previewMerge(path, source string) (_ *MergePreview, err error) {
+	preview := newMergePreview()
+	mergeOutput, mergeErr := newGitCommand(path, "merge", "--no-commit", "--no-ff", source).CombinedOutput()
+	if !hasMergeHead(path) {
+		if mergeErr != nil {
+			return nil, fmt.Errorf("merge preview failed: %s", strings.TrimSpace(string(mergeOutput)))
+		}
+		// Nothing to merge, git did not start a merge.
+		preview.UpToDate = true
+		return preview, nil
+	}
+	// MERGE_HEAD exists either because the merge stopped before committing or
+	// because it hit conflicts; in both cases the staged result is what we want.
+	defer func() {
+		if output, abortErr := newGitCommand(path, "merge", "--abort").CombinedOutput(); abortErr != nil && err == nil {
+			err = fmt.Errorf("abort merge preview failed: %s", strings.TrimSpace(string(output)))
+		}
+	}()
+	return r.collectMergePreview(path, preview, "--cached")
+}
+
+// previewSquash performs `git merge --squash` and resets the worktree. A
+// squash merge records no MERGE_HEAD, so `git merge --abort` cannot undo it.
+func (r *GitRepo) previewSquash(path, source string) (_ *MergePreview, err error) {
+	preview := newMergePreview()
+	if isAncestor(path, source, "HEAD") {
+		preview.UpToDate = true
+		return preview, nil
+	}
+	mergeOutput, mergeErr := newGitCommand(path, "merge", "--squash", source).CombinedOutput()
+	defer func() {
+		if output, resetErr := newGitCommand(path, "reset", "--hard", "-q", "HEAD").CombinedOutput(); resetErr != nil && err == nil {
+			err = fmt.Errorf("reset squash preview failed: %s", strings.TrimSpace(string(output)))
+		}
+		removeGitPathFile(path, "SQUASH_MSG")
+	}()
+	if mergeErr != nil && len(r.GetConflictFiles(path)) == 0 {
+		return nil, fmt.Errorf("squash preview failed: %s", strings.TrimSpace(string(mergeOutput)))
+	}
+	return r.collectMergePreview(path, preview, "--cached")
+}
+
+// previewRebase replays the commits of the worktree onto source in a temporary
+// detached worktree. When the rebase stops on a conflict, the preview lists
+// the conflicts of the first conflicting commit and the files changed up to
+// that point.
+func (r *GitRepo) previewRebase(path, source string) (_ *MergePreview, err error) {
+	preview := newMergePreview()
+	if isAncestor(path, source, "HEAD") {
+		preview.UpToDate = true
+		return preview, nil
+	}
+	head, err := newGitCommand(path, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return nil, fmt.Errorf("resolve HEAD failed: %w", err)
+	}
+	base := strings.TrimSpace(string(head))
+
+	tmpDir, err := os.MkdirTemp("", "code-kanban-rebase-preview-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+	if output, addErr := newGitCommand(path, "worktree", "add", "--detach", tmpDir, base).CombinedOutput(); addErr != nil {
+		return nil, gitFailure("add rebase preview worktree", string(output))
+	}
+	defer func() {
+		if output, removeErr := newGitCommand(path, "worktree", "remove", "--force", tmpDir).CombinedOutput(); removeErr != nil {
+			_ = newGitCommand(path, "worktree", "prune").Run()
+			if err == nil {
+				err = fmt.Errorf("remove rebase preview worktree failed: %s", strings.TrimSpace(string(output)))
+			}
+		}
+	}()
+
+	rebaseOutput, rebaseErr := newNoEditorGitCommand(tmpDir, "rebase", source).CombinedOutput()
+	if rebaseErr == nil {
+		return r.collectMergePreview(tmpDir, preview, base, "HEAD")
+	}
+	defer func() {
+		_ = newGitCommand(tmpDir, "rebase", "--abort").Run()
+	}()
+	if len(r.GetConflictFiles(tmpDir)) == 0 {
+		return nil, fmt.Errorf("rebase preview failed: %s", strings.TrimSpace(string(rebaseOutput)))
+	}
+	return r.collectMergePreview(tmpDir, preview, "--cached", base)
+}
+
+// collectMergePreview fills preview from `git diff --name-status <args>` and
+// the conflicted files of the worktree at path.
+func (r *GitRepo) collectMergePreview(path string, preview *MergePreview, diffArgs ...string) (*MergePreview, error) {
+	args := append([]string{"diff", "--name-status"}, diffArgs...)
+	diffOutput, diffErr := newGitCommand(path, args...).CombinedOutput()
+	if diffErr != nil {
+		return nil, fmt.Errorf("diff merge preview failed: %s", strings.TrimSpace(string(diffOutput)))
+	}
+	preview.Files = parseNameStatus(string(diffOutput))
+	preview.Conflicts = r.GetConflictFiles(path)
+	return preview, nil
+}
+
+func newMergePreview() *MergePreview {
+	return &MergePreview{
+		Files:     []MergePreviewFile{},
+		Conflicts: []string{},
+	}
+}
+
+// isAncestor reports whether commit is an ancestor of (or equal to) of.
+func isAncestor(path, commit, of string) bool {
+	return newGitCommand(path, "merge-base", "--is-ancestor", commit, of).Run() == nil
+}
+
+// removeGitPathFile removes a state file such as SQUASH_MSG from the git
+// directory of the worktree at path.
+func removeGitPathFile(path, name string) {
+	output, err := newGitCommand(path, "rev-parse", "--git-path", name).Output()
+	if err != nil {
+		return
+	}
+	file := strings.TrimSpace(string(output))
+	if !filepath.IsAbs(file) {
+		file = filepath.Join(path, file)
+	}
+	_ = os.Remove(file)
+}
+
+func hasMergeHead(path string) bool {
+	return hasRef(path, "MERGE_HEAD")
+}
+
+// parseNameStatus parses `git diff --name-status` output. Renames and copies
+// report the destination path.
+func parseNameStatus(output string) []MergePreviewFile {
+	files := make([]MergePreviewFile, 0)
+	for _, line := range strings.Split(output, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 {
+			continue
+		}
+		status := strings.TrimSpace(fields[0])
+		if status != "" {
+			status = status[:1]
+		}
+		files = append(files, MergePreviewFile{
+			Path:   strings.TrimSpace(fields[len(fields)-1]),
+			Status: status,
+		})
+	}
+	return files
+}
@@ -0,0 +1,173 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseNameStatus(t *testing.T) {
+	output := "M\tREADME.md\nA\tnew.txt\nR100\told.txt\trenamed.txt\nU\tconflict.txt\n"
+
+	got := parseNameStatus(output)
+	want := []MergePreviewFile{
+		{Path: "README.md", Status: "M"},
+		{Path: "new.txt", Status: "A"},
+		{Path: "renamed.txt", Status: "R"},
+		{Path: "conflict.txt", Status: "U"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected name-status result: %#v", got)
+	}
+}
+
+func TestPreviewMerge(t *testing.T) {
+	SetTestEnvOverride(testGitEnv())
+	t.Cleanup(func() { SetTestEnvOverride(nil) })
+
+	repoDir := initTestRepo(t)
+	repo, err := DetectRepository(repoDir)
+	if err != nil {
+		t.Fatalf("DetectRepository returned error: %v", err)
+	}
+
+	runGit(t, repoDir, "checkout", "-b", "feature/preview")
+	if err := os.WriteFile(filepath.Join(repoDir, "feature.txt"), []byte("feature\n"), 0o644); err != nil {
+		t.Fatalf("write feature file: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Feature\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "feature change")
+
+	runGit(t, repoDir, "checkout", "main")
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Main\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, repoDir, "commit", "-am", "main change")
+
+	preview, err := repo.PreviewMerge(repoDir, "feature/preview", MergeStrategyMerge)
+	if err != nil {
+		t.Fatalf("PreviewMerge returned error: %v", err)
+	}
+	if len(preview.Files) != 2 {
+		t.Fatalf("expected 2 changed files got %#v", preview.Files)
+	}
+	if !reflect.DeepEqual(preview.Conflicts, []string{"README.md"}) {
+		t.Fatalf("expected README.md conflict got %#v", preview.Conflicts)
+	}
+	if hasMergeHead(repoDir) {
+		t.Fatalf("expected merge to be aborted after preview")
+	}
+	if _, err := os.Stat(filepath.Join(repoDir, "feature.txt")); !os.IsNotExist(err) {
+		t.Fatalf("expected worktree to be restored, feature.txt stat err=%v", err)
+	}
+
+	upToDate, err := repo.PreviewMerge(repoDir, "main", MergeStrategyMerge)
+	if err != nil {
+		t.Fatalf("PreviewMerge on same branch returned error: %v", err)
+	}
+	if !upToDate.UpToDate {
+		t.Fatalf("expected up-to-date preview got %#v", upToDate)
+	}
+}
+
+func TestPreviewMergeStrategies(t *testing.T) {
+	SetTestEnvOverride(testGitEnv())
+	t.Cleanup(func() { SetTestEnvOverride(nil) })
+
+	repoDir := initTestRepo(t)
+	repo, err := DetectRepository(repoDir)
+	if err != nil {
+		t.Fatalf("DetectRepository returned error: %v", err)
+	}
+	writeFile := func(name, content string) {
+		t.Helper()
+		if err := os.WriteFile(filepath.Join(repoDir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	gitOutput := func(args ...string) string {
+		t.Helper()
+		output, err := newGitCommand(repoDir, args...).Output()
+		if err != nil {
+			t.Fatalf("git %s: %v", strings.Join(args, " "), err)
+		}
+		return string(output)
+	}
+	headOf := func() string { return gitOutput("rev-parse", "HEAD") }
+	assertRestored := func(strategy MergeStrategy, head string) {
+		t.Helper()
+		if got := headOf(); got != head {
+			t.Fatalf("%s preview moved HEAD from %s to %s", strategy, head, got)
+		}
+		if output := gitOutput("status", "--porcelain"); output != "" {
+			t.Fatalf("%s preview left changes behind: %q", strategy, output)
+		}
+		if output := gitOutput("worktree", "list", "--porcelain"); strings.Count(output, "worktree ") != 1 {
+			t.Fatalf("%s preview left a worktree behind: %q", strategy, output)
+		}
+	}
+
+	// main 与 feature 各自新增一个文件，rebase/squash 均无冲突
+	runGit(t, repoDir, "checkout", "-b", "feature/strategy")
+	writeFile("feature.txt", "feature\n")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "feature file")
+	runGit(t, repoDir, "checkout", "main")
+	writeFile("main.txt", "main\n")
+	runGit(t, repoDir, "add", ".")
+	runGit(t, repoDir, "commit", "-m", "main file")
+	head := headOf()
+
+	squash, err := repo.PreviewMerge(repoDir, "feature/strategy", MergeStrategySquash)
+	if err != nil {
+		t.Fatalf("squash preview returned error: %v", err)
+	}
+	if !reflect.DeepEqual(squash.Files, []MergePreviewFile{{Path: "feature.txt", Status: "A"}}) || len(squash.Conflicts) != 0 {
+		t.Fatalf("unexpected squash preview %#v", squash)
+	}
+	assertRestored(MergeStrategySquash, head)
+	if _, err := os.Stat(filepath.Join(repoDir, ".git", "SQUASH_MSG")); !os.IsNotExist(err) {
+		t.Fatalf("expected SQUASH_MSG to be removed, stat err=%v", err)
+	}
+
+	// rebase 把 main 的提交重放到 feature 上，工作区会多出 feature.txt
+	rebase, err := repo.PreviewMerge(repoDir, "feature/strategy", MergeStrategyRebase)
+	if err != nil {
+		t.Fatalf("rebase preview returned error: %v", err)
+	}
+	if !reflect.DeepEqual(rebase.Files, []MergePreviewFile{{Path: "feature.txt", Status: "A"}}) || len(rebase.Conflicts) != 0 {
+		t.Fatalf("unexpected rebase preview %#v", rebase)
+	}
+	assertRestored(MergeStrategyRebase, head)
+
+	// 双方修改同一文件时两种策略都报告冲突
+	runGit(t, repoDir, "checkout", "feature/strategy")
+	writeFile("README.md", "# Feature\n")
+	runGit(t, repoDir, "commit", "-am", "feature readme")
+	runGit(t, repoDir, "checkout", "main")
+	writeFile("README.md", "# Main\n")
+	runGit(t, repoDir, "commit", "-am", "main readme")
+	head = headOf()
+	for _, strategy := range []MergeStrategy{MergeStrategySquash, MergeStrategyRebase} {
+		preview, err := repo.PreviewMerge(repoDir, "feature/strategy", strategy)
+		if err != nil {
+			t.Fatalf("%s preview returned error: %v", strategy, err)
+		}
+		if !reflect.DeepEqual(preview.Conflicts, []string{"README.md"}) {
+			t.Fatalf("expected README.md conflict for %s, got %#v", strategy, preview)
+		}
+		assertRestored(strategy, head)
+	}
+
+	for _, strategy := range []MergeStrategy{MergeStrategySquash, MergeStrategyRebase} {
+		upToDate, err := repo.PreviewMerge(repoDir, "main", strategy)
+		if err != nil || !upToDate.UpToDate {
+			t.Fatalf("expected up-to-date %s preview, got %#v err=%v", strategy, upToDate, err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package git
+
+import (
+	"errors"
+	"os/exec"
+)
+
+// ErrNoOperationInProgress indicates there is no stopped rebase, merge,
+// cherry-pick or revert to continue.
+var ErrNoOperationInProgress = errors.New("no operation in progress")
+
+// Operation is a multi-step git operation that can stop on conflicts.
+type Operation string
+
+const (
+	OperationNone       Operation = ""
+	OperationRebase     Operation = "rebase"
+	OperationMerge      Operation = "merge"
+	OperationCherryPick Operation = "cherry-pick"
+	OperationRevert     Operation = "revert"
+)
+
+// InProgressOperation reports the operation currently stopped in the worktree.
+// A rebase wins over the others because its steps are cherry-picks internally.
+func (r *GitRepo) InProgressOperation(path string) Operation {
+	path = r.resolveWorktreePath(path)
+	if r.IsRebaseInProgress(path) {
+		return OperationRebase
+	}
+	switch {
+	case hasRef(path, "MERGE_HEAD"):
+		return OperationMerge
+	case hasRef(path, "CHERRY_PICK_HEAD"):
+		return OperationCherryPick
+	case hasRef(path, "REVERT_HEAD"):
+		return OperationRevert
+	}
+	return OperationNone
+}
+
+// ContinueOperation continues whichever operation is stopped in the worktree
+// once its conflicts are resolved and staged, and returns that operation.
+// Commit messages prepared by git are used as they are.
+func (r *GitRepo) ContinueOperation(path string) (Operation, error) {
+	if r == nil {
+		return OperationNone, errors.New("git repository is not initialized")
+	}
+	path = r.resolveWorktreePath(path)
+
+	operation := r.InProgressOperation(path)
+	switch operation {
+	case OperationNone:
+		return operation, ErrNoOperationInProgress
+	case OperationRebase:
+		return operation, r.RebaseContinue(path)
+	}
+
+	output, err := newNoEditorGitCommand(path, string(operation), "--continue").CombinedOutput()
+	if err != nil {
+		return operation, gitFailure(string(operation)+" continue", string(output))
+	}
+	return operation, nil
+}
+
+func hasRef(path, ref string) bool {
+	return newGitCommand(path, "rev-parse", "-q", "--verify", ref).Run() == nil
+}
+
+// newNoEditorGitCommand builds a git command that accepts the prepared commit
+// message instead of waiting for an editor that nobody can see.
+func newNoEditorGitCommand(dir string, args ...string) *exec.Cmd {
+	cmd := newGitCommand(dir, args...)
+	cmd.Env = append(cmd.Env, "GIT_EDITOR=true")
+	return cmd
+}
@@ -0,0 +1,56 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestContinueOperation(t *testing.T) {
+	SetTestEnvOverride(testGitEnv())
+	t.Cleanup(func() { SetTestEnvOverride(nil) })
+
+	repoDir := initTestRepo(t)
+	repo, err := DetectRepository(repoDir)
+	if err != nil {
+		t.Fatalf("DetectRepository returned error: %v", err)
+	}
+
+	if _, err := repo.ContinueOperation(repoDir); !errors.Is(err, ErrNoOperationInProgress) {
+		t.Fatalf("expected ErrNoOperationInProgress, got %v", err)
+	}
+
+	runGit(t, repoDir, "checkout", "-b", "feature/continue")
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Feature\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, repoDir, "commit", "-am", "feature change")
+	runGit(t, repoDir, "checkout", "main")
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Main\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, repoDir, "commit", "-am", "main change")
+
+	if err := repo.MergeBranch(repoDir, "feature/continue", MergeStrategyMerge); !IsConflictError(err) {
+		t.Fatalf("expected merge conflict, got %v", err)
+	}
+	if op := repo.InProgressOperation(repoDir); op != OperationMerge {
+		t.Fatalf("expected merge in progress, got %q", op)
+	}
+
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Resolved\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, repoDir, "add", "README.md")
+	op, err := repo.ContinueOperation(repoDir)
+	if err != nil {
+		t.Fatalf("ContinueOperation returned error: %v", err)
+	}
+	if op != OperationMerge {
+		t.Fatalf("expected merge to be continued, got %q", op)
+	}
+	if op := repo.InProgressOperation(repoDir); op != OperationNone {
+		t.Fatalf("expected no operation after continue, got %q", op)
+	}
+}
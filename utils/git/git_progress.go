@@ -0,0 +1,198 @@
+package git
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// GitProgress 是 git --progress 在 stderr 中输出的一条进度，如 "Receiving objects:  45% (450/1000)"
+type GitProgress struct {
+	Phase   string `json:"phase"`
+	Percent int    `json:"percent"`
+	Current int64  `json:"current,omitempty"`
+	Total   int64  `json:"total,omitempty"`
+}
+
+// ProgressFunc receives progress updates of a long running git operation.
+type ProgressFunc func(GitProgress)
+
+var gitProgressPattern = regexp.MustCompile(`^(?:remote:\s*)?([A-Za-z][A-Za-z ]*?):\s+(\d{1,3})%(?:\s+\((\d+)/(\d+)\))?`)
+
+// progressOutputMaxBytes 失败时保留的 stderr 非进度输出，用于错误信息
+const progressOutputMaxBytes = 16 * 1024
+
+// parseGitProgress extracts a progress update from one stderr line.
+func parseGitProgress(line string) (GitProgress, bool) {
+	match := gitProgressPattern.FindStringSubmatch(strings.TrimSpace(line))
+	if match == nil {
+		return GitProgress{}, false
+	}
+	percent, err := strconv.Atoi(match[2])
+	if err != nil || percent > 100 {
+		return GitProgress{}, false
+	}
+	progress := GitProgress{Phase: match[1], Percent: percent}
+	if match[3] != "" {
+		progress.Current, _ = strconv.ParseInt(match[3], 10, 64)
+		progress.Total, _ = strconv.ParseInt(match[4], 10, 64)
+	}
+	return progress, true
+}
+
+// scanProgressLines splits on both \r and \n because git redraws progress
+// lines in place with carriage returns.
+func scanProgressLines(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
+
+// runWithProgress runs git with --progress, reports parsed progress lines to
+// onProgress and returns the remaining stderr output in the error on failure.
+func runWithProgress(ctx context.Context, dir string, onProgress ProgressFunc, args ...string) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	cmd := newGitCommandContext(ctx, dir, args...)
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return err
+	}
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+
+	var output bytes.Buffer
+	lastPhase, lastPercent := "", -1
+	scanner := bufio.NewScanner(stderr)
+	scanner.Split(scanProgressLines)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if progress, ok := parseGitProgress(line); ok {
+			// 同一阶段的百分比不变时不重复推送
+			if onProgress != nil && (progress.Phase != lastPhase || progress.Percent != lastPercent) {
+				onProgress(progress)
+			}
+			lastPhase, lastPercent = progress.Phase, progress.Percent
+			continue
+		}
+		if strings.TrimSpace(line) != "" && output.Len() < progressOutputMaxBytes {
+			output.WriteString(line)
+			output.WriteByte('\n')
+		}
+	}
+
+	if err := cmd.Wait(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		message := strings.TrimSpace(output.String())
+		if message == "" {
+			message = strings.TrimSpace(stdout.String())
+		}
+		return gitFailure("git "+strings.Join(args, " "), message)
+	}
+	return nil
+}
+
+// Fetch runs `git fetch --progress` in the worktree, fetching all remotes when remote is empty.
+func (r *GitRepo) Fetch(ctx context.Context, worktreePath, remote string, onProgress ProgressFunc) error {
+	if r == nil {
+		return errors.New("git repository is not initialized")
+	}
+	args := []string{"fetch", "--progress", "--prune"}
+	if remote = strings.TrimSpace(remote); remote != "" {
+		if strings.HasPrefix(remote, "-") {
+			return fmt.Errorf("invalid remote %q", remote)
+		}
+		args = append(args, remote)
+	} else {
+		args = append(args, "--all")
+	}
+	return runWithProgress(ctx, r.worktreeDir(worktreePath), onProgress, args...)
+}
+
+// Pull runs `git pull --ff-only --progress` in the worktree so a divergent branch
+// is reported instead of creating an unexpected merge commit.
+func (r *GitRepo) Pull(ctx context.Context, worktreePath string, onProgress ProgressFunc) error {
+	if r == nil {
+		return errors.New("git repository is not initialized")
+	}
+	return runWithProgress(ctx, r.worktreeDir(worktreePath), onProgress, "pull", "--ff-only", "--progress")
+}
+
+// CloneOptions controls how Clone fetches the repository.
+type CloneOptions struct {
+	// Depth 大于 0 时浅克隆最近 Depth 个提交
+	Depth int
+	// Branch 检出的分支或标签，为空时使用远程默认分支
+	Branch            string
+	RecurseSubmodules bool
+	OnProgress        ProgressFunc
+}
+
+// Clone clones url into dest with progress reporting. When dest did not exist
+// before, whatever the failed or cancelled clone left behind is removed.
+func Clone(ctx context.Context, url, dest string, opts CloneOptions) error {
+	url = strings.TrimSpace(url)
+	dest = strings.TrimSpace(dest)
+	if url == "" || dest == "" {
+		return errors.New("clone url and destination are required")
+	}
+	if strings.HasPrefix(url, "-") {
+		return fmt.Errorf("invalid clone url %q", url)
+	}
+	if opts.Depth < 0 {
+		return fmt.Errorf("invalid clone depth %d", opts.Depth)
+	}
+	branch := strings.TrimSpace(opts.Branch)
+	if strings.HasPrefix(branch, "-") {
+		return fmt.Errorf("invalid clone branch %q", branch)
+	}
+
+	args := []string{"clone", "--progress"}
+	if opts.Depth > 0 {
+		args = append(args, "--depth", strconv.Itoa(opts.Depth))
+	}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	if opts.RecurseSubmodules {
+		args = append(args, "--recurse-submodules")
+	}
+	args = append(args, "--", url, dest)
+
+	_, statErr := os.Stat(dest)
+	existed := statErr == nil
+	if err := runWithProgress(ctx, "", opts.OnProgress, args...); err != nil {
+		// 被取消时 git 来不及清理，目标目录是本次新建的才删除，避免误删用户文件
+		if !existed {
+			_ = os.RemoveAll(dest)
+		}
+		return err
+	}
+	return nil
+}
+
+func (r *GitRepo) worktreeDir(path string) string {
+	if target := strings.TrimSpace(path); target != "" {
+		return target
+	}
+	return r.Path
+}
@@ -0,0 +1,113 @@
+package git
+
+import (
+	"bufio"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseGitProgress(t *testing.T) {
+	cases := []struct {
+		line string
+		want GitProgress
+		ok   bool
+	}{
+		{"Receiving objects:  45% (450/1000), 1.20 MiB | 2.00 MiB/s", GitProgress{Phase: "Receiving objects", Percent: 45, Current: 450, Total: 1000}, true},
+		{"remote: Counting objects: 100% (12/12), done.", GitProgress{Phase: "Counting objects", Percent: 100, Current: 12, Total: 12}, true},
+		{"Resolving deltas:   3%", GitProgress{Phase: "Resolving deltas", Percent: 3}, true},
+		{"Cloning into 'repo'...", GitProgress{}, false},
+		{"fatal: repository not found", GitProgress{}, false},
+	}
+	for _, tc := range cases {
+		got, ok := parseGitProgress(tc.line)
+		if ok != tc.ok || got != tc.want {
+			t.Fatalf("parseGitProgress(%q) = %+v, %v; want %+v, %v", tc.line, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestScanProgressLines(t *testing.T) {
+	scanner := bufio.NewScanner(strings.NewReader("Receiving objects:  10%\rReceiving objects: 100%, done.\nResolving"))
+	scanner.Split(scanProgressLines)
+	var lines []string
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if len(lines) != 3 || lines[1] != "Receiving objects: 100%, done." || lines[2] != "Resolving" {
+		t.Fatalf("unexpected lines %q", lines)
+	}
+}
+
+func TestCloneAndFetchWithProgress(t *testing.T) {
+	source := initTestRepo(t)
+	dest := filepath.Join(t.TempDir(), "clone")
+
+	var updates []GitProgress
+	// file:// 强制走传输协议，本地路径克隆会直接硬链接而没有进度
+	url := "file://" + filepath.ToSlash(source)
+	if err := Clone(context.Background(), url, dest, CloneOptions{OnProgress: func(p GitProgress) { updates = append(updates, p) }}); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "README.md")); err != nil {
+		t.Fatalf("expected cloned README: %v", err)
+	}
+	if len(updates) == 0 {
+		t.Fatalf("expected progress updates during clone")
+	}
+	last := updates[len(updates)-1]
+	if last.Percent < 0 || last.Percent > 100 || last.Phase == "" {
+		t.Fatalf("unexpected progress %+v", last)
+	}
+
+	repo, err := DetectRepository(dest)
+	if err != nil {
+		t.Fatalf("open clone: %v", err)
+	}
+	if err := repo.Fetch(context.Background(), "", "origin", nil); err != nil {
+		t.Fatalf("fetch failed: %v", err)
+	}
+	if err := repo.Fetch(context.Background(), "", "--upload-pack=evil", nil); err == nil {
+		t.Fatalf("expected option-like remote to be rejected")
+	}
+
+	missing := filepath.Join(t.TempDir(), "missing")
+	if err := Clone(context.Background(), "file:///nonexistent/repo", missing, CloneOptions{}); err == nil || !strings.Contains(err.Error(), "failed") {
+		t.Fatalf("expected clone failure with stderr, got %v", err)
+	}
+	if _, err := os.Stat(missing); !os.IsNotExist(err) {
+		t.Fatalf("expected failed clone destination to be removed, got %v", err)
+	}
+}
+
+func TestCloneOptions(t *testing.T) {
+	source := initTestRepo(t)
+	runGit(t, source, "checkout", "-b", "feature")
+	if err := os.WriteFile(filepath.Join(source, "feature.txt"), []byte("feature"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGit(t, source, "add", ".")
+	runGit(t, source, "commit", "-m", "feature commit")
+
+	dest := filepath.Join(t.TempDir(), "shallow")
+	url := "file://" + filepath.ToSlash(source)
+	if err := Clone(context.Background(), url, dest, CloneOptions{Depth: 1, Branch: "feature"}); err != nil {
+		t.Fatalf("clone failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dest, "feature.txt")); err != nil {
+		t.Fatalf("expected feature branch to be checked out: %v", err)
+	}
+	out, err := newGitCommand(dest, "rev-list", "--count", "HEAD").Output()
+	if err != nil {
+		t.Fatalf("rev-list failed: %v", err)
+	}
+	if strings.TrimSpace(string(out)) != "1" {
+		t.Fatalf("expected shallow clone with 1 commit, got %s", out)
+	}
+
+	if err := Clone(context.Background(), url, filepath.Join(t.TempDir(), "bad"), CloneOptions{Branch: "--upload-pack=evil"}); err == nil {
+		t.Fatalf("expected option-like branch to be rejected")
+	}
+}
@@ -0,0 +1,142 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrRebaseConflict is matched by RebaseConflictError.
+	ErrRebaseConflict = errors.New("rebase has conflicts")
+	// ErrNoRebaseInProgress indicates abort/continue was called without a stopped rebase.
+	ErrNoRebaseInProgress = errors.New("no rebase in progress")
+)
+
+// RebaseConflictError reports the files that stopped a rebase. The rebase stays
+// in progress until RebaseContinue or RebaseAbort is called.
+type RebaseConflictError struct {
+	Files []string
+}
+
+func (e *RebaseConflictError) Error() string {
+	if len(e.Files) == 0 {
+		return ErrRebaseConflict.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrRebaseConflict.Error(), strings.Join(e.Files, ", "))
+}
+
+func (e *RebaseConflictError) Unwrap() error {
+	return ErrRebaseConflict
+}
+
+// RebaseOnto runs `git rebase <upstream>` in the worktree at path.
+func (r *GitRepo) RebaseOnto(path, upstream string) error {
+	if r == nil {
+		return errors.New("git repository is not initialized")
+	}
+	path = r.resolveWorktreePath(path)
+	upstream = strings.TrimSpace(upstream)
+	if upstream == "" {
+		return errors.New("upstream branch is required")
+	}
+	if strings.HasPrefix(upstream, "-") {
+		return fmt.Errorf("invalid upstream %q", upstream)
+	}
+	if r.IsRebaseInProgress(path) {
+		return errors.New("a rebase is already in progress")
+	}
+
+	output, err := newGitCommand(path, "rebase", upstream).CombinedOutput()
+	return r.rebaseResult(path, "rebase", output, err)
+}
+
+// RebaseContinue resumes a stopped rebase after the conflicts were resolved and
+// staged. Commit messages are kept as they are.
+func (r *GitRepo) RebaseContinue(path string) error {
+	if r == nil {
+		return errors.New("git repository is not initialized")
+	}
+	path = r.resolveWorktreePath(path)
+	if !r.IsRebaseInProgress(path) {
+		return ErrNoRebaseInProgress
+	}
+
+	output, err := newNoEditorGitCommand(path, "rebase", "--continue").CombinedOutput()
+	return r.rebaseResult(path, "rebase continue", output, err)
+}
+
+// RebaseSkip drops the commit the rebase stopped on and carries on with the rest.
+func (r *GitRepo) RebaseSkip(path string) error {
+	if r == nil {
+		return errors.New("git repository is not initialized")
+	}
+	path = r.resolveWorktreePath(path)
+	if !r.IsRebaseInProgress(path) {
+		return ErrNoRebaseInProgress
+	}
+
+	output, err := newNoEditorGitCommand(path, "rebase", "--skip").CombinedOutput()
+	return r.rebaseResult(path, "rebase skip", output, err)
+}
+
+// RebaseAbort abandons a stopped rebase and restores the branch.
+func (r *GitRepo) RebaseAbort(path string) error {
+	if r == nil {
+		return errors.New("git repository is not initialized")
+	}
+	path = r.resolveWorktreePath(path)
+	if !r.IsRebaseInProgress(path) {
+		return ErrNoRebaseInProgress
+	}
+
+	output, err := newGitCommand(path, "rebase", "--abort").CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("rebase abort failed: %s", strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// IsRebaseInProgress reports whether the worktree has a stopped rebase.
+func (r *GitRepo) IsRebaseInProgress(path string) bool {
+	path = r.resolveWorktreePath(path)
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		output, err := newGitCommand(path, "rev-parse", "--git-path", name).Output()
+		if err != nil {
+			continue
+		}
+		dir := strings.TrimSpace(string(output))
+		if dir == "" {
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(path, dir)
+		}
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return true
+		}
+	}
+	return false
+}
+
+// rebaseResult turns a failed rebase command into a RebaseConflictError when git
+// stopped on conflicts.
+func (r *GitRepo) rebaseResult(path, action string, output []byte, err error) error {
+	if err == nil {
+		return nil
+	}
+	if r.IsRebaseInProgress(path) {
+		return &RebaseConflictError{Files: r.GetConflictFiles(path)}
+	}
+	return gitFailure(action, string(output))
+}
+
+func (r *GitRepo) resolveWorktreePath(path string) string {
+	path = strings.TrimSpace(path)
+	if path == "" && r != nil {
+		path = r.Path
+	}
+	return path
+}
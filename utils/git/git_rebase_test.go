@@ -0,0 +1,98 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRebaseOnto(t *testing.T) {
+	SetTestEnvOverride(testGitEnv())
+	t.Cleanup(func() { SetTestEnvOverride(nil) })
+
+	repoDir := initTestRepo(t)
+	repo, err := DetectRepository(repoDir)
+	if err != nil {
+		t.Fatalf("DetectRepository returned error: %v", err)
+	}
+
+	runGit(t, repoDir, "checkout", "-b", "feature/rebase")
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Feature\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, repoDir, "commit", "-am", "feature change")
+
+	runGit(t, repoDir, "checkout", "main")
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Main\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, repoDir, "commit", "-am", "main change")
+	runGit(t, repoDir, "checkout", "feature/rebase")
+
+	err = repo.RebaseOnto(repoDir, "main")
+	var conflict *RebaseConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrRebaseConflict) {
+		t.Fatalf("expected rebase conflict, got %v", err)
+	}
+	if !reflect.DeepEqual(conflict.Files, []string{"README.md"}) {
+		t.Fatalf("expected README.md conflict got %#v", conflict.Files)
+	}
+	if !repo.IsRebaseInProgress(repoDir) {
+		t.Fatal("expected rebase to stay in progress after conflict")
+	}
+
+	if err := repo.RebaseAbort(repoDir); err != nil {
+		t.Fatalf("RebaseAbort returned error: %v", err)
+	}
+	if repo.IsRebaseInProgress(repoDir) {
+		t.Fatal("expected rebase to be aborted")
+	}
+	if err := repo.RebaseAbort(repoDir); !errors.Is(err, ErrNoRebaseInProgress) {
+		t.Fatalf("expected ErrNoRebaseInProgress, got %v", err)
+	}
+
+	// 解决冲突后继续
+	if err := repo.RebaseOnto(repoDir, "main"); !errors.Is(err, ErrRebaseConflict) {
+		t.Fatalf("expected rebase conflict, got %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Resolved\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, repoDir, "add", "README.md")
+	if err := repo.RebaseContinue(repoDir); err != nil {
+		t.Fatalf("RebaseContinue returned error: %v", err)
+	}
+	if repo.IsRebaseInProgress(repoDir) {
+		t.Fatal("expected rebase to finish")
+	}
+
+	if err := repo.RebaseOnto(repoDir, "main"); err != nil {
+		t.Fatalf("expected up-to-date rebase to succeed, got %v", err)
+	}
+
+	// 跳过冲突提交后分支只剩主干的改动
+	runGit(t, repoDir, "checkout", "-b", "feature/skip", "main~1")
+	if err := os.WriteFile(filepath.Join(repoDir, "README.md"), []byte("# Skip\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	runGit(t, repoDir, "commit", "-am", "skipped change")
+	if err := repo.RebaseOnto(repoDir, "main"); !errors.Is(err, ErrRebaseConflict) {
+		t.Fatalf("expected rebase conflict, got %v", err)
+	}
+	if err := repo.RebaseSkip(repoDir); err != nil {
+		t.Fatalf("RebaseSkip returned error: %v", err)
+	}
+	content, err := os.ReadFile(filepath.Join(repoDir, "README.md"))
+	if err != nil {
+		t.Fatalf("read README: %v", err)
+	}
+	if strings.TrimSpace(string(content)) != "# Main" {
+		t.Fatalf("expected skipped commit to be dropped, README=%q", content)
+	}
+	if err := repo.RebaseOnto(repoDir, "--root"); err == nil {
+		t.Fatal("expected option-like upstream to be rejected")
+	}
+}
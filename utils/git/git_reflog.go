@@ -0,0 +1,84 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// ReflogDefaultLimit/ReflogMaxLimit 单次返回的 reflog 条数
+	ReflogDefaultLimit = 100
+	ReflogMaxLimit     = 1000
+)
+
+// ReflogEntry is one entry of the HEAD reflog: where HEAD pointed after an
+// operation such as commit, reset, rebase or checkout.
+type ReflogEntry struct {
+	// Selector 形如 HEAD@{2}，可直接作为 reset 的目标
+	Selector string `json:"selector"`
+	Commit   string `json:"commit"`
+	ShortSHA string `json:"shortSha"`
+	// Action 操作名称，如 commit、commit (amend)、reset、rebase (finish)、checkout
+	Action  string    `json:"action"`
+	Message string    `json:"message"`
+	Time    time.Time `json:"time"`
+}
+
+// GetReflog returns up to limit entries of the HEAD reflog of the worktree at
+// path, newest first. limit <= 0 uses ReflogDefaultLimit and values above
+// ReflogMaxLimit are capped. A repository without reflog yields no entries.
+func GetReflog(path string, limit int) ([]ReflogEntry, error) {
+	if limit <= 0 {
+		limit = ReflogDefaultLimit
+	}
+	limit = min(limit, ReflogMaxLimit)
+
+	// --date=unix 让选择器带上时间戳，HEAD@{n} 的序号按输出顺序补回
+	cmd := newGitCommand(path, "reflog", "show", "-n", strconv.Itoa(limit),
+		"--date=unix", "--format=%H%x00%gd%x00%gs", "HEAD", "--")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			stderr := strings.TrimSpace(string(exitErr.Stderr))
+			// 尚无提交时 HEAD 没有 reflog
+			if strings.Contains(stderr, "unknown revision") || strings.Contains(stderr, "bad default revision") {
+				return []ReflogEntry{}, nil
+			}
+			return nil, fmt.Errorf("git reflog failed: %s", stderr)
+		}
+		return nil, err
+	}
+	return parseReflog(string(output)), nil
+}
+
+func parseReflog(output string) []ReflogEntry {
+	entries := make([]ReflogEntry, 0)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\x00", 3)
+		if len(fields) != 3 {
+			continue
+		}
+		entry := ReflogEntry{
+			Selector: fmt.Sprintf("HEAD@{%d}", len(entries)),
+			Commit:   fields[0],
+			ShortSHA: shortCommit(fields[0]),
+			Message:  fields[2],
+		}
+		if open := strings.LastIndexByte(fields[1], '{'); open >= 0 {
+			stamp := strings.TrimSuffix(fields[1][open+1:], "}")
+			if seconds, err := strconv.ParseInt(stamp, 10, 64); err == nil {
+				entry.Time = time.Unix(seconds, 0)
+			}
+		}
+		if action, message, ok := strings.Cut(fields[2], ": "); ok {
+			entry.Action, entry.Message = action, message
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
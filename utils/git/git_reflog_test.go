@@ -0,0 +1,57 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseReflog(t *testing.T) {
+	output := "aaaaaaaaaa\x00HEAD@{1700000100}\x00reset: moving to HEAD~1\n" +
+		"bbbbbbbbbb\x00HEAD@{1700000000}\x00commit (amend): fix typo\n"
+	entries := parseReflog(output)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %+v", entries)
+	}
+	if e := entries[0]; e.Selector != "HEAD@{0}" || e.Action != "reset" || e.Message != "moving to HEAD~1" || e.ShortSHA != "aaaaaaa" || e.Time.Unix() != 1700000100 {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+	if e := entries[1]; e.Selector != "HEAD@{1}" || e.Action != "commit (amend)" || e.Message != "fix typo" {
+		t.Fatalf("unexpected entry %+v", e)
+	}
+}
+
+func TestGetReflog(t *testing.T) {
+	repoDir := initTestRepo(t)
+	if err := os.WriteFile(filepath.Join(repoDir, "lost.txt"), []byte("lost\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGit(t, repoDir, "add", "lost.txt")
+	runGit(t, repoDir, "commit", "-m", "lost work")
+	runGit(t, repoDir, "reset", "--hard", "HEAD~1")
+
+	entries, err := GetReflog(repoDir, 0)
+	if err != nil {
+		t.Fatalf("GetReflog failed: %v", err)
+	}
+	if len(entries) < 2 {
+		t.Fatalf("expected reset and commit entries, got %+v", entries)
+	}
+	if entries[0].Action != "reset" || entries[1].Action != "commit" || entries[1].Message != "lost work" {
+		t.Fatalf("unexpected entries %+v", entries[:2])
+	}
+	if entries[0].Time.IsZero() || len(entries[1].Commit) != 40 {
+		t.Fatalf("unexpected entry details %+v", entries[1])
+	}
+
+	// 被 reset 丢弃的提交仍可通过 reflog 中的 SHA 找回
+	runGit(t, repoDir, "reset", "--hard", entries[1].Commit)
+	if _, err := os.Stat(filepath.Join(repoDir, "lost.txt")); err != nil {
+		t.Fatalf("expected lost work to be restored: %v", err)
+	}
+
+	limited, err := GetReflog(repoDir, 1)
+	if err != nil || len(limited) != 1 {
+		t.Fatalf("expected one entry, got %+v err=%v", limited, err)
+	}
+}
@@ -64,6 +64,13 @@ func TestBranchAndWorktreeOperations(t *testing.T) {
 		t.Fatalf("feature/test branch not present in local branches: %#v", local)
 	}
 
+	if exists, err := repo.BranchExists("feature/test"); err != nil || !exists {
+		t.Fatalf("expected feature/test to exist: %v (%v)", exists, err)
+	}
+	if exists, err := repo.BranchExists("feature/missing"); err != nil || exists {
+		t.Fatalf("expected feature/missing to be absent: %v (%v)", exists, err)
+	}
+
 	worktreeParent := t.TempDir()
 	worktreePath := filepath.Join(worktreeParent, "feature-test")
 
@@ -0,0 +1,177 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	// ErrInvalidFilePath 文件路径为空、是绝对路径或指向 worktree 之外
+	ErrInvalidFilePath = errors.New("file path must be relative to the worktree")
+	// ErrNoUnstagedChanges 要丢弃的文件没有未暂存的改动
+	ErrNoUnstagedChanges = errors.New("file has no unstaged changes")
+)
+
+// FileChange is the status of a single changed file in a worktree.
+type FileChange struct {
+	Path string `json:"path"`
+	// OrigPath 重命名或复制前的路径
+	OrigPath string `json:"origPath,omitempty"`
+	// Index / Worktree 为 porcelain 的 X、Y 状态码（M/A/D/R/C/T/U），"." 表示无改动，未跟踪文件均为 "?"
+	Index      string `json:"index"`
+	Worktree   string `json:"worktree"`
+	Staged     bool   `json:"staged"`
+	Unstaged   bool   `json:"unstaged"`
+	Untracked  bool   `json:"untracked"`
+	Conflicted bool   `json:"conflicted"`
+}
+
+// GetFileChanges lists the changed files of the worktree at path, including
+// every untracked file.
+func GetFileChanges(path string) ([]FileChange, error) {
+	cmd := newGitCommand(path, "status", "--porcelain=2", "-z", "--untracked-files=all")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return parseFileChanges(string(output)), nil
+}
+
+// parseFileChanges parses NUL separated porcelain=2 records. Renamed and copied
+// entries are followed by an extra record holding the original path.
+func parseFileChanges(output string) []FileChange {
+	records := strings.Split(output, "\x00")
+	changes := make([]FileChange, 0, len(records))
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if record == "" {
+			continue
+		}
+		switch record[0] {
+		case '?':
+			changes = append(changes, FileChange{Path: strings.TrimPrefix(record, "? "), Index: "?", Worktree: "?", Unstaged: true, Untracked: true})
+		case '1':
+			if fields := strings.SplitN(record, " ", 9); len(fields) == 9 {
+				changes = append(changes, newFileChange(fields[1], fields[8]))
+			}
+		case '2':
+			fields := strings.SplitN(record, " ", 10)
+			if len(fields) != 10 {
+				continue
+			}
+			change := newFileChange(fields[1], fields[9])
+			if i+1 < len(records) {
+				i++
+				change.OrigPath = records[i]
+			}
+			changes = append(changes, change)
+		case 'u':
+			if fields := strings.SplitN(record, " ", 11); len(fields) == 11 {
+				change := newFileChange(fields[1], fields[10])
+				change.Conflicted = true
+				changes = append(changes, change)
+			}
+		}
+	}
+	return changes
+}
+
+func newFileChange(xy, path string) FileChange {
+	change := FileChange{Path: path, Index: ".", Worktree: "."}
+	if len(xy) == 2 {
+		change.Index, change.Worktree = xy[:1], xy[1:]
+	}
+	change.Staged = change.Index != "."
+	change.Unstaged = change.Worktree != "."
+	return change
+}
+
+// StageFiles stages the given files, including deletions, like git add --all.
+func (r *GitRepo) StageFiles(path string, files []string) error {
+	pathspecs, err := normalizeFilePaths(files)
+	if err != nil {
+		return err
+	}
+	return r.runInWorktree(path, append([]string{"--literal-pathspecs", "add", "--all", "--"}, pathspecs...)...)
+}
+
+// UnstageFiles moves the given files out of the index and keeps the working
+// tree untouched. It also works before the first commit.
+func (r *GitRepo) UnstageFiles(path string, files []string) error {
+	pathspecs, err := normalizeFilePaths(files)
+	if err != nil {
+		return err
+	}
+	return r.runInWorktree(path, append([]string{"--literal-pathspecs", "reset", "-q", "--"}, pathspecs...)...)
+}
+
+// DiscardChanges drops the unstaged changes of the given files: tracked files
+// are checked out from the index and untracked files are deleted. Staged
+// changes are kept; unstage them first to discard them as well.
+func (r *GitRepo) DiscardChanges(path string, files []string) error {
+	if r == nil {
+		return errors.New("git repository is not initialized")
+	}
+	pathspecs, err := normalizeFilePaths(files)
+	if err != nil {
+		return err
+	}
+	target := strings.TrimSpace(path)
+	if target == "" {
+		target = r.Path
+	}
+	changes, err := GetFileChanges(target)
+	if err != nil {
+		return err
+	}
+	byPath := make(map[string]FileChange, len(changes))
+	for _, change := range changes {
+		byPath[change.Path] = change
+	}
+
+	var tracked, untracked []string
+	for _, file := range pathspecs {
+		change, ok := byPath[file]
+		switch {
+		case !ok || !change.Unstaged:
+			return fmt.Errorf("%w: %s", ErrNoUnstagedChanges, file)
+		case change.Untracked:
+			untracked = append(untracked, file)
+		default:
+			tracked = append(tracked, file)
+		}
+	}
+
+	if len(tracked) > 0 {
+		if err := r.runInWorktree(target, append([]string{"--literal-pathspecs", "checkout", "--"}, tracked...)...); err != nil {
+			return err
+		}
+	}
+	if len(untracked) > 0 {
+		return r.runInWorktree(target, append([]string{"--literal-pathspecs", "clean", "-f", "-q", "--"}, untracked...)...)
+	}
+	return nil
+}
+
+// normalizeFilePaths converts paths to the slash separated form git status
+// reports and rejects paths escaping the worktree.
+func normalizeFilePaths(files []string) ([]string, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("%w: no files given", ErrInvalidFilePath)
+	}
+	result := make([]string, 0, len(files))
+	for _, file := range files {
+		trimmed := strings.TrimSpace(file)
+		if trimmed == "" || filepath.IsAbs(trimmed) || strings.HasPrefix(trimmed, "/") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidFilePath, file)
+		}
+		cleaned := filepath.ToSlash(filepath.Clean(trimmed))
+		if cleaned == "." || cleaned == ".." || strings.HasPrefix(cleaned, "../") {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidFilePath, file)
+		}
+		result = append(result, cleaned)
+	}
+	return result, nil
+}
@@ -0,0 +1,89 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseFileChanges(t *testing.T) {
+	output := "1 .M N... 100644 100644 100644 a a a.txt\x00" +
+		"2 R. N... 100644 100644 100644 b b R100 new name.txt\x00old name.txt\x00" +
+		"u UU N... 100644 100644 100644 100644 c c c conflict.txt\x00" +
+		"? dir/new.txt\x00"
+	changes := parseFileChanges(output)
+	if len(changes) != 4 {
+		t.Fatalf("expected 4 changes, got %+v", changes)
+	}
+	if c := changes[0]; c.Path != "a.txt" || c.Staged || !c.Unstaged || c.Worktree != "M" {
+		t.Fatalf("unexpected modified entry %+v", c)
+	}
+	if c := changes[1]; c.Path != "new name.txt" || c.OrigPath != "old name.txt" || !c.Staged || c.Unstaged {
+		t.Fatalf("unexpected renamed entry %+v", c)
+	}
+	if c := changes[2]; c.Path != "conflict.txt" || !c.Conflicted {
+		t.Fatalf("unexpected conflict entry %+v", c)
+	}
+	if c := changes[3]; c.Path != "dir/new.txt" || !c.Untracked || !c.Unstaged {
+		t.Fatalf("unexpected untracked entry %+v", c)
+	}
+}
+
+func TestStageUnstageDiscardFiles(t *testing.T) {
+	dir := initTestRepo(t)
+	repo, err := DetectRepository(dir)
+	if err != nil {
+		t.Fatalf("DetectRepository: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "README.md"), []byte("changed\n"), 0o644); err != nil {
+		t.Fatalf("write README: %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "new file.txt"), []byte("new\n"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+
+	find := func(path string) FileChange {
+		t.Helper()
+		changes, err := GetFileChanges(dir)
+		if err != nil {
+			t.Fatalf("GetFileChanges: %v", err)
+		}
+		for _, change := range changes {
+			if change.Path == path {
+				return change
+			}
+		}
+		return FileChange{}
+	}
+
+	if err := repo.StageFiles(dir, []string{"new file.txt"}); err != nil {
+		t.Fatalf("StageFiles: %v", err)
+	}
+	if c := find("new file.txt"); !c.Staged || c.Index != "A" {
+		t.Fatalf("expected file to be staged, got %+v", c)
+	}
+	if c := find("README.md"); c.Staged || !c.Unstaged {
+		t.Fatalf("expected README to stay unstaged, got %+v", c)
+	}
+
+	if err := repo.UnstageFiles(dir, []string{"new file.txt"}); err != nil {
+		t.Fatalf("UnstageFiles: %v", err)
+	}
+	if c := find("new file.txt"); !c.Untracked {
+		t.Fatalf("expected file to be untracked again, got %+v", c)
+	}
+
+	if err := repo.DiscardChanges(dir, []string{"README.md", "new file.txt"}); err != nil {
+		t.Fatalf("DiscardChanges: %v", err)
+	}
+	if changes, _ := GetFileChanges(dir); len(changes) != 0 {
+		t.Fatalf("expected clean worktree, got %+v", changes)
+	}
+	if err := repo.DiscardChanges(dir, []string{"README.md"}); !errors.Is(err, ErrNoUnstagedChanges) {
+		t.Fatalf("expected ErrNoUnstagedChanges, got %v", err)
+	}
+	if err := repo.StageFiles(dir, []string{"../outside.txt"}); !errors.Is(err, ErrInvalidFilePath) {
+		t.Fatalf("expected ErrInvalidFilePath, got %v", err)
+	}
+}
@@ -13,16 +13,31 @@ import (
 
 // WorktreeStatus aggregates repository state insights for a worktree.
 type WorktreeStatus struct {
-	Branch     string
+	Branch   string
+	Detached bool
+	// Upstream 当前分支的上游，如 origin/main；为空时 Ahead/Behind 恒为 0
+	Upstream   string
 	Ahead      int
 	Behind     int
 	Modified   int
 	Staged     int
 	Untracked  int
 	Conflicted int
+	// Renamed / Copied 是 porcelain=2 中 2 开头的记录，同时计入 Staged 或 Modified
+	Renamed    int
+	Copied     int
+	Renames    []RenamedFile
 	LastCommit *CommitInfo
 }
 
+// RenamedFile records a rename or copy detected by git status.
+type RenamedFile struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Copied bool   `json:"copied,omitempty"`
+	Score  int    `json:"score"` // 相似度百分比
+}
+
 // CommitInfo describes a git commit summary.
 type CommitInfo struct {
 	SHA     string
@@ -33,10 +48,14 @@ type CommitInfo struct {
 
 // GetWorktreeStatus gathers branch, diff, and status metrics for a worktree path.
 func GetWorktreeStatus(path string) (*WorktreeStatus, error) {
-	if status, err := collectWorktreeStatusViaGit(path); err == nil {
-		return status, nil
+	status, err := collectWorktreeStatusViaGit(path)
+	if err != nil {
+		status, err = getWorktreeStatusWithGoGit(path)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return getWorktreeStatusWithGoGit(path)
+	return status, nil
 }
 
 // GetWorktreeStatus returns the status for the provided worktree path. When
@@ -87,6 +106,7 @@ func getWorktreeStatusWithGoGit(path string) (*WorktreeStatus, error) {
 	status := &WorktreeStatus{}
 
 	if head, err := repo.Head(); err == nil {
+		status.Detached = !head.Name().IsBranch()
 		status.Branch = head.Name().Short()
 		if status.Branch == "" || status.Branch == "HEAD" {
 			status.Branch = describeBranch(path)
@@ -172,8 +192,16 @@ func parseStatusHeader(status *WorktreeStatus, line string) {
 
 	switch fields[0] {
 	case "branch.head":
-		if len(fields) > 1 && fields[1] != "(detached)" {
-			status.Branch = fields[1]
+		if len(fields) > 1 {
+			if fields[1] == "(detached)" {
+				status.Detached = true
+			} else {
+				status.Branch = fields[1]
+			}
+		}
+	case "branch.upstream":
+		if len(fields) > 1 {
+			status.Upstream = fields[1]
 		}
 	case "branch.ab":
 		if len(fields) >= 3 {
@@ -207,6 +235,34 @@ func parseTrackedStatus(status *WorktreeStatus, line string) {
 	if y != '.' {
 		status.Modified++
 	}
+	if line[0] == '2' {
+		parseRenamedStatus(status, line)
+	}
+}
+
+// parseRenamedStatus parses "2 <XY> <sub> <mH> <mI> <mW> <hH> <hI> <Xscore> <path>\t<origPath>".
+// Paths may contain spaces, so only the fixed leading fields are split.
+func parseRenamedStatus(status *WorktreeStatus, line string) {
+	fields := strings.SplitN(line, " ", 10)
+	if len(fields) < 10 || len(fields[8]) < 2 {
+		return
+	}
+	to, from, ok := strings.Cut(fields[9], "\t")
+	if !ok {
+		return
+	}
+	score, _ := strconv.Atoi(fields[8][1:])
+	rename := RenamedFile{From: from, To: to, Score: score}
+	switch fields[8][0] {
+	case 'R':
+		status.Renamed++
+	case 'C':
+		status.Copied++
+		rename.Copied = true
+	default:
+		return
+	}
+	status.Renames = append(status.Renames, rename)
 }
 
 func parseAheadBehindToken(token string) int {
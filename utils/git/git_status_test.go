@@ -11,6 +11,8 @@ func TestParseGitStatusOutput(t *testing.T) {
 1 M. N... 0000000 0000000 0000000 README.md
 1 .M N... 0000000 0000000 0000000 file.go
 1 MM N... 0000000 0000000 0000000 both.txt
+2 R. N... 100644 100644 100644 1111111 1111111 R100 docs/new name.md	docs/old name.md
+2 C. N... 100644 100644 100644 2222222 2222222 C75 copy.go	orig.go
 ? new.txt
 u UU N... 0000000 0000000 0000000 conflict.txt
 `
@@ -26,8 +28,8 @@ u UU N... 0000000 0000000 0000000 conflict.txt
 	if status.Ahead != 2 || status.Behind != 1 {
 		t.Fatalf("unexpected ahead/behind counts: %+v", status)
 	}
-	if status.Staged != 2 {
-		t.Fatalf("expected staged=2 got %d", status.Staged)
+	if status.Staged != 4 {
+		t.Fatalf("expected staged=4 got %d", status.Staged)
 	}
 	if status.Modified != 2 {
 		t.Fatalf("expected modified=2 got %d", status.Modified)
@@ -38,4 +40,36 @@ u UU N... 0000000 0000000 0000000 conflict.txt
 	if status.Conflicted != 1 {
 		t.Fatalf("expected conflicted=1 got %d", status.Conflicted)
 	}
+	if status.Renamed != 1 || status.Copied != 1 {
+		t.Fatalf("expected renamed=1 copied=1 got %d %d", status.Renamed, status.Copied)
+	}
+	want := []RenamedFile{
+		{From: "docs/old name.md", To: "docs/new name.md", Score: 100},
+		{From: "orig.go", To: "copy.go", Copied: true, Score: 75},
+	}
+	if len(status.Renames) != len(want) {
+		t.Fatalf("unexpected renames: %+v", status.Renames)
+	}
+	for i := range want {
+		if status.Renames[i] != want[i] {
+			t.Fatalf("rename %d: expected %+v got %+v", i, want[i], status.Renames[i])
+		}
+	}
+}
+
+func TestParseGitStatusOutputDetached(t *testing.T) {
+	output := `# branch.oid 3d2b07e3ce0b
+# branch.head (detached)
+`
+
+	status := parseGitStatusOutput(output)
+	if status == nil {
+		t.Fatalf("parseGitStatusOutput returned nil")
+	}
+	if !status.Detached {
+		t.Fatalf("expected detached status")
+	}
+	if status.Branch != "" {
+		t.Fatalf("expected empty branch got %q", status.Branch)
+	}
 }
@@ -0,0 +1,146 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Submodule describes a submodule declared in .gitmodules.
+type Submodule struct {
+	Name   string `json:"name"`
+	Path   string `json:"path"`
+	URL    string `json:"url,omitempty"`
+	Branch string `json:"branch,omitempty"`
+	// Commit 已初始化时为检出的提交，未初始化时为父仓库记录的提交
+	Commit      string `json:"commit,omitempty"`
+	Initialized bool   `json:"initialized"`
+	// OutOfDate 检出的提交与父仓库记录的不一致，执行 update 后恢复一致
+	OutOfDate bool `json:"outOfDate"`
+	// Conflicted 子模块存在合并冲突
+	Conflicted bool `json:"conflicted"`
+}
+
+// GetSubmodules lists the submodules declared in .gitmodules of the worktree at
+// path, with their state from `git submodule status`. A repository without
+// .gitmodules has no submodules.
+func GetSubmodules(path string) ([]Submodule, error) {
+	if _, err := os.Stat(filepath.Join(path, ".gitmodules")); err != nil {
+		if os.IsNotExist(err) {
+			return []Submodule{}, nil
+		}
+		return nil, err
+	}
+
+	// 没有匹配的配置项时 git config 以 1 退出，视为没有子模块
+	output, err := newGitCommand(path, "config", "-f", ".gitmodules", "--get-regexp", `^submodule\..*\.(path|url|branch)$`).Output()
+	if err != nil && len(output) == 0 {
+		return []Submodule{}, nil
+	}
+	submodules := parseGitmodulesConfig(string(output))
+	if len(submodules) == 0 {
+		return submodules, nil
+	}
+
+	statusOutput, err := newGitCommand(path, "submodule", "status").CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("git submodule status failed: %s", strings.TrimSpace(string(statusOutput)))
+	}
+	applySubmoduleStatus(submodules, string(statusOutput))
+	return submodules, nil
+}
+
+// UpdateSubmodules initializes and checks out the submodules of the worktree at
+// path at the commits recorded by the parent repository.
+func UpdateSubmodules(path string, recursive bool) error {
+	args := []string{"submodule", "update", "--init"}
+	if recursive {
+		args = append(args, "--recursive")
+	}
+	if output, err := newGitCommand(path, args...).CombinedOutput(); err != nil {
+		return gitFailure("git "+strings.Join(args, " "), string(output))
+	}
+	return nil
+}
+
+// parseGitmodulesConfig parses `git config --get-regexp` output of .gitmodules
+// ("submodule.<name>.<key> <value>" per line) in declaration order. Entries
+// without a path are dropped because git ignores them as well.
+func parseGitmodulesConfig(output string) []Submodule {
+	submodules := make([]Submodule, 0)
+	index := make(map[string]int)
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if !ok {
+			continue
+		}
+		rest, ok := strings.CutPrefix(key, "submodule.")
+		if !ok {
+			continue
+		}
+		dot := strings.LastIndex(rest, ".")
+		if dot <= 0 {
+			continue
+		}
+		name, field := rest[:dot], rest[dot+1:]
+		i, ok := index[name]
+		if !ok {
+			i = len(submodules)
+			index[name] = i
+			submodules = append(submodules, Submodule{Name: name})
+		}
+		value = strings.TrimSpace(value)
+		switch field {
+		case "path":
+			submodules[i].Path = value
+		case "url":
+			submodules[i].URL = value
+		case "branch":
+			submodules[i].Branch = value
+		}
+	}
+
+	result := submodules[:0]
+	for _, submodule := range submodules {
+		if submodule.Path != "" {
+			result = append(result, submodule)
+		}
+	}
+	return result
+}
+
+// applySubmoduleStatus fills the state of submodules from `git submodule
+// status` output. Each line is "<flag><sha> <path>[ (<describe>)]" where the
+// flag is ' ' (up to date), '-' (not initialized), '+' (checked out commit
+// differs from the recorded one) or 'U' (merge conflicts).
+func applySubmoduleStatus(submodules []Submodule, output string) {
+	byPath := make(map[string]*Submodule, len(submodules))
+	for i := range submodules {
+		byPath[filepath.ToSlash(submodules[i].Path)] = &submodules[i]
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if len(line) < 2 {
+			continue
+		}
+		flag := line[0]
+		sha, rest, ok := strings.Cut(line[1:], " ")
+		if !ok {
+			continue
+		}
+		// 路径可能包含空格，只去掉结尾的 describe 部分
+		if strings.HasSuffix(rest, ")") {
+			if idx := strings.LastIndex(rest, " ("); idx >= 0 {
+				rest = rest[:idx]
+			}
+		}
+		submodule, ok := byPath[rest]
+		if !ok {
+			continue
+		}
+		submodule.Commit = sha
+		submodule.Initialized = flag != '-'
+		submodule.OutOfDate = flag == '+'
+		submodule.Conflicted = flag == 'U'
+	}
+}
@@ -0,0 +1,84 @@
+package git
+
+import (
+	"testing"
+)
+
+func TestParseGitmodulesConfig(t *testing.T) {
+	output := "submodule.libs/core.path libs/core\n" +
+		"submodule.libs/core.url https://example.com/core.git\n" +
+		"submodule.docs.url ../docs.git\n" +
+		"submodule.docs.path docs site\n" +
+		"submodule.docs.branch main\n" +
+		"submodule.orphan.url https://example.com/orphan.git\n"
+	got := parseGitmodulesConfig(output)
+	if len(got) != 2 {
+		t.Fatalf("expected 2 submodules, got %#v", got)
+	}
+	if got[0].Name != "libs/core" || got[0].Path != "libs/core" || got[0].URL != "https://example.com/core.git" {
+		t.Fatalf("unexpected first submodule %#v", got[0])
+	}
+	if got[1].Name != "docs" || got[1].Path != "docs site" || got[1].Branch != "main" {
+		t.Fatalf("unexpected second submodule %#v", got[1])
+	}
+
+	applySubmoduleStatus(got, "+1111111111111111111111111111111111111111 libs/core (v1.0-1-g1111111)\n"+
+		"-2222222222222222222222222222222222222222 docs site\n")
+	if !got[0].Initialized || !got[0].OutOfDate || got[0].Commit != "1111111111111111111111111111111111111111" {
+		t.Fatalf("unexpected core state %#v", got[0])
+	}
+	if got[1].Initialized || got[1].OutOfDate || got[1].Commit != "2222222222222222222222222222222222222222" {
+		t.Fatalf("unexpected docs state %#v", got[1])
+	}
+}
+
+func TestGetAndUpdateSubmodules(t *testing.T) {
+	SetTestEnvOverride(append(testGitEnv(),
+		"GIT_CONFIG_COUNT=1",
+		"GIT_CONFIG_KEY_0=protocol.file.allow",
+		"GIT_CONFIG_VALUE_0=always",
+	))
+	t.Cleanup(func() { SetTestEnvOverride(nil) })
+
+	repoDir := initTestRepo(t)
+	submodules, err := GetSubmodules(repoDir)
+	if err != nil {
+		t.Fatalf("GetSubmodules returned error: %v", err)
+	}
+	if len(submodules) != 0 {
+		t.Fatalf("expected no submodules, got %#v", submodules)
+	}
+
+	libDir := initTestRepo(t)
+	runGit(t, repoDir, "-c", "protocol.file.allow=always", "submodule", "add", libDir, "lib")
+	runGit(t, repoDir, "commit", "-m", "add submodule")
+
+	submodules, err = GetSubmodules(repoDir)
+	if err != nil {
+		t.Fatalf("GetSubmodules returned error: %v", err)
+	}
+	if len(submodules) != 1 || !submodules[0].Initialized {
+		t.Fatalf("expected initialized lib submodule, got %#v", submodules)
+	}
+
+	cloneDir := t.TempDir()
+	runGit(t, cloneDir, "clone", repoDir, ".")
+	submodules, err = GetSubmodules(cloneDir)
+	if err != nil {
+		t.Fatalf("GetSubmodules returned error: %v", err)
+	}
+	if len(submodules) != 1 || submodules[0].Path != "lib" || submodules[0].Initialized {
+		t.Fatalf("expected uninitialized lib submodule, got %#v", submodules)
+	}
+
+	if err := UpdateSubmodules(cloneDir, true); err != nil {
+		t.Fatalf("UpdateSubmodules returned error: %v", err)
+	}
+	submodules, err = GetSubmodules(cloneDir)
+	if err != nil {
+		t.Fatalf("GetSubmodules returned error: %v", err)
+	}
+	if len(submodules) != 1 || !submodules[0].Initialized || submodules[0].OutOfDate || submodules[0].Commit == "" {
+		t.Fatalf("expected initialized lib submodule, got %#v", submodules)
+	}
+}
@@ -0,0 +1,100 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// unpushedCommitsMax 列出未推送提交的上限，提醒用户时只需要给出概览
+const unpushedCommitsMax = 100
+
+// GetUnpushedCommits lists commits of HEAD at path that are not on its upstream
+// branch, newest first. It returns nil without error when HEAD has no upstream.
+func GetUnpushedCommits(path string) ([]CommitInfo, error) {
+	return unpushedCommits(path, "HEAD")
+}
+
+// UnpushedCommits lists commits of the local branch that are not on its upstream.
+// It returns nil without error when the branch has no upstream.
+func (r *GitRepo) UnpushedCommits(branch string) ([]CommitInfo, error) {
+	if r == nil {
+		return nil, errors.New("git repository is not initialized")
+	}
+	branch = strings.TrimSpace(branch)
+	if branch == "" {
+		return nil, errors.New("branch name is required")
+	}
+	if strings.HasPrefix(branch, "-") {
+		return nil, fmt.Errorf("invalid branch name %q", branch)
+	}
+	return unpushedCommits(r.Path, branch)
+}
+
+// GetUnreferencedCommits lists commits of HEAD at path that no branch, tag or
+// remote-tracking ref contains, newest first. For a detached HEAD these commits
+// become unreachable once the worktree is removed.
+func GetUnreferencedCommits(path string) ([]CommitInfo, error) {
+	args := []string{"log", "-n", strconv.Itoa(unpushedCommitsMax),
+		"--pretty=format:%H%x00%an%x00%ad%x00%s%x1e", "--date=iso-strict",
+		"HEAD", "--not", "--branches", "--tags", "--remotes", "--"}
+	output, err := newGitCommand(path, args...).Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git log HEAD --not --branches failed: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return parseCommitRecords(string(output)), nil
+}
+
+func unpushedCommits(path, ref string) ([]CommitInfo, error) {
+	// 没有上游（或 HEAD 游离）时 rev-parse 失败，视为无需检测
+	upstream := ref + "@{upstream}"
+	if err := newGitCommand(path, "rev-parse", "--verify", "--quiet", upstream).Run(); err != nil {
+		return nil, nil
+	}
+
+	cmd := newGitCommand(path, "log", "-n", strconv.Itoa(unpushedCommitsMax),
+		"--pretty=format:%H%x00%an%x00%ad%x00%s%x1e", "--date=iso-strict", upstream+".."+ref, "--")
+	output, err := cmd.Output()
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			return nil, fmt.Errorf("git log %s failed: %s", upstream+".."+ref, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, err
+	}
+	return parseCommitRecords(string(output)), nil
+}
+
+// parseCommitRecords parses records written with
+// --pretty=format:%H%x00%an%x00%ad%x00%s%x1e.
+func parseCommitRecords(output string) []CommitInfo {
+	commits := make([]CommitInfo, 0)
+	for _, record := range strings.Split(output, "\x1e") {
+		record = strings.TrimSpace(record)
+		if record == "" {
+			continue
+		}
+		parts := strings.SplitN(record, "\x00", 4)
+		if len(parts) < 4 {
+			continue
+		}
+		timestamp, err := time.Parse(time.RFC3339, strings.TrimSpace(parts[2]))
+		if err != nil {
+			timestamp = time.Time{}
+		}
+		commits = append(commits, CommitInfo{
+			SHA:     shortCommit(strings.TrimSpace(parts[0])),
+			Author:  strings.TrimSpace(parts[1]),
+			Date:    timestamp,
+			Message: strings.TrimSpace(parts[3]),
+		})
+	}
+	return commits
+}
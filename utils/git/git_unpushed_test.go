@@ -0,0 +1,50 @@
+package git
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetUnpushedCommits(t *testing.T) {
+	dir := initTestRepo(t)
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	runGit(t, dir, "init", "--bare", remote)
+	runGit(t, dir, "remote", "set-url", "origin", remote)
+
+	commits, err := GetUnpushedCommits(dir)
+	if err != nil || commits != nil {
+		t.Fatalf("expected detection to be skipped without upstream, got %v, %v", commits, err)
+	}
+
+	runGit(t, dir, "push", "-u", "origin", "main")
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(name), 0o644); err != nil {
+			t.Fatalf("write file: %v", err)
+		}
+		runGit(t, dir, "add", name)
+		runGit(t, dir, "commit", "-m", "add "+name)
+	}
+
+	commits, err = GetUnpushedCommits(dir)
+	if err != nil {
+		t.Fatalf("GetUnpushedCommits returned error: %v", err)
+	}
+	if len(commits) != 2 || commits[0].Message != "add b.txt" || commits[1].Message != "add a.txt" {
+		t.Fatalf("unexpected unpushed commits: %+v", commits)
+	}
+
+	repo, err := DetectRepository(dir)
+	if err != nil {
+		t.Fatalf("DetectRepository: %v", err)
+	}
+	byBranch, err := repo.UnpushedCommits("main")
+	if err != nil || len(byBranch) != 2 {
+		t.Fatalf("expected 2 unpushed commits on main, got %v, %v", byBranch, err)
+	}
+
+	runGit(t, dir, "push", "origin", "main")
+	if commits, err := GetUnpushedCommits(dir); err != nil || len(commits) != 0 {
+		t.Fatalf("expected no unpushed commits after push, got %v, %v", commits, err)
+	}
+}
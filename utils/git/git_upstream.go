@@ -0,0 +1,53 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// GetUpstream returns the upstream of the branch checked out at path, such as
+// "origin/main". It returns "" without error when the branch has no upstream
+// or HEAD is detached; ahead/behind stay 0 until an upstream is set.
+func GetUpstream(path string) (string, error) {
+	output, err := newGitCommand(path, "symbolic-ref", "--quiet", "--short", "HEAD").Output()
+	if err != nil {
+		// symbolic-ref --quiet 在 HEAD 游离时以 1 退出，其余错误为 128
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			return "", nil
+		}
+		if exitErr != nil {
+			return "", gitFailure("git symbolic-ref", string(exitErr.Stderr))
+		}
+		return "", err
+	}
+	branch := strings.TrimSpace(string(output))
+	upstream, err := runGitOutput(path, "for-each-ref", "--format=%(upstream:short)", "refs/heads/"+branch)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(upstream), nil
+}
+
+// SetUpstream sets the upstream of the branch checked out at path to
+// remoteBranch (e.g. origin/feature-x), which must already exist; fetch first
+// or push with -u for branches not yet on the remote.
+func SetUpstream(path, remoteBranch string) error {
+	upstream := strings.TrimSpace(remoteBranch)
+	if upstream == "" {
+		return errors.New("remote branch is required")
+	}
+	if strings.HasPrefix(upstream, "-") {
+		return fmt.Errorf("invalid remote branch %q", upstream)
+	}
+	if err := newGitCommand(path, "rev-parse", "--verify", "--quiet", upstream+"^{commit}").Run(); err != nil {
+		return fmt.Errorf("%w: %s", ErrRefNotFound, upstream)
+	}
+	output, err := newGitCommand(path, "branch", "--set-upstream-to="+upstream).CombinedOutput()
+	if err != nil {
+		return gitFailure("set upstream", string(output))
+	}
+	return nil
+}
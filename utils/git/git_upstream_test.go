@@ -0,0 +1,56 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetAndSetUpstream(t *testing.T) {
+	dir := initTestRepo(t)
+	remote := filepath.Join(t.TempDir(), "remote.git")
+	runGit(t, dir, "init", "--bare", remote)
+	runGit(t, dir, "remote", "set-url", "origin", remote)
+	runGit(t, dir, "push", "origin", "main")
+
+	if upstream, err := GetUpstream(dir); err != nil || upstream != "" {
+		t.Fatalf("expected no upstream, got %q, %v", upstream, err)
+	}
+	status, err := GetWorktreeStatus(dir)
+	if err != nil {
+		t.Fatalf("GetWorktreeStatus: %v", err)
+	}
+	if status.Upstream != "" {
+		t.Fatalf("expected empty upstream in status, got %q", status.Upstream)
+	}
+
+	if err := SetUpstream(dir, "origin/missing"); !errors.Is(err, ErrRefNotFound) {
+		t.Fatalf("expected ErrRefNotFound, got %v", err)
+	}
+	runGit(t, dir, "fetch", "origin")
+	if err := SetUpstream(dir, "origin/main"); err != nil {
+		t.Fatalf("SetUpstream: %v", err)
+	}
+	if upstream, err := GetUpstream(dir); err != nil || upstream != "origin/main" {
+		t.Fatalf("expected origin/main, got %q, %v", upstream, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "a.txt"), []byte("a"), 0o644); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	runGit(t, dir, "add", "a.txt")
+	runGit(t, dir, "commit", "-m", "add a.txt")
+	status, err = GetWorktreeStatus(dir)
+	if err != nil {
+		t.Fatalf("GetWorktreeStatus: %v", err)
+	}
+	if status.Upstream != "origin/main" || status.Ahead != 1 {
+		t.Fatalf("expected 1 ahead of origin/main, got %+v", status)
+	}
+
+	runGit(t, dir, "checkout", "--detach")
+	if upstream, err := GetUpstream(dir); err != nil || upstream != "" {
+		t.Fatalf("expected no upstream for detached HEAD, got %q, %v", upstream, err)
+	}
+}
@@ -3,6 +3,7 @@ package git
 import (
 	"errors"
 	"fmt"
+	"os/exec"
 	"path/filepath"
 	"runtime"
 	"strings"
@@ -15,6 +16,7 @@ type WorktreeInfo struct {
 	HeadCommit string
 	IsMain     bool
 	IsBare     bool
+	Detached   bool
 }
 
 // ListWorktrees enumerates worktrees attached to the repository.
@@ -24,7 +26,7 @@ func (r *GitRepo) ListWorktrees() ([]WorktreeInfo, error) {
 	}
 
 	// Prune stale entries first so list output matches actual filesystem state.
-	_ = r.PruneWorktrees()
+	_, _ = r.PruneWorktrees()
 
 	cmd := newGitCommand(r.Path, "worktree", "list", "--porcelain")
 
@@ -71,11 +73,36 @@ func (r *GitRepo) AddWorktree(path, branch string, createBranch bool) error {
 
 	cmd := newGitCommand(r.Path, args...)
 	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("add worktree failed: %s", strings.TrimSpace(string(output)))
+		return gitFailure("add worktree", string(output))
 	}
 	return nil
 }
 
+// AddDetachedWorktree adds a new worktree at path with a detached HEAD at commit
+// and returns the full SHA of the checked out commit.
+func (r *GitRepo) AddDetachedWorktree(path, commit string) (string, error) {
+	if r == nil {
+		return "", errors.New("git repository is not initialized")
+	}
+	if strings.TrimSpace(path) == "" {
+		return "", errors.New("worktree path is required")
+	}
+	targetPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", err
+	}
+	sha, err := r.ResolveCommit(commit)
+	if err != nil {
+		return "", err
+	}
+
+	cmd := newGitCommand(r.Path, "worktree", "add", "--detach", targetPath, sha)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return "", gitFailure("add worktree", string(output))
+	}
+	return sha, nil
+}
+
 // RemoveWorktree removes an existing worktree.
 func (r *GitRepo) RemoveWorktree(path string, force bool) error {
 	if r == nil {
@@ -97,26 +124,78 @@ func (r *GitRepo) RemoveWorktree(path string, force bool) error {
 		// If the error indicates the worktree doesn't exist, try pruning
 		if strings.Contains(errMsg, "is not a working tree") || strings.Contains(errMsg, "not found") {
 			// Try to prune stale worktree entries
-			if pruneErr := r.PruneWorktrees(); pruneErr == nil {
+			if _, pruneErr := r.PruneWorktrees(); pruneErr == nil {
 				// After pruning, the worktree metadata should be cleaned up
 				return nil
 			}
 		}
-		return fmt.Errorf("remove worktree failed: %s", errMsg)
+		return gitFailure("remove worktree", errMsg)
 	}
 	return nil
 }
 
-// PruneWorktrees runs `git worktree prune` to clean stale entries.
-func (r *GitRepo) PruneWorktrees() error {
+// ErrWorktreeMoveUnsupported indicates the installed git predates `git worktree move` (2.17).
+var ErrWorktreeMoveUnsupported = errors.New("git worktree move is not supported by the installed git, upgrade to git 2.17 or later")
+
+// MoveWorktree moves a linked worktree to newPath with `git worktree move`.
+// Git versions without the subcommand yield ErrWorktreeMoveUnsupported; a manual
+// rename is not attempted because `git worktree repair` only arrived in 2.30.
+func (r *GitRepo) MoveWorktree(oldPath, newPath string) error {
 	if r == nil {
 		return errors.New("git repository is not initialized")
 	}
-	cmd := newGitCommand(r.Path, "worktree", "prune")
-	if output, err := cmd.CombinedOutput(); err != nil {
-		return fmt.Errorf("prune worktrees failed: %s", strings.TrimSpace(string(output)))
+	if strings.TrimSpace(oldPath) == "" || strings.TrimSpace(newPath) == "" {
+		return errors.New("worktree path is required")
 	}
-	return nil
+	targetPath, err := filepath.Abs(newPath)
+	if err != nil {
+		return err
+	}
+
+	cmd := newGitCommand(r.Path, "worktree", "move", oldPath, targetPath)
+	output, err := cmd.CombinedOutput()
+	if err == nil {
+		return nil
+	}
+	exitCode := -1
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	}
+	return moveWorktreeFailure(exitCode, string(output))
+}
+
+// moveWorktreeFailure 将 `git worktree move` 的失败转换为错误，旧版 git 不认识该子命令时返回 ErrWorktreeMoveUnsupported
+func moveWorktreeFailure(exitCode int, output string) error {
+	if exitCode == 129 && strings.Contains(output, "usage: git worktree") {
+		return fmt.Errorf("move worktree failed: %w", ErrWorktreeMoveUnsupported)
+	}
+	return gitFailure("move worktree", output)
+}
+
+// PruneWorktrees runs `git worktree prune -v` to clean stale entries and returns
+// the pruned entries, e.g. "worktrees/feature: gitdir file points to non-existent location".
+func (r *GitRepo) PruneWorktrees() ([]string, error) {
+	if r == nil {
+		return nil, errors.New("git repository is not initialized")
+	}
+	cmd := newGitCommand(r.Path, "worktree", "prune", "-v")
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		return nil, fmt.Errorf("prune worktrees failed: %s", strings.TrimSpace(string(output)))
+	}
+	return parsePrunedWorktrees(string(output)), nil
+}
+
+// parsePrunedWorktrees keeps the "Removing ..." lines of `git worktree prune -v`.
+func parsePrunedWorktrees(output string) []string {
+	pruned := make([]string, 0)
+	for _, line := range strings.Split(output, "\n") {
+		if entry, ok := strings.CutPrefix(strings.TrimSpace(line), "Removing "); ok && entry != "" {
+			pruned = append(pruned, entry)
+		}
+	}
+	return pruned
 }
 
 func parseWorktreeList(output string) []WorktreeInfo {
@@ -138,11 +217,12 @@ func parseWorktreeList(output string) []WorktreeInfo {
 			continue
 		}
 
+		// Attribute lines such as `bare` and `detached` carry no value.
 		parts := strings.SplitN(line, " ", 2)
-		if len(parts) != 2 {
-			continue
+		key, val := parts[0], ""
+		if len(parts) == 2 {
+			val = strings.TrimSpace(parts[1])
 		}
-		key, val := parts[0], strings.TrimSpace(parts[1])
 
 		switch key {
 		case "worktree":
@@ -158,7 +238,7 @@ func parseWorktreeList(output string) []WorktreeInfo {
 		case "bare":
 			current.IsBare = true
 		case "detached":
-			current.Branch = val
+			current.Detached = true
 		}
 	}
 	resetCurrent()
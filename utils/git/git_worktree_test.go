@@ -1,7 +1,11 @@
 package git
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -33,3 +37,85 @@ branch refs/heads/feature/demo
 		t.Fatalf("unexpected worktree list: %#v", got)
 	}
 }
+
+func TestParseWorktreeListDetachedAndBare(t *testing.T) {
+	input := `worktree /repo.git
+bare
+
+worktree /repo/detached
+HEAD 5da41358595c294c5b4af4a3e163192f7ca2ce50
+detached
+`
+
+	got := parseWorktreeList(input)
+	want := []WorktreeInfo{
+		{
+			Path:   "/repo.git",
+			IsBare: true,
+		},
+		{
+			Path:       "/repo/detached",
+			HeadCommit: "5da4135",
+			Detached:   true,
+		},
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected worktree list: %#v", got)
+	}
+}
+
+func TestParsePrunedWorktrees(t *testing.T) {
+	output := "Removing worktrees/feature: gitdir file points to non-existent location\n" +
+		"Removing worktrees/old: not a valid directory\n\n"
+	want := []string{
+		"worktrees/feature: gitdir file points to non-existent location",
+		"worktrees/old: not a valid directory",
+	}
+	if got := parsePrunedWorktrees(output); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected entries: %#v", got)
+	}
+	if got := parsePrunedWorktrees(""); len(got) != 0 {
+		t.Fatalf("expected no entries, got %#v", got)
+	}
+}
+
+func TestMoveWorktreeFailure(t *testing.T) {
+	usage := "error: unknown subcommand: `move'\nusage: git worktree add [<options>] <path> [<commit-ish>]\n"
+	if err := moveWorktreeFailure(129, usage); !errors.Is(err, ErrWorktreeMoveUnsupported) {
+		t.Fatalf("expected usage error of old git to yield ErrWorktreeMoveUnsupported, got %v", err)
+	}
+	err := moveWorktreeFailure(128, "fatal: cannot move a locked working tree")
+	if err == nil || errors.Is(err, ErrWorktreeMoveUnsupported) {
+		t.Fatalf("expected a locked worktree to yield a plain git failure, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "locked working tree") {
+		t.Fatalf("expected git output in error, got %v", err)
+	}
+}
+
+func TestMoveWorktreeLocked(t *testing.T) {
+	SetTestEnvOverride(testGitEnv())
+	defer SetTestEnvOverride(nil)
+
+	repoDir := initTestRepo(t)
+	oldPath := filepath.Join(t.TempDir(), "feature")
+	runGit(t, repoDir, "worktree", "add", "-b", "feature", oldPath)
+	runGit(t, repoDir, "worktree", "lock", oldPath)
+	repo, err := DetectRepository(repoDir)
+	if err != nil {
+		t.Fatalf("DetectRepository returned error: %v", err)
+	}
+
+	// 锁定的 worktree 移动失败时保持原位
+	newPath := filepath.Join(t.TempDir(), "moved")
+	if err := repo.MoveWorktree(oldPath, newPath); err == nil {
+		t.Fatalf("expected moving a locked worktree to fail")
+	}
+	if _, err := os.Stat(oldPath); err != nil {
+		t.Fatalf("expected the locked worktree to stay in place: %v", err)
+	}
+	if _, err := os.Stat(newPath); !os.IsNotExist(err) {
+		t.Fatalf("expected no directory at the target, got %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+package process
+
+import (
+	"fmt"
+	"time"
+
+	gocache "github.com/patrickmn/go-cache"
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// maxMemoryTreeDepth 限制递归深度，防止异常进程树导致长时间遍历
+const maxMemoryTreeDepth = 8
+
+// GetProcessTreeMemory returns the resident memory (RSS) in bytes used by a process
+// and all of its descendants. Returns 0 if the process cannot be inspected.
+func GetProcessTreeMemory(pid int32) uint64 {
+	if pid <= 0 {
+		return 0
+	}
+
+	cacheKey := fmt.Sprintf("tree_rss_%d", pid)
+	if cached, found := processCache.Get(cacheKey); found {
+		return cached.(uint64)
+	}
+
+	result := make(chan uint64, 1)
+	go func() {
+		proc, err := process.NewProcess(pid)
+		if err != nil {
+			result <- 0
+			return
+		}
+		result <- sumTreeMemory(proc, 0)
+	}()
+
+	select {
+	case total := <-result:
+		processCache.Set(cacheKey, total, gocache.DefaultExpiration)
+		return total
+	case <-time.After(queryTimeout):
+		return 0
+	}
+}
+
+func sumTreeMemory(proc *process.Process, depth int) uint64 {
+	var total uint64
+	if info, err := proc.MemoryInfo(); err == nil && info != nil {
+		total += info.RSS
+	}
+	if depth >= maxMemoryTreeDepth {
+		return total
+	}
+	children, err := proc.Children()
+	if err != nil {
+		return total
+	}
+	for _, child := range children {
+		total += sumTreeMemory(child, depth+1)
+	}
+	return total
+}
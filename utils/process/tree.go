@@ -0,0 +1,47 @@
+package process
+
+import (
+	"time"
+
+	"github.com/shirou/gopsutil/v4/process"
+)
+
+// GetProcessTree returns pid followed by all of its descendants, parents before
+// children. The result is never cached because callers act on it immediately.
+func GetProcessTree(pid int32) []int32 {
+	if pid <= 0 {
+		return nil
+	}
+
+	result := make(chan []int32, 1)
+	go func() {
+		proc, err := process.NewProcess(pid)
+		if err != nil {
+			result <- nil
+			return
+		}
+		result <- collectTree(proc, 0, nil)
+	}()
+
+	select {
+	case pids := <-result:
+		return pids
+	case <-time.After(queryTimeout):
+		return []int32{pid}
+	}
+}
+
+func collectTree(proc *process.Process, depth int, pids []int32) []int32 {
+	pids = append(pids, proc.Pid)
+	if depth >= maxMemoryTreeDepth {
+		return pids
+	}
+	children, err := proc.Children()
+	if err != nil {
+		return pids
+	}
+	for _, child := range children {
+		pids = collectTree(child, depth+1, pids)
+	}
+	return pids
+}
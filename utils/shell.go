@@ -45,6 +45,17 @@ func ResolveShellCommand(override string, cfg TerminalShellConfig) ([]string, er
 	return nil, fmt.Errorf("no suitable shell found for %s", runtime.GOOS)
 }
 
+// ResolveConfiguredShell resolves the shell configured for the current
+// platform. A shell set by the user must exist; only an empty value or the
+// built-in default falls back to probing the platform candidates.
+func ResolveConfiguredShell(cfg TerminalShellConfig) ([]string, error) {
+	configured := ConfiguredShell(cfg)
+	if configured == "" || configured == builtinShell() {
+		return ResolveShellCommand("", cfg)
+	}
+	return ResolveShellCommand(configured, cfg)
+}
+
 func parsePreferredShell(raw string) ([]string, error) {
 	parts, err := shlex.Split(raw)
 	if err != nil {
@@ -59,6 +70,87 @@ func parsePreferredShell(raw string) ([]string, error) {
 	return parts, nil
 }
 
+// DefaultShellName 按平台配置的 shell 在命名列表中的名称
+const DefaultShellName = "default"
+
+// ShellOption 可供选择的 shell，Available 表示命令在本机可执行
+type ShellOption struct {
+	Name      string `json:"name"`
+	Command   string `json:"command"`
+	Default   bool   `json:"default"`
+	Available bool   `json:"available"`
+}
+
+// NamedShell returns the command of the named shell. Empty and DefaultShellName
+// resolve to the platform shell and report false; callers then fall back to
+// ResolveShellCommand with an empty override.
+func NamedShell(cfg TerminalShellConfig, name string) (string, bool) {
+	name = strings.TrimSpace(name)
+	for _, shell := range cfg.Named {
+		if strings.TrimSpace(shell.Name) == name {
+			return strings.TrimSpace(shell.Command), true
+		}
+	}
+	return "", false
+}
+
+// ListShells returns the platform shell as DefaultShellName followed by the named
+// shells in configured order.
+func ListShells(cfg TerminalShellConfig) []ShellOption {
+	options := make([]ShellOption, 0, len(cfg.Named)+1)
+	defaultShell := ShellOption{Name: DefaultShellName, Command: ConfiguredShell(cfg), Default: true}
+	if parts, err := ResolveConfiguredShell(cfg); err == nil {
+		// 使用默认配置时展示实际会启动的候选项
+		defaultShell.Command = strings.Join(parts, " ")
+		defaultShell.Available = true
+	}
+	options = append(options, defaultShell)
+
+	for _, shell := range cfg.Named {
+		name := strings.TrimSpace(shell.Name)
+		if name == "" || name == DefaultShellName {
+			continue
+		}
+		option := ShellOption{Name: name, Command: strings.TrimSpace(shell.Command)}
+		if parts, err := shlex.Split(option.Command); err == nil && len(parts) > 0 {
+			option.Available = ensureExecutable(parts[0]) == nil
+		}
+		options = append(options, option)
+	}
+	return options
+}
+
+// 默认配置中各平台的 shell
+const (
+	builtinWindowsShell = "pwsh.exe -NoLogo"
+	builtinLinuxShell   = "/bin/bash"
+	builtinDarwinShell  = "/bin/zsh"
+)
+
+// builtinShell 当前平台默认配置的 shell
+func builtinShell() string {
+	switch runtime.GOOS {
+	case "windows":
+		return builtinWindowsShell
+	case "darwin":
+		return builtinDarwinShell
+	default:
+		return builtinLinuxShell
+	}
+}
+
+// ConfiguredShell returns the shell configured for the current platform.
+func ConfiguredShell(cfg TerminalShellConfig) string {
+	switch runtime.GOOS {
+	case "windows":
+		return strings.TrimSpace(cfg.Windows)
+	case "darwin":
+		return strings.TrimSpace(cfg.Darwin)
+	default:
+		return strings.TrimSpace(cfg.Linux)
+	}
+}
+
 func buildShellCandidates(cfg TerminalShellConfig) []string {
 	var candidates []string
 	appendCandidate := func(raw string) {
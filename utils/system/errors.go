@@ -9,4 +9,5 @@ var (
 	ErrEditorCommandMissing = errors.New("no supported editor command found")
 	ErrUnsupportedEditor    = errors.New("unsupported editor target")
 	ErrCustomEditorCommand  = errors.New("invalid custom editor command")
+	ErrUnknownTool          = errors.New("unknown tool")
 )
@@ -0,0 +1,107 @@
+package system
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"code-kanban/utils/cache"
+)
+
+const (
+	// toolCheckTTL 检测结果的缓存时间，安装工具后最多等待这么久才能看到变化
+	toolCheckTTL = 30 * time.Second
+	// toolVersionTimeout 执行 --version 的超时，部分 CLI 首次运行较慢
+	toolVersionTimeout = 5 * time.Second
+)
+
+// ToolInfo describes whether a command line tool is available in PATH.
+type ToolInfo struct {
+	Name      string `json:"name"`
+	Installed bool   `json:"installed"`
+	Path      string `json:"path,omitempty"`
+	// Version 为 --version 输出的第一行，执行失败时为空
+	Version        string `json:"version,omitempty"`
+	InstallCommand string `json:"installCommand,omitempty"`
+}
+
+// knownTools 可检测的 AI CLI 及其安装命令。只允许检测列表中的工具，避免通过接口执行任意命令
+var knownTools = map[string]string{
+	"claude":       "npm install -g @anthropic-ai/claude-code",
+	"codex":        "npm install -g @openai/codex",
+	"gemini":       "npm install -g @google/gemini-cli",
+	"qwen":         "npm install -g @qwen-code/qwen-code",
+	"copilot":      "npm install -g @github/copilot",
+	"cursor-agent": "curl https://cursor.com/install -fsS | bash",
+}
+
+var (
+	// toolCache 首次检测时才创建，避免导入包时就启动缓存的清理协程
+	toolCache     *cache.Cache
+	toolCacheOnce sync.Once
+)
+
+func toolCheckCache() *cache.Cache {
+	toolCacheOnce.Do(func() {
+		toolCache = cache.NewCache(toolCheckTTL)
+	})
+	return toolCache
+}
+
+// KnownTools returns the names accepted by CheckTool, sorted.
+func KnownTools() []string {
+	names := make([]string, 0, len(knownTools))
+	for name := range knownTools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CheckTool looks up a known AI CLI in PATH and reads its version. Results are
+// cached briefly so the check can run every time a session is created.
+func CheckTool(ctx context.Context, name string) (*ToolInfo, error) {
+	name = strings.TrimSpace(name)
+	installCommand, ok := knownTools[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q, supported: %s", ErrUnknownTool, name, strings.Join(KnownTools(), ", "))
+	}
+	if cached, ok := toolCheckCache().Get(name); ok {
+		info := *cached.(*ToolInfo)
+		return &info, nil
+	}
+
+	info := &ToolInfo{Name: name, InstallCommand: installCommand}
+	if path, err := exec.LookPath(name); err == nil {
+		info.Installed = true
+		info.Path = path
+		info.Version = toolVersion(ctx, path)
+	}
+	cachedInfo := *info
+	toolCheckCache().Set(name, &cachedInfo)
+	return info, nil
+}
+
+func toolVersion(ctx context.Context, path string) string {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, toolVersionTimeout)
+	defer cancel()
+
+	output, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return ""
+	}
+	for _, line := range bytes.Split(output, []byte("\n")) {
+		if text := strings.TrimSpace(string(line)); text != "" {
+			return text
+		}
+	}
+	return ""
+}
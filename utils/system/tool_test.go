@@ -0,0 +1,54 @@
+package system
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestCheckTool(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake tool is a shell script")
+	}
+	dir := t.TempDir()
+	script := "#!/bin/sh\necho\necho 'codex-cli 1.2.3'\n"
+	if err := os.WriteFile(filepath.Join(dir, "codex"), []byte(script), 0o755); err != nil {
+		t.Fatalf("write fake tool: %v", err)
+	}
+	t.Setenv("PATH", dir)
+	toolCheckCache().Delete("codex")
+	toolCheckCache().Delete("claude")
+	t.Cleanup(func() {
+		toolCheckCache().Delete("codex")
+		toolCheckCache().Delete("claude")
+	})
+
+	ctx := context.Background()
+	info, err := CheckTool(ctx, " codex ")
+	if err != nil {
+		t.Fatalf("CheckTool: %v", err)
+	}
+	if !info.Installed || info.Path != filepath.Join(dir, "codex") || info.Version != "codex-cli 1.2.3" {
+		t.Fatalf("unexpected info %+v", info)
+	}
+	info, err = CheckTool(ctx, "claude")
+	if err != nil || info.Installed || info.InstallCommand == "" {
+		t.Fatalf("expected claude to be missing with an install command, got %+v, %v", info, err)
+	}
+	if _, err := CheckTool(ctx, "rm"); !errors.Is(err, ErrUnknownTool) {
+		t.Fatalf("expected ErrUnknownTool, got %v", err)
+	}
+
+	// 结果在 TTL 内被缓存，修改返回值不影响缓存
+	if err := os.Remove(filepath.Join(dir, "codex")); err != nil {
+		t.Fatalf("remove fake tool: %v", err)
+	}
+	info, _ = CheckTool(ctx, "codex")
+	info.Version = "changed"
+	if cached, _ := CheckTool(ctx, "codex"); !cached.Installed || cached.Version != "codex-cli 1.2.3" {
+		t.Fatalf("expected the cached result, got %+v", cached)
+	}
+}